@@ -83,26 +83,32 @@ type Config struct {
 
 // An Alertmanager manages the alerts for one user.
 type Alertmanager struct {
-	cfg             *Config
-	api             *api.API
-	logger          log.Logger
-	state           State
-	persister       *statePersister
-	nflog           *nflog.Log
-	silences        *silence.Silences
-	marker          types.Marker
-	alerts          *mem.Alerts
-	dispatcher      *dispatch.Dispatcher
-	inhibitor       *inhibit.Inhibitor
-	pipelineBuilder *notify.PipelineBuilder
-	stop            chan struct{}
-	wg              sync.WaitGroup
-	mux             *http.ServeMux
-	registry        *prometheus.Registry
+	cfg                 *Config
+	api                 *api.API
+	logger              log.Logger
+	state               State
+	persister           *statePersister
+	nflog               *nflog.Log
+	silences            *silence.Silences
+	silencesLimiter     *silencesLimiter
+	notificationHistory *notificationHistory
+	marker              types.Marker
+	alerts              *mem.Alerts
+	dispatcher          *dispatch.Dispatcher
+	inhibitor           *inhibit.Inhibitor
+	pipelineBuilder     *notify.PipelineBuilder
+	stop                chan struct{}
+	wg                  sync.WaitGroup
+	mux                 *http.ServeMux
+	registry            *prometheus.Registry
 
 	// Pipeline created during last ApplyConfig call. Used for testing only.
 	lastPipeline notify.Stage
 
+	// Integrations built during last ApplyConfig call, by receiver name.
+	integrationsMtx sync.Mutex
+	integrations    map[string][]notify.Integration
+
 	// The Dispatcher is the only component we need to recreate when we call ApplyConfig.
 	// Given its metrics don't have any variable labels we need to re-use the same metrics.
 	dispatcherMetrics *dispatch.DispatcherMetrics
@@ -221,6 +227,17 @@ func New(cfg *Config, reg *prometheus.Registry) (*Alertmanager, error) {
 	c = am.state.AddState("sil:"+cfg.UserID, am.silences, am.registry)
 	am.silences.SetBroadcast(c.Broadcast)
 
+	am.silencesLimiter = newSilencesLimiter(cfg.UserID, cfg.Limits, am.silences, am.registry)
+
+	am.notificationHistory = newNotificationHistory(cfg.UserID, cfg.Retention, cfg.Limits, log.With(am.logger, "component", "notification-history"), am.registry)
+
+	// The notification history is replicated and persisted only when enabled, so that it can be
+	// enabled once all the replicas have been upgraded to a version supporting it.
+	if am.notificationHistory.maxEntries() > 0 {
+		c = am.state.AddState("nh:"+cfg.UserID, am.notificationHistory, am.registry)
+		am.notificationHistory.SetBroadcast(c.Broadcast)
+	}
+
 	// State replication needs to be started after the state keys are defined.
 	if service, ok := am.state.(services.Service); ok {
 		if err := service.StartAsync(context.Background()); err != nil {
@@ -284,6 +301,17 @@ func New(cfg *Config, reg *prometheus.Registry) (*Alertmanager, error) {
 		am.mux.Handle(a, http.NotFoundHandler())
 	}
 
+	// Enforce the silences limits on the upstream API endpoints creating silences.
+	for _, p := range []string{"/api/v1/silences", "/api/v2/silences"} {
+		a := path.Join(am.cfg.ExternalURL.Path, p)
+		upstream, _ := am.mux.Handler(&http.Request{Method: http.MethodPost, URL: &url.URL{Path: a}})
+		am.mux.Handle(a, am.silencesLimiter.wrap(upstream))
+	}
+
+	am.mux.HandleFunc(path.Join(am.cfg.ExternalURL.Path, "/api/v1/notifications"), am.serveNotificationHistory)
+	am.mux.HandleFunc(path.Join(am.cfg.ExternalURL.Path, "/api/v1/silences/export"), am.exportSilences)
+	am.mux.HandleFunc(path.Join(am.cfg.ExternalURL.Path, "/api/v1/silences/import"), am.importSilences)
+
 	am.dispatcherMetrics = dispatch.NewDispatcherMetrics(true, am.registry)
 
 	//TODO: From this point onward, the alertmanager _might_ receive requests - we need to make sure we've settled and are ready.
@@ -359,9 +387,11 @@ func (am *Alertmanager) ApplyConfig(userID string, conf *config.Config, rawCfg s
 				integration: integrationName,
 			}
 
-			return newRateLimitedNotifier(notifier, rl, 10*time.Second, am.rateLimitedNotifications.WithLabelValues(integrationName))
+			notifier = newRateLimitedNotifier(notifier, rl, 10*time.Second, am.rateLimitedNotifications.WithLabelValues(integrationName))
 		}
-		return notifier
+
+		// Wrap the rate-limited notifier too, so that rate-limited notifications are recorded as failed attempts.
+		return newNotificationHistoryNotifier(notifier, integrationName, am.notificationHistory)
 	})
 	if err != nil {
 		return nil
@@ -382,6 +412,11 @@ func (am *Alertmanager) ApplyConfig(userID string, conf *config.Config, rawCfg s
 		am.state,
 	)
 	am.lastPipeline = pipeline
+
+	am.integrationsMtx.Lock()
+	am.integrations = integrationsMap
+	am.integrationsMtx.Unlock()
+
 	am.dispatcher = dispatch.NewDispatcher(
 		am.alerts,
 		dispatch.NewRoute(conf.Route, nil),
@@ -454,6 +489,15 @@ func (am *Alertmanager) getFullState() (*clusterpb.FullState, error) {
 	return nil, errors.New("ring-based sharding not enabled")
 }
 
+// getReceiverIntegrations returns the integrations currently used to notify the given receiver.
+func (am *Alertmanager) getReceiverIntegrations(receiver string) ([]notify.Integration, bool) {
+	am.integrationsMtx.Lock()
+	defer am.integrationsMtx.Unlock()
+
+	integrations, ok := am.integrations[receiver]
+	return integrations, ok
+}
+
 // buildIntegrationsMap builds a map of name to the list of integration notifiers off of a
 // list of receiver config.
 func buildIntegrationsMap(nc []*config.Receiver, tmpl *template.Template, firewallDialer *util_net.FirewallDialer, logger log.Logger, notifierWrapper func(string, notify.Notifier) notify.Notifier) (map[string][]notify.Integration, error) {
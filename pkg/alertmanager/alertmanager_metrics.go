@@ -64,6 +64,9 @@ type alertmanagerMetrics struct {
 	insertAlertFailures                     *prometheus.Desc
 	alertsLimiterAlertsCount                *prometheus.Desc
 	alertsLimiterAlertsSize                 *prometheus.Desc
+	notificationHistoryRecorded             *prometheus.Desc
+	notificationHistoryDropped              *prometheus.Desc
+	insertSilenceFailures                   *prometheus.Desc
 }
 
 func newAlertmanagerMetrics() *alertmanagerMetrics {
@@ -229,6 +232,18 @@ func newAlertmanagerMetrics() *alertmanagerMetrics {
 			"cortex_alertmanager_alerts_limiter_current_alerts_size_bytes",
 			"Total size of alerts tracked by alerts limiter.",
 			[]string{"user"}, nil),
+		notificationHistoryRecorded: prometheus.NewDesc(
+			"cortex_alertmanager_notification_history_recorded_total",
+			"Number of notification attempts recorded in the notification history.",
+			[]string{"user", "integration"}, nil),
+		notificationHistoryDropped: prometheus.NewDesc(
+			"cortex_alertmanager_notification_history_dropped_total",
+			"Number of notification history entries dropped because of the retention or the max entries limit.",
+			[]string{"user"}, nil),
+		insertSilenceFailures: prometheus.NewDesc(
+			"cortex_alertmanager_silences_insert_limited_total",
+			"Total number of failures to create or import silences due to hitting alertmanager limits.",
+			[]string{"user"}, nil),
 	}
 }
 
@@ -283,6 +298,9 @@ func (m *alertmanagerMetrics) Describe(out chan<- *prometheus.Desc) {
 	out <- m.insertAlertFailures
 	out <- m.alertsLimiterAlertsCount
 	out <- m.alertsLimiterAlertsSize
+	out <- m.notificationHistoryRecorded
+	out <- m.notificationHistoryDropped
+	out <- m.insertSilenceFailures
 }
 
 func (m *alertmanagerMetrics) Collect(out chan<- prometheus.Metric) {
@@ -334,4 +352,8 @@ func (m *alertmanagerMetrics) Collect(out chan<- prometheus.Metric) {
 	data.SendSumOfCountersPerUser(out, m.insertAlertFailures, "alertmanager_alerts_insert_limited_total")
 	data.SendSumOfGaugesPerUser(out, m.alertsLimiterAlertsCount, "alertmanager_alerts_limiter_current_alerts")
 	data.SendSumOfGaugesPerUser(out, m.alertsLimiterAlertsSize, "alertmanager_alerts_limiter_current_alerts_size_bytes")
+
+	data.SendSumOfCountersPerUserWithLabels(out, m.notificationHistoryRecorded, "alertmanager_notification_history_recorded_total", "integration")
+	data.SendSumOfCountersPerUser(out, m.notificationHistoryDropped, "alertmanager_notification_history_dropped_total")
+	data.SendSumOfCountersPerUser(out, m.insertSilenceFailures, "alertmanager_silences_insert_limited_total")
 }
@@ -7,18 +7,23 @@ import (
 	fmt "fmt"
 	_ "github.com/gogo/protobuf/gogoproto"
 	proto "github.com/gogo/protobuf/proto"
+	github_com_gogo_protobuf_sortkeys "github.com/gogo/protobuf/sortkeys"
+	github_com_gogo_protobuf_types "github.com/gogo/protobuf/types"
+	_ "github.com/golang/protobuf/ptypes/timestamp"
 	clusterpb "github.com/prometheus/alertmanager/cluster/clusterpb"
 	io "io"
 	math "math"
 	math_bits "math/bits"
 	reflect "reflect"
 	strings "strings"
+	time "time"
 )
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
 var _ = fmt.Errorf
 var _ = math.Inf
+var _ = time.Kitchen
 
 // This is a compile-time assertion to ensure that this generated file
 // is compatible with the proto package it is being compiled against.
@@ -179,36 +184,227 @@ func (m *FullStateDesc) GetState() *clusterpb.FullState {
 	return nil
 }
 
+// NotificationHistoryEntry records a single attempt to notify a receiver integration.
+type NotificationHistoryEntry struct {
+	ID             string            `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Receiver       string            `protobuf:"bytes,2,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	Integration    string            `protobuf:"bytes,3,opt,name=integration,proto3" json:"integration,omitempty"`
+	GroupKey       string            `protobuf:"bytes,4,opt,name=group_key,json=groupKey,proto3" json:"group_key,omitempty"`
+	GroupLabels    map[string]string `protobuf:"bytes,5,rep,name=group_labels,json=groupLabels,proto3" json:"group_labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	FiringAlerts   int32             `protobuf:"varint,6,opt,name=firing_alerts,json=firingAlerts,proto3" json:"firing_alerts,omitempty"`
+	ResolvedAlerts int32             `protobuf:"varint,7,opt,name=resolved_alerts,json=resolvedAlerts,proto3" json:"resolved_alerts,omitempty"`
+	Success        bool              `protobuf:"varint,8,opt,name=success,proto3" json:"success,omitempty"`
+	Error          string            `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	StartedAt      time.Time         `protobuf:"bytes,10,opt,name=started_at,json=startedAt,proto3,stdtime" json:"started_at"`
+	FinishedAt     time.Time         `protobuf:"bytes,11,opt,name=finished_at,json=finishedAt,proto3,stdtime" json:"finished_at"`
+}
+
+func (m *NotificationHistoryEntry) Reset()      { *m = NotificationHistoryEntry{} }
+func (*NotificationHistoryEntry) ProtoMessage() {}
+func (*NotificationHistoryEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{3}
+}
+func (m *NotificationHistoryEntry) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *NotificationHistoryEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_NotificationHistoryEntry.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *NotificationHistoryEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NotificationHistoryEntry.Merge(m, src)
+}
+func (m *NotificationHistoryEntry) XXX_Size() int {
+	return m.Size()
+}
+func (m *NotificationHistoryEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_NotificationHistoryEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NotificationHistoryEntry proto.InternalMessageInfo
+
+func (m *NotificationHistoryEntry) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *NotificationHistoryEntry) GetReceiver() string {
+	if m != nil {
+		return m.Receiver
+	}
+	return ""
+}
+
+func (m *NotificationHistoryEntry) GetIntegration() string {
+	if m != nil {
+		return m.Integration
+	}
+	return ""
+}
+
+func (m *NotificationHistoryEntry) GetGroupKey() string {
+	if m != nil {
+		return m.GroupKey
+	}
+	return ""
+}
+
+func (m *NotificationHistoryEntry) GetGroupLabels() map[string]string {
+	if m != nil {
+		return m.GroupLabels
+	}
+	return nil
+}
+
+func (m *NotificationHistoryEntry) GetFiringAlerts() int32 {
+	if m != nil {
+		return m.FiringAlerts
+	}
+	return 0
+}
+
+func (m *NotificationHistoryEntry) GetResolvedAlerts() int32 {
+	if m != nil {
+		return m.ResolvedAlerts
+	}
+	return 0
+}
+
+func (m *NotificationHistoryEntry) GetSuccess() bool {
+	if m != nil {
+		return m.Success
+	}
+	return false
+}
+
+func (m *NotificationHistoryEntry) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *NotificationHistoryEntry) GetStartedAt() time.Time {
+	if m != nil {
+		return m.StartedAt
+	}
+	return time.Time{}
+}
+
+func (m *NotificationHistoryEntry) GetFinishedAt() time.Time {
+	if m != nil {
+		return m.FinishedAt
+	}
+	return time.Time{}
+}
+
+type NotificationHistoryDesc struct {
+	Entries []NotificationHistoryEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries"`
+}
+
+func (m *NotificationHistoryDesc) Reset()      { *m = NotificationHistoryDesc{} }
+func (*NotificationHistoryDesc) ProtoMessage() {}
+func (*NotificationHistoryDesc) Descriptor() ([]byte, []int) {
+	return fileDescriptor_20493709c38b81dc, []int{4}
+}
+func (m *NotificationHistoryDesc) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *NotificationHistoryDesc) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_NotificationHistoryDesc.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *NotificationHistoryDesc) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NotificationHistoryDesc.Merge(m, src)
+}
+func (m *NotificationHistoryDesc) XXX_Size() int {
+	return m.Size()
+}
+func (m *NotificationHistoryDesc) XXX_DiscardUnknown() {
+	xxx_messageInfo_NotificationHistoryDesc.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NotificationHistoryDesc proto.InternalMessageInfo
+
+func (m *NotificationHistoryDesc) GetEntries() []NotificationHistoryEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*AlertConfigDesc)(nil), "alerts.AlertConfigDesc")
 	proto.RegisterType((*TemplateDesc)(nil), "alerts.TemplateDesc")
 	proto.RegisterType((*FullStateDesc)(nil), "alerts.FullStateDesc")
+	proto.RegisterType((*NotificationHistoryEntry)(nil), "alerts.NotificationHistoryEntry")
+	proto.RegisterMapType((map[string]string)(nil), "alerts.NotificationHistoryEntry.GroupLabelsEntry")
+	proto.RegisterType((*NotificationHistoryDesc)(nil), "alerts.NotificationHistoryDesc")
 }
 
 func init() { proto.RegisterFile("alerts.proto", fileDescriptor_20493709c38b81dc) }
 
 var fileDescriptor_20493709c38b81dc = []byte{
-	// 320 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x50, 0xbf, 0x4e, 0xc2, 0x40,
-	0x18, 0xbf, 0x13, 0x24, 0x70, 0x62, 0x4c, 0x1a, 0x06, 0x42, 0xe2, 0x27, 0x61, 0x22, 0x0e, 0x6d,
-	0x82, 0x9b, 0x03, 0x09, 0x68, 0x7c, 0x00, 0x74, 0x72, 0x31, 0xd7, 0x7a, 0x14, 0x92, 0x96, 0x6b,
-	0xee, 0xae, 0x41, 0x37, 0x1f, 0xc1, 0x47, 0x70, 0xf4, 0x51, 0x1c, 0x19, 0x19, 0xe5, 0x58, 0x18,
-	0x79, 0x04, 0x73, 0x77, 0xa5, 0x38, 0xf5, 0xf7, 0xf5, 0xf7, 0xe7, 0xfb, 0x7e, 0x47, 0x9a, 0x34,
-	0x61, 0x42, 0x49, 0x3f, 0x13, 0x5c, 0x71, 0xaf, 0xe6, 0xa6, 0x4e, 0x2b, 0xe6, 0x31, 0xb7, 0xbf,
-	0x02, 0x83, 0x1c, 0xdb, 0x19, 0xc7, 0x73, 0x35, 0xcb, 0x43, 0x3f, 0xe2, 0x69, 0x90, 0x09, 0x9e,
-	0x32, 0x35, 0x63, 0xb9, 0x0c, 0xac, 0x27, 0xa5, 0x0b, 0x1a, 0x33, 0x11, 0x44, 0x49, 0x2e, 0xd5,
-	0xf1, 0x9b, 0x85, 0x07, 0xe4, 0x32, 0x7a, 0x6f, 0xe4, 0x62, 0x64, 0xf4, 0x77, 0x7c, 0x31, 0x9d,
-	0xc7, 0xf7, 0x4c, 0x46, 0x9e, 0x47, 0xaa, 0xb9, 0x64, 0xa2, 0x8d, 0xbb, 0xb8, 0xdf, 0x98, 0x58,
-	0xec, 0x5d, 0x12, 0x22, 0xe8, 0xf2, 0x25, 0xb2, 0xaa, 0xf6, 0x89, 0x65, 0x1a, 0x82, 0x2e, 0x9d,
-	0xcd, 0x1b, 0x90, 0x86, 0x62, 0x69, 0x96, 0x50, 0xc5, 0x64, 0xbb, 0xd2, 0xad, 0xf4, 0xcf, 0x06,
-	0x2d, 0xbf, 0x68, 0xf2, 0x54, 0x10, 0x26, 0x7b, 0x72, 0x94, 0xf5, 0x86, 0xa4, 0xf9, 0x9f, 0xf2,
-	0x3a, 0xa4, 0x3e, 0x9d, 0x27, 0x6c, 0x41, 0x53, 0x56, 0xac, 0x2e, 0x67, 0x73, 0x52, 0xc8, 0x5f,
-	0xdf, 0x8b, 0xc5, 0x16, 0xf7, 0x46, 0xe4, 0xfc, 0x21, 0x4f, 0x92, 0x47, 0x75, 0x08, 0xb8, 0x26,
-	0xa7, 0xd2, 0x0c, 0xd6, 0x6d, 0x0e, 0x28, 0x3b, 0xfb, 0xa5, 0x70, 0xe2, 0x24, 0xb7, 0xd5, 0xdd,
-	0xd7, 0x15, 0x1a, 0x0f, 0x57, 0x1b, 0x40, 0xeb, 0x0d, 0xa0, 0xfd, 0x06, 0xf0, 0x87, 0x06, 0xfc,
-	0xad, 0x01, 0xff, 0x68, 0xc0, 0x2b, 0x0d, 0xf8, 0x57, 0x03, 0xde, 0x69, 0x40, 0x7b, 0x0d, 0xf8,
-	0x73, 0x0b, 0x68, 0xb5, 0x05, 0xb4, 0xde, 0x02, 0x7a, 0xae, 0xbb, 0x62, 0x59, 0x18, 0xd6, 0xec,
-	0x1b, 0xde, 0xfc, 0x05, 0x00, 0x00, 0xff, 0xff, 0x3d, 0xbd, 0xc1, 0x50, 0xb5, 0x01, 0x00, 0x00,
+	// 642 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x52, 0xbb, 0x6e, 0xdb, 0x4a,
+	0x10, 0xe5, 0xea, 0x65, 0x69, 0x24, 0x5f, 0x1b, 0x0b, 0xe3, 0x5e, 0x42, 0x17, 0xa1, 0x04, 0xa5,
+	0x88, 0x90, 0x82, 0x42, 0x9c, 0x26, 0x70, 0x61, 0x44, 0xb2, 0x9d, 0x07, 0x12, 0xa4, 0x60, 0x5c,
+	0x25, 0x85, 0x41, 0x52, 0x2b, 0x7a, 0x11, 0x92, 0x2b, 0xec, 0x2e, 0xed, 0xa8, 0xcb, 0x27, 0xf8,
+	0x13, 0x52, 0xa6, 0xc9, 0x7f, 0xb8, 0x74, 0xe9, 0xca, 0x89, 0xe9, 0xc6, 0xa5, 0x3f, 0x21, 0xd8,
+	0x5d, 0x52, 0x36, 0x82, 0x04, 0x41, 0x2a, 0xce, 0x99, 0x39, 0xe7, 0x70, 0x76, 0x66, 0xa0, 0xe3,
+	0xc7, 0x84, 0x4b, 0xe1, 0xce, 0x39, 0x93, 0x0c, 0x37, 0x0c, 0xea, 0x6e, 0x44, 0x2c, 0x62, 0x3a,
+	0x35, 0x52, 0x91, 0xa9, 0x76, 0x7b, 0x11, 0x63, 0x51, 0x4c, 0x46, 0x1a, 0x05, 0xd9, 0x6c, 0x24,
+	0x69, 0x42, 0x84, 0xf4, 0x93, 0x79, 0x41, 0x98, 0x44, 0x54, 0x1e, 0x66, 0x81, 0x1b, 0xb2, 0x44,
+	0x91, 0x12, 0x22, 0x0f, 0x49, 0x26, 0x46, 0xda, 0x34, 0xf1, 0x53, 0x3f, 0x22, 0x7c, 0x14, 0xc6,
+	0x99, 0x90, 0xb7, 0xdf, 0x79, 0x50, 0x46, 0xc6, 0x63, 0xf0, 0x11, 0xd6, 0xc6, 0x8a, 0xbf, 0xc3,
+	0xd2, 0x19, 0x8d, 0x76, 0x89, 0x08, 0x31, 0x86, 0x5a, 0x26, 0x08, 0xb7, 0x51, 0x1f, 0x0d, 0x5b,
+	0x9e, 0x8e, 0xf1, 0x3d, 0x00, 0xee, 0x1f, 0x1f, 0x84, 0x9a, 0x65, 0x57, 0x74, 0xa5, 0xc5, 0xfd,
+	0x63, 0x23, 0xc3, 0x9b, 0xd0, 0x92, 0x24, 0x99, 0xc7, 0xbe, 0x24, 0xc2, 0xae, 0xf6, 0xab, 0xc3,
+	0xf6, 0xe6, 0x86, 0x5b, 0x3c, 0x75, 0xbf, 0x28, 0x28, 0x6f, 0xef, 0x96, 0x36, 0xd8, 0x86, 0xce,
+	0xdd, 0x12, 0xee, 0x42, 0x73, 0x46, 0x63, 0x92, 0xfa, 0x09, 0x29, 0x7e, 0xbd, 0xc4, 0xaa, 0xa5,
+	0x80, 0x4d, 0x17, 0xc5, 0x8f, 0x75, 0x3c, 0x18, 0xc3, 0xea, 0xb3, 0x2c, 0x8e, 0xdf, 0xca, 0xd2,
+	0xe0, 0x21, 0xd4, 0x85, 0x02, 0x5a, 0xad, 0x1a, 0x58, 0xbe, 0xd9, 0x5d, 0x12, 0x3d, 0x43, 0xd9,
+	0xaa, 0x5d, 0x7f, 0xee, 0x59, 0x83, 0xaf, 0x35, 0xb0, 0xdf, 0x30, 0x49, 0x67, 0x34, 0xf4, 0x25,
+	0x65, 0xe9, 0x0b, 0x2a, 0x24, 0xe3, 0x8b, 0xbd, 0x54, 0xf2, 0x05, 0xfe, 0x17, 0x2a, 0x74, 0x6a,
+	0x3a, 0x99, 0x34, 0xf2, 0x8b, 0x5e, 0xe5, 0xe5, 0xae, 0x57, 0xa1, 0x53, 0xd5, 0x27, 0x27, 0x21,
+	0xa1, 0x47, 0x84, 0x17, 0xfd, 0x2c, 0x31, 0xee, 0x43, 0x9b, 0xa6, 0x92, 0x44, 0x5c, 0xdb, 0xd9,
+	0x55, 0x5d, 0xbe, 0x9b, 0xc2, 0xff, 0x43, 0x2b, 0xe2, 0x2c, 0x9b, 0x1f, 0x7c, 0x20, 0x0b, 0xbb,
+	0x66, 0xe4, 0x3a, 0xf1, 0x8a, 0x2c, 0xf0, 0x3e, 0x74, 0x4c, 0x31, 0xf6, 0x03, 0x12, 0x0b, 0xbb,
+	0xae, 0x27, 0xf9, 0xa8, 0x9c, 0xe4, 0xef, 0x5a, 0x75, 0x9f, 0x2b, 0xd1, 0x6b, 0xad, 0xd1, 0x09,
+	0xaf, 0x1d, 0xdd, 0x66, 0xf0, 0x7d, 0x58, 0x9d, 0x51, 0x4e, 0xd3, 0xe8, 0xc0, 0xf8, 0xd8, 0x8d,
+	0x3e, 0x1a, 0xd6, 0xbd, 0x8e, 0x49, 0xea, 0xed, 0x0b, 0xfc, 0x00, 0xd6, 0x38, 0x11, 0x2c, 0x3e,
+	0x22, 0xd3, 0x92, 0xb6, 0xa2, 0x69, 0xff, 0x94, 0xe9, 0x82, 0x68, 0xc3, 0x8a, 0xc8, 0xc2, 0x90,
+	0x08, 0x61, 0x37, 0xfb, 0x68, 0xd8, 0xf4, 0x4a, 0x88, 0x37, 0xa0, 0x4e, 0x38, 0x67, 0xdc, 0x6e,
+	0xe9, 0x67, 0x19, 0x80, 0x77, 0x00, 0x84, 0xf4, 0xb9, 0x54, 0xbe, 0xd2, 0x06, 0xbd, 0x9a, 0xae,
+	0x6b, 0x4e, 0xdb, 0x2d, 0x4f, 0xdb, 0xdd, 0x2f, 0x4f, 0x7b, 0xd2, 0x3c, 0xbd, 0xe8, 0x59, 0x27,
+	0xdf, 0x7a, 0xc8, 0x6b, 0x15, 0xba, 0xb1, 0xc4, 0x7b, 0xd0, 0x9e, 0xd1, 0x94, 0x8a, 0x43, 0xe3,
+	0xd2, 0xfe, 0x0b, 0x17, 0x28, 0x85, 0x63, 0xd9, 0xdd, 0x86, 0xf5, 0x9f, 0x47, 0x85, 0xd7, 0xa1,
+	0xaa, 0x56, 0x61, 0x2e, 0x4e, 0x85, 0xea, 0x1d, 0x47, 0x7e, 0x9c, 0x91, 0x62, 0xbb, 0x06, 0x6c,
+	0x55, 0x9e, 0xa0, 0xc1, 0x7b, 0xf8, 0xef, 0x17, 0x3b, 0xd0, 0xc7, 0xf7, 0x14, 0x56, 0x48, 0x2a,
+	0x39, 0x25, 0xc2, 0x46, 0x7a, 0x6b, 0xfd, 0x3f, 0x6d, 0x6d, 0x52, 0x53, 0x3d, 0x7a, 0xa5, 0x6c,
+	0xb2, 0x7d, 0x76, 0xe9, 0x58, 0xe7, 0x97, 0x8e, 0x75, 0x73, 0xe9, 0xa0, 0x4f, 0xb9, 0x83, 0xbe,
+	0xe4, 0x0e, 0x3a, 0xcd, 0x1d, 0x74, 0x96, 0x3b, 0xe8, 0x7b, 0xee, 0xa0, 0xeb, 0xdc, 0xb1, 0x6e,
+	0x72, 0x07, 0x9d, 0x5c, 0x39, 0xd6, 0xd9, 0x95, 0x63, 0x9d, 0x5f, 0x39, 0xd6, 0xbb, 0xa6, 0xf9,
+	0xcb, 0x3c, 0x08, 0x1a, 0x7a, 0x0c, 0x8f, 0x7f, 0x0c, 0x00, 0xec, 0xb8, 0xa8, 0x80, 0x63, 0x04,
+	0x00, 0x00,
 }
 
 func (this *AlertConfigDesc) Equal(that interface{}) bool {
@@ -273,6 +469,94 @@ func (this *TemplateDesc) Equal(that interface{}) bool {
 	}
 	return true
 }
+func (this *NotificationHistoryEntry) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*NotificationHistoryEntry)
+	if !ok {
+		that2, ok := that.(NotificationHistoryEntry)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if this.ID != that1.ID {
+		return false
+	}
+	if this.Receiver != that1.Receiver {
+		return false
+	}
+	if this.Integration != that1.Integration {
+		return false
+	}
+	if this.GroupKey != that1.GroupKey {
+		return false
+	}
+	if len(this.GroupLabels) != len(that1.GroupLabels) {
+		return false
+	}
+	for i := range this.GroupLabels {
+		if this.GroupLabels[i] != that1.GroupLabels[i] {
+			return false
+		}
+	}
+	if this.FiringAlerts != that1.FiringAlerts {
+		return false
+	}
+	if this.ResolvedAlerts != that1.ResolvedAlerts {
+		return false
+	}
+	if this.Success != that1.Success {
+		return false
+	}
+	if this.Error != that1.Error {
+		return false
+	}
+	if !this.StartedAt.Equal(that1.StartedAt) {
+		return false
+	}
+	if !this.FinishedAt.Equal(that1.FinishedAt) {
+		return false
+	}
+	return true
+}
+func (this *NotificationHistoryDesc) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*NotificationHistoryDesc)
+	if !ok {
+		that2, ok := that.(NotificationHistoryDesc)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if len(this.Entries) != len(that1.Entries) {
+		return false
+	}
+	for i := range this.Entries {
+		if !this.Entries[i].Equal(&that1.Entries[i]) {
+			return false
+		}
+	}
+	return true
+}
 func (this *AlertConfigDesc) GoString() string {
 	if this == nil {
 		return "nil"
@@ -310,6 +594,54 @@ func (this *FullStateDesc) GoString() string {
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
+func (this *NotificationHistoryEntry) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 15)
+	s = append(s, "&alertspb.NotificationHistoryEntry{")
+	s = append(s, "ID: "+fmt.Sprintf("%#v", this.ID)+",\n")
+	s = append(s, "Receiver: "+fmt.Sprintf("%#v", this.Receiver)+",\n")
+	s = append(s, "Integration: "+fmt.Sprintf("%#v", this.Integration)+",\n")
+	s = append(s, "GroupKey: "+fmt.Sprintf("%#v", this.GroupKey)+",\n")
+	keysForGroupLabels := make([]string, 0, len(this.GroupLabels))
+	for k, _ := range this.GroupLabels {
+		keysForGroupLabels = append(keysForGroupLabels, k)
+	}
+	github_com_gogo_protobuf_sortkeys.Strings(keysForGroupLabels)
+	mapStringForGroupLabels := "map[string]string{"
+	for _, k := range keysForGroupLabels {
+		mapStringForGroupLabels += fmt.Sprintf("%#v: %#v,", k, this.GroupLabels[k])
+	}
+	mapStringForGroupLabels += "}"
+	if this.GroupLabels != nil {
+		s = append(s, "GroupLabels: "+mapStringForGroupLabels+",\n")
+	}
+	s = append(s, "FiringAlerts: "+fmt.Sprintf("%#v", this.FiringAlerts)+",\n")
+	s = append(s, "ResolvedAlerts: "+fmt.Sprintf("%#v", this.ResolvedAlerts)+",\n")
+	s = append(s, "Success: "+fmt.Sprintf("%#v", this.Success)+",\n")
+	s = append(s, "Error: "+fmt.Sprintf("%#v", this.Error)+",\n")
+	s = append(s, "StartedAt: "+fmt.Sprintf("%#v", this.StartedAt)+",\n")
+	s = append(s, "FinishedAt: "+fmt.Sprintf("%#v", this.FinishedAt)+",\n")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *NotificationHistoryDesc) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 5)
+	s = append(s, "&alertspb.NotificationHistoryDesc{")
+	if this.Entries != nil {
+		vs := make([]*NotificationHistoryEntry, len(this.Entries))
+		for i := range vs {
+			vs[i] = &this.Entries[i]
+		}
+		s = append(s, "Entries: "+fmt.Sprintf("%#v", vs)+",\n")
+	}
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
 func valueToGoStringAlerts(v interface{}, typ string) string {
 	rv := reflect.ValueOf(v)
 	if rv.IsNil() {
@@ -441,68 +773,283 @@ func (m *FullStateDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func encodeVarintAlerts(dAtA []byte, offset int, v uint64) int {
-	offset -= sovAlerts(v)
-	base := offset
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *NotificationHistoryEntry) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	dAtA[offset] = uint8(v)
-	return base
+	return dAtA[:n], nil
 }
-func (m *AlertConfigDesc) Size() (n int) {
-	if m == nil {
-		return 0
-	}
+
+func (m *NotificationHistoryEntry) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *NotificationHistoryEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	l = len(m.User)
-	if l > 0 {
-		n += 1 + l + sovAlerts(uint64(l))
+	n2, err2 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.FinishedAt, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.FinishedAt):])
+	if err2 != nil {
+		return 0, err2
 	}
-	l = len(m.RawConfig)
-	if l > 0 {
-		n += 1 + l + sovAlerts(uint64(l))
+	i -= n2
+	i = encodeVarintAlerts(dAtA, i, uint64(n2))
+	i--
+	dAtA[i] = 0x5a
+	n3, err3 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.StartedAt, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.StartedAt):])
+	if err3 != nil {
+		return 0, err3
 	}
-	if len(m.Templates) > 0 {
-		for _, e := range m.Templates {
-			l = e.Size()
-			n += 1 + l + sovAlerts(uint64(l))
+	i -= n3
+	i = encodeVarintAlerts(dAtA, i, uint64(n3))
+	i--
+	dAtA[i] = 0x52
+	if len(m.Error) > 0 {
+		i -= len(m.Error)
+		copy(dAtA[i:], m.Error)
+		i = encodeVarintAlerts(dAtA, i, uint64(len(m.Error)))
+		i--
+		dAtA[i] = 0x4a
+	}
+	if m.Success {
+		i--
+		if m.Success {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x40
 	}
-	return n
-}
-
-func (m *TemplateDesc) Size() (n int) {
-	if m == nil {
-		return 0
+	if m.ResolvedAlerts != 0 {
+		i = encodeVarintAlerts(dAtA, i, uint64(m.ResolvedAlerts))
+		i--
+		dAtA[i] = 0x38
 	}
-	var l int
-	_ = l
-	l = len(m.Filename)
-	if l > 0 {
-		n += 1 + l + sovAlerts(uint64(l))
+	if m.FiringAlerts != 0 {
+		i = encodeVarintAlerts(dAtA, i, uint64(m.FiringAlerts))
+		i--
+		dAtA[i] = 0x30
 	}
-	l = len(m.Body)
-	if l > 0 {
-		n += 1 + l + sovAlerts(uint64(l))
+	if len(m.GroupLabels) > 0 {
+		for k := range m.GroupLabels {
+			v := m.GroupLabels[k]
+			baseI := i
+			i -= len(v)
+			copy(dAtA[i:], v)
+			i = encodeVarintAlerts(dAtA, i, uint64(len(v)))
+			i--
+			dAtA[i] = 0x12
+			i -= len(k)
+			copy(dAtA[i:], k)
+			i = encodeVarintAlerts(dAtA, i, uint64(len(k)))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintAlerts(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x2a
+		}
 	}
-	return n
-}
-
-func (m *FullStateDesc) Size() (n int) {
-	if m == nil {
-		return 0
+	if len(m.GroupKey) > 0 {
+		i -= len(m.GroupKey)
+		copy(dAtA[i:], m.GroupKey)
+		i = encodeVarintAlerts(dAtA, i, uint64(len(m.GroupKey)))
+		i--
+		dAtA[i] = 0x22
 	}
-	var l int
-	_ = l
-	if m.State != nil {
-		l = m.State.Size()
-		n += 1 + l + sovAlerts(uint64(l))
+	if len(m.Integration) > 0 {
+		i -= len(m.Integration)
+		copy(dAtA[i:], m.Integration)
+		i = encodeVarintAlerts(dAtA, i, uint64(len(m.Integration)))
+		i--
+		dAtA[i] = 0x1a
 	}
-	return n
+	if len(m.Receiver) > 0 {
+		i -= len(m.Receiver)
+		copy(dAtA[i:], m.Receiver)
+		i = encodeVarintAlerts(dAtA, i, uint64(len(m.Receiver)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.ID) > 0 {
+		i -= len(m.ID)
+		copy(dAtA[i:], m.ID)
+		i = encodeVarintAlerts(dAtA, i, uint64(len(m.ID)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *NotificationHistoryDesc) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *NotificationHistoryDesc) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *NotificationHistoryDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Entries) > 0 {
+		for iNdEx := len(m.Entries) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Entries[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintAlerts(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintAlerts(dAtA []byte, offset int, v uint64) int {
+	offset -= sovAlerts(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *AlertConfigDesc) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.User)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	l = len(m.RawConfig)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	if len(m.Templates) > 0 {
+		for _, e := range m.Templates {
+			l = e.Size()
+			n += 1 + l + sovAlerts(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *TemplateDesc) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Filename)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	l = len(m.Body)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	return n
+}
+
+func (m *FullStateDesc) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.State != nil {
+		l = m.State.Size()
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	return n
+}
+
+func (m *NotificationHistoryEntry) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.ID)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	l = len(m.Receiver)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	l = len(m.Integration)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	l = len(m.GroupKey)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	if len(m.GroupLabels) > 0 {
+		for k, v := range m.GroupLabels {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovAlerts(uint64(len(k))) + 1 + len(v) + sovAlerts(uint64(len(v)))
+			n += mapEntrySize + 1 + sovAlerts(uint64(mapEntrySize))
+		}
+	}
+	if m.FiringAlerts != 0 {
+		n += 1 + sovAlerts(uint64(m.FiringAlerts))
+	}
+	if m.ResolvedAlerts != 0 {
+		n += 1 + sovAlerts(uint64(m.ResolvedAlerts))
+	}
+	if m.Success {
+		n += 2
+	}
+	l = len(m.Error)
+	if l > 0 {
+		n += 1 + l + sovAlerts(uint64(l))
+	}
+	l = github_com_gogo_protobuf_types.SizeOfStdTime(m.StartedAt)
+	n += 1 + l + sovAlerts(uint64(l))
+	l = github_com_gogo_protobuf_types.SizeOfStdTime(m.FinishedAt)
+	n += 1 + l + sovAlerts(uint64(l))
+	return n
+}
+
+func (m *NotificationHistoryDesc) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Entries) > 0 {
+		for _, e := range m.Entries {
+			l = e.Size()
+			n += 1 + l + sovAlerts(uint64(l))
+		}
+	}
+	return n
 }
 
 func sovAlerts(x uint64) (n int) {
@@ -543,21 +1090,423 @@ func (this *FullStateDesc) String() string {
 	if this == nil {
 		return "nil"
 	}
-	s := strings.Join([]string{`&FullStateDesc{`,
-		`State:` + strings.Replace(fmt.Sprintf("%v", this.State), "FullState", "clusterpb.FullState", 1) + `,`,
-		`}`,
-	}, "")
-	return s
-}
-func valueToStringAlerts(v interface{}) string {
-	rv := reflect.ValueOf(v)
-	if rv.IsNil() {
-		return "nil"
+	s := strings.Join([]string{`&FullStateDesc{`,
+		`State:` + strings.Replace(fmt.Sprintf("%v", this.State), "FullState", "clusterpb.FullState", 1) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *NotificationHistoryEntry) String() string {
+	if this == nil {
+		return "nil"
+	}
+	keysForGroupLabels := make([]string, 0, len(this.GroupLabels))
+	for k, _ := range this.GroupLabels {
+		keysForGroupLabels = append(keysForGroupLabels, k)
+	}
+	github_com_gogo_protobuf_sortkeys.Strings(keysForGroupLabels)
+	mapStringForGroupLabels := "map[string]string{"
+	for _, k := range keysForGroupLabels {
+		mapStringForGroupLabels += fmt.Sprintf("%v: %v,", k, this.GroupLabels[k])
+	}
+	mapStringForGroupLabels += "}"
+	s := strings.Join([]string{`&NotificationHistoryEntry{`,
+		`ID:` + fmt.Sprintf("%v", this.ID) + `,`,
+		`Receiver:` + fmt.Sprintf("%v", this.Receiver) + `,`,
+		`Integration:` + fmt.Sprintf("%v", this.Integration) + `,`,
+		`GroupKey:` + fmt.Sprintf("%v", this.GroupKey) + `,`,
+		`GroupLabels:` + mapStringForGroupLabels + `,`,
+		`FiringAlerts:` + fmt.Sprintf("%v", this.FiringAlerts) + `,`,
+		`ResolvedAlerts:` + fmt.Sprintf("%v", this.ResolvedAlerts) + `,`,
+		`Success:` + fmt.Sprintf("%v", this.Success) + `,`,
+		`Error:` + fmt.Sprintf("%v", this.Error) + `,`,
+		`StartedAt:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.StartedAt), "Timestamp", "timestamp.Timestamp", 1), `&`, ``, 1) + `,`,
+		`FinishedAt:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.FinishedAt), "Timestamp", "timestamp.Timestamp", 1), `&`, ``, 1) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *NotificationHistoryDesc) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForEntries := "[]NotificationHistoryEntry{"
+	for _, f := range this.Entries {
+		repeatedStringForEntries += strings.Replace(strings.Replace(f.String(), "NotificationHistoryEntry", "NotificationHistoryEntry", 1), `&`, ``, 1) + ","
+	}
+	repeatedStringForEntries += "}"
+	s := strings.Join([]string{`&NotificationHistoryDesc{`,
+		`Entries:` + repeatedStringForEntries + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func valueToStringAlerts(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.IsNil() {
+		return "nil"
+	}
+	pv := reflect.Indirect(rv).Interface()
+	return fmt.Sprintf("*%v", pv)
+}
+func (m *AlertConfigDesc) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAlerts
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AlertConfigDesc: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AlertConfigDesc: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field User", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.User = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RawConfig", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.RawConfig = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Templates", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Templates = append(m.Templates, &TemplateDesc{})
+			if err := m.Templates[len(m.Templates)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAlerts(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TemplateDesc) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAlerts
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TemplateDesc: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TemplateDesc: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Filename", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Filename = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Body", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Body = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAlerts(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *FullStateDesc) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowAlerts
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FullStateDesc: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FullStateDesc: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field State", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.State == nil {
+				m.State = &clusterpb.FullState{}
+			}
+			if err := m.State.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipAlerts(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
 	}
-	pv := reflect.Indirect(rv).Interface()
-	return fmt.Sprintf("*%v", pv)
+	return nil
 }
-func (m *AlertConfigDesc) Unmarshal(dAtA []byte) error {
+func (m *NotificationHistoryEntry) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -580,15 +1529,15 @@ func (m *AlertConfigDesc) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: AlertConfigDesc: wiretype end group for non-group")
+			return fmt.Errorf("proto: NotificationHistoryEntry: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: AlertConfigDesc: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: NotificationHistoryEntry: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field User", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -616,11 +1565,11 @@ func (m *AlertConfigDesc) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.User = string(dAtA[iNdEx:postIndex])
+			m.ID = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field RawConfig", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Receiver", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -648,13 +1597,13 @@ func (m *AlertConfigDesc) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.RawConfig = string(dAtA[iNdEx:postIndex])
+			m.Receiver = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Templates", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Integration", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowAlerts
@@ -664,82 +1613,244 @@ func (m *AlertConfigDesc) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthAlerts
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthAlerts
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Templates = append(m.Templates, &TemplateDesc{})
-			if err := m.Templates[len(m.Templates)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Integration = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipAlerts(dAtA[iNdEx:])
-			if err != nil {
-				return err
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupKey", wireType)
 			}
-			if skippy < 0 {
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthAlerts
 			}
-			if (iNdEx + skippy) < 0 {
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthAlerts
 			}
-			if (iNdEx + skippy) > l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *TemplateDesc) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowAlerts
+			m.GroupKey = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupLabels", wireType)
 			}
-			if iNdEx >= l {
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+			if m.GroupLabels == nil {
+				m.GroupLabels = make(map[string]string)
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: TemplateDesc: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: TemplateDesc: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowAlerts
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowAlerts
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthAlerts
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthAlerts
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowAlerts
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthAlerts
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthAlerts
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipAlerts(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthAlerts
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.GroupLabels[mapkey] = mapvalue
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FiringAlerts", wireType)
+			}
+			m.FiringAlerts = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FiringAlerts |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResolvedAlerts", wireType)
+			}
+			m.ResolvedAlerts = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ResolvedAlerts |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Success", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Success = bool(v != 0)
+		case 9:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Filename", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Error", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -767,13 +1878,13 @@ func (m *TemplateDesc) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Filename = string(dAtA[iNdEx:postIndex])
+			m.Error = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 2:
+		case 10:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Body", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field StartedAt", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowAlerts
@@ -783,23 +1894,57 @@ func (m *TemplateDesc) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthAlerts
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthAlerts
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Body = string(dAtA[iNdEx:postIndex])
+			if err := github_com_gogo_protobuf_types.StdTimeUnmarshal(&m.StartedAt, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FinishedAt", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAlerts
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthAlerts
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := github_com_gogo_protobuf_types.StdTimeUnmarshal(&m.FinishedAt, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -825,7 +1970,7 @@ func (m *TemplateDesc) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *FullStateDesc) Unmarshal(dAtA []byte) error {
+func (m *NotificationHistoryDesc) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -848,15 +1993,15 @@ func (m *FullStateDesc) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: FullStateDesc: wiretype end group for non-group")
+			return fmt.Errorf("proto: NotificationHistoryDesc: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: FullStateDesc: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: NotificationHistoryDesc: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field State", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Entries", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -883,10 +2028,8 @@ func (m *FullStateDesc) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.State == nil {
-				m.State = &clusterpb.FullState{}
-			}
-			if err := m.State.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Entries = append(m.Entries, NotificationHistoryEntry{})
+			if err := m.Entries[len(m.Entries)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
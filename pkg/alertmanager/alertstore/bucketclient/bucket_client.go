@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"io/ioutil"
+	"path"
+	"sort"
 	"strings"
 	"sync"
 
@@ -32,10 +34,17 @@ const (
 	// The name of alertmanager full state objects (notification log + silences).
 	fullStateName = "fullstate"
 
+	// The prefix under which alertmanager full state snapshots are stored.
+	// Note that objects stored under this prefix follow the pattern:
+	//     alertmanager/<user-id>/snapshots/<snapshot-id>
+	fullStateSnapshotsPrefix = "snapshots"
+
 	// How many users to load concurrently.
 	fetchConcurrency = 16
 )
 
+var errInvalidStateSnapshotID = errors.New("invalid state snapshot ID")
+
 // BucketAlertStore is used to support the AlertStore interface against an object storage backend. It is implemented
 // using the Thanos objstore.Bucket interface
 type BucketAlertStore struct {
@@ -172,6 +181,83 @@ func (s *BucketAlertStore) DeleteFullState(ctx context.Context, userID string) e
 	return err
 }
 
+// ListFullStateSnapshots implements alertstore.AlertStore.
+func (s *BucketAlertStore) ListFullStateSnapshots(ctx context.Context, userID string) ([]string, error) {
+	var snapshotIDs []string
+
+	err := s.getAlertmanagerUserBucket(userID).Iter(ctx, fullStateSnapshotsPrefix+"/", func(key string) error {
+		snapshotIDs = append(snapshotIDs, path.Base(key))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(snapshotIDs)
+	return snapshotIDs, nil
+}
+
+// GetFullStateSnapshot implements alertstore.AlertStore.
+func (s *BucketAlertStore) GetFullStateSnapshot(ctx context.Context, userID, snapshotID string) (alertspb.FullStateDesc, error) {
+	bkt := s.getAlertmanagerUserBucket(userID)
+	fs := alertspb.FullStateDesc{}
+
+	key, err := getFullStateSnapshotKey(snapshotID)
+	if err != nil {
+		return fs, err
+	}
+
+	err = s.get(ctx, bkt, key, &fs)
+	if s.amBucket.IsObjNotFoundErr(err) {
+		return fs, alertspb.ErrNotFound
+	}
+
+	return fs, err
+}
+
+// SetFullStateSnapshot implements alertstore.AlertStore.
+func (s *BucketAlertStore) SetFullStateSnapshot(ctx context.Context, userID, snapshotID string, fs alertspb.FullStateDesc) error {
+	bkt := s.getAlertmanagerUserBucket(userID)
+
+	key, err := getFullStateSnapshotKey(snapshotID)
+	if err != nil {
+		return err
+	}
+
+	fsBytes, err := fs.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return bkt.Upload(ctx, key, bytes.NewBuffer(fsBytes))
+}
+
+// DeleteFullStateSnapshot implements alertstore.AlertStore.
+func (s *BucketAlertStore) DeleteFullStateSnapshot(ctx context.Context, userID, snapshotID string) error {
+	userBkt := s.getAlertmanagerUserBucket(userID)
+
+	key, err := getFullStateSnapshotKey(snapshotID)
+	if err != nil {
+		return err
+	}
+
+	err = userBkt.Delete(ctx, key)
+	if userBkt.IsObjNotFoundErr(err) {
+		return nil
+	}
+	return err
+}
+
+// getFullStateSnapshotKey returns the object key of a state snapshot, making sure
+// the snapshot ID can't reference objects outside of the snapshots prefix.
+func getFullStateSnapshotKey(snapshotID string) (string, error) {
+	if snapshotID == "" || snapshotID == "." || snapshotID == ".." || strings.ContainsAny(snapshotID, "/\\") {
+		return "", errors.Wrapf(errInvalidStateSnapshotID, "snapshot: %q", snapshotID)
+	}
+
+	return path.Join(fullStateSnapshotsPrefix, snapshotID), nil
+}
+
 func (s *BucketAlertStore) getAlertConfig(ctx context.Context, userID string) (alertspb.AlertConfigDesc, error) {
 	config := alertspb.AlertConfigDesc{}
 	err := s.get(ctx, s.getUserBucket(userID), userID, &config)
@@ -113,6 +113,26 @@ func (c *Store) DeleteFullState(ctx context.Context, user string) error {
 	return errState
 }
 
+// ListFullStateSnapshots implements alertstore.AlertStore.
+func (c *Store) ListFullStateSnapshots(ctx context.Context, user string) ([]string, error) {
+	return nil, errState
+}
+
+// GetFullStateSnapshot implements alertstore.AlertStore.
+func (c *Store) GetFullStateSnapshot(ctx context.Context, user, snapshotID string) (alertspb.FullStateDesc, error) {
+	return alertspb.FullStateDesc{}, errState
+}
+
+// SetFullStateSnapshot implements alertstore.AlertStore.
+func (c *Store) SetFullStateSnapshot(ctx context.Context, user, snapshotID string, fs alertspb.FullStateDesc) error {
+	return errState
+}
+
+// DeleteFullStateSnapshot implements alertstore.AlertStore.
+func (c *Store) DeleteFullStateSnapshot(ctx context.Context, user, snapshotID string) error {
+	return errState
+}
+
 func (c *Store) reloadConfigs(ctx context.Context) (map[string]alertspb.AlertConfigDesc, error) {
 	configs, err := c.configClient.GetAlerts(ctx, c.since)
 	if err != nil {
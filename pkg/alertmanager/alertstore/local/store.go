@@ -121,6 +121,26 @@ func (f *Store) DeleteFullState(ctx context.Context, user string) error {
 	return errState
 }
 
+// ListFullStateSnapshots implements alertstore.AlertStore.
+func (f *Store) ListFullStateSnapshots(ctx context.Context, user string) ([]string, error) {
+	return nil, errState
+}
+
+// GetFullStateSnapshot implements alertstore.AlertStore.
+func (f *Store) GetFullStateSnapshot(ctx context.Context, user, snapshotID string) (alertspb.FullStateDesc, error) {
+	return alertspb.FullStateDesc{}, errState
+}
+
+// SetFullStateSnapshot implements alertstore.AlertStore.
+func (f *Store) SetFullStateSnapshot(ctx context.Context, user, snapshotID string, fs alertspb.FullStateDesc) error {
+	return errState
+}
+
+// DeleteFullStateSnapshot implements alertstore.AlertStore.
+func (f *Store) DeleteFullStateSnapshot(ctx context.Context, user, snapshotID string) error {
+	return errState
+}
+
 func (f *Store) reloadConfigs() (map[string]alertspb.AlertConfigDesc, error) {
 	configs := map[string]alertspb.AlertConfigDesc{}
 	err := filepath.Walk(f.cfg.Path, func(path string, info os.FileInfo, err error) error {
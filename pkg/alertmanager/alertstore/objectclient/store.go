@@ -161,3 +161,23 @@ func (a *AlertStore) SetFullState(ctx context.Context, user string, cfg alertspb
 func (a *AlertStore) DeleteFullState(ctx context.Context, user string) error {
 	return errState
 }
+
+// ListFullStateSnapshots implements alertstore.AlertStore.
+func (a *AlertStore) ListFullStateSnapshots(ctx context.Context, user string) ([]string, error) {
+	return nil, errState
+}
+
+// GetFullStateSnapshot implements alertstore.AlertStore.
+func (a *AlertStore) GetFullStateSnapshot(ctx context.Context, user, snapshotID string) (alertspb.FullStateDesc, error) {
+	return alertspb.FullStateDesc{}, errState
+}
+
+// SetFullStateSnapshot implements alertstore.AlertStore.
+func (a *AlertStore) SetFullStateSnapshot(ctx context.Context, user, snapshotID string, fs alertspb.FullStateDesc) error {
+	return errState
+}
+
+// DeleteFullStateSnapshot implements alertstore.AlertStore.
+func (a *AlertStore) DeleteFullStateSnapshot(ctx context.Context, user, snapshotID string) error {
+	return errState
+}
@@ -52,6 +52,20 @@ type AlertStore interface {
 	// DeleteFullState deletes the alertmanager state for an user.
 	// If state for the user doesn't exist, no error is reported.
 	DeleteFullState(ctx context.Context, user string) error
+
+	// ListFullStateSnapshots returns the IDs of the alertmanager state snapshots for the given user,
+	// sorted in ascending order.
+	ListFullStateSnapshots(ctx context.Context, user string) ([]string, error)
+
+	// GetFullStateSnapshot loads and returns an alertmanager state snapshot for the given user.
+	GetFullStateSnapshot(ctx context.Context, user, snapshotID string) (alertspb.FullStateDesc, error)
+
+	// SetFullStateSnapshot stores an alertmanager state snapshot for the given user.
+	SetFullStateSnapshot(ctx context.Context, user, snapshotID string, fs alertspb.FullStateDesc) error
+
+	// DeleteFullStateSnapshot deletes an alertmanager state snapshot for an user.
+	// If the snapshot doesn't exist, no error is reported.
+	DeleteFullStateSnapshot(ctx context.Context, user, snapshotID string) error
 }
 
 // NewLegacyAlertStore returns a new alertmanager storage backend poller and store
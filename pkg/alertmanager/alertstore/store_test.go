@@ -274,3 +274,83 @@ func TestBucketAlertStore_GetSetDeleteFullState(t *testing.T) {
 		require.NoError(t, store.DeleteFullState(ctx, "user-1"))
 	}
 }
+
+func TestBucketAlertStore_GetSetDeleteFullStateSnapshots(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	store := bucketclient.NewBucketAlertStore(bucket, nil, log.NewNopLogger())
+	ctx := context.Background()
+
+	state1 := makeTestFullState("one")
+	state2 := makeTestFullState("two")
+
+	// The storage is empty.
+	{
+		_, err := store.GetFullStateSnapshot(ctx, "user-1", "20210101T000000Z")
+		assert.Equal(t, alertspb.ErrNotFound, err)
+
+		snapshots, err := store.ListFullStateSnapshots(ctx, "user-1")
+		assert.NoError(t, err)
+		assert.Empty(t, snapshots)
+	}
+
+	// The storage contains snapshots.
+	{
+		require.NoError(t, store.SetFullStateSnapshot(ctx, "user-1", "20210102T000000Z", state2))
+		require.NoError(t, store.SetFullStateSnapshot(ctx, "user-1", "20210101T000000Z", state1))
+		require.NoError(t, store.SetFullState(ctx, "user-1", state2))
+
+		res, err := store.GetFullStateSnapshot(ctx, "user-1", "20210101T000000Z")
+		require.NoError(t, err)
+		assert.Equal(t, state1, res)
+
+		exists, err := bucket.Exists(ctx, "alertmanager/user-1/snapshots/20210102T000000Z")
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		// Snapshots are sorted and don't include the full state.
+		snapshots, err := store.ListFullStateSnapshots(ctx, "user-1")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"20210101T000000Z", "20210102T000000Z"}, snapshots)
+
+		snapshots, err = store.ListFullStateSnapshots(ctx, "user-2")
+		assert.NoError(t, err)
+		assert.Empty(t, snapshots)
+
+		users, err := store.ListUsersWithFullState(ctx)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"user-1"}, users)
+	}
+
+	// The storage has had a snapshot deleted.
+	{
+		require.NoError(t, store.DeleteFullStateSnapshot(ctx, "user-1", "20210101T000000Z"))
+
+		_, err := store.GetFullStateSnapshot(ctx, "user-1", "20210101T000000Z")
+		assert.Equal(t, alertspb.ErrNotFound, err)
+
+		snapshots, err := store.ListFullStateSnapshots(ctx, "user-1")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"20210102T000000Z"}, snapshots)
+
+		// Delete again (should be idempotent).
+		require.NoError(t, store.DeleteFullStateSnapshot(ctx, "user-1", "20210101T000000Z"))
+	}
+
+	// Snapshot IDs can't reference objects outside of the snapshots prefix.
+	{
+		require.NoError(t, store.SetFullState(ctx, "user-2", state2))
+
+		for _, snapshotID := range []string{"", ".", "..", "../fullstate", "../../user-2/fullstate"} {
+			_, err := store.GetFullStateSnapshot(ctx, "user-1", snapshotID)
+			assert.Error(t, err, snapshotID)
+			assert.NotEqual(t, alertspb.ErrNotFound, err, snapshotID)
+
+			assert.Error(t, store.SetFullStateSnapshot(ctx, "user-1", snapshotID, state1), snapshotID)
+			assert.Error(t, store.DeleteFullStateSnapshot(ctx, "user-1", snapshotID), snapshotID)
+		}
+
+		exists, err := bucket.Exists(ctx, "alertmanager/user-2/fullstate")
+		require.NoError(t, err)
+		assert.True(t, exists)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"time"
 
 	"github.com/cortexproject/cortex/pkg/alertmanager/alertspb"
 	"github.com/cortexproject/cortex/pkg/tenant"
@@ -20,6 +21,7 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/pkg/errors"
 	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
 	"github.com/prometheus/alertmanager/template"
 	commoncfg "github.com/prometheus/common/config"
 	"gopkg.in/yaml.v2"
@@ -172,6 +174,181 @@ func (am *MultitenantAlertmanager) DeleteUserConfig(w http.ResponseWriter, r *ht
 	w.WriteHeader(http.StatusOK)
 }
 
+// ValidateUserConfig validates the Alertmanager configuration and templates in the request body
+// against the tenant's limits without storing anything, and reports all the issues found.
+func (am *MultitenantAlertmanager) ValidateUserConfig(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	var input io.Reader
+	maxConfigSize := am.limits.AlertmanagerMaxConfigSize(userID)
+	if maxConfigSize > 0 {
+		// LimitReader will return EOF after reading specified number of bytes. To check if
+		// we have read too many bytes, allow one extra byte.
+		input = io.LimitReader(r.Body, int64(maxConfigSize)+1)
+	} else {
+		input = r.Body
+	}
+
+	payload, err := ioutil.ReadAll(input)
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingConfiguration, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	var errs []ConfigValidationError
+	if maxConfigSize > 0 && len(payload) > maxConfigSize {
+		errs = append(errs, ConfigValidationError{Type: validationErrorLimits, Message: fmt.Sprintf(errConfigurationTooBig, maxConfigSize)})
+	} else {
+		cfg := &UserConfig{}
+		if err := yaml.Unmarshal(payload, cfg); err != nil {
+			errs = append(errs, ConfigValidationError{Type: validationErrorConfig, Message: fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error())})
+		} else {
+			cfgDesc := alertspb.ToProto(cfg.AlertmanagerConfig, cfg.TemplateFiles, userID)
+			errs = dryRunUserConfig(cfgDesc, am.limits, userID, am.cfg.ExternalURL.URL)
+		}
+	}
+
+	resp := ConfigValidationResponse{Valid: len(errs) == 0, Errors: errs}
+	if !resp.Valid {
+		level.Debug(logger).Log("msg", errValidatingConfig, "errors", len(errs))
+		// The content type must be set before writing the status code.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	util.WriteJSONResponse(w, resp)
+}
+
+// TestReceiver sends a synthetic alert to each integration of a receiver, looked up in the stored
+// configuration or in the configuration provided in the request, and reports the per-integration outcome.
+func (am *MultitenantAlertmanager) TestReceiver(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	var input io.Reader
+	maxConfigSize := am.limits.AlertmanagerMaxConfigSize(userID)
+	if maxConfigSize > 0 {
+		// LimitReader will return EOF after reading specified number of bytes. To check if
+		// we have read too many bytes, allow one extra byte.
+		input = io.LimitReader(r.Body, int64(maxConfigSize)+1)
+	} else {
+		input = r.Body
+	}
+
+	payload, err := ioutil.ReadAll(input)
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingConfiguration, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if maxConfigSize > 0 && len(payload) > maxConfigSize {
+		msg := fmt.Sprintf(errConfigurationTooBig, maxConfigSize)
+		level.Warn(logger).Log("msg", msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	req := TestReceiverRequest{}
+	if err := yaml.Unmarshal(payload, &req); err != nil {
+		level.Error(logger).Log("msg", errMarshallingYAML, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if req.Receiver == "" {
+		http.Error(w, "the receiver name is required", http.StatusBadRequest)
+		return
+	}
+
+	am.alertmanagersMtx.Lock()
+	userAM := am.alertmanagers[userID]
+	am.alertmanagersMtx.Unlock()
+
+	var (
+		integrations []notify.Integration
+		cfgDesc      alertspb.AlertConfigDesc
+		found        bool
+	)
+
+	switch {
+	case req.Config.AlertmanagerConfig != "":
+		cfgDesc = alertspb.ToProto(req.Config.AlertmanagerConfig, req.Config.TemplateFiles, userID)
+		if err := validateUserConfig(logger, cfgDesc, am.limits, userID); err != nil {
+			level.Warn(logger).Log("msg", errValidatingConfig, "err", err.Error())
+			http.Error(w, fmt.Sprintf("%s: %s", errValidatingConfig, err.Error()), http.StatusBadRequest)
+			return
+		}
+
+	case userAM != nil:
+		// Use the integrations of the running per-tenant Alertmanager, so that
+		// the test notification is subject to the same rate-limits.
+		integrations, found = userAM.getReceiverIntegrations(req.Receiver)
+		if !found {
+			http.Error(w, fmt.Sprintf(errReceiverNotFound, req.Receiver), http.StatusNotFound)
+			return
+		}
+
+	default:
+		cfgDesc, err = am.store.GetAlertConfig(r.Context(), userID)
+		if errors.Is(err, alertspb.ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			level.Error(logger).Log("msg", errReadingConfiguration, "err", err.Error())
+			http.Error(w, fmt.Sprintf("%s: %s", errReadingConfiguration, err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if cfgDesc.RawConfig == "" {
+			if am.fallbackConfig == "" {
+				http.Error(w, "the Alertmanager is not configured", http.StatusNotFound)
+				return
+			}
+			cfgDesc.RawConfig = am.fallbackConfig
+		}
+	}
+
+	if !found {
+		rateLimited := am.testNotificationsRateLimited
+		if userAM != nil {
+			rateLimited = userAM.rateLimitedNotifications
+		}
+
+		integrations, err = am.buildTestReceiverIntegrations(cfgDesc, req.Receiver, rateLimited)
+		if err != nil {
+			level.Warn(logger).Log("msg", "unable to build receiver integrations", "receiver", req.Receiver, "err", err.Error())
+			http.Error(w, fmt.Sprintf("unable to build receiver integrations: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if integrations == nil {
+			http.Error(w, fmt.Sprintf(errReceiverNotFound, req.Receiver), http.StatusNotFound)
+			return
+		}
+	}
+
+	results := sendTestNotification(r.Context(), req.Receiver, integrations, req.Alert.toAlert(time.Now()))
+	for _, res := range results {
+		level.Info(logger).Log("msg", "sent test notification", "receiver", req.Receiver, "integration", res.Name, "index", res.Index, "success", res.Success, "err", res.Error)
+	}
+
+	util.WriteJSONResponse(w, TestReceiverResponse{
+		Receiver:     req.Receiver,
+		Integrations: results,
+	})
+}
+
 // Partially copied from: https://github.com/prometheus/alertmanager/blob/8e861c646bf67599a1704fc843c6a94d519ce312/cli/check_config.go#L65-L96
 func validateUserConfig(logger log.Logger, cfg alertspb.AlertConfigDesc, limits Limits, user string) error {
 	// We don't have a valid use case for empty configurations. If a tenant does not have a
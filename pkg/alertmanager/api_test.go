@@ -3,6 +3,7 @@ package alertmanager
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -586,6 +587,169 @@ template_files:
 	}
 }
 
+func TestAMConfigDryRunValidationAPI(t *testing.T) {
+	testCases := map[string]struct {
+		cfg                string
+		maxTemplates       int
+		blockCIDRNetworks  string
+		expectedStatusCode int
+		expectedErrors     []ConfigValidationError
+	}{
+		"valid config with templates": {
+			cfg: `
+alertmanager_config: |
+  route:
+    receiver: 'default-receiver'
+  templates:
+    - 'custom.tmpl'
+  receivers:
+    - name: default-receiver
+      webhook_configs:
+        - url: http://127.0.0.1:8080/hook
+      slack_configs:
+        - api_url: http://127.0.0.1:8080/slack
+          title: '{{ template "custom.title" . }}'
+template_files:
+  "custom.tmpl": '{{ define "custom.title" }}{{ .CommonLabels.alertname }} ({{ len .Alerts.Firing }} firing){{ end }}'
+`,
+			expectedStatusCode: http.StatusOK,
+		},
+		"invalid YAML": {
+			cfg:                `alertmanager_config: [`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrors: []ConfigValidationError{
+				{Type: validationErrorConfig, Message: "error marshalling YAML Alertmanager config: yaml: line 1: did not find expected node content"},
+			},
+		},
+		"undefined receiver": {
+			cfg: `
+alertmanager_config: |
+  route:
+    receiver: 'default-receiver'
+`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrors: []ConfigValidationError{
+				{Type: validationErrorConfig, Message: `undefined receiver "default-receiver" used in route`},
+			},
+		},
+		"template which fails to parse": {
+			cfg: `
+alertmanager_config: |
+  route:
+    receiver: 'default-receiver'
+  templates:
+    - '*.tmpl'
+  receivers:
+    - name: default-receiver
+template_files:
+  "good.tmpl": '{{ define "good" }}good{{ end }}'
+  "bad.tmpl": '{{ define "bad" }}{{ .Alerts{{ end }}'
+`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrors: []ConfigValidationError{
+				{Type: validationErrorTemplate, Name: "bad.tmpl", Message: "template: bad.tmpl:1: bad character U+007B '{'"},
+			},
+		},
+		"template which fails to render and receiver referencing an undefined template": {
+			cfg: `
+alertmanager_config: |
+  route:
+    receiver: 'default-receiver'
+  templates:
+    - 'custom.tmpl'
+  receivers:
+    - name: default-receiver
+      webhook_configs:
+        - url: http://127.0.0.1:8080/hook
+      slack_configs:
+        - api_url: http://127.0.0.1:8080/slack
+          title: '{{ template "missing" . }}'
+template_files:
+  "custom.tmpl": '{{ define "custom.title" }}{{ .NotExisting }}{{ end }}'
+`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrors: []ConfigValidationError{
+				{Type: validationErrorTemplate, Name: "custom.title", Message: `template: custom.tmpl:1:30: executing "custom.title" at <.NotExisting>: can't evaluate field NotExisting in type *template.Data`},
+				{Type: validationErrorReceiver, Name: "default-receiver", Message: `slack_configs[0].title: template: :1:12: executing "" at <{{template "missing" .}}>: template "missing" not defined`},
+			},
+		},
+		"receiver URL blocked by the firewall": {
+			cfg: `
+alertmanager_config: |
+  route:
+    receiver: 'default-receiver'
+  receivers:
+    - name: default-receiver
+      webhook_configs:
+        - url: http://10.0.0.1:8080/hook
+        - url: http://127.0.0.1:8080/hook
+        - url: http://receiver.invalid:8080/hook
+`,
+			blockCIDRNetworks:  "10.0.0.0/8",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrors: []ConfigValidationError{
+				{Type: validationErrorReceiver, Name: "default-receiver", Message: `host "10.0.0.1" is blocked by the receivers firewall`},
+			},
+		},
+		"all limits and filename issues are reported": {
+			cfg: `
+alertmanager_config: |
+  route:
+    receiver: 'default-receiver'
+  receivers:
+    - name: default-receiver
+template_files:
+  "t1.tmpl": "Some template"
+  "not/valid.tmpl": "Some template"
+`,
+			maxTemplates:       1,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrors: []ConfigValidationError{
+				{Type: validationErrorLimits, Message: fmt.Sprintf(errTooManyTemplates, 2, 1)},
+				{Type: validationErrorTemplate, Name: "not/valid.tmpl", Message: `invalid template name "not/valid.tmpl": the template name cannot contain any path`},
+			},
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			limits := &mockAlertManagerLimits{maxTemplatesCount: tc.maxTemplates}
+			if tc.blockCIDRNetworks != "" {
+				require.NoError(t, (*flagext.CIDRSliceCSV)(&limits.blockCIDRNetworks).Set(tc.blockCIDRNetworks))
+			}
+
+			store := prepareInMemoryAlertStore()
+			am := &MultitenantAlertmanager{
+				cfg:    mockAlertmanagerConfig(t),
+				store:  store,
+				logger: util_log.Logger,
+				limits: limits,
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "http://alertmanager/api/v1/alerts/validate", bytes.NewReader([]byte(tc.cfg)))
+			ctx := user.InjectOrgID(req.Context(), "testing")
+			w := httptest.NewRecorder()
+			am.ValidateUserConfig(w, req.WithContext(ctx))
+			resp := w.Result()
+
+			require.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			actual := ConfigValidationResponse{}
+			require.NoError(t, json.Unmarshal(body, &actual))
+			assert.Equal(t, len(tc.expectedErrors) == 0, actual.Valid)
+			assert.ElementsMatch(t, tc.expectedErrors, actual.Errors)
+
+			// Nothing should have been stored.
+			_, err = store.GetAlertConfig(context.Background(), "testing")
+			require.Equal(t, alertspb.ErrNotFound, err)
+		})
+	}
+}
+
 func TestMultitenantAlertmanager_DeleteUserConfig(t *testing.T) {
 	storage := objstore.NewInMemBucket()
 	alertStore := bucketclient.NewBucketAlertStore(storage, nil, log.NewNopLogger())
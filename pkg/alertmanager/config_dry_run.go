@@ -0,0 +1,376 @@
+package alertmanager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	tmpltext "text/template"
+	"time"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	commoncfg "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+
+	"github.com/cortexproject/cortex/pkg/alertmanager/alertspb"
+	util_net "github.com/cortexproject/cortex/pkg/util/net"
+)
+
+// Types of the errors reported by the configuration dry-run validation.
+const (
+	validationErrorConfig   = "config"
+	validationErrorLimits   = "limits"
+	validationErrorTemplate = "template"
+	validationErrorReceiver = "receiver"
+)
+
+// ConfigValidationError is a single issue found while validating an Alertmanager configuration.
+type ConfigValidationError struct {
+	// Type is the kind of issue found: config, limits, template or receiver.
+	Type string `json:"type"`
+	// Name is the name of the template or receiver the issue refers to, if any.
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+}
+
+// ConfigValidationResponse is the response returned by the configuration dry-run validation.
+type ConfigValidationResponse struct {
+	Valid  bool                    `json:"valid"`
+	Errors []ConfigValidationError `json:"errors,omitempty"`
+}
+
+// dryRunUserConfig validates the input configuration as it would be applied by the Alertmanager:
+// the config and templates are parsed with the tenant's limits, each template is rendered against
+// a sample set of alerts and each receiver IP address is checked against the tenant's receivers firewall.
+// Unlike validateUserConfig(), it doesn't stop at the first error but returns all the issues found.
+func dryRunUserConfig(cfg alertspb.AlertConfigDesc, limits Limits, user string, externalURL *url.URL) []ConfigValidationError {
+	var errs []ConfigValidationError
+	addErr := func(typ, name, msg string) {
+		errs = append(errs, ConfigValidationError{Type: typ, Name: name, Message: msg})
+	}
+
+	if cfg.RawConfig == "" {
+		addErr(validationErrorConfig, "", "configuration provided is empty, if you'd like to remove your configuration please use the delete configuration endpoint")
+		return errs
+	}
+
+	amCfg, err := config.Load(cfg.RawConfig)
+	if err != nil {
+		addErr(validationErrorConfig, "", err.Error())
+		return errs
+	}
+
+	if err := validateAlertmanagerConfig(amCfg); err != nil {
+		addErr(validationErrorConfig, "", err.Error())
+	}
+
+	for _, name := range amCfg.Templates {
+		if err := validateTemplateFilename(name); err != nil {
+			addErr(validationErrorConfig, name, err.Error())
+		}
+	}
+
+	if l := limits.AlertmanagerMaxTemplatesCount(user); l > 0 && len(cfg.Templates) > l {
+		addErr(validationErrorLimits, "", fmt.Sprintf(errTooManyTemplates, len(cfg.Templates), l))
+	}
+
+	maxSize := limits.AlertmanagerMaxTemplateSize(user)
+	for _, tmpl := range cfg.Templates {
+		if maxSize > 0 && len(tmpl.Body) > maxSize {
+			addErr(validationErrorLimits, tmpl.Filename, fmt.Sprintf(errTemplateTooBig, tmpl.Filename, len(tmpl.Body), maxSize))
+		}
+		if err := validateTemplateFilename(tmpl.Filename); err != nil {
+			addErr(validationErrorTemplate, tmpl.Filename, err.Error())
+		}
+	}
+
+	// Do not go further if the configuration can't be safely applied.
+	if len(errs) > 0 {
+		return errs
+	}
+
+	tmpl, templateNames, tmplErrs := dryRunTemplates(cfg, amCfg)
+	errs = append(errs, tmplErrs...)
+	if tmpl == nil {
+		return errs
+	}
+
+	if externalURL != nil {
+		tmpl.ExternalURL = externalURL
+	} else {
+		tmpl.ExternalURL = &url.URL{}
+	}
+
+	// Render each template defined by the tenant against a sample set of alerts.
+	data := tmpl.Data("dry-run", model.LabelSet{model.AlertNameLabel: "DryRunAlert"}, sampleAlerts(time.Now())...)
+	for _, name := range templateNames {
+		if _, err := tmpl.ExecuteTextString(fmt.Sprintf("{{ template %q . }}", name), data); err != nil {
+			addErr(validationErrorTemplate, name, err.Error())
+		}
+	}
+
+	firewall := util_net.NewFirewallDialer(newFirewallDialerConfigProvider(user, limits))
+	for _, rcv := range amCfg.Receivers {
+		data := tmpl.Data(rcv.Name, model.LabelSet{model.AlertNameLabel: "DryRunAlert"}, sampleAlerts(time.Now())...)
+
+		forEachTemplatedString(reflect.ValueOf(rcv), "", func(path, text string) {
+			if _, err := tmpl.ExecuteTextString(text, data); err != nil {
+				addErr(validationErrorReceiver, rcv.Name, fmt.Sprintf("%s: %s", path, err.Error()))
+			}
+		})
+
+		for _, u := range receiverURLs(rcv) {
+			if err := checkReceiverURL(firewall, u); err != nil {
+				addErr(validationErrorReceiver, rcv.Name, err.Error())
+			}
+		}
+	}
+
+	return errs
+}
+
+// dryRunTemplates parses the templates referenced by the configuration and returns the resulting
+// template, together with the names of the templates defined by the tenant. The returned template
+// is nil if the templates can't be parsed.
+func dryRunTemplates(cfg alertspb.AlertConfigDesc, amCfg *config.Config) (*template.Template, []string, []ConfigValidationError) {
+	var errs []ConfigValidationError
+
+	userTempDir, err := ioutil.TempDir("", "dry-run-config-"+cfg.User)
+	if err != nil {
+		return nil, nil, []ConfigValidationError{{Type: validationErrorTemplate, Message: err.Error()}}
+	}
+	defer os.RemoveAll(userTempDir)
+
+	bodies := make(map[string]string, len(cfg.Templates))
+	for _, tmpl := range cfg.Templates {
+		templateFilepath, err := safeTemplateFilepath(userTempDir, tmpl.Filename)
+		if err != nil {
+			errs = append(errs, ConfigValidationError{Type: validationErrorTemplate, Name: tmpl.Filename, Message: err.Error()})
+			continue
+		}
+
+		if _, err = storeTemplateFile(templateFilepath, tmpl.Body); err != nil {
+			errs = append(errs, ConfigValidationError{Type: validationErrorTemplate, Name: tmpl.Filename, Message: fmt.Sprintf("unable to store template file '%s'", tmpl.Filename)})
+			continue
+		}
+		bodies[templateFilepath] = tmpl.Body
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+
+	templateFiles := make([]string, len(amCfg.Templates))
+	for i, t := range amCfg.Templates {
+		templateFiles[i] = filepath.Join(userTempDir, t)
+	}
+
+	// Parse each referenced file on its own first, so that errors can be reported per file.
+	names := map[string]struct{}{}
+	for _, glob := range templateFiles {
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			errs = append(errs, ConfigValidationError{Type: validationErrorTemplate, Name: filepath.Base(glob), Message: err.Error()})
+			continue
+		}
+
+		for _, match := range matches {
+			filename := filepath.Base(match)
+			parsed, err := tmpltext.New(filename).Funcs(tmpltext.FuncMap(template.DefaultFuncs)).Parse(bodies[match])
+			if err != nil {
+				errs = append(errs, ConfigValidationError{Type: validationErrorTemplate, Name: filename, Message: err.Error()})
+				continue
+			}
+
+			for _, t := range parsed.Templates() {
+				if t.Name() != filename {
+					names[t.Name()] = struct{}{}
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+
+	tmpl, err := template.FromGlobs(templateFiles...)
+	if err != nil {
+		return nil, nil, []ConfigValidationError{{Type: validationErrorTemplate, Message: err.Error()}}
+	}
+
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	return tmpl, sortedNames, nil
+}
+
+// sampleAlerts returns the set of alerts used to render templates during the dry-run validation.
+func sampleAlerts(now time.Time) []*types.Alert {
+	return []*types.Alert{
+		{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					model.AlertNameLabel: "DryRunAlert",
+					"severity":           "critical",
+					"instance":           "instance-1",
+				},
+				Annotations: model.LabelSet{
+					"summary":     "Sample firing alert",
+					"description": "This alert has been generated to validate the Alertmanager configuration.",
+				},
+				StartsAt:     now.Add(-5 * time.Minute),
+				EndsAt:       now.Add(time.Hour),
+				GeneratorURL: "http://localhost/graph",
+			},
+			UpdatedAt: now,
+		},
+		{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					model.AlertNameLabel: "DryRunAlert",
+					"severity":           "warning",
+					"instance":           "instance-2",
+				},
+				Annotations: model.LabelSet{
+					"summary":     "Sample resolved alert",
+					"description": "This alert has been generated to validate the Alertmanager configuration.",
+				},
+				StartsAt:     now.Add(-time.Hour),
+				EndsAt:       now.Add(-time.Minute),
+				GeneratorURL: "http://localhost/graph",
+			},
+			UpdatedAt: now,
+		},
+	}
+}
+
+// forEachTemplatedString recursively scans the input value and calls fn for each string
+// field containing a template, passing the YAML path of the field.
+func forEachTemplatedString(v reflect.Value, path string, fn func(path, text string)) {
+	if !v.IsValid() {
+		return
+	}
+
+	// Skip data types which can't contain templates (and may contain secrets).
+	switch v.Type() {
+	case reflect.TypeOf(config.Secret("")), reflect.TypeOf(config.URL{}), reflect.TypeOf(config.SecretURL{}), reflect.TypeOf(commoncfg.HTTPClientConfig{}):
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			forEachTemplatedString(v.Elem(), path, fn)
+		}
+
+	case reflect.String:
+		if strings.Contains(v.String(), "{{") {
+			fn(path, v.String())
+		}
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			forEachTemplatedString(v.Field(i), name, fn)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			forEachTemplatedString(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			forEachTemplatedString(v.MapIndex(key), fmt.Sprintf("%s[%v]", path, key.Interface()), fn)
+		}
+	}
+}
+
+// receiverURLs returns the URLs the receiver integrations send notifications to
+// through the receivers firewall.
+func receiverURLs(rcv *config.Receiver) []*url.URL {
+	var urls []*url.URL
+	add := func(u *url.URL) {
+		if u != nil {
+			urls = append(urls, u)
+		}
+	}
+
+	for _, c := range rcv.WebhookConfigs {
+		if c.URL != nil {
+			add(c.URL.URL)
+		}
+	}
+	for _, c := range rcv.PagerdutyConfigs {
+		if c.URL != nil {
+			add(c.URL.URL)
+		}
+	}
+	for _, c := range rcv.OpsGenieConfigs {
+		if c.APIURL != nil {
+			add(c.APIURL.URL)
+		}
+	}
+	for _, c := range rcv.WechatConfigs {
+		if c.APIURL != nil {
+			add(c.APIURL.URL)
+		}
+	}
+	for _, c := range rcv.SlackConfigs {
+		if c.APIURL != nil {
+			add(c.APIURL.URL)
+		}
+	}
+	for _, c := range rcv.VictorOpsConfigs {
+		if c.APIURL != nil {
+			add(c.APIURL.URL)
+		}
+	}
+	return urls
+}
+
+// checkReceiverURL returns an error if the host of the input URL is an IP address blocked by the
+// receivers firewall. Hostnames are not resolved, because the dry-run validation must not do any
+// network lookup: they're checked by the firewall when the notifications are sent. The error never
+// contains the full URL, given it may contain secrets.
+func checkReceiverURL(firewall *util_net.FirewallDialer, u *url.URL) error {
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("receiver URL has no host")
+	}
+
+	// URLs rewritten by the Alertmanager to the per-tenant monitor are trusted.
+	if u.String() == autoWebhookURL {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil && firewall.IsBlocked(ip) {
+		return fmt.Errorf("host %q is blocked by the receivers firewall", host)
+	}
+
+	return nil
+}
@@ -77,7 +77,8 @@ func (d *Distributor) isQuorumWritePath(p string) bool {
 }
 
 func (d *Distributor) isUnaryWritePath(p string) bool {
-	return strings.HasSuffix(p, "/silences")
+	return strings.HasSuffix(p, "/silences") ||
+		strings.HasSuffix(p, "/silences/import")
 }
 
 func (d *Distributor) isUnaryDeletePath(p string) bool {
@@ -106,6 +107,13 @@ func (d *Distributor) isQuorumReadPath(p string) (bool, merger.Merger) {
 	if strings.HasSuffix(path.Dir(p), "/v2/silence") {
 		return true, merger.V2SilenceID{}
 	}
+	if strings.HasSuffix(p, "/v1/notifications") {
+		return true, merger.V1NotificationHistory{}
+	}
+	if strings.HasSuffix(p, "/v1/silences/export") {
+		// The export has the same format of the v2 API.
+		return true, merger.V2Silences{}
+	}
 	return false, nil
 }
 
@@ -138,6 +138,34 @@ func TestDistributor_DistributeRequest(t *testing.T) {
 			expectedTotalCalls: 3,
 			route:              "/v2/silences",
 			responseBody:       []byte(`[]`),
+		}, {
+			name:               "Read /v1/notifications is sent to 3 AMs",
+			numAM:              5,
+			numHappyAM:         5,
+			replicationFactor:  3,
+			isRead:             true,
+			expStatusCode:      http.StatusOK,
+			expectedTotalCalls: 3,
+			route:              "/notifications",
+			responseBody:       []byte(`{"status":"success","data":[]}`),
+		}, {
+			name:               "Read /v1/silences/export is sent to 3 AMs",
+			numAM:              5,
+			numHappyAM:         5,
+			replicationFactor:  3,
+			isRead:             true,
+			expStatusCode:      http.StatusOK,
+			expectedTotalCalls: 3,
+			route:              "/silences/export",
+			responseBody:       []byte(`[]`),
+		}, {
+			name:               "Write /v1/silences/import is sent to only 1 AM",
+			numAM:              5,
+			numHappyAM:         5,
+			replicationFactor:  3,
+			expStatusCode:      http.StatusOK,
+			expectedTotalCalls: 1,
+			route:              "/silences/import",
 		}, {
 			name:               "Write /silences is sent to only 1 AM",
 			numAM:              5,
@@ -288,6 +316,9 @@ func TestDistributor_IsPathSupported(t *testing.T) {
 		"/alertmanager/api/v1/silence/really":   true,
 		"/alertmanager/api/v1/status":           true,
 		"/alertmanager/api/v1/receivers":        true,
+		"/alertmanager/api/v1/notifications":    true,
+		"/alertmanager/api/v1/silences/export":  true,
+		"/alertmanager/api/v1/silences/import":  true,
 		"/alertmanager/api/v1/other":            false,
 		"/alertmanager/api/v2/alerts":           true,
 		"/alertmanager/api/v2/alerts/groups":    true,
@@ -0,0 +1,86 @@
+package merger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// V1NotificationHistory implements the Merger interface for GET /v1/notifications. It returns the
+// union of the notification history entries over all the responses, deduplicated by ID and ordered
+// from the newest to the oldest, like the notification history of a single Alertmanager. Entries are
+// passed through unmodified, so only the fields needed for merging are decoded.
+type V1NotificationHistory struct{}
+
+func (V1NotificationHistory) MergeResponses(in [][]byte) ([]byte, error) {
+	type bodyType struct {
+		Status string            `json:"status"`
+		Data   []json.RawMessage `json:"data"`
+	}
+
+	entries := make([]json.RawMessage, 0)
+	for _, body := range in {
+		parsed := bodyType{}
+		if err := json.Unmarshal(body, &parsed); err != nil {
+			return nil, err
+		}
+		if parsed.Status != statusSuccess {
+			return nil, fmt.Errorf("unable to merge response of status: %s", parsed.Status)
+		}
+		entries = append(entries, parsed.Data...)
+	}
+
+	merged, err := mergeV1NotificationHistory(entries)
+	if err != nil {
+		return nil, err
+	}
+	body := bodyType{
+		Status: statusSuccess,
+		Data:   merged,
+	}
+
+	return json.Marshal(body)
+}
+
+func mergeV1NotificationHistory(in []json.RawMessage) ([]json.RawMessage, error) {
+	type entryKey struct {
+		ID        string    `json:"id"`
+		StartedAt time.Time `json:"startedAt"`
+	}
+	type entry struct {
+		key entryKey
+		raw json.RawMessage
+	}
+
+	// The same entry is replicated to every Alertmanager holding the tenant, so select an arbitrary one.
+	entries := make(map[string]entry)
+	for _, raw := range in {
+		key := entryKey{}
+		if err := json.Unmarshal(raw, &key); err != nil {
+			return nil, err
+		}
+		if _, ok := entries[key.ID]; !ok {
+			entries[key.ID] = entry{key: key, raw: raw}
+		}
+	}
+
+	sorted := make([]entry, 0, len(entries))
+	for _, e := range entries {
+		sorted = append(sorted, e)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].key.StartedAt.Equal(sorted[j].key.StartedAt) {
+			return sorted[i].key.StartedAt.After(sorted[j].key.StartedAt)
+		}
+		return sorted[i].key.ID > sorted[j].key.ID
+	})
+
+	result := make([]json.RawMessage, 0, len(sorted))
+	for _, e := range sorted {
+		result = append(result, e.raw)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,98 @@
+package merger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestV1NotificationHistory(t *testing.T) {
+	entry1 := `{` +
+		`"id":"01F8MECHZX3TBDSZ7XRADM79XE",` +
+		`"receiver":"team-a",` +
+		`"integration":"webhook",` +
+		`"groupKey":"{}:{alertname=\"HighLatency\"}",` +
+		`"groupLabels":{"alertname":"HighLatency"},` +
+		`"firingAlerts":1,` +
+		`"resolvedAlerts":0,` +
+		`"success":true,` +
+		`"startedAt":"2021-06-21T10:00:00Z",` +
+		`"finishedAt":"2021-06-21T10:00:01Z"` +
+		`}`
+	entry2 := `{` +
+		`"id":"01F8MECHZX3TBDSZ7XRADM79XF",` +
+		`"receiver":"team-a",` +
+		`"integration":"email",` +
+		`"groupKey":"{}:{alertname=\"HighLatency\"}",` +
+		`"groupLabels":{"alertname":"HighLatency"},` +
+		`"firingAlerts":1,` +
+		`"resolvedAlerts":0,` +
+		`"success":false,` +
+		`"error":"failed to notify due to rate limits",` +
+		`"startedAt":"2021-06-21T10:00:00Z",` +
+		`"finishedAt":"2021-06-21T10:00:00Z"` +
+		`}`
+	entry3 := `{` +
+		`"id":"01F8MF0000000000000000000A",` +
+		`"receiver":"team-b",` +
+		`"integration":"webhook",` +
+		`"groupKey":"{}:{alertname=\"DiskFull\"}",` +
+		`"groupLabels":{"alertname":"DiskFull"},` +
+		`"firingAlerts":0,` +
+		`"resolvedAlerts":2,` +
+		`"success":true,` +
+		`"startedAt":"2021-06-21T11:00:00Z",` +
+		`"finishedAt":"2021-06-21T11:00:01Z"` +
+		`}`
+
+	tests := map[string]struct {
+		in          [][]byte
+		expected    []byte
+		expectedErr string
+	}{
+		"no responses": {
+			in:       [][]byte{},
+			expected: []byte(`{"status":"success","data":[]}`),
+		},
+		"empty responses": {
+			in: [][]byte{
+				[]byte(`{"status":"success","data":[]}`),
+				[]byte(`{"status":"success","data":[]}`),
+			},
+			expected: []byte(`{"status":"success","data":[]}`),
+		},
+		"entries replicated to all responses are deduplicated": {
+			in: [][]byte{
+				[]byte(`{"status":"success","data":[` + entry1 + `]}`),
+				[]byte(`{"status":"success","data":[` + entry1 + `]}`),
+			},
+			expected: []byte(`{"status":"success","data":[` + entry1 + `]}`),
+		},
+		"entries are merged and sorted from the newest to the oldest": {
+			in: [][]byte{
+				[]byte(`{"status":"success","data":[` + entry2 + `,` + entry1 + `]}`),
+				[]byte(`{"status":"success","data":[` + entry3 + `,` + entry1 + `]}`),
+			},
+			expected: []byte(`{"status":"success","data":[` + entry3 + `,` + entry2 + `,` + entry1 + `]}`),
+		},
+		"error status": {
+			in: [][]byte{
+				[]byte(`{"status":"success","data":[]}`),
+				[]byte(`{"status":"error","data":[]}`),
+			},
+			expectedErr: "unable to merge response of status: error",
+		},
+	}
+
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			out, err := V1NotificationHistory{}.MergeResponses(c.in)
+			if c.expectedErr != "" {
+				require.EqualError(t, err, c.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, string(c.expected), string(out))
+		})
+	}
+}
@@ -230,6 +230,17 @@ type Limits interface {
 	// AlertmanagerMaxAlertsSizeBytes returns total max size of alerts that tenant can have active at the same time. 0 = no limit.
 	// Size of the alert is computed from alert labels, annotations and generator URL.
 	AlertmanagerMaxAlertsSizeBytes(tenant string) int
+
+	// AlertmanagerNotificationHistoryMaxEntries returns max number of notification attempts kept in the tenant's
+	// notification history. 0 = notification history disabled.
+	AlertmanagerNotificationHistoryMaxEntries(tenant string) int
+
+	// AlertmanagerMaxSilencesCount returns max number of active and pending silences that tenant can have. 0 = no limit.
+	AlertmanagerMaxSilencesCount(tenant string) int
+
+	// AlertmanagerMaxSilenceSizeBytes returns max size of a single silence. 0 = no limit.
+	// Size of the silence is computed from silence matchers, comment and author.
+	AlertmanagerMaxSilenceSizeBytes(tenant string) int
 }
 
 // A MultitenantAlertmanager manages Alertmanager instances for multiple
@@ -282,6 +293,14 @@ type MultitenantAlertmanager struct {
 	tenantsDiscovered prometheus.Gauge
 	syncTotal         *prometheus.CounterVec
 	syncFailures      *prometheus.CounterVec
+
+	// Rate-limited test notifications of tenants without an Alertmanager running in this instance.
+	testNotificationsRateLimited *prometheus.CounterVec
+
+	// Rate limiters of the test notifications of tenants without an Alertmanager running in this
+	// instance, for each tenant and integration type, so that the rate limits are enforced across requests.
+	testNotificationsLimitersMtx sync.Mutex
+	testNotificationsLimiters    map[string]map[string]*testNotificationsLimiter
 }
 
 // NewMultitenantAlertmanager creates a new MultitenantAlertmanager.
@@ -383,6 +402,11 @@ func createMultitenantAlertmanager(cfg *MultitenantAlertmanagerConfig, fallbackC
 			Name: "cortex_alertmanager_tenants_owned",
 			Help: "Current number of tenants owned by the Alertmanager instance.",
 		}),
+		testNotificationsRateLimited: promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_alertmanager_test_notifications_rate_limited_total",
+			Help: "Number of rate-limited test notifications per integration, sent for tenants without an Alertmanager running in the instance.",
+		}, []string{"integration"}),
+		testNotificationsLimiters: map[string]map[string]*testNotificationsLimiter{},
 	}
 
 	// Initialize the top-level metrics.
@@ -678,6 +702,7 @@ func (am *MultitenantAlertmanager) loadAndSyncConfigs(ctx context.Context, syncR
 
 	am.syncConfigs(cfgs)
 	am.deleteUnusedLocalUserState()
+	am.deleteUnusedTestNotificationsLimiters(allUsers)
 
 	// Currently, remote state persistence is only used when sharding is enabled.
 	if am.cfg.ShardingEnabled {
@@ -869,20 +894,8 @@ func (am *MultitenantAlertmanager) setConfig(cfg alertspb.AlertConfigDesc) error
 		return fmt.Errorf("no usable Alertmanager configuration for %v", cfg.User)
 	}
 
-	// Transform webhook configs URLs to the per tenant monitor
-	if am.cfg.AutoWebhookRoot != "" {
-		for i, r := range userAmConfig.Receivers {
-			for j, w := range r.WebhookConfigs {
-				if w.URL.String() == autoWebhookURL {
-					u, err := url.Parse(am.cfg.AutoWebhookRoot + "/" + cfg.User + "/monitor")
-					if err != nil {
-						return err
-					}
-
-					userAmConfig.Receivers[i].WebhookConfigs[j].URL = &amconfig.URL{URL: u}
-				}
-			}
-		}
+	if err := am.transformConfig(cfg.User, userAmConfig); err != nil {
+		return err
 	}
 
 	// If no Alertmanager instance exists for this user yet, start one.
@@ -906,6 +919,28 @@ func (am *MultitenantAlertmanager) setConfig(cfg alertspb.AlertConfigDesc) error
 	return nil
 }
 
+// transformConfig transforms the webhook configs URLs to the per tenant monitor.
+func (am *MultitenantAlertmanager) transformConfig(userID string, amConfig *amconfig.Config) error {
+	if am.cfg.AutoWebhookRoot == "" {
+		return nil
+	}
+
+	for i, r := range amConfig.Receivers {
+		for j, w := range r.WebhookConfigs {
+			if w.URL.String() == autoWebhookURL {
+				u, err := url.Parse(am.cfg.AutoWebhookRoot + "/" + userID + "/monitor")
+				if err != nil {
+					return err
+				}
+
+				amConfig.Receivers[i].WebhookConfigs[j].URL = &amconfig.URL{URL: u}
+			}
+		}
+	}
+
+	return nil
+}
+
 func (am *MultitenantAlertmanager) getTenantDirectory(userID string) string {
 	return filepath.Join(am.cfg.DataDir, userID)
 }
@@ -1090,6 +1125,22 @@ func (am *MultitenantAlertmanager) ReadFullStateForUser(ctx context.Context, use
 	// We should only query state from other replicas, and not our own state.
 	addrs := replicationSet.GetAddressesWithout(am.ringLifecycler.GetInstanceAddr())
 
+	results, err := am.readFullStateFromReplicas(ctx, userID, addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	// We only require the state from a single replica, though we return as many as we were able to obtain.
+	if len(results) == 0 {
+		return nil, fmt.Errorf("failed to read state from any replica")
+	}
+
+	return results, nil
+}
+
+// readFullStateFromReplicas reads the full state for user from each of the input replicas, skipping the
+// replicas failing to respond.
+func (am *MultitenantAlertmanager) readFullStateFromReplicas(ctx context.Context, userID string, addrs []string) ([]*clusterpb.FullState, error) {
 	var (
 		resultsMtx sync.Mutex
 		results    []*clusterpb.FullState
@@ -1097,7 +1148,7 @@ func (am *MultitenantAlertmanager) ReadFullStateForUser(ctx context.Context, use
 
 	// Note that the jobs swallow the errors - this is because we want to give each replica a chance to respond.
 	jobs := concurrency.CreateJobsFromStrings(addrs)
-	err = concurrency.ForEach(ctx, jobs, len(jobs), func(ctx context.Context, job interface{}) error {
+	err := concurrency.ForEach(ctx, jobs, len(jobs), func(ctx context.Context, job interface{}) error {
 		addr := job.(string)
 		level.Debug(am.logger).Log("msg", "contacting replica for full state", "user", userID, "addr", addr)
 
@@ -1131,11 +1182,6 @@ func (am *MultitenantAlertmanager) ReadFullStateForUser(ctx context.Context, use
 		return nil, err
 	}
 
-	// We only require the state from a single replica, though we return as many as we were able to obtain.
-	if len(results) == 0 {
-		return nil, fmt.Errorf("failed to read state from any replica")
-	}
-
 	return results, nil
 }
 
@@ -1189,6 +1235,9 @@ func (am *MultitenantAlertmanager) deleteUnusedRemoteUserState(ctx context.Conte
 		}
 
 		err := am.store.DeleteFullState(ctx, userID)
+		if err == nil {
+			err = am.deleteStateSnapshots(ctx, userID)
+		}
 		if err != nil {
 			level.Warn(am.logger).Log("msg", "failed to delete remote state for user", "user", userID, "err", err)
 		} else {
@@ -113,6 +113,19 @@ func TestMultitenantAlertmanagerConfig_Validate(t *testing.T) {
 			},
 			expected: errInvalidPersistInterval,
 		},
+		"should fail if persist max snapshots is negative": {
+			setup: func(t *testing.T, cfg *MultitenantAlertmanagerConfig, storageCfg *alertstore.Config) {
+				cfg.Persister.MaxSnapshots = -1
+			},
+			expected: errInvalidPersistMaxSnapshots,
+		},
+		"should fail if snapshots are enabled and persist snapshot interval is 0": {
+			setup: func(t *testing.T, cfg *MultitenantAlertmanagerConfig, storageCfg *alertstore.Config) {
+				cfg.Persister.MaxSnapshots = 3
+				cfg.Persister.SnapshotInterval = 0
+			},
+			expected: errInvalidPersistSnapshotInterval,
+		},
 		"should fail if external URL ends with /": {
 			setup: func(t *testing.T, cfg *MultitenantAlertmanagerConfig, storageCfg *alertstore.Config) {
 				require.NoError(t, cfg.ExternalURL.Set("http://localhost/prefix/"))
@@ -2027,6 +2040,11 @@ type mockAlertManagerLimits struct {
 	maxDispatcherAggregationGroups int
 	maxAlertsCount                 int
 	maxAlertsSizeBytes             int
+	blockCIDRNetworks              []flagext.CIDR
+	blockPrivateAddresses          bool
+	notificationHistoryMaxEntries  int
+	maxSilencesCount               int
+	maxSilenceSizeBytes            int
 }
 
 func (m *mockAlertManagerLimits) AlertmanagerMaxConfigSize(tenant string) int {
@@ -2042,11 +2060,11 @@ func (m *mockAlertManagerLimits) AlertmanagerMaxTemplateSize(tenant string) int
 }
 
 func (m *mockAlertManagerLimits) AlertmanagerReceiversBlockCIDRNetworks(user string) []flagext.CIDR {
-	panic("implement me")
+	return m.blockCIDRNetworks
 }
 
 func (m *mockAlertManagerLimits) AlertmanagerReceiversBlockPrivateAddresses(user string) bool {
-	panic("implement me")
+	return m.blockPrivateAddresses
 }
 
 func (m *mockAlertManagerLimits) NotificationRateLimit(_ string, integration string) rate.Limit {
@@ -2068,3 +2086,15 @@ func (m *mockAlertManagerLimits) AlertmanagerMaxAlertsCount(_ string) int {
 func (m *mockAlertManagerLimits) AlertmanagerMaxAlertsSizeBytes(_ string) int {
 	return m.maxAlertsSizeBytes
 }
+
+func (m *mockAlertManagerLimits) AlertmanagerNotificationHistoryMaxEntries(_ string) int {
+	return m.notificationHistoryMaxEntries
+}
+
+func (m *mockAlertManagerLimits) AlertmanagerMaxSilencesCount(_ string) int {
+	return m.maxSilencesCount
+}
+
+func (m *mockAlertManagerLimits) AlertmanagerMaxSilenceSizeBytes(_ string) int {
+	return m.maxSilenceSizeBytes
+}
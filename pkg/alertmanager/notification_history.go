@@ -0,0 +1,389 @@
+package alertmanager
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/cortexproject/cortex/pkg/alertmanager/alertspb"
+	"github.com/cortexproject/cortex/pkg/util"
+)
+
+const (
+	// Values accepted by the "outcome" filter of the notification history API.
+	notificationOutcomeSuccess = "success"
+	notificationOutcomeFailure = "failure"
+)
+
+// notificationHistory is a bounded, per-tenant log of the notification attempts made by the tenant's
+// Alertmanager. It implements cluster.State so that it is replicated and persisted together with the
+// notification log and silences.
+type notificationHistory struct {
+	userID     string
+	retention  time.Duration
+	maxEntries func() int
+	logger     log.Logger
+	now        func() time.Time
+
+	mtx       sync.Mutex
+	entries   map[string]*alertspb.NotificationHistoryEntry
+	broadcast func([]byte)
+
+	recordedTotal *prometheus.CounterVec
+	droppedTotal  prometheus.Counter
+}
+
+func newNotificationHistory(userID string, retention time.Duration, limits Limits, logger log.Logger, reg prometheus.Registerer) *notificationHistory {
+	h := &notificationHistory{
+		userID:    userID,
+		retention: retention,
+		maxEntries: func() int {
+			if limits == nil {
+				return 0
+			}
+			return limits.AlertmanagerNotificationHistoryMaxEntries(userID)
+		},
+		logger:    logger,
+		now:       time.Now,
+		entries:   map[string]*alertspb.NotificationHistoryEntry{},
+		broadcast: func([]byte) {},
+
+		recordedTotal: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "alertmanager_notification_history_recorded_total",
+			Help: "Number of notification attempts recorded in the notification history.",
+		}, []string{"integration"}),
+		droppedTotal: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "alertmanager_notification_history_dropped_total",
+			Help: "Number of notification history entries dropped because of the retention or the max entries limit.",
+		}),
+	}
+
+	return h
+}
+
+// SetBroadcast sets the function used to replicate newly recorded entries to the other replicas.
+func (h *notificationHistory) SetBroadcast(f func([]byte)) {
+	h.mtx.Lock()
+	h.broadcast = f
+	h.mtx.Unlock()
+}
+
+// MarshalBinary implements cluster.State.
+func (h *notificationHistory) MarshalBinary() ([]byte, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	desc := alertspb.NotificationHistoryDesc{
+		Entries: make([]alertspb.NotificationHistoryEntry, 0, len(h.entries)),
+	}
+	for _, e := range h.entries {
+		desc.Entries = append(desc.Entries, *e)
+	}
+
+	return desc.Marshal()
+}
+
+// Merge implements cluster.State.
+func (h *notificationHistory) Merge(b []byte) error {
+	desc := alertspb.NotificationHistoryDesc{}
+	if err := desc.Unmarshal(b); err != nil {
+		return err
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	for i := range desc.Entries {
+		e := desc.Entries[i]
+		if _, ok := h.entries[e.ID]; !ok {
+			h.entries[e.ID] = &e
+		}
+	}
+	h.gc()
+
+	return nil
+}
+
+// record adds an entry to the history and broadcasts it to the other replicas.
+func (h *notificationHistory) record(e *alertspb.NotificationHistoryEntry) {
+	if h.maxEntries() <= 0 {
+		return
+	}
+
+	b, err := (&alertspb.NotificationHistoryDesc{Entries: []alertspb.NotificationHistoryEntry{*e}}).Marshal()
+	if err != nil {
+		level.Warn(h.logger).Log("msg", "failed to encode notification history entry", "err", err)
+		return
+	}
+
+	h.mtx.Lock()
+	h.entries[e.ID] = e
+	h.gc()
+	broadcast := h.broadcast
+	h.mtx.Unlock()
+
+	h.recordedTotal.WithLabelValues(e.Integration).Inc()
+	broadcast(b)
+}
+
+// gc drops the entries older than the retention and, when the max entries limit is exceeded,
+// the oldest entries. Must be called with the lock held.
+func (h *notificationHistory) gc() {
+	maxEntries := h.maxEntries()
+	if maxEntries <= 0 {
+		h.droppedTotal.Add(float64(len(h.entries)))
+		h.entries = map[string]*alertspb.NotificationHistoryEntry{}
+		return
+	}
+
+	if h.retention > 0 {
+		threshold := h.now().Add(-h.retention)
+		for id, e := range h.entries {
+			if e.StartedAt.Before(threshold) {
+				delete(h.entries, id)
+				h.droppedTotal.Inc()
+			}
+		}
+	}
+
+	if len(h.entries) <= maxEntries {
+		return
+	}
+
+	sorted := h.sortedEntries()
+	for _, e := range sorted[maxEntries:] {
+		delete(h.entries, e.ID)
+		h.droppedTotal.Inc()
+	}
+}
+
+// sortedEntries returns all entries, newest first. Must be called with the lock held.
+func (h *notificationHistory) sortedEntries() []*alertspb.NotificationHistoryEntry {
+	sorted := make([]*alertspb.NotificationHistoryEntry, 0, len(h.entries))
+	for _, e := range h.entries {
+		sorted = append(sorted, e)
+	}
+	sortNotificationHistoryEntries(sorted)
+	return sorted
+}
+
+// query returns the entries matching the given filter, newest first.
+func (h *notificationHistory) query(f notificationHistoryFilter) []*alertspb.NotificationHistoryEntry {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	res := []*alertspb.NotificationHistoryEntry{}
+	for _, e := range h.sortedEntries() {
+		if f.matches(e) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func sortNotificationHistoryEntries(entries []*alertspb.NotificationHistoryEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].StartedAt.Equal(entries[j].StartedAt) {
+			return entries[i].StartedAt.After(entries[j].StartedAt)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+}
+
+// notificationHistoryFilter selects notification history entries.
+type notificationHistoryFilter struct {
+	receiver    string
+	integration string
+	outcome     string
+	since       time.Time
+	until       time.Time
+	matchers    []*labels.Matcher
+}
+
+func (f notificationHistoryFilter) matches(e *alertspb.NotificationHistoryEntry) bool {
+	if f.receiver != "" && f.receiver != e.Receiver {
+		return false
+	}
+	if f.integration != "" && f.integration != e.Integration {
+		return false
+	}
+	if f.outcome == notificationOutcomeSuccess && !e.Success {
+		return false
+	}
+	if f.outcome == notificationOutcomeFailure && e.Success {
+		return false
+	}
+	if !f.since.IsZero() && e.StartedAt.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && e.StartedAt.After(f.until) {
+		return false
+	}
+	for _, m := range f.matchers {
+		if !m.Matches(e.GroupLabels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseNotificationHistoryFilter(r *http.Request) (notificationHistoryFilter, error) {
+	q := r.URL.Query()
+	f := notificationHistoryFilter{
+		receiver:    q.Get("receiver"),
+		integration: q.Get("integration"),
+		outcome:     q.Get("outcome"),
+	}
+
+	if f.outcome != "" && f.outcome != notificationOutcomeSuccess && f.outcome != notificationOutcomeFailure {
+		return f, fmt.Errorf("invalid outcome %q, supported values are %q and %q", f.outcome, notificationOutcomeSuccess, notificationOutcomeFailure)
+	}
+
+	var err error
+	if v := q.Get("since"); v != "" {
+		if f.since, err = time.Parse(time.RFC3339, v); err != nil {
+			return f, fmt.Errorf("invalid since: %v", err)
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if f.until, err = time.Parse(time.RFC3339, v); err != nil {
+			return f, fmt.Errorf("invalid until: %v", err)
+		}
+	}
+
+	for _, v := range q["filter"] {
+		matchers, err := labels.ParseMatchers(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid filter: %v", err)
+		}
+		f.matchers = append(f.matchers, matchers...)
+	}
+
+	return f, nil
+}
+
+// NotificationHistoryEntry is a single notification attempt, as returned by the notification history API.
+type NotificationHistoryEntry struct {
+	ID             string            `json:"id"`
+	Receiver       string            `json:"receiver"`
+	Integration    string            `json:"integration"`
+	GroupKey       string            `json:"groupKey"`
+	GroupLabels    map[string]string `json:"groupLabels"`
+	FiringAlerts   int               `json:"firingAlerts"`
+	ResolvedAlerts int               `json:"resolvedAlerts"`
+	Success        bool              `json:"success"`
+	Error          string            `json:"error,omitempty"`
+	StartedAt      time.Time         `json:"startedAt"`
+	FinishedAt     time.Time         `json:"finishedAt"`
+}
+
+// NotificationHistoryResponse is the response of the notification history API.
+type NotificationHistoryResponse struct {
+	Status string                     `json:"status"`
+	Data   []NotificationHistoryEntry `json:"data"`
+}
+
+// serveNotificationHistory serves the notification history of the tenant, filtered by the request parameters.
+func (am *Alertmanager) serveNotificationHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	f, err := parseNotificationHistoryFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries := am.notificationHistory.query(f)
+	res := NotificationHistoryResponse{
+		Status: "success",
+		Data:   make([]NotificationHistoryEntry, 0, len(entries)),
+	}
+	for _, e := range entries {
+		res.Data = append(res.Data, NotificationHistoryEntry{
+			ID:             e.ID,
+			Receiver:       e.Receiver,
+			Integration:    e.Integration,
+			GroupKey:       e.GroupKey,
+			GroupLabels:    e.GroupLabels,
+			FiringAlerts:   int(e.FiringAlerts),
+			ResolvedAlerts: int(e.ResolvedAlerts),
+			Success:        e.Success,
+			Error:          e.Error,
+			StartedAt:      e.StartedAt,
+			FinishedAt:     e.FinishedAt,
+		})
+	}
+
+	util.WriteJSONResponse(w, res)
+}
+
+// notificationHistoryNotifier records every notification attempt of the upstream notifier into the history.
+type notificationHistoryNotifier struct {
+	upstream    notify.Notifier
+	integration string
+	history     *notificationHistory
+}
+
+func newNotificationHistoryNotifier(upstream notify.Notifier, integration string, history *notificationHistory) *notificationHistoryNotifier {
+	return &notificationHistoryNotifier{
+		upstream:    upstream,
+		integration: integration,
+		history:     history,
+	}
+}
+
+func (n *notificationHistoryNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+	startedAt := n.history.now()
+	retry, err := n.upstream.Notify(ctx, alerts...)
+	finishedAt := n.history.now()
+
+	e := &alertspb.NotificationHistoryEntry{
+		ID:          ulid.MustNew(ulid.Timestamp(startedAt), rand.Reader).String(),
+		Integration: n.integration,
+		GroupLabels: map[string]string{},
+		Success:     err == nil,
+		StartedAt:   startedAt,
+		FinishedAt:  finishedAt,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if receiver, ok := notify.ReceiverName(ctx); ok {
+		e.Receiver = receiver
+	}
+	if groupKey, ok := notify.GroupKey(ctx); ok {
+		e.GroupKey = groupKey
+	}
+	if groupLabels, ok := notify.GroupLabels(ctx); ok {
+		for name, value := range groupLabels {
+			e.GroupLabels[string(name)] = string(value)
+		}
+	}
+	for _, a := range alerts {
+		if a.ResolvedAt(finishedAt) {
+			e.ResolvedAlerts++
+		} else {
+			e.FiringAlerts++
+		}
+	}
+
+	n.history.record(e)
+
+	return retry, err
+}
@@ -0,0 +1,319 @@
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/cortexproject/cortex/pkg/alertmanager/alertspb"
+	"github.com/cortexproject/cortex/pkg/util/test"
+)
+
+func TestNotificationHistory_RecordAndGC(t *testing.T) {
+	now := time.Now()
+	h := newNotificationHistory("user", time.Hour, &mockAlertManagerLimits{notificationHistoryMaxEntries: 3}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	h.now = func() time.Time { return now }
+
+	var broadcasted [][]byte
+	h.SetBroadcast(func(b []byte) { broadcasted = append(broadcasted, b) })
+
+	// Entries older than the retention are dropped.
+	h.record(&alertspb.NotificationHistoryEntry{ID: "expired", StartedAt: now.Add(-2 * time.Hour)})
+	for i := 0; i < 4; i++ {
+		h.record(&alertspb.NotificationHistoryEntry{ID: fmt.Sprintf("entry-%d", i), StartedAt: now.Add(time.Duration(i) * time.Second)})
+	}
+
+	// Oldest entries are dropped once the max entries limit is reached.
+	assert.Equal(t, []string{"entry-3", "entry-2", "entry-1"}, notificationHistoryIDs(h.query(notificationHistoryFilter{})))
+
+	// Each recorded entry is broadcasted to the other replicas on its own.
+	require.Len(t, broadcasted, 5)
+	for _, b := range broadcasted {
+		desc := alertspb.NotificationHistoryDesc{}
+		require.NoError(t, desc.Unmarshal(b))
+		require.Len(t, desc.Entries, 1)
+	}
+}
+
+func TestNotificationHistory_Disabled(t *testing.T) {
+	h := newNotificationHistory("user", time.Hour, &mockAlertManagerLimits{notificationHistoryMaxEntries: 0}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	broadcasted := 0
+	h.SetBroadcast(func([]byte) { broadcasted++ })
+
+	h.record(&alertspb.NotificationHistoryEntry{ID: "entry", StartedAt: time.Now()})
+	assert.Empty(t, h.query(notificationHistoryFilter{}))
+	assert.Zero(t, broadcasted)
+}
+
+func TestNotificationHistory_MarshalAndMerge(t *testing.T) {
+	now := time.Now()
+	limits := &mockAlertManagerLimits{notificationHistoryMaxEntries: 10}
+
+	h1 := newNotificationHistory("user", time.Hour, limits, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	h1.record(&alertspb.NotificationHistoryEntry{ID: "entry-1", Receiver: "a", StartedAt: now.Add(-time.Minute)})
+	h1.record(&alertspb.NotificationHistoryEntry{ID: "entry-2", Receiver: "a", StartedAt: now})
+
+	h2 := newNotificationHistory("user", time.Hour, limits, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	h2.record(&alertspb.NotificationHistoryEntry{ID: "entry-2", Receiver: "a", StartedAt: now})
+	h2.record(&alertspb.NotificationHistoryEntry{ID: "entry-3", Receiver: "b", StartedAt: now.Add(time.Minute)})
+
+	b, err := h1.MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, h2.Merge(b))
+
+	assert.Equal(t, []string{"entry-3", "entry-2", "entry-1"}, notificationHistoryIDs(h2.query(notificationHistoryFilter{})))
+
+	// Merging the same state again is a no-op.
+	require.NoError(t, h2.Merge(b))
+	assert.Len(t, h2.query(notificationHistoryFilter{}), 3)
+
+	require.Error(t, h2.Merge([]byte("invalid")))
+}
+
+func TestNotificationHistory_Filter(t *testing.T) {
+	now := time.Date(2021, 6, 21, 10, 0, 0, 0, time.UTC)
+	h := newNotificationHistory("user", 0, &mockAlertManagerLimits{notificationHistoryMaxEntries: 10}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	h.record(&alertspb.NotificationHistoryEntry{
+		ID: "1", Receiver: "team-a", Integration: "webhook", Success: true, StartedAt: now,
+		GroupLabels: map[string]string{"alertname": "HighLatency", "cluster": "eu"},
+	})
+	h.record(&alertspb.NotificationHistoryEntry{
+		ID: "2", Receiver: "team-a", Integration: "email", Success: false, Error: "failed", StartedAt: now.Add(time.Hour),
+		GroupLabels: map[string]string{"alertname": "HighLatency", "cluster": "us"},
+	})
+	h.record(&alertspb.NotificationHistoryEntry{
+		ID: "3", Receiver: "team-b", Integration: "webhook", Success: true, StartedAt: now.Add(2 * time.Hour),
+		GroupLabels: map[string]string{"alertname": "DiskFull"},
+	})
+
+	for name, tc := range map[string]struct {
+		query       string
+		expectedIDs []string
+		expectedErr string
+	}{
+		"no filter": {
+			expectedIDs: []string{"3", "2", "1"},
+		},
+		"by receiver": {
+			query:       "receiver=team-a",
+			expectedIDs: []string{"2", "1"},
+		},
+		"by integration": {
+			query:       "integration=webhook",
+			expectedIDs: []string{"3", "1"},
+		},
+		"by outcome": {
+			query:       "outcome=failure",
+			expectedIDs: []string{"2"},
+		},
+		"by time range": {
+			query:       "since=2021-06-21T10:30:00Z&until=2021-06-21T11:30:00Z",
+			expectedIDs: []string{"2"},
+		},
+		"by group labels": {
+			query:       `filter={alertname="HighLatency"}&filter=cluster=~"e.*"`,
+			expectedIDs: []string{"1"},
+		},
+		"invalid outcome": {
+			query:       "outcome=unknown",
+			expectedErr: `invalid outcome "unknown", supported values are "success" and "failure"`,
+		},
+		"invalid time": {
+			query:       "since=yesterday",
+			expectedErr: "invalid since",
+		},
+		"invalid filter": {
+			query:       "filter=" + url.QueryEscape(`{alertname=~"("}`),
+			expectedErr: "invalid filter",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, err := parseNotificationHistoryFilter(httptest.NewRequest(http.MethodGet, "/api/v1/notifications?"+tc.query, nil))
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedIDs, notificationHistoryIDs(h.query(f)))
+		})
+	}
+}
+
+func TestNotificationHistoryNotifier(t *testing.T) {
+	h := newNotificationHistory("user", time.Hour, &mockAlertManagerLimits{notificationHistoryMaxEntries: 10}, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	now := time.Now()
+	ctx := notify.WithReceiverName(context.Background(), "team-a")
+	ctx = notify.WithGroupKey(ctx, "{}:{alertname=\"HighLatency\"}")
+	ctx = notify.WithGroupLabels(ctx, model.LabelSet{"alertname": "HighLatency"})
+
+	alerts := []*types.Alert{
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "HighLatency"}, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}},
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "HighLatency"}, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(-time.Minute)}},
+	}
+
+	n := newNotificationHistoryNotifier(&failingNotifier{err: errors.New("connection refused"), retry: true}, "webhook", h)
+	retry, err := n.Notify(ctx, alerts...)
+	assert.True(t, retry)
+	assert.EqualError(t, err, "connection refused")
+
+	entries := h.query(notificationHistoryFilter{})
+	require.Len(t, entries, 1)
+	assert.NotEmpty(t, entries[0].ID)
+	assert.Equal(t, "team-a", entries[0].Receiver)
+	assert.Equal(t, "webhook", entries[0].Integration)
+	assert.Equal(t, "{}:{alertname=\"HighLatency\"}", entries[0].GroupKey)
+	assert.Equal(t, map[string]string{"alertname": "HighLatency"}, entries[0].GroupLabels)
+	assert.Equal(t, int32(1), entries[0].FiringAlerts)
+	assert.Equal(t, int32(1), entries[0].ResolvedAlerts)
+	assert.False(t, entries[0].Success)
+	assert.Equal(t, "connection refused", entries[0].Error)
+	assert.False(t, entries[0].FinishedAt.Before(entries[0].StartedAt))
+}
+
+func TestAlertmanager_NotificationHistoryAPI(t *testing.T) {
+	user := "test"
+
+	received := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	am, err := New(&Config{
+		UserID:          user,
+		Logger:          log.NewNopLogger(),
+		Limits:          &mockAlertManagerLimits{notificationHistoryMaxEntries: 10, emailNotificationRateLimit: rate.Inf},
+		TenantDataDir:   t.TempDir(),
+		ExternalURL:     &url.URL{Path: "/am"},
+		ShardingEnabled: false,
+		Retention:       time.Hour,
+	}, prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	defer am.StopAndWait()
+
+	cfgRaw := fmt.Sprintf(`receivers:
+- name: 'prod'
+  webhook_configs:
+  - url: '%s'
+
+route:
+  group_by: ['alertname']
+  group_wait: 10ms
+  group_interval: 10ms
+  receiver: 'prod'`, server.URL)
+
+	cfg, err := config.Load(cfgRaw)
+	require.NoError(t, err)
+	require.NoError(t, am.ApplyConfig(user, cfg, cfgRaw))
+
+	now := time.Now()
+	require.NoError(t, am.alerts.Put(&types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "HighLatency", "instance": "a"},
+			StartsAt: now,
+			EndsAt:   now.Add(5 * time.Minute),
+		},
+		UpdatedAt: now,
+	}))
+
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification not received")
+	}
+
+	query := func(params string) NotificationHistoryResponse {
+		w := httptest.NewRecorder()
+		am.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/am/api/v1/notifications?"+params, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		res := NotificationHistoryResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res
+	}
+
+	// The attempt is recorded right after the notification has been sent.
+	test.Poll(t, time.Second, 1, func() interface{} {
+		return len(query("").Data)
+	})
+
+	res := query("receiver=prod&outcome=success")
+	assert.Equal(t, "success", res.Status)
+	require.Len(t, res.Data, 1)
+	assert.Equal(t, "prod", res.Data[0].Receiver)
+	assert.Equal(t, "webhook", res.Data[0].Integration)
+	assert.Equal(t, map[string]string{"alertname": "HighLatency"}, res.Data[0].GroupLabels)
+	assert.Equal(t, 1, res.Data[0].FiringAlerts)
+	assert.True(t, res.Data[0].Success)
+
+	assert.Empty(t, query("outcome=failure").Data)
+
+	w := httptest.NewRecorder()
+	am.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/am/api/v1/notifications?outcome=unknown", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func notificationHistoryIDs(entries []*alertspb.NotificationHistoryEntry) []string {
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+type failingNotifier struct {
+	retry bool
+	err   error
+}
+
+func (n *failingNotifier) Notify(context.Context, ...*types.Alert) (bool, error) {
+	return n.retry, n.err
+}
+
+func TestAlertmanager_NotificationHistoryShouldBeReplicatedOnlyWhenEnabled(t *testing.T) {
+	for _, maxEntries := range []int{0, 10} {
+		t.Run(fmt.Sprintf("max entries: %d", maxEntries), func(t *testing.T) {
+			am, err := New(&Config{
+				UserID:            "user-1",
+				Logger:            log.NewNopLogger(),
+				Limits:            &mockAlertManagerLimits{notificationHistoryMaxEntries: maxEntries},
+				TenantDataDir:     t.TempDir(),
+				ExternalURL:       &url.URL{Path: "/am"},
+				ShardingEnabled:   true,
+				ReplicationFactor: 1,
+				Store:             prepareInMemoryAlertStore(),
+				PersisterConfig:   PersisterConfig{Interval: time.Hour},
+				Retention:         time.Hour,
+			}, prometheus.NewPedanticRegistry())
+			require.NoError(t, err)
+			defer am.StopAndWait()
+
+			replicated := am.state.(*state)
+			replicated.mtx.Lock()
+			_, ok := replicated.states["nh:user-1"]
+			replicated.mtx.Unlock()
+
+			assert.Equal(t, maxEntries > 0, ok)
+		})
+	}
+}
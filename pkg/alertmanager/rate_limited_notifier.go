@@ -29,15 +29,25 @@ type rateLimitedNotifier struct {
 }
 
 func newRateLimitedNotifier(upstream notify.Notifier, limits rateLimits, recheckInterval time.Duration, counter prometheus.Counter) *rateLimitedNotifier {
+	return newRateLimitedNotifierWithLimiter(upstream, limits, newNotificationsLimiter(limits), recheckInterval, counter)
+}
+
+// newRateLimitedNotifierWithLimiter is like newRateLimitedNotifier, but uses the input limiter,
+// so that the rate limit can be shared by notifiers built at different times.
+func newRateLimitedNotifierWithLimiter(upstream notify.Notifier, limits rateLimits, limiter *rate.Limiter, recheckInterval time.Duration, counter prometheus.Counter) *rateLimitedNotifier {
 	return &rateLimitedNotifier{
 		upstream:        upstream,
 		counter:         counter,
 		limits:          limits,
-		limiter:         rate.NewLimiter(limits.RateLimit(), limits.Burst()),
+		limiter:         limiter,
 		recheckInterval: recheckInterval,
 	}
 }
 
+func newNotificationsLimiter(limits rateLimits) *rate.Limiter {
+	return rate.NewLimiter(limits.RateLimit(), limits.Burst())
+}
+
 var errRateLimited = errors.New("failed to notify due to rate limits")
 
 func (r *rateLimitedNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
@@ -0,0 +1,367 @@
+package alertmanager
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	v2 "github.com/prometheus/alertmanager/api/v2"
+	v2_models "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+
+	"github.com/cortexproject/cortex/pkg/util"
+	util_log "github.com/cortexproject/cortex/pkg/util/log"
+)
+
+var (
+	errTooManySilences = "too many silences, limit: %d"
+	errSilenceTooBig   = "silence too big: %d bytes, size limit: %d bytes"
+
+	errInvalidSilencesState = errors.New("invalid silences state")
+)
+
+// silencesLimiter limits the number and size of silences created via the Alertmanager API.
+// Only active and pending silences are counted, and the size of a silence is
+// determined by the sum of bytes of its matchers, comment and author.
+type silencesLimiter struct {
+	tenant   string
+	limits   Limits
+	silences *silence.Silences
+
+	// Held while checking the limits and storing the silence, so that concurrent
+	// requests can't exceed the max number of silences.
+	mtx sync.Mutex
+
+	failureCounter prometheus.Counter
+}
+
+func newSilencesLimiter(tenant string, limits Limits, silences *silence.Silences, reg prometheus.Registerer) *silencesLimiter {
+	return &silencesLimiter{
+		tenant:   tenant,
+		limits:   limits,
+		silences: silences,
+		failureCounter: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "alertmanager_silences_insert_limited_total",
+			Help: "Number of failures to create or import silences due to silence limits.",
+		}),
+	}
+}
+
+// check returns an error if storing the input silence would exceed the limits.
+func (l *silencesLimiter) check(sil *silencepb.Silence) error {
+	if l.limits == nil {
+		return nil
+	}
+
+	if sizeLimit := l.limits.AlertmanagerMaxSilenceSizeBytes(l.tenant); sizeLimit > 0 {
+		if size := silenceSize(sil); size > sizeLimit {
+			l.failureCounter.Inc()
+			return fmt.Errorf(errSilenceTooBig, size, sizeLimit)
+		}
+	}
+
+	countLimit := l.limits.AlertmanagerMaxSilencesCount(l.tenant)
+	if countLimit <= 0 {
+		return nil
+	}
+
+	// Updating an active or pending silence replaces it, so it doesn't increase the count.
+	if sil.Id != "" {
+		if _, err := l.silences.QueryOne(silence.QIDs(sil.Id), silence.QState(types.SilenceStateActive, types.SilenceStatePending)); err == nil {
+			return nil
+		}
+	}
+
+	count, err := l.silences.CountState(types.SilenceStateActive, types.SilenceStatePending)
+	if err != nil {
+		return err
+	}
+	if count+1 > countLimit {
+		l.failureCounter.Inc()
+		return fmt.Errorf(errTooManySilences, countLimit)
+	}
+
+	return nil
+}
+
+// wrap returns an handler enforcing the limits on the silences created by the upstream handler.
+func (l *silencesLimiter) wrap(upstream http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			upstream.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		// The v1 and v2 APIs share the same silence format. Invalid silences are
+		// passed through, so that they're rejected by the upstream handler.
+		sil, err := parsePostableSilence(body)
+		if err == nil {
+			l.mtx.Lock()
+			defer l.mtx.Unlock()
+
+			if err := l.check(sil); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		upstream.ServeHTTP(w, r)
+	})
+}
+
+func parsePostableSilence(body []byte) (*silencepb.Silence, error) {
+	ps := v2_models.PostableSilence{}
+	if err := swag.ReadJSON(body, &ps); err != nil {
+		return nil, err
+	}
+	if err := ps.Validate(strfmt.Default); err != nil {
+		return nil, err
+	}
+	return v2.PostableSilenceToProto(&ps)
+}
+
+// silenceSize returns the size of the silence, computed from its matchers, comment and author.
+func silenceSize(sil *silencepb.Silence) int {
+	size := len(sil.Comment) + len(sil.CreatedBy)
+	for _, m := range sil.Matchers {
+		size += len(m.Name) + len(m.Pattern)
+	}
+	return size
+}
+
+// SilencesImportResponse is the response of the silences import API.
+type SilencesImportResponse struct {
+	Imported int                  `json:"imported"`
+	Skipped  int                  `json:"skipped"`
+	Errors   []SilenceImportError `json:"errors,omitempty"`
+}
+
+// SilenceImportError describes a silence which couldn't be imported.
+type SilenceImportError struct {
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// exportSilences serves all the silences of the tenant, including the expired ones,
+// in the same format as the GET /api/v2/silences endpoint.
+func (am *Alertmanager) exportSilences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sils, _, err := am.silences.Query()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := make(v2_models.GettableSilences, 0, len(sils))
+	for _, sil := range sils {
+		s, err := v2.GettableSilenceFromProto(sil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		res = append(res, &s)
+	}
+	v2.SortSilences(res)
+
+	util.WriteJSONResponse(w, res)
+}
+
+// importSilences stores the silences in the request body, in the same format returned by
+// exportSilences. Silences keep their ID, so that importing the same silences multiple times
+// is idempotent: silences already existing and expired silences are skipped. Silences without
+// an ID are created as new silences. Imported silences are replicated to the other
+// Alertmanagers holding the tenant via state replication.
+func (am *Alertmanager) importSilences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	logger := util_log.WithContext(r.Context(), am.logger)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	in := v2_models.GettableSilences{}
+	if err := swag.ReadJSON(body, &in); err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse silences: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	res := SilencesImportResponse{}
+	for _, gs := range in {
+		if gs == nil {
+			continue
+		}
+
+		id := swag.StringValue(gs.ID)
+		imported, err := am.importSilence(id, gs)
+		if err != nil {
+			res.Errors = append(res.Errors, SilenceImportError{ID: id, Error: err.Error()})
+			continue
+		}
+
+		if imported {
+			res.Imported++
+		} else {
+			res.Skipped++
+		}
+	}
+
+	level.Info(logger).Log("msg", "silences imported", "imported", res.Imported, "skipped", res.Skipped, "failed", len(res.Errors))
+
+	util.WriteJSONResponse(w, res)
+}
+
+// importSilence stores a single silence, returning false if it has been skipped.
+func (am *Alertmanager) importSilence(id string, gs *v2_models.GettableSilence) (bool, error) {
+	if id != "" {
+		if _, err := am.silences.QueryOne(silence.QIDs(id)); err == nil {
+			return false, nil
+		}
+	}
+
+	ps := &v2_models.PostableSilence{Silence: gs.Silence}
+	if err := ps.Validate(strfmt.Default); err != nil {
+		return false, err
+	}
+	sil, err := v2.PostableSilenceToProto(ps)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	if !sil.EndsAt.After(now) {
+		return false, nil
+	}
+
+	am.silencesLimiter.mtx.Lock()
+	defer am.silencesLimiter.mtx.Unlock()
+
+	if err := am.silencesLimiter.check(sil); err != nil {
+		return false, err
+	}
+
+	if id == "" {
+		_, err := am.silences.Set(sil)
+		return err == nil, err
+	}
+
+	// Silences with an unknown ID can't be created via Set(), so the silence is merged
+	// into the local state instead, which also broadcasts it to the other replicas.
+	sil.Id = id
+	sil.UpdatedAt = now
+	if err := validateImportedSilence(sil); err != nil {
+		return false, err
+	}
+
+	b, err := marshalMeshSilence(&silencepb.MeshSilence{
+		Silence:   sil,
+		ExpiresAt: sil.EndsAt.Add(am.cfg.Retention),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if err := am.silences.Merge(b); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// validateImportedSilence applies the same validation done by the Alertmanager when a silence is created.
+func validateImportedSilence(sil *silencepb.Silence) error {
+	if len(sil.Matchers) == 0 {
+		return errors.New("at least one matcher required")
+	}
+
+	allMatchEmpty := true
+	for i, m := range sil.Matchers {
+		if !model.LabelName(m.Name).IsValid() {
+			return fmt.Errorf("invalid label matcher %d: invalid label name %q", i, m.Name)
+		}
+
+		switch m.Type {
+		case silencepb.Matcher_EQUAL:
+			allMatchEmpty = allMatchEmpty && m.Pattern == ""
+		case silencepb.Matcher_REGEXP, silencepb.Matcher_NOT_REGEXP:
+			re, err := regexp.Compile("^(?:" + m.Pattern + ")$")
+			if err != nil {
+				return fmt.Errorf("invalid label matcher %d: %v", i, err)
+			}
+			allMatchEmpty = allMatchEmpty && (m.Type == silencepb.Matcher_REGEXP && re.MatchString(""))
+		default:
+			allMatchEmpty = false
+		}
+	}
+	if allMatchEmpty {
+		return errors.New("at least one matcher must not match the empty string")
+	}
+
+	if sil.StartsAt.IsZero() || sil.EndsAt.IsZero() {
+		return errors.New("invalid zero start or end timestamp")
+	}
+	if sil.EndsAt.Before(sil.StartsAt) {
+		return errors.New("end time must not be before start time")
+	}
+	return nil
+}
+
+// marshalMeshSilence encodes the silence in the length-delimited format expected by Silences.Merge().
+func marshalMeshSilence(e *silencepb.MeshSilence) ([]byte, error) {
+	data, err := e.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(data))
+	n := binary.PutUvarint(buf, uint64(len(data)))
+	return append(buf[:n], data...), nil
+}
+
+// unmarshalMeshSilences decodes the silences in the length-delimited format used by the silences state.
+func unmarshalMeshSilences(b []byte) ([]*silencepb.MeshSilence, error) {
+	var res []*silencepb.MeshSilence
+	for len(b) > 0 {
+		size, n := binary.Uvarint(b)
+		if n <= 0 || size > uint64(len(b)-n) {
+			return nil, errInvalidSilencesState
+		}
+
+		ms := &silencepb.MeshSilence{}
+		if err := ms.Unmarshal(b[n : n+int(size)]); err != nil {
+			return nil, err
+		}
+		res = append(res, ms)
+		b = b[n+int(size):]
+	}
+	return res, nil
+}
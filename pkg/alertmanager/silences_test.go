@@ -0,0 +1,262 @@
+package alertmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertmanager_SilencesLimits(t *testing.T) {
+	for name, tc := range map[string]struct {
+		maxSilencesCount    int
+		maxSilenceSizeBytes int
+		silences            []string
+		expectedCodes       []int
+		expectedError       string
+	}{
+		"no limits": {
+			silences:      []string{silenceJSON("", "a", "test"), silenceJSON("", "b", "test"), silenceJSON("", "c", "test")},
+			expectedCodes: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		"count limit reached": {
+			maxSilencesCount: 2,
+			silences:         []string{silenceJSON("", "a", "test"), silenceJSON("", "b", "test"), silenceJSON("", "c", "test")},
+			expectedCodes:    []int{http.StatusOK, http.StatusOK, http.StatusBadRequest},
+			expectedError:    "too many silences, limit: 2",
+		},
+		"size limit reached": {
+			// The size is the sum of the matcher name and value, the author and the comment.
+			maxSilenceSizeBytes: 20,
+			silences:            []string{silenceJSON("", "a", "short"), silenceJSON("", "b", "a very long comment")},
+			expectedCodes:       []int{http.StatusOK, http.StatusBadRequest},
+			expectedError:       "silence too big: 30 bytes, size limit: 20 bytes",
+		},
+		"invalid silences are rejected by the upstream API": {
+			maxSilencesCount: 1,
+			silences:         []string{`{"matchers":[]}`},
+			expectedCodes:    []int{http.StatusBadRequest},
+		},
+	} {
+		for _, apiVersion := range []string{"v1", "v2"} {
+			t.Run(fmt.Sprintf("%s (%s)", name, apiVersion), func(t *testing.T) {
+				reg := prometheus.NewPedanticRegistry()
+				am := createAlertmanagerForSilencesTest(t, &mockAlertManagerLimits{
+					maxSilencesCount:    tc.maxSilencesCount,
+					maxSilenceSizeBytes: tc.maxSilenceSizeBytes,
+				}, reg)
+
+				var lastBody string
+				for i, s := range tc.silences {
+					w := httptest.NewRecorder()
+					am.mux.ServeHTTP(w, newJSONRequest(http.MethodPost, "/am/api/"+apiVersion+"/silences", strings.NewReader(s)))
+					if tc.expectedCodes[i] == http.StatusOK {
+						require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+					} else {
+						// The v1 and v2 APIs use different client error codes for invalid silences.
+						require.Equal(t, 4, w.Code/100, w.Body.String())
+					}
+					lastBody = w.Body.String()
+				}
+
+				if tc.expectedError != "" {
+					assert.Contains(t, lastBody, tc.expectedError)
+					assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+						# HELP alertmanager_silences_insert_limited_total Number of failures to create or import silences due to silence limits.
+						# TYPE alertmanager_silences_insert_limited_total counter
+						alertmanager_silences_insert_limited_total 1
+					`), "alertmanager_silences_insert_limited_total"))
+				}
+			})
+		}
+	}
+}
+
+func TestAlertmanager_SilencesLimitsWithConcurrentRequests(t *testing.T) {
+	const (
+		maxSilencesCount = 5
+		numRequests      = 50
+	)
+
+	am := createAlertmanagerForSilencesTest(t, &mockAlertManagerLimits{maxSilencesCount: maxSilencesCount}, prometheus.NewPedanticRegistry())
+
+	wg := sync.WaitGroup{}
+	wg.Add(numRequests)
+
+	for i := 0; i < numRequests; i++ {
+		go func(i int) {
+			defer wg.Done()
+
+			w := httptest.NewRecorder()
+			am.mux.ServeHTTP(w, newJSONRequest(http.MethodPost, "/am/api/v2/silences", strings.NewReader(silenceJSON("", fmt.Sprintf("silence-%d", i), "test"))))
+		}(i)
+	}
+
+	wg.Wait()
+
+	// The concurrent requests can't exceed the limit.
+	count, err := am.silences.CountState(types.SilenceStateActive, types.SilenceStatePending)
+	require.NoError(t, err)
+	assert.Equal(t, maxSilencesCount, count)
+}
+
+func TestAlertmanager_SilencesLimitsAllowUpdates(t *testing.T) {
+	am := createAlertmanagerForSilencesTest(t, &mockAlertManagerLimits{maxSilencesCount: 1}, prometheus.NewPedanticRegistry())
+
+	id, err := am.silences.Set(silenceProto(t, "a", "test"))
+	require.NoError(t, err)
+
+	// Updating the existing silence doesn't count against the limit. Since the start time changes,
+	// the existing silence is expired and replaced by a new one.
+	w := httptest.NewRecorder()
+	am.mux.ServeHTTP(w, newJSONRequest(http.MethodPost, "/am/api/v2/silences", strings.NewReader(silenceJSON(id, "a", "updated"))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	updated := struct {
+		SilenceID string `json:"silenceID"`
+	}{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	id = updated.SilenceID
+
+	w = httptest.NewRecorder()
+	am.mux.ServeHTTP(w, newJSONRequest(http.MethodPost, "/am/api/v2/silences", strings.NewReader(silenceJSON("", "b", "test"))))
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	// Expired silences don't count against the limit.
+	require.NoError(t, am.silences.Expire(id))
+
+	w = httptest.NewRecorder()
+	am.mux.ServeHTTP(w, newJSONRequest(http.MethodPost, "/am/api/v2/silences", strings.NewReader(silenceJSON("", "b", "test"))))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestAlertmanager_SilencesExportImport(t *testing.T) {
+	source := createAlertmanagerForSilencesTest(t, nil, prometheus.NewPedanticRegistry())
+
+	activeID, err := source.silences.Set(silenceProto(t, "a", "active"))
+	require.NoError(t, err)
+	expiredID, err := source.silences.Set(silenceProto(t, "b", "expired"))
+	require.NoError(t, err)
+	require.NoError(t, source.silences.Expire(expiredID))
+
+	// Export the silences, including the expired ones.
+	w := httptest.NewRecorder()
+	source.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/am/api/v1/silences/export", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	exported := w.Body.Bytes()
+
+	var exportedSilences []struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(exported, &exportedSilences))
+	require.Len(t, exportedSilences, 2)
+
+	// Import the silences into another Alertmanager.
+	var broadcasted [][]byte
+	target := createAlertmanagerForSilencesTest(t, &mockAlertManagerLimits{maxSilencesCount: 10}, prometheus.NewPedanticRegistry())
+	target.silences.SetBroadcast(func(b []byte) { broadcasted = append(broadcasted, b) })
+
+	importSilences := func(body []byte) SilencesImportResponse {
+		w := httptest.NewRecorder()
+		target.mux.ServeHTTP(w, newJSONRequest(http.MethodPost, "/am/api/v1/silences/import", bytes.NewReader(body)))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		res := SilencesImportResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res
+	}
+
+	// The expired silence is skipped, while the active one keeps its ID.
+	assert.Equal(t, SilencesImportResponse{Imported: 1, Skipped: 1}, importSilences(exported))
+
+	sils, _, err := target.silences.Query(silence.QState(types.SilenceStateActive))
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+	assert.Equal(t, activeID, sils[0].Id)
+	assert.Equal(t, "active", sils[0].Comment)
+
+	// The imported silence has been broadcasted to the other replicas.
+	assert.Len(t, broadcasted, 1)
+
+	// Importing the same silences again is a no-op.
+	assert.Equal(t, SilencesImportResponse{Imported: 0, Skipped: 2}, importSilences(exported))
+
+	// Silences without ID are created as new silences, while invalid silences are reported.
+	res := importSilences([]byte(`[` + silenceJSON("", "c", "new") + `,{"id":"invalid","matchers":[]}]`))
+	assert.Equal(t, 1, res.Imported)
+	require.Len(t, res.Errors, 1)
+	assert.Equal(t, "invalid", res.Errors[0].ID)
+
+	count, err := target.silences.CountState(types.SilenceStateActive)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	w = httptest.NewRecorder()
+	target.mux.ServeHTTP(w, newJSONRequest(http.MethodPost, "/am/api/v1/silences/import", strings.NewReader("invalid")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAlertmanager_SilencesImportLimits(t *testing.T) {
+	am := createAlertmanagerForSilencesTest(t, &mockAlertManagerLimits{maxSilencesCount: 1}, prometheus.NewPedanticRegistry())
+
+	w := httptest.NewRecorder()
+	body := `[` + silenceJSON("id-1", "a", "test") + `,` + silenceJSON("id-2", "b", "test") + `]`
+	am.mux.ServeHTTP(w, newJSONRequest(http.MethodPost, "/am/api/v1/silences/import", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	res := SilencesImportResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, SilencesImportResponse{
+		Imported: 1,
+		Errors:   []SilenceImportError{{ID: "id-2", Error: "too many silences, limit: 1"}},
+	}, res)
+}
+
+func createAlertmanagerForSilencesTest(t *testing.T, limits Limits, reg *prometheus.Registry) *Alertmanager {
+	am, err := New(&Config{
+		UserID:        "user",
+		Logger:        log.NewNopLogger(),
+		Limits:        limits,
+		TenantDataDir: t.TempDir(),
+		ExternalURL:   &url.URL{Path: "/am"},
+		Retention:     time.Hour,
+	}, reg)
+	require.NoError(t, err)
+	t.Cleanup(am.StopAndWait)
+
+	return am
+}
+
+func silenceJSON(id, value, comment string) string {
+	now := time.Now()
+	return fmt.Sprintf(`{"id":%q,"matchers":[{"name":"instance","value":%q,"isRegex":false,"isEqual":true}],"startsAt":%q,"endsAt":%q,"createdBy":"me","comment":%q}`,
+		id, value, now.Format(time.RFC3339Nano), now.Add(time.Hour).Format(time.RFC3339Nano), comment)
+}
+
+func silenceProto(t *testing.T, value, comment string) *silencepb.Silence {
+	sil, err := parsePostableSilence([]byte(silenceJSON("", value, comment)))
+	require.NoError(t, err)
+	return sil
+}
+
+func newJSONRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
@@ -22,21 +22,33 @@ const (
 )
 
 var (
-	errInvalidPersistInterval = errors.New("invalid alertmanager persist interval, must be greater than zero")
+	errInvalidPersistInterval         = errors.New("invalid alertmanager persist interval, must be greater than zero")
+	errInvalidPersistSnapshotInterval = errors.New("invalid alertmanager persist snapshot interval, must be greater than zero")
+	errInvalidPersistMaxSnapshots     = errors.New("invalid alertmanager persist max snapshots, must be greater than or equal to zero")
 )
 
 type PersisterConfig struct {
-	Interval time.Duration `yaml:"persist_interval"`
+	Interval         time.Duration `yaml:"persist_interval"`
+	SnapshotInterval time.Duration `yaml:"persist_snapshot_interval"`
+	MaxSnapshots     int           `yaml:"persist_max_snapshots"`
 }
 
 func (cfg *PersisterConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
 	f.DurationVar(&cfg.Interval, prefix+".persist-interval", 15*time.Minute, "The interval between persisting the current alertmanager state (notification log and silences) to object storage. This is only used when sharding is enabled. This state is read when all replicas for a shard can not be contacted. In this scenario, having persisted the state more frequently will result in potentially fewer lost silences, and fewer duplicate notifications.")
+	f.DurationVar(&cfg.SnapshotInterval, prefix+".persist-snapshot-interval", time.Hour, "The minimum interval between snapshots of the alertmanager state written to object storage. Snapshots are written when the state is persisted, and they can be listed and restored via the admin API. This is only used when sharding is enabled and snapshots are enabled.")
+	f.IntVar(&cfg.MaxSnapshots, prefix+".persist-max-snapshots", 0, "The maximum number of alertmanager state snapshots retained in object storage per tenant. When the limit is reached, the oldest snapshots are deleted. 0 to disable state snapshots.")
 }
 
 func (cfg *PersisterConfig) Validate() error {
 	if cfg.Interval <= 0 {
 		return errInvalidPersistInterval
 	}
+	if cfg.MaxSnapshots < 0 {
+		return errInvalidPersistMaxSnapshots
+	}
+	if cfg.MaxSnapshots > 0 && cfg.SnapshotInterval <= 0 {
+		return errInvalidPersistSnapshotInterval
+	}
 	return nil
 }
 
@@ -54,7 +66,10 @@ type statePersister struct {
 	userID string
 	logger log.Logger
 
-	timeout time.Duration
+	timeout          time.Duration
+	snapshotInterval time.Duration
+	maxSnapshots     int
+	now              func() time.Time
 
 	persistTotal  prometheus.Counter
 	persistFailed prometheus.Counter
@@ -64,11 +79,14 @@ type statePersister struct {
 func newStatePersister(cfg PersisterConfig, userID string, state PersistableState, store alertstore.AlertStore, l log.Logger, r prometheus.Registerer) *statePersister {
 
 	s := &statePersister{
-		state:   state,
-		store:   store,
-		userID:  userID,
-		logger:  l,
-		timeout: defaultPersistTimeout,
+		state:            state,
+		store:            store,
+		userID:           userID,
+		logger:           l,
+		timeout:          defaultPersistTimeout,
+		snapshotInterval: cfg.SnapshotInterval,
+		maxSnapshots:     cfg.MaxSnapshots,
+		now:              time.Now,
 		persistTotal: promauto.With(r).NewCounter(prometheus.CounterOpts{
 			Name: "alertmanager_state_persist_total",
 			Help: "Number of times we have tried to persist the running state to remote storage.",
@@ -126,5 +144,45 @@ func (s *statePersister) persist(ctx context.Context) (err error) {
 		return err
 	}
 
+	if s.maxSnapshots > 0 {
+		if err = s.snapshot(ctx, desc); err != nil {
+			return errors.Wrap(err, "failed to snapshot state")
+		}
+	}
+
+	return nil
+}
+
+// snapshot writes a new state snapshot if the last one is older than the snapshot interval,
+// and deletes the oldest snapshots exceeding the max number of snapshots.
+func (s *statePersister) snapshot(ctx context.Context, desc alertspb.FullStateDesc) error {
+	snapshotIDs, err := s.store.ListFullStateSnapshots(ctx, s.userID)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	if len(snapshotIDs) > 0 {
+		last, err := parseStateSnapshotID(snapshotIDs[len(snapshotIDs)-1])
+		if err == nil && now.Sub(last) < s.snapshotInterval {
+			return nil
+		}
+	}
+
+	snapshotID := formatStateSnapshotID(now)
+	if err := s.store.SetFullStateSnapshot(ctx, s.userID, snapshotID, desc); err != nil {
+		return err
+	}
+	level.Debug(s.logger).Log("msg", "state snapshot written", "user", s.userID, "snapshot", snapshotID)
+
+	snapshotIDs = append(snapshotIDs, snapshotID)
+	for len(snapshotIDs) > s.maxSnapshots {
+		if err := s.store.DeleteFullStateSnapshot(ctx, s.userID, snapshotIDs[0]); err != nil {
+			return err
+		}
+		level.Debug(s.logger).Log("msg", "state snapshot deleted", "user", s.userID, "snapshot", snapshotIDs[0])
+		snapshotIDs = snapshotIDs[1:]
+	}
+
 	return nil
 }
@@ -55,6 +55,7 @@ type fakeStore struct {
 
 	writesMtx sync.Mutex
 	writes    []fakeStoreWrite
+	snapshots []string
 }
 
 func (f *fakeStore) SetFullState(ctx context.Context, user string, desc alertspb.FullStateDesc) error {
@@ -64,6 +65,31 @@ func (f *fakeStore) SetFullState(ctx context.Context, user string, desc alertspb
 	return nil
 }
 
+func (f *fakeStore) ListFullStateSnapshots(ctx context.Context, user string) ([]string, error) {
+	f.writesMtx.Lock()
+	defer f.writesMtx.Unlock()
+	return append([]string(nil), f.snapshots...), nil
+}
+
+func (f *fakeStore) SetFullStateSnapshot(ctx context.Context, user, snapshotID string, desc alertspb.FullStateDesc) error {
+	f.writesMtx.Lock()
+	defer f.writesMtx.Unlock()
+	f.snapshots = append(f.snapshots, snapshotID)
+	return nil
+}
+
+func (f *fakeStore) DeleteFullStateSnapshot(ctx context.Context, user, snapshotID string) error {
+	f.writesMtx.Lock()
+	defer f.writesMtx.Unlock()
+	for i, id := range f.snapshots {
+		if id == snapshotID {
+			f.snapshots = append(f.snapshots[:i], f.snapshots[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
 func (f *fakeStore) getWrites() []fakeStoreWrite {
 	f.writesMtx.Lock()
 	defer f.writesMtx.Unlock()
@@ -155,3 +181,36 @@ func TestStatePersister_Position1ShouldNotWrite(t *testing.T) {
 		assert.Equal(t, 0, len(store.getWrites()))
 	}
 }
+
+func TestStatePersister_ShouldWriteSnapshots(t *testing.T) {
+	state := newFakePersistableState()
+	state.getResult = makeTestFullState()
+	store := &fakeStore{}
+	cfg := PersisterConfig{Interval: time.Second, SnapshotInterval: time.Hour, MaxSnapshots: 2}
+
+	s := newStatePersister(cfg, "user-1", state, store, log.NewNopLogger(), nil)
+
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	// The first persist writes a snapshot.
+	require.NoError(t, s.persist(context.Background()))
+	assert.Equal(t, []string{"20210601T100000Z"}, store.snapshots)
+
+	// No snapshot is written until the snapshot interval has elapsed.
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, s.persist(context.Background()))
+	assert.Equal(t, []string{"20210601T100000Z"}, store.snapshots)
+
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, s.persist(context.Background()))
+	assert.Equal(t, []string{"20210601T100000Z", "20210601T110000Z"}, store.snapshots)
+
+	// The oldest snapshots are deleted when the max number of snapshots is exceeded.
+	now = now.Add(time.Hour)
+	require.NoError(t, s.persist(context.Background()))
+	assert.Equal(t, []string{"20210601T110000Z", "20210601T120000Z"}, store.snapshots)
+
+	// The full state is written on each persist.
+	assert.Len(t, store.getWrites(), 4)
+}
@@ -0,0 +1,323 @@
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/alertmanager/cluster/clusterpb"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/weaveworks/common/user"
+
+	"github.com/cortexproject/cortex/pkg/alertmanager/alertmanagerpb"
+	"github.com/cortexproject/cortex/pkg/alertmanager/alertspb"
+	"github.com/cortexproject/cortex/pkg/tenant"
+	"github.com/cortexproject/cortex/pkg/util"
+	"github.com/cortexproject/cortex/pkg/util/concurrency"
+	util_log "github.com/cortexproject/cortex/pkg/util/log"
+)
+
+const (
+	// The format of state snapshot IDs, which sorts snapshots chronologically.
+	stateSnapshotIDFormat = "20060102T150405Z"
+
+	errListingStateSnapshots         = "error listing state snapshots"
+	errReadingStateSnapshot          = "error reading state snapshot"
+	errRestoringStateSnapshot        = "error restoring state snapshot"
+	errStateSnapshotNotFound         = "state snapshot not found"
+	errMissingStateSnapshot          = "missing snapshot parameter"
+	errInvalidStateSnapshot          = "invalid snapshot parameter"
+	errStateSnapshotRequiresSharding = "restoring state snapshots requires sharding to be enabled"
+)
+
+func formatStateSnapshotID(t time.Time) string {
+	return t.UTC().Format(stateSnapshotIDFormat)
+}
+
+func parseStateSnapshotID(id string) (time.Time, error) {
+	return time.Parse(stateSnapshotIDFormat, id)
+}
+
+// StateSnapshot describes an Alertmanager state snapshot persisted in the alert store.
+type StateSnapshot struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StateSnapshotsResponse is the response of the state snapshots listing API.
+type StateSnapshotsResponse struct {
+	Snapshots []StateSnapshot `json:"snapshots"`
+}
+
+// StateSnapshotRestoreResponse is the response of the state snapshot restore API.
+type StateSnapshotRestoreResponse struct {
+	Snapshot         string `json:"snapshot"`
+	RestoredSilences int    `json:"restored_silences"`
+	Replicas         int    `json:"replicas"`
+}
+
+// ListStateSnapshots lists the state snapshots of the tenant, from the oldest to the newest.
+func (am *MultitenantAlertmanager) ListStateSnapshots(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	snapshotIDs, err := am.store.ListFullStateSnapshots(r.Context(), userID)
+	if err != nil {
+		level.Error(logger).Log("msg", errListingStateSnapshots, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errListingStateSnapshots, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	res := StateSnapshotsResponse{Snapshots: make([]StateSnapshot, 0, len(snapshotIDs))}
+	for _, id := range snapshotIDs {
+		ts, err := parseStateSnapshotID(id)
+		if err != nil {
+			level.Warn(logger).Log("msg", "ignoring state snapshot with invalid ID", "snapshot", id, "err", err)
+			continue
+		}
+		res.Snapshots = append(res.Snapshots, StateSnapshot{ID: id, Timestamp: ts})
+	}
+
+	util.WriteJSONResponse(w, res)
+}
+
+// RestoreStateSnapshot restores the state snapshot of the tenant in the "snapshot" parameter
+// on all the replicas holding the tenant.
+func (am *MultitenantAlertmanager) RestoreStateSnapshot(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	if !am.cfg.ShardingEnabled {
+		http.Error(w, errStateSnapshotRequiresSharding, http.StatusBadRequest)
+		return
+	}
+
+	snapshotID := r.FormValue("snapshot")
+	if snapshotID == "" {
+		http.Error(w, errMissingStateSnapshot, http.StatusBadRequest)
+		return
+	}
+	if _, err := parseStateSnapshotID(snapshotID); err != nil {
+		http.Error(w, errInvalidStateSnapshot, http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := am.store.GetFullStateSnapshot(r.Context(), userID, snapshotID)
+	if errors.Is(err, alertspb.ErrNotFound) {
+		http.Error(w, errStateSnapshotNotFound, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingStateSnapshot, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingStateSnapshot, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	res, err := am.restoreStateSnapshot(r.Context(), userID, snapshot.State)
+	if err != nil {
+		level.Error(logger).Log("msg", errRestoringStateSnapshot, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errRestoringStateSnapshot, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	res.Snapshot = snapshotID
+
+	level.Info(logger).Log("msg", "state snapshot restored", "snapshot", snapshotID, "restored_silences", res.RestoredSilences, "replicas", res.Replicas)
+
+	util.WriteJSONResponse(w, res)
+}
+
+// restoreStateSnapshot reads the current state of the tenant from its replicas, and pushes the
+// snapshot parts to all of them. It's considered a success if at least one replica has been restored.
+func (am *MultitenantAlertmanager) restoreStateSnapshot(ctx context.Context, userID string, snapshot *clusterpb.FullState) (StateSnapshotRestoreResponse, error) {
+	replicationSet, err := am.ring.Get(shardByUser(userID), RingOp, nil, nil, nil)
+	if err != nil {
+		return StateSnapshotRestoreResponse{}, err
+	}
+	addrs := replicationSet.GetAddresses()
+
+	current, err := am.readFullStateFromReplicas(ctx, userID, addrs)
+	if err != nil {
+		return StateSnapshotRestoreResponse{}, err
+	}
+	if len(current) == 0 {
+		return StateSnapshotRestoreResponse{}, fmt.Errorf("failed to read state from any replica")
+	}
+
+	parts, restoredSilences, err := stateSnapshotRestoreParts(userID, snapshot, current, time.Now(), am.cfg.Retention)
+	if err != nil {
+		return StateSnapshotRestoreResponse{}, err
+	}
+
+	var (
+		replicasMtx sync.Mutex
+		replicas    int
+	)
+
+	// Note that the jobs swallow the errors - this is because we want to restore as many replicas as possible.
+	jobs := concurrency.CreateJobsFromStrings(addrs)
+	err = concurrency.ForEach(ctx, jobs, len(jobs), func(ctx context.Context, job interface{}) error {
+		addr := job.(string)
+
+		c, err := am.alertmanagerClientsPool.GetClientFor(addr)
+		if err != nil {
+			level.Error(am.logger).Log("msg", "failed to get rpc client", "err", err)
+			return nil
+		}
+
+		for i := range parts {
+			resp, err := c.UpdateState(user.InjectOrgID(ctx, userID), &parts[i])
+			if err != nil {
+				level.Error(am.logger).Log("msg", "rpc restoring state snapshot on replica failed", "addr", addr, "user", userID, "key", parts[i].Key, "err", err)
+				return nil
+			}
+			if resp.Status != alertmanagerpb.OK {
+				level.Error(am.logger).Log("msg", "failed to restore state snapshot on replica", "addr", addr, "user", userID, "key", parts[i].Key, "status", resp.Status, "err", resp.Error)
+				return nil
+			}
+		}
+
+		replicasMtx.Lock()
+		replicas++
+		replicasMtx.Unlock()
+		return nil
+	})
+	if err != nil {
+		return StateSnapshotRestoreResponse{}, err
+	}
+
+	if replicas == 0 {
+		return StateSnapshotRestoreResponse{}, fmt.Errorf("failed to restore state on any replica")
+	}
+
+	return StateSnapshotRestoreResponse{RestoredSilences: restoredSilences, Replicas: replicas}, nil
+}
+
+// stateSnapshotRestoreParts returns the state parts to merge into the replicas to restore the snapshot.
+// Since merging silences only keeps their most recently updated version, the silences of the snapshot
+// which differ from their current version are restored with an updated timestamp. Silences whose
+// end time is in the past can't be restored, and silences created after the snapshot are kept.
+// All the other parts of the snapshot (e.g. the notification log) are merged as they are.
+func stateSnapshotRestoreParts(userID string, snapshot *clusterpb.FullState, current []*clusterpb.FullState, now time.Time, retention time.Duration) ([]clusterpb.Part, int, error) {
+	silencesKey := "sil:" + userID
+
+	// Find the latest version of each silence across the replicas.
+	currentSilences := map[string]*silencepb.Silence{}
+	for _, fs := range current {
+		for _, p := range fs.Parts {
+			if p.Key != silencesKey {
+				continue
+			}
+
+			sils, err := unmarshalMeshSilences(p.Data)
+			if err != nil {
+				return nil, 0, errors.Wrap(err, "failed to decode the current silences")
+			}
+			for _, ms := range sils {
+				if existing, ok := currentSilences[ms.Silence.Id]; ok && !ms.Silence.UpdatedAt.After(existing.UpdatedAt) {
+					continue
+				}
+				currentSilences[ms.Silence.Id] = ms.Silence
+			}
+		}
+	}
+
+	if snapshot == nil {
+		return nil, 0, nil
+	}
+
+	var (
+		parts            = make([]clusterpb.Part, 0, len(snapshot.Parts))
+		restoredSilences = 0
+	)
+
+	for _, p := range snapshot.Parts {
+		if p.Key != silencesKey {
+			parts = append(parts, p)
+			continue
+		}
+
+		sils, err := unmarshalMeshSilences(p.Data)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "failed to decode the snapshot silences")
+		}
+
+		var data []byte
+		for _, ms := range sils {
+			sil := ms.Silence
+			if !sil.EndsAt.After(now) {
+				continue
+			}
+			if existing, ok := currentSilences[sil.Id]; ok {
+				if equal, err := equalSilences(existing, sil); err != nil {
+					return nil, 0, err
+				} else if equal {
+					continue
+				}
+			}
+
+			sil.UpdatedAt = now
+			b, err := marshalMeshSilence(&silencepb.MeshSilence{
+				Silence:   sil,
+				ExpiresAt: sil.EndsAt.Add(retention),
+			})
+			if err != nil {
+				return nil, 0, err
+			}
+
+			data = append(data, b...)
+			restoredSilences++
+		}
+
+		if len(data) > 0 {
+			parts = append(parts, clusterpb.Part{Key: p.Key, Data: data})
+		}
+	}
+
+	return parts, restoredSilences, nil
+}
+
+// equalSilences returns whether the two silences are equal, regardless of when they've been updated.
+func equalSilences(a, b *silencepb.Silence) (bool, error) {
+	ac, bc := *a, *b
+	ac.UpdatedAt, bc.UpdatedAt = time.Time{}, time.Time{}
+
+	ab, err := ac.Marshal()
+	if err != nil {
+		return false, err
+	}
+	bb, err := bc.Marshal()
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ab, bb), nil
+}
+
+// deleteStateSnapshots deletes all the state snapshots of the user.
+func (am *MultitenantAlertmanager) deleteStateSnapshots(ctx context.Context, userID string) error {
+	snapshotIDs, err := am.store.ListFullStateSnapshots(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range snapshotIDs {
+		if err := am.store.DeleteFullStateSnapshot(ctx, userID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/alertmanager/cluster/clusterpb"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/cortexproject/cortex/pkg/alertmanager/alertspb"
+	"github.com/cortexproject/cortex/pkg/ring"
+	"github.com/cortexproject/cortex/pkg/ring/kv/consul"
+	"github.com/cortexproject/cortex/pkg/util/services"
+)
+
+func TestStateSnapshotRestoreParts(t *testing.T) {
+	now := time.Now()
+	snapshotTime := now.Add(-time.Hour)
+
+	newSilence := func(id string, endsAt, updatedAt time.Time) *silencepb.MeshSilence {
+		return &silencepb.MeshSilence{
+			Silence: &silencepb.Silence{
+				Id:        id,
+				Matchers:  []*silencepb.Matcher{{Name: "instance", Pattern: id}},
+				StartsAt:  snapshotTime,
+				EndsAt:    endsAt,
+				UpdatedAt: updatedAt,
+			},
+			ExpiresAt: endsAt.Add(time.Hour),
+		}
+	}
+	encode := func(sils ...*silencepb.MeshSilence) []byte {
+		var data []byte
+		for _, s := range sils {
+			b, err := marshalMeshSilence(s)
+			require.NoError(t, err)
+			data = append(data, b...)
+		}
+		return data
+	}
+
+	snapshot := &clusterpb.FullState{Parts: []clusterpb.Part{
+		{Key: "nfl:user", Data: []byte("nflog")},
+		{Key: "sil:user", Data: encode(
+			newSilence("unchanged", now.Add(time.Hour), snapshotTime),
+			newSilence("expired-after-snapshot", now.Add(time.Hour), snapshotTime),
+			newSilence("ended", now.Add(-time.Minute), snapshotTime),
+		)},
+	}}
+	current := []*clusterpb.FullState{{Parts: []clusterpb.Part{
+		{Key: "sil:user", Data: encode(
+			newSilence("unchanged", now.Add(time.Hour), snapshotTime),
+			newSilence("expired-after-snapshot", now.Add(-time.Minute), now.Add(-time.Minute)),
+			newSilence("created-after-snapshot", now.Add(time.Hour), now.Add(-time.Minute)),
+		)},
+	}}}
+
+	parts, restored, err := stateSnapshotRestoreParts("user", snapshot, current, now, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+	require.Len(t, parts, 2)
+
+	// Parts other than the silences are restored as they are.
+	assert.Equal(t, snapshot.Parts[0], parts[0])
+
+	// Only the silence which changed since the snapshot is restored, with an updated timestamp.
+	sils, err := unmarshalMeshSilences(parts[1].Data)
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+	assert.Equal(t, "expired-after-snapshot", sils[0].Silence.Id)
+	assert.True(t, now.Equal(sils[0].Silence.UpdatedAt))
+	assert.True(t, now.Add(2*time.Hour).Equal(sils[0].ExpiresAt))
+
+	// Invalid silences state is reported.
+	_, _, err = stateSnapshotRestoreParts("user", &clusterpb.FullState{Parts: []clusterpb.Part{{Key: "sil:user", Data: []byte{0xff}}}}, current, now, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestMultitenantAlertmanager_RestoreStateSnapshot(t *testing.T) {
+	const userID = "u-1"
+
+	ctx := context.Background()
+	ringStore := consul.NewInMemoryClient(ring.GetCodec())
+	alertStore := prepareInMemoryAlertStore()
+	clientPool := newPassthroughAlertmanagerClientPool()
+
+	require.NoError(t, alertStore.SetAlertConfig(ctx, alertspb.AlertConfigDesc{
+		User:      userID,
+		RawConfig: simpleConfigOne,
+		Templates: []*alertspb.TemplateDesc{},
+	}))
+
+	var instances []*MultitenantAlertmanager
+	for i := 1; i <= 2; i++ {
+		cfg := mockAlertmanagerConfig(t)
+		cfg.ShardingRing.ReplicationFactor = 2
+		cfg.ShardingRing.InstanceID = fmt.Sprintf("alertmanager-%d", i)
+		cfg.ShardingRing.InstanceAddr = fmt.Sprintf("127.0.0.%d", i)
+		cfg.ShardingEnabled = true
+
+		// Do not check the ring topology changes or poll in an interval in this test (we explicitly sync alertmanagers).
+		cfg.PollInterval = time.Hour
+		cfg.ShardingRing.RingCheckPeriod = time.Hour
+
+		am, err := createMultitenantAlertmanager(cfg, nil, nil, alertStore, ringStore, nil, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+		require.NoError(t, err)
+
+		clientPool.setServer(cfg.ShardingRing.InstanceAddr+":0", am)
+		am.alertmanagerClientsPool = clientPool
+
+		require.NoError(t, services.StartAndAwaitRunning(ctx, am))
+		t.Cleanup(func() {
+			require.NoError(t, services.StopAndAwaitTerminated(ctx, am))
+		})
+
+		instances = append(instances, am)
+	}
+
+	for _, am := range instances {
+		for i := range instances {
+			require.NoError(t, ring.WaitInstanceState(ctx, am.ring, fmt.Sprintf("alertmanager-%d", i+1), ring.ACTIVE))
+		}
+	}
+	for _, am := range instances {
+		require.NoError(t, am.loadAndSyncConfigs(ctx, reasonRingChange))
+	}
+
+	userSilences := func(am *MultitenantAlertmanager) *silence.Silences {
+		am.alertmanagersMtx.Lock()
+		defer am.alertmanagersMtx.Unlock()
+		return am.alertmanagers[userID].silences
+	}
+	isActiveOnAllReplicas := func(id string) bool {
+		for _, am := range instances {
+			if _, err := userSilences(am).QueryOne(silence.QIDs(id), silence.QState(types.SilenceStateActive)); err != nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Create a silence, and snapshot the state once it's been replicated.
+	id, err := userSilences(instances[0]).Set(silenceProto(t, "a", "test"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return isActiveOnAllReplicas(id) }, 5*time.Second, 100*time.Millisecond)
+
+	instances[0].alertmanagersMtx.Lock()
+	fs, err := instances[0].alertmanagers[userID].getFullState()
+	instances[0].alertmanagersMtx.Unlock()
+	require.NoError(t, err)
+
+	snapshotID := formatStateSnapshotID(time.Now())
+	require.NoError(t, alertStore.SetFullStateSnapshot(ctx, userID, snapshotID, alertspb.FullStateDesc{State: fs}))
+
+	// Expire the silence.
+	require.NoError(t, userSilences(instances[0]).Expire(id))
+	require.Eventually(t, func() bool {
+		_, err := userSilences(instances[1]).QueryOne(silence.QIDs(id), silence.QState(types.SilenceStateExpired))
+		return err == nil
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// The snapshot is listed.
+	{
+		req := httptest.NewRequest(http.MethodGet, "/multitenant_alertmanager/state_snapshots", nil)
+		w := httptest.NewRecorder()
+		instances[1].ListStateSnapshots(w, req.WithContext(user.InjectOrgID(req.Context(), userID)))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		res := StateSnapshotsResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.Len(t, res.Snapshots, 1)
+		assert.Equal(t, snapshotID, res.Snapshots[0].ID)
+	}
+
+	restore := func(snapshotID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/multitenant_alertmanager/state_snapshots/restore?snapshot="+snapshotID, nil)
+		w := httptest.NewRecorder()
+		instances[1].RestoreStateSnapshot(w, req.WithContext(user.InjectOrgID(req.Context(), userID)))
+		return w
+	}
+
+	// Restoring the snapshot reactivates the silence on all the replicas.
+	{
+		w := restore(snapshotID)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		res := StateSnapshotRestoreResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, StateSnapshotRestoreResponse{Snapshot: snapshotID, RestoredSilences: 1, Replicas: 2}, res)
+		assert.True(t, isActiveOnAllReplicas(id))
+	}
+
+	// Restoring the snapshot again has no effect on the silences.
+	{
+		w := restore(snapshotID)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		res := StateSnapshotRestoreResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, 0, res.RestoredSilences)
+	}
+
+	assert.Equal(t, http.StatusNotFound, restore("20000101T000000Z").Code)
+	assert.Equal(t, http.StatusBadRequest, restore("").Code)
+	assert.Equal(t, http.StatusBadRequest, restore(url.QueryEscape("../../u-2/snapshots/20000101T000000Z")).Code)
+}
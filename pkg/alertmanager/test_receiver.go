@@ -0,0 +1,250 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"golang.org/x/time/rate"
+
+	"github.com/cortexproject/cortex/pkg/alertmanager/alertspb"
+	util_net "github.com/cortexproject/cortex/pkg/util/net"
+)
+
+const (
+	// testReceiverTimeout is the max time a single integration is allowed to take to send a test notification.
+	testReceiverTimeout = 30 * time.Second
+
+	errReceiverNotFound = "receiver %q not found in the Alertmanager config"
+)
+
+// TestReceiverRequest is the request to send a test notification to a receiver.
+type TestReceiverRequest struct {
+	// Receiver is the name of the receiver to notify.
+	Receiver string `yaml:"receiver"`
+
+	// Alert is the synthetic alert to send. If empty, a default alert is sent.
+	Alert TestAlert `yaml:"alert"`
+
+	// Config is the configuration to look the receiver up in. If empty, the stored configuration is used.
+	Config UserConfig `yaml:",inline"`
+}
+
+// TestAlert is a synthetic alert sent to test a receiver.
+type TestAlert struct {
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// TestReceiverResponse is the outcome of sending a test notification to a receiver.
+type TestReceiverResponse struct {
+	Receiver     string                  `json:"receiver"`
+	Integrations []TestIntegrationResult `json:"integrations"`
+}
+
+// TestIntegrationResult is the outcome of sending a test notification through a single integration.
+type TestIntegrationResult struct {
+	Name           string  `json:"name"`
+	Index          int     `json:"index"`
+	Success        bool    `json:"success"`
+	LatencySeconds float64 `json:"latency_seconds"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// toAlert builds the alert to send, filling in default labels and annotations.
+func (t TestAlert) toAlert(now time.Time) *types.Alert {
+	labels := model.LabelSet{
+		model.AlertNameLabel: "TestAlert",
+		"instance":           "Cortex",
+	}
+	for name, value := range t.Labels {
+		labels[model.LabelName(name)] = model.LabelValue(value)
+	}
+
+	annotations := model.LabelSet{
+		"summary":     "Notification test",
+		"description": "This is a test notification sent by the Cortex Alertmanager.",
+	}
+	for name, value := range t.Annotations {
+		annotations[model.LabelName(name)] = model.LabelValue(value)
+	}
+
+	return &types.Alert{
+		Alert: model.Alert{
+			Labels:      labels,
+			Annotations: annotations,
+			StartsAt:    now,
+			EndsAt:      now.Add(5 * time.Minute),
+		},
+		UpdatedAt: now,
+	}
+}
+
+// buildTestReceiverIntegrations builds the integrations for the given receiver off of the input config,
+// the same way the per-tenant Alertmanager does (including the receivers firewall and rate-limits).
+// Returns nil integrations if the receiver doesn't exist in the config.
+func (am *MultitenantAlertmanager) buildTestReceiverIntegrations(cfg alertspb.AlertConfigDesc, receiver string, rateLimited *prometheus.CounterVec) ([]notify.Integration, error) {
+	amCfg, err := config.Load(cfg.RawConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := am.transformConfig(cfg.User, amCfg); err != nil {
+		return nil, err
+	}
+
+	var rcv *config.Receiver
+	for _, r := range amCfg.Receivers {
+		if r.Name == receiver {
+			rcv = r
+			break
+		}
+	}
+	if rcv == nil {
+		return nil, nil
+	}
+
+	tmpl, _, errs := dryRunTemplates(cfg, amCfg)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("unable to parse templates: %s", errs[0].Message)
+	}
+	tmpl.ExternalURL = am.cfg.ExternalURL.URL
+
+	firewallDialer := util_net.NewFirewallDialer(newFirewallDialerConfigProvider(cfg.User, am.limits))
+	logger := log.With(am.logger, "user", cfg.User)
+
+	return buildReceiverIntegrations(rcv, tmpl, firewallDialer, logger, func(integrationName string, notifier notify.Notifier) notify.Notifier {
+		if am.limits != nil {
+			rl := &tenantRateLimits{
+				tenant:      cfg.User,
+				limits:      am.limits,
+				integration: integrationName,
+			}
+
+			limiter := am.testNotificationsLimiter(cfg.User, integrationName, rl)
+			return newRateLimitedNotifierWithLimiter(notifier, rl, limiter, 10*time.Second, rateLimited.WithLabelValues(integrationName))
+		}
+		return notifier
+	})
+}
+
+// testNotificationsLimiter is the rate limiter of the test notifications sent through
+// the integrations of a given type.
+type testNotificationsLimiter struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// replenished returns whether the limiter has not been used for long enough to be full again,
+// and so it can be replaced by a new one without allowing more notifications.
+func (l *testNotificationsLimiter) replenished(now time.Time) bool {
+	limit := l.limiter.Limit()
+	if limit == rate.Inf {
+		return true
+	}
+	if limit <= 0 {
+		return false
+	}
+
+	return now.Sub(l.lastUsed).Seconds()*float64(limit) >= float64(l.limiter.Burst())
+}
+
+// testNotificationsLimiter returns the rate limiter of the test notifications sent through the input
+// tenant's integrations of the given type, creating it if it doesn't exist yet. The limiter is shared by
+// all the integrations of the same type, regardless of the receiver, because the receivers of a test
+// notification can be freely renamed by the tenant.
+func (am *MultitenantAlertmanager) testNotificationsLimiter(userID, integrationName string, limits rateLimits) *rate.Limiter {
+	am.testNotificationsLimitersMtx.Lock()
+	defer am.testNotificationsLimitersMtx.Unlock()
+
+	l, ok := am.testNotificationsLimiters[userID][integrationName]
+	if !ok {
+		if am.testNotificationsLimiters[userID] == nil {
+			am.testNotificationsLimiters[userID] = map[string]*testNotificationsLimiter{}
+		}
+
+		l = &testNotificationsLimiter{limiter: newNotificationsLimiter(limits)}
+		am.testNotificationsLimiters[userID][integrationName] = l
+	}
+
+	l.lastUsed = time.Now()
+	return l.limiter
+}
+
+// deleteUnusedTestNotificationsLimiters removes the test notifications rate limiters of the tenants
+// which don't have an Alertmanager configuration anymore, and the ones which are full again.
+func (am *MultitenantAlertmanager) deleteUnusedTestNotificationsLimiters(allUsers []string) {
+	users := make(map[string]struct{}, len(allUsers))
+	for _, userID := range allUsers {
+		users[userID] = struct{}{}
+	}
+
+	am.testNotificationsLimitersMtx.Lock()
+	defer am.testNotificationsLimitersMtx.Unlock()
+
+	now := time.Now()
+	for userID, limiters := range am.testNotificationsLimiters {
+		if _, ok := users[userID]; !ok {
+			delete(am.testNotificationsLimiters, userID)
+			continue
+		}
+
+		for integrationName, l := range limiters {
+			if l.replenished(now) {
+				delete(limiters, integrationName)
+			}
+		}
+		if len(limiters) == 0 {
+			delete(am.testNotificationsLimiters, userID)
+		}
+	}
+}
+
+// sendTestNotification sends the alert through each of the input integrations concurrently
+// and returns the per-integration outcome.
+func sendTestNotification(ctx context.Context, receiver string, integrations []notify.Integration, alert *types.Alert) []TestIntegrationResult {
+	groupLabels := model.LabelSet{model.AlertNameLabel: alert.Labels[model.AlertNameLabel]}
+
+	ctx = notify.WithReceiverName(ctx, receiver)
+	ctx = notify.WithGroupKey(ctx, fmt.Sprintf("{}/test:%s", groupLabels.String()))
+	ctx = notify.WithGroupLabels(ctx, groupLabels)
+	ctx = notify.WithNow(ctx, alert.StartsAt)
+	ctx = notify.WithFiringAlerts(ctx, []uint64{uint64(alert.Fingerprint())})
+
+	results := make([]TestIntegrationResult, len(integrations))
+	wg := sync.WaitGroup{}
+	wg.Add(len(integrations))
+
+	for i := range integrations {
+		go func(i int) {
+			defer wg.Done()
+
+			integration := integrations[i]
+			integrationCtx, cancel := context.WithTimeout(ctx, testReceiverTimeout)
+			defer cancel()
+
+			start := time.Now()
+			_, err := integration.Notify(integrationCtx, alert)
+
+			results[i] = TestIntegrationResult{
+				Name:           integration.Name(),
+				Index:          integration.Index(),
+				Success:        err == nil,
+				LatencySeconds: time.Since(start).Seconds(),
+			}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,291 @@
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
+
+	"github.com/cortexproject/cortex/pkg/alertmanager/alertspb"
+	util_log "github.com/cortexproject/cortex/pkg/util/log"
+	"github.com/cortexproject/cortex/pkg/util/services"
+)
+
+const testReceiverConfig = `
+route:
+  receiver: webhook
+receivers:
+  - name: webhook
+    webhook_configs:
+      - url: %s
+      - url: %s
+`
+
+func TestMultitenantAlertmanager_TestReceiver(t *testing.T) {
+	received := atomic.NewInt32(0)
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Inc()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer okServer.Close()
+
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failingServer.Close()
+
+	cfg := fmt.Sprintf(testReceiverConfig, okServer.URL, failingServer.URL)
+	body, err := json.Marshal(map[string]interface{}{
+		"receiver":            "webhook",
+		"alertmanager_config": cfg,
+		"alert": map[string]interface{}{
+			"labels": map[string]string{"alertname": "Custom"},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		body                  string
+		storedConfig          string
+		rateLimit             rate.Limit
+		rateLimitBurst        int
+		blockPrivateAddresses bool
+		expectedStatusCode    int
+		expectedReceived      int32
+		expectedSuccess       []bool
+		expectedErrors        []string
+		expectedRateLimited   int
+	}{
+		"should send the test notification to each integration of a receiver in the provided config": {
+			body:               string(body),
+			rateLimit:          rate.Inf,
+			rateLimitBurst:     10,
+			expectedStatusCode: http.StatusOK,
+			expectedReceived:   1,
+			expectedSuccess:    []bool{true, false},
+			expectedErrors:     []string{"", "unexpected status code 400"},
+		},
+		"should send the test notification to each integration of a receiver in the stored config": {
+			body:               `receiver: webhook`,
+			storedConfig:       cfg,
+			rateLimit:          rate.Inf,
+			rateLimitBurst:     10,
+			expectedStatusCode: http.StatusOK,
+			expectedReceived:   1,
+			expectedSuccess:    []bool{true, false},
+			expectedErrors:     []string{"", "unexpected status code 400"},
+		},
+		"should honor the notifications rate-limit": {
+			body:                string(body),
+			rateLimit:           0,
+			rateLimitBurst:      0,
+			expectedStatusCode:  http.StatusOK,
+			expectedSuccess:     []bool{false, false},
+			expectedErrors:      []string{errRateLimited.Error(), errRateLimited.Error()},
+			expectedRateLimited: 2,
+		},
+		"should honor the receivers firewall": {
+			body:                  string(body),
+			rateLimit:             rate.Inf,
+			rateLimitBurst:        10,
+			blockPrivateAddresses: true,
+			expectedStatusCode:    http.StatusOK,
+			expectedSuccess:       []bool{false, false},
+			expectedErrors:        []string{"blocked address", "blocked address"},
+		},
+		"should return 404 if the receiver does not exist": {
+			body:               `receiver: unknown`,
+			storedConfig:       cfg,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		"should return 404 if the tenant has no config": {
+			body:               `receiver: webhook`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		"should return 400 if the receiver name is missing": {
+			body:               `alert: {}`,
+			storedConfig:       cfg,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			received.Store(0)
+
+			store := prepareInMemoryAlertStore()
+			if testData.storedConfig != "" {
+				require.NoError(t, store.SetAlertConfig(context.Background(), alertspb.AlertConfigDesc{User: "user-1", RawConfig: testData.storedConfig}))
+			}
+
+			limits := &mockAlertManagerLimits{
+				emailNotificationRateLimit: testData.rateLimit,
+				emailNotificationBurst:     testData.rateLimitBurst,
+				blockPrivateAddresses:      testData.blockPrivateAddresses,
+			}
+
+			reg := prometheus.NewPedanticRegistry()
+			am, err := createMultitenantAlertmanager(mockAlertmanagerConfig(t), nil, nil, store, nil, limits, util_log.Logger, reg)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/alerts/receivers/test", bytes.NewReader([]byte(testData.body)))
+			req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+			w := httptest.NewRecorder()
+			am.TestReceiver(w, req)
+
+			resp := w.Result()
+			require.Equal(t, testData.expectedStatusCode, resp.StatusCode)
+			if testData.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			actual := TestReceiverResponse{}
+			require.NoError(t, json.Unmarshal(respBody, &actual))
+			assert.Equal(t, "webhook", actual.Receiver)
+			require.Len(t, actual.Integrations, len(testData.expectedSuccess))
+
+			for i, res := range actual.Integrations {
+				assert.Equal(t, "webhook", res.Name)
+				assert.Equal(t, i, res.Index)
+				assert.Equal(t, testData.expectedSuccess[i], res.Success)
+				assert.Contains(t, res.Error, testData.expectedErrors[i])
+				assert.GreaterOrEqual(t, res.LatencySeconds, float64(0))
+			}
+
+			assert.Equal(t, testData.expectedReceived, received.Load())
+			assert.Equal(t, float64(testData.expectedRateLimited), testutil.ToFloat64(am.testNotificationsRateLimited.WithLabelValues("webhook")))
+		})
+	}
+}
+
+func TestMultitenantAlertmanager_TestReceiverWithRunningAlertmanager(t *testing.T) {
+	received := atomic.NewInt32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Inc()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := prepareInMemoryAlertStore()
+	require.NoError(t, store.SetAlertConfig(context.Background(), alertspb.AlertConfigDesc{
+		User:      "user-1",
+		RawConfig: fmt.Sprintf(testReceiverConfig, server.URL, server.URL),
+	}))
+
+	// Allow a single notification, so that we can check the running Alertmanager rate-limits are honored.
+	limits := &mockAlertManagerLimits{emailNotificationRateLimit: rate.Limit(0.0001), emailNotificationBurst: 1}
+
+	am, err := createMultitenantAlertmanager(mockAlertmanagerConfig(t), nil, nil, store, nil, limits, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), am))
+	defer services.StopAndAwaitTerminated(context.Background(), am) //nolint:errcheck
+
+	require.Len(t, am.alertmanagers, 1)
+
+	for _, expectedSuccess := range []bool{true, false} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/alerts/receivers/test", bytes.NewReader([]byte(`receiver: webhook`)))
+		req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+		w := httptest.NewRecorder()
+		am.TestReceiver(w, req)
+
+		resp := w.Result()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		actual := TestReceiverResponse{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&actual))
+		require.Len(t, actual.Integrations, 2)
+		for _, res := range actual.Integrations {
+			assert.Equal(t, expectedSuccess, res.Success)
+		}
+	}
+
+	assert.Equal(t, int32(2), received.Load())
+}
+
+func TestMultitenantAlertmanager_TestReceiverShouldEnforceRateLimitsAcrossRequests(t *testing.T) {
+	received := atomic.NewInt32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Inc()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// The same receiver is configured twice with different names.
+	store := prepareInMemoryAlertStore()
+	require.NoError(t, store.SetAlertConfig(context.Background(), alertspb.AlertConfigDesc{
+		User: "user-1",
+		RawConfig: fmt.Sprintf(testReceiverConfig, server.URL, server.URL) + fmt.Sprintf(`
+  - name: webhook-renamed
+    webhook_configs:
+      - url: %s
+      - url: %s
+`, server.URL, server.URL),
+	}))
+
+	// Allow a single request to notify both webhooks, so that the second request gets rate-limited
+	// even if it's sent to a receiver with a different name.
+	limits := &mockAlertManagerLimits{emailNotificationRateLimit: rate.Limit(0.0001), emailNotificationBurst: 2}
+
+	am, err := createMultitenantAlertmanager(mockAlertmanagerConfig(t), nil, nil, store, nil, limits, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+
+	for _, testData := range []struct {
+		receiver        string
+		expectedSuccess bool
+	}{
+		{receiver: "webhook", expectedSuccess: true},
+		{receiver: "webhook-renamed", expectedSuccess: false},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/alerts/receivers/test", bytes.NewReader([]byte("receiver: "+testData.receiver)))
+		req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+		w := httptest.NewRecorder()
+		am.TestReceiver(w, req)
+
+		resp := w.Result()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		actual := TestReceiverResponse{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&actual))
+		require.Len(t, actual.Integrations, 2)
+		for _, res := range actual.Integrations {
+			assert.Equal(t, testData.expectedSuccess, res.Success)
+		}
+	}
+
+	assert.Equal(t, int32(2), received.Load())
+	assert.Equal(t, float64(2), testutil.ToFloat64(am.testNotificationsRateLimited.WithLabelValues("webhook")))
+
+	// A single limiter is tracked for the integration type.
+	require.Len(t, am.testNotificationsLimiters["user-1"], 1)
+
+	// The limiters which are not full again are kept.
+	am.deleteUnusedTestNotificationsLimiters([]string{"user-1"})
+	require.Len(t, am.testNotificationsLimiters["user-1"], 1)
+
+	// The limiters which are full again are removed.
+	am.testNotificationsLimiters["user-1"]["webhook"].lastUsed = time.Now().Add(-time.Duration(float64(limits.emailNotificationBurst)/0.0001) * time.Second)
+	am.deleteUnusedTestNotificationsLimiters([]string{"user-1"})
+	assert.Empty(t, am.testNotificationsLimiters)
+
+	// The limiters of the tenants without a config anymore are removed.
+	am.testNotificationsLimiter("user-1", "webhook", &tenantRateLimits{tenant: "user-1", limits: limits, integration: "webhook"})
+	am.deleteUnusedTestNotificationsLimiters(nil)
+	assert.Empty(t, am.testNotificationsLimiters)
+}
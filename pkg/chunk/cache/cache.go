@@ -34,6 +34,7 @@ type Config struct {
 	MemcacheClient MemcachedClientConfig `yaml:"memcached_client"`
 	Redis          RedisConfig           `yaml:"redis"`
 	Fifocache      FifoCacheConfig       `yaml:"fifocache"`
+	DiskCache      DiskCacheConfig       `yaml:"diskcache"`
 
 	// This is to name the cache metrics properly.
 	Prefix string `yaml:"prefix" doc:"hidden"`
@@ -49,6 +50,7 @@ func (cfg *Config) RegisterFlagsWithPrefix(prefix string, description string, f
 	cfg.MemcacheClient.RegisterFlagsWithPrefix(prefix, description, f)
 	cfg.Redis.RegisterFlagsWithPrefix(prefix, description, f)
 	cfg.Fifocache.RegisterFlagsWithPrefix(prefix, description, f)
+	cfg.DiskCache.RegisterFlagsWithPrefix(prefix, description, f)
 
 	f.BoolVar(&cfg.EnableFifoCache, prefix+"cache.enable-fifocache", false, description+"Enable in-memory cache.")
 	f.DurationVar(&cfg.DefaultValidity, prefix+"default-validity", 0, description+"The default validity of entries for caches unless overridden.")
@@ -57,7 +59,10 @@ func (cfg *Config) RegisterFlagsWithPrefix(prefix string, description string, f
 }
 
 func (cfg *Config) Validate() error {
-	return cfg.Fifocache.Validate()
+	if err := cfg.Fifocache.Validate(); err != nil {
+		return err
+	}
+	return cfg.DiskCache.Validate()
 }
 
 // New creates a new Cache using Config.
@@ -78,6 +83,19 @@ func New(cfg Config, reg prometheus.Registerer, logger log.Logger) (Cache, error
 		}
 	}
 
+	if cfg.DiskCache.Directory != "" {
+		if cfg.DiskCache.Validity == 0 && cfg.DefaultValidity != 0 {
+			cfg.DiskCache.Validity = cfg.DefaultValidity
+		}
+
+		cacheName := cfg.Prefix + "diskcache"
+		cache, err := NewDiskCache(cacheName, cfg.DiskCache, reg, logger)
+		if err != nil {
+			return nil, err
+		}
+		caches = append(caches, Instrument(cacheName, cache, reg))
+	}
+
 	if (cfg.MemcacheClient.Host != "" || cfg.MemcacheClient.Addresses != "") && cfg.Redis.Endpoint != "" {
 		return nil, errors.New("use of multiple cache storage systems is not supported")
 	}
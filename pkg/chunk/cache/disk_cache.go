@@ -0,0 +1,538 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	util_log "github.com/cortexproject/cortex/pkg/util/log"
+)
+
+const (
+	diskCacheTmpFilePrefix = ".tmp-"
+
+	// Each file starts with the magic number, followed by the CRC32 of the rest of the file,
+	// the expiration (unix nanoseconds, 0 if never expires), the key length and the key.
+	diskCacheHeaderSize = 4 + 4 + 8 + 4
+
+	// The access time of an entry file is updated on hits at most once per interval, to preserve
+	// the LRU order across restarts without writing to the disk on each hit.
+	diskCacheTouchInterval = time.Minute
+)
+
+var (
+	diskCacheMagic        = []byte("CDC1")
+	diskCacheCastagnoli   = crc32.MakeTable(crc32.Castagnoli)
+	errDiskCacheCorrupted = errors.New("corrupted cache entry")
+	errDiskCacheMaxSize   = errors.New("the disk cache max size must be greater than 0 when the disk cache is enabled")
+	errDiskCacheWriteBack = errors.New("the disk cache write-back concurrency and buffer must not be negative")
+)
+
+// DiskCacheConfig holds config for the DiskCache.
+type DiskCacheConfig struct {
+	Directory    string        `yaml:"directory"`
+	MaxSizeBytes uint64        `yaml:"max_size_bytes"`
+	Validity     time.Duration `yaml:"validity"`
+
+	WriteBackGoroutines int `yaml:"writeback_goroutines"`
+	WriteBackBuffer     int `yaml:"writeback_buffer"`
+}
+
+// RegisterFlagsWithPrefix adds the flags required to config this to the given FlagSet
+func (cfg *DiskCacheConfig) RegisterFlagsWithPrefix(prefix, description string, f *flag.FlagSet) {
+	f.StringVar(&cfg.Directory, prefix+"diskcache.directory", "", description+"Directory where the on-disk cache stores its entries (eg. on a local SSD). The entries are kept across restarts. If empty, the disk cache is disabled.")
+	f.Uint64Var(&cfg.MaxSizeBytes, prefix+"diskcache.max-size-bytes", 10*1024*1024*1024, description+"Maximum size of the on-disk cache in bytes. The least recently used entries are evicted once the limit is reached.")
+	f.DurationVar(&cfg.Validity, prefix+"diskcache.duration", 0, description+"The expiry duration for the on-disk cache. 0 to never expire entries.")
+	f.IntVar(&cfg.WriteBackGoroutines, prefix+"diskcache.write-back-concurrency", 4, description+"At what concurrency to write entries to the on-disk cache in background. 0 to write the entries synchronously.")
+	f.IntVar(&cfg.WriteBackBuffer, prefix+"diskcache.write-back-buffer", 10000, description+"How many key batches to buffer for background writes to the on-disk cache. Writes are dropped when the buffer is full.")
+}
+
+func (cfg *DiskCacheConfig) Validate() error {
+	if cfg.Directory != "" && cfg.MaxSizeBytes == 0 {
+		return errDiskCacheMaxSize
+	}
+	if cfg.WriteBackGoroutines < 0 || cfg.WriteBackBuffer < 0 {
+		return errDiskCacheWriteBack
+	}
+	return nil
+}
+
+// DiskCache is a bounded LRU cache storing each entry in a file within a local directory.
+// Each entry is stored along with its key and a checksum, so that corrupted entries are
+// detected (and removed) on read. The cache content survives restarts: at startup the
+// entries are loaded from the directory, ordered by their last access time. Entries are
+// written in background, unless the write-back concurrency is 0.
+type DiskCache struct {
+	logger        log.Logger
+	dir           string
+	maxSizeBytes  uint64
+	validity      time.Duration
+	touchInterval time.Duration
+
+	writeBack bool
+	wg        sync.WaitGroup
+	quit      chan struct{}
+	bgWrites  chan diskCacheWrite
+
+	lock      sync.Mutex
+	entries   map[string]*list.Element
+	lru       *list.List
+	sizeBytes uint64
+
+	entriesAdded     prometheus.Counter
+	entriesEvicted   prometheus.Counter
+	entriesCurrent   prometheus.Gauge
+	entriesCorrupted prometheus.Counter
+	totalGets        prometheus.Counter
+	totalMisses      prometheus.Counter
+	diskBytes        prometheus.Gauge
+	droppedWrites    prometheus.Counter
+}
+
+type diskCacheEntry struct {
+	// The entry ID is the hash of the key, and it's used as file name.
+	id   string
+	size uint64
+
+	// When the entry file access time has been last updated.
+	touched time.Time
+}
+
+type diskCacheWrite struct {
+	keys []string
+	bufs [][]byte
+	ttl  time.Duration
+}
+
+// NewDiskCache returns a new DiskCache, loading the entries previously stored in the directory.
+func NewDiskCache(name string, cfg DiskCacheConfig, reg prometheus.Registerer, logger log.Logger) (*DiskCache, error) {
+	util_log.WarnExperimentalUse("On-disk cache")
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(cfg.Directory, os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "create disk cache directory")
+	}
+
+	c := &DiskCache{
+		logger:        log.With(logger, "cache", name),
+		dir:           cfg.Directory,
+		maxSizeBytes:  cfg.MaxSizeBytes,
+		validity:      cfg.Validity,
+		touchInterval: diskCacheTouchInterval,
+		writeBack:     cfg.WriteBackGoroutines > 0,
+		quit:          make(chan struct{}),
+		bgWrites:      make(chan diskCacheWrite, cfg.WriteBackBuffer),
+		entries:       make(map[string]*list.Element),
+		lru:           list.New(),
+
+		entriesAdded: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace:   "cortex",
+			Subsystem:   "diskcache",
+			Name:        "added_total",
+			Help:        "The total number of entries added to the on-disk cache",
+			ConstLabels: prometheus.Labels{"cache": name},
+		}),
+		entriesEvicted: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace:   "cortex",
+			Subsystem:   "diskcache",
+			Name:        "evicted_total",
+			Help:        "The total number of entries evicted from the on-disk cache",
+			ConstLabels: prometheus.Labels{"cache": name},
+		}),
+		entriesCurrent: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Namespace:   "cortex",
+			Subsystem:   "diskcache",
+			Name:        "entries",
+			Help:        "The current number of entries in the on-disk cache",
+			ConstLabels: prometheus.Labels{"cache": name},
+		}),
+		entriesCorrupted: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace:   "cortex",
+			Subsystem:   "diskcache",
+			Name:        "corrupted_total",
+			Help:        "The total number of corrupted entries found (and removed) in the on-disk cache",
+			ConstLabels: prometheus.Labels{"cache": name},
+		}),
+		totalGets: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace:   "cortex",
+			Subsystem:   "diskcache",
+			Name:        "gets_total",
+			Help:        "The total number of Get calls on the on-disk cache",
+			ConstLabels: prometheus.Labels{"cache": name},
+		}),
+		totalMisses: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace:   "cortex",
+			Subsystem:   "diskcache",
+			Name:        "misses_total",
+			Help:        "The total number of Get calls on the on-disk cache that had no valid entry",
+			ConstLabels: prometheus.Labels{"cache": name},
+		}),
+		diskBytes: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Namespace:   "cortex",
+			Subsystem:   "diskcache",
+			Name:        "size_bytes",
+			Help:        "The current size of the on-disk cache in bytes",
+			ConstLabels: prometheus.Labels{"cache": name},
+		}),
+		droppedWrites: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace:   "cortex",
+			Subsystem:   "diskcache",
+			Name:        "dropped_writes_total",
+			Help:        "The total number of key batches not written to the on-disk cache because the write-back buffer was full",
+			ConstLabels: prometheus.Labels{"cache": name},
+		}),
+	}
+
+	if err := c.load(); err != nil {
+		return nil, errors.Wrap(err, "load disk cache entries")
+	}
+
+	c.wg.Add(cfg.WriteBackGoroutines)
+	for i := 0; i < cfg.WriteBackGoroutines; i++ {
+		go c.writeBackLoop()
+	}
+
+	return c, nil
+}
+
+// load the entries stored in the directory, ordered by last access (modification) time.
+func (c *DiskCache) load() error {
+	type loadedEntry struct {
+		diskCacheEntry
+		accessed time.Time
+	}
+
+	var loaded []loadedEntry
+
+	err := filepath.Walk(c.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		// Remove leftovers of writes interrupted by a crash.
+		if strings.HasPrefix(info.Name(), diskCacheTmpFilePrefix) {
+			if err := os.Remove(path); err != nil {
+				level.Warn(c.logger).Log("msg", "failed to remove temporary disk cache file", "path", path, "err", err)
+			}
+			return nil
+		}
+
+		if filepath.Dir(path) != c.entryDir(info.Name()) {
+			// Not a cache entry.
+			return nil
+		}
+
+		loaded = append(loaded, loadedEntry{diskCacheEntry: diskCacheEntry{id: info.Name(), size: uint64(info.Size()), touched: info.ModTime()}, accessed: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(loaded, func(i, j int) bool {
+		return loaded[i].accessed.Before(loaded[j].accessed)
+	})
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, entry := range loaded {
+		entry := entry.diskCacheEntry
+		c.entries[entry.id] = c.lru.PushFront(&entry)
+		c.sizeBytes += entry.size
+	}
+	c.evict()
+	c.entriesCurrent.Set(float64(c.lru.Len()))
+	c.diskBytes.Set(float64(c.sizeBytes))
+
+	level.Info(c.logger).Log("msg", "loaded disk cache entries", "entries", c.lru.Len(), "bytes", c.sizeBytes)
+	return nil
+}
+
+// Store implements Cache.
+func (c *DiskCache) Store(_ context.Context, keys []string, bufs [][]byte) {
+	c.StoreWithTTL(keys, bufs, c.validity)
+}
+
+// StoreWithTTL stores the input entries with the given TTL (0 to never expire the entries).
+func (c *DiskCache) StoreWithTTL(keys []string, bufs [][]byte, ttl time.Duration) {
+	if !c.writeBack {
+		c.write(keys, bufs, ttl)
+		return
+	}
+
+	select {
+	case c.bgWrites <- diskCacheWrite{keys: keys, bufs: bufs, ttl: ttl}:
+	default:
+		c.droppedWrites.Inc()
+	}
+}
+
+func (c *DiskCache) write(keys []string, bufs [][]byte, ttl time.Duration) {
+	for i := range keys {
+		if err := c.put(keys[i], bufs[i], ttl); err != nil {
+			level.Warn(c.logger).Log("msg", "failed to store entry to disk cache", "key", keys[i], "err", err)
+		}
+	}
+}
+
+// Fetch implements Cache.
+func (c *DiskCache) Fetch(_ context.Context, keys []string) (found []string, bufs [][]byte, missing []string) {
+	found, missing, bufs = make([]string, 0, len(keys)), make([]string, 0, len(keys)), make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		val, ok := c.get(key)
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+
+		found = append(found, key)
+		bufs = append(bufs, val)
+	}
+	return
+}
+
+// Stop implements Cache. The background writes are stopped, and the entries are kept on disk.
+func (c *DiskCache) Stop() {
+	close(c.quit)
+	c.wg.Wait()
+}
+
+func (c *DiskCache) writeBackLoop() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case w := <-c.bgWrites:
+			c.write(w.keys, w.bufs, w.ttl)
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func (c *DiskCache) put(key string, value []byte, ttl time.Duration) error {
+	var expiration int64
+	if ttl > 0 {
+		expiration = time.Now().Add(ttl).UnixNano()
+	}
+
+	data := encodeDiskCacheEntry(key, value, expiration)
+	size := uint64(len(data))
+	if size > c.maxSizeBytes {
+		// Cannot keep this item in the cache.
+		return nil
+	}
+
+	id := diskCacheEntryID(key)
+	dir := c.entryDir(id)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// Write to a temporary file and then rename it, so that partially written
+	// entries are never read.
+	tmp, err := ioutil.TempFile(dir, diskCacheTmpFilePrefix)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, id)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if element, ok := c.entries[id]; ok {
+		entry := element.Value.(*diskCacheEntry)
+		c.sizeBytes = c.sizeBytes - entry.size + size
+		entry.size = size
+		entry.touched = time.Now()
+		c.lru.MoveToFront(element)
+	} else {
+		c.entries[id] = c.lru.PushFront(&diskCacheEntry{id: id, size: size, touched: time.Now()})
+		c.sizeBytes += size
+	}
+
+	c.entriesAdded.Inc()
+	c.evict()
+	c.entriesCurrent.Set(float64(c.lru.Len()))
+	c.diskBytes.Set(float64(c.sizeBytes))
+	return nil
+}
+
+// evict the least recently used entries until the cache size is within the limit.
+// The caller must hold the lock.
+func (c *DiskCache) evict() {
+	for c.sizeBytes > c.maxSizeBytes {
+		element := c.lru.Back()
+		if element == nil {
+			break
+		}
+
+		c.removeEntry(element)
+		c.entriesEvicted.Inc()
+	}
+}
+
+// removeEntry removes an entry from the cache, including its file. The caller must hold the lock.
+func (c *DiskCache) removeEntry(element *list.Element) {
+	entry := c.lru.Remove(element).(*diskCacheEntry)
+	delete(c.entries, entry.id)
+	c.sizeBytes -= entry.size
+
+	if err := os.Remove(filepath.Join(c.entryDir(entry.id), entry.id)); err != nil && !os.IsNotExist(err) {
+		level.Warn(c.logger).Log("msg", "failed to remove disk cache entry", "id", entry.id, "err", err)
+	}
+}
+
+func (c *DiskCache) get(key string) ([]byte, bool) {
+	c.totalGets.Inc()
+
+	id := diskCacheEntryID(key)
+
+	now := time.Now()
+	touch := false
+
+	c.lock.Lock()
+	element, ok := c.entries[id]
+	if ok {
+		c.lru.MoveToFront(element)
+
+		// Only update the file access time once in a while, to not write to the disk on each hit.
+		if entry := element.Value.(*diskCacheEntry); now.Sub(entry.touched) >= c.touchInterval {
+			entry.touched = now
+			touch = true
+		}
+	}
+	c.lock.Unlock()
+
+	if !ok {
+		c.totalMisses.Inc()
+		return nil, false
+	}
+
+	path := filepath.Join(c.entryDir(id), id)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		// The entry may have been concurrently evicted.
+		c.totalMisses.Inc()
+		if os.IsNotExist(err) {
+			c.remove(id, element)
+		}
+		return nil, false
+	}
+
+	storedKey, value, expiration, err := decodeDiskCacheEntry(data)
+	if err != nil || storedKey != key {
+		level.Warn(c.logger).Log("msg", "removing corrupted disk cache entry", "id", id, "err", err)
+		c.entriesCorrupted.Inc()
+		c.totalMisses.Inc()
+		c.remove(id, element)
+		return nil, false
+	}
+
+	if expiration > 0 && now.UnixNano() > expiration {
+		c.totalMisses.Inc()
+		c.remove(id, element)
+		return nil, false
+	}
+
+	// Keep track of the access time, so that the LRU order is preserved across restarts.
+	if touch {
+		_ = os.Chtimes(path, now, now)
+	}
+
+	return value, true
+}
+
+// remove the entry from the cache, if it's still the input one.
+func (c *DiskCache) remove(id string, element *list.Element) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.entries[id] == element {
+		c.removeEntry(element)
+		c.entriesCurrent.Set(float64(c.lru.Len()))
+		c.diskBytes.Set(float64(c.sizeBytes))
+	}
+}
+
+// entryDir returns the directory of an entry. Entries are spread across sub-directories
+// to avoid having too many files in a single directory.
+func (c *DiskCache) entryDir(id string) string {
+	if len(id) < 2 {
+		return c.dir
+	}
+	return filepath.Join(c.dir, id[:2])
+}
+
+func diskCacheEntryID(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func encodeDiskCacheEntry(key string, value []byte, expiration int64) []byte {
+	data := make([]byte, diskCacheHeaderSize+len(key)+len(value))
+	copy(data, diskCacheMagic)
+	binary.LittleEndian.PutUint64(data[8:], uint64(expiration))
+	binary.LittleEndian.PutUint32(data[16:], uint32(len(key)))
+	copy(data[diskCacheHeaderSize:], key)
+	copy(data[diskCacheHeaderSize+len(key):], value)
+	binary.LittleEndian.PutUint32(data[4:], crc32.Checksum(data[8:], diskCacheCastagnoli))
+	return data
+}
+
+func decodeDiskCacheEntry(data []byte) (key string, value []byte, expiration int64, err error) {
+	if len(data) < diskCacheHeaderSize || !bytes.Equal(data[:4], diskCacheMagic) {
+		return "", nil, 0, errDiskCacheCorrupted
+	}
+	if binary.LittleEndian.Uint32(data[4:]) != crc32.Checksum(data[8:], diskCacheCastagnoli) {
+		return "", nil, 0, errDiskCacheCorrupted
+	}
+
+	expiration = int64(binary.LittleEndian.Uint64(data[8:]))
+	keyLen := int(binary.LittleEndian.Uint32(data[16:]))
+	if len(data) < diskCacheHeaderSize+keyLen {
+		return "", nil, 0, errDiskCacheCorrupted
+	}
+
+	key = string(data[diskCacheHeaderSize : diskCacheHeaderSize+keyLen])
+	value = data[diskCacheHeaderSize+keyLen:]
+	return key, value, expiration, nil
+}
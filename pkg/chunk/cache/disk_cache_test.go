@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cortexproject/cortex/pkg/util/test"
+)
+
+func TestDiskCache_StoreAndFetch(t *testing.T) {
+	c, err := NewDiskCache("test", DiskCacheConfig{Directory: t.TempDir(), MaxSizeBytes: 1024 * 1024}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c.Store(ctx, []string{"key-1", "key-2"}, [][]byte{[]byte("value-1"), []byte("value-2")})
+
+	found, bufs, missing := c.Fetch(ctx, []string{"key-1", "key-2", "key-3"})
+	assert.Equal(t, []string{"key-1", "key-2"}, found)
+	assert.Equal(t, [][]byte{[]byte("value-1"), []byte("value-2")}, bufs)
+	assert.Equal(t, []string{"key-3"}, missing)
+
+	// Overwrite an entry.
+	c.Store(ctx, []string{"key-1"}, [][]byte{[]byte("updated")})
+	found, bufs, missing = c.Fetch(ctx, []string{"key-1"})
+	assert.Equal(t, []string{"key-1"}, found)
+	assert.Equal(t, [][]byte{[]byte("updated")}, bufs)
+	assert.Empty(t, missing)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.entriesCurrent))
+	assert.Equal(t, float64(4), testutil.ToFloat64(c.totalGets))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.totalMisses))
+}
+
+func TestDiskCache_Eviction(t *testing.T) {
+	const cnt = 10
+
+	entrySize := uint64(len(encodeDiskCacheEntry("key-00", []byte("value-00"), 0)))
+	c, err := NewDiskCache("test", DiskCacheConfig{Directory: t.TempDir(), MaxSizeBytes: 5 * entrySize}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < cnt; i++ {
+		c.Store(ctx, []string{fmt.Sprintf("key-%02d", i)}, [][]byte{[]byte(fmt.Sprintf("value-%02d", i))})
+
+		// Keep the first entry hot, so that it's never evicted.
+		found, _, _ := c.Fetch(ctx, []string{"key-00"})
+		require.Equal(t, []string{"key-00"}, found)
+	}
+
+	assert.Equal(t, float64(5), testutil.ToFloat64(c.entriesCurrent))
+	assert.Equal(t, float64(cnt-5), testutil.ToFloat64(c.entriesEvicted))
+	assert.Equal(t, float64(5*entrySize), testutil.ToFloat64(c.diskBytes))
+
+	found, _, missing := c.Fetch(ctx, []string{"key-00", "key-01", "key-05", "key-06", "key-09"})
+	assert.Equal(t, []string{"key-00", "key-06", "key-09"}, found)
+	assert.Equal(t, []string{"key-01", "key-05"}, missing)
+
+	// Evicted entries must be removed from disk too.
+	assert.Equal(t, 5, countDiskCacheFiles(t, c.dir))
+}
+
+func TestDiskCache_ShouldDetectCorruptedEntries(t *testing.T) {
+	c, err := NewDiskCache("test", DiskCacheConfig{Directory: t.TempDir(), MaxSizeBytes: 1024 * 1024}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c.Store(ctx, []string{"key-1", "key-2"}, [][]byte{[]byte("value-1"), []byte("value-2")})
+
+	// Flip a byte of the first entry's value.
+	id := diskCacheEntryID("key-1")
+	path := filepath.Join(c.entryDir(id), id)
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	require.NoError(t, ioutil.WriteFile(path, data, 0644))
+
+	found, bufs, missing := c.Fetch(ctx, []string{"key-1", "key-2"})
+	assert.Equal(t, []string{"key-2"}, found)
+	assert.Equal(t, [][]byte{[]byte("value-2")}, bufs)
+	assert.Equal(t, []string{"key-1"}, missing)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.entriesCorrupted))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.entriesCurrent))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDiskCache_ShouldExpireEntries(t *testing.T) {
+	c, err := NewDiskCache("test", DiskCacheConfig{Directory: t.TempDir(), MaxSizeBytes: 1024 * 1024}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.StoreWithTTL([]string{"key-1", "key-2"}, [][]byte{[]byte("value-1"), []byte("value-2")}, 0)
+	c.StoreWithTTL([]string{"key-3"}, [][]byte{[]byte("value-3")}, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	found, _, missing := c.Fetch(context.Background(), []string{"key-1", "key-2", "key-3"})
+	assert.Equal(t, []string{"key-1", "key-2"}, found)
+	assert.Equal(t, []string{"key-3"}, missing)
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.entriesCurrent))
+}
+
+func TestDiskCache_ShouldSurviveRestarts(t *testing.T) {
+	dir := t.TempDir()
+	entrySize := uint64(len(encodeDiskCacheEntry("key-0", []byte("value-0"), 0)))
+	cfg := DiskCacheConfig{Directory: dir, MaxSizeBytes: 3 * entrySize}
+	ctx := context.Background()
+
+	c, err := NewDiskCache("test", cfg, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	c.touchInterval = 0
+
+	for i := 0; i < 3; i++ {
+		c.Store(ctx, []string{fmt.Sprintf("key-%d", i)}, [][]byte{[]byte(fmt.Sprintf("value-%d", i))})
+	}
+
+	// Make sure the access times are different, so that the LRU order is preserved on restart.
+	id := diskCacheEntryID("key-0")
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(c.entryDir(id), id), past, past))
+	found, _, _ := c.Fetch(ctx, []string{"key-0"})
+	require.Equal(t, []string{"key-0"}, found)
+	c.Stop()
+
+	// Simulate a write interrupted by a crash.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, id[:2], diskCacheTmpFilePrefix+"123"), []byte("partial"), 0644))
+
+	c, err = NewDiskCache("test", cfg, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, float64(3), testutil.ToFloat64(c.entriesCurrent))
+	assert.Equal(t, float64(3*entrySize), testutil.ToFloat64(c.diskBytes))
+	assert.Equal(t, 3, countDiskCacheFiles(t, dir))
+
+	found, bufs, missing := c.Fetch(ctx, []string{"key-0", "key-1", "key-2"})
+	assert.Equal(t, []string{"key-0", "key-1", "key-2"}, found)
+	assert.Equal(t, [][]byte{[]byte("value-0"), []byte("value-1"), []byte("value-2")}, bufs)
+	assert.Empty(t, missing)
+}
+
+func TestDiskCache_ShouldEvictOnStartupWhenMaxSizeIsReduced(t *testing.T) {
+	dir := t.TempDir()
+	entrySize := uint64(len(encodeDiskCacheEntry("key-0", []byte("value-0"), 0)))
+	ctx := context.Background()
+
+	c, err := NewDiskCache("test", DiskCacheConfig{Directory: dir, MaxSizeBytes: 3 * entrySize}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		c.Store(ctx, []string{fmt.Sprintf("key-%d", i)}, [][]byte{[]byte(fmt.Sprintf("value-%d", i))})
+
+		id := diskCacheEntryID(fmt.Sprintf("key-%d", i))
+		accessed := time.Now().Add(time.Duration(i-10) * time.Minute)
+		require.NoError(t, os.Chtimes(filepath.Join(c.entryDir(id), id), accessed, accessed))
+	}
+
+	c, err = NewDiskCache("test", DiskCacheConfig{Directory: dir, MaxSizeBytes: entrySize}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.entriesCurrent))
+	assert.Equal(t, 1, countDiskCacheFiles(t, dir))
+
+	found, _, missing := c.Fetch(ctx, []string{"key-0", "key-1", "key-2"})
+	assert.Equal(t, []string{"key-2"}, found)
+	assert.Equal(t, []string{"key-0", "key-1"}, missing)
+}
+
+func TestDiskCache_ShouldWriteEntriesInBackground(t *testing.T) {
+	c, err := NewDiskCache("test", DiskCacheConfig{Directory: t.TempDir(), MaxSizeBytes: 1024 * 1024, WriteBackGoroutines: 1, WriteBackBuffer: 10}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	defer c.Stop()
+
+	ctx := context.Background()
+	c.Store(ctx, []string{"key-1", "key-2"}, [][]byte{[]byte("value-1"), []byte("value-2")})
+
+	test.Poll(t, time.Second, []string{"key-1", "key-2"}, func() interface{} {
+		found, _, _ := c.Fetch(ctx, []string{"key-1", "key-2"})
+		return found
+	})
+	assert.Equal(t, float64(0), testutil.ToFloat64(c.droppedWrites))
+}
+
+func TestDiskCache_ShouldDropWritesWhenTheWriteBackBufferIsFull(t *testing.T) {
+	c, err := NewDiskCache("test", DiskCacheConfig{Directory: t.TempDir(), MaxSizeBytes: 1024 * 1024, WriteBackGoroutines: 1, WriteBackBuffer: 1}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+
+	// Stop the background writes, so that the buffer is never drained.
+	c.Stop()
+
+	ctx := context.Background()
+	c.Store(ctx, []string{"key-1"}, [][]byte{[]byte("value-1")})
+	c.Store(ctx, []string{"key-2"}, [][]byte{[]byte("value-2")})
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.droppedWrites))
+	assert.Equal(t, float64(0), testutil.ToFloat64(c.entriesCurrent))
+}
+
+func TestDiskCache_ShouldNotUpdateTheAccessTimeOnEachHit(t *testing.T) {
+	c, err := NewDiskCache("test", DiskCacheConfig{Directory: t.TempDir(), MaxSizeBytes: 1024 * 1024}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c.Store(ctx, []string{"key-1"}, [][]byte{[]byte("value-1")})
+
+	id := diskCacheEntryID("key-1")
+	path := filepath.Join(c.entryDir(id), id)
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(path, past, past))
+
+	// The entry has just been written, so the access time is not updated.
+	found, _, _ := c.Fetch(ctx, []string{"key-1"})
+	require.Equal(t, []string{"key-1"}, found)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(past))
+
+	// Once the touch interval has elapsed, the access time is updated on the next hit.
+	c.touchInterval = 0
+	found, _, _ = c.Fetch(ctx, []string{"key-1"})
+	require.Equal(t, []string{"key-1"}, found)
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().After(past))
+}
+
+func countDiskCacheFiles(t *testing.T, dir string) int {
+	count := 0
+	require.NoError(t, filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			count++
+		}
+		return nil
+	}))
+	return count
+}
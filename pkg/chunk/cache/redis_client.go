@@ -89,6 +89,11 @@ func (c *RedisClient) Ping(ctx context.Context) error {
 }
 
 func (c *RedisClient) MSet(ctx context.Context, keys []string, values [][]byte) error {
+	return c.MSetWithTTL(ctx, keys, values, c.expiration)
+}
+
+// MSetWithTTL is like MSet but stores the keys with the input TTL instead of the configured expiration.
+func (c *RedisClient) MSetWithTTL(ctx context.Context, keys []string, values [][]byte, ttl time.Duration) error {
 	var cancel context.CancelFunc
 	if c.timeout > 0 {
 		ctx, cancel = context.WithTimeout(ctx, c.timeout)
@@ -97,7 +102,7 @@ func (c *RedisClient) MSet(ctx context.Context, keys []string, values [][]byte)
 
 	pipe := c.rdb.TxPipeline()
 	for i := range keys {
-		pipe.Set(ctx, keys[i], values[i], c.expiration)
+		pipe.Set(ctx, keys[i], values[i], ttl)
 	}
 	_, err := pipe.Exec(ctx)
 	return err
@@ -647,7 +647,7 @@ func (c *Compactor) compactUser(ctx context.Context, userID string) error {
 		syncer,
 		c.blocksGrouperFactory(ctx, c.compactorCfg, bucket, ulogger, reg, c.blocksMarkedForDeletion, c.garbageCollectedBlocks),
 		c.blocksPlanner,
-		c.blocksCompactor,
+		newExemplarsCompactor(ctx, c.blocksCompactor, bucket, ulogger),
 		path.Join(c.compactorCfg.DataDir, "compact"),
 		bucket,
 		c.compactorCfg.CompactionConcurrency,
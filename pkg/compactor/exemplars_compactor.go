@@ -0,0 +1,69 @@
+package compactor
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/thanos-io/thanos/pkg/compact"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+	cortex_tsdb "github.com/cortexproject/cortex/pkg/storage/tsdb"
+)
+
+// exemplarsCompactor wraps a compactor to carry over the exemplars of the source blocks to the
+// compacted block. Exemplars are not part of the TSDB block format, so the underlying compactor
+// doesn't know about them.
+type exemplarsCompactor struct {
+	compact.Compactor
+
+	ctx    context.Context
+	bkt    objstore.Bucket
+	logger log.Logger
+}
+
+func newExemplarsCompactor(ctx context.Context, compactor compact.Compactor, bkt objstore.Bucket, logger log.Logger) *exemplarsCompactor {
+	return &exemplarsCompactor{
+		Compactor: compactor,
+		ctx:       ctx,
+		bkt:       bkt,
+		logger:    logger,
+	}
+}
+
+// Compact implements compact.Compactor. The exemplars of the source blocks, downloaded along with
+// the blocks, are merged and uploaded before the compacted block is uploaded by the caller.
+func (c *exemplarsCompactor) Compact(dest string, dirs []string, open []*tsdb.Block) (ulid.ULID, error) {
+	id, err := c.Compactor.Compact(dest, dirs, open)
+	if err != nil || id == (ulid.ULID{}) {
+		return id, err
+	}
+
+	var sets [][]cortexpb.TimeSeries
+	for _, dir := range dirs {
+		series, _, err := cortex_tsdb.ReadBlockExemplarsFile(dir)
+		if err != nil {
+			return id, errors.Wrapf(err, "read exemplars of source block %s", dir)
+		}
+
+		if len(series) > 0 {
+			sets = append(sets, series)
+		}
+	}
+
+	if len(sets) == 0 {
+		return id, nil
+	}
+
+	merged := cortex_tsdb.MergeExemplarSeries(sets...)
+	if err := cortex_tsdb.UploadBlockExemplars(c.ctx, c.bkt, id, merged); err != nil {
+		return id, errors.Wrapf(err, "upload exemplars of compacted block %s", id.String())
+	}
+
+	level.Debug(c.logger).Log("msg", "uploaded exemplars of compacted block", "block", id.String(), "series", len(merged))
+	return id, nil
+}
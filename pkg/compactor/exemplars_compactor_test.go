@@ -0,0 +1,98 @@
+package compactor
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+	cortex_tsdb "github.com/cortexproject/cortex/pkg/storage/tsdb"
+)
+
+func TestExemplarsCompactor_ShouldCarryOverExemplarsOfSourceBlocks(t *testing.T) {
+	ctx := context.Background()
+	compactedID := ulid.MustNew(3, nil)
+
+	dir, err := ioutil.TempDir(os.TempDir(), "exemplars-compactor")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, os.RemoveAll(dir))
+	})
+
+	var (
+		series1 = []cortexpb.LabelAdapter{{Name: "__name__", Value: "series_1"}}
+		series2 = []cortexpb.LabelAdapter{{Name: "__name__", Value: "series_2"}}
+		trace   = []cortexpb.LabelAdapter{{Name: "traceID", Value: "123"}}
+	)
+
+	// Create 3 source blocks, one of which has no exemplars.
+	block1Dir := filepath.Join(dir, ulid.MustNew(1, nil).String())
+	block2Dir := filepath.Join(dir, ulid.MustNew(2, nil).String())
+	block3Dir := filepath.Join(dir, ulid.MustNew(4, nil).String())
+	for _, d := range []string{block1Dir, block2Dir, block3Dir} {
+		require.NoError(t, os.Mkdir(d, os.ModePerm))
+	}
+
+	require.NoError(t, cortex_tsdb.WriteBlockExemplarsFile(block1Dir, []cortexpb.TimeSeries{
+		{Labels: series1, Exemplars: []cortexpb.Exemplar{{Labels: trace, Value: 1, TimestampMs: 10}}},
+	}))
+	require.NoError(t, cortex_tsdb.WriteBlockExemplarsFile(block2Dir, []cortexpb.TimeSeries{
+		{Labels: series1, Exemplars: []cortexpb.Exemplar{{Labels: trace, Value: 2, TimestampMs: 20}}},
+		{Labels: series2, Exemplars: []cortexpb.Exemplar{{Labels: trace, Value: 3, TimestampMs: 30}}},
+	}))
+
+	bkt := objstore.NewInMemBucket()
+	c := newExemplarsCompactor(ctx, &compactorMock{id: compactedID}, bkt, log.NewNopLogger())
+
+	id, err := c.Compact(dir, []string{block1Dir, block2Dir, block3Dir}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, compactedID, id)
+
+	actual, err := cortex_tsdb.ReadBlockExemplars(ctx, bkt, compactedID)
+	require.NoError(t, err)
+	assert.Equal(t, []cortexpb.TimeSeries{
+		{Labels: series1, Exemplars: []cortexpb.Exemplar{{Labels: trace, Value: 1, TimestampMs: 10}, {Labels: trace, Value: 2, TimestampMs: 20}}},
+		{Labels: series2, Exemplars: []cortexpb.Exemplar{{Labels: trace, Value: 3, TimestampMs: 30}}},
+	}, actual)
+}
+
+func TestExemplarsCompactor_ShouldNotUploadExemplarsIfSourceBlocksHaveNone(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "exemplars-compactor")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, os.RemoveAll(dir))
+	})
+
+	bkt := objstore.NewInMemBucket()
+	c := newExemplarsCompactor(context.Background(), &compactorMock{id: ulid.MustNew(3, nil)}, bkt, log.NewNopLogger())
+
+	_, err = c.Compact(dir, []string{dir}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, bkt.Objects())
+}
+
+// compactorMock is a compactor returning the mocked block ID from any compaction.
+type compactorMock struct {
+	id ulid.ULID
+}
+
+func (m *compactorMock) Plan(_ string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *compactorMock) Write(_ string, _ tsdb.BlockReader, _, _ int64, _ *tsdb.BlockMeta) (ulid.ULID, error) {
+	return m.id, nil
+}
+
+func (m *compactorMock) Compact(_ string, _ []string, _ []*tsdb.Block) (ulid.ULID, error) {
+	return m.id, nil
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/cortexproject/cortex/pkg/util/limiter"
 	util_log "github.com/cortexproject/cortex/pkg/util/log"
 	util_math "github.com/cortexproject/cortex/pkg/util/math"
+	"github.com/cortexproject/cortex/pkg/util/push"
 	"github.com/cortexproject/cortex/pkg/util/services"
 	"github.com/cortexproject/cortex/pkg/util/validation"
 )
@@ -79,9 +80,18 @@ type Distributor struct {
 	// For handling HA replicas.
 	HATracker *haTracker
 
+	// For forwarding samples to remote-write endpoints, if enabled.
+	forwarder *forwarder
+
 	// Per-user rate limiter.
 	ingestionRateLimiter *limiter.RateLimiter
 
+	// Set only when the shared ingestion rate strategy is used, to track the received samples.
+	sharedIngestionRates *sharedStrategy
+
+	// Per-user and per-rule rate limiter of the metric limit rules. Nil if rate limiting is disabled.
+	metricLimitsRateLimiter *limiter.RateLimiter
+
 	// Manager for subservices (HA Tracker, distributor ring and client pool)
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -140,6 +150,12 @@ type Config struct {
 
 	// Limits for distributor
 	InstanceLimits InstanceLimits `yaml:"instance_limits"`
+
+	// Forwarding of the tenants' samples to remote-write endpoints.
+	Forwarding ForwardingConfig `yaml:"forwarding"`
+
+	// Mapping of the InfluxDB line protocol push API.
+	Influx push.InfluxConfig `yaml:"influx"`
 }
 
 type InstanceLimits struct {
@@ -152,6 +168,8 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	cfg.PoolConfig.RegisterFlags(f)
 	cfg.HATrackerConfig.RegisterFlags(f)
 	cfg.DistributorRing.RegisterFlags(f)
+	cfg.Influx.RegisterFlags(f)
+	cfg.Forwarding.RegisterFlags(f)
 
 	f.IntVar(&cfg.MaxRecvMsgSize, "distributor.max-recv-msg-size", 100<<20, "remote_write API max receive message size (bytes).")
 	f.DurationVar(&cfg.RemoteTimeout, "distributor.remote-timeout", 2*time.Second, "Timeout for downstream ingesters.")
@@ -174,6 +192,10 @@ func (cfg *Config) Validate(limits validation.Limits) error {
 		return errInvalidTenantShardSize
 	}
 
+	if err := cfg.Forwarding.Validate(); err != nil {
+		return err
+	}
+
 	return cfg.HATrackerConfig.Validate()
 }
 
@@ -207,10 +229,12 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 	var ingestionRateStrategy limiter.RateLimiterStrategy
 	var distributorsLifeCycler *ring.Lifecycler
 	var distributorsRing *ring.Ring
+	var sharedIngestionRates *sharedStrategy
+	var metricLimitsRateStrategy limiter.RateLimiterStrategy
 
 	if !canJoinDistributorsRing {
 		ingestionRateStrategy = newInfiniteIngestionRateStrategy()
-	} else if limits.IngestionRateStrategy() == validation.GlobalIngestionRateStrategy {
+	} else if strategy := limits.IngestionRateStrategy(); strategy == validation.GlobalIngestionRateStrategy || strategy == validation.SharedIngestionRateStrategy {
 		distributorsLifeCycler, err = ring.NewLifecycler(cfg.DistributorRing.ToLifecyclerConfig(), nil, "distributor", ring.DistributorRingKey, true, reg)
 		if err != nil {
 			return nil, err
@@ -222,9 +246,23 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 		}
 		subservices = append(subservices, distributorsLifeCycler, distributorsRing)
 
-		ingestionRateStrategy = newGlobalIngestionRateStrategy(limits, distributorsLifeCycler)
+		if strategy == validation.SharedIngestionRateStrategy {
+			sharedIngestionRates, err = newSharedIngestionRateStrategy(cfg.DistributorRing, limits, reg, log)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to initialize the shared ingestion rate strategy")
+			}
+			subservices = append(subservices, sharedIngestionRates)
+
+			ingestionRateStrategy = sharedIngestionRates
+		} else {
+			ingestionRateStrategy = newGlobalIngestionRateStrategy(limits, distributorsLifeCycler)
+		}
+
+		// The rate of the metric limit rules is evenly split between the distributors.
+		metricLimitsRateStrategy = newMetricLimitsRateStrategy(limits, distributorsLifeCycler)
 	} else {
 		ingestionRateStrategy = newLocalIngestionRateStrategy(limits)
+		metricLimitsRateStrategy = newMetricLimitsRateStrategy(limits, nil)
 	}
 
 	d := &Distributor{
@@ -234,6 +272,7 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 		ingesterPool:           NewPool(cfg.PoolConfig, ingestersRing, cfg.IngesterClientFactory, log),
 		distributorsLifeCycler: distributorsLifeCycler,
 		distributorsRing:       distributorsRing,
+		sharedIngestionRates:   sharedIngestionRates,
 		limits:                 limits,
 		ingestionRateLimiter:   limiter.NewRateLimiter(ingestionRateStrategy, 10*time.Second),
 		HATracker:              haTracker,
@@ -349,6 +388,15 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 	d.replicationFactor.Set(float64(ingestersRing.ReplicationFactor()))
 	d.activeUsers = util.NewActiveUsersCleanupWithDefaultValues(d.cleanupInactiveUser)
 
+	if metricLimitsRateStrategy != nil {
+		d.metricLimitsRateLimiter = limiter.NewRateLimiter(metricLimitsRateStrategy, 10*time.Second)
+	}
+
+	if cfg.Forwarding.Enabled {
+		d.forwarder = newForwarder(cfg.Forwarding, limits, reg, log)
+		subservices = append(subservices, d.forwarder)
+	}
+
 	subservices = append(subservices, d.ingesterPool, d.activeUsers)
 	d.subservices, err = services.NewManager(subservices...)
 	if err != nil {
@@ -393,6 +441,10 @@ func (d *Distributor) cleanupInactiveUser(userID string) {
 
 	d.HATracker.cleanupHATrackerMetricsForUser(userID)
 
+	if d.forwarder != nil {
+		d.forwarder.cleanupMetricsForUser(userID)
+	}
+
 	d.receivedSamples.DeleteLabelValues(userID)
 	d.receivedExemplars.DeleteLabelValues(userID)
 	d.receivedMetadata.DeleteLabelValues(userID)
@@ -487,6 +539,17 @@ func (d *Distributor) checkSample(ctx context.Context, userID, cluster, replica
 	// At this point we know we have both HA labels, we should lookup
 	// the cluster/instance here to see if we want to accept this sample.
 	err := d.HATracker.checkReplica(ctx, userID, cluster, replica, time.Now())
+
+	// When deduplicating samples, we accept the samples from all the replicas. The replica
+	// label is removed, so that the ingesters can deduplicate the samples of each series.
+	// The cluster is still tracked, so that the max HA clusters limit is enforced.
+	if d.limits.HADeduplicationMode(userID) == validation.HADeduplicationSamples {
+		if err != nil && !errors.Is(err, replicasNotMatchError{}) {
+			return false, err
+		}
+		return true, nil
+	}
+
 	// checkReplica should only have returned an error if there was a real error talking to Consul, or if the replica labels don't match.
 	if err != nil { // Don't accept the sample.
 		return false, err
@@ -706,6 +769,25 @@ func (d *Distributor) Push(ctx context.Context, req *cortexpb.WriteRequest) (*co
 		validatedMetadata = append(validatedMetadata, m)
 	}
 
+	discardedSeries, metricLimitsErr := d.applyMetricLimits(now, userID, validatedTimeseries)
+	if len(discardedSeries) > 0 {
+		keptKeys := seriesKeys[:0]
+		keptSeries := validatedTimeseries[:0]
+		for i, ts := range validatedTimeseries {
+			if _, ok := discardedSeries[i]; ok {
+				validatedSamples -= len(ts.Samples)
+				validatedExemplars -= len(ts.Exemplars)
+				continue
+			}
+			keptKeys = append(keptKeys, seriesKeys[i])
+			keptSeries = append(keptSeries, ts)
+		}
+		seriesKeys, validatedTimeseries = keptKeys, keptSeries
+	}
+	if metricLimitsErr != nil && firstPartialErr == nil {
+		firstPartialErr = metricLimitsErr
+	}
+
 	d.receivedSamples.WithLabelValues(userID).Add(float64(validatedSamples))
 	d.receivedExemplars.WithLabelValues(userID).Add((float64(validatedExemplars)))
 	d.receivedMetadata.WithLabelValues(userID).Add(float64(len(validatedMetadata)))
@@ -718,6 +800,10 @@ func (d *Distributor) Push(ctx context.Context, req *cortexpb.WriteRequest) (*co
 	}
 
 	totalN := validatedSamples + validatedExemplars + len(validatedMetadata)
+	if d.sharedIngestionRates != nil {
+		// Track the rate limited samples too, so that the limit is shared based on the received samples.
+		d.sharedIngestionRates.add(userID, totalN)
+	}
 	if !d.ingestionRateLimiter.AllowN(now, userID, totalN) {
 		// Ensure the request slice is reused if the request is rate limited.
 		cortexpb.ReuseSlice(req.Timeseries)
@@ -748,6 +834,9 @@ func (d *Distributor) Push(ctx context.Context, req *cortexpb.WriteRequest) (*co
 		op = ring.Write
 	}
 
+	// Buffered, so that the push outcome can be sent even if the cleanup is never called.
+	pushResult := make(chan error, 1)
+
 	err = ring.DoBatch(ctx, op, subRing, keys, func(ingester ring.InstanceDesc, indexes []int) error {
 		timeseries := make([]cortexpb.PreallocTimeseries, 0, len(indexes))
 		var metadata []*cortexpb.MetricMetadata
@@ -772,7 +861,16 @@ func (d *Distributor) Push(ctx context.Context, req *cortexpb.WriteRequest) (*co
 		localCtx = util.AddSourceIPsToOutgoingContext(localCtx, source)
 
 		return d.send(localCtx, ingester, timeseries, metadata, req.Source)
-	}, func() { cortexpb.ReuseSlice(req.Timeseries) })
+	}, func() {
+		// Only the series accepted by the ingesters are forwarded. The cleanup runs once all
+		// the ingesters have been called, so it waits for the push outcome and forwards the
+		// series before the request slice is reused.
+		if pushErr := <-pushResult; pushErr == nil && d.forwarder != nil && len(validatedTimeseries) > 0 {
+			d.forwarder.forward(userID, validatedTimeseries)
+		}
+		cortexpb.ReuseSlice(req.Timeseries)
+	})
+	pushResult <- err
 	if err != nil {
 		return nil, err
 	}
@@ -880,17 +978,19 @@ func (d *Distributor) LabelValuesForLabelName(ctx context.Context, from, to mode
 	return values, nil
 }
 
-// LabelNames returns all of the label names.
-func (d *Distributor) LabelNames(ctx context.Context, from, to model.Time) ([]string, error) {
+// LabelNames returns all of the label names. If matchers are provided, only the label
+// names of the series matching them are returned.
+func (d *Distributor) LabelNames(ctx context.Context, from, to model.Time, matchers ...*labels.Matcher) ([]string, error) {
 	replicationSet, err := d.GetIngestersForMetadata(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	req := &ingester_client.LabelNamesRequest{
-		StartTimestampMs: int64(from),
-		EndTimestampMs:   int64(to),
+	req, err := ingester_client.ToLabelNamesRequest(from, to, matchers)
+	if err != nil {
+		return nil, err
 	}
+
 	resps, err := d.ForReplicationSet(ctx, replicationSet, func(ctx context.Context, client ingester_client.IngesterClient) (interface{}, error) {
 		return client.LabelNames(ctx, req)
 	})
@@ -7,6 +7,7 @@ import (
 	"io"
 	"math"
 	"net/http"
+	"net/http/httptest"
 	"sort"
 	"strconv"
 	"strings"
@@ -469,6 +470,50 @@ func TestDistributor_PushIngestionRateLimiter(t *testing.T) {
 	}
 }
 
+func TestDistributor_PushMetricLimitsRateLimiter(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "metric-limits")
+
+	limits := &validation.Limits{}
+	flagext.DefaultValues(limits)
+	limits.MetricLimits = []validation.MetricLimit{
+		{Name: "first-sample", Matcher: `{__name__="foo", sample="0"}`, MaxSamplesPerSec: 1, BurstSize: 3},
+		{Name: "unlimited", Matcher: `{__name__="foo"}`},
+	}
+
+	distributors, ingesters, r, _ := prepare(t, prepConfig{
+		numIngesters:     3,
+		happyIngesters:   3,
+		numDistributors:  1,
+		shardByAllLabels: true,
+		limits:           limits,
+	})
+	defer stopAll(distributors, r)
+
+	// The samples of the series matching the rule are accepted until the burst is consumed.
+	for i := 0; i < 3; i++ {
+		response, err := distributors[0].Push(ctx, makeWriteRequest(int64(i*10), 2, 0))
+		require.NoError(t, err)
+		assert.Equal(t, emptyResponse, response)
+	}
+
+	// Then only the samples of the series matching the rule are discarded.
+	response, err := distributors[0].Push(ctx, makeWriteRequest(30, 2, 0))
+	assert.Equal(t, emptyResponse, response)
+	assert.Equal(t, httpgrpc.Errorf(http.StatusTooManyRequests, "ingestion rate limit (1) of the metric limit rule first-sample exceeded while adding 1 samples"), err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(validation.DiscardedSamples.WithLabelValues(validation.MetricLimitRateLimitedReason("first-sample"), "metric-limits")))
+
+	// Each sample is replicated to the 3 ingesters.
+	test.Poll(t, time.Second, 7*3, func() interface{} {
+		samples := 0
+		for i := range ingesters {
+			for _, ts := range ingesters[i].series() {
+				samples += len(ts.Samples)
+			}
+		}
+		return samples
+	})
+}
+
 func TestDistributor_PushInstanceLimits(t *testing.T) {
 
 	type testPush struct {
@@ -720,6 +765,71 @@ func TestDistributor_PushHAInstances(t *testing.T) {
 	}
 }
 
+func TestDistributor_PushHAInstancesWithSamplesDeduplication(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	limits.AcceptHASamples = true
+	limits.HADeduplicationMode = validation.HADeduplicationSamples
+
+	ds, ingesters, r, _ := prepare(t, prepConfig{
+		numIngesters:     3,
+		happyIngesters:   3,
+		numDistributors:  1,
+		shardByAllLabels: true,
+		limits:           &limits,
+	})
+	defer stopAll(ds, r)
+	d := ds[0]
+
+	tracker, err := newHATracker(HATrackerConfig{
+		EnableHATracker: true,
+		KVStore:         kv.Config{Mock: kv.PrefixClient(consul.NewInMemoryClient(GetReplicaDescCodec()), "prefix")},
+		UpdateTimeout:   100 * time.Millisecond,
+		FailoverTimeout: time.Second,
+	}, trackerLimits{maxClusters: 1}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), tracker))
+	defer services.StopAndAwaitTerminated(context.Background(), tracker) //nolint:errcheck
+	d.HATracker = tracker
+
+	// Even if a replica has been elected, the samples of all the replicas are accepted.
+	require.NoError(t, d.HATracker.checkReplica(ctx, "user", "cluster0", "instance2", time.Now()))
+
+	for _, replica := range []string{"instance0", "instance1"} {
+		response, err := d.Push(ctx, makeWriteRequestHA(5, replica, "cluster0"))
+		require.NoError(t, err)
+		assert.Equal(t, emptyResponse, response)
+	}
+
+	// The series are pushed to the ingesters without the replica label, so the
+	// series of both replicas are the same.
+	series := map[string]struct{}{}
+	for i := range ingesters {
+		for _, ts := range ingesters[i].series() {
+			series[cortexpb.FromLabelAdaptersToLabels(ts.Labels).String()] = struct{}{}
+		}
+	}
+	assert.Len(t, series, 5)
+	for s := range series {
+		assert.NotContains(t, s, "__replica__")
+	}
+
+	// The cluster is tracked, and the max HA clusters limit is enforced.
+	test.Poll(t, time.Second, 1, func() interface{} {
+		tracker.electedLock.RLock()
+		defer tracker.electedLock.RUnlock()
+		return tracker.clusters["user"]
+	})
+
+	_, err = d.Push(ctx, makeWriteRequestHA(5, "instance0", "cluster1"))
+	httpResp, ok := httpgrpc.HTTPResponseFromError(err)
+	require.True(t, ok)
+	assert.Equal(t, int32(http.StatusBadRequest), httpResp.Code)
+	assert.Contains(t, string(httpResp.Body), "too many HA clusters")
+}
+
 func TestDistributor_PushQuery(t *testing.T) {
 	const shuffleShardSize = 5
 
@@ -1806,6 +1916,69 @@ func TestDistributor_MetricsForLabelMatchers(t *testing.T) {
 	}
 }
 
+func TestDistributor_LabelNames(t *testing.T) {
+	const numIngesters = 5
+
+	fixtures := []struct {
+		lbls      labels.Labels
+		value     float64
+		timestamp int64
+	}{
+		{labels.Labels{{Name: labels.MetricName, Value: "test_1"}, {Name: "status", Value: "200"}}, 1, 100000},
+		{labels.Labels{{Name: labels.MetricName, Value: "test_1"}, {Name: "status", Value: "500"}}, 1, 110000},
+		{labels.Labels{{Name: labels.MetricName, Value: "test_2"}, {Name: "route", Value: "get_user"}}, 2, 200000},
+	}
+
+	tests := map[string]struct {
+		matchers       []*labels.Matcher
+		expectedResult []string
+	}{
+		"should return all label names if no matcher is provided": {
+			expectedResult: []string{labels.MetricName, "route", "status"},
+		},
+		"should return an empty response if no metric match": {
+			matchers: []*labels.Matcher{
+				mustNewMatcher(labels.MatchEqual, model.MetricNameLabel, "unknown"),
+			},
+			expectedResult: []string{},
+		},
+		"should return the label names of the series matching the matchers": {
+			matchers: []*labels.Matcher{
+				mustNewMatcher(labels.MatchEqual, model.MetricNameLabel, "test_1"),
+			},
+			expectedResult: []string{labels.MetricName, "status"},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			now := model.Now()
+
+			// Create distributor
+			ds, _, r, _ := prepare(t, prepConfig{
+				numIngesters:     numIngesters,
+				happyIngesters:   numIngesters,
+				numDistributors:  1,
+				shardByAllLabels: true,
+			})
+			defer stopAll(ds, r)
+
+			// Push fixtures
+			ctx := user.InjectOrgID(context.Background(), "test")
+
+			for _, series := range fixtures {
+				req := mockWriteRequest(series.lbls, series.value, series.timestamp)
+				_, err := ds[0].Push(ctx, req)
+				require.NoError(t, err)
+			}
+
+			names, err := ds[0].LabelNames(ctx, now, now, testData.matchers...)
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedResult, names)
+		})
+	}
+}
+
 func TestDistributor_MetricsMetadata(t *testing.T) {
 	const numIngesters = 5
 
@@ -1885,6 +2058,61 @@ func mockWriteRequest(lbls labels.Labels, value float64, timestampMs int64) *cor
 	return cortexpb.ToWriteRequest([]labels.Labels{lbls}, samples, nil, cortexpb.API)
 }
 
+func TestDistributor_Push_ShouldForwardOnlyAcceptedSeries(t *testing.T) {
+	tests := map[string]struct {
+		happyIngesters   int
+		expectedErr      error
+		expectedRequests int
+	}{
+		"should forward the series accepted by the ingesters": {
+			happyIngesters:   3,
+			expectedRequests: 1,
+		},
+		"should not forward the series rejected by the ingesters": {
+			happyIngesters: 0,
+			expectedErr:    errFail,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := &forwardingServer{}
+			httpSrv := httptest.NewServer(srv)
+			defer httpSrv.Close()
+
+			limits := &validation.Limits{}
+			flagext.DefaultValues(limits)
+			limits.ForwardingEndpoints = []string{httpSrv.URL}
+
+			ds, ingesters, r, _ := prepare(t, prepConfig{
+				numIngesters:      3,
+				happyIngesters:    tc.happyIngesters,
+				numDistributors:   1,
+				limits:            limits,
+				forwardingEnabled: true,
+			})
+			defer stopAll(ds, r)
+
+			ctx := user.InjectOrgID(context.Background(), "user")
+			_, err := ds[0].Push(ctx, makeWriteRequest(0, 5, 0))
+			assert.Equal(t, tc.expectedErr, err)
+
+			// The series are forwarded once all the ingesters have been called.
+			test.Poll(t, time.Second, 3, func() interface{} {
+				calls := 0
+				for i := range ingesters {
+					calls += ingesters[i].countCalls("Push")
+				}
+				return calls
+			})
+			test.Poll(t, time.Second, tc.expectedRequests, func() interface{} {
+				requests, _, _ := srv.received()
+				return requests
+			})
+		})
+	}
+}
+
 type prepConfig struct {
 	numIngesters, happyIngesters int
 	queryDelay                   time.Duration
@@ -1897,6 +2125,7 @@ type prepConfig struct {
 	maxInflightRequests          int
 	maxIngestionRate             float64
 	replicationFactor            int
+	forwardingEnabled            bool
 }
 
 func prepare(t *testing.T, cfg prepConfig) ([]*Distributor, []mockIngester, *ring.Ring, []*prometheus.Registry) {
@@ -1985,6 +2214,7 @@ func prepare(t *testing.T, cfg prepConfig) ([]*Distributor, []mockIngester, *rin
 		distributorCfg.SkipLabelNameValidation = cfg.skipLabelNameValidation
 		distributorCfg.InstanceLimits.MaxInflightPushRequests = cfg.maxInflightRequests
 		distributorCfg.InstanceLimits.MaxIngestionRate = cfg.maxIngestionRate
+		distributorCfg.Forwarding.Enabled = cfg.forwardingEnabled
 
 		if cfg.shuffleShardEnabled {
 			distributorCfg.ShardingStrategy = util.ShardingStrategyShuffle
@@ -2344,6 +2574,37 @@ func (i *mockIngester) MetricsForLabelMatchers(ctx context.Context, req *client.
 	return &response, nil
 }
 
+func (i *mockIngester) LabelNames(ctx context.Context, req *client.LabelNamesRequest, opts ...grpc.CallOption) (*client.LabelNamesResponse, error) {
+	i.Lock()
+	defer i.Unlock()
+
+	i.trackCall("LabelNames")
+
+	if !i.happy {
+		return nil, errFail
+	}
+
+	_, _, matchers, err := client.FromLabelNamesRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]struct{}{}
+	for _, ts := range i.timeseries {
+		if match(ts.Labels, matchers) {
+			for _, l := range ts.Labels {
+				names[l.Name] = struct{}{}
+			}
+		}
+	}
+
+	response := client.LabelNamesResponse{}
+	for name := range names {
+		response.LabelNames = append(response.LabelNames, name)
+	}
+	return &response, nil
+}
+
 func (i *mockIngester) MetricsMetadata(ctx context.Context, req *client.MetricsMetadataRequest, opts ...grpc.CallOption) (*client.MetricsMetadataResponse, error) {
 	i.Lock()
 	defer i.Unlock()
@@ -0,0 +1,341 @@
+package distributor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/weaveworks/common/user"
+	"go.uber.org/atomic"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+	"github.com/cortexproject/cortex/pkg/util"
+	"github.com/cortexproject/cortex/pkg/util/services"
+)
+
+const (
+	// Reasons for dropping forwarded samples.
+	forwardingQueueFull    = "queue_full"
+	forwardingSendFailed   = "send_failed"
+	forwardingEncodeFailed = "encode_failed"
+
+	// Max size of the error message read from the failed responses.
+	forwardingMaxErrMsgSize = 256
+)
+
+var (
+	errInvalidForwardingQueueSize  = errors.New("invalid forwarding queue size, must be greater than zero")
+	errInvalidForwardingWorkers    = errors.New("invalid forwarding workers, must be greater than zero")
+	errInvalidForwardingQueueBytes = errors.New("invalid forwarding queue max bytes, must be greater than zero")
+)
+
+// ForwardingConfig configures the forwarding of the tenants' samples to remote-write endpoints.
+type ForwardingConfig struct {
+	Enabled        bool               `yaml:"enabled"`
+	QueueSize      int                `yaml:"queue_size"`
+	QueueMaxBytes  int64              `yaml:"queue_max_bytes"`
+	Workers        int                `yaml:"workers"`
+	RequestTimeout time.Duration      `yaml:"request_timeout"`
+	BackoffConfig  util.BackoffConfig `yaml:"backoff_config"`
+}
+
+// RegisterFlags adds the flags required to config this to the given FlagSet.
+func (cfg *ForwardingConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "distributor.forwarding.enabled", false, "Enable forwarding the accepted samples of the tenants with forwarding endpoints configured to the remote-write endpoints. Samples are forwarded asynchronously, and dropped if they can't be forwarded.")
+	f.IntVar(&cfg.QueueSize, "distributor.forwarding.queue-size", 1000, "Maximum number of forwarding requests queued in each distributor. Samples are dropped when the queue is full.")
+	f.Int64Var(&cfg.QueueMaxBytes, "distributor.forwarding.queue-max-bytes", 64*1024*1024, "Maximum size, in bytes, of the encoded forwarding requests queued in each distributor. Samples are dropped when the queue is full.")
+	f.IntVar(&cfg.Workers, "distributor.forwarding.workers", 10, "Number of concurrent workers sending the queued forwarding requests.")
+	f.DurationVar(&cfg.RequestTimeout, "distributor.forwarding.request-timeout", 10*time.Second, "Timeout of each request sent to a forwarding endpoint.")
+	cfg.BackoffConfig.RegisterFlags("distributor.forwarding", f)
+}
+
+// Validate the config and returns an error if the validation doesn't pass.
+func (cfg *ForwardingConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.QueueSize <= 0 {
+		return errInvalidForwardingQueueSize
+	}
+	if cfg.QueueMaxBytes <= 0 {
+		return errInvalidForwardingQueueBytes
+	}
+	if cfg.Workers <= 0 {
+		return errInvalidForwardingWorkers
+	}
+	return nil
+}
+
+type forwardingLimits interface {
+	// ForwardingEndpoints returns the remote-write endpoints the user's samples are forwarded to.
+	ForwardingEndpoints(userID string) []string
+
+	// ForwardingRelabelConfigs returns the relabel configs applied to the user's series before forwarding them.
+	ForwardingRelabelConfigs(userID string) []*relabel.Config
+}
+
+// forwardingRequest is a remote-write request queued to be sent to an endpoint.
+type forwardingRequest struct {
+	userID   string
+	endpoint string
+	body     []byte
+	samples  int
+}
+
+// forwarder asynchronously forwards the accepted series of the tenants to remote-write endpoints.
+// Series are encoded when they're enqueued, and then sent by a pool of workers with retries.
+// Requests are dropped when the queue is full, either in number of requests or in bytes, or
+// they can't be sent, so that forwarding never slows down nor fails the ingestion.
+type forwarder struct {
+	services.Service
+
+	cfg    ForwardingConfig
+	limits forwardingLimits
+	client *http.Client
+	log    log.Logger
+
+	queue       chan forwardingRequest
+	queuedBytes *atomic.Int64
+
+	forwardedSamples *prometheus.CounterVec
+	droppedSamples   *prometheus.CounterVec
+	retries          *prometheus.CounterVec
+}
+
+func newForwarder(cfg ForwardingConfig, limits forwardingLimits, reg prometheus.Registerer, logger log.Logger) *forwarder {
+	f := &forwarder{
+		cfg:    cfg,
+		limits: limits,
+		client: &http.Client{Timeout: cfg.RequestTimeout},
+		log:    logger,
+		queue:  make(chan forwardingRequest, cfg.QueueSize),
+
+		queuedBytes: atomic.NewInt64(0),
+
+		forwardedSamples: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_forwarding_samples_total",
+			Help: "The total number of samples forwarded to remote-write endpoints.",
+		}, []string{"user"}),
+		droppedSamples: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_forwarding_dropped_samples_total",
+			Help: "The total number of samples which couldn't be forwarded to remote-write endpoints.",
+		}, []string{"user", "reason"}),
+		retries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_forwarding_retries_total",
+			Help: "The total number of retried forwarding requests.",
+		}, []string{"user"}),
+	}
+
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cortex_distributor_forwarding_queue_length",
+		Help: "The number of forwarding requests waiting to be sent.",
+	}, func() float64 {
+		return float64(len(f.queue))
+	})
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cortex_distributor_forwarding_queue_bytes",
+		Help: "The size, in bytes, of the forwarding requests waiting to be sent.",
+	}, func() float64 {
+		return float64(f.queuedBytes.Load())
+	})
+
+	f.Service = services.NewBasicService(nil, f.running, nil)
+	return f
+}
+
+func (f *forwarder) running(ctx context.Context) error {
+	wg := sync.WaitGroup{}
+	wg.Add(f.cfg.Workers)
+
+	for i := 0; i < f.cfg.Workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case req := <-f.queue:
+					f.queuedBytes.Sub(int64(len(req.body)))
+					f.send(ctx, req)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// forward enqueues the series to be forwarded to the user's endpoints, if any. The series are
+// encoded before returning, so that the input series can be reused once this function returns.
+func (f *forwarder) forward(userID string, series []cortexpb.PreallocTimeseries) {
+	endpoints := f.limits.ForwardingEndpoints(userID)
+	if len(endpoints) == 0 {
+		return
+	}
+
+	// Check the queue capacity before relabelling and encoding the series, to not waste
+	// any work when the samples would be dropped anyway.
+	if !f.hasCapacity(len(endpoints)) {
+		f.droppedSamples.WithLabelValues(userID, forwardingQueueFull).Add(float64(countSamples(series) * len(endpoints)))
+		return
+	}
+
+	req, samples := forwardingWriteRequest(series, f.limits.ForwardingRelabelConfigs(userID))
+	if len(req.Timeseries) == 0 {
+		return
+	}
+
+	data, err := req.Marshal()
+	if err != nil {
+		level.Warn(f.log).Log("msg", "failed to encode forwarding request", "user", userID, "err", err)
+		f.droppedSamples.WithLabelValues(userID, forwardingEncodeFailed).Add(float64(samples * len(endpoints)))
+		return
+	}
+	body := snappy.Encode(nil, data)
+
+	for _, endpoint := range endpoints {
+		if !f.enqueue(forwardingRequest{userID: userID, endpoint: endpoint, body: body, samples: samples}) {
+			f.droppedSamples.WithLabelValues(userID, forwardingQueueFull).Add(float64(samples))
+		}
+	}
+}
+
+// hasCapacity returns whether the queue can currently accept the given number of requests.
+// The size of the requests isn't known before encoding them, so it's only checked whether the
+// queue is already full in bytes.
+func (f *forwarder) hasCapacity(requests int) bool {
+	return len(f.queue)+requests <= cap(f.queue) && f.queuedBytes.Load() < f.cfg.QueueMaxBytes
+}
+
+// enqueue adds the request to the queue, unless the queue is full. Returns whether the request
+// has been enqueued.
+func (f *forwarder) enqueue(req forwardingRequest) bool {
+	size := int64(len(req.body))
+	if f.queuedBytes.Add(size) > f.cfg.QueueMaxBytes {
+		f.queuedBytes.Sub(size)
+		return false
+	}
+
+	select {
+	case f.queue <- req:
+		return true
+	default:
+		f.queuedBytes.Sub(size)
+		return false
+	}
+}
+
+func countSamples(series []cortexpb.PreallocTimeseries) int {
+	samples := 0
+	for _, ts := range series {
+		samples += len(ts.Samples)
+	}
+	return samples
+}
+
+// forwardingWriteRequest builds the write request with the series to forward, after applying the
+// relabel configs, and returns it together with the number of samples in it.
+func forwardingWriteRequest(series []cortexpb.PreallocTimeseries, relabelConfigs []*relabel.Config) (*cortexpb.WriteRequest, int) {
+	req := &cortexpb.WriteRequest{Timeseries: make([]cortexpb.PreallocTimeseries, 0, len(series))}
+	samples := 0
+
+	for _, ts := range series {
+		if len(relabelConfigs) > 0 {
+			lbls := relabel.Process(cortexpb.FromLabelAdaptersToLabels(ts.Labels), relabelConfigs...)
+			if len(lbls) == 0 {
+				continue
+			}
+
+			ts = cortexpb.PreallocTimeseries{TimeSeries: &cortexpb.TimeSeries{
+				Labels:    cortexpb.FromLabelsToLabelAdapters(lbls),
+				Samples:   ts.Samples,
+				Exemplars: ts.Exemplars,
+			}}
+		}
+
+		req.Timeseries = append(req.Timeseries, ts)
+		samples += len(ts.Samples)
+	}
+
+	return req, samples
+}
+
+// send sends the request to the endpoint, retrying on network errors, 5xx and 429 responses.
+func (f *forwarder) send(ctx context.Context, req forwardingRequest) {
+	backoff := util.NewBackoff(ctx, f.cfg.BackoffConfig)
+
+	var err error
+	for backoff.Ongoing() {
+		var retryable bool
+		if retryable, err = f.post(ctx, req); err == nil {
+			f.forwardedSamples.WithLabelValues(req.userID).Add(float64(req.samples))
+			return
+		}
+		if !retryable {
+			break
+		}
+
+		f.retries.WithLabelValues(req.userID).Inc()
+		backoff.Wait()
+	}
+
+	level.Warn(f.log).Log("msg", "failed to forward samples", "user", req.userID, "endpoint", req.endpoint, "err", err)
+	f.droppedSamples.WithLabelValues(req.userID, forwardingSendFailed).Add(float64(req.samples))
+}
+
+// post sends the request, and returns whether the request can be retried in case of error.
+func (f *forwarder) post(ctx context.Context, req forwardingRequest) (bool, error) {
+	httpReq, err := http.NewRequest(http.MethodPost, req.endpoint, bytes.NewReader(req.body))
+	if err != nil {
+		return false, err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Encoding", "snappy")
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	// The samples are forwarded on behalf of the same tenant.
+	if err := user.InjectOrgIDIntoHTTPRequest(user.InjectOrgID(ctx, req.userID), httpReq); err != nil {
+		return false, err
+	}
+
+	resp, err := f.client.Do(httpReq)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, forwardingMaxErrMsgSize))
+	err = fmt.Errorf("server returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	return resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+func (f *forwarder) cleanupMetricsForUser(userID string) {
+	f.forwardedSamples.DeleteLabelValues(userID)
+	f.retries.DeleteLabelValues(userID)
+	for _, reason := range []string{forwardingQueueFull, forwardingSendFailed, forwardingEncodeFailed} {
+		f.droppedSamples.DeleteLabelValues(userID, reason)
+	}
+}
@@ -0,0 +1,293 @@
+package distributor
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+	"github.com/cortexproject/cortex/pkg/util"
+	"github.com/cortexproject/cortex/pkg/util/services"
+	"github.com/cortexproject/cortex/pkg/util/test"
+)
+
+type mockForwardingLimits struct {
+	endpoints      []string
+	relabelConfigs []*relabel.Config
+}
+
+func (m mockForwardingLimits) ForwardingEndpoints(string) []string { return m.endpoints }
+
+func (m mockForwardingLimits) ForwardingRelabelConfigs(string) []*relabel.Config {
+	return m.relabelConfigs
+}
+
+// forwardingServer is a remote-write server recording the received series, and replying
+// with the configured status codes in order (and 200 once exhausted).
+type forwardingServer struct {
+	mtx      sync.Mutex
+	codes    []int
+	requests int
+	tenants  []string
+	series   []string
+}
+
+func (s *forwardingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.requests++
+	if len(s.codes) > 0 {
+		code := s.codes[0]
+		s.codes = s.codes[1:]
+		if code != http.StatusOK {
+			http.Error(w, "error", code)
+			return
+		}
+	}
+
+	compressed, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := cortexpb.WriteRequest{}
+	if err := req.Unmarshal(data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.tenants = append(s.tenants, r.Header.Get("X-Scope-OrgID"))
+	for _, ts := range req.Timeseries {
+		s.series = append(s.series, cortexpb.FromLabelAdaptersToLabels(ts.Labels).String())
+	}
+}
+
+func (s *forwardingServer) received() (int, []string, []string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.requests, s.tenants, s.series
+}
+
+func TestForwardingConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		cfg      ForwardingConfig
+		expected error
+	}{
+		"should pass if disabled": {
+			cfg: ForwardingConfig{Enabled: false},
+		},
+		"should pass with a valid config": {
+			cfg: ForwardingConfig{Enabled: true, QueueSize: 1, QueueMaxBytes: 1, Workers: 1},
+		},
+		"should fail on invalid queue size": {
+			cfg:      ForwardingConfig{Enabled: true, QueueSize: 0, QueueMaxBytes: 1, Workers: 1},
+			expected: errInvalidForwardingQueueSize,
+		},
+		"should fail on invalid queue max bytes": {
+			cfg:      ForwardingConfig{Enabled: true, QueueSize: 1, QueueMaxBytes: 0, Workers: 1},
+			expected: errInvalidForwardingQueueBytes,
+		},
+		"should fail on invalid workers": {
+			cfg:      ForwardingConfig{Enabled: true, QueueSize: 1, QueueMaxBytes: 1, Workers: 0},
+			expected: errInvalidForwardingWorkers,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.cfg.Validate())
+		})
+	}
+}
+
+func TestForwardingWriteRequest(t *testing.T) {
+	series := []cortexpb.PreallocTimeseries{
+		makeForwardingSeries("keep", 2),
+		makeForwardingSeries("drop", 1),
+	}
+
+	relabelConfigs := []*relabel.Config{
+		{
+			SourceLabels: model.LabelNames{labels.MetricName},
+			Regex:        relabel.MustNewRegexp("drop"),
+			Action:       relabel.Drop,
+		},
+		{
+			TargetLabel: "region",
+			Replacement: "eu",
+			Regex:       relabel.MustNewRegexp("(.*)"),
+			Action:      relabel.Replace,
+		},
+	}
+
+	req, samples := forwardingWriteRequest(series, relabelConfigs)
+	assert.Equal(t, 2, samples)
+	require.Len(t, req.Timeseries, 1)
+	assert.Equal(t, `{__name__="keep", region="eu"}`, cortexpb.FromLabelAdaptersToLabels(req.Timeseries[0].Labels).String())
+
+	// The input series must not be modified.
+	assert.Equal(t, `{__name__="keep"}`, cortexpb.FromLabelAdaptersToLabels(series[0].Labels).String())
+
+	req, samples = forwardingWriteRequest(series, nil)
+	assert.Equal(t, 3, samples)
+	assert.Len(t, req.Timeseries, 2)
+}
+
+func TestForwarder_Forward(t *testing.T) {
+	tests := map[string]struct {
+		codes            []int
+		expectedRequests int
+		expectedSeries   []string
+		expectedMetrics  string
+	}{
+		"should forward the series": {
+			expectedRequests: 1,
+			expectedSeries:   []string{`{__name__="series_1"}`, `{__name__="series_2"}`},
+			expectedMetrics: `
+				# HELP cortex_distributor_forwarding_samples_total The total number of samples forwarded to remote-write endpoints.
+				# TYPE cortex_distributor_forwarding_samples_total counter
+				cortex_distributor_forwarding_samples_total{user="user-1"} 3
+			`,
+		},
+		"should retry on 5xx and 429 responses": {
+			codes:            []int{http.StatusInternalServerError, http.StatusTooManyRequests},
+			expectedRequests: 3,
+			expectedSeries:   []string{`{__name__="series_1"}`, `{__name__="series_2"}`},
+			expectedMetrics: `
+				# HELP cortex_distributor_forwarding_samples_total The total number of samples forwarded to remote-write endpoints.
+				# TYPE cortex_distributor_forwarding_samples_total counter
+				cortex_distributor_forwarding_samples_total{user="user-1"} 3
+				# HELP cortex_distributor_forwarding_retries_total The total number of retried forwarding requests.
+				# TYPE cortex_distributor_forwarding_retries_total counter
+				cortex_distributor_forwarding_retries_total{user="user-1"} 2
+			`,
+		},
+		"should drop the samples on 4xx responses": {
+			codes:            []int{http.StatusBadRequest},
+			expectedRequests: 1,
+			expectedMetrics: `
+				# HELP cortex_distributor_forwarding_dropped_samples_total The total number of samples which couldn't be forwarded to remote-write endpoints.
+				# TYPE cortex_distributor_forwarding_dropped_samples_total counter
+				cortex_distributor_forwarding_dropped_samples_total{reason="send_failed",user="user-1"} 3
+			`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := &forwardingServer{codes: tc.codes}
+			httpSrv := httptest.NewServer(srv)
+			defer httpSrv.Close()
+
+			reg := prometheus.NewPedanticRegistry()
+			f := newForwarder(ForwardingConfig{
+				Enabled:        true,
+				QueueSize:      10,
+				QueueMaxBytes:  1024 * 1024,
+				Workers:        1,
+				RequestTimeout: time.Second,
+				BackoffConfig:  util.BackoffConfig{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxRetries: 5},
+			}, mockForwardingLimits{endpoints: []string{httpSrv.URL}}, reg, log.NewNopLogger())
+			require.NoError(t, services.StartAndAwaitRunning(context.Background(), f))
+			defer services.StopAndAwaitTerminated(context.Background(), f) //nolint:errcheck
+
+			f.forward("user-1", []cortexpb.PreallocTimeseries{makeForwardingSeries("series_1", 2), makeForwardingSeries("series_2", 1)})
+
+			test.Poll(t, time.Second, nil, func() interface{} {
+				return testutil.GatherAndCompare(reg, strings.NewReader(tc.expectedMetrics),
+					"cortex_distributor_forwarding_samples_total",
+					"cortex_distributor_forwarding_retries_total",
+					"cortex_distributor_forwarding_dropped_samples_total")
+			})
+
+			requests, tenants, series := srv.received()
+			assert.Equal(t, tc.expectedRequests, requests)
+			assert.Equal(t, tc.expectedSeries, series)
+			for _, tenant := range tenants {
+				assert.Equal(t, "user-1", tenant)
+			}
+		})
+	}
+}
+
+func TestForwarder_ShouldDropSamplesWhenQueueIsFull(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+
+	// The forwarder is not started, so that the queued requests are never sent.
+	f := newForwarder(ForwardingConfig{Enabled: true, QueueSize: 2, QueueMaxBytes: 1024 * 1024, Workers: 1}, mockForwardingLimits{endpoints: []string{"http://localhost/a", "http://localhost/b"}}, reg, log.NewNopLogger())
+	f.forward("user-1", []cortexpb.PreallocTimeseries{makeForwardingSeries("series_1", 2)})
+
+	// The queue has no capacity left for the requests to both endpoints, so the series aren't encoded at all.
+	f.forward("user-1", []cortexpb.PreallocTimeseries{makeForwardingSeries("series_2", 3)})
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_distributor_forwarding_dropped_samples_total The total number of samples which couldn't be forwarded to remote-write endpoints.
+		# TYPE cortex_distributor_forwarding_dropped_samples_total counter
+		cortex_distributor_forwarding_dropped_samples_total{reason="queue_full",user="user-1"} 6
+		# HELP cortex_distributor_forwarding_queue_length The number of forwarding requests waiting to be sent.
+		# TYPE cortex_distributor_forwarding_queue_length gauge
+		cortex_distributor_forwarding_queue_length 2
+	`), "cortex_distributor_forwarding_dropped_samples_total", "cortex_distributor_forwarding_queue_length"))
+
+	f.cleanupMetricsForUser("user-1")
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(""), "cortex_distributor_forwarding_dropped_samples_total"))
+}
+
+func TestForwarder_ShouldDropSamplesWhenQueueIsFullInBytes(t *testing.T) {
+	series := []cortexpb.PreallocTimeseries{makeForwardingSeries("series_1", 2)}
+	req, _ := forwardingWriteRequest(series, nil)
+	data, err := req.Marshal()
+	require.NoError(t, err)
+	size := len(snappy.Encode(nil, data))
+
+	reg := prometheus.NewPedanticRegistry()
+
+	// The queue fits the request to the first endpoint only. The forwarder is not started,
+	// so that the queued requests are never sent.
+	f := newForwarder(ForwardingConfig{Enabled: true, QueueSize: 10, QueueMaxBytes: int64(size), Workers: 1}, mockForwardingLimits{endpoints: []string{"http://localhost/a", "http://localhost/b"}}, reg, log.NewNopLogger())
+	f.forward("user-1", series)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(fmt.Sprintf(`
+		# HELP cortex_distributor_forwarding_dropped_samples_total The total number of samples which couldn't be forwarded to remote-write endpoints.
+		# TYPE cortex_distributor_forwarding_dropped_samples_total counter
+		cortex_distributor_forwarding_dropped_samples_total{reason="queue_full",user="user-1"} 2
+		# HELP cortex_distributor_forwarding_queue_length The number of forwarding requests waiting to be sent.
+		# TYPE cortex_distributor_forwarding_queue_length gauge
+		cortex_distributor_forwarding_queue_length 1
+		# HELP cortex_distributor_forwarding_queue_bytes The size, in bytes, of the forwarding requests waiting to be sent.
+		# TYPE cortex_distributor_forwarding_queue_bytes gauge
+		cortex_distributor_forwarding_queue_bytes %d
+	`, size)), "cortex_distributor_forwarding_dropped_samples_total", "cortex_distributor_forwarding_queue_length", "cortex_distributor_forwarding_queue_bytes"))
+}
+
+func makeForwardingSeries(name string, samples int) cortexpb.PreallocTimeseries {
+	ts := cortexpb.PreallocTimeseries{TimeSeries: &cortexpb.TimeSeries{
+		Labels: []cortexpb.LabelAdapter{{Name: labels.MetricName, Value: name}},
+	}}
+	for i := 0; i < samples; i++ {
+		ts.Samples = append(ts.Samples, cortexpb.Sample{Value: float64(i), TimestampMs: int64(i)})
+	}
+	return ts
+}
@@ -27,6 +27,8 @@ import (
 var (
 	errNegativeUpdateTimeoutJitterMax = errors.New("HA tracker max update timeout jitter shouldn't be negative")
 	errInvalidFailoverTimeout         = "HA Tracker failover timeout (%v) must be at least 1s greater than update timeout - max jitter (%v)"
+	errHATrackerDisabled              = errors.New("HA tracker is not enabled")
+	errHAClusterNotFound              = errors.New("HA cluster not found")
 )
 
 type haTrackerLimits interface {
@@ -376,7 +378,7 @@ func (c *haTracker) checkReplica(ctx context.Context, userID, cluster, replica s
 		}
 	}
 
-	err := c.checkKVStore(ctx, key, replica, now)
+	err := c.checkKVStore(ctx, key, replica, now, false)
 	c.kvCASCalls.WithLabelValues(userID, cluster).Inc()
 	if err != nil {
 		// The callback within checkKVStore will return a replicasNotMatchError if the sample is being deduped,
@@ -388,9 +390,36 @@ func (c *haTracker) checkReplica(ctx context.Context, userID, cluster, replica s
 	return err
 }
 
-func (c *haTracker) checkKVStore(ctx context.Context, key, replica string, now time.Time) error {
+// forceReplica elects the replica for the user's cluster, regardless of the update and failover
+// timeouts. The cluster must already be tracked. Samples from the previously elected replica are
+// then rejected until the failover timeout expires without receiving samples from the new replica.
+func (c *haTracker) forceReplica(ctx context.Context, userID, cluster, replica string, now time.Time) error {
+	if !c.cfg.EnableHATracker {
+		return errHATrackerDisabled
+	}
+	key := fmt.Sprintf("%s/%s", userID, cluster)
+
+	err := c.checkKVStore(ctx, key, replica, now, true)
+	c.kvCASCalls.WithLabelValues(userID, cluster).Inc()
+	if err != nil {
+		return err
+	}
+
+	level.Info(c.logger).Log("msg", "forced HA replica election", "user", userID, "cluster", cluster, "replica", replica)
+	return nil
+}
+
+// checkKVStore elects the replica in the KV store if there's no elected replica for the key, or the
+// elected replica is the same but its timestamp should be updated, or the elected replica has timed
+// out. If force is true, the replica is elected as long as the key exists and it's not deleted.
+func (c *haTracker) checkKVStore(ctx context.Context, key, replica string, now time.Time, force bool) error {
 	return c.client.CAS(ctx, key, func(in interface{}) (out interface{}, retry bool, err error) {
-		if desc, ok := in.(*ReplicaDesc); ok && desc.DeletedAt == 0 {
+		desc, ok := in.(*ReplicaDesc)
+		if force && (!ok || desc.DeletedAt > 0) {
+			return nil, false, errHAClusterNotFound
+		}
+
+		if ok && desc.DeletedAt == 0 && !force {
 			// We don't need to CAS and update the timestamp in the KV store if the timestamp we've received
 			// this sample at is less than updateTimeout amount of time since the timestamp in the KV store.
 			if desc.Replica == replica && now.Sub(timestamp.Time(desc.ReceivedAt)) < c.cfg.UpdateTimeout+c.updateTimeoutJitter {
@@ -407,6 +436,7 @@ func (c *haTracker) checkKVStore(ctx context.Context, key, replica string, now t
 		// There was either invalid or no data for the key, so we now accept samples
 		// from this replica. Invalid could mean that the timestamp in the KV store was
 		// out of date based on the update and failover timeouts when compared to now.
+		// Otherwise the election of this replica has been forced.
 		return &ReplicaDesc{
 			Replica:    replica,
 			ReceivedAt: timestamp.FromTime(now),
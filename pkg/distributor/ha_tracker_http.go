@@ -1,6 +1,7 @@
 package distributor
 
 import (
+	"errors"
 	"html/template"
 	"net/http"
 	"sort"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/prometheus/prometheus/pkg/timestamp"
 
+	"github.com/cortexproject/cortex/pkg/tenant"
 	"github.com/cortexproject/cortex/pkg/util"
 )
 
@@ -99,3 +101,97 @@ func (h *haTracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		Now:     time.Now(),
 	}, trackerTmpl, req)
 }
+
+// HAClusterStatus is the status of a HA cluster tracked by the HA tracker.
+type HAClusterStatus struct {
+	Cluster string `json:"cluster"`
+	Replica string `json:"replica"`
+
+	// LastSampleTime is the time of the last sample received from the elected replica,
+	// as stored in the KV store. It's updated at most once every update timeout.
+	LastSampleTime time.Time `json:"last_sample_time"`
+
+	// FailoverTime is the time after which another replica is elected, unless
+	// samples are received from the elected replica in the meanwhile.
+	FailoverTime time.Time `json:"failover_time"`
+}
+
+// HATenantStatus is the status of the HA clusters of a tenant.
+type HATenantStatus struct {
+	UserID   string            `json:"user_id"`
+	Clusters []HAClusterStatus `json:"clusters"`
+}
+
+// HAClustersResponse is the response of the HA tracker clusters API.
+type HAClustersResponse struct {
+	Tenants []HATenantStatus `json:"tenants"`
+}
+
+// ClustersHandler responds with the HA clusters of each tenant, and their elected replica, in JSON.
+// The "user" parameter can be used to only list the clusters of the given tenant.
+func (h *haTracker) ClustersHandler(w http.ResponseWriter, req *http.Request) {
+	userFilter := req.FormValue("user")
+	tenants := map[string][]HAClusterStatus{}
+
+	h.electedLock.RLock()
+	for key, desc := range h.elected {
+		chunks := strings.SplitN(key, "/", 2)
+		if userFilter != "" && chunks[0] != userFilter {
+			continue
+		}
+
+		tenants[chunks[0]] = append(tenants[chunks[0]], h.clusterStatus(chunks[1], desc))
+	}
+	h.electedLock.RUnlock()
+
+	resp := HAClustersResponse{Tenants: make([]HATenantStatus, 0, len(tenants))}
+	for userID, clusters := range tenants {
+		sort.Slice(clusters, func(i, j int) bool { return clusters[i].Cluster < clusters[j].Cluster })
+		resp.Tenants = append(resp.Tenants, HATenantStatus{UserID: userID, Clusters: clusters})
+	}
+	sort.Slice(resp.Tenants, func(i, j int) bool { return resp.Tenants[i].UserID < resp.Tenants[j].UserID })
+
+	util.WriteJSONResponse(w, resp)
+}
+
+// FailoverHandler forces the election of the "replica" for the "cluster" of the tenant,
+// for example to stop accepting samples from the elected replica during its maintenance.
+func (h *haTracker) FailoverHandler(w http.ResponseWriter, req *http.Request) {
+	userID, err := tenant.TenantID(req.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	cluster, replica := req.FormValue("cluster"), req.FormValue("replica")
+	if cluster == "" || replica == "" {
+		http.Error(w, "cluster and replica parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	switch err := h.forceReplica(req.Context(), userID, cluster, replica, now); {
+	case errors.Is(err, errHATrackerDisabled):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, errHAClusterNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	util.WriteJSONResponse(w, h.clusterStatus(cluster, ReplicaDesc{Replica: replica, ReceivedAt: timestamp.FromTime(now)}))
+}
+
+func (h *haTracker) clusterStatus(cluster string, desc ReplicaDesc) HAClusterStatus {
+	lastSampleTime := timestamp.Time(desc.ReceivedAt)
+
+	return HAClusterStatus{
+		Cluster:        cluster,
+		Replica:        desc.Replica,
+		LastSampleTime: lastSampleTime,
+		FailoverTime:   lastSampleTime.Add(h.cfg.FailoverTimeout),
+	}
+}
@@ -2,7 +2,10 @@ package distributor
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -592,6 +595,118 @@ func TestReplicasNotMatchError(t *testing.T) {
 	assert.False(t, errors.Is(err, &replicasNotMatchError{}))
 }
 
+func TestHATracker_ForceReplica(t *testing.T) {
+	c, err := newHATracker(HATrackerConfig{
+		EnableHATracker:        true,
+		KVStore:                kv.Config{Mock: consul.NewInMemoryClient(GetReplicaDescCodec())},
+		UpdateTimeout:          time.Second,
+		UpdateTimeoutJitterMax: 0,
+		FailoverTimeout:        5 * time.Second,
+	}, trackerLimits{maxClusters: 100}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	defer services.StopAndAwaitTerminated(context.Background(), c) //nolint:errcheck
+
+	now := time.Now()
+
+	// Can't force the election for a cluster which is not tracked yet.
+	assert.Equal(t, errHAClusterNotFound, c.forceReplica(context.Background(), "user", "cluster", "replica2", now))
+
+	require.NoError(t, c.checkReplica(context.Background(), "user", "cluster", "replica1", now))
+	checkReplicaTimestamp(t, time.Second, c, "user", "cluster", "replica1", now)
+
+	// Force the election of replica2 before the failover timeout.
+	now = now.Add(100 * time.Millisecond)
+	require.NoError(t, c.forceReplica(context.Background(), "user", "cluster", "replica2", now))
+	checkReplicaTimestamp(t, time.Second, c, "user", "cluster", "replica2", now)
+
+	// Samples from replica1 are now rejected, both from the cache and after the update timeout.
+	assert.True(t, errors.Is(c.checkReplica(context.Background(), "user", "cluster", "replica1", now), replicasNotMatchError{}))
+	assert.True(t, errors.Is(c.checkReplica(context.Background(), "user", "cluster", "replica1", now.Add(2*time.Second)), replicasNotMatchError{}))
+	assert.NoError(t, c.checkReplica(context.Background(), "user", "cluster", "replica2", now.Add(2*time.Second)))
+
+	// Forcing the election is not supported if the HA tracker is disabled.
+	disabled, err := newHATracker(HATrackerConfig{EnableHATracker: false}, trackerLimits{}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, errHATrackerDisabled, disabled.forceReplica(context.Background(), "user", "cluster", "replica2", now))
+}
+
+func TestHATracker_ClustersAndFailoverHandlers(t *testing.T) {
+	c, err := newHATracker(HATrackerConfig{
+		EnableHATracker:        true,
+		KVStore:                kv.Config{Mock: consul.NewInMemoryClient(GetReplicaDescCodec())},
+		UpdateTimeout:          time.Second,
+		UpdateTimeoutJitterMax: 0,
+		FailoverTimeout:        5 * time.Second,
+	}, trackerLimits{maxClusters: 100}, nil, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
+	defer services.StopAndAwaitTerminated(context.Background(), c) //nolint:errcheck
+
+	now := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, c.checkReplica(context.Background(), "user-1", "b", "replica1", now))
+	require.NoError(t, c.checkReplica(context.Background(), "user-1", "a", "replica1", now))
+	require.NoError(t, c.checkReplica(context.Background(), "user-2", "a", "replica2", now))
+	waitForClustersUpdate(t, 2, c, "user-1")
+	waitForClustersUpdate(t, 1, c, "user-2")
+
+	getClusters := func(query string) HAClustersResponse {
+		resp := httptest.NewRecorder()
+		c.ClustersHandler(resp, httptest.NewRequest(http.MethodGet, "/distributor/ha_tracker/clusters"+query, nil))
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		out := HAClustersResponse{}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+		return out
+	}
+
+	clusters := getClusters("")
+	require.Len(t, clusters.Tenants, 2)
+	assert.Equal(t, "user-1", clusters.Tenants[0].UserID)
+	require.Len(t, clusters.Tenants[0].Clusters, 2)
+	assert.Equal(t, "a", clusters.Tenants[0].Clusters[0].Cluster)
+	assert.Equal(t, "b", clusters.Tenants[0].Clusters[1].Cluster)
+	assert.Equal(t, "replica1", clusters.Tenants[0].Clusters[0].Replica)
+	assert.True(t, now.Equal(clusters.Tenants[0].Clusters[0].LastSampleTime))
+	assert.True(t, now.Add(5*time.Second).Equal(clusters.Tenants[0].Clusters[0].FailoverTime))
+
+	clusters = getClusters("?user=user-2")
+	require.Len(t, clusters.Tenants, 1)
+	assert.Equal(t, "user-2", clusters.Tenants[0].UserID)
+	assert.Equal(t, "replica2", clusters.Tenants[0].Clusters[0].Replica)
+
+	failover := func(userID, form string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/distributor/ha_tracker/failover", strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if userID != "" {
+			req = req.WithContext(user.InjectOrgID(req.Context(), userID))
+		}
+
+		resp := httptest.NewRecorder()
+		c.FailoverHandler(resp, req)
+		return resp
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, failover("", "cluster=a&replica=replica3").Code)
+	assert.Equal(t, http.StatusBadRequest, failover("user-1", "cluster=a").Code)
+	assert.Equal(t, http.StatusNotFound, failover("user-1", "cluster=c&replica=replica3").Code)
+
+	resp := failover("user-1", "cluster=a&replica=replica3")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	status := HAClusterStatus{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+	assert.Equal(t, "a", status.Cluster)
+	assert.Equal(t, "replica3", status.Replica)
+
+	// Only the cluster of the tenant has failed over.
+	test.Poll(t, time.Second, "replica3", func() interface{} {
+		return getClusters("?user=user-1").Tenants[0].Clusters[0].Replica
+	})
+	assert.Equal(t, "replica1", getClusters("?user=user-1").Tenants[0].Clusters[1].Replica)
+	assert.Equal(t, "replica2", getClusters("?user=user-2").Tenants[0].Clusters[0].Replica)
+}
+
 type trackerLimits struct {
 	maxClusters int
 }
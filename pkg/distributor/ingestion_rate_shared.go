@@ -0,0 +1,390 @@
+package distributor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+
+	"github.com/cortexproject/cortex/pkg/ring/kv"
+	"github.com/cortexproject/cortex/pkg/ring/kv/codec"
+	"github.com/cortexproject/cortex/pkg/ring/kv/memberlist"
+	util_math "github.com/cortexproject/cortex/pkg/util/math"
+	"github.com/cortexproject/cortex/pkg/util/services"
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+const (
+	// ingestionRatesKey is the key of the ingestion rates in the distributors ring KV store.
+	ingestionRatesKey = "distributor-ingestion-rates"
+
+	// Tenants whose local ingestion rate drops below this rate are not tracked anymore.
+	minTrackedIngestionRate = 0.01
+
+	// The local rates are published to the KV store only when a tenant's rate changes by more
+	// than this ratio, or when the published rates are about to become stale.
+	ingestionRatesPublishThreshold = 0.1
+)
+
+// ingestionRatesDesc holds the ingestion rates of each distributor, keyed by distributor ID.
+type ingestionRatesDesc struct {
+	Distributors map[string]distributorIngestionRates `json:"distributors"`
+}
+
+// distributorIngestionRates holds the per-tenant ingestion rates (samples/sec) of a distributor.
+type distributorIngestionRates struct {
+	// Unix timestamp in milliseconds when the rates have been updated.
+	Timestamp int64              `json:"timestamp"`
+	Rates     map[string]float64 `json:"rates"`
+
+	// Deleted marks a tombstone, which replaces the rates of a distributor which stopped or whose
+	// rates are stale. Tombstones are required because memberlist doesn't propagate deletions.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+func newIngestionRatesTombstone(now time.Time) distributorIngestionRates {
+	return distributorIngestionRates{Timestamp: timestamp.FromTime(now), Deleted: true}
+}
+
+func newIngestionRatesDesc() *ingestionRatesDesc {
+	return &ingestionRatesDesc{Distributors: map[string]distributorIngestionRates{}}
+}
+
+// Merge implements memberlist.Mergeable. The rates of each distributor are replaced by the most recent ones,
+// and a tombstone wins over rates with the same timestamp.
+func (d *ingestionRatesDesc) Merge(mergeable memberlist.Mergeable, _ bool) (memberlist.Mergeable, error) {
+	if mergeable == nil {
+		return nil, nil
+	}
+
+	other, ok := mergeable.(*ingestionRatesDesc)
+	if !ok {
+		return nil, fmt.Errorf("expected *distributor.ingestionRatesDesc, got %T", mergeable)
+	}
+	if other == nil {
+		return nil, nil
+	}
+	if d.Distributors == nil {
+		d.Distributors = map[string]distributorIngestionRates{}
+	}
+
+	change := newIngestionRatesDesc()
+	for id, rates := range other.Distributors {
+		current, ok := d.Distributors[id]
+		if !ok || rates.Timestamp > current.Timestamp || (rates.Timestamp == current.Timestamp && rates.Deleted && !current.Deleted) {
+			d.Distributors[id] = rates
+			change.Distributors[id] = rates
+		}
+	}
+
+	if len(change.Distributors) == 0 {
+		return nil, nil
+	}
+	return change, nil
+}
+
+// MergeContent implements memberlist.Mergeable.
+func (d *ingestionRatesDesc) MergeContent() []string {
+	ids := make([]string, 0, len(d.Distributors))
+	for id := range d.Distributors {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// RemoveTombstones implements memberlist.Mergeable.
+func (d *ingestionRatesDesc) RemoveTombstones(limit time.Time) (total, removed int) {
+	for id, rates := range d.Distributors {
+		if !rates.Deleted {
+			continue
+		}
+
+		if limit.IsZero() || timestamp.Time(rates.Timestamp).Before(limit) {
+			delete(d.Distributors, id)
+			removed++
+		} else {
+			total++
+		}
+	}
+	return
+}
+
+// ingestionRatesCodec is a JSON codec for the ingestion rates stored in the KV store.
+type ingestionRatesCodec struct{}
+
+// GetIngestionRatesCodec returns the codec used to store the distributors ingestion rates in the KV store.
+func GetIngestionRatesCodec() codec.Codec {
+	return ingestionRatesCodec{}
+}
+
+func (ingestionRatesCodec) CodecID() string {
+	return "ingestionRates"
+}
+
+func (ingestionRatesCodec) Decode(data []byte) (interface{}, error) {
+	desc := newIngestionRatesDesc()
+	if err := json.Unmarshal(data, desc); err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+func (ingestionRatesCodec) Encode(msg interface{}) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// sharedStrategy is an ingestion rate strategy which shares the tenants' ingestion rate limit
+// between the distributors, proportionally to the rate of samples received by each distributor.
+// Each distributor periodically reads the rates of the other distributors from the KV store, and
+// publishes its per-tenant ingestion rates when they significantly change, in order to limit the
+// contention on the shared key. When the tenant's overall rate is within the limit,
+// each distributor gets its own rate plus an even share of the remaining limit. Otherwise, the
+// limit is split proportionally to each distributor's rate.
+type sharedStrategy struct {
+	services.Service
+
+	cfg        RingConfig
+	limits     *validation.Overrides
+	client     kv.Client
+	instanceID string
+	logger     log.Logger
+
+	// Rates of the samples received by this distributor, including the rejected ones.
+	localMtx   sync.RWMutex
+	localRates map[string]*util_math.EwmaRate
+
+	// Rates of this distributor last published to the KV store. Only accessed by updateRates.
+	published distributorIngestionRates
+
+	// Total rates of the other distributors, and the number of distributors (including this one).
+	sharedMtx       sync.RWMutex
+	otherRates      map[string]float64
+	numDistributors int
+}
+
+func newSharedIngestionRateStrategy(cfg RingConfig, limits *validation.Overrides, reg prometheus.Registerer, logger log.Logger) (*sharedStrategy, error) {
+	client, err := kv.NewClient(cfg.KVStore, GetIngestionRatesCodec(), kv.RegistererWithKVName(reg, "distributor-ingestion-rates"))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &sharedStrategy{
+		cfg:             cfg,
+		limits:          limits,
+		client:          client,
+		instanceID:      cfg.InstanceID,
+		logger:          logger,
+		localRates:      map[string]*util_math.EwmaRate{},
+		otherRates:      map[string]float64{},
+		numDistributors: 1,
+	}
+
+	s.Service = services.NewBasicService(nil, s.running, s.stopping)
+	return s, nil
+}
+
+func (s *sharedStrategy) running(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.HeartbeatPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.updateRates(ctx, time.Now())
+		}
+	}
+}
+
+func (s *sharedStrategy) stopping(_ error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.HeartbeatPeriod)
+	defer cancel()
+
+	err := s.client.CAS(ctx, ingestionRatesKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		desc, ok := in.(*ingestionRatesDesc)
+		if !ok || desc == nil {
+			return nil, false, nil
+		}
+		if rates, exists := desc.Distributors[s.instanceID]; !exists || rates.Deleted {
+			return nil, false, nil
+		}
+
+		desc.Distributors[s.instanceID] = newIngestionRatesTombstone(time.Now())
+		return desc, true, nil
+	})
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "failed to remove the distributor ingestion rates from the KV store", "err", err)
+	}
+	return nil
+}
+
+// add tracks the samples received by this distributor for the tenant.
+func (s *sharedStrategy) add(tenantID string, n int) {
+	s.localMtx.RLock()
+	r, ok := s.localRates[tenantID]
+	s.localMtx.RUnlock()
+
+	if ok {
+		r.Add(int64(n))
+		return
+	}
+
+	s.localMtx.Lock()
+	defer s.localMtx.Unlock()
+
+	if r, ok = s.localRates[tenantID]; !ok {
+		r = util_math.NewEWMARate(0.2, s.cfg.HeartbeatPeriod)
+		s.localRates[tenantID] = r
+	}
+	r.Add(int64(n))
+}
+
+// updateRates updates the local rates, publishes them to the KV store if needed and reads the rates of the other distributors.
+func (s *sharedStrategy) updateRates(ctx context.Context, now time.Time) {
+	local := map[string]float64{}
+
+	s.localMtx.Lock()
+	for tenantID, r := range s.localRates {
+		r.Tick()
+		if rate := r.Rate(); rate >= minTrackedIngestionRate {
+			local[tenantID] = rate
+		} else {
+			delete(s.localRates, tenantID)
+		}
+	}
+	s.localMtx.Unlock()
+
+	var current *ingestionRatesDesc
+	if s.shouldPublish(local, now) {
+		current = s.publishRates(ctx, local, now)
+	} else {
+		val, err := s.client.Get(ctx, ingestionRatesKey)
+		if err != nil {
+			level.Warn(s.logger).Log("msg", "failed to read the distributor ingestion rates from the KV store", "err", err)
+			return
+		}
+		current, _ = val.(*ingestionRatesDesc)
+	}
+	if current == nil {
+		return
+	}
+
+	others := map[string]float64{}
+	numDistributors := 1
+	for id, rates := range current.Distributors {
+		if id == s.instanceID || rates.Deleted || s.isStale(rates, now) {
+			continue
+		}
+
+		numDistributors++
+		for tenantID, rate := range rates.Rates {
+			others[tenantID] += rate
+		}
+	}
+
+	s.sharedMtx.Lock()
+	s.otherRates = others
+	s.numDistributors = numDistributors
+	s.sharedMtx.Unlock()
+}
+
+// shouldPublish returns whether the local rates should be published to the KV store, because
+// they significantly changed since last published or the published ones are about to become stale.
+func (s *sharedStrategy) shouldPublish(local map[string]float64, now time.Time) bool {
+	if s.published.Timestamp == 0 || now.Sub(timestamp.Time(s.published.Timestamp)) >= s.cfg.HeartbeatTimeout/2 {
+		return true
+	}
+	if len(local) != len(s.published.Rates) {
+		return true
+	}
+
+	for tenantID, rate := range local {
+		published, ok := s.published.Rates[tenantID]
+		if !ok || math.Abs(rate-published) > ingestionRatesPublishThreshold*math.Max(rate, published) {
+			return true
+		}
+	}
+	return false
+}
+
+// publishRates publishes the local rates to the KV store, replacing the stale rates of the other
+// distributors with tombstones, and returns the rates of all distributors. Returns nil on error.
+func (s *sharedStrategy) publishRates(ctx context.Context, local map[string]float64, now time.Time) *ingestionRatesDesc {
+	var current *ingestionRatesDesc
+	err := s.client.CAS(ctx, ingestionRatesKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		desc, ok := in.(*ingestionRatesDesc)
+		if !ok || desc == nil {
+			desc = newIngestionRatesDesc()
+		}
+		if desc.Distributors == nil {
+			desc.Distributors = map[string]distributorIngestionRates{}
+		}
+
+		for id, rates := range desc.Distributors {
+			if !s.isStale(rates, now) {
+				continue
+			}
+
+			if rates.Deleted {
+				// Remove the old tombstones. With memberlist, they're removed by the KV store instead.
+				delete(desc.Distributors, id)
+			} else {
+				desc.Distributors[id] = newIngestionRatesTombstone(now)
+			}
+		}
+
+		desc.Distributors[s.instanceID] = distributorIngestionRates{Timestamp: timestamp.FromTime(now), Rates: local}
+		current = desc
+		return desc, true, nil
+	})
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "failed to update the distributor ingestion rates in the KV store", "err", err)
+		return nil
+	}
+
+	s.published = current.Distributors[s.instanceID]
+	return current
+}
+
+func (s *sharedStrategy) isStale(rates distributorIngestionRates, now time.Time) bool {
+	return now.Sub(timestamp.Time(rates.Timestamp)) > s.cfg.HeartbeatTimeout
+}
+
+func (s *sharedStrategy) Limit(tenantID string) float64 {
+	limit := s.limits.IngestionRate(tenantID)
+
+	s.localMtx.RLock()
+	local := 0.0
+	if r, ok := s.localRates[tenantID]; ok {
+		local = r.Rate()
+	}
+	s.localMtx.RUnlock()
+
+	s.sharedMtx.RLock()
+	others := s.otherRates[tenantID]
+	numDistributors := s.numDistributors
+	s.sharedMtx.RUnlock()
+
+	total := local + others
+	if total > 0 && total >= limit {
+		// Split the limit proportionally to the rate of each distributor.
+		return limit * local / total
+	}
+
+	// Each distributor gets its own rate, and an even share of the remaining limit.
+	return local + (limit-total)/float64(numDistributors)
+}
+
+func (s *sharedStrategy) Burst(tenantID string) int {
+	// The meaning of burst doesn't change for the shared strategy, in order
+	// to keep it easier to understand for users / operators.
+	return s.limits.IngestionBurstSize(tenantID)
+}
@@ -0,0 +1,256 @@
+package distributor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cortexproject/cortex/pkg/ring/kv"
+	"github.com/cortexproject/cortex/pkg/ring/kv/consul"
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+func TestIngestionRatesDesc_Merge(t *testing.T) {
+	desc := &ingestionRatesDesc{Distributors: map[string]distributorIngestionRates{
+		"distributor-1": {Timestamp: 10, Rates: map[string]float64{"user-1": 1}},
+		"distributor-2": {Timestamp: 20, Rates: map[string]float64{"user-1": 2}},
+	}}
+
+	change, err := desc.Merge(&ingestionRatesDesc{Distributors: map[string]distributorIngestionRates{
+		"distributor-1": {Timestamp: 15, Rates: map[string]float64{"user-1": 3}},
+		"distributor-2": {Timestamp: 15, Rates: map[string]float64{"user-1": 4}},
+		"distributor-3": {Timestamp: 15, Rates: map[string]float64{"user-1": 5}},
+	}}, false)
+	require.NoError(t, err)
+
+	// Only the most recent rates of each distributor are kept.
+	expectedChange := &ingestionRatesDesc{Distributors: map[string]distributorIngestionRates{
+		"distributor-1": {Timestamp: 15, Rates: map[string]float64{"user-1": 3}},
+		"distributor-3": {Timestamp: 15, Rates: map[string]float64{"user-1": 5}},
+	}}
+	assert.Equal(t, expectedChange, change)
+	assert.Equal(t, &ingestionRatesDesc{Distributors: map[string]distributorIngestionRates{
+		"distributor-1": {Timestamp: 15, Rates: map[string]float64{"user-1": 3}},
+		"distributor-2": {Timestamp: 20, Rates: map[string]float64{"user-1": 2}},
+		"distributor-3": {Timestamp: 15, Rates: map[string]float64{"user-1": 5}},
+	}}, desc)
+
+	// Merging the same rates again doesn't result in a change.
+	change, err = desc.Merge(expectedChange, false)
+	require.NoError(t, err)
+	assert.Nil(t, change)
+
+	// A tombstone replaces older rates and the rates with the same timestamp.
+	change, err = desc.Merge(&ingestionRatesDesc{Distributors: map[string]distributorIngestionRates{
+		"distributor-1": {Timestamp: 15, Deleted: true},
+		"distributor-2": {Timestamp: 30, Deleted: true},
+	}}, false)
+	require.NoError(t, err)
+	assert.Equal(t, &ingestionRatesDesc{Distributors: map[string]distributorIngestionRates{
+		"distributor-1": {Timestamp: 15, Deleted: true},
+		"distributor-2": {Timestamp: 30, Deleted: true},
+	}}, change)
+
+	// Newer rates replace a tombstone.
+	change, err = desc.Merge(&ingestionRatesDesc{Distributors: map[string]distributorIngestionRates{
+		"distributor-1": {Timestamp: 40, Rates: map[string]float64{"user-1": 6}},
+	}}, false)
+	require.NoError(t, err)
+	assert.Equal(t, &ingestionRatesDesc{Distributors: map[string]distributorIngestionRates{
+		"distributor-1": {Timestamp: 40, Rates: map[string]float64{"user-1": 6}},
+	}}, change)
+
+	// The codec encodes and decodes the rates.
+	data, err := GetIngestionRatesCodec().Encode(desc)
+	require.NoError(t, err)
+	decoded, err := GetIngestionRatesCodec().Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, desc, decoded)
+
+	// Only the tombstones older than the limit are removed.
+	total, removed := desc.RemoveTombstones(time.UnixMilli(31))
+	assert.Equal(t, 0, total)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, &ingestionRatesDesc{Distributors: map[string]distributorIngestionRates{
+		"distributor-1": {Timestamp: 40, Rates: map[string]float64{"user-1": 6}},
+		"distributor-3": {Timestamp: 15, Rates: map[string]float64{"user-1": 5}},
+	}}, desc)
+}
+
+func TestSharedIngestionRateStrategy(t *testing.T) {
+	const heartbeatTimeout = time.Minute
+
+	tests := map[string]struct {
+		ingestionRate  float64
+		expectedLimits []float64
+	}{
+		"should split the limit proportionally to the distributors rates when the overall rate exceeds the limit": {
+			ingestionRate:  500,
+			expectedLimits: []float64{450, 50, 0},
+		},
+		"should evenly share the remaining limit when the overall rate is within the limit": {
+			ingestionRate:  1300,
+			expectedLimits: []float64{1000, 200, 100},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			limits := validation.Limits{
+				IngestionRateStrategy: validation.SharedIngestionRateStrategy,
+				IngestionRate:         testData.ingestionRate,
+				IngestionBurstSize:    10000,
+			}
+			overrides, err := validation.NewOverrides(limits, nil)
+			require.NoError(t, err)
+
+			kvStore := consul.NewInMemoryClient(GetIngestionRatesCodec())
+			now := time.Now()
+
+			// The third distributor doesn't receive samples for the user.
+			var strategies []*sharedStrategy
+			for _, id := range []string{"distributor-1", "distributor-2", "distributor-3"} {
+				s, err := newSharedIngestionRateStrategy(RingConfig{
+					KVStore:          kv.Config{Mock: kvStore},
+					HeartbeatPeriod:  time.Second,
+					HeartbeatTimeout: heartbeatTimeout,
+					InstanceID:       id,
+				}, overrides, nil, log.NewNopLogger())
+				require.NoError(t, err)
+				strategies = append(strategies, s)
+			}
+
+			// Update the rates twice, so that every distributor reads the rates of the others.
+			for i := 0; i < 2; i++ {
+				strategies[0].add("user", 900)
+				strategies[1].add("user", 100)
+
+				for _, s := range strategies {
+					s.updateRates(context.Background(), now)
+				}
+			}
+
+			for i, s := range strategies {
+				assert.InDelta(t, testData.expectedLimits[i], s.Limit("user"), 0.001, "distributor %d", i)
+				assert.Equal(t, 10000, s.Burst("user"))
+			}
+
+			// A user without samples gets an even share of the limit.
+			for _, s := range strategies {
+				assert.InDelta(t, testData.ingestionRate/3, s.Limit("another"), 0.001)
+			}
+		})
+	}
+}
+
+func TestSharedIngestionRateStrategy_ShouldIgnoreStaleRates(t *testing.T) {
+	limits := validation.Limits{IngestionRate: 1000, IngestionBurstSize: 10000}
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+
+	kvStore := consul.NewInMemoryClient(GetIngestionRatesCodec())
+	now := time.Now()
+
+	// Store the rates of a distributor which stopped updating them.
+	require.NoError(t, kvStore.CAS(context.Background(), ingestionRatesKey, func(in interface{}) (interface{}, bool, error) {
+		return &ingestionRatesDesc{Distributors: map[string]distributorIngestionRates{
+			"stale": {Timestamp: timestamp.FromTime(now.Add(-2 * time.Minute)), Rates: map[string]float64{"user": 5000}},
+		}}, true, nil
+	}))
+
+	s, err := newSharedIngestionRateStrategy(RingConfig{
+		KVStore:          kv.Config{Mock: kvStore},
+		HeartbeatPeriod:  time.Second,
+		HeartbeatTimeout: time.Minute,
+		InstanceID:       "distributor-1",
+	}, overrides, nil, log.NewNopLogger())
+	require.NoError(t, err)
+
+	s.add("user", 100)
+	s.updateRates(context.Background(), now)
+	assert.InDelta(t, 1000, s.Limit("user"), 0.001)
+
+	// The stale rates have been replaced by a tombstone.
+	val, err := kvStore.Get(context.Background(), ingestionRatesKey)
+	require.NoError(t, err)
+	assert.Equal(t, newIngestionRatesTombstone(now), val.(*ingestionRatesDesc).Distributors["stale"])
+
+	// The tombstone is removed once stale too.
+	now = now.Add(2 * time.Minute)
+	s.updateRates(context.Background(), now)
+
+	val, err = kvStore.Get(context.Background(), ingestionRatesKey)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"distributor-1"}, val.(*ingestionRatesDesc).MergeContent())
+}
+
+func TestSharedIngestionRateStrategy_ShouldPublishRatesOnlyOnSignificantChanges(t *testing.T) {
+	limits := validation.Limits{IngestionRate: 1000, IngestionBurstSize: 10000}
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+
+	kvStore := consul.NewInMemoryClient(GetIngestionRatesCodec())
+	now := time.Now()
+
+	s, err := newSharedIngestionRateStrategy(RingConfig{
+		KVStore:          kv.Config{Mock: kvStore},
+		HeartbeatPeriod:  time.Second,
+		HeartbeatTimeout: time.Minute,
+		InstanceID:       "distributor-1",
+	}, overrides, nil, log.NewNopLogger())
+	require.NoError(t, err)
+
+	getPublishedTimestamp := func() int64 {
+		val, err := kvStore.Get(context.Background(), ingestionRatesKey)
+		require.NoError(t, err)
+		return val.(*ingestionRatesDesc).Distributors["distributor-1"].Timestamp
+	}
+
+	s.add("user", 100)
+	s.updateRates(context.Background(), now)
+	assert.Equal(t, timestamp.FromTime(now), getPublishedTimestamp())
+
+	// The rate hasn't changed, so it's not published again.
+	s.add("user", 100)
+	s.updateRates(context.Background(), now.Add(time.Second))
+	assert.Equal(t, timestamp.FromTime(now), getPublishedTimestamp())
+
+	// The rate has significantly changed.
+	s.add("user", 1000)
+	s.updateRates(context.Background(), now.Add(2*time.Second))
+	assert.Equal(t, timestamp.FromTime(now.Add(2*time.Second)), getPublishedTimestamp())
+
+	// The published rates are about to become stale.
+	s.add("user", 280)
+	s.updateRates(context.Background(), now.Add(time.Minute))
+	assert.Equal(t, timestamp.FromTime(now.Add(time.Minute)), getPublishedTimestamp())
+}
+
+func TestSharedIngestionRateStrategy_ShouldStoreATombstoneOnStop(t *testing.T) {
+	limits := validation.Limits{IngestionRate: 1000, IngestionBurstSize: 10000}
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+
+	kvStore := consul.NewInMemoryClient(GetIngestionRatesCodec())
+
+	s, err := newSharedIngestionRateStrategy(RingConfig{
+		KVStore:          kv.Config{Mock: kvStore},
+		HeartbeatPeriod:  time.Second,
+		HeartbeatTimeout: time.Minute,
+		InstanceID:       "distributor-1",
+	}, overrides, nil, log.NewNopLogger())
+	require.NoError(t, err)
+
+	s.add("user", 100)
+	s.updateRates(context.Background(), time.Now())
+	require.NoError(t, s.stopping(nil))
+
+	val, err := kvStore.Get(context.Background(), ingestionRatesKey)
+	require.NoError(t, err)
+	assert.True(t, val.(*ingestionRatesDesc).Distributors["distributor-1"].Deleted)
+}
@@ -0,0 +1,137 @@
+package distributor
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+	"github.com/cortexproject/cortex/pkg/util/limiter"
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+// metricLimitsKeySeparator separates the tenant ID and the rule name in the keys of the
+// metric limits rate limiter. It's not a supported character in tenant IDs.
+const metricLimitsKeySeparator = "/"
+
+// metricLimitsStrategy is the rate limiter strategy of the metric limit rules, whose
+// rate limiters are keyed by tenant ID and rule name.
+type metricLimitsStrategy struct {
+	limits *validation.Overrides
+
+	// Set when the rate of the rules is evenly split between the distributors.
+	ring ReadLifecycler
+}
+
+func newMetricLimitsRateStrategy(limits *validation.Overrides, ring ReadLifecycler) limiter.RateLimiterStrategy {
+	return &metricLimitsStrategy{
+		limits: limits,
+		ring:   ring,
+	}
+}
+
+func (s *metricLimitsStrategy) Limit(key string) float64 {
+	tenantID, ruleName := splitMetricLimitsKey(key)
+
+	limit := 0.0
+	if rule := s.rule(tenantID, ruleName); rule != nil {
+		limit = rule.MaxSamplesPerSec
+	}
+
+	if s.ring == nil {
+		return limit
+	}
+	if numDistributors := s.ring.HealthyInstancesCount(); numDistributors > 0 {
+		return limit / float64(numDistributors)
+	}
+	return limit
+}
+
+func (s *metricLimitsStrategy) Burst(key string) int {
+	// Like for the global strategy, the burst is not split between the distributors.
+	tenantID, ruleName := splitMetricLimitsKey(key)
+	if rule := s.rule(tenantID, ruleName); rule != nil && rule.BurstSize > 0 {
+		return rule.BurstSize
+	}
+	return s.limits.IngestionBurstSize(tenantID)
+}
+
+func (s *metricLimitsStrategy) rule(tenantID, ruleName string) *validation.MetricLimit {
+	rules := s.limits.MetricLimits(tenantID)
+	for i := range rules {
+		if rules[i].Name == ruleName {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func metricLimitsKey(tenantID, ruleName string) string {
+	return tenantID + metricLimitsKeySeparator + ruleName
+}
+
+func splitMetricLimitsKey(key string) (tenantID, ruleName string) {
+	parts := strings.SplitN(key, metricLimitsKeySeparator, 2)
+	if len(parts) != 2 {
+		return key, ""
+	}
+	return parts[0], parts[1]
+}
+
+// applyMetricLimits enforces the samples rate of the tenant's metric limit rules, and returns the
+// indexes of the series whose samples exceed the rate of a matching rule, which are discarded.
+// The samples of a series are discarded if any of the matching rules is rate limited.
+func (d *Distributor) applyMetricLimits(now time.Time, userID string, series []cortexpb.PreallocTimeseries) (map[int]struct{}, error) {
+	rules := d.limits.MetricLimits(userID)
+	if d.metricLimitsRateLimiter == nil || len(rules) == 0 {
+		return nil, nil
+	}
+
+	var (
+		discarded map[int]struct{}
+		firstErr  error
+		matching  []int
+	)
+
+	for r := range rules {
+		rule := &rules[r]
+		if rule.MaxSamplesPerSec <= 0 {
+			continue
+		}
+
+		matching = matching[:0]
+		samples := 0
+		for i, ts := range series {
+			if rule.Matches(cortexpb.FromLabelAdaptersToLabels(ts.Labels)) {
+				matching = append(matching, i)
+				samples += len(ts.Samples)
+			}
+		}
+
+		key := metricLimitsKey(userID, rule.Name)
+		if samples == 0 || d.metricLimitsRateLimiter.AllowN(now, key, samples) {
+			continue
+		}
+
+		if discarded == nil {
+			discarded = map[int]struct{}{}
+		}
+
+		discardedSamples := 0
+		for _, i := range matching {
+			if _, ok := discarded[i]; !ok {
+				discarded[i] = struct{}{}
+				discardedSamples += len(series[i].Samples)
+			}
+		}
+		validation.DiscardedSamples.WithLabelValues(validation.MetricLimitRateLimitedReason(rule.Name), userID).Add(float64(discardedSamples))
+
+		if firstErr == nil {
+			firstErr = httpgrpc.Errorf(http.StatusTooManyRequests, "ingestion rate limit (%v) of the metric limit rule %s exceeded while adding %d samples", d.metricLimitsRateLimiter.Limit(now, key), rule.Name, samples)
+		}
+	}
+
+	return discarded, firstErr
+}
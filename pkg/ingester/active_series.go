@@ -3,15 +3,19 @@ package ingester
 import (
 	"hash"
 	"math"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/cespare/xxhash"
+	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql/parser"
 	"go.uber.org/atomic"
 
 	"github.com/cortexproject/cortex/pkg/util"
+	"github.com/cortexproject/cortex/pkg/util/validation"
 )
 
 const (
@@ -20,9 +24,81 @@ const (
 
 // ActiveSeries is keeping track of recently active series for a single tenant.
 type ActiveSeries struct {
+	// Held for reading while updating the series, and for writing while reloading the matchers.
+	matchersMtx sync.RWMutex
+	matchers    *ActiveSeriesMatchers
+
 	stripes [numActiveSeriesStripes]activeSeriesStripe
 }
 
+// ActiveSeriesMatchers are the named series selectors of the active series custom trackers.
+type ActiveSeriesMatchers struct {
+	config   validation.ActiveSeriesCustomTrackers
+	names    []string
+	matchers [][]*labels.Matcher
+}
+
+// NewActiveSeriesMatchers parses the series selectors of the input active series custom trackers.
+func NewActiveSeriesMatchers(trackers validation.ActiveSeriesCustomTrackers) (*ActiveSeriesMatchers, error) {
+	asm := &ActiveSeriesMatchers{config: trackers}
+	for name := range trackers {
+		asm.names = append(asm.names, name)
+	}
+	sort.Strings(asm.names)
+
+	for _, name := range asm.names {
+		matchers, err := parser.ParseMetricSelector(trackers[name])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid matcher of the active series custom tracker %s", name)
+		}
+		asm.matchers = append(asm.matchers, matchers)
+	}
+
+	return asm, nil
+}
+
+// Names returns the names of the custom trackers, in the same order of the counts returned by ActiveWithMatchers.
+func (asm *ActiveSeriesMatchers) Names() []string {
+	if asm == nil {
+		return nil
+	}
+	return asm.names
+}
+
+// Equals returns whether the matchers have been built from the input custom trackers.
+func (asm *ActiveSeriesMatchers) Equals(trackers validation.ActiveSeriesCustomTrackers) bool {
+	if asm == nil {
+		return len(trackers) == 0
+	}
+	if len(asm.config) != len(trackers) {
+		return false
+	}
+	for name, matcher := range trackers {
+		if current, ok := asm.config[name]; !ok || current != matcher {
+			return false
+		}
+	}
+	return true
+}
+
+func (asm *ActiveSeriesMatchers) matches(series labels.Labels) []bool {
+	if asm == nil || len(asm.matchers) == 0 {
+		return nil
+	}
+
+	matches := make([]bool, len(asm.matchers))
+	for i, matchers := range asm.matchers {
+		matches[i] = true
+		for _, m := range matchers {
+			if !m.Matches(series.Get(m.Name)) {
+				matches[i] = false
+				break
+			}
+		}
+	}
+	return matches
+}
+
 // activeSeriesStripe holds a subset of the series timestamps for a single tenant.
 type activeSeriesStripe struct {
 	// Unix nanoseconds. Only used by purge. Zero = unknown.
@@ -30,23 +106,28 @@ type activeSeriesStripe struct {
 	// without holding the lock -- hence the atomic).
 	oldestEntryTs atomic.Int64
 
-	mu     sync.RWMutex
-	refs   map[uint64][]activeSeriesEntry
-	active int // Number of active entries in this stripe. Only decreased during purge or clear.
+	mu             sync.RWMutex
+	refs           map[uint64][]activeSeriesEntry
+	active         int   // Number of active entries in this stripe. Only decreased during purge or clear.
+	activeMatching []int // Number of active entries in this stripe matching each custom tracker.
 }
 
 // activeSeriesEntry holds a timestamp for single series.
 type activeSeriesEntry struct {
-	lbs   labels.Labels
-	nanos *atomic.Int64 // Unix timestamp in nanoseconds. Needs to be a pointer because we don't store pointers to entries in the stripe.
+	lbs     labels.Labels
+	nanos   *atomic.Int64 // Unix timestamp in nanoseconds. Needs to be a pointer because we don't store pointers to entries in the stripe.
+	matches []bool        // Whether the series matches each custom tracker.
 }
 
-func NewActiveSeries() *ActiveSeries {
-	c := &ActiveSeries{}
+// NewActiveSeries makes a new ActiveSeries, counting the series matching the input
+// custom trackers matchers too. The matchers can be nil.
+func NewActiveSeries(asm *ActiveSeriesMatchers) *ActiveSeries {
+	c := &ActiveSeries{matchers: asm}
 
 	// Stripes are pre-allocated so that we only read on them and no lock is required.
 	for i := 0; i < numActiveSeriesStripes; i++ {
 		c.stripes[i].refs = map[uint64][]activeSeriesEntry{}
+		c.stripes[i].activeMatching = make([]int, len(asm.Names()))
 	}
 
 	return c
@@ -57,7 +138,30 @@ func (c *ActiveSeries) UpdateSeries(series labels.Labels, now time.Time, labelsC
 	fp := fingerprint(series)
 	stripeID := fp % numActiveSeriesStripes
 
-	c.stripes[stripeID].updateSeriesTimestamp(now, series, fp, labelsCopy)
+	c.matchersMtx.RLock()
+	defer c.matchersMtx.RUnlock()
+
+	c.stripes[stripeID].updateSeriesTimestamp(now, series, fp, labelsCopy, c.matchers)
+}
+
+// CurrentMatchers returns the matchers of the custom trackers.
+func (c *ActiveSeries) CurrentMatchers() *ActiveSeriesMatchers {
+	c.matchersMtx.RLock()
+	defer c.matchersMtx.RUnlock()
+
+	return c.matchers
+}
+
+// ReloadMatchers replaces the matchers of the custom trackers. The tracked series are
+// kept, and matched against the new matchers.
+func (c *ActiveSeries) ReloadMatchers(asm *ActiveSeriesMatchers) {
+	c.matchersMtx.Lock()
+	defer c.matchersMtx.Unlock()
+
+	c.matchers = asm
+	for s := 0; s < numActiveSeriesStripes; s++ {
+		c.stripes[s].rematch(asm)
+	}
 }
 
 var sep = []byte{model.SeparatorByte}
@@ -102,13 +206,27 @@ func (c *ActiveSeries) Active() int {
 	return total
 }
 
-func (s *activeSeriesStripe) updateSeriesTimestamp(now time.Time, series labels.Labels, fingerprint uint64, labelsCopy func(labels.Labels) labels.Labels) {
+// ActiveWithMatchers returns the total number of active series, and the number of active
+// series matching each custom tracker, in the same order of the matchers names.
+func (c *ActiveSeries) ActiveWithMatchers() (int, []int) {
+	c.matchersMtx.RLock()
+	defer c.matchersMtx.RUnlock()
+
+	total := 0
+	totalMatching := make([]int, len(c.matchers.Names()))
+	for s := 0; s < numActiveSeriesStripes; s++ {
+		total += c.stripes[s].getActiveWithMatchers(totalMatching)
+	}
+	return total, totalMatching
+}
+
+func (s *activeSeriesStripe) updateSeriesTimestamp(now time.Time, series labels.Labels, fingerprint uint64, labelsCopy func(labels.Labels) labels.Labels, asm *ActiveSeriesMatchers) {
 	nowNanos := now.UnixNano()
 
 	e := s.findEntryForSeries(fingerprint, series)
 	entryTimeSet := false
 	if e == nil {
-		e, entryTimeSet = s.findOrCreateEntryForSeries(fingerprint, series, nowNanos, labelsCopy)
+		e, entryTimeSet = s.findOrCreateEntryForSeries(fingerprint, series, nowNanos, labelsCopy, asm)
 	}
 
 	if !entryTimeSet {
@@ -142,7 +260,10 @@ func (s *activeSeriesStripe) findEntryForSeries(fingerprint uint64, series label
 	return nil
 }
 
-func (s *activeSeriesStripe) findOrCreateEntryForSeries(fingerprint uint64, series labels.Labels, nowNanos int64, labelsCopy func(labels.Labels) labels.Labels) (*atomic.Int64, bool) {
+func (s *activeSeriesStripe) findOrCreateEntryForSeries(fingerprint uint64, series labels.Labels, nowNanos int64, labelsCopy func(labels.Labels) labels.Labels, asm *ActiveSeriesMatchers) (*atomic.Int64, bool) {
+	// Match the series before taking the lock, since it may be expensive.
+	matches := asm.matches(series)
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -154,9 +275,16 @@ func (s *activeSeriesStripe) findOrCreateEntryForSeries(fingerprint uint64, seri
 	}
 
 	s.active++
+	for i, match := range matches {
+		if match {
+			s.activeMatching[i]++
+		}
+	}
+
 	e := activeSeriesEntry{
-		lbs:   labelsCopy(series),
-		nanos: atomic.NewInt64(nowNanos),
+		lbs:     labelsCopy(series),
+		nanos:   atomic.NewInt64(nowNanos),
+		matches: matches,
 	}
 
 	s.refs[fingerprint] = append(s.refs[fingerprint], e)
@@ -172,6 +300,25 @@ func (s *activeSeriesStripe) clear() {
 	s.oldestEntryTs.Store(0)
 	s.refs = map[uint64][]activeSeriesEntry{}
 	s.active = 0
+	for i := range s.activeMatching {
+		s.activeMatching[i] = 0
+	}
+}
+
+// rematch matches all the series against the input matchers, and recomputes the counts of
+// the series matching the custom trackers.
+func (s *activeSeriesStripe) rematch(asm *ActiveSeriesMatchers) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	activeMatching := make([]int, len(asm.Names()))
+	for _, entries := range s.refs {
+		for i := range entries {
+			entries[i].matches = asm.matches(entries[i].lbs)
+			countMatches(activeMatching, entries[i].matches)
+		}
+	}
+	s.activeMatching = activeMatching
 }
 
 func (s *activeSeriesStripe) purge(keepUntil time.Time) {
@@ -185,6 +332,7 @@ func (s *activeSeriesStripe) purge(keepUntil time.Time) {
 	defer s.mu.Unlock()
 
 	active := 0
+	activeMatching := make([]int, len(s.activeMatching))
 
 	oldest := int64(math.MaxInt64)
 	for fp, entries := range s.refs {
@@ -198,6 +346,7 @@ func (s *activeSeriesStripe) purge(keepUntil time.Time) {
 			}
 
 			active++
+			countMatches(activeMatching, entries[0].matches)
 			if ts < oldest {
 				oldest = ts
 			}
@@ -215,6 +364,7 @@ func (s *activeSeriesStripe) purge(keepUntil time.Time) {
 					oldest = ts
 				}
 
+				countMatches(activeMatching, entries[i].matches)
 				i++
 			}
 		}
@@ -234,6 +384,15 @@ func (s *activeSeriesStripe) purge(keepUntil time.Time) {
 		s.oldestEntryTs.Store(oldest)
 	}
 	s.active = active
+	s.activeMatching = activeMatching
+}
+
+func countMatches(counts []int, matches []bool) {
+	for i, match := range matches {
+		if match {
+			counts[i]++
+		}
+	}
 }
 
 func (s *activeSeriesStripe) getActive() int {
@@ -242,3 +401,15 @@ func (s *activeSeriesStripe) getActive() int {
 
 	return s.active
 }
+
+// getActiveWithMatchers returns the number of active series, and adds the number of
+// active series matching each custom tracker to the input counts.
+func (s *activeSeriesStripe) getActiveWithMatchers(counts []int) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i, count := range s.activeMatching {
+		counts[i] += count
+	}
+	return s.active
+}
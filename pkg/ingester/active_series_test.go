@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/cortexproject/cortex/pkg/ingester/client"
+	"github.com/cortexproject/cortex/pkg/util/validation"
 )
 
 func copyFn(l labels.Labels) labels.Labels { return l }
@@ -22,7 +23,7 @@ func TestActiveSeries_UpdateSeries(t *testing.T) {
 	ls1 := []labels.Label{{Name: "a", Value: "1"}}
 	ls2 := []labels.Label{{Name: "a", Value: "2"}}
 
-	c := NewActiveSeries()
+	c := NewActiveSeries(nil)
 	assert.Equal(t, 0, c.Active())
 
 	c.UpdateSeries(ls1, time.Now(), copyFn)
@@ -42,7 +43,7 @@ func TestActiveSeries_ShouldCorrectlyHandleFingerprintCollisions(t *testing.T) {
 
 	require.True(t, client.Fingerprint(ls1) == client.Fingerprint(ls2))
 
-	c := NewActiveSeries()
+	c := NewActiveSeries(nil)
 	c.UpdateSeries(ls1, time.Now(), copyFn)
 	c.UpdateSeries(ls2, time.Now(), copyFn)
 
@@ -60,7 +61,7 @@ func TestActiveSeries_Purge(t *testing.T) {
 
 	// Run the same test for increasing TTL values
 	for ttl := 0; ttl < len(series); ttl++ {
-		c := NewActiveSeries()
+		c := NewActiveSeries(nil)
 
 		for i := 0; i < len(series); i++ {
 			c.UpdateSeries(series[i], time.Unix(int64(i), 0), copyFn)
@@ -80,7 +81,7 @@ func TestActiveSeries_PurgeOpt(t *testing.T) {
 	ls1 := metric.Set("_", "ypfajYg2lsv").Labels()
 	ls2 := metric.Set("_", "KiqbryhzUpn").Labels()
 
-	c := NewActiveSeries()
+	c := NewActiveSeries(nil)
 
 	now := time.Now()
 	c.UpdateSeries(ls1, now.Add(-2*time.Minute), copyFn)
@@ -102,6 +103,79 @@ func TestActiveSeries_PurgeOpt(t *testing.T) {
 	assert.Equal(t, 1, c.Active())
 }
 
+func TestActiveSeries_WithMatchers(t *testing.T) {
+	asm, err := NewActiveSeriesMatchers(validation.ActiveSeriesCustomTrackers{
+		"checkout": `{team="checkout"}`,
+		"http":     `{__name__=~"http_.*"}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"checkout", "http"}, asm.Names())
+
+	ls1 := labels.FromStrings(labels.MetricName, "http_requests_total", "team", "checkout")
+	ls2 := labels.FromStrings(labels.MetricName, "http_requests_total", "team", "search")
+	ls3 := labels.FromStrings(labels.MetricName, "queue_length", "team", "checkout")
+
+	c := NewActiveSeries(asm)
+	now := time.Now()
+	c.UpdateSeries(ls1, now.Add(-2*time.Minute), copyFn)
+	c.UpdateSeries(ls2, now, copyFn)
+	c.UpdateSeries(ls3, now, copyFn)
+
+	active, activeMatching := c.ActiveWithMatchers()
+	assert.Equal(t, 3, active)
+	assert.Equal(t, []int{2, 2}, activeMatching)
+
+	// Purged series are not counted anymore.
+	c.Purge(now.Add(-time.Minute))
+	active, activeMatching = c.ActiveWithMatchers()
+	assert.Equal(t, 2, active)
+	assert.Equal(t, []int{1, 1}, activeMatching)
+
+	// Reloading the matchers keeps the series, matching them against the new matchers.
+	updated := validation.ActiveSeriesCustomTrackers{"search": `{team="search"}`}
+	assert.True(t, c.CurrentMatchers().Equals(validation.ActiveSeriesCustomTrackers{
+		"checkout": `{team="checkout"}`,
+		"http":     `{__name__=~"http_.*"}`,
+	}))
+	assert.False(t, c.CurrentMatchers().Equals(updated))
+
+	asm, err = NewActiveSeriesMatchers(updated)
+	require.NoError(t, err)
+	c.ReloadMatchers(asm)
+
+	active, activeMatching = c.ActiveWithMatchers()
+	assert.Equal(t, 2, active)
+	assert.Equal(t, []int{1}, activeMatching)
+
+	// New series are matched against the new matchers too.
+	c.UpdateSeries(labels.FromStrings(labels.MetricName, "queue_length", "team", "search"), now, copyFn)
+	active, activeMatching = c.ActiveWithMatchers()
+	assert.Equal(t, 3, active)
+	assert.Equal(t, []int{2}, activeMatching)
+
+	// Purged series are not counted anymore with the new matchers either.
+	c.Purge(now.Add(time.Minute))
+	active, activeMatching = c.ActiveWithMatchers()
+	assert.Equal(t, 0, active)
+	assert.Equal(t, []int{0}, activeMatching)
+}
+
+func TestActiveSeriesMatchers_Equals(t *testing.T) {
+	var asm *ActiveSeriesMatchers
+	assert.True(t, asm.Equals(nil))
+	assert.False(t, asm.Equals(validation.ActiveSeriesCustomTrackers{"a": `{a="1"}`}))
+
+	asm, err := NewActiveSeriesMatchers(validation.ActiveSeriesCustomTrackers{"a": `{a="1"}`})
+	require.NoError(t, err)
+	assert.True(t, asm.Equals(validation.ActiveSeriesCustomTrackers{"a": `{a="1"}`}))
+	assert.False(t, asm.Equals(validation.ActiveSeriesCustomTrackers{"a": `{a="2"}`}))
+	assert.False(t, asm.Equals(validation.ActiveSeriesCustomTrackers{"b": `{a="1"}`}))
+	assert.False(t, asm.Equals(nil))
+
+	_, err = NewActiveSeriesMatchers(validation.ActiveSeriesCustomTrackers{"a": `{a=}`})
+	assert.Error(t, err)
+}
+
 var activeSeriesTestGoroutines = []int{50, 100, 500}
 
 func BenchmarkActiveSeriesTest_single_series(b *testing.B) {
@@ -117,7 +191,7 @@ func benchmarkActiveSeriesConcurrencySingleSeries(b *testing.B, goroutines int)
 		{Name: "a", Value: "a"},
 	}
 
-	c := NewActiveSeries()
+	c := NewActiveSeries(nil)
 
 	wg := &sync.WaitGroup{}
 	start := make(chan struct{})
@@ -144,7 +218,7 @@ func benchmarkActiveSeriesConcurrencySingleSeries(b *testing.B, goroutines int)
 }
 
 func BenchmarkActiveSeries_UpdateSeries(b *testing.B) {
-	c := NewActiveSeries()
+	c := NewActiveSeries(nil)
 
 	// Prepare series
 	nameBuf := bytes.Buffer{}
@@ -179,7 +253,7 @@ func benchmarkPurge(b *testing.B, twice bool) {
 	const numExpiresSeries = numSeries / 25
 
 	now := time.Now()
-	c := NewActiveSeries()
+	c := NewActiveSeries(nil)
 
 	series := [numSeries]labels.Labels{}
 	for s := 0; s < numSeries; s++ {
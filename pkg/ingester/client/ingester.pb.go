@@ -516,8 +516,9 @@ func (m *LabelValuesResponse) GetLabelValues() []string {
 }
 
 type LabelNamesRequest struct {
-	StartTimestampMs int64 `protobuf:"varint,1,opt,name=start_timestamp_ms,json=startTimestampMs,proto3" json:"start_timestamp_ms,omitempty"`
-	EndTimestampMs   int64 `protobuf:"varint,2,opt,name=end_timestamp_ms,json=endTimestampMs,proto3" json:"end_timestamp_ms,omitempty"`
+	StartTimestampMs int64          `protobuf:"varint,1,opt,name=start_timestamp_ms,json=startTimestampMs,proto3" json:"start_timestamp_ms,omitempty"`
+	EndTimestampMs   int64          `protobuf:"varint,2,opt,name=end_timestamp_ms,json=endTimestampMs,proto3" json:"end_timestamp_ms,omitempty"`
+	Matchers         *LabelMatchers `protobuf:"bytes,3,opt,name=matchers,proto3" json:"matchers,omitempty"`
 }
 
 func (m *LabelNamesRequest) Reset()      { *m = LabelNamesRequest{} }
@@ -566,6 +567,13 @@ func (m *LabelNamesRequest) GetEndTimestampMs() int64 {
 	return 0
 }
 
+func (m *LabelNamesRequest) GetMatchers() *LabelMatchers {
+	if m != nil {
+		return m.Matchers
+	}
+	return nil
+}
+
 type LabelNamesResponse struct {
 	LabelNames []string `protobuf:"bytes,1,rep,name=label_names,json=labelNames,proto3" json:"label_names,omitempty"`
 }
@@ -1348,86 +1356,86 @@ func init() {
 func init() { proto.RegisterFile("ingester.proto", fileDescriptor_60f6df4f3586b478) }
 
 var fileDescriptor_60f6df4f3586b478 = []byte{
-	// 1253 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x57, 0xcd, 0x6f, 0x13, 0x57,
-	0x10, 0xdf, 0x17, 0x7f, 0x10, 0x8f, 0x1d, 0xe3, 0xbc, 0x00, 0x31, 0x4b, 0xd9, 0xd0, 0x95, 0x68,
-	0xad, 0xb6, 0x38, 0x90, 0x7e, 0x08, 0xaa, 0x56, 0xc8, 0x81, 0x00, 0x29, 0x98, 0xc0, 0xc6, 0xb4,
-	0x55, 0xa5, 0x6a, 0xb5, 0xb6, 0x5f, 0x9c, 0x2d, 0xfb, 0xc5, 0xbe, 0xb7, 0x15, 0xdc, 0x2a, 0xf5,
-	0x0f, 0x68, 0xd5, 0x53, 0xaf, 0xbd, 0xf5, 0xdc, 0x4b, 0x6f, 0x3d, 0xf5, 0xc0, 0x91, 0x23, 0xea,
-	0x01, 0x15, 0x73, 0xe9, 0x91, 0xfe, 0x07, 0xd5, 0xbe, 0x7d, 0xbb, 0xde, 0xdd, 0xd8, 0x40, 0x24,
-	0xd2, 0x9b, 0x77, 0xe6, 0x37, 0xf3, 0x7e, 0x6f, 0x66, 0xde, 0xcc, 0x18, 0xea, 0xa6, 0x33, 0x22,
-	0x94, 0x11, 0xbf, 0xed, 0xf9, 0x2e, 0x73, 0x71, 0x79, 0xe0, 0xfa, 0x8c, 0xdc, 0x97, 0xcf, 0x8c,
-	0x4c, 0xb6, 0x1b, 0xf4, 0xdb, 0x03, 0xd7, 0x5e, 0x1d, 0xb9, 0x23, 0x77, 0x95, 0xab, 0xfb, 0xc1,
-	0x0e, 0xff, 0xe2, 0x1f, 0xfc, 0x57, 0x64, 0x26, 0x5f, 0x48, 0xc1, 0x23, 0x0f, 0x9e, 0xef, 0x7e,
-	0x43, 0x06, 0x4c, 0x7c, 0xad, 0x7a, 0x77, 0x47, 0xb1, 0xa2, 0x2f, 0x7e, 0x44, 0xa6, 0xea, 0xa7,
-	0x50, 0xd5, 0x88, 0x31, 0xd4, 0xc8, 0xbd, 0x80, 0x50, 0x86, 0xdb, 0x70, 0xe8, 0x5e, 0x40, 0x7c,
-	0x93, 0xd0, 0x26, 0x3a, 0x55, 0x68, 0x55, 0xd7, 0x8e, 0xb4, 0x05, 0xfc, 0x76, 0x40, 0xfc, 0x07,
-	0x02, 0xa6, 0xc5, 0x20, 0xf5, 0x22, 0xd4, 0x22, 0x73, 0xea, 0xb9, 0x0e, 0x25, 0x78, 0x15, 0x0e,
-	0xf9, 0x84, 0x06, 0x16, 0x8b, 0xed, 0x8f, 0xe6, 0xec, 0x23, 0x9c, 0x16, 0xa3, 0xd4, 0x9f, 0x11,
-	0xd4, 0xd2, 0xae, 0xf1, 0x7b, 0x80, 0x29, 0x33, 0x7c, 0xa6, 0x33, 0xd3, 0x26, 0x94, 0x19, 0xb6,
-	0xa7, 0xdb, 0xa1, 0x33, 0xd4, 0x2a, 0x68, 0x0d, 0xae, 0xe9, 0xc5, 0x8a, 0x2e, 0xc5, 0x2d, 0x68,
-	0x10, 0x67, 0x98, 0xc5, 0xce, 0x71, 0x6c, 0x9d, 0x38, 0xc3, 0x34, 0xf2, 0x2c, 0xcc, 0xdb, 0x06,
-	0x1b, 0xec, 0x12, 0x9f, 0x36, 0x0b, 0xd9, 0xab, 0xdd, 0x30, 0xfa, 0xc4, 0xea, 0x46, 0x4a, 0x2d,
-	0x41, 0xa9, 0xbf, 0x20, 0x38, 0xb2, 0x71, 0x9f, 0xd8, 0x9e, 0x65, 0xf8, 0xff, 0x0b, 0xc5, 0x73,
-	0x7b, 0x28, 0x1e, 0x9d, 0x46, 0x91, 0xa6, 0x38, 0x5e, 0x87, 0x85, 0x4c, 0x60, 0xf1, 0xc7, 0x00,
-	0xfc, 0xa4, 0x69, 0x39, 0xf4, 0xfa, 0xed, 0xf0, 0xb8, 0x6d, 0xae, 0x5b, 0x2f, 0x3e, 0x7c, 0xb2,
-	0x22, 0x69, 0x29, 0xb4, 0xfa, 0x13, 0x82, 0x25, 0xee, 0x6d, 0x9b, 0xf9, 0xc4, 0xb0, 0x13, 0x9f,
-	0x17, 0xa1, 0x3a, 0xd8, 0x0d, 0x9c, 0xbb, 0x19, 0xa7, 0xcb, 0x31, 0xb5, 0x89, 0xcb, 0x4b, 0x21,
-	0x48, 0xf8, 0x4d, 0x5b, 0xe4, 0x48, 0xcd, 0xed, 0x8b, 0xd4, 0x36, 0x1c, 0xcd, 0x25, 0xe1, 0x35,
-	0xdc, 0xf4, 0x0f, 0x04, 0x98, 0x87, 0xf4, 0x73, 0xc3, 0x0a, 0x08, 0x8d, 0x13, 0x7b, 0x12, 0xc0,
-	0x0a, 0xa5, 0xba, 0x63, 0xd8, 0x84, 0x27, 0xb4, 0xa2, 0x55, 0xb8, 0xe4, 0xa6, 0x61, 0x93, 0x19,
-	0x79, 0x9f, 0xdb, 0x47, 0xde, 0x0b, 0x2f, 0xcd, 0x7b, 0xf1, 0x14, 0x7a, 0x95, 0xbc, 0x9f, 0x87,
-	0xa5, 0x0c, 0x7f, 0x11, 0x93, 0x37, 0xa1, 0x16, 0x5d, 0xe0, 0x5b, 0x2e, 0xe7, 0x51, 0xa9, 0x68,
-	0x55, 0x6b, 0x02, 0x55, 0xef, 0xc2, 0xe2, 0x8d, 0xf8, 0x46, 0xf4, 0x80, 0x2b, 0x5a, 0xfd, 0x50,
-	0x84, 0x59, 0x1c, 0x26, 0x58, 0xae, 0x40, 0x75, 0x12, 0xe6, 0x98, 0x24, 0x24, 0x71, 0xa6, 0x2a,
-	0x86, 0xc6, 0x1d, 0x4a, 0xfc, 0x6d, 0x66, 0xb0, 0x98, 0xa2, 0xfa, 0x3b, 0x82, 0xc5, 0x94, 0x50,
-	0xb8, 0x3a, 0x1d, 0xb7, 0x50, 0xd3, 0x75, 0x74, 0xdf, 0x60, 0x51, 0xd6, 0x90, 0xb6, 0x90, 0x48,
-	0x35, 0x83, 0x91, 0x30, 0xb1, 0x4e, 0x60, 0xeb, 0x49, 0x01, 0xa2, 0x56, 0x51, 0xab, 0x38, 0x81,
-	0x1d, 0x15, 0x48, 0x78, 0x7d, 0xc3, 0x33, 0xf5, 0x9c, 0xa7, 0x02, 0xf7, 0xd4, 0x30, 0x3c, 0x73,
-	0x33, 0xe3, 0xac, 0x0d, 0x4b, 0x7e, 0x60, 0x91, 0x3c, 0xbc, 0xc8, 0xe1, 0x8b, 0xa1, 0x2a, 0x83,
-	0x57, 0xbf, 0x86, 0xa5, 0x90, 0xf8, 0xe6, 0xe5, 0x2c, 0xf5, 0x65, 0x38, 0x14, 0x50, 0xe2, 0xeb,
-	0xe6, 0x50, 0x54, 0x5a, 0x39, 0xfc, 0xdc, 0x1c, 0xe2, 0x33, 0x50, 0x1c, 0x1a, 0xcc, 0xe0, 0x34,
-	0xab, 0x6b, 0xc7, 0xe3, 0x52, 0xd8, 0x73, 0x79, 0x8d, 0xc3, 0xd4, 0xab, 0x80, 0x43, 0x15, 0xcd,
-	0x7a, 0x3f, 0x07, 0x25, 0x1a, 0x0a, 0xc4, 0xc3, 0x38, 0x91, 0xf6, 0x92, 0x63, 0xa2, 0x45, 0x48,
-	0xf5, 0x37, 0x04, 0x4a, 0x97, 0x30, 0xdf, 0x1c, 0xd0, 0x2b, 0xae, 0x9f, 0xad, 0xbc, 0x03, 0xee,
-	0x7c, 0xe7, 0xa1, 0x16, 0x97, 0xb6, 0x4e, 0x09, 0x7b, 0x71, 0xf7, 0xab, 0xc6, 0xd0, 0x6d, 0xc2,
-	0xd4, 0xeb, 0xb0, 0x32, 0x93, 0xb3, 0x08, 0x45, 0x0b, 0xca, 0x36, 0x87, 0x88, 0x58, 0x34, 0x26,
-	0x4d, 0x22, 0x32, 0xd5, 0x84, 0x5e, 0x6d, 0xc2, 0x31, 0xe1, 0xac, 0x4b, 0x98, 0x11, 0x46, 0x37,
-	0xae, 0xbe, 0x2d, 0x58, 0xde, 0xa3, 0x11, 0xee, 0x3f, 0x80, 0x79, 0x5b, 0xc8, 0xc4, 0x01, 0xcd,
-	0xfc, 0x01, 0x89, 0x4d, 0x82, 0x54, 0xff, 0x45, 0x70, 0x38, 0xd7, 0x39, 0xc3, 0x78, 0xed, 0xf8,
-	0xae, 0xad, 0xc7, 0x4b, 0xc1, 0xa4, 0x34, 0xea, 0xa1, 0x7c, 0x53, 0x88, 0x37, 0x87, 0xe9, 0xda,
-	0x99, 0xcb, 0xd4, 0x8e, 0x03, 0x65, 0xfe, 0x8e, 0xe2, 0x01, 0xb2, 0x34, 0xa1, 0xc2, 0x83, 0x73,
-	0xcb, 0x30, 0xfd, 0xf5, 0x4e, 0xd8, 0x0f, 0xff, 0x7a, 0xb2, 0xb2, 0xaf, 0xb5, 0x21, 0xb2, 0xef,
-	0x0c, 0x0d, 0x8f, 0x11, 0x5f, 0x13, 0xa7, 0xe0, 0x77, 0xa1, 0x1c, 0x35, 0xfa, 0x66, 0x91, 0x9f,
-	0xb7, 0x10, 0xa7, 0x2c, 0x3d, 0x0b, 0x04, 0x44, 0xfd, 0x01, 0x41, 0x29, 0xba, 0xe9, 0x41, 0xd5,
-	0x91, 0x0c, 0xf3, 0xc4, 0x19, 0xb8, 0x43, 0xd3, 0x19, 0xf1, 0xe7, 0x5b, 0xd2, 0x92, 0x6f, 0x8c,
-	0xc5, 0xb3, 0x0a, 0xdf, 0x69, 0x4d, 0xbc, 0x9d, 0x26, 0x1c, 0xeb, 0xf9, 0x86, 0x43, 0x77, 0x88,
-	0xcf, 0x89, 0x25, 0x45, 0xa3, 0x76, 0x60, 0x21, 0x53, 0x4d, 0x99, 0xfd, 0x01, 0xbd, 0xd2, 0xfe,
-	0xa0, 0x43, 0x2d, 0xad, 0xc1, 0xa7, 0xa1, 0xc8, 0x1e, 0x78, 0x51, 0x87, 0xaa, 0xaf, 0x2d, 0xc6,
-	0xd6, 0x5c, 0xdd, 0x7b, 0xe0, 0x11, 0x8d, 0xab, 0x43, 0x9e, 0x7c, 0xfc, 0x44, 0x89, 0xe5, 0xbf,
-	0xf1, 0x11, 0x28, 0xf1, 0x8e, 0xce, 0x2f, 0x55, 0xd1, 0xa2, 0x0f, 0xf5, 0x7b, 0x04, 0xf5, 0x49,
-	0x0d, 0x5d, 0x31, 0x2d, 0xf2, 0x3a, 0x4a, 0x48, 0x86, 0xf9, 0x1d, 0xd3, 0x22, 0x9c, 0x43, 0x74,
-	0x5c, 0xf2, 0x3d, 0x2d, 0x86, 0xef, 0x7c, 0x06, 0x95, 0xe4, 0x0a, 0xb8, 0x02, 0xa5, 0x8d, 0xdb,
-	0x77, 0x3a, 0x37, 0x1a, 0x12, 0x5e, 0x80, 0xca, 0xcd, 0xad, 0x9e, 0x1e, 0x7d, 0x22, 0x7c, 0x18,
-	0xaa, 0xda, 0xc6, 0xd5, 0x8d, 0x2f, 0xf5, 0x6e, 0xa7, 0x77, 0xe9, 0x5a, 0x63, 0x0e, 0x63, 0xa8,
-	0x47, 0x82, 0x9b, 0x5b, 0x42, 0x56, 0x58, 0xfb, 0xb3, 0x0c, 0xf3, 0x31, 0x47, 0x7c, 0x01, 0x8a,
-	0xb7, 0x02, 0xba, 0x8b, 0x8f, 0x4d, 0x6a, 0xf8, 0x0b, 0xdf, 0x64, 0x44, 0xbc, 0x49, 0x79, 0x79,
-	0x8f, 0x5c, 0xe4, 0x4e, 0xc2, 0x1f, 0x41, 0x89, 0x2f, 0x0b, 0x78, 0xea, 0xfa, 0x2a, 0x4f, 0x5f,
-	0x4a, 0x55, 0x09, 0x5f, 0x86, 0x6a, 0x6a, 0x01, 0x9a, 0x61, 0x7d, 0x22, 0x23, 0xcd, 0xee, 0x4a,
-	0xaa, 0x74, 0x16, 0xe1, 0x2d, 0xa8, 0x73, 0x55, 0xbc, 0xb7, 0x50, 0xfc, 0x46, 0x6c, 0x32, 0x6d,
-	0x9f, 0x94, 0x4f, 0xce, 0xd0, 0x26, 0xb4, 0xae, 0x41, 0x35, 0x35, 0xed, 0xb1, 0x9c, 0x29, 0xbc,
-	0xcc, 0x0a, 0x33, 0x21, 0x37, 0x65, 0x3d, 0x50, 0x25, 0xbc, 0x01, 0x30, 0x19, 0xc8, 0xf8, 0x78,
-	0x06, 0x9c, 0xde, 0x08, 0x64, 0x79, 0x9a, 0x2a, 0x71, 0xb3, 0x0e, 0x95, 0x64, 0x1c, 0xe1, 0xe6,
-	0x94, 0x09, 0x15, 0x39, 0x99, 0x3d, 0xbb, 0x54, 0x09, 0x5f, 0x81, 0x5a, 0xc7, 0xb2, 0x5e, 0xc5,
-	0x8d, 0x9c, 0xd6, 0xd0, 0xbc, 0x1f, 0x2b, 0x69, 0xcd, 0xf9, 0x09, 0x80, 0xdf, 0x4a, 0xde, 0xd8,
-	0x0b, 0xc7, 0x9a, 0xfc, 0xf6, 0x4b, 0x71, 0xc9, 0x69, 0x3d, 0x38, 0x9c, 0x1b, 0x04, 0x58, 0xc9,
-	0x59, 0xe7, 0x66, 0x87, 0xbc, 0x32, 0x53, 0x9f, 0x78, 0xed, 0x42, 0x3d, 0xdb, 0x87, 0xf0, 0xac,
-	0xf5, 0x5a, 0x4e, 0x4e, 0x9b, 0xd1, 0xb8, 0xa4, 0x16, 0x5a, 0xff, 0xe4, 0xd1, 0x53, 0x45, 0x7a,
-	0xfc, 0x54, 0x91, 0x9e, 0x3f, 0x55, 0xd0, 0x77, 0x63, 0x05, 0xfd, 0x3a, 0x56, 0xd0, 0xc3, 0xb1,
-	0x82, 0x1e, 0x8d, 0x15, 0xf4, 0xf7, 0x58, 0x41, 0xff, 0x8c, 0x15, 0xe9, 0xf9, 0x58, 0x41, 0x3f,
-	0x3e, 0x53, 0xa4, 0x47, 0xcf, 0x14, 0xe9, 0xf1, 0x33, 0x45, 0xfa, 0xaa, 0x3c, 0xb0, 0x4c, 0xe2,
-	0xb0, 0x7e, 0x99, 0xff, 0x33, 0x7c, 0xff, 0xbf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x0e, 0x4f, 0x5c,
-	0xe0, 0x9d, 0x0e, 0x00, 0x00,
+	// 1249 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x57, 0xcd, 0x8f, 0xd3, 0x46,
+	0x14, 0xf7, 0x6c, 0x3e, 0xd8, 0xbc, 0x64, 0x43, 0x76, 0x16, 0xd8, 0x60, 0x8a, 0x97, 0x5a, 0xa2,
+	0x8d, 0xda, 0x92, 0x85, 0xed, 0x87, 0xa0, 0x6a, 0x85, 0xb2, 0xb0, 0xc0, 0x16, 0xc2, 0x82, 0x37,
+	0xb4, 0x55, 0xa5, 0xca, 0x72, 0x92, 0xd9, 0xac, 0x8b, 0xbf, 0xf0, 0x8c, 0x2b, 0xb8, 0x55, 0xea,
+	0x1f, 0xd0, 0xaa, 0xa7, 0x9e, 0x2a, 0xf5, 0xd6, 0x73, 0x2f, 0xbd, 0xf5, 0xd4, 0x03, 0x47, 0x8e,
+	0xa8, 0x07, 0x54, 0xc2, 0xa5, 0x47, 0xfa, 0x1f, 0x54, 0x1e, 0x8f, 0x1d, 0xdb, 0x9b, 0xc0, 0x22,
+	0x01, 0xb7, 0xf8, 0xbd, 0xdf, 0xfb, 0xcd, 0xfb, 0x9a, 0x79, 0x2f, 0x50, 0x37, 0x9d, 0x11, 0xa1,
+	0x8c, 0xf8, 0x6d, 0xcf, 0x77, 0x99, 0x8b, 0xcb, 0x03, 0xd7, 0x67, 0xe4, 0xae, 0x7c, 0x6a, 0x64,
+	0xb2, 0xdd, 0xa0, 0xdf, 0x1e, 0xb8, 0xf6, 0xea, 0xc8, 0x1d, 0xb9, 0xab, 0x5c, 0xdd, 0x0f, 0x76,
+	0xf8, 0x17, 0xff, 0xe0, 0xbf, 0x22, 0x33, 0xf9, 0x5c, 0x0a, 0x1e, 0x31, 0x78, 0xbe, 0xfb, 0x0d,
+	0x19, 0x30, 0xf1, 0xb5, 0xea, 0xdd, 0x1e, 0xc5, 0x8a, 0xbe, 0xf8, 0x11, 0x99, 0xaa, 0x9f, 0x42,
+	0x55, 0x23, 0xc6, 0x50, 0x23, 0x77, 0x02, 0x42, 0x19, 0x6e, 0xc3, 0x81, 0x3b, 0x01, 0xf1, 0x4d,
+	0x42, 0x9b, 0xe8, 0x44, 0xa1, 0x55, 0x5d, 0x3b, 0xd4, 0x16, 0xf0, 0x9b, 0x01, 0xf1, 0xef, 0x09,
+	0x98, 0x16, 0x83, 0xd4, 0xf3, 0x50, 0x8b, 0xcc, 0xa9, 0xe7, 0x3a, 0x94, 0xe0, 0x55, 0x38, 0xe0,
+	0x13, 0x1a, 0x58, 0x2c, 0xb6, 0x3f, 0x9c, 0xb3, 0x8f, 0x70, 0x5a, 0x8c, 0x52, 0x7f, 0x46, 0x50,
+	0x4b, 0x53, 0xe3, 0xf7, 0x00, 0x53, 0x66, 0xf8, 0x4c, 0x67, 0xa6, 0x4d, 0x28, 0x33, 0x6c, 0x4f,
+	0xb7, 0x43, 0x32, 0xd4, 0x2a, 0x68, 0x0d, 0xae, 0xe9, 0xc5, 0x8a, 0x2e, 0xc5, 0x2d, 0x68, 0x10,
+	0x67, 0x98, 0xc5, 0xce, 0x71, 0x6c, 0x9d, 0x38, 0xc3, 0x34, 0xf2, 0x34, 0xcc, 0xdb, 0x06, 0x1b,
+	0xec, 0x12, 0x9f, 0x36, 0x0b, 0xd9, 0xd0, 0xae, 0x19, 0x7d, 0x62, 0x75, 0x23, 0xa5, 0x96, 0xa0,
+	0xd4, 0x5f, 0x11, 0x1c, 0xda, 0xb8, 0x4b, 0x6c, 0xcf, 0x32, 0xfc, 0xd7, 0xe2, 0xe2, 0x99, 0x3d,
+	0x2e, 0x1e, 0x9e, 0xe6, 0x22, 0x4d, 0xf9, 0x78, 0x15, 0x16, 0x32, 0x89, 0xc5, 0x1f, 0x03, 0xf0,
+	0x93, 0xa6, 0xd5, 0xd0, 0xeb, 0xb7, 0xc3, 0xe3, 0xb6, 0xb9, 0x6e, 0xbd, 0x78, 0xff, 0xd1, 0x8a,
+	0xa4, 0xa5, 0xd0, 0xea, 0x4f, 0x08, 0x96, 0x38, 0xdb, 0x36, 0xf3, 0x89, 0x61, 0x27, 0x9c, 0xe7,
+	0xa1, 0x3a, 0xd8, 0x0d, 0x9c, 0xdb, 0x19, 0xd2, 0xe5, 0xd8, 0xb5, 0x09, 0xe5, 0x85, 0x10, 0x24,
+	0x78, 0xd3, 0x16, 0x39, 0xa7, 0xe6, 0x5e, 0xc8, 0xa9, 0x6d, 0x38, 0x9c, 0x2b, 0xc2, 0x4b, 0x88,
+	0xf4, 0x4f, 0x04, 0x98, 0xa7, 0xf4, 0x73, 0xc3, 0x0a, 0x08, 0x8d, 0x0b, 0x7b, 0x1c, 0xc0, 0x0a,
+	0xa5, 0xba, 0x63, 0xd8, 0x84, 0x17, 0xb4, 0xa2, 0x55, 0xb8, 0xe4, 0xba, 0x61, 0x93, 0x19, 0x75,
+	0x9f, 0x7b, 0x81, 0xba, 0x17, 0x9e, 0x5b, 0xf7, 0xe2, 0x09, 0xb4, 0x9f, 0xba, 0x9f, 0x85, 0xa5,
+	0x8c, 0xff, 0x22, 0x27, 0x6f, 0x42, 0x2d, 0x0a, 0xe0, 0x5b, 0x2e, 0xe7, 0x59, 0xa9, 0x68, 0x55,
+	0x6b, 0x02, 0x55, 0x7f, 0x41, 0xb0, 0x78, 0x2d, 0x0e, 0x89, 0xbe, 0xde, 0x96, 0xde, 0x57, 0x68,
+	0x1f, 0x02, 0x4e, 0xfb, 0x27, 0x22, 0x5b, 0x81, 0xea, 0xa4, 0x34, 0x71, 0x60, 0x90, 0xd4, 0x86,
+	0xaa, 0x18, 0x1a, 0xb7, 0x28, 0xf1, 0xb7, 0x99, 0xc1, 0xe2, 0xa8, 0xd4, 0x3f, 0x10, 0x2c, 0xa6,
+	0x84, 0x82, 0xea, 0x64, 0xfc, 0xec, 0x9a, 0xae, 0xa3, 0xfb, 0x06, 0x8b, 0x2a, 0x8d, 0xb4, 0x85,
+	0x44, 0xaa, 0x19, 0x8c, 0x84, 0xcd, 0xe0, 0x04, 0xb6, 0x9e, 0x34, 0x2d, 0x6a, 0x15, 0xb5, 0x8a,
+	0x13, 0xd8, 0x51, 0x53, 0x85, 0x19, 0x33, 0x3c, 0x53, 0xcf, 0x31, 0x15, 0x38, 0x53, 0xc3, 0xf0,
+	0xcc, 0xcd, 0x0c, 0x59, 0x1b, 0x96, 0xfc, 0xc0, 0x22, 0x79, 0x78, 0x91, 0xc3, 0x17, 0x43, 0x55,
+	0x06, 0xaf, 0x7e, 0x0d, 0x4b, 0xa1, 0xe3, 0x9b, 0x17, 0xb3, 0xae, 0x2f, 0xc3, 0x81, 0x80, 0x12,
+	0x5f, 0x37, 0x87, 0xa2, 0x3b, 0xcb, 0xe1, 0xe7, 0xe6, 0x10, 0x9f, 0x82, 0xe2, 0xd0, 0x60, 0x06,
+	0x77, 0xb3, 0xba, 0x76, 0x34, 0xce, 0xf1, 0x9e, 0xe0, 0x35, 0x0e, 0x53, 0x2f, 0x03, 0x0e, 0x55,
+	0x34, 0xcb, 0x7e, 0x06, 0x4a, 0x34, 0x14, 0x88, 0xcb, 0x74, 0x2c, 0xcd, 0x92, 0xf3, 0x44, 0x8b,
+	0x90, 0xea, 0xef, 0x08, 0x94, 0x2e, 0x61, 0xbe, 0x39, 0xa0, 0x97, 0x5c, 0x3f, 0x5b, 0xd2, 0x57,
+	0xdc, 0x5a, 0x67, 0xa1, 0x16, 0xf7, 0x8c, 0x4e, 0x09, 0x7b, 0xf6, 0x8b, 0x59, 0x8d, 0xa1, 0xdb,
+	0x84, 0xa9, 0x57, 0x61, 0x65, 0xa6, 0xcf, 0x22, 0x15, 0x2d, 0x28, 0xdb, 0x1c, 0x22, 0x72, 0xd1,
+	0x98, 0x3c, 0x2c, 0x91, 0xa9, 0x26, 0xf4, 0x6a, 0x13, 0x8e, 0x08, 0xb2, 0x2e, 0x61, 0x46, 0x98,
+	0xdd, 0xb8, 0xfb, 0xb6, 0x60, 0x79, 0x8f, 0x46, 0xd0, 0x7f, 0x00, 0xf3, 0xb6, 0x90, 0x89, 0x03,
+	0x9a, 0xf9, 0x03, 0x12, 0x9b, 0x04, 0xa9, 0xfe, 0x87, 0xe0, 0x60, 0xee, 0xb5, 0x0d, 0xf3, 0xb5,
+	0xe3, 0xbb, 0xb6, 0x1e, 0x2f, 0x12, 0x93, 0xd6, 0xa8, 0x87, 0xf2, 0x4d, 0x21, 0xde, 0x1c, 0xa6,
+	0x7b, 0x67, 0x2e, 0xd3, 0x3b, 0x0e, 0x94, 0xf9, 0x3d, 0x8a, 0x87, 0xce, 0xd2, 0xc4, 0x15, 0x9e,
+	0x9c, 0x1b, 0x86, 0xe9, 0xaf, 0x77, 0xc2, 0x37, 0xf4, 0xef, 0x47, 0x2b, 0x2f, 0xb4, 0x6a, 0x44,
+	0xf6, 0x9d, 0xa1, 0xe1, 0x31, 0xe2, 0x6b, 0xe2, 0x14, 0xfc, 0x2e, 0x94, 0xa3, 0xe1, 0xd0, 0x2c,
+	0xf2, 0xf3, 0x16, 0xe2, 0x92, 0xa5, 0xe7, 0x87, 0x80, 0xa8, 0x3f, 0x20, 0x28, 0x45, 0x91, 0xbe,
+	0xaa, 0x3e, 0x92, 0x61, 0x9e, 0x38, 0x03, 0x77, 0x68, 0x3a, 0x23, 0x7e, 0x7d, 0x4b, 0x5a, 0xf2,
+	0x8d, 0xb1, 0xb8, 0x56, 0xe1, 0x3d, 0xad, 0x89, 0xbb, 0xd3, 0x84, 0x23, 0x3d, 0xdf, 0x70, 0xe8,
+	0x0e, 0xf1, 0xb9, 0x63, 0x49, 0xd3, 0xa8, 0x1d, 0x58, 0xc8, 0x74, 0x53, 0x66, 0xe7, 0x40, 0xfb,
+	0xda, 0x39, 0x74, 0xa8, 0xa5, 0x35, 0xf8, 0x24, 0x14, 0xd9, 0x3d, 0x2f, 0x7a, 0xa1, 0xea, 0x6b,
+	0x8b, 0xb1, 0x35, 0x57, 0xf7, 0xee, 0x79, 0x44, 0xe3, 0xea, 0xd0, 0x4f, 0x3e, 0xb2, 0xa2, 0xc2,
+	0xf2, 0xdf, 0xf8, 0x10, 0x94, 0xf8, 0x14, 0xe0, 0x41, 0x55, 0xb4, 0xe8, 0x43, 0xfd, 0x1e, 0x41,
+	0x7d, 0xd2, 0x43, 0x97, 0x4c, 0x8b, 0xbc, 0x8c, 0x16, 0x92, 0x61, 0x7e, 0xc7, 0xb4, 0x08, 0xf7,
+	0x21, 0x3a, 0x2e, 0xf9, 0x9e, 0x96, 0xc3, 0x77, 0x3e, 0x83, 0x4a, 0x12, 0x02, 0xae, 0x40, 0x69,
+	0xe3, 0xe6, 0xad, 0xce, 0xb5, 0x86, 0x84, 0x17, 0xa0, 0x72, 0x7d, 0xab, 0xa7, 0x47, 0x9f, 0x08,
+	0x1f, 0x84, 0xaa, 0xb6, 0x71, 0x79, 0xe3, 0x4b, 0xbd, 0xdb, 0xe9, 0x5d, 0xb8, 0xd2, 0x98, 0xc3,
+	0x18, 0xea, 0x91, 0xe0, 0xfa, 0x96, 0x90, 0x15, 0xd6, 0xfe, 0x2a, 0xc3, 0x7c, 0xec, 0x23, 0x3e,
+	0x07, 0xc5, 0x1b, 0x01, 0xdd, 0xc5, 0x47, 0x26, 0x3d, 0xfc, 0x85, 0x6f, 0x32, 0x22, 0xee, 0xa4,
+	0xbc, 0xbc, 0x47, 0x2e, 0x6a, 0x27, 0xe1, 0x8f, 0xa0, 0xc4, 0x17, 0x0c, 0x3c, 0x75, 0xe5, 0x95,
+	0xa7, 0x2f, 0xb2, 0xaa, 0x84, 0x2f, 0x42, 0x35, 0xb5, 0x34, 0xcd, 0xb0, 0x3e, 0x96, 0x91, 0x66,
+	0xf7, 0x2b, 0x55, 0x3a, 0x8d, 0xf0, 0x16, 0xd4, 0xb9, 0x2a, 0xde, 0x75, 0x28, 0x7e, 0x23, 0x36,
+	0x99, 0xb6, 0x83, 0xca, 0xc7, 0x67, 0x68, 0x13, 0xb7, 0xae, 0x40, 0x35, 0xb5, 0x21, 0x60, 0x39,
+	0xd3, 0x78, 0x99, 0xb5, 0x47, 0x3e, 0x36, 0x55, 0x97, 0x30, 0x6d, 0x00, 0x4c, 0x06, 0x32, 0x3e,
+	0x9a, 0x01, 0xa7, 0x97, 0x08, 0x59, 0x9e, 0xa6, 0x4a, 0x68, 0xd6, 0xa1, 0x92, 0x8c, 0x23, 0xdc,
+	0x9c, 0x32, 0xa1, 0x22, 0x92, 0xd9, 0xb3, 0x4b, 0x95, 0xf0, 0x25, 0xa8, 0x75, 0x2c, 0x6b, 0x3f,
+	0x34, 0x72, 0x5a, 0x43, 0xf3, 0x3c, 0x16, 0x2c, 0xcf, 0x98, 0x00, 0xf8, 0xad, 0xe4, 0x8e, 0x3d,
+	0x73, 0xac, 0xc9, 0x6f, 0x3f, 0x17, 0x97, 0x9c, 0xd6, 0x83, 0x83, 0xb9, 0x41, 0x80, 0x95, 0x9c,
+	0x75, 0x6e, 0x76, 0xc8, 0x2b, 0x33, 0xf5, 0x09, 0x6b, 0x17, 0xea, 0xd9, 0x77, 0x08, 0xcf, 0x5a,
+	0xc9, 0xe5, 0xe4, 0xb4, 0x19, 0x0f, 0x97, 0xd4, 0x42, 0xeb, 0x9f, 0x3c, 0x78, 0xac, 0x48, 0x0f,
+	0x1f, 0x2b, 0xd2, 0xd3, 0xc7, 0x0a, 0xfa, 0x6e, 0xac, 0xa0, 0xdf, 0xc6, 0x0a, 0xba, 0x3f, 0x56,
+	0xd0, 0x83, 0xb1, 0x82, 0xfe, 0x19, 0x2b, 0xe8, 0xdf, 0xb1, 0x22, 0x3d, 0x1d, 0x2b, 0xe8, 0xc7,
+	0x27, 0x8a, 0xf4, 0xe0, 0x89, 0x22, 0x3d, 0x7c, 0xa2, 0x48, 0x5f, 0x95, 0x07, 0x96, 0x49, 0x1c,
+	0xd6, 0x2f, 0xf3, 0x7f, 0x93, 0xef, 0xff, 0x3f, 0x00, 0xf2, 0xe0, 0xb5, 0x58, 0xd1, 0x0e, 0x00,
+	0x00,
 }
 
 func (x MatchType) String() string {
@@ -1747,6 +1755,9 @@ func (this *LabelNamesRequest) Equal(that interface{}) bool {
 	if this.EndTimestampMs != that1.EndTimestampMs {
 		return false
 	}
+	if !this.Matchers.Equal(that1.Matchers) {
+		return false
+	}
 	return true
 }
 func (this *LabelNamesResponse) Equal(that interface{}) bool {
@@ -2327,10 +2338,13 @@ func (this *LabelNamesRequest) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 6)
+	s := make([]string, 0, 7)
 	s = append(s, "&client.LabelNamesRequest{")
 	s = append(s, "StartTimestampMs: "+fmt.Sprintf("%#v", this.StartTimestampMs)+",\n")
 	s = append(s, "EndTimestampMs: "+fmt.Sprintf("%#v", this.EndTimestampMs)+",\n")
+	if this.Matchers != nil {
+		s = append(s, "Matchers: "+fmt.Sprintf("%#v", this.Matchers)+",\n")
+	}
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -3426,6 +3440,18 @@ func (m *LabelNamesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Matchers != nil {
+		{
+			size, err := m.Matchers.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintIngester(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
 	if m.EndTimestampMs != 0 {
 		i = encodeVarintIngester(dAtA, i, uint64(m.EndTimestampMs))
 		i--
@@ -4210,6 +4236,10 @@ func (m *LabelNamesRequest) Size() (n int) {
 	if m.EndTimestampMs != 0 {
 		n += 1 + sovIngester(uint64(m.EndTimestampMs))
 	}
+	if m.Matchers != nil {
+		l = m.Matchers.Size()
+		n += 1 + l + sovIngester(uint64(l))
+	}
 	return n
 }
 
@@ -4621,6 +4651,7 @@ func (this *LabelNamesRequest) String() string {
 	s := strings.Join([]string{`&LabelNamesRequest{`,
 		`StartTimestampMs:` + fmt.Sprintf("%v", this.StartTimestampMs) + `,`,
 		`EndTimestampMs:` + fmt.Sprintf("%v", this.EndTimestampMs) + `,`,
+		`Matchers:` + strings.Replace(this.Matchers.String(), "LabelMatchers", "LabelMatchers", 1) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -5857,6 +5888,42 @@ func (m *LabelNamesRequest) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Matchers", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowIngester
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthIngester
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthIngester
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Matchers == nil {
+				m.Matchers = &LabelMatchers{}
+			}
+			if err := m.Matchers.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipIngester(dAtA[iNdEx:])
@@ -0,0 +1,69 @@
+package ingester
+
+import (
+	"sync"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+const numHASeriesTimestampsShards = 128
+
+type haSeriesTimestampsShard struct {
+	mtx sync.Mutex
+	m   map[uint64]int64
+}
+
+// haSeriesTimestamps tracks the timestamp of the latest ingested sample of the series
+// pushed by HA replicas, when deduplicating their samples. Series are identified by
+// their labels hash.
+type haSeriesTimestamps struct {
+	shards []haSeriesTimestampsShard
+}
+
+func newHASeriesTimestamps() *haSeriesTimestamps {
+	shards := make([]haSeriesTimestampsShard, 0, numHASeriesTimestampsShards)
+	for i := 0; i < numHASeriesTimestampsShards; i++ {
+		shards = append(shards, haSeriesTimestampsShard{
+			m: map[uint64]int64{},
+		})
+	}
+	return &haSeriesTimestamps{shards: shards}
+}
+
+func (h *haSeriesTimestamps) getShard(hash uint64) *haSeriesTimestampsShard {
+	return &h.shards[hash%numHASeriesTimestampsShards]
+}
+
+// accept returns whether a sample of the series should be appended, or dropped because it's
+// been received less than minInterval after the latest sample recorded for the series.
+func (h *haSeriesTimestamps) accept(hash uint64, ts, minInterval int64) bool {
+	shard := h.getShard(hash)
+	shard.mtx.Lock()
+	defer shard.mtx.Unlock()
+
+	latest, ok := shard.m[hash]
+	return !ok || ts >= latest+minInterval
+}
+
+// record records the timestamp of a sample of the series, once it's been successfully appended.
+func (h *haSeriesTimestamps) record(hash uint64, ts int64) {
+	shard := h.getShard(hash)
+	shard.mtx.Lock()
+	defer shard.mtx.Unlock()
+
+	if latest, ok := shard.m[hash]; !ok || ts > latest {
+		shard.m[hash] = ts
+	}
+}
+
+// delete stops tracking the series, once they've been removed from the head.
+func (h *haSeriesTimestamps) delete(metrics ...labels.Labels) {
+	for _, metric := range metrics {
+		hash := metric.Hash()
+
+		shard := h.getShard(hash)
+		shard.mtx.Lock()
+		delete(shard.m, hash)
+		shard.mtx.Unlock()
+	}
+}
@@ -0,0 +1,36 @@
+package ingester
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHASeriesTimestamps(t *testing.T) {
+	const minInterval = 10
+
+	metric := labels.FromStrings(labels.MetricName, "test", "cluster", "c1")
+	hash := metric.Hash()
+	h := newHASeriesTimestamps()
+
+	// A sample of a series not tracked yet is always accepted.
+	assert.True(t, h.accept(hash, 100, minInterval))
+
+	// A sample which has not been recorded (eg. because its append failed) doesn't
+	// cause the following samples to be dropped.
+	assert.True(t, h.accept(hash, 105, minInterval))
+
+	// Once a sample has been recorded, the samples received within the min interval are dropped.
+	h.record(hash, 105)
+	assert.False(t, h.accept(hash, 110, minInterval))
+	assert.True(t, h.accept(hash, 115, minInterval))
+
+	// An older sample doesn't move the recorded timestamp back.
+	h.record(hash, 90)
+	assert.False(t, h.accept(hash, 110, minInterval))
+
+	// The series is not tracked anymore once deleted.
+	h.delete(metric)
+	assert.True(t, h.accept(hash, 100, minInterval))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -204,6 +205,9 @@ type Ingester struct {
 	// Prometheus block storage
 	TSDBState TSDBState
 
+	// Progress of the preparation for downscale. Only used by V2-ingester.
+	prepareDownscale prepareDownscaleStatus
+
 	// Rate of pushed samples. Only used by V2-ingester to limit global samples push rate.
 	ingestionRate        *util_math.EwmaRate
 	inflightPushRequests atomic.Int64
@@ -968,8 +972,32 @@ func (i *Ingester) LabelNames(ctx context.Context, req *client.LabelNamesRequest
 		return &client.LabelNamesResponse{}, nil
 	}
 
+	// TODO Right now we ignore start and end.
+	_, _, matchers, err := client.FromLabelNamesRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
 	resp := &client.LabelNamesResponse{}
-	resp.LabelNames = append(resp.LabelNames, state.index.LabelNames()...)
+	if len(matchers) == 0 {
+		resp.LabelNames = append(resp.LabelNames, state.index.LabelNames()...)
+		return resp, nil
+	}
+
+	names := map[string]struct{}{}
+	if err := state.forSeriesMatching(ctx, matchers, func(ctx context.Context, fp model.Fingerprint, series *memorySeries) error {
+		for _, l := range series.metric {
+			names[l.Name] = struct{}{}
+		}
+		return nil
+	}, nil, 0); err != nil {
+		return nil, err
+	}
+
+	for name := range names {
+		resp.LabelNames = append(resp.LabelNames, name)
+	}
+	sort.Strings(resp.LabelNames)
 
 	return resp, nil
 }
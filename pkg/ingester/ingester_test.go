@@ -506,6 +506,28 @@ func TestIngesterAppendBlankLabel(t *testing.T) {
 	assert.Equal(t, expected, res)
 }
 
+func TestIngesterLabelNames(t *testing.T) {
+	_, ing := newDefaultTestStore(t)
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	require.NoError(t, ing.append(ctx, userID, labelPairs{{Name: model.MetricNameLabel, Value: "test_1"}, {Name: "status", Value: "200"}}, 1, 0, cortexpb.API, nil))
+	require.NoError(t, ing.append(ctx, userID, labelPairs{{Name: model.MetricNameLabel, Value: "test_2"}, {Name: "route", Value: "get_user"}}, 1, 0, cortexpb.API, nil))
+
+	// Without matchers, all label names are returned.
+	res, err := ing.LabelNames(ctx, &client.LabelNamesRequest{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{model.MetricNameLabel, "route", "status"}, res.LabelNames)
+
+	// With matchers, only the label names of the matching series are returned.
+	req, err := client.ToLabelNamesRequest(0, model.Latest, []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "test_2")})
+	require.NoError(t, err)
+
+	res, err = ing.LabelNames(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{model.MetricNameLabel, "route"}, res.LabelNames)
+}
+
 func TestIngesterUserLimitExceeded(t *testing.T) {
 	limits := defaultLimitsTestConfig()
 	limits.MaxLocalSeriesPerUser = 1
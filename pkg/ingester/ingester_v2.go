@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -23,6 +24,7 @@ import (
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	tsdb_errors "github.com/prometheus/prometheus/tsdb/errors"
 	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/shipper"
@@ -114,6 +116,16 @@ type userTSDB struct {
 	seriesInMetric *metricCounter
 	limiter        *Limiter
 
+	// Series count of the tenant's metric limit rules.
+	seriesInMetricLimits *metricLimitsCounter
+
+	// Series count of each label value, to enforce the max label values per label name limit.
+	labelValues *labelValuesCounter
+
+	// Latest ingested sample timestamp of the series pushed by HA replicas,
+	// when deduplicating their samples.
+	haSeriesTimestamps *haSeriesTimestamps
+
 	instanceSeriesCount *atomic.Int64 // Shared across all userTSDB instances created by ingester.
 	instanceLimitsFn    func() *InstanceLimits
 
@@ -127,6 +139,13 @@ type userTSDB struct {
 	// Thanos shipper used to ship blocks to the storage.
 	shipper Shipper
 
+	// Bucket client used to upload the exemplars of the blocks before shipping them
+	// (nil if exemplars or blocks shipping are disabled).
+	exemplarsBucket objstore.Bucket
+
+	// The blocks whose exemplars have already been uploaded. Only accessed while shipping blocks.
+	exemplarsUploadedBlocks map[ulid.ULID]struct{}
+
 	// When deletion marker is found for the tenant (checked before shipping),
 	// shipping stops and TSDB is closed before reaching idle timeout time (if enabled).
 	deletionMarkFound atomic.Bool
@@ -252,6 +271,16 @@ func (u *userTSDB) PreCreation(metric labels.Labels) error {
 		return err
 	}
 
+	// Series per metric limit rule.
+	if err := u.seriesInMetricLimits.canAddSeries(metric); err != nil {
+		return err
+	}
+
+	// Values per label name limit.
+	if err := u.labelValues.canAddSeries(metric); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -265,13 +294,20 @@ func (u *userTSDB) PostCreation(metric labels.Labels) {
 		return
 	}
 	u.seriesInMetric.increaseSeriesForMetric(metricName)
+	u.seriesInMetricLimits.increaseSeries(metric)
+	u.labelValues.increaseSeries(metric)
 }
 
 // PostDeletion implements SeriesLifecycleCallback interface.
 func (u *userTSDB) PostDeletion(metrics ...labels.Labels) {
 	u.instanceSeriesCount.Sub(int64(len(metrics)))
 
+	u.haSeriesTimestamps.delete(metrics...)
+
 	for _, metric := range metrics {
+		u.seriesInMetricLimits.decreaseSeries(metric)
+		u.labelValues.decreaseSeries(metric)
+
 		metricName, err := extract.MetricNameFromLabels(metric)
 		if err != nil {
 			// This should never happen because it has already been checked in PreCreation().
@@ -335,6 +371,104 @@ func (u *userTSDB) getCachedShippedBlocks() map[ulid.ULID]struct{} {
 	return u.shippedBlocks
 }
 
+// uploadBlocksExemplars uploads the exemplars of the blocks not shipped yet. The exemplars of a block are
+// selected from the TSDB exemplars storage and persisted in the block directory the first time, so that
+// they're not lost if the in-memory exemplars are evicted or the ingester restarts before shipping.
+// The exemplars of each block are uploaded only once, and a failure doesn't prevent the upload of the
+// other blocks exemplars.
+func (u *userTSDB) uploadBlocksExemplars(ctx context.Context) error {
+	if u.exemplarsBucket == nil {
+		return nil
+	}
+
+	shippedBlocks := u.getCachedShippedBlocks()
+	blocks := u.Blocks()
+	errs := tsdb_errors.NewMulti()
+
+	if u.exemplarsUploadedBlocks == nil {
+		u.exemplarsUploadedBlocks = map[ulid.ULID]struct{}{}
+	}
+
+	// Forget about the blocks which don't exist anymore.
+	local := make(map[ulid.ULID]struct{}, len(blocks))
+	for _, b := range blocks {
+		local[b.Meta().ULID] = struct{}{}
+	}
+	for id := range u.exemplarsUploadedBlocks {
+		if _, ok := local[id]; !ok {
+			delete(u.exemplarsUploadedBlocks, id)
+		}
+	}
+
+	for _, b := range blocks {
+		meta := b.Meta()
+		if _, ok := shippedBlocks[meta.ULID]; ok {
+			continue
+		}
+		if _, ok := u.exemplarsUploadedBlocks[meta.ULID]; ok {
+			continue
+		}
+
+		if err := u.uploadBlockExemplars(ctx, b); err != nil {
+			errs.Add(errors.Wrapf(err, "block %s", meta.ULID.String()))
+			continue
+		}
+
+		u.exemplarsUploadedBlocks[meta.ULID] = struct{}{}
+	}
+
+	return errs.Err()
+}
+
+func (u *userTSDB) uploadBlockExemplars(ctx context.Context, b *tsdb.Block) error {
+	meta := b.Meta()
+
+	series, ok, err := cortex_tsdb.ReadBlockExemplarsFile(b.Dir())
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		if series, err = u.selectBlockExemplars(ctx, meta.MinTime, meta.MaxTime); err != nil {
+			return err
+		}
+		if err := cortex_tsdb.WriteBlockExemplarsFile(b.Dir(), series); err != nil {
+			return err
+		}
+	}
+
+	// Blocks without exemplars have no exemplars file in the storage.
+	if len(series) == 0 {
+		return nil
+	}
+
+	return cortex_tsdb.UploadBlockExemplars(ctx, u.exemplarsBucket, meta.ULID, series)
+}
+
+// selectBlockExemplars returns the exemplars of all series within the input block time range.
+func (u *userTSDB) selectBlockExemplars(ctx context.Context, minT, maxT int64) ([]cortexpb.TimeSeries, error) {
+	q, err := u.ExemplarQuerier(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The block max time is exclusive, while the exemplars time range is inclusive.
+	res, err := q.Select(minT, maxT-1, []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, ".+")})
+	if err != nil {
+		return nil, err
+	}
+
+	series := make([]cortexpb.TimeSeries, 0, len(res))
+	for _, r := range res {
+		series = append(series, cortexpb.TimeSeries{
+			Labels:    cortexpb.FromLabelsToLabelAdapters(r.SeriesLabels),
+			Exemplars: cortexpb.FromExemplarsToExemplarProtos(r.Exemplars),
+		})
+	}
+
+	return series, nil
+}
+
 // getOldestUnshippedBlockTime returns the unix timestamp with milliseconds precision of the oldest
 // TSDB block not shipped to the storage yet, or 0 if all blocks have been shipped.
 func (u *userTSDB) getOldestUnshippedBlockTime() uint64 {
@@ -703,17 +837,51 @@ func (i *Ingester) v2UpdateActiveSeries() {
 			continue
 		}
 
+		// Reload the custom trackers if they've changed.
+		if !userDB.activeSeries.CurrentMatchers().Equals(i.limits.ActiveSeriesCustomTrackers(userID)) {
+			if asm := i.activeSeriesMatchers(userID); asm != nil {
+				userDB.activeSeries.ReloadMatchers(asm)
+				i.metrics.deleteActiveSeriesCustomTrackersForUser(userID)
+			}
+		}
+
 		userDB.activeSeries.Purge(purgeTime)
-		i.metrics.activeSeriesPerUser.WithLabelValues(userID).Set(float64(userDB.activeSeries.Active()))
+
+		active, activeMatching := userDB.activeSeries.ActiveWithMatchers()
+		i.metrics.activeSeriesPerUser.WithLabelValues(userID).Set(float64(active))
+		for idx, name := range userDB.activeSeries.CurrentMatchers().Names() {
+			i.metrics.activeSeriesCustomTrackersPerUser.WithLabelValues(userID, name).Set(float64(activeMatching[idx]))
+		}
 	}
 }
 
+// activeSeriesMatchers returns the matchers of the user's active series custom trackers,
+// or nil if they can't be parsed.
+func (i *Ingester) activeSeriesMatchers(userID string) *ActiveSeriesMatchers {
+	asm, err := NewActiveSeriesMatchers(i.limits.ActiveSeriesCustomTrackers(userID))
+	if err != nil {
+		level.Warn(i.logger).Log("msg", "failed to parse the active series custom trackers", "user", userID, "err", err)
+		return nil
+	}
+	return asm
+}
+
 // GetRef() is an extra method added to TSDB to let Cortex check before calling Add()
 type extendedAppender interface {
 	storage.Appender
 	storage.GetRef
 }
 
+// hasLabel returns whether the labels contain the label name.
+func hasLabel(lbls []cortexpb.LabelAdapter, name string) bool {
+	for _, l := range lbls {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // v2Push adds metrics to a block
 func (i *Ingester) v2Push(ctx context.Context, req *cortexpb.WriteRequest) (*cortexpb.WriteResponse, error) {
 	var firstPartialErr error
@@ -747,6 +915,11 @@ func (i *Ingester) v2Push(ctx context.Context, req *cortexpb.WriteRequest) (*cor
 	}
 	i.userStatesMtx.RUnlock()
 
+	// Ensure the ingester hasn't been switched to read-only before being scaled down.
+	if i.prepareDownscale.isReadOnly() {
+		return nil, errIngesterReadOnly
+	}
+
 	if err := db.acquireAppendLock(); err != nil {
 		return &cortexpb.WriteResponse{}, httpgrpc.Errorf(http.StatusServiceUnavailable, wrapWithUser(err, userID).Error())
 	}
@@ -769,6 +942,20 @@ func (i *Ingester) v2Push(ctx context.Context, req *cortexpb.WriteRequest) (*cor
 		newValueForTimestampCount = 0
 		perUserSeriesLimitCount   = 0
 		perMetricSeriesLimitCount = 0
+		haDeduplicatedCount       = 0
+
+		// Samples discarded because of the series limit of the metric limit rules, by rule.
+		metricLimitSeriesCount map[string]int
+
+		// Samples discarded because of the max label values per label name limit, by label name.
+		labelValuesLimitCount map[string]int
+
+		// When deduplicating the samples from HA replicas, the replicas push the same series,
+		// so duplicated and out of order samples are expected and not reported as errors for
+		// the series having the HA cluster label (the distributors only remove the replica label).
+		haDeduplication         = i.limits.HADeduplicationMode(userID) == validation.HADeduplicationSamples
+		haClusterLabel          = i.limits.HAClusterLabel(userID)
+		haDeduplicationInterval = i.limits.HADeduplicationInterval(userID).Milliseconds()
 
 		updateFirstPartial = func(errFn func() error) {
 			if firstPartialErr == nil {
@@ -789,23 +976,49 @@ func (i *Ingester) v2Push(ctx context.Context, req *cortexpb.WriteRequest) (*cor
 		// To find out if any sample was added to this series, we keep old value.
 		oldSucceededSamplesCount := succeededSamplesCount
 
+		// Samples of the series pushed by HA replicas, which don't line up with the
+		// latest ingested sample, are dropped so that replicas are not interleaved.
+		var (
+			haSeries     = haDeduplication && hasLabel(ts.Labels, haClusterLabel)
+			haSeriesHash uint64
+		)
+		if haSeries && haDeduplicationInterval > 0 {
+			haSeriesHash = cortexpb.FromLabelAdaptersToLabels(ts.Labels).Hash()
+		}
+
 		for _, s := range ts.Samples {
 			var err error
 
+			if haSeries && haDeduplicationInterval > 0 && !db.haSeriesTimestamps.accept(haSeriesHash, s.TimestampMs, haDeduplicationInterval) {
+				haDeduplicatedCount++
+				continue
+			}
+
 			// If the cached reference exists, we try to use it.
 			if ref != 0 {
-				if _, err = app.Append(ref, copiedLabels, s.TimestampMs, s.Value); err == nil {
-					succeededSamplesCount++
-					continue
-				}
-
+				_, err = app.Append(ref, copiedLabels, s.TimestampMs, s.Value)
 			} else {
 				// Copy the label set because both TSDB and the active series tracker may retain it.
 				copiedLabels = cortexpb.FromLabelAdaptersToLabelsWithCopy(ts.Labels)
 
 				// Retain the reference in case there are multiple samples for the series.
-				if ref, err = app.Append(0, copiedLabels, s.TimestampMs, s.Value); err == nil {
-					succeededSamplesCount++
+				ref, err = app.Append(0, copiedLabels, s.TimestampMs, s.Value)
+			}
+
+			if err == nil {
+				// The timestamp is recorded only once the sample has been appended, so that
+				// a failed sample doesn't cause the following ones to be dropped.
+				if haSeries && haDeduplicationInterval > 0 {
+					db.haSeriesTimestamps.record(haSeriesHash, s.TimestampMs)
+				}
+
+				succeededSamplesCount++
+				continue
+			}
+
+			if haSeries {
+				if cause := errors.Cause(err); cause == storage.ErrOutOfOrderSample || cause == storage.ErrDuplicateSampleForTimestamp {
+					haDeduplicatedCount++
 					continue
 				}
 			}
@@ -845,6 +1058,30 @@ func (i *Ingester) v2Push(ctx context.Context, req *cortexpb.WriteRequest) (*cor
 				continue
 			}
 
+			var labelValuesErr *labelValuesLimitError
+			if errors.As(err, &labelValuesErr) {
+				if labelValuesLimitCount == nil {
+					labelValuesLimitCount = map[string]int{}
+				}
+				labelValuesLimitCount[labelValuesErr.labelName]++
+				updateFirstPartial(func() error {
+					return makeMetricLimitError(perLabelNameValuesLimit, copiedLabels, i.limiter.FormatError(userID, labelValuesErr))
+				})
+				continue
+			}
+
+			var ruleErr *metricLimitSeriesError
+			if errors.As(err, &ruleErr) {
+				if metricLimitSeriesCount == nil {
+					metricLimitSeriesCount = map[string]int{}
+				}
+				metricLimitSeriesCount[ruleErr.rule]++
+				updateFirstPartial(func() error {
+					return makeMetricLimitError(validation.MetricLimitMaxSeriesReason(ruleErr.rule), copiedLabels, i.limiter.FormatError(userID, ruleErr))
+				})
+				continue
+			}
+
 			// The error looks an issue on our side, so we should rollback
 			if rollbackErr := app.Rollback(); rollbackErr != nil {
 				level.Warn(i.logger).Log("msg", "failed to rollback on error", "user", userID, "err", rollbackErr)
@@ -930,6 +1167,16 @@ func (i *Ingester) v2Push(ctx context.Context, req *cortexpb.WriteRequest) (*cor
 	if perMetricSeriesLimitCount > 0 {
 		validation.DiscardedSamples.WithLabelValues(perMetricSeriesLimit, userID).Add(float64(perMetricSeriesLimitCount))
 	}
+	for labelName, count := range labelValuesLimitCount {
+		validation.DiscardedSamples.WithLabelValues(perLabelNameValuesLimit, userID).Add(float64(count))
+		i.metrics.labelValuesLimitDiscardedSamples.WithLabelValues(userID, labelName).Add(float64(count))
+	}
+	for rule, count := range metricLimitSeriesCount {
+		validation.DiscardedSamples.WithLabelValues(validation.MetricLimitMaxSeriesReason(rule), userID).Add(float64(count))
+	}
+	if haDeduplicatedCount > 0 {
+		i.metrics.haDeduplicatedSamples.WithLabelValues(userID).Add(float64(haDeduplicatedCount))
+	}
 
 	// Distributor counts both samples and metadata, so for consistency ingester does the same.
 	i.ingestionRate.Add(int64(succeededSamplesCount + ingestedMetadata))
@@ -1120,6 +1367,11 @@ func (i *Ingester) v2LabelValues(ctx context.Context, req *client.LabelValuesReq
 }
 
 func (i *Ingester) v2LabelNames(ctx context.Context, req *client.LabelNamesRequest) (*client.LabelNamesResponse, error) {
+	startTimestampMs, endTimestampMs, matchers, err := client.FromLabelNamesRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
 	userID, err := tenant.TenantID(ctx)
 	if err != nil {
 		return nil, err
@@ -1130,7 +1382,7 @@ func (i *Ingester) v2LabelNames(ctx context.Context, req *client.LabelNamesReque
 		return &client.LabelNamesResponse{}, nil
 	}
 
-	mint, maxt, err := metadataQueryRange(req.StartTimestampMs, req.EndTimestampMs, db)
+	mint, maxt, err := metadataQueryRange(startTimestampMs, endTimestampMs, db)
 	if err != nil {
 		return nil, err
 	}
@@ -1141,7 +1393,12 @@ func (i *Ingester) v2LabelNames(ctx context.Context, req *client.LabelNamesReque
 	}
 	defer q.Close()
 
-	names, _, err := q.LabelNames()
+	var names []string
+	if len(matchers) == 0 {
+		names, _, err = q.LabelNames()
+	} else {
+		names, err = labelNamesForMatchers(q, mint, maxt, matchers)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1151,6 +1408,31 @@ func (i *Ingester) v2LabelNames(ctx context.Context, req *client.LabelNamesReque
 	}, nil
 }
 
+// labelNamesForMatchers returns the sorted label names of the series matching the input matchers.
+// The series are selected without chunks, so only the postings and series labels are read.
+func labelNamesForMatchers(q storage.Querier, mint, maxt int64, matchers []*labels.Matcher) ([]string, error) {
+	hints := &storage.SelectHints{Start: mint, End: maxt, Func: "series"}
+	set := q.Select(false, hints, matchers...)
+
+	names := map[string]struct{}{}
+	for set.Next() {
+		for _, l := range set.At().Labels() {
+			names[l.Name] = struct{}{}
+		}
+	}
+	if err := set.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
 func (i *Ingester) v2MetricsForLabelMatchers(ctx context.Context, req *client.MetricsForLabelMatchersRequest) (*client.MetricsForLabelMatchersResponse, error) {
 	userID, err := tenant.TenantID(ctx)
 	if err != nil {
@@ -1556,11 +1838,14 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 	blockRanges := i.cfg.BlocksStorageConfig.TSDB.BlockRanges.ToMilliseconds()
 
 	userDB := &userTSDB{
-		userID:              userID,
-		activeSeries:        NewActiveSeries(),
-		seriesInMetric:      newMetricCounter(i.limiter, i.cfg.getIgnoreSeriesLimitForMetricNamesMap()),
-		ingestedAPISamples:  util_math.NewEWMARate(0.2, i.cfg.RateUpdatePeriod),
-		ingestedRuleSamples: util_math.NewEWMARate(0.2, i.cfg.RateUpdatePeriod),
+		userID:               userID,
+		activeSeries:         NewActiveSeries(i.activeSeriesMatchers(userID)),
+		seriesInMetric:       newMetricCounter(i.limiter, i.cfg.getIgnoreSeriesLimitForMetricNamesMap()),
+		seriesInMetricLimits: newMetricLimitsCounter(i.limiter, userID),
+		labelValues:          newLabelValuesCounter(i.limiter, userID),
+		haSeriesTimestamps:   newHASeriesTimestamps(),
+		ingestedAPISamples:   util_math.NewEWMARate(0.2, i.cfg.RateUpdatePeriod),
+		ingestedRuleSamples:  util_math.NewEWMARate(0.2, i.cfg.RateUpdatePeriod),
 
 		instanceLimitsFn:    i.getInstanceLimits,
 		instanceSeriesCount: &i.TSDBState.seriesCount,
@@ -1623,11 +1908,13 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 
 	// Create a new shipper for this database
 	if i.cfg.BlocksStorageConfig.TSDB.IsBlocksShippingEnabled() {
+		userBucket := bucket.NewUserBucketClient(userID, i.TSDBState.bucket, i.limits)
+
 		userDB.shipper = shipper.New(
 			userLogger,
 			tsdbPromReg,
 			udir,
-			bucket.NewUserBucketClient(userID, i.TSDBState.bucket, i.limits),
+			userBucket,
 			func() labels.Labels { return l },
 			metadata.ReceiveSource,
 			false, // No need to upload compacted blocks. Cortex compactor takes care of that.
@@ -1635,6 +1922,10 @@ func (i *Ingester) createTSDB(userID string) (*userTSDB, error) {
 			metadata.NoneFunc,
 		)
 
+		if i.cfg.BlocksStorageConfig.TSDB.MaxExemplars > 0 {
+			userDB.exemplarsBucket = userBucket
+		}
+
 		// Initialise the shipper blocks cache.
 		if err := userDB.updateCachedShippedBlocks(); err != nil {
 			level.Error(userLogger).Log("msg", "failed to update cached shipped blocks after shipper initialisation", "err", err)
@@ -1673,6 +1964,7 @@ func (i *Ingester) closeAllTSDB() {
 
 			i.metrics.memUsers.Dec()
 			i.metrics.activeSeriesPerUser.DeleteLabelValues(userID)
+			i.metrics.deleteActiveSeriesCustomTrackersForUser(userID)
 		}(userDB)
 	}
 
@@ -1887,6 +2179,13 @@ func (i *Ingester) shipBlocks(ctx context.Context, allowed *util.AllowedTenants)
 		}
 		defer userDB.casState(activeShipping, active)
 
+		// The exemplars are uploaded before the blocks, so that they're available as soon as the
+		// blocks are discovered. Failing to upload them doesn't prevent shipping the blocks.
+		if err := userDB.uploadBlocksExemplars(ctx); err != nil {
+			i.metrics.blocksExemplarsUploadFailures.Inc()
+			level.Warn(i.logger).Log("msg", "failed to upload TSDB blocks exemplars to the storage", "user", userID, "err", err)
+		}
+
 		uploaded, err := userDB.shipper.Sync(ctx)
 		if err != nil {
 			level.Warn(i.logger).Log("msg", "shipper failed to synchronize TSDB blocks with the storage", "user", userID, "uploaded", uploaded, "err", err)
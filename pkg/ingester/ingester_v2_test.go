@@ -33,6 +33,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/shipper"
 	"github.com/weaveworks/common/httpgrpc"
@@ -45,6 +46,7 @@ import (
 	"github.com/cortexproject/cortex/pkg/cortexpb"
 	"github.com/cortexproject/cortex/pkg/ingester/client"
 	"github.com/cortexproject/cortex/pkg/ring"
+	"github.com/cortexproject/cortex/pkg/storage/bucket"
 	cortex_tsdb "github.com/cortexproject/cortex/pkg/storage/tsdb"
 	"github.com/cortexproject/cortex/pkg/util"
 	util_math "github.com/cortexproject/cortex/pkg/util/math"
@@ -671,6 +673,294 @@ func TestIngester_v2Push_ShouldCorrectlyTrackMetricsInMultiTenantScenario(t *tes
 	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expectedMetrics), metricNames...))
 }
 
+func TestIngester_v2Push_ShouldDeduplicateHASamples(t *testing.T) {
+	metricLabelAdapters := []cortexpb.LabelAdapter{{Name: labels.MetricName, Value: "test"}, {Name: "cluster", Value: "c1"}}
+	metricLabels := cortexpb.FromLabelAdaptersToLabels(metricLabelAdapters)
+	metricNames := []string{
+		"cortex_ingester_ingested_samples_total",
+		"cortex_ingester_ingested_samples_failures_total",
+		"cortex_ingester_ha_deduplicated_samples_total",
+	}
+
+	registry := prometheus.NewRegistry()
+
+	cfg := defaultIngesterTestConfig()
+	cfg.LifecyclerConfig.JoinAfter = 0
+
+	limits := defaultLimitsTestConfig()
+	limits.HADeduplicationMode = validation.HADeduplicationSamples
+	limits.HADeduplicationInterval = 0
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", registry)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until the ingester is ACTIVE
+	test.Poll(t, 100*time.Millisecond, ring.ACTIVE, func() interface{} {
+		return i.lifecycler.GetState()
+	})
+
+	// The samples of two replicas, scraping at the same time or not, are pushed for the same series.
+	ctx := user.InjectOrgID(context.Background(), "test")
+	for _, samples := range [][]cortexpb.Sample{
+		{{Value: 1, TimestampMs: 10}, {Value: 2, TimestampMs: 20}},
+		{{Value: 1.5, TimestampMs: 10}, {Value: 2, TimestampMs: 20}, {Value: 3, TimestampMs: 30}},
+		{{Value: 2.5, TimestampMs: 25}},
+	} {
+		series := make([]labels.Labels, 0, len(samples))
+		for range samples {
+			series = append(series, metricLabels)
+		}
+
+		_, err := i.v2Push(ctx, cortexpb.ToWriteRequest(series, samples, nil, cortexpb.API))
+		require.NoError(t, err)
+	}
+
+	// The same value for the same timestamp is ingested again by the TSDB, while the
+	// other duplicated and out of order samples are deduplicated.
+	expectedMetrics := `
+		# HELP cortex_ingester_ingested_samples_total The total number of samples ingested.
+		# TYPE cortex_ingester_ingested_samples_total counter
+		cortex_ingester_ingested_samples_total 4
+		# HELP cortex_ingester_ingested_samples_failures_total The total number of samples that errored on ingestion.
+		# TYPE cortex_ingester_ingested_samples_failures_total counter
+		cortex_ingester_ingested_samples_failures_total 0
+		# HELP cortex_ingester_ha_deduplicated_samples_total The total number of duplicated or out of order samples dropped per user, when deduplicating the samples from HA replicas.
+		# TYPE cortex_ingester_ha_deduplicated_samples_total counter
+		cortex_ingester_ha_deduplicated_samples_total{user="test"} 2
+	`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expectedMetrics), metricNames...))
+
+	res, err := i.v2Query(ctx, &client.QueryRequest{
+		StartTimestampMs: math.MinInt64,
+		EndTimestampMs:   math.MaxInt64,
+		Matchers:         []*client.LabelMatcher{{Type: client.EQUAL, Name: labels.MetricName, Value: "test"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []cortexpb.TimeSeries{{
+		Labels:  metricLabelAdapters,
+		Samples: []cortexpb.Sample{{Value: 1, TimestampMs: 10}, {Value: 2, TimestampMs: 20}, {Value: 3, TimestampMs: 30}},
+	}}, res.Timeseries)
+
+	// Out of order samples of series not pushed by HA replicas are still rejected.
+	nonHASeries := []labels.Labels{labels.FromStrings(labels.MetricName, "test")}
+	_, err = i.v2Push(ctx, cortexpb.ToWriteRequest(nonHASeries, []cortexpb.Sample{{Value: 1, TimestampMs: 20}}, nil, cortexpb.API))
+	require.NoError(t, err)
+	_, err = i.v2Push(ctx, cortexpb.ToWriteRequest(nonHASeries, []cortexpb.Sample{{Value: 1, TimestampMs: 10}}, nil, cortexpb.API))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), storage.ErrOutOfOrderSample.Error())
+}
+
+func TestIngester_v2Push_ShouldDeduplicateHASamplesWithUnalignedTimestamps(t *testing.T) {
+	metricLabelAdapters := []cortexpb.LabelAdapter{{Name: labels.MetricName, Value: "test"}, {Name: "cluster", Value: "c1"}}
+	metricLabels := cortexpb.FromLabelAdaptersToLabels(metricLabelAdapters)
+	registry := prometheus.NewRegistry()
+
+	cfg := defaultIngesterTestConfig()
+	cfg.LifecyclerConfig.JoinAfter = 0
+
+	limits := defaultLimitsTestConfig()
+	limits.HADeduplicationMode = validation.HADeduplicationSamples
+	limits.HADeduplicationInterval = model.Duration(10 * time.Second)
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", registry)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until the ingester is ACTIVE
+	test.Poll(t, 100*time.Millisecond, ring.ACTIVE, func() interface{} {
+		return i.lifecycler.GetState()
+	})
+
+	// Two replicas scrape the same target every 15s, with a 7s offset. Their samples
+	// are pushed as soon as they're scraped, until the first replica stops.
+	ctx := user.InjectOrgID(context.Background(), "test")
+	for _, ts := range []int64{0, 7, 15, 22, 30, 37, 45, 52, 67, 82} {
+		sample := cortexpb.Sample{Value: float64(ts), TimestampMs: ts * 1000}
+		_, err := i.v2Push(ctx, cortexpb.ToWriteRequest([]labels.Labels{metricLabels}, []cortexpb.Sample{sample}, nil, cortexpb.API))
+		require.NoError(t, err)
+	}
+
+	// The samples of the second replica are dropped, until the first replica stops.
+	assert.Equal(t, 4.0, testutil.ToFloat64(i.metrics.haDeduplicatedSamples.WithLabelValues("test")))
+
+	res, err := i.v2Query(ctx, &client.QueryRequest{
+		StartTimestampMs: math.MinInt64,
+		EndTimestampMs:   math.MaxInt64,
+		Matchers:         []*client.LabelMatcher{{Type: client.EQUAL, Name: labels.MetricName, Value: "test"}},
+	})
+	require.NoError(t, err)
+
+	var expected []cortexpb.Sample
+	for _, ts := range []int64{0, 15, 30, 45, 67, 82} {
+		expected = append(expected, cortexpb.Sample{Value: float64(ts), TimestampMs: ts * 1000})
+	}
+	assert.Equal(t, []cortexpb.TimeSeries{{Labels: metricLabelAdapters, Samples: expected}}, res.Timeseries)
+}
+
+func TestIngester_v2Push_ShouldEnforceMetricLimitsSeriesLimit(t *testing.T) {
+	cfg := defaultIngesterTestConfig()
+	cfg.LifecyclerConfig.JoinAfter = 0
+	cfg.DistributorShardByAllLabels = false
+
+	limits := defaultLimitsTestConfig()
+	limits.MetricLimits = []validation.MetricLimit{
+		{Name: "http", Matcher: `{__name__=~"http_.*"}`, MaxSeries: 2},
+		{Name: "unlimited", Matcher: `{__name__=~".+"}`},
+	}
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until the ingester is ACTIVE
+	test.Poll(t, 100*time.Millisecond, ring.ACTIVE, func() interface{} {
+		return i.lifecycler.GetState()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "metric-limits")
+	series := []labels.Labels{
+		labels.FromStrings(labels.MetricName, "http_requests_total", "path", "/a"),
+		labels.FromStrings(labels.MetricName, "http_requests_total", "path", "/b"),
+		labels.FromStrings(labels.MetricName, "http_requests_total", "path", "/c"),
+		labels.FromStrings(labels.MetricName, "grpc_requests_total", "path", "/a"),
+	}
+	samples := []cortexpb.Sample{{Value: 1, TimestampMs: 10}, {Value: 1, TimestampMs: 10}, {Value: 1, TimestampMs: 10}, {Value: 1, TimestampMs: 10}}
+
+	_, err = i.v2Push(ctx, cortexpb.ToWriteRequest(series, samples, nil, cortexpb.API))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "series limit of 2 of the metric limit rule http exceeded")
+	assert.Equal(t, 1.0, testutil.ToFloat64(validation.DiscardedSamples.WithLabelValues(validation.MetricLimitMaxSeriesReason("http"), "metric-limits")))
+
+	// The series not matching the rule are still created.
+	db := i.getTSDB("metric-limits")
+	require.NotNil(t, db)
+	assert.Equal(t, uint64(3), db.Head().NumSeries())
+
+	// Once a matching series is deleted, another one can be created.
+	db.PostDeletion(series[0])
+	_, err = i.v2Push(ctx, cortexpb.ToWriteRequest(series[2:3], samples[:1], nil, cortexpb.API))
+	require.NoError(t, err)
+}
+
+func TestIngester_v2Push_ShouldEnforceMaxLabelValuesPerLabelName(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	cfg := defaultIngesterTestConfig()
+	cfg.LifecyclerConfig.JoinAfter = 0
+
+	limits := defaultLimitsTestConfig()
+	limits.MaxLabelValuesPerLabelName = 2
+
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", registry)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until the ingester is ACTIVE
+	test.Poll(t, 100*time.Millisecond, ring.ACTIVE, func() interface{} {
+		return i.lifecycler.GetState()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "label-values")
+	push := func(series ...labels.Labels) error {
+		samples := make([]cortexpb.Sample, 0, len(series))
+		for range series {
+			samples = append(samples, cortexpb.Sample{Value: 1, TimestampMs: 10})
+		}
+		_, err := i.v2Push(ctx, cortexpb.ToWriteRequest(series, samples, nil, cortexpb.API))
+		return err
+	}
+
+	require.NoError(t, push(
+		labels.FromStrings(labels.MetricName, "requests_total", "path", "/a"),
+		labels.FromStrings(labels.MetricName, "requests_total", "path", "/b"),
+	))
+
+	// A new value for the label name is rejected, while other series are still created.
+	err = push(
+		labels.FromStrings(labels.MetricName, "requests_total", "path", "/c"),
+		labels.FromStrings(labels.MetricName, "errors_total", "path", "/a"),
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "per-label-name values limit of 2 exceeded for label path")
+
+	// Existing values and new metric names are accepted.
+	require.NoError(t, push(
+		labels.FromStrings(labels.MetricName, "latency_seconds", "path", "/b"),
+	))
+
+	assert.Equal(t, uint64(4), i.getTSDB("label-values").Head().NumSeries())
+	assert.Equal(t, 1.0, testutil.ToFloat64(validation.DiscardedSamples.WithLabelValues(perLabelNameValuesLimit, "label-values")))
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+		# HELP cortex_ingester_label_values_limit_discarded_samples_total The total number of samples discarded because their series introduced a new value for a label name which reached the max label values per label name limit.
+		# TYPE cortex_ingester_label_values_limit_discarded_samples_total counter
+		cortex_ingester_label_values_limit_discarded_samples_total{label_name="path",user="label-values"} 1
+	`), "cortex_ingester_label_values_limit_discarded_samples_total"))
+}
+
+func TestIngester_v2UpdateActiveSeries_ShouldTrackCustomTrackers(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	cfg := defaultIngesterTestConfig()
+	cfg.LifecyclerConfig.JoinAfter = 0
+	cfg.ActiveSeriesMetricsEnabled = true
+
+	limits := defaultLimitsTestConfig()
+	limits.ActiveSeriesCustomTrackers = validation.ActiveSeriesCustomTrackers{
+		"checkout": `{team="checkout"}`,
+	}
+	i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, "", registry)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until the ingester is ACTIVE
+	test.Poll(t, 100*time.Millisecond, ring.ACTIVE, func() interface{} {
+		return i.lifecycler.GetState()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+	series := []labels.Labels{
+		labels.FromStrings(labels.MetricName, "requests_total", "team", "checkout"),
+		labels.FromStrings(labels.MetricName, "requests_total", "team", "search"),
+		labels.FromStrings(labels.MetricName, "errors_total", "team", "checkout"),
+	}
+	samples := []cortexpb.Sample{{Value: 1, TimestampMs: 10}, {Value: 1, TimestampMs: 10}, {Value: 1, TimestampMs: 10}}
+	_, err = i.v2Push(ctx, cortexpb.ToWriteRequest(series, samples, nil, cortexpb.API))
+	require.NoError(t, err)
+
+	i.v2UpdateActiveSeries()
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+		# HELP cortex_ingester_active_series Number of currently active series per user.
+		# TYPE cortex_ingester_active_series gauge
+		cortex_ingester_active_series{user="test"} 3
+		# HELP cortex_ingester_active_series_custom_tracker Number of currently active series matching a pre-configured label matchers per user.
+		# TYPE cortex_ingester_active_series_custom_tracker gauge
+		cortex_ingester_active_series_custom_tracker{name="checkout",user="test"} 2
+	`), "cortex_ingester_active_series", "cortex_ingester_active_series_custom_tracker"))
+
+	// Once the custom trackers change, the active series are matched against the new trackers.
+	limits.ActiveSeriesCustomTrackers = validation.ActiveSeriesCustomTrackers{
+		"search": `{team="search"}`,
+	}
+	i.limits, err = validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+
+	i.v2UpdateActiveSeries()
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+		# HELP cortex_ingester_active_series Number of currently active series per user.
+		# TYPE cortex_ingester_active_series gauge
+		cortex_ingester_active_series{user="test"} 3
+		# HELP cortex_ingester_active_series_custom_tracker Number of currently active series matching a pre-configured label matchers per user.
+		# TYPE cortex_ingester_active_series_custom_tracker gauge
+		cortex_ingester_active_series_custom_tracker{name="search",user="test"} 1
+	`), "cortex_ingester_active_series", "cortex_ingester_active_series_custom_tracker"))
+}
+
 func TestIngester_v2Push_DecreaseInactiveSeries(t *testing.T) {
 	metricLabelAdapters := []cortexpb.LabelAdapter{{Name: labels.MetricName, Value: "test"}}
 	metricLabels := cortexpb.FromLabelAdaptersToLabels(metricLabelAdapters)
@@ -1134,6 +1424,36 @@ func Test_Ingester_v2LabelNames(t *testing.T) {
 	res, err := i.v2LabelNames(ctx, &client.LabelNamesRequest{})
 	require.NoError(t, err)
 	assert.ElementsMatch(t, expected, res.LabelNames)
+
+	// Get label names with matchers
+	matchersTests := map[string]struct {
+		matchers []*labels.Matcher
+		expected []string
+	}{
+		"should return the label names of the matching series": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "test_1")},
+			expected: []string{"__name__", "route", "status"},
+		},
+		"should return only the metric name if the matching series have no other labels": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "test_2")},
+			expected: []string{"__name__"},
+		},
+		"should return no label names if no series match": {
+			matchers: []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "unknown")},
+			expected: []string{},
+		},
+	}
+
+	for testName, testData := range matchersTests {
+		t.Run(testName, func(t *testing.T) {
+			req, err := client.ToLabelNamesRequest(0, model.Latest, testData.matchers)
+			require.NoError(t, err)
+
+			res, err := i.v2LabelNames(ctx, req)
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, res.LabelNames)
+		})
+	}
 }
 
 func Test_Ingester_v2LabelValues(t *testing.T) {
@@ -2399,6 +2719,128 @@ func TestIngester_shipBlocks(t *testing.T) {
 	}
 }
 
+func TestIngester_shipBlocksShouldUploadBlocksExemplars(t *testing.T) {
+	cfg := defaultIngesterTestConfig()
+	cfg.LifecyclerConfig.JoinAfter = 0
+	cfg.BlocksStorageConfig.TSDB.MaxExemplars = 10
+
+	// Create ingester
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil)
+	require.NoError(t, err)
+
+	// Use in-memory bucket.
+	bkt := objstore.NewInMemBucket()
+
+	i.TSDBState.bucket = bkt
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until it's ACTIVE
+	test.Poll(t, 1*time.Second, ring.ACTIVE, func() interface{} {
+		return i.lifecycler.GetState()
+	})
+
+	// Push a sample along with an exemplar.
+	ctx := user.InjectOrgID(context.Background(), userID)
+	ts := util.TimeToMillis(time.Now())
+	req, _, _, _ := mockWriteRequest(t, labels.Labels{{Name: labels.MetricName, Value: "test"}}, 1, ts)
+	req.Timeseries[0].Exemplars = []cortexpb.Exemplar{{Labels: []cortexpb.LabelAdapter{{Name: "traceID", Value: "123"}}, Value: 1, TimestampMs: ts}}
+	_, err = i.v2Push(ctx, req)
+	require.NoError(t, err)
+
+	i.compactBlocks(context.Background(), true, nil)
+	i.shipBlocks(context.Background(), nil)
+
+	// Look for the exemplars file of the shipped block.
+	var exemplarsPaths []string
+	for name := range bkt.Objects() {
+		if strings.HasSuffix(name, "/"+cortex_tsdb.BlockExemplarsFilename) {
+			exemplarsPaths = append(exemplarsPaths, name)
+		}
+	}
+	require.Len(t, exemplarsPaths, 1)
+
+	blockID, err := ulid.Parse(strings.Split(exemplarsPaths[0], "/")[1])
+	require.NoError(t, err)
+
+	series, err := cortex_tsdb.ReadBlockExemplars(context.Background(), bucket.NewUserBucketClient(userID, bkt, nil), blockID)
+	require.NoError(t, err)
+	require.Len(t, series, 1)
+	assert.Equal(t, []cortexpb.Exemplar{{Labels: []cortexpb.LabelAdapter{{Name: "traceID", Value: "123"}}, Value: 1, TimestampMs: ts}}, series[0].Exemplars)
+}
+
+func TestIngester_shipBlocksShouldShipBlocksEvenIfExemplarsUploadFails(t *testing.T) {
+	cfg := defaultIngesterTestConfig()
+	cfg.LifecyclerConfig.JoinAfter = 0
+	cfg.BlocksStorageConfig.TSDB.MaxExemplars = 10
+
+	// Create ingester
+	reg := prometheus.NewPedanticRegistry()
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, reg)
+	require.NoError(t, err)
+
+	// Use in-memory bucket.
+	bkt := objstore.NewInMemBucket()
+
+	i.TSDBState.bucket = bkt
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until it's ACTIVE
+	test.Poll(t, 1*time.Second, ring.ACTIVE, func() interface{} {
+		return i.lifecycler.GetState()
+	})
+
+	// Push a sample along with an exemplar.
+	ctx := user.InjectOrgID(context.Background(), userID)
+	ts := util.TimeToMillis(time.Now())
+	req, _, _, _ := mockWriteRequest(t, labels.Labels{{Name: labels.MetricName, Value: "test"}}, 1, ts)
+	req.Timeseries[0].Exemplars = []cortexpb.Exemplar{{Labels: []cortexpb.LabelAdapter{{Name: "traceID", Value: "123"}}, Value: 1, TimestampMs: ts}}
+	_, err = i.v2Push(ctx, req)
+	require.NoError(t, err)
+
+	i.compactBlocks(context.Background(), true, nil)
+
+	// Fail the exemplars upload, and don't ship the blocks.
+	exemplarsBucket := &bucket.ClientMock{}
+	exemplarsBucket.On("Upload", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("upload failed")).Once()
+	exemplarsBucket.On("Upload", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	userDB := i.getTSDB(userID)
+	require.NotNil(t, userDB)
+	userDB.exemplarsBucket = exemplarsBucket
+
+	realShipper := userDB.shipper
+	shipper := &shipperMock{}
+	shipper.On("Sync", mock.Anything).Return(0, nil)
+	userDB.shipper = shipper
+
+	i.shipBlocks(context.Background(), nil)
+	shipper.AssertNumberOfCalls(t, "Sync", 1)
+	exemplarsBucket.AssertNumberOfCalls(t, "Upload", 1)
+	assert.Equal(t, float64(1), testutil.ToFloat64(i.metrics.blocksExemplarsUploadFailures))
+
+	// The exemplars upload is retried at the next shipping, but only until it succeeds.
+	i.shipBlocks(context.Background(), nil)
+	i.shipBlocks(context.Background(), nil)
+	shipper.AssertNumberOfCalls(t, "Sync", 3)
+	exemplarsBucket.AssertNumberOfCalls(t, "Upload", 2)
+	assert.Equal(t, float64(1), testutil.ToFloat64(i.metrics.blocksExemplarsUploadFailures))
+
+	// Ship the blocks with the real shipper.
+	userDB.shipper = realShipper
+	i.shipBlocks(context.Background(), nil)
+	exemplarsBucket.AssertNumberOfCalls(t, "Upload", 2)
+
+	shippedBlocks := 0
+	for name := range bkt.Objects() {
+		if strings.HasSuffix(name, "/"+metadata.MetaFilename) {
+			shippedBlocks++
+		}
+	}
+	assert.Equal(t, 1, shippedBlocks)
+}
+
 func TestIngester_dontShipBlocksWhenTenantDeletionMarkerIsPresent(t *testing.T) {
 	cfg := defaultIngesterTestConfig()
 	cfg.LifecyclerConfig.JoinAfter = 0
@@ -2813,6 +3255,64 @@ func TestIngester_flushing(t *testing.T) {
 			},
 		},
 
+		"prepareDownscaleHandler": {
+			setupIngester: func(cfg *Config) {
+				cfg.BlocksStorageConfig.TSDB.FlushBlocksOnShutdown = false
+				cfg.LifecyclerConfig.UnregisterOnShutdown = false
+			},
+
+			action: func(t *testing.T, i *Ingester, reg *prometheus.Registry) {
+				pushSingleSampleWithMetadata(t, i)
+
+				// Not started yet.
+				rec := httptest.NewRecorder()
+				i.PrepareDownscaleHandler(rec, httptest.NewRequest("GET", "/ingester/prepare-downscale", nil))
+				require.Equal(t, http.StatusAccepted, rec.Code)
+				require.Contains(t, rec.Body.String(), `"state":"idle"`)
+
+				// Using wait=true makes this a synchronous call.
+				rec = httptest.NewRecorder()
+				i.PrepareDownscaleHandler(rec, httptest.NewRequest("POST", "/ingester/prepare-downscale?wait=true", nil))
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), `"state":"completed"`)
+				require.Contains(t, rec.Body.String(), `"tenants":1`)
+
+				// The ingester doesn't receive writes anymore, but it's still queried.
+				require.Equal(t, ring.READONLY, i.lifecycler.GetState())
+				require.True(t, i.lifecycler.ShouldUnregisterOnShutdown())
+
+				ctx := user.InjectOrgID(context.Background(), userID)
+				req, _, _, _ := mockWriteRequest(t, labels.Labels{{Name: labels.MetricName, Value: "test"}}, 0, util.TimeToMillis(time.Now()))
+				_, err := i.v2Push(ctx, req)
+				require.Equal(t, errIngesterReadOnly, err)
+
+				verifyCompactedHead(t, i, true)
+				require.NoError(t, testutil.GatherAndCompare(reg, bytes.NewBufferString(`
+					# HELP cortex_ingester_shipper_uploads_total Total number of uploaded TSDB blocks
+					# TYPE cortex_ingester_shipper_uploads_total counter
+					cortex_ingester_shipper_uploads_total 1
+				`), "cortex_ingester_shipper_uploads_total"))
+
+				// The status is still reported once completed.
+				rec = httptest.NewRecorder()
+				i.PrepareDownscaleHandler(rec, httptest.NewRequest("GET", "/ingester/prepare-downscale", nil))
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), `"state":"completed"`)
+
+				// The preparation can be cancelled, switching the ingester back to active.
+				rec = httptest.NewRecorder()
+				i.PrepareDownscaleHandler(rec, httptest.NewRequest("DELETE", "/ingester/prepare-downscale", nil))
+				require.Equal(t, http.StatusAccepted, rec.Code)
+				require.Contains(t, rec.Body.String(), `"state":"idle"`)
+
+				require.Equal(t, ring.ACTIVE, i.lifecycler.GetState())
+				require.False(t, i.lifecycler.ShouldUnregisterOnShutdown())
+
+				_, err = i.v2Push(ctx, req)
+				require.NoError(t, err)
+			},
+		},
+
 		"flushMultipleBlocksWithDataSpanning3Days": {
 			setupIngester: func(cfg *Config) {
 				cfg.BlocksStorageConfig.TSDB.FlushBlocksOnShutdown = false
@@ -0,0 +1,137 @@
+package ingester
+
+import (
+	"sync"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/segmentio/fasthash/fnv1a"
+
+	"github.com/cortexproject/cortex/pkg/util"
+)
+
+const numLabelValuesCounterShards = 128
+
+type labelValuesCounterShard struct {
+	mtx sync.Mutex
+
+	// Number of in-memory series for each label value, by label name.
+	values map[string]map[string]int
+}
+
+// labelValuesCounter counts the in-memory series of a tenant for each label name and value, in
+// order to enforce the max label values per label name limit without looking up the TSDB head.
+// Series are counted only while the limit is enabled, so series created before the limit is
+// enabled aren't counted until the TSDB head is reloaded (e.g. the ingester restarts).
+type labelValuesCounter struct {
+	limiter *Limiter
+	userID  string
+	shards  []labelValuesCounterShard
+}
+
+func newLabelValuesCounter(limiter *Limiter, userID string) *labelValuesCounter {
+	shards := make([]labelValuesCounterShard, 0, numLabelValuesCounterShards)
+	for i := 0; i < numLabelValuesCounterShards; i++ {
+		shards = append(shards, labelValuesCounterShard{
+			values: map[string]map[string]int{},
+		})
+	}
+
+	return &labelValuesCounter{
+		limiter: limiter,
+		userID:  userID,
+		shards:  shards,
+	}
+}
+
+// canAddSeries returns an error if the series introduces a new value for a label name which
+// already has the max number of values. The metric name is not limited.
+func (c *labelValuesCounter) canAddSeries(metric labels.Labels) error {
+	if !c.enabled() {
+		return nil
+	}
+
+	for _, l := range metric {
+		if l.Name == labels.MetricName {
+			continue
+		}
+
+		shard := c.getShard(l.Name)
+		shard.mtx.Lock()
+		values := shard.values[l.Name]
+		_, exists := values[l.Value]
+		numValues := len(values)
+		shard.mtx.Unlock()
+
+		// Only new values count towards the limit.
+		if exists {
+			continue
+		}
+		if err := c.limiter.AssertMaxLabelValuesPerLabelName(c.userID, l.Name, numValues); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *labelValuesCounter) increaseSeries(metric labels.Labels) {
+	if !c.enabled() {
+		return
+	}
+
+	for _, l := range metric {
+		if l.Name == labels.MetricName {
+			continue
+		}
+
+		shard := c.getShard(l.Name)
+		shard.mtx.Lock()
+		values, ok := shard.values[l.Name]
+		if !ok {
+			values = map[string]int{}
+			shard.values[l.Name] = values
+		}
+		values[l.Value]++
+		shard.mtx.Unlock()
+	}
+}
+
+// decreaseSeries is called even if the limit is disabled, so that the counts of the series
+// created while the limit was enabled don't leak.
+func (c *labelValuesCounter) decreaseSeries(metric labels.Labels) {
+	if c == nil || c.limiter == nil {
+		return
+	}
+
+	for _, l := range metric {
+		if l.Name == labels.MetricName {
+			continue
+		}
+
+		shard := c.getShard(l.Name)
+		shard.mtx.Lock()
+		if values, ok := shard.values[l.Name]; ok {
+			// The series may have been created before the limit was enabled, so it may not be counted.
+			if count, ok := values[l.Value]; ok {
+				if count > 1 {
+					values[l.Value] = count - 1
+				} else {
+					delete(values, l.Value)
+				}
+			}
+			if len(values) == 0 {
+				delete(shard.values, l.Name)
+			}
+		}
+		shard.mtx.Unlock()
+	}
+}
+
+func (c *labelValuesCounter) enabled() bool {
+	return c != nil && c.limiter != nil && c.limiter.limits.MaxLabelValuesPerLabelName(c.userID) > 0
+}
+
+func (c *labelValuesCounter) getShard(labelName string) *labelValuesCounterShard {
+	return &c.shards[util.HashFP(model.Fingerprint(fnv1a.HashString64(labelName)))%numLabelValuesCounterShards]
+}
@@ -0,0 +1,54 @@
+package ingester
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cortexproject/cortex/pkg/util"
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+func TestLabelValuesCounter(t *testing.T) {
+	newLimiter := func(maxLabelValues int) *Limiter {
+		overrides, err := validation.NewOverrides(validation.Limits{MaxLabelValuesPerLabelName: maxLabelValues}, nil)
+		require.NoError(t, err)
+		return NewLimiter(overrides, nil, util.ShardingStrategyDefault, true, 1, false)
+	}
+
+	c := newLabelValuesCounter(newLimiter(2), "user-1")
+
+	a := labels.FromStrings(labels.MetricName, "requests_total", "path", "/a")
+	b := labels.FromStrings(labels.MetricName, "requests_total", "path", "/b")
+	c1 := labels.FromStrings(labels.MetricName, "requests_total", "path", "/c")
+	errorsA := labels.FromStrings(labels.MetricName, "errors_total", "path", "/a")
+
+	for _, series := range []labels.Labels{a, b} {
+		require.NoError(t, c.canAddSeries(series))
+		c.increaseSeries(series)
+	}
+
+	// A new value is rejected, while existing values and new metric names are accepted.
+	assert.Equal(t, &labelValuesLimitError{labelName: "path"}, c.canAddSeries(c1))
+	require.NoError(t, c.canAddSeries(errorsA))
+	c.increaseSeries(errorsA)
+
+	// A value is removed only once all its series have been deleted.
+	c.decreaseSeries(a)
+	assert.Error(t, c.canAddSeries(c1))
+	c.decreaseSeries(errorsA)
+	assert.NoError(t, c.canAddSeries(c1))
+
+	// Series are not counted while the limit is disabled, but are still decreased.
+	c.limiter = newLimiter(0)
+	assert.NoError(t, c.canAddSeries(c1))
+	c.increaseSeries(c1)
+	c.decreaseSeries(b)
+
+	c.limiter = newLimiter(1)
+	assert.NoError(t, c.canAddSeries(c1))
+	c.increaseSeries(c1)
+	assert.Error(t, c.canAddSeries(a))
+}
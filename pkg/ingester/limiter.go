@@ -18,6 +18,24 @@ var (
 	errMaxMetadataPerUserLimitExceeded   = errors.New("per-user metric metadata limit exceeded")
 )
 
+// metricLimitSeriesError is returned when the series limit of a metric limit rule has been reached.
+type metricLimitSeriesError struct {
+	rule string
+}
+
+func (e *metricLimitSeriesError) Error() string {
+	return fmt.Sprintf("series limit of the metric limit rule %s exceeded", e.rule)
+}
+
+// labelValuesLimitError is returned when the max label values per label name limit has been reached.
+type labelValuesLimitError struct {
+	labelName string
+}
+
+func (e *labelValuesLimitError) Error() string {
+	return fmt.Sprintf("per-label-name values limit exceeded for label %s", e.labelName)
+}
+
 // RingCount is the interface exposed by a ring implementation which allows
 // to count members
 type RingCount interface {
@@ -65,6 +83,26 @@ func (l *Limiter) AssertMaxSeriesPerMetric(userID string, series int) error {
 	return errMaxSeriesPerMetricLimitExceeded
 }
 
+// AssertMaxSeriesPerMetricLimit limit of the metric limit rule has not been reached compared
+// to the current number of series matching the rule in input and returns an error if so.
+func (l *Limiter) AssertMaxSeriesPerMetricLimit(userID string, rule *validation.MetricLimit, series int) error {
+	if actualLimit := l.maxSeriesPerMetricLimit(userID, rule.MaxSeries); series < actualLimit {
+		return nil
+	}
+
+	return &metricLimitSeriesError{rule: rule.Name}
+}
+
+// AssertMaxLabelValuesPerLabelName limit has not been reached compared to the current
+// number of values of the label name in input and returns an error if so.
+func (l *Limiter) AssertMaxLabelValuesPerLabelName(userID, labelName string, values int) error {
+	if actualLimit := l.limits.MaxLabelValuesPerLabelName(userID); actualLimit <= 0 || values < actualLimit {
+		return nil
+	}
+
+	return &labelValuesLimitError{labelName: labelName}
+}
+
 // AssertMaxMetadataPerMetric limit has not been reached compared to the current
 // number of metadata per metric in input and returns an error if so.
 func (l *Limiter) AssertMaxMetadataPerMetric(userID string, metadata int) error {
@@ -103,6 +141,17 @@ func (l *Limiter) MaxSeriesPerQuery(userID string) int {
 // FormatError returns the input error enriched with the actual limits for the given user.
 // It acts as pass-through if the input error is unknown.
 func (l *Limiter) FormatError(userID string, err error) error {
+	var ruleErr *metricLimitSeriesError
+	if errors.As(err, &ruleErr) {
+		return l.formatMaxSeriesPerMetricLimitError(userID, ruleErr.rule)
+	}
+
+	var labelValuesErr *labelValuesLimitError
+	if errors.As(err, &labelValuesErr) {
+		return fmt.Errorf("per-label-name values limit of %d exceeded for label %s, please contact administrator to raise it or remove the high cardinality label",
+			l.limits.MaxLabelValuesPerLabelName(userID), labelValuesErr.labelName)
+	}
+
 	switch err {
 	case errMaxSeriesPerUserLimitExceeded:
 		return l.formatMaxSeriesPerUserError(userID)
@@ -135,6 +184,20 @@ func (l *Limiter) formatMaxSeriesPerMetricError(userID string) error {
 		minNonZero(localLimit, globalLimit), localLimit, globalLimit, actualLimit)
 }
 
+func (l *Limiter) formatMaxSeriesPerMetricLimitError(userID, rule string) error {
+	globalLimit := 0
+	for _, r := range l.limits.MetricLimits(userID) {
+		if r.Name == rule {
+			globalLimit = r.MaxSeries
+			break
+		}
+	}
+	actualLimit := l.maxSeriesPerMetricLimit(userID, globalLimit)
+
+	return fmt.Errorf("series limit of %d of the metric limit rule %s exceeded, please contact administrator to raise it (actual local limit: %d)",
+		globalLimit, rule, actualLimit)
+}
+
 func (l *Limiter) formatMaxMetadataPerUserError(userID string) error {
 	actualLimit := l.maxMetadataPerUser(userID)
 	localLimit := l.limits.MaxLocalMetricsWithMetadataPerUser(userID)
@@ -179,6 +242,22 @@ func (l *Limiter) maxSeriesPerMetric(userID string) int {
 	return localLimit
 }
 
+func (l *Limiter) maxSeriesPerMetricLimit(userID string, globalLimit int) int {
+	localLimit := globalLimit
+
+	// Like for the global per-metric limit, series matching a rule are evenly
+	// distributed across ingesters only when sharding by all labels.
+	if globalLimit > 0 && l.shardByAllLabels {
+		localLimit = l.convertGlobalToLocalLimit(userID, globalLimit)
+	}
+
+	if localLimit <= 0 {
+		localLimit = math.MaxInt32
+	}
+
+	return localLimit
+}
+
 func (l *Limiter) maxMetadataPerMetric(userID string) int {
 	localLimit := l.limits.MaxLocalMetadataPerMetric(userID)
 	globalLimit := l.limits.MaxGlobalMetadataPerMetric(userID)
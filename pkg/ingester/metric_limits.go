@@ -0,0 +1,107 @@
+package ingester
+
+import (
+	"sync"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+// metricLimitsCounter counts the in-memory series of a tenant matching each metric limit rule.
+// Series are counted by rule name, and the count is reset when the selector of the rule changes,
+// so series created before a rule is added or changed aren't counted until the TSDB head is
+// reloaded (e.g. the ingester restarts).
+type metricLimitsCounter struct {
+	limiter *Limiter
+	userID  string
+
+	mtx    sync.Mutex
+	series map[string]metricLimitSeries
+}
+
+// metricLimitSeries is the number of series matching the selector of a rule.
+type metricLimitSeries struct {
+	matcher string
+	count   int
+}
+
+func newMetricLimitsCounter(limiter *Limiter, userID string) *metricLimitsCounter {
+	return &metricLimitsCounter{
+		limiter: limiter,
+		userID:  userID,
+		series:  map[string]metricLimitSeries{},
+	}
+}
+
+// canAddSeries returns an error if the series limit of any of the rules matching the series has been reached.
+func (c *metricLimitsCounter) canAddSeries(metric labels.Labels) error {
+	if c == nil || c.limiter == nil {
+		return nil
+	}
+
+	rules := c.limiter.limits.MetricLimits(c.userID)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for r := range rules {
+		rule := &rules[r]
+		if rule.MaxSeries <= 0 || !rule.Matches(metric) {
+			continue
+		}
+		if err := c.limiter.AssertMaxSeriesPerMetricLimit(c.userID, rule, c.seriesLocked(rule).count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *metricLimitsCounter) increaseSeries(metric labels.Labels) {
+	c.update(metric, 1)
+}
+
+func (c *metricLimitsCounter) decreaseSeries(metric labels.Labels) {
+	c.update(metric, -1)
+}
+
+func (c *metricLimitsCounter) update(metric labels.Labels, delta int) {
+	if c == nil || c.limiter == nil {
+		return
+	}
+
+	rules := c.limiter.limits.MetricLimits(c.userID)
+	if len(rules) == 0 {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for r := range rules {
+		rule := &rules[r]
+		if !rule.Matches(metric) {
+			continue
+		}
+
+		// The count may go below zero if the series has been created before the rule was added or changed.
+		series := c.seriesLocked(rule)
+		if series.count += delta; series.count > 0 {
+			c.series[rule.Name] = series
+		} else {
+			delete(c.series, rule.Name)
+		}
+	}
+}
+
+// seriesLocked returns the series counted for the rule, ignoring the ones counted
+// while the rule had a different selector. Must be called with the lock held.
+func (c *metricLimitsCounter) seriesLocked(rule *validation.MetricLimit) metricLimitSeries {
+	if series, ok := c.series[rule.Name]; ok && series.matcher == rule.Matcher {
+		return series
+	}
+	return metricLimitSeries{matcher: rule.Matcher}
+}
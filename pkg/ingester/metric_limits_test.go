@@ -0,0 +1,59 @@
+package ingester
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cortexproject/cortex/pkg/util"
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+func TestMetricLimitsCounter(t *testing.T) {
+	newLimiter := func(rules string) *Limiter {
+		var limits validation.Limits
+		require.NoError(t, yaml.UnmarshalStrict([]byte(rules), &limits))
+		overrides, err := validation.NewOverrides(limits, nil)
+		require.NoError(t, err)
+		ring := &ringCountMock{}
+		ring.On("HealthyInstancesCount").Return(1)
+		return NewLimiter(overrides, ring, util.ShardingStrategyDefault, true, 1, false)
+	}
+
+	c := newMetricLimitsCounter(newLimiter(`
+metric_limits:
+  - name: requests
+    matcher: '{__name__="http_requests_total"}'
+    max_series: 2
+`), "user-1")
+
+	httpA := labels.FromStrings(labels.MetricName, "http_requests_total", "path", "/a")
+	httpB := labels.FromStrings(labels.MetricName, "http_requests_total", "path", "/b")
+	httpC := labels.FromStrings(labels.MetricName, "http_requests_total", "path", "/c")
+	grpcA := labels.FromStrings(labels.MetricName, "grpc_requests_total", "path", "/a")
+	grpcB := labels.FromStrings(labels.MetricName, "grpc_requests_total", "path", "/b")
+
+	for _, series := range []labels.Labels{httpA, httpB, grpcA} {
+		require.NoError(t, c.canAddSeries(series))
+		c.increaseSeries(series)
+	}
+	assert.Equal(t, &metricLimitSeriesError{rule: "requests"}, c.canAddSeries(httpC))
+
+	// The series counted with the previous selector of the rule aren't counted anymore once it changes.
+	c.limiter = newLimiter(`
+metric_limits:
+  - name: requests
+    matcher: '{__name__="grpc_requests_total"}'
+    max_series: 2
+`)
+	require.NoError(t, c.canAddSeries(grpcB))
+	c.increaseSeries(grpcB)
+	c.decreaseSeries(httpA)
+	require.NoError(t, c.canAddSeries(httpC))
+
+	c.increaseSeries(grpcA)
+	assert.Equal(t, &metricLimitSeriesError{rule: "requests"}, c.canAddSeries(grpcA))
+}
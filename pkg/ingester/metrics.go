@@ -1,11 +1,13 @@
 package ingester
 
 import (
+	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/atomic"
 
 	"github.com/cortexproject/cortex/pkg/util"
+	util_log "github.com/cortexproject/cortex/pkg/util/log"
 	util_math "github.com/cortexproject/cortex/pkg/util/math"
 )
 
@@ -59,6 +61,18 @@ type ingesterMetrics struct {
 
 	activeSeriesPerUser *prometheus.GaugeVec
 
+	// Active series matching the custom trackers, per user and tracker name.
+	activeSeriesCustomTrackersPerUser *prometheus.GaugeVec
+
+	// Samples dropped when deduplicating the samples from HA replicas.
+	haDeduplicatedSamples *prometheus.CounterVec
+
+	// Samples discarded by the max label values per label name limit, by label name.
+	labelValuesLimitDiscardedSamples *prometheus.CounterVec
+
+	// Failures to upload the exemplars of the blocks being shipped.
+	blocksExemplarsUploadFailures prometheus.Counter
+
 	// Global limit metrics
 	maxUsersGauge           prometheus.GaugeFunc
 	maxSeriesGauge          prometheus.GaugeFunc
@@ -287,15 +301,34 @@ func newIngesterMetrics(r prometheus.Registerer, createMetricsConflictingWithTSD
 			return 0
 		}),
 
+		haDeduplicatedSamples: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ingester_ha_deduplicated_samples_total",
+			Help: "The total number of duplicated or out of order samples dropped per user, when deduplicating the samples from HA replicas.",
+		}, []string{"user"}),
+		labelValuesLimitDiscardedSamples: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_ingester_label_values_limit_discarded_samples_total",
+			Help: "The total number of samples discarded because their series introduced a new value for a label name which reached the max label values per label name limit.",
+		}, []string{"user", "label_name"}),
+
+		blocksExemplarsUploadFailures: promauto.With(r).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_shipper_exemplars_upload_failures_total",
+			Help: "The total number of failures uploading the exemplars of the TSDB blocks being shipped to the storage.",
+		}),
+
 		// Not registered automatically, but only if activeSeriesEnabled is true.
 		activeSeriesPerUser: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "cortex_ingester_active_series",
 			Help: "Number of currently active series per user.",
 		}, []string{"user"}),
+		activeSeriesCustomTrackersPerUser: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_ingester_active_series_custom_tracker",
+			Help: "Number of currently active series matching a pre-configured label matchers per user.",
+		}, []string{"user", "name"}),
 	}
 
 	if activeSeriesEnabled && r != nil {
 		r.MustRegister(m.activeSeriesPerUser)
+		r.MustRegister(m.activeSeriesCustomTrackersPerUser)
 	}
 
 	if createMetricsConflictingWithTSDB {
@@ -317,6 +350,11 @@ func (m *ingesterMetrics) deletePerUserMetrics(userID string) {
 	m.memMetadataCreatedTotal.DeleteLabelValues(userID)
 	m.memMetadataRemovedTotal.DeleteLabelValues(userID)
 	m.activeSeriesPerUser.DeleteLabelValues(userID)
+	m.deleteActiveSeriesCustomTrackersForUser(userID)
+	m.haDeduplicatedSamples.DeleteLabelValues(userID)
+	if err := util.DeleteMatchingLabels(m.labelValuesLimitDiscardedSamples, map[string]string{"user": userID}); err != nil {
+		level.Warn(util_log.Logger).Log("msg", "failed to remove cortex_ingester_label_values_limit_discarded_samples_total metric for user", "user", userID, "err", err)
+	}
 
 	if m.memSeriesCreatedTotal != nil {
 		m.memSeriesCreatedTotal.DeleteLabelValues(userID)
@@ -655,3 +693,9 @@ func (sm *tsdbMetrics) setRegistryForUser(userID string, registry *prometheus.Re
 func (sm *tsdbMetrics) removeRegistryForUser(userID string) {
 	sm.regs.RemoveUserRegistry(userID, false)
 }
+
+func (m *ingesterMetrics) deleteActiveSeriesCustomTrackersForUser(userID string) {
+	if err := util.DeleteMatchingLabels(m.activeSeriesCustomTrackersPerUser, map[string]string{"user": userID}); err != nil {
+		level.Warn(util_log.Logger).Log("msg", "failed to remove cortex_ingester_active_series_custom_tracker metric for user", "user", userID, "err", err)
+	}
+}
@@ -0,0 +1,295 @@
+package ingester
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/weaveworks/common/httpgrpc"
+
+	"github.com/cortexproject/cortex/pkg/ring"
+)
+
+var (
+	errIngesterReadOnly           = httpgrpc.Errorf(http.StatusServiceUnavailable, "ingester is read-only because it is being prepared for downscale")
+	errPrepareDownscaleInProgress = errors.New("the preparation for downscale is in progress and can't be cancelled")
+)
+
+type prepareDownscaleState string
+
+const (
+	prepareDownscaleIdle       prepareDownscaleState = "idle"
+	prepareDownscaleInProgress prepareDownscaleState = "in_progress"
+	prepareDownscaleCompleted  prepareDownscaleState = "completed"
+	prepareDownscaleFailed     prepareDownscaleState = "failed"
+
+	prepareDownscalePhaseReadOnly   = "read_only"
+	prepareDownscalePhaseCompacting = "compacting"
+	prepareDownscalePhaseShipping   = "shipping"
+)
+
+// prepareDownscaleStatus tracks the progress of the preparation of the ingester for downscale.
+type prepareDownscaleStatus struct {
+	mtx        sync.Mutex
+	readOnly   bool
+	state      prepareDownscaleState
+	phase      string
+	tenants    int
+	startedAt  time.Time
+	finishedAt time.Time
+	err        error
+}
+
+// prepareDownscaleResponse is the JSON response of the prepare downscale endpoint.
+type prepareDownscaleResponse struct {
+	State      prepareDownscaleState `json:"state"`
+	Phase      string                `json:"phase,omitempty"`
+	Tenants    int                   `json:"tenants"`
+	StartedAt  *time.Time            `json:"started_at,omitempty"`
+	FinishedAt *time.Time            `json:"finished_at,omitempty"`
+	Error      string                `json:"error,omitempty"`
+}
+
+func (s *prepareDownscaleStatus) isReadOnly() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.readOnly
+}
+
+// start switches the status to in progress, and returns false if the preparation was already started.
+func (s *prepareDownscaleStatus) start(now time.Time, tenants int) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.state == prepareDownscaleInProgress || s.state == prepareDownscaleCompleted {
+		return false
+	}
+
+	s.state = prepareDownscaleInProgress
+	s.phase = prepareDownscalePhaseReadOnly
+	s.tenants = tenants
+	s.startedAt = now
+	s.finishedAt = time.Time{}
+	s.err = nil
+	return true
+}
+
+func (s *prepareDownscaleStatus) setReadOnly(readOnly bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.readOnly = readOnly
+}
+
+// cancel switches the status back to idle, and returns false if the preparation is in progress.
+func (s *prepareDownscaleStatus) cancel() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.state == prepareDownscaleInProgress {
+		return false
+	}
+
+	s.readOnly = false
+	s.state = prepareDownscaleIdle
+	s.phase = ""
+	s.tenants = 0
+	s.startedAt = time.Time{}
+	s.finishedAt = time.Time{}
+	s.err = nil
+	return true
+}
+
+func (s *prepareDownscaleStatus) setPhase(phase string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.phase = phase
+}
+
+func (s *prepareDownscaleStatus) finish(now time.Time, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.phase = ""
+	s.finishedAt = now
+	s.err = err
+	if err != nil {
+		s.state = prepareDownscaleFailed
+	} else {
+		s.state = prepareDownscaleCompleted
+	}
+}
+
+func (s *prepareDownscaleStatus) response() prepareDownscaleResponse {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	res := prepareDownscaleResponse{
+		State:   s.state,
+		Phase:   s.phase,
+		Tenants: s.tenants,
+	}
+	if res.State == "" {
+		res.State = prepareDownscaleIdle
+	}
+	if !s.startedAt.IsZero() {
+		startedAt := s.startedAt
+		res.StartedAt = &startedAt
+	}
+	if !s.finishedAt.IsZero() {
+		finishedAt := s.finishedAt
+		res.FinishedAt = &finishedAt
+	}
+	if s.err != nil {
+		res.Error = s.err.Error()
+	}
+	return res
+}
+
+// PrepareDownscaleHandler prepares the ingester to be scaled down. A POST request switches the
+// ingester to read-only (the instance is marked as READONLY in the ring, so it doesn't receive
+// writes anymore but it's still queried), then force compacts the TSDB head and ships the blocks
+// of all tenants. A DELETE request switches the ingester back to ACTIVE, unless the preparation is
+// in progress. All requests report the progress, and the response status code is 200 only once the
+// ingester can be safely terminated. Only supported by the blocks storage.
+func (i *Ingester) PrepareDownscaleHandler(w http.ResponseWriter, r *http.Request) {
+	if !i.cfg.BlocksStorageEnabled {
+		http.Error(w, "prepare downscale is only supported by the blocks storage", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if err := i.startPrepareDownscale(r); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	case http.MethodDelete:
+		if err := i.cancelPrepareDownscale(r.Context()); err == errPrepareDownscaleInProgress {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	res := i.prepareDownscale.response()
+	data, err := json.Marshal(res)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch res.State {
+	case prepareDownscaleCompleted:
+		w.WriteHeader(http.StatusOK)
+	case prepareDownscaleFailed:
+		w.WriteHeader(http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+	_, _ = w.Write(data)
+}
+
+func (i *Ingester) startPrepareDownscale(r *http.Request) error {
+	ingCtx := i.BasicService.ServiceContext()
+	if ingCtx == nil || ingCtx.Err() != nil {
+		return errors.New("ingester not running")
+	}
+
+	if !i.prepareDownscale.start(time.Now(), len(i.getTSDBUsers())) {
+		return nil
+	}
+
+	run := func() {
+		err := i.prepareForDownscale(ingCtx)
+		if err != nil {
+			level.Error(i.logger).Log("msg", "failed to prepare the ingester for downscale", "err", err)
+		} else {
+			level.Info(i.logger).Log("msg", "ingester prepared for downscale")
+		}
+		i.prepareDownscale.finish(time.Now(), err)
+	}
+
+	if err := r.ParseForm(); err == nil && r.Form.Get(waitParam) == "true" {
+		// Run synchronously. This simplifies and speeds up tests.
+		run()
+	} else {
+		go run()
+	}
+	return nil
+}
+
+// cancelPrepareDownscale switches the ingester back to ACTIVE in the ring and accepts writes again.
+func (i *Ingester) cancelPrepareDownscale(ctx context.Context) error {
+	if !i.prepareDownscale.cancel() {
+		return errPrepareDownscaleInProgress
+	}
+
+	level.Info(i.logger).Log("msg", "cancelling the preparation of the ingester for downscale: switching back to active")
+	i.lifecycler.SetUnregisterOnShutdown(i.cfg.LifecyclerConfig.UnregisterOnShutdown)
+
+	if i.lifecycler.GetState() == ring.READONLY {
+		if err := i.lifecycler.ChangeState(ctx, ring.ACTIVE); err != nil {
+			return errors.Wrap(err, "failed to change the ingester state in the ring")
+		}
+	}
+	return nil
+}
+
+func (i *Ingester) prepareForDownscale(ctx context.Context) error {
+	level.Info(i.logger).Log("msg", "preparing the ingester for downscale: switching to read-only")
+
+	// Stop receiving writes from distributors, while the ingester is still queried.
+	if i.lifecycler.GetState() != ring.READONLY {
+		if err := i.lifecycler.ChangeState(ctx, ring.READONLY); err != nil {
+			return errors.Wrap(err, "failed to change the ingester state in the ring")
+		}
+	}
+
+	// Reject the push requests only once the READONLY state has been stored in the ring, so that
+	// the distributors don't keep sending writes to this ingester while they're rejected.
+	i.prepareDownscale.setReadOnly(true)
+
+	// Once terminated, the ingester owns no data and must leave the ring.
+	i.lifecycler.SetUnregisterOnShutdown(true)
+
+	level.Info(i.logger).Log("msg", "preparing the ingester for downscale: compacting TSDB head")
+	i.prepareDownscale.setPhase(prepareDownscalePhaseCompacting)
+	if err := i.triggerAndWait(ctx, i.TSDBState.forceCompactTrigger); err != nil {
+		return errors.Wrap(err, "failed to compact TSDB blocks")
+	}
+
+	if i.cfg.BlocksStorageConfig.TSDB.IsBlocksShippingEnabled() {
+		level.Info(i.logger).Log("msg", "preparing the ingester for downscale: shipping TSDB blocks")
+		i.prepareDownscale.setPhase(prepareDownscalePhaseShipping)
+		if err := i.triggerAndWait(ctx, i.TSDBState.shipTrigger); err != nil {
+			return errors.Wrap(err, "failed to ship TSDB blocks")
+		}
+	}
+
+	return nil
+}
+
+// triggerAndWait sends a request for all tenants to the input trigger, and waits until it has been processed.
+func (i *Ingester) triggerAndWait(ctx context.Context, trigger chan<- requestWithUsersAndCallback) error {
+	callbackCh := make(chan struct{})
+
+	select {
+	case trigger <- requestWithUsersAndCallback{callback: callbackCh}:
+	case <-ctx.Done():
+		return errors.New("ingester not running anymore")
+	}
+
+	select {
+	case <-callbackCh:
+		return nil
+	case <-ctx.Done():
+		return errors.New("ingester not running anymore")
+	}
+}
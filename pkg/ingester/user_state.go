@@ -61,8 +61,9 @@ type userState struct {
 
 // DiscardedSamples metric labels
 const (
-	perUserSeriesLimit   = "per_user_series_limit"
-	perMetricSeriesLimit = "per_metric_series_limit"
+	perUserSeriesLimit      = "per_user_series_limit"
+	perMetricSeriesLimit    = "per_metric_series_limit"
+	perLabelNameValuesLimit = "per_label_name_values_limit"
 )
 
 func newUserStates(limiter *Limiter, cfg Config, metrics *ingesterMetrics, logger log.Logger) *userStates {
@@ -154,7 +155,7 @@ func (us *userStates) getOrCreate(userID string) *userState {
 			discardedSamples:      validation.DiscardedSamples.MustCurryWith(prometheus.Labels{"user": userID}),
 			createdChunks:         us.metrics.createdChunks,
 
-			activeSeries:      NewActiveSeries(),
+			activeSeries:      NewActiveSeries(nil),
 			activeSeriesGauge: us.metrics.activeSeriesPerUser.WithLabelValues(userID),
 		}
 		state.mapper = newFPMapper(state.fpToSeries, logger)
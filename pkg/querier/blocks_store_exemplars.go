@@ -0,0 +1,181 @@
+package querier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/exemplar"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"go.uber.org/atomic"
+	"golang.org/x/sync/errgroup"
+	grpc_metadata "google.golang.org/grpc/metadata"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+	cortex_tsdb "github.com/cortexproject/cortex/pkg/storage/tsdb"
+	"github.com/cortexproject/cortex/pkg/storage/tsdb/bucketindex"
+	"github.com/cortexproject/cortex/pkg/storegateway/storegatewaypb"
+	"github.com/cortexproject/cortex/pkg/util/spanlogger"
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+// ExemplarQuerier returns a new exemplar querier on the exemplars persisted in the blocks storage.
+func (q *BlocksStoreQueryable) ExemplarQuerier(ctx context.Context) (storage.ExemplarQuerier, error) {
+	return &blocksStoreExemplarQuerier{ctx: ctx, queryable: q}, nil
+}
+
+type blocksStoreExemplarQuerier struct {
+	ctx       context.Context
+	queryable *BlocksStoreQueryable
+}
+
+// Select implements storage.ExemplarQuerier.
+func (q *blocksStoreExemplarQuerier) Select(start, end int64, matchers ...[]*labels.Matcher) ([]exemplar.QueryResult, error) {
+	querier, err := q.queryable.newQuerier(q.ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := querier.selectExemplars(matchers...)
+	if err != nil {
+		return nil, err
+	}
+
+	return exemplarSeriesToQueryResults(series), nil
+}
+
+// selectExemplars returns the exemplars of the series matching any of the input matchers sets,
+// read from the exemplars persisted in the blocks queried through the store-gateways.
+func (q *blocksStoreQuerier) selectExemplars(matcherSets ...[]*labels.Matcher) ([]cortexpb.TimeSeries, error) {
+	spanLog, spanCtx := spanlogger.New(q.ctx, "blocksStoreQuerier.selectExemplars")
+	defer spanLog.Span.Finish()
+
+	storeMatcherSets := make([]storegatewaypb.ExemplarsMatchers, 0, len(matcherSets))
+	for _, matchers := range matcherSets {
+		converted, err := storepb.PromMatchersToMatchers(matchers...)
+		if err != nil {
+			return nil, errors.Wrap(err, "converting matchers")
+		}
+		storeMatcherSets = append(storeMatcherSets, storegatewaypb.ExemplarsMatchers{Matchers: converted})
+	}
+
+	var (
+		resSets = [][]cortexpb.TimeSeries(nil)
+
+		// The exemplars limit is enforced across all store-gateway responses of the query.
+		numExemplars = atomic.NewInt32(0)
+	)
+
+	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, _ bucketindex.Blocks, _ map[ulid.ULID][]string, minT, maxT int64) ([]ulid.ULID, error) {
+		sets, queriedBlocks, err := q.fetchExemplarsFromStores(spanCtx, clients, minT, maxT, storeMatcherSets, numExemplars)
+		if err != nil {
+			return nil, err
+		}
+
+		resSets = append(resSets, sets...)
+		return queriedBlocks, nil
+	}
+
+	// Partial responses can't be reported, because exemplar queries have no warnings, so they're just logged.
+	warnings, err := q.queryWithConsistencyCheck(spanCtx, spanLog, q.minT, q.maxT, queryFunc)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		level.Warn(spanLog).Log("msg", "exemplars query returned a partial response", "warning", w)
+	}
+
+	return cortex_tsdb.MergeExemplarSeries(resSets...), nil
+}
+
+func (q *blocksStoreQuerier) fetchExemplarsFromStores(
+	ctx context.Context,
+	clients map[BlocksStoreClient][]ulid.ULID,
+	minT int64,
+	maxT int64,
+	matchers []storegatewaypb.ExemplarsMatchers,
+	numExemplars *atomic.Int32,
+) ([][]cortexpb.TimeSeries, []ulid.ULID, error) {
+	var (
+		reqCtx        = grpc_metadata.AppendToOutgoingContext(ctx, cortex_tsdb.TenantIDExternalLabel, q.userID)
+		g, gCtx       = errgroup.WithContext(reqCtx)
+		mtx           = sync.Mutex{}
+		sets          = [][]cortexpb.TimeSeries{}
+		queriedBlocks = []ulid.ULID(nil)
+		spanLog       = spanlogger.FromContext(ctx)
+		maxExemplars  = q.limits.MaxFetchedExemplarsPerQuery(q.userID)
+	)
+
+	// Concurrently fetch exemplars from all clients.
+	for c, blockIDs := range clients {
+		// Change variables scope since it will be used in a goroutine.
+		c := c
+		blockIDs := blockIDs
+
+		g.Go(func() error {
+			req := &storegatewaypb.ExemplarsRequest{
+				MinTime:  minT,
+				MaxTime:  maxT,
+				Matchers: matchers,
+				BlockIds: convertULIDsToString(blockIDs),
+			}
+
+			resp, err := c.Exemplars(gCtx, req)
+			if err != nil {
+				if q.isPartialResponseTolerated(ctx, err) {
+					level.Warn(spanLog).Log("msg", "failed to fetch exemplars from store-gateway, its blocks will be considered missing", "instance", c.RemoteAddress(), "err", err)
+					return nil
+				}
+				return errors.Wrapf(err, "failed to fetch exemplars from %s", c.RemoteAddress())
+			}
+
+			myQueriedBlocks := make([]ulid.ULID, 0, len(resp.QueriedBlockIds))
+			for _, rawID := range resp.QueriedBlockIds {
+				id, err := ulid.Parse(rawID)
+				if err != nil {
+					return errors.Wrapf(err, "failed to parse queried block IDs from %s", c.RemoteAddress())
+				}
+				myQueriedBlocks = append(myQueriedBlocks, id)
+			}
+
+			series := make([]cortexpb.TimeSeries, 0, len(resp.Series))
+			count := 0
+			for _, s := range resp.Series {
+				series = append(series, storegatewaypb.ToCortexExemplarSeries(s))
+				count += len(s.Exemplars)
+			}
+
+			if maxExemplars > 0 && int(numExemplars.Add(int32(count))) > maxExemplars {
+				return validation.LimitError(fmt.Sprintf(errMaxFetchedExemplarsPerQueryLimit, maxExemplars))
+			}
+
+			level.Debug(spanLog).Log("msg", "received exemplars from store-gateway",
+				"instance", c.RemoteAddress(),
+				"num series", len(series),
+				"num exemplars", count,
+				"requested blocks", strings.Join(convertULIDsToString(blockIDs), " "),
+				"queried blocks", strings.Join(convertULIDsToString(myQueriedBlocks), " "))
+
+			// Store the result.
+			mtx.Lock()
+			sets = append(sets, series)
+			queriedBlocks = append(queriedBlocks, myQueriedBlocks...)
+			mtx.Unlock()
+
+			return nil
+		})
+	}
+
+	// Wait until all client requests complete.
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	return sets, queriedBlocks, nil
+}
@@ -0,0 +1,145 @@
+package querier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+	"github.com/cortexproject/cortex/pkg/storage/tsdb/bucketindex"
+	"github.com/cortexproject/cortex/pkg/storegateway/storegatewaypb"
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+func TestBlocksStoreQuerier_SelectExemplars(t *testing.T) {
+	const (
+		minT = int64(10)
+		maxT = int64(20)
+	)
+
+	var (
+		block1   = ulid.MustNew(1, nil)
+		block2   = ulid.MustNew(2, nil)
+		series1  = labels.FromStrings(labels.MetricName, "metric_1")
+		series2  = labels.FromStrings(labels.MetricName, "metric_2")
+		trace1   = labels.FromStrings("traceID", "1")
+		trace2   = labels.FromStrings("traceID", "2")
+		matchers = []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, "metric_.*")}
+	)
+
+	mockExemplar := func(lbls labels.Labels, value float64, ts int64) storegatewaypb.Exemplar {
+		return storegatewaypb.Exemplar{Labels: labelpb.ZLabelsFromPromLabels(lbls), Value: value, TimestampMs: ts}
+	}
+
+	tests := map[string]struct {
+		storeSetResponses []interface{}
+		limits            BlocksStoreLimits
+		expectedSeries    []cortexpb.TimeSeries
+		expectedErr       error
+	}{
+		"a single store-gateway instance holds the required blocks": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						Series: []storegatewaypb.ExemplarSeries{
+							{Labels: labelpb.ZLabelsFromPromLabels(series1), Exemplars: []storegatewaypb.Exemplar{mockExemplar(trace1, 1, 15)}},
+						},
+						QueriedBlockIds: []string{block1.String(), block2.String()},
+					}}: {block1, block2},
+				},
+			},
+			limits: &blocksStoreLimitsMock{},
+			expectedSeries: []cortexpb.TimeSeries{
+				{
+					Labels:    cortexpb.FromLabelsToLabelAdapters(series1),
+					Exemplars: []cortexpb.Exemplar{{Labels: cortexpb.FromLabelsToLabelAdapters(trace1), Value: 1, TimestampMs: 15}},
+				},
+			},
+		},
+		"multiple store-gateway instances holds the required blocks with overlapping exemplars": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						Series: []storegatewaypb.ExemplarSeries{
+							{Labels: labelpb.ZLabelsFromPromLabels(series1), Exemplars: []storegatewaypb.Exemplar{mockExemplar(trace1, 1, 15)}},
+						},
+						QueriedBlockIds: []string{block1.String()},
+					}}: {block1},
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						Series: []storegatewaypb.ExemplarSeries{
+							{Labels: labelpb.ZLabelsFromPromLabels(series1), Exemplars: []storegatewaypb.Exemplar{mockExemplar(trace1, 1, 15), mockExemplar(trace2, 2, 16)}},
+							{Labels: labelpb.ZLabelsFromPromLabels(series2), Exemplars: []storegatewaypb.Exemplar{mockExemplar(trace2, 3, 17)}},
+						},
+						QueriedBlockIds: []string{block2.String()},
+					}}: {block2},
+				},
+			},
+			limits: &blocksStoreLimitsMock{},
+			expectedSeries: []cortexpb.TimeSeries{
+				{
+					Labels: cortexpb.FromLabelsToLabelAdapters(series1),
+					Exemplars: []cortexpb.Exemplar{
+						{Labels: cortexpb.FromLabelsToLabelAdapters(trace1), Value: 1, TimestampMs: 15},
+						{Labels: cortexpb.FromLabelsToLabelAdapters(trace2), Value: 2, TimestampMs: 16},
+					},
+				},
+				{
+					Labels:    cortexpb.FromLabelsToLabelAdapters(series2),
+					Exemplars: []cortexpb.Exemplar{{Labels: cortexpb.FromLabelsToLabelAdapters(trace2), Value: 3, TimestampMs: 17}},
+				},
+			},
+		},
+		"max fetched exemplars per query limit hit": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedExemplarsResponse: &storegatewaypb.ExemplarsResponse{
+						Series: []storegatewaypb.ExemplarSeries{
+							{Labels: labelpb.ZLabelsFromPromLabels(series1), Exemplars: []storegatewaypb.Exemplar{mockExemplar(trace1, 1, 15), mockExemplar(trace2, 2, 16)}},
+						},
+						QueriedBlockIds: []string{block1.String(), block2.String()},
+					}}: {block1, block2},
+				},
+			},
+			limits:      &blocksStoreLimitsMock{maxFetchedExemplarsPerQuery: 1},
+			expectedErr: validation.LimitError(fmt.Sprintf(errMaxFetchedExemplarsPerQueryLimit, 1)),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", minT, maxT).Return(bucketindex.Blocks{{ID: block1}, {ID: block2}}, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), nil)
+
+			q := &blocksStoreQuerier{
+				ctx:         context.Background(),
+				minT:        minT,
+				maxT:        maxT,
+				userID:      "user-1",
+				finder:      finder,
+				stores:      &blocksStoreSetMock{mockedResponses: testData.storeSetResponses},
+				consistency: NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+				logger:      log.NewNopLogger(),
+				metrics:     newBlocksStoreQueryableMetrics(nil),
+				limits:      testData.limits,
+			}
+
+			actual, err := q.selectExemplars(matchers)
+			if testData.expectedErr != nil {
+				require.Error(t, err)
+				assert.Equal(t, testData.expectedErr.Error(), err.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedSeries, actual)
+		})
+	}
+}
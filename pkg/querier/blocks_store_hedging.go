@@ -0,0 +1,293 @@
+package querier
+
+import (
+	"context"
+	"flag"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/atomic"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// The number of most recent store-gateway requests latencies used to compute the hedging delay.
+	hedgingLatencySamples = 1024
+
+	// The min number of latency samples required before starting to hedge requests.
+	hedgingMinLatencySamples = 100
+
+	hedgingSkippedReasonBudgetExhausted = "budget-exhausted"
+	hedgingSkippedReasonNoReplica       = "no-replica"
+)
+
+var (
+	errInvalidHedgingLatencyPercentile = errors.New("the store-gateway hedging latency percentile must be greater than 0 and lower than 100")
+	errInvalidHedgingMaxPerQuery       = errors.New("the store-gateway hedging max requests per query must be greater than 0")
+)
+
+// StoreGatewayHedgingConfig configures the hedging of the requests sent to the store-gateways.
+type StoreGatewayHedgingConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	LatencyPercentile float64       `yaml:"latency_percentile"`
+	MinDelay          time.Duration `yaml:"min_delay"`
+	MaxPerQuery       int           `yaml:"max_per_query"`
+}
+
+func (cfg *StoreGatewayHedgingConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, prefix+".enabled", false, "True to enable hedging of the series requests sent to store-gateways. If a store-gateway has not responded within the configured latency percentile, the same request is sent to another store-gateway holding a replica of the same blocks, and the first response received is used.")
+	f.Float64Var(&cfg.LatencyPercentile, prefix+".latency-percentile", 90, "The percentile of the most recent store-gateway series requests latency after which a request is hedged.")
+	f.DurationVar(&cfg.MinDelay, prefix+".min-delay", 100*time.Millisecond, "The minimum time to wait for a store-gateway response before hedging the request, regardless of the observed latency percentile.")
+	f.IntVar(&cfg.MaxPerQuery, prefix+".max-per-query", 2, "The max number of hedged requests issued for a single query.")
+}
+
+// Validate the config.
+func (cfg *StoreGatewayHedgingConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.LatencyPercentile <= 0 || cfg.LatencyPercentile >= 100 {
+		return errInvalidHedgingLatencyPercentile
+	}
+	if cfg.MaxPerQuery <= 0 {
+		return errInvalidHedgingMaxPerQuery
+	}
+	return nil
+}
+
+// storeGatewayHedging tracks the latency of the store-gateway requests and hedges the
+// requests which take longer than the configured latency percentile.
+type storeGatewayHedging struct {
+	cfg StoreGatewayHedgingConfig
+
+	// Ring buffer of the most recent requests latencies.
+	latenciesMx sync.Mutex
+	latencies   []time.Duration
+	next        int
+
+	// Metrics.
+	hedgedRequests  prometheus.Counter
+	hedgedWins      prometheus.Counter
+	skippedRequests *prometheus.CounterVec
+}
+
+func newStoreGatewayHedging(cfg StoreGatewayHedgingConfig, reg prometheus.Registerer) *storeGatewayHedging {
+	h := &storeGatewayHedging{
+		cfg:       cfg,
+		latencies: make([]time.Duration, 0, hedgingLatencySamples),
+		hedgedRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "querier_storegateway_hedged_requests_total",
+			Help:      "Total number of series requests hedged to another store-gateway because of a slow response.",
+		}),
+		hedgedWins: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "querier_storegateway_hedged_requests_won_total",
+			Help:      "Total number of hedged series requests which completed before the original request.",
+		}),
+		skippedRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "querier_storegateway_hedged_requests_skipped_total",
+			Help:      "Total number of slow series requests which have not been hedged.",
+		}, []string{"reason"}),
+	}
+
+	// Init the metrics for all reasons.
+	h.skippedRequests.WithLabelValues(hedgingSkippedReasonBudgetExhausted)
+	h.skippedRequests.WithLabelValues(hedgingSkippedReasonNoReplica)
+
+	return h
+}
+
+// observe records the latency of a successful store-gateway request.
+func (h *storeGatewayHedging) observe(latency time.Duration) {
+	h.latenciesMx.Lock()
+	defer h.latenciesMx.Unlock()
+
+	if len(h.latencies) < hedgingLatencySamples {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % hedgingLatencySamples
+}
+
+// delay returns how long to wait for a store-gateway response before hedging the request.
+// Returns false if not enough latencies have been observed yet to hedge requests.
+func (h *storeGatewayHedging) delay() (time.Duration, bool) {
+	h.latenciesMx.Lock()
+	if len(h.latencies) < hedgingMinLatencySamples {
+		h.latenciesMx.Unlock()
+		return 0, false
+	}
+
+	sorted := make([]time.Duration, len(h.latencies))
+	copy(sorted, h.latencies)
+	h.latenciesMx.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	delay := sorted[int(float64(len(sorted)-1)*h.cfg.LatencyPercentile/100)]
+	if delay < h.cfg.MinDelay {
+		delay = h.cfg.MinDelay
+	}
+
+	return delay, true
+}
+
+// newBudget returns the number of hedged requests a single query can issue.
+func (h *storeGatewayHedging) newBudget() *atomic.Int32 {
+	return atomic.NewInt32(int32(h.cfg.MaxPerQuery))
+}
+
+// fetchSeries fetches the series of the input blocks from the input store-gateway. If the store-gateway
+// has not responded within the hedging delay, the same request is sent to the store-gateways returned by
+// getHedgedClients (other replicas of the same blocks), and the first successful result is returned.
+// The successful results which are discarded are passed to release.
+func (h *storeGatewayHedging) fetchSeries(
+	ctx context.Context,
+	c BlocksStoreClient,
+	blockIDs []ulid.ULID,
+	budget *atomic.Int32,
+	getHedgedClients func() (map[BlocksStoreClient][]ulid.ULID, error),
+	fetch func(ctx context.Context, c BlocksStoreClient, blockIDs []ulid.ULID) (*seriesFetchResult, error),
+	release func(*seriesFetchResult),
+) (*seriesFetchResult, error) {
+	// The request which doesn't complete first is canceled once returning.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		res    *seriesFetchResult
+		err    error
+		hedged bool
+	}
+
+	// The channel is buffered, so that the request not completing first doesn't block.
+	outcomes := make(chan outcome, 2)
+	pending := 1
+
+	// Once returning, the results of the requests still running are released when they complete.
+	defer func() {
+		go func(pending int) {
+			for ; pending > 0; pending-- {
+				if o := <-outcomes; o.err == nil {
+					release(o.res)
+				}
+			}
+		}(pending)
+	}()
+
+	go func() {
+		res, err := fetch(ctx, c, blockIDs)
+		outcomes <- outcome{res: res, err: err}
+	}()
+
+	// If the hedging delay can't be computed yet, the timer channel is nil and never fires.
+	var timerC <-chan time.Time
+	if delay, ok := h.delay(); ok {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		timerC = timer.C
+	}
+
+	for {
+		select {
+		case o := <-outcomes:
+			pending--
+
+			// If one request failed while the other is still running, we wait for the other one.
+			if o.err != nil && pending > 0 {
+				continue
+			}
+			if o.err == nil && o.hedged {
+				h.hedgedWins.Inc()
+			}
+			return o.res, o.err
+
+		case <-timerC:
+			timerC = nil
+
+			clients, err := getHedgedClients()
+			if err != nil || len(clients) == 0 {
+				h.skippedRequests.WithLabelValues(hedgingSkippedReasonNoReplica).Inc()
+				continue
+			}
+			if budget.Dec() < 0 {
+				h.skippedRequests.WithLabelValues(hedgingSkippedReasonBudgetExhausted).Inc()
+				continue
+			}
+
+			h.hedgedRequests.Inc()
+			pending++
+
+			go func() {
+				res, err := fetchSeriesFromClients(ctx, clients, fetch, release)
+				outcomes <- outcome{res: res, err: err, hedged: true}
+			}()
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// fetchSeriesFromClients concurrently fetches the series from all input clients and merges the results.
+// If any request fails, the results of the successful ones are passed to release.
+func fetchSeriesFromClients(
+	ctx context.Context,
+	clients map[BlocksStoreClient][]ulid.ULID,
+	fetch func(ctx context.Context, c BlocksStoreClient, blockIDs []ulid.ULID) (*seriesFetchResult, error),
+	release func(*seriesFetchResult),
+) (*seriesFetchResult, error) {
+	var (
+		g, gCtx = errgroup.WithContext(ctx)
+		mtx     = sync.Mutex{}
+		merged  = &seriesFetchResult{}
+	)
+
+	for c, blockIDs := range clients {
+		c := c
+		blockIDs := blockIDs
+
+		g.Go(func() error {
+			res, err := fetch(gCtx, c, blockIDs)
+			if err != nil {
+				return err
+			}
+
+			mtx.Lock()
+			merged.merge(res)
+			mtx.Unlock()
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		release(merged)
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+// excludeStoreGatewayForBlocks returns a copy of the input exclude map, with the input
+// store-gateway address added to the excluded addresses of the input blocks.
+func excludeStoreGatewayForBlocks(exclude map[ulid.ULID][]string, blockIDs []ulid.ULID, addr string) map[ulid.ULID][]string {
+	out := make(map[ulid.ULID][]string, len(exclude)+len(blockIDs))
+	for blockID, addrs := range exclude {
+		out[blockID] = addrs
+	}
+
+	for _, blockID := range blockIDs {
+		out[blockID] = append(append([]string(nil), out[blockID]...), addr)
+	}
+
+	return out
+}
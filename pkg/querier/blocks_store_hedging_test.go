@@ -0,0 +1,226 @@
+package querier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"google.golang.org/grpc"
+
+	"github.com/cortexproject/cortex/pkg/storage/tsdb/bucketindex"
+	"github.com/cortexproject/cortex/pkg/storegateway/storegatewaypb"
+)
+
+func TestStoreGatewayHedgingConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&StoreGatewayHedgingConfig{Enabled: false}).Validate())
+	assert.NoError(t, (&StoreGatewayHedgingConfig{Enabled: true, LatencyPercentile: 90, MaxPerQuery: 1}).Validate())
+	assert.Equal(t, errInvalidHedgingLatencyPercentile, (&StoreGatewayHedgingConfig{Enabled: true, LatencyPercentile: 100, MaxPerQuery: 1}).Validate())
+	assert.Equal(t, errInvalidHedgingLatencyPercentile, (&StoreGatewayHedgingConfig{Enabled: true, LatencyPercentile: 0, MaxPerQuery: 1}).Validate())
+	assert.Equal(t, errInvalidHedgingMaxPerQuery, (&StoreGatewayHedgingConfig{Enabled: true, LatencyPercentile: 90, MaxPerQuery: 0}).Validate())
+}
+
+func TestStoreGatewayHedging_Delay(t *testing.T) {
+	h := newStoreGatewayHedging(StoreGatewayHedgingConfig{Enabled: true, LatencyPercentile: 90, MinDelay: time.Millisecond, MaxPerQuery: 1}, nil)
+
+	// Not enough samples.
+	for i := 1; i < hedgingMinLatencySamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	_, ok := h.delay()
+	assert.False(t, ok)
+
+	h.observe(hedgingMinLatencySamples * time.Millisecond)
+	delay, ok := h.delay()
+	require.True(t, ok)
+	assert.Equal(t, 90*time.Millisecond, delay)
+
+	// The delay is never lower than the min delay.
+	h.cfg.MinDelay = time.Second
+	delay, ok = h.delay()
+	require.True(t, ok)
+	assert.Equal(t, time.Second, delay)
+
+	// Only the most recent samples are used.
+	for i := 0; i < hedgingLatencySamples; i++ {
+		h.observe(2 * time.Second)
+	}
+	delay, ok = h.delay()
+	require.True(t, ok)
+	assert.Equal(t, 2*time.Second, delay)
+}
+
+func TestBlocksStoreQuerier_Select_ShouldHedgeSlowStoreGateways(t *testing.T) {
+	const (
+		metricName = "test_metric"
+		minT       = int64(10)
+		maxT       = int64(20)
+	)
+
+	var (
+		block1          = ulid.MustNew(1, nil)
+		metricNameLabel = labels.Label{Name: labels.MetricName, Value: metricName}
+		seriesLabels    = labels.Labels{metricNameLabel, {Name: "series", Value: "1"}}
+	)
+
+	newSlowClient := func(addr string, delay time.Duration) BlocksStoreClient {
+		return &slowStoreGatewayClientMock{
+			storeGatewayClientMock: storeGatewayClientMock{remoteAddr: addr, mockedSeriesResponses: []*storepb.SeriesResponse{
+				mockSeriesResponse(seriesLabels, minT, 1),
+				mockHintsResponse(block1),
+			}},
+			delay: delay,
+		}
+	}
+
+	tests := map[string]struct {
+		storeSetResponses []interface{}
+		budget            int32
+		expectedHedged    int
+		expectedWon       int
+		expectedSkipped   map[string]int
+	}{
+		"should hedge the request to another replica if the store-gateway is slow": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{newSlowClient("1.1.1.1", 10*time.Second): {block1}},
+				map[BlocksStoreClient][]ulid.ULID{newSlowClient("2.2.2.2", 0): {block1}},
+			},
+			budget:         1,
+			expectedHedged: 1,
+			expectedWon:    1,
+		},
+		"should use the original response if completes before the hedged one": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{newSlowClient("1.1.1.1", 200*time.Millisecond): {block1}},
+				map[BlocksStoreClient][]ulid.ULID{newSlowClient("2.2.2.2", 10*time.Second): {block1}},
+			},
+			budget:         1,
+			expectedHedged: 1,
+			expectedWon:    0,
+		},
+		"should not hedge the request if the hedging budget has been exhausted": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{newSlowClient("1.1.1.1", 200*time.Millisecond): {block1}},
+				map[BlocksStoreClient][]ulid.ULID{newSlowClient("2.2.2.2", 0): {block1}},
+			},
+			budget:          0,
+			expectedSkipped: map[string]int{hedgingSkippedReasonBudgetExhausted: 1},
+		},
+		"should not hedge the request if there are no other replicas": {
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{newSlowClient("1.1.1.1", 200*time.Millisecond): {block1}},
+				errors.New("no store-gateway instance left after filtering out excluded instances"),
+			},
+			budget:          1,
+			expectedSkipped: map[string]int{hedgingSkippedReasonNoReplica: 1},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			reg := prometheus.NewPedanticRegistry()
+			stores := &blocksStoreSetMock{mockedResponses: testData.storeSetResponses}
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", minT, maxT).Return(bucketindex.Blocks{{ID: block1}}, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), nil)
+
+			hedging := newStoreGatewayHedging(StoreGatewayHedgingConfig{Enabled: true, LatencyPercentile: 90, MinDelay: 50 * time.Millisecond, MaxPerQuery: 1}, reg)
+			for i := 0; i < hedgingMinLatencySamples; i++ {
+				hedging.observe(time.Millisecond)
+			}
+
+			q := &blocksStoreQuerier{
+				ctx:           context.Background(),
+				minT:          minT,
+				maxT:          maxT,
+				userID:        "user-1",
+				finder:        finder,
+				stores:        stores,
+				consistency:   NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+				logger:        log.NewNopLogger(),
+				metrics:       newBlocksStoreQueryableMetrics(reg),
+				limits:        &blocksStoreLimitsMock{},
+				hedging:       hedging,
+				hedgingBudget: hedging.newBudget(),
+			}
+			q.hedgingBudget.Store(testData.budget)
+
+			start := time.Now()
+			set := q.Select(true, nil, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, metricName))
+			require.NoError(t, set.Err())
+			assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+
+			require.True(t, set.Next())
+			assert.Equal(t, seriesLabels, set.At().Labels())
+			assert.False(t, set.Next())
+
+			assert.Equal(t, float64(testData.expectedHedged), testutil.ToFloat64(hedging.hedgedRequests))
+			assert.Equal(t, float64(testData.expectedWon), testutil.ToFloat64(hedging.hedgedWins))
+			for _, reason := range []string{hedgingSkippedReasonBudgetExhausted, hedgingSkippedReasonNoReplica} {
+				assert.Equal(t, float64(testData.expectedSkipped[reason]), testutil.ToFloat64(hedging.skippedRequests.WithLabelValues(reason)), reason)
+			}
+		})
+	}
+}
+
+func TestFetchSeriesFromClients_ShouldReleaseTheSuccessfulResultsOnFailure(t *testing.T) {
+	block1 := ulid.MustNew(1, nil)
+	block2 := ulid.MustNew(2, nil)
+	clients := map[BlocksStoreClient][]ulid.ULID{
+		&storeGatewayClientMock{remoteAddr: "1.1.1.1"}: {block1},
+		&storeGatewayClientMock{remoteAddr: "2.2.2.2"}: {block2},
+	}
+
+	fetch := func(_ context.Context, c BlocksStoreClient, blockIDs []ulid.ULID) (*seriesFetchResult, error) {
+		if c.RemoteAddress() == "2.2.2.2" {
+			return nil, errors.New("failed")
+		}
+		return &seriesFetchResult{queriedBlocks: blockIDs, numChunks: 2, chunksBytes: 100}, nil
+	}
+
+	var released []*seriesFetchResult
+	_, err := fetchSeriesFromClients(context.Background(), clients, fetch, func(res *seriesFetchResult) {
+		released = append(released, res)
+	})
+	require.Error(t, err)
+
+	require.Len(t, released, 1)
+	assert.Equal(t, 2, released[0].numChunks)
+	assert.Equal(t, 100, released[0].chunksBytes)
+}
+
+func TestExcludeStoreGatewayForBlocks(t *testing.T) {
+	block1 := ulid.MustNew(1, nil)
+	block2 := ulid.MustNew(2, nil)
+
+	exclude := map[ulid.ULID][]string{block1: {"1.1.1.1"}}
+	actual := excludeStoreGatewayForBlocks(exclude, []ulid.ULID{block1, block2}, "2.2.2.2")
+
+	assert.Equal(t, map[ulid.ULID][]string{block1: {"1.1.1.1", "2.2.2.2"}, block2: {"2.2.2.2"}}, actual)
+	assert.Equal(t, map[ulid.ULID][]string{block1: {"1.1.1.1"}}, exclude, "the input map should not be modified")
+}
+
+// slowStoreGatewayClientMock is a storeGatewayClientMock whose series requests take
+// the configured delay before returning, unless the context is canceled.
+type slowStoreGatewayClientMock struct {
+	storeGatewayClientMock
+
+	delay time.Duration
+}
+
+func (m *slowStoreGatewayClientMock) Series(ctx context.Context, in *storepb.SeriesRequest, opts ...grpc.CallOption) (storegatewaypb.StoreGateway_SeriesClient, error) {
+	select {
+	case <-time.After(m.delay):
+		return m.storeGatewayClientMock.Series(ctx, in, opts...)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -20,9 +20,11 @@ import (
 	"github.com/prometheus/prometheus/storage"
 	"github.com/thanos-io/thanos/pkg/block"
 	"github.com/thanos-io/thanos/pkg/extprom"
+	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/store/hintspb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 	"github.com/thanos-io/thanos/pkg/strutil"
+	"github.com/weaveworks/common/httpgrpc"
 	"go.uber.org/atomic"
 	"golang.org/x/sync/errgroup"
 	grpc_metadata "google.golang.org/grpc/metadata"
@@ -56,6 +58,8 @@ const (
 var (
 	errNoStoreGatewayAddress  = errors.New("no store-gateway address configured")
 	errMaxChunksPerQueryLimit = "the query hit the max number of chunks limit while fetching chunks from store-gateways for %s (limit: %d)"
+
+	errMaxFetchedExemplarsPerQueryLimit = "the query hit the max number of exemplars limit (limit: %d exemplars)"
 )
 
 // BlocksStoreSet is the interface used to get the clients to query series on a set of blocks.
@@ -65,7 +69,7 @@ type BlocksStoreSet interface {
 	// GetClientsFor returns the store gateway clients that should be used to
 	// query the set of blocks in input. The exclude parameter is the map of
 	// blocks -> store-gateway addresses that should be excluded.
-	GetClientsFor(userID string, blockIDs []ulid.ULID, exclude map[ulid.ULID][]string) (map[BlocksStoreClient][]ulid.ULID, error)
+	GetClientsFor(userID string, blocks bucketindex.Blocks, exclude map[ulid.ULID][]string) (map[BlocksStoreClient][]ulid.ULID, error)
 }
 
 // BlocksFinder is the interface used to find blocks for a given user and time range.
@@ -93,11 +97,14 @@ type BlocksStoreLimits interface {
 
 	MaxChunksPerQueryFromStore(userID string) int
 	StoreGatewayTenantShardSize(userID string) int
+	QueryPartialResponseEnabled(userID string) bool
+	MaxFetchedExemplarsPerQuery(userID string) int
 }
 
 type blocksStoreQueryableMetrics struct {
-	storesHit prometheus.Histogram
-	refetches prometheus.Histogram
+	storesHit        prometheus.Histogram
+	refetches        prometheus.Histogram
+	partialResponses prometheus.Counter
 }
 
 func newBlocksStoreQueryableMetrics(reg prometheus.Registerer) *blocksStoreQueryableMetrics {
@@ -114,6 +121,11 @@ func newBlocksStoreQueryableMetrics(reg prometheus.Registerer) *blocksStoreQuery
 			Help:      "Number of re-fetches attempted while querying store-gateway instances due to missing blocks.",
 			Buckets:   []float64{0, 1, 2},
 		}),
+		partialResponses: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Namespace: "cortex",
+			Name:      "querier_storegateway_partial_responses_total",
+			Help:      "Total number of queries which returned a partial result because some blocks couldn't be queried from any store-gateway.",
+		}),
 	}
 }
 
@@ -130,6 +142,12 @@ type BlocksStoreQueryable struct {
 	metrics         *blocksStoreQueryableMetrics
 	limits          BlocksStoreLimits
 
+	// Hedging of the requests sent to store-gateways (nil if disabled).
+	hedging *storeGatewayHedging
+
+	// Bucket client closed on shutdown (nil if not owned by the queryable).
+	bucketClient objstore.Bucket
+
 	// Subservices manager.
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -167,7 +185,7 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 	}
 
 	// Blocks finder doesn't use chunks, but we pass config for consistency.
-	cachingBucket, err := cortex_tsdb.CreateCachingBucket(storageCfg.BucketStore.ChunksCache, storageCfg.BucketStore.MetadataCache, bucketClient, logger, extprom.WrapRegistererWith(prometheus.Labels{"component": "querier"}, reg))
+	cachingBucket, err := cortex_tsdb.CreateCachingBucket("querier", storageCfg.BucketStore.ChunksCache, storageCfg.BucketStore.MetadataCache, bucketClient, logger, extprom.WrapRegistererWith(prometheus.Labels{"component": "querier"}, reg))
 	if err != nil {
 		return nil, errors.Wrap(err, "create caching bucket")
 	}
@@ -240,7 +258,18 @@ func NewBlocksStoreQueryableFromConfig(querierCfg Config, gatewayCfg storegatewa
 		reg,
 	)
 
-	return NewBlocksStoreQueryable(stores, finder, consistency, limits, querierCfg.QueryStoreAfter, logger, reg)
+	q, err := NewBlocksStoreQueryable(stores, finder, consistency, limits, querierCfg.QueryStoreAfter, logger, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	if querierCfg.StoreGatewayHedging.Enabled {
+		q.hedging = newStoreGatewayHedging(querierCfg.StoreGatewayHedging, reg)
+	}
+
+	q.bucketClient = bucketClient
+
+	return q, nil
 }
 
 func (q *BlocksStoreQueryable) starting(ctx context.Context) error {
@@ -265,11 +294,24 @@ func (q *BlocksStoreQueryable) running(ctx context.Context) error {
 }
 
 func (q *BlocksStoreQueryable) stopping(_ error) error {
-	return services.StopManagerAndAwaitStopped(context.Background(), q.subservices)
+	err := services.StopManagerAndAwaitStopped(context.Background(), q.subservices)
+
+	// The bucket client is closed once the subservices using it have been stopped.
+	if q.bucketClient != nil {
+		if closeErr := q.bucketClient.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
 }
 
 // Querier returns a new Querier on the storage.
 func (q *BlocksStoreQueryable) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+	return q.newQuerier(ctx, mint, maxt)
+}
+
+func (q *BlocksStoreQueryable) newQuerier(ctx context.Context, mint, maxt int64) (*blocksStoreQuerier, error) {
 	if s := q.State(); s != services.Running {
 		return nil, errors.Errorf("BlocksStoreQueryable is not running: %v", s)
 	}
@@ -279,7 +321,7 @@ func (q *BlocksStoreQueryable) Querier(ctx context.Context, mint, maxt int64) (s
 		return nil, err
 	}
 
-	return &blocksStoreQuerier{
+	querier := &blocksStoreQuerier{
 		ctx:             ctx,
 		minT:            mint,
 		maxT:            maxt,
@@ -291,7 +333,14 @@ func (q *BlocksStoreQueryable) Querier(ctx context.Context, mint, maxt int64) (s
 		consistency:     q.consistency,
 		logger:          q.logger,
 		queryStoreAfter: q.queryStoreAfter,
-	}, nil
+	}
+
+	if q.hedging != nil {
+		querier.hedging = q.hedging
+		querier.hedgingBudget = q.hedging.newBudget()
+	}
+
+	return querier, nil
 }
 
 type blocksStoreQuerier struct {
@@ -308,6 +357,11 @@ type blocksStoreQuerier struct {
 	// If set, the querier manipulates the max time to not be greater than
 	// "now - queryStoreAfter" so that most recent blocks are not queried.
 	queryStoreAfter time.Duration
+
+	// Hedging of the series requests sent to store-gateways (nil if disabled) and
+	// the number of hedged requests the query can still issue.
+	hedging       *storeGatewayHedging
+	hedgingBudget *atomic.Int32
 }
 
 // Select implements storage.Querier interface.
@@ -317,6 +371,11 @@ func (q *blocksStoreQuerier) Select(_ bool, sp *storage.SelectHints, matchers ..
 }
 
 func (q *blocksStoreQuerier) LabelNames() ([]string, storage.Warnings, error) {
+	return q.LabelNamesWithMatchers()
+}
+
+// LabelNamesWithMatchers implements series.LabelNamesWithMatchersQuerier.
+func (q *blocksStoreQuerier) LabelNamesWithMatchers(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
 	spanLog, spanCtx := spanlogger.New(q.ctx, "blocksStoreQuerier.LabelNames")
 	defer spanLog.Span.Finish()
 
@@ -328,8 +387,8 @@ func (q *blocksStoreQuerier) LabelNames() ([]string, storage.Warnings, error) {
 		resWarnings = storage.Warnings(nil)
 	)
 
-	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
-		nameSets, warnings, queriedBlocks, err := q.fetchLabelNamesFromStore(spanCtx, clients, minT, maxT)
+	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, _ bucketindex.Blocks, _ map[ulid.ULID][]string, minT, maxT int64) ([]ulid.ULID, error) {
+		nameSets, warnings, queriedBlocks, err := q.fetchLabelNamesFromStore(spanCtx, clients, minT, maxT, matchers...)
 		if err != nil {
 			return nil, err
 		}
@@ -342,12 +401,12 @@ func (q *blocksStoreQuerier) LabelNames() ([]string, storage.Warnings, error) {
 		return queriedBlocks, nil
 	}
 
-	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, queryFunc)
+	warnings, err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, queryFunc)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return strutil.MergeSlices(resNameSets...), resWarnings, nil
+	return strutil.MergeSlices(resNameSets...), append(resWarnings, warnings...), nil
 }
 
 func (q *blocksStoreQuerier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
@@ -363,7 +422,7 @@ func (q *blocksStoreQuerier) LabelValues(name string, matchers ...*labels.Matche
 		resultMtx sync.Mutex
 	)
 
-	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
+	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, _ bucketindex.Blocks, _ map[ulid.ULID][]string, minT, maxT int64) ([]ulid.ULID, error) {
 		valueSets, warnings, queriedBlocks, err := q.fetchLabelValuesFromStore(spanCtx, name, clients, minT, maxT, matchers...)
 		if err != nil {
 			return nil, err
@@ -377,12 +436,12 @@ func (q *blocksStoreQuerier) LabelValues(name string, matchers ...*labels.Matche
 		return queriedBlocks, nil
 	}
 
-	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, queryFunc)
+	warnings, err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, queryFunc)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return strutil.MergeSlices(resValueSets...), resWarnings, nil
+	return strutil.MergeSlices(resValueSets...), append(resWarnings, warnings...), nil
 }
 
 func (q *blocksStoreQuerier) Close() error {
@@ -409,8 +468,8 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		resultMtx sync.Mutex
 	)
 
-	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
-		seriesSets, queriedBlocks, warnings, numChunks, err := q.fetchSeriesFromStores(spanCtx, sp, clients, minT, maxT, matchers, convertedMatchers, maxChunksLimit, leftChunksLimit)
+	queryFunc := func(clients map[BlocksStoreClient][]ulid.ULID, blocks bucketindex.Blocks, exclude map[ulid.ULID][]string, minT, maxT int64) ([]ulid.ULID, error) {
+		seriesSets, queriedBlocks, warnings, numChunks, err := q.fetchSeriesFromStores(spanCtx, sp, clients, blocks, exclude, minT, maxT, matchers, convertedMatchers, maxChunksLimit, leftChunksLimit)
 		if err != nil {
 			return nil, err
 		}
@@ -430,7 +489,7 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 		return queriedBlocks, nil
 	}
 
-	err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, queryFunc)
+	warnings, err := q.queryWithConsistencyCheck(spanCtx, spanLog, minT, maxT, queryFunc)
 	if err != nil {
 		return storage.ErrSeriesSet(err)
 	}
@@ -441,11 +500,15 @@ func (q *blocksStoreQuerier) selectSorted(sp *storage.SelectHints, matchers ...*
 
 	return series.NewSeriesSetWithWarnings(
 		storage.NewMergeSeriesSet(resSeriesSets, storage.ChainedSeriesMerge),
-		resWarnings)
+		append(resWarnings, warnings...))
 }
 
+// queryWithConsistencyCheck runs the queryFunc against the store-gateways holding the blocks in the
+// input time range, retrying the missing blocks on other store-gateways. If some blocks can't be queried
+// from any store-gateway, an error is returned unless partial responses are enabled for the tenant, in
+// which case the time ranges of the missing blocks are returned as warnings.
 func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logger log.Logger, minT, maxT int64,
-	queryFunc func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error)) error {
+	queryFunc func(clients map[BlocksStoreClient][]ulid.ULID, blocks bucketindex.Blocks, exclude map[ulid.ULID][]string, minT, maxT int64) ([]ulid.ULID, error)) (storage.Warnings, error) {
 	// If queryStoreAfter is enabled, we do manipulate the query maxt to query samples up until
 	// now - queryStoreAfter, because the most recent time range is covered by ingesters. This
 	// optimization is particularly important for the blocks storage because can be used to skip
@@ -462,38 +525,49 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 		if maxT < minT {
 			q.metrics.storesHit.Observe(0)
 			level.Debug(logger).Log("msg", "empty query time range after max time manipulation")
-			return nil
+			return nil, nil
 		}
 	}
 
 	// Find the list of blocks we need to query given the time range.
 	knownBlocks, knownDeletionMarks, err := q.finder.GetBlocks(ctx, q.userID, minT, maxT)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(knownBlocks) == 0 {
 		q.metrics.storesHit.Observe(0)
 		level.Debug(logger).Log("msg", "no blocks found")
-		return nil
+		return nil, nil
 	}
 
 	level.Debug(logger).Log("msg", "found blocks to query", "expected", knownBlocks.String())
 
 	var (
 		// At the beginning the list of blocks to query are all known blocks.
-		remainingBlocks = knownBlocks.GetULIDs()
+		remainingBlocks = knownBlocks
 		attemptedBlocks = map[ulid.ULID][]string{}
 		touchedStores   = map[string]struct{}{}
 
 		resQueriedBlocks = []ulid.ULID(nil)
+
+		partialResponse = q.limits.QueryPartialResponseEnabled(q.userID)
 	)
 
 	for attempt := 1; attempt <= maxFetchSeriesAttempts; attempt++ {
 		// Find the set of store-gateway instances having the blocks. The exclude parameter is the
 		// map of blocks queried so far, with the list of store-gateway addresses for each block.
 		clients, err := q.stores.GetClientsFor(q.userID, remainingBlocks, attemptedBlocks)
-		if err != nil {
+		if err != nil && partialResponse {
+			// Some blocks can't be queried from any store-gateway, so we query the available ones
+			// and the other ones will be reported as missing.
+			level.Warn(logger).Log("msg", "unable to get store-gateway clients for some blocks, querying the available ones", "attempt", attempt, "err", err)
+			clients = q.getClientsForAvailableBlocks(remainingBlocks, attemptedBlocks)
+
+			if len(clients) == 0 && attempt > 1 {
+				break
+			}
+		} else if err != nil {
 			// If it's a retry and we get an error, it means there are no more store-gateways left
 			// from which running another attempt, so we're just stopping retrying.
 			if attempt > 1 {
@@ -501,15 +575,16 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 				break
 			}
 
-			return err
+			return nil, err
 		}
 		level.Debug(logger).Log("msg", "found store-gateway instances to query", "num instances", len(clients), "attempt", attempt)
 
 		// Fetch series from stores. If an error occur we do not retry because retries
-		// are only meant to cover missing blocks.
-		queriedBlocks, err := queryFunc(clients, minT, maxT)
+		// are only meant to cover missing blocks. The remaining blocks and the attempted ones
+		// are passed to pick other store-gateways in case the request is hedged.
+		queriedBlocks, err := queryFunc(clients, remainingBlocks, attemptedBlocks, minT, maxT)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		level.Debug(logger).Log("msg", "received series from all store-gateways", "queried blocks", strings.Join(convertULIDsToString(queriedBlocks), " "))
 
@@ -530,24 +605,51 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(ctx context.Context, logg
 			q.metrics.storesHit.Observe(float64(len(touchedStores)))
 			q.metrics.refetches.Observe(float64(attempt - 1))
 
-			return nil
+			return nil, nil
 		}
 
 		level.Debug(logger).Log("msg", "consistency check failed", "attempt", attempt, "missing blocks", strings.Join(convertULIDsToString(missingBlocks), " "))
 
 		// The next attempt should just query the missing blocks.
-		remainingBlocks = missingBlocks
+		remainingBlocks = filterBlocksByIDs(knownBlocks, missingBlocks)
 	}
 
 	// We've not been able to query all expected blocks after all retries.
+	if partialResponse {
+		q.metrics.partialResponses.Inc()
+		level.Warn(util_log.WithContext(ctx, logger)).Log("msg", "returning partial response because some blocks were not queried", "missing blocks", strings.Join(convertULIDsToString(remainingBlocks.GetULIDs()), " "))
+		return storage.Warnings{newPartialResponseWarning(remainingBlocks)}, nil
+	}
+
 	level.Warn(util_log.WithContext(ctx, logger)).Log("msg", "failed consistency check", "err", err)
-	return fmt.Errorf("consistency check failed because some blocks were not queried: %s", strings.Join(convertULIDsToString(remainingBlocks), " "))
+	return nil, fmt.Errorf("consistency check failed because some blocks were not queried: %s", strings.Join(convertULIDsToString(remainingBlocks.GetULIDs()), " "))
+}
+
+// getClientsForAvailableBlocks returns the store-gateway clients for the input blocks which can be
+// queried from at least one non excluded store-gateway, skipping the other ones.
+func (q *blocksStoreQuerier) getClientsForAvailableBlocks(blocks bucketindex.Blocks, exclude map[ulid.ULID][]string) map[BlocksStoreClient][]ulid.ULID {
+	clients := map[BlocksStoreClient][]ulid.ULID{}
+
+	for _, block := range blocks {
+		blockClients, err := q.stores.GetClientsFor(q.userID, bucketindex.Blocks{block}, exclude)
+		if err != nil {
+			continue
+		}
+
+		for c, blockIDs := range blockClients {
+			clients[c] = append(clients[c], blockIDs...)
+		}
+	}
+
+	return clients
 }
 
 func (q *blocksStoreQuerier) fetchSeriesFromStores(
 	ctx context.Context,
 	sp *storage.SelectHints,
 	clients map[BlocksStoreClient][]ulid.ULID,
+	blocks bucketindex.Blocks,
+	exclude map[ulid.ULID][]string,
 	minT int64,
 	maxT int64,
 	matchers []*labels.Matcher,
@@ -563,10 +665,30 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(
 		warnings      = storage.Warnings(nil)
 		queriedBlocks = []ulid.ULID(nil)
 		numChunks     = atomic.NewInt32(0)
-		spanLog       = spanlogger.FromContext(ctx)
 		queryLimiter  = limiter.QueryLimiterFromContextWithFallback(ctx)
 	)
 
+	// Releases the chunks charged to the query limits by a request whose result is discarded.
+	release := func(res *seriesFetchResult) {
+		numChunks.Sub(int32(res.numChunks))
+		queryLimiter.ReleaseChunkBytes(res.chunksBytes)
+	}
+
+	// See: https://github.com/prometheus/prometheus/pull/8050
+	// TODO(goutham): we should ideally be passing the hints down to the storage layer
+	// and let the TSDB return us data with no chunks as in prometheus#8050.
+	// But this is an acceptable workaround for now.
+	skipChunks := sp != nil && sp.Func == "series"
+
+	fetch := func(ctx context.Context, c BlocksStoreClient, blockIDs []ulid.ULID) (*seriesFetchResult, error) {
+		req, err := createSeriesRequest(minT, maxT, convertedMatchers, skipChunks, blockIDs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create series request")
+		}
+
+		return q.fetchSeriesFromStore(ctx, c, req, blockIDs, matchers, maxChunksLimit, leftChunksLimit, numChunks, release)
+	}
+
 	// Concurrently fetch series from all clients.
 	for c, blockIDs := range clients {
 		// Change variables scope since it will be used in a goroutine.
@@ -574,110 +696,169 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(
 		blockIDs := blockIDs
 
 		g.Go(func() error {
-			// See: https://github.com/prometheus/prometheus/pull/8050
-			// TODO(goutham): we should ideally be passing the hints down to the storage layer
-			// and let the TSDB return us data with no chunks as in prometheus#8050.
-			// But this is an acceptable workaround for now.
-			skipChunks := sp != nil && sp.Func == "series"
-
-			req, err := createSeriesRequest(minT, maxT, convertedMatchers, skipChunks, blockIDs)
-			if err != nil {
-				return errors.Wrapf(err, "failed to create series request")
+			var (
+				res *seriesFetchResult
+				err error
+			)
+
+			if q.hedging != nil {
+				res, err = q.hedging.fetchSeries(gCtx, c, blockIDs, q.hedgingBudget, func() (map[BlocksStoreClient][]ulid.ULID, error) {
+					return q.stores.GetClientsFor(q.userID, filterBlocksByIDs(blocks, blockIDs), excludeStoreGatewayForBlocks(exclude, blockIDs, c.RemoteAddress()))
+				}, fetch, release)
+			} else {
+				res, err = fetch(gCtx, c, blockIDs)
 			}
-
-			stream, err := c.Series(gCtx, req)
 			if err != nil {
-				return errors.Wrapf(err, "failed to fetch series from %s", c.RemoteAddress())
+				if q.isPartialResponseTolerated(ctx, err) {
+					level.Warn(spanlogger.FromContext(ctx)).Log("msg", "failed to fetch series from store-gateway, its blocks will be considered missing", "instance", c.RemoteAddress(), "err", err)
+					return nil
+				}
+				return err
 			}
 
-			mySeries := []*storepb.Series(nil)
-			myWarnings := storage.Warnings(nil)
-			myQueriedBlocks := []ulid.ULID(nil)
+			// Store the result.
+			mtx.Lock()
+			seriesSets = append(seriesSets, &blockQuerierSeriesSet{series: res.series})
+			warnings = append(warnings, res.warnings...)
+			queriedBlocks = append(queriedBlocks, res.queriedBlocks...)
+			mtx.Unlock()
 
-			for {
-				// Ensure the context hasn't been canceled in the meanwhile (eg. an error occurred
-				// in another goroutine).
-				if gCtx.Err() != nil {
-					return gCtx.Err()
-				}
+			return nil
+		})
+	}
 
-				resp, err := stream.Recv()
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					return errors.Wrapf(err, "failed to receive series from %s", c.RemoteAddress())
-				}
+	// Wait until all client requests complete.
+	if err := g.Wait(); err != nil {
+		return nil, nil, nil, 0, err
+	}
 
-				// Response may either contain series, warning or hints.
-				if s := resp.GetSeries(); s != nil {
-					mySeries = append(mySeries, s)
-
-					// Add series fingerprint to query limiter; will return error if we are over the limit
-					limitErr := queryLimiter.AddSeries(cortexpb.FromLabelsToLabelAdapters(s.PromLabels()))
-					if limitErr != nil {
-						return validation.LimitError(limitErr.Error())
-					}
-
-					// Ensure the max number of chunks limit hasn't been reached (max == 0 means disabled).
-					if maxChunksLimit > 0 {
-						actual := numChunks.Add(int32(len(s.Chunks)))
-						if actual > int32(leftChunksLimit) {
-							return validation.LimitError(fmt.Sprintf(errMaxChunksPerQueryLimit, util.LabelMatchersToString(matchers), maxChunksLimit))
-						}
-					}
-					chunksSize := 0
-					for _, c := range s.Chunks {
-						chunksSize += c.Size()
-					}
-					if chunkBytesLimitErr := queryLimiter.AddChunkBytes(chunksSize); chunkBytesLimitErr != nil {
-						return validation.LimitError(chunkBytesLimitErr.Error())
-					}
-				}
+	return seriesSets, queriedBlocks, warnings, int(numChunks.Load()), nil
+}
 
-				if w := resp.GetWarning(); w != "" {
-					myWarnings = append(myWarnings, errors.New(w))
-				}
+// seriesFetchResult holds the series received from one or more store-gateways.
+type seriesFetchResult struct {
+	series        []*storepb.Series
+	warnings      storage.Warnings
+	queriedBlocks []ulid.ULID
+	numChunks     int
+	chunksBytes   int
+}
 
-				if h := resp.GetHints(); h != nil {
-					hints := hintspb.SeriesResponseHints{}
-					if err := types.UnmarshalAny(h, &hints); err != nil {
-						return errors.Wrapf(err, "failed to unmarshal series hints from %s", c.RemoteAddress())
-					}
+func (r *seriesFetchResult) merge(other *seriesFetchResult) {
+	r.series = append(r.series, other.series...)
+	r.warnings = append(r.warnings, other.warnings...)
+	r.queriedBlocks = append(r.queriedBlocks, other.queriedBlocks...)
+	r.numChunks += other.numChunks
+	r.chunksBytes += other.chunksBytes
+}
 
-					ids, err := convertBlockHintsToULIDs(hints.QueriedBlocks)
-					if err != nil {
-						return errors.Wrapf(err, "failed to parse queried block IDs from received hints")
-					}
+// fetchSeriesFromStore fetches the series from a single store-gateway. The received chunks are charged
+// to the query limits while streaming. When hedging is enabled, the chunks charged by a failed or canceled
+// request are released, because the same blocks are fetched by another request.
+func (q *blocksStoreQuerier) fetchSeriesFromStore(
+	ctx context.Context,
+	c BlocksStoreClient,
+	req *storepb.SeriesRequest,
+	blockIDs []ulid.ULID,
+	matchers []*labels.Matcher,
+	maxChunksLimit int,
+	leftChunksLimit int,
+	numChunks *atomic.Int32,
+	release func(*seriesFetchResult),
+) (_ *seriesFetchResult, returnErr error) {
+	var (
+		start        = time.Now()
+		spanLog      = spanlogger.FromContext(ctx)
+		queryLimiter = limiter.QueryLimiterFromContextWithFallback(ctx)
+		res          = &seriesFetchResult{}
+	)
+
+	if q.hedging != nil {
+		defer func() {
+			if returnErr != nil {
+				release(res)
+			}
+		}()
+	}
+
+	stream, err := c.Series(ctx, req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch series from %s", c.RemoteAddress())
+	}
+
+	for {
+		// Ensure the context hasn't been canceled in the meanwhile (eg. an error occurred
+		// in another goroutine).
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to receive series from %s", c.RemoteAddress())
+		}
 
-					myQueriedBlocks = append(myQueriedBlocks, ids...)
+		// Response may either contain series, warning or hints.
+		if s := resp.GetSeries(); s != nil {
+			res.series = append(res.series, s)
+
+			// Add series fingerprint to query limiter; will return error if we are over the limit
+			limitErr := queryLimiter.AddSeries(cortexpb.FromLabelsToLabelAdapters(s.PromLabels()))
+			if limitErr != nil {
+				return nil, validation.LimitError(limitErr.Error())
+			}
+
+			// Ensure the max number of chunks limit hasn't been reached (max == 0 means disabled).
+			if maxChunksLimit > 0 {
+				res.numChunks += len(s.Chunks)
+				if actual := numChunks.Add(int32(len(s.Chunks))); actual > int32(leftChunksLimit) {
+					return nil, validation.LimitError(fmt.Sprintf(errMaxChunksPerQueryLimit, util.LabelMatchersToString(matchers), maxChunksLimit))
 				}
 			}
+			chunksSize := 0
+			for _, c := range s.Chunks {
+				chunksSize += c.Size()
+			}
+			res.chunksBytes += chunksSize
+			if chunkBytesLimitErr := queryLimiter.AddChunkBytes(chunksSize); chunkBytesLimitErr != nil {
+				return nil, validation.LimitError(chunkBytesLimitErr.Error())
+			}
+		}
 
-			level.Debug(spanLog).Log("msg", "received series from store-gateway",
-				"instance", c.RemoteAddress(),
-				"num series", len(mySeries),
-				"bytes series", countSeriesBytes(mySeries),
-				"requested blocks", strings.Join(convertULIDsToString(blockIDs), " "),
-				"queried blocks", strings.Join(convertULIDsToString(myQueriedBlocks), " "))
+		if w := resp.GetWarning(); w != "" {
+			res.warnings = append(res.warnings, errors.New(w))
+		}
 
-			// Store the result.
-			mtx.Lock()
-			seriesSets = append(seriesSets, &blockQuerierSeriesSet{series: mySeries})
-			warnings = append(warnings, myWarnings...)
-			queriedBlocks = append(queriedBlocks, myQueriedBlocks...)
-			mtx.Unlock()
+		if h := resp.GetHints(); h != nil {
+			hints := hintspb.SeriesResponseHints{}
+			if err := types.UnmarshalAny(h, &hints); err != nil {
+				return nil, errors.Wrapf(err, "failed to unmarshal series hints from %s", c.RemoteAddress())
+			}
 
-			return nil
-		})
+			ids, err := convertBlockHintsToULIDs(hints.QueriedBlocks)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse queried block IDs from received hints")
+			}
+
+			res.queriedBlocks = append(res.queriedBlocks, ids...)
+		}
 	}
 
-	// Wait until all client requests complete.
-	if err := g.Wait(); err != nil {
-		return nil, nil, nil, 0, err
+	if q.hedging != nil {
+		q.hedging.observe(time.Since(start))
 	}
 
-	return seriesSets, queriedBlocks, warnings, int(numChunks.Load()), nil
+	level.Debug(spanLog).Log("msg", "received series from store-gateway",
+		"instance", c.RemoteAddress(),
+		"num series", len(res.series),
+		"bytes series", countSeriesBytes(res.series),
+		"requested blocks", strings.Join(convertULIDsToString(blockIDs), " "),
+		"queried blocks", strings.Join(convertULIDsToString(res.queriedBlocks), " "))
+
+	return res, nil
 }
 
 func (q *blocksStoreQuerier) fetchLabelNamesFromStore(
@@ -685,6 +866,7 @@ func (q *blocksStoreQuerier) fetchLabelNamesFromStore(
 	clients map[BlocksStoreClient][]ulid.ULID,
 	minT int64,
 	maxT int64,
+	matchers ...*labels.Matcher,
 ) ([][]string, storage.Warnings, []ulid.ULID, error) {
 	var (
 		reqCtx        = grpc_metadata.AppendToOutgoingContext(ctx, cortex_tsdb.TenantIDExternalLabel, q.userID)
@@ -703,13 +885,17 @@ func (q *blocksStoreQuerier) fetchLabelNamesFromStore(
 		blockIDs := blockIDs
 
 		g.Go(func() error {
-			req, err := createLabelNamesRequest(minT, maxT, blockIDs)
+			req, err := createLabelNamesRequest(minT, maxT, blockIDs, matchers...)
 			if err != nil {
 				return errors.Wrapf(err, "failed to create label names request")
 			}
 
 			namesResp, err := c.LabelNames(gCtx, req)
 			if err != nil {
+				if q.isPartialResponseTolerated(ctx, err) {
+					level.Warn(spanLog).Log("msg", "failed to fetch label names from store-gateway, its blocks will be considered missing", "instance", c.RemoteAddress(), "err", err)
+					return nil
+				}
 				return errors.Wrapf(err, "failed to fetch series from %s", c.RemoteAddress())
 			}
 
@@ -787,6 +973,10 @@ func (q *blocksStoreQuerier) fetchLabelValuesFromStore(
 
 			valuesResp, err := c.LabelValues(gCtx, req)
 			if err != nil {
+				if q.isPartialResponseTolerated(ctx, err) {
+					level.Warn(spanLog).Log("msg", "failed to fetch label values from store-gateway, its blocks will be considered missing", "instance", c.RemoteAddress(), "err", err)
+					return nil
+				}
 				return errors.Wrapf(err, "failed to fetch series from %s", c.RemoteAddress())
 			}
 
@@ -862,10 +1052,11 @@ func createSeriesRequest(minT, maxT int64, matchers []storepb.LabelMatcher, skip
 	}, nil
 }
 
-func createLabelNamesRequest(minT, maxT int64, blockIDs []ulid.ULID) (*storepb.LabelNamesRequest, error) {
+func createLabelNamesRequest(minT, maxT int64, blockIDs []ulid.ULID, matchers ...*labels.Matcher) (*storepb.LabelNamesRequest, error) {
 	req := &storepb.LabelNamesRequest{
-		Start: minT,
-		End:   maxT,
+		Start:    minT,
+		End:      maxT,
+		Matchers: convertMatchersToLabelMatcher(matchers),
 	}
 
 	// Selectively query only specific blocks.
@@ -918,6 +1109,71 @@ func createLabelValuesRequest(minT, maxT int64, label string, blockIDs []ulid.UL
 	return req, nil
 }
 
+// filterBlocksByIDs returns the blocks whose ID is in the input list.
+func filterBlocksByIDs(blocks bucketindex.Blocks, ids []ulid.ULID) bucketindex.Blocks {
+	res := make(bucketindex.Blocks, 0, len(ids))
+	for _, b := range blocks {
+		for _, id := range ids {
+			if b.ID == id {
+				res = append(res, b)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// isPartialResponseTolerated returns whether an error received from a store-gateway can be tolerated
+// because partial responses are enabled for the tenant, in which case the blocks of the failed request
+// are considered missing. Limit errors, client errors and canceled queries are never tolerated.
+func (q *blocksStoreQuerier) isPartialResponseTolerated(ctx context.Context, err error) bool {
+	if !q.limits.QueryPartialResponseEnabled(q.userID) || ctx.Err() != nil {
+		return false
+	}
+
+	var limitErr validation.LimitError
+	if errors.As(err, &limitErr) {
+		return false
+	}
+
+	if resp, ok := httpgrpc.HTTPResponseFromError(errors.Cause(err)); ok && resp.Code/100 == 4 {
+		return false
+	}
+
+	return true
+}
+
+// newPartialResponseWarning returns the warning reporting the time ranges of the input blocks which
+// couldn't be queried. Overlapping and adjacent blocks are merged into a single time range.
+func newPartialResponseWarning(missing bucketindex.Blocks) error {
+	blocks := make(bucketindex.Blocks, len(missing))
+	copy(blocks, missing)
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].MinTime < blocks[j].MinTime })
+
+	var (
+		ranges   []string
+		rangeMin = blocks[0].MinTime
+		rangeMax = blocks[0].MaxTime
+	)
+
+	formatRange := func(minT, maxT int64) string {
+		return fmt.Sprintf("%s - %s", util.TimeFromMillis(minT).UTC().Format(time.RFC3339), util.TimeFromMillis(maxT).UTC().Format(time.RFC3339))
+	}
+
+	for _, b := range blocks[1:] {
+		if b.MinTime <= rangeMax {
+			rangeMax = math.Max64(rangeMax, b.MaxTime)
+			continue
+		}
+
+		ranges = append(ranges, formatRange(rangeMin, rangeMax))
+		rangeMin, rangeMax = b.MinTime, b.MaxTime
+	}
+	ranges = append(ranges, formatRange(rangeMin, rangeMax))
+
+	return fmt.Errorf("partial response: %d blocks couldn't be queried from any store-gateway, the result may be missing data in the time ranges: %s", len(blocks), strings.Join(ranges, ", "))
+}
+
 func convertULIDsToString(ids []ulid.ULID) []string {
 	res := make([]string, len(ids))
 	for idx, id := range ids {
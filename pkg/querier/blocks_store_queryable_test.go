@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"sort"
 	"strings"
 	"testing"
@@ -25,9 +26,12 @@ import (
 	"github.com/thanos-io/thanos/pkg/store/hintspb"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/user"
+	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 
+	"github.com/cortexproject/cortex/pkg/querier/series"
 	"github.com/cortexproject/cortex/pkg/storage/tsdb/bucketindex"
 	"github.com/cortexproject/cortex/pkg/storegateway/storegatewaypb"
 	"github.com/cortexproject/cortex/pkg/util"
@@ -274,6 +278,9 @@ func TestBlocksStoreQuerier_Select(t *testing.T) {
 				cortex_querier_storegateway_instances_hit_per_query_sum 3
 				cortex_querier_storegateway_instances_hit_per_query_count 1
 
+				# HELP cortex_querier_storegateway_partial_responses_total Total number of queries which returned a partial result because some blocks couldn't be queried from any store-gateway.
+				# TYPE cortex_querier_storegateway_partial_responses_total counter
+				cortex_querier_storegateway_partial_responses_total 0
 				# HELP cortex_querier_storegateway_refetches_per_query Number of re-fetches attempted while querying store-gateway instances due to missing blocks.
 				# TYPE cortex_querier_storegateway_refetches_per_query histogram
 				cortex_querier_storegateway_refetches_per_query_bucket{le="0"} 1
@@ -400,6 +407,9 @@ func TestBlocksStoreQuerier_Select(t *testing.T) {
 				cortex_querier_storegateway_instances_hit_per_query_sum 4
 				cortex_querier_storegateway_instances_hit_per_query_count 1
 
+				# HELP cortex_querier_storegateway_partial_responses_total Total number of queries which returned a partial result because some blocks couldn't be queried from any store-gateway.
+				# TYPE cortex_querier_storegateway_partial_responses_total counter
+				cortex_querier_storegateway_partial_responses_total 0
 				# HELP cortex_querier_storegateway_refetches_per_query Number of re-fetches attempted while querying store-gateway instances due to missing blocks.
 				# TYPE cortex_querier_storegateway_refetches_per_query histogram
 				cortex_querier_storegateway_refetches_per_query_bucket{le="0"} 0
@@ -425,7 +435,7 @@ func TestBlocksStoreQuerier_Select(t *testing.T) {
 				},
 			},
 			limits:       &blocksStoreLimitsMock{maxChunksPerQuery: 3},
-			queryLimiter: noOpQueryLimiter,
+			queryLimiter: limiter.NewQueryLimiter(0, 0),
 			expectedSeries: []seriesResult{
 				{
 					lbls: labels.New(metricNameLabel, series1Label),
@@ -489,7 +499,7 @@ func TestBlocksStoreQuerier_Select(t *testing.T) {
 				},
 			},
 			limits:       &blocksStoreLimitsMock{maxChunksPerQuery: 3},
-			queryLimiter: noOpQueryLimiter,
+			queryLimiter: limiter.NewQueryLimiter(0, 0),
 			expectedErr:  validation.LimitError(fmt.Sprintf(errMaxChunksPerQueryLimit, fmt.Sprintf("{__name__=%q}", metricName), 3)),
 		},
 		"max series per query limit hit while fetching chunks": {
@@ -820,6 +830,9 @@ func TestBlocksStoreQuerier_Labels(t *testing.T) {
 				cortex_querier_storegateway_instances_hit_per_query_sum 3
 				cortex_querier_storegateway_instances_hit_per_query_count 1
 
+				# HELP cortex_querier_storegateway_partial_responses_total Total number of queries which returned a partial result because some blocks couldn't be queried from any store-gateway.
+				# TYPE cortex_querier_storegateway_partial_responses_total counter
+				cortex_querier_storegateway_partial_responses_total 0
 				# HELP cortex_querier_storegateway_refetches_per_query Number of re-fetches attempted while querying store-gateway instances due to missing blocks.
 				# TYPE cortex_querier_storegateway_refetches_per_query histogram
 				cortex_querier_storegateway_refetches_per_query_bucket{le="0"} 1
@@ -993,6 +1006,9 @@ func TestBlocksStoreQuerier_Labels(t *testing.T) {
 				cortex_querier_storegateway_instances_hit_per_query_sum 4
 				cortex_querier_storegateway_instances_hit_per_query_count 1
 
+				# HELP cortex_querier_storegateway_partial_responses_total Total number of queries which returned a partial result because some blocks couldn't be queried from any store-gateway.
+				# TYPE cortex_querier_storegateway_partial_responses_total counter
+				cortex_querier_storegateway_partial_responses_total 0
 				# HELP cortex_querier_storegateway_refetches_per_query Number of re-fetches attempted while querying store-gateway instances due to missing blocks.
 				# TYPE cortex_querier_storegateway_refetches_per_query histogram
 				cortex_querier_storegateway_refetches_per_query_bucket{le="0"} 0
@@ -1067,6 +1083,214 @@ func TestBlocksStoreQuerier_Labels(t *testing.T) {
 	}
 }
 
+func TestBlocksStoreQuerier_LabelNamesWithMatchers_ShouldPushDownMatchers(t *testing.T) {
+	const (
+		minT = int64(10)
+		maxT = int64(20)
+	)
+
+	var (
+		block1   = ulid.MustNew(1, nil)
+		series1  = labels.FromStrings(labels.MetricName, "metric_1", "status", "200")
+		matchers = []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "metric_1")}
+	)
+
+	client := &labelNamesRecorderClientMock{storeGatewayClientMock: storeGatewayClientMock{
+		remoteAddr: "1.1.1.1",
+		mockedLabelNamesResponse: &storepb.LabelNamesResponse{
+			Names: namesFromSeries(series1),
+			Hints: mockNamesHints(block1),
+		},
+	}}
+
+	finder := &blocksFinderMock{}
+	finder.On("GetBlocks", mock.Anything, "user-1", minT, maxT).Return(bucketindex.Blocks{{ID: block1}}, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), nil)
+
+	q := &blocksStoreQuerier{
+		ctx:         context.Background(),
+		minT:        minT,
+		maxT:        maxT,
+		userID:      "user-1",
+		finder:      finder,
+		stores:      &blocksStoreSetMock{mockedResponses: []interface{}{map[BlocksStoreClient][]ulid.ULID{client: {block1}}}},
+		consistency: NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+		logger:      log.NewNopLogger(),
+		metrics:     newBlocksStoreQueryableMetrics(nil),
+		limits:      &blocksStoreLimitsMock{},
+	}
+
+	names, warnings, err := series.LabelNamesWithMatchers(q, nil, matchers...)
+	require.NoError(t, err)
+	assert.Len(t, warnings, 0)
+	assert.Equal(t, namesFromSeries(series1), names)
+
+	require.NotNil(t, client.lastLabelNamesRequest)
+	assert.Equal(t, []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: labels.MetricName, Value: "metric_1"}}, client.lastLabelNamesRequest.Matchers)
+}
+
+func TestBlocksStoreQuerier_Select_PartialResponse(t *testing.T) {
+	const (
+		metricName = "test_metric"
+		minT       = int64(10)
+		maxT       = int64(20)
+	)
+
+	var (
+		block1          = ulid.MustNew(1, nil)
+		block2          = ulid.MustNew(2, nil)
+		metricNameLabel = labels.Label{Name: labels.MetricName, Value: metricName}
+		series1         = labels.Labels{metricNameLabel, {Name: "series", Value: "1"}}
+		noReplicaErr    = errors.New("no store-gateway instance left after checking exclude for block")
+		knownBlocks     = bucketindex.Blocks{
+			{ID: block1, MinTime: 0, MaxTime: 2 * time.Hour.Milliseconds()},
+			{ID: block2, MinTime: 2 * time.Hour.Milliseconds(), MaxTime: 4 * time.Hour.Milliseconds()},
+		}
+	)
+
+	// The store-gateway only returns the series of the first block.
+	newClient := func() BlocksStoreClient {
+		return &storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedSeriesResponses: []*storepb.SeriesResponse{
+			mockSeriesResponse(series1, minT, 1),
+			mockHintsResponse(block1),
+		}}
+	}
+
+	tests := map[string]struct {
+		partialResponseEnabled   bool
+		storeSetResponses        []interface{}
+		expectedErr              string
+		expectedWarnings         []string
+		expectedPartialResponses int
+	}{
+		"should fail the query if a block is missing and partial responses are disabled": {
+			partialResponseEnabled: false,
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{newClient(): {block1, block2}},
+				noReplicaErr,
+			},
+			expectedErr: fmt.Sprintf("consistency check failed because some blocks were not queried: %s", block2.String()),
+		},
+		"should return a partial response if a block is missing and partial responses are enabled": {
+			partialResponseEnabled: true,
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{newClient(): {block1, block2}},
+				// The retry can't find any store-gateway for the missing block.
+				noReplicaErr,
+				noReplicaErr,
+			},
+			expectedWarnings:         []string{"partial response: 1 blocks couldn't be queried from any store-gateway, the result may be missing data in the time ranges: 1970-01-01T02:00:00Z - 1970-01-01T04:00:00Z"},
+			expectedPartialResponses: 1,
+		},
+		"should query the available blocks if no store-gateway holds a block and partial responses are enabled": {
+			partialResponseEnabled: true,
+			storeSetResponses: []interface{}{
+				// The first attempt fails because block2 has no store-gateway, so each block is looked up on its own.
+				noReplicaErr,
+				map[BlocksStoreClient][]ulid.ULID{newClient(): {block1}},
+				noReplicaErr,
+				// The retry can't find any store-gateway for the missing block.
+				noReplicaErr,
+				noReplicaErr,
+			},
+			expectedWarnings:         []string{"partial response: 1 blocks couldn't be queried from any store-gateway, the result may be missing data in the time ranges: 1970-01-01T02:00:00Z - 1970-01-01T04:00:00Z"},
+			expectedPartialResponses: 1,
+		},
+		"should return a partial response if a store-gateway fails and partial responses are enabled": {
+			partialResponseEnabled: true,
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					newClient(): {block1},
+					&failingStoreGatewayClientMock{remoteAddr: "2.2.2.2", err: errors.New("unavailable")}: {block2},
+				},
+				// The retry can't find any other store-gateway for the missing block.
+				noReplicaErr,
+				noReplicaErr,
+			},
+			expectedWarnings:         []string{"partial response: 1 blocks couldn't be queried from any store-gateway, the result may be missing data in the time ranges: 1970-01-01T02:00:00Z - 1970-01-01T04:00:00Z"},
+			expectedPartialResponses: 1,
+		},
+		"should fail the query if a store-gateway fails and partial responses are disabled": {
+			partialResponseEnabled: false,
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					newClient(): {block1},
+					&failingStoreGatewayClientMock{remoteAddr: "2.2.2.2", err: errors.New("unavailable")}: {block2},
+				},
+			},
+			expectedErr: "failed to fetch series from 2.2.2.2: unavailable",
+		},
+		"should fail the query if a store-gateway returns a limit error and partial responses are enabled": {
+			partialResponseEnabled: true,
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					newClient(): {block1},
+					&failingStoreGatewayClientMock{remoteAddr: "2.2.2.2", err: httpgrpc.Errorf(http.StatusUnprocessableEntity, "limit exceeded")}: {block2},
+				},
+			},
+			expectedErr: "failed to fetch series from 2.2.2.2: rpc error: code = Code(422) desc = limit exceeded",
+		},
+		"should fail the query if no store-gateway holds a block and partial responses are disabled": {
+			partialResponseEnabled: false,
+			storeSetResponses: []interface{}{
+				noReplicaErr,
+			},
+			expectedErr: noReplicaErr.Error(),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			reg := prometheus.NewPedanticRegistry()
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", minT, maxT).Return(knownBlocks, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), nil)
+
+			q := &blocksStoreQuerier{
+				ctx:         context.Background(),
+				minT:        minT,
+				maxT:        maxT,
+				userID:      "user-1",
+				finder:      finder,
+				stores:      &blocksStoreSetMock{mockedResponses: testData.storeSetResponses},
+				consistency: NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+				logger:      log.NewNopLogger(),
+				metrics:     newBlocksStoreQueryableMetrics(reg),
+				limits:      &blocksStoreLimitsMock{partialResponseEnabled: testData.partialResponseEnabled},
+			}
+
+			set := q.Select(true, nil, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, metricName))
+			if testData.expectedErr != "" {
+				require.Error(t, set.Err())
+				assert.Equal(t, testData.expectedErr, set.Err().Error())
+				return
+			}
+
+			require.True(t, set.Next())
+			assert.Equal(t, series1, set.At().Labels())
+			assert.False(t, set.Next())
+			require.NoError(t, set.Err())
+
+			var warnings []string
+			for _, w := range set.Warnings() {
+				warnings = append(warnings, w.Error())
+			}
+			assert.Equal(t, testData.expectedWarnings, warnings)
+			assert.Equal(t, float64(testData.expectedPartialResponses), testutil.ToFloat64(q.metrics.partialResponses))
+		})
+	}
+}
+
+func TestNewPartialResponseWarning(t *testing.T) {
+	hour := time.Hour.Milliseconds()
+
+	warning := newPartialResponseWarning(bucketindex.Blocks{
+		{ID: ulid.MustNew(3, nil), MinTime: 6 * hour, MaxTime: 8 * hour},
+		{ID: ulid.MustNew(1, nil), MinTime: 0, MaxTime: 2 * hour},
+		{ID: ulid.MustNew(2, nil), MinTime: 1 * hour, MaxTime: 4 * hour},
+	})
+
+	assert.Equal(t, "partial response: 3 blocks couldn't be queried from any store-gateway, the result may be missing data in the time ranges: 1970-01-01T00:00:00Z - 1970-01-01T04:00:00Z, 1970-01-01T06:00:00Z - 1970-01-01T08:00:00Z", warning.Error())
+}
+
 func TestBlocksStoreQuerier_SelectSortedShouldHonorQueryStoreAfter(t *testing.T) {
 	now := time.Now()
 
@@ -1267,6 +1491,93 @@ func TestBlocksStoreQuerier_PromQLExecution(t *testing.T) {
 	assert.Equal(t, series2Samples, matrix[1].Points)
 }
 
+func TestBlocksStoreQuerier_Select_ShouldEnforceChunksLimitsWhileStreaming(t *testing.T) {
+	const (
+		metricName = "test_metric"
+		minT       = int64(10)
+		maxT       = int64(20)
+	)
+
+	var (
+		block1          = ulid.MustNew(1, nil)
+		block2          = ulid.MustNew(2, nil)
+		metricNameLabel = labels.Label{Name: labels.MetricName, Value: metricName}
+		series1Label    = labels.Label{Name: "series", Value: "1"}
+		series2Label    = labels.Label{Name: "series", Value: "2"}
+		chunkSize       = mockSeriesResponse(labels.Labels{metricNameLabel, series1Label}, minT, 1).GetSeries().Chunks[0].Size()
+	)
+
+	// Each store-gateway returns 2 chunks and then doesn't complete the request until the context is
+	// canceled, so the query can only fail if the limits are enforced while the series are streamed.
+	newClient := func(addr string, blockID ulid.ULID) *blockingStoreGatewayClientMock {
+		return &blockingStoreGatewayClientMock{
+			storeGatewayClientMock: storeGatewayClientMock{remoteAddr: addr, mockedSeriesResponses: []*storepb.SeriesResponse{
+				mockSeriesResponse(labels.Labels{metricNameLabel, series1Label}, minT, 1),
+				mockSeriesResponse(labels.Labels{metricNameLabel, series2Label}, minT+1, 2),
+				mockHintsResponse(blockID),
+			}},
+			timeout: 10 * time.Second,
+		}
+	}
+
+	tests := map[string]struct {
+		limits       BlocksStoreLimits
+		queryLimiter *limiter.QueryLimiter
+		expectedErr  error
+	}{
+		"should fail the query once the max chunks per query limit is reached": {
+			limits:       &blocksStoreLimitsMock{maxChunksPerQuery: 3},
+			queryLimiter: limiter.NewQueryLimiter(0, 0),
+			expectedErr:  validation.LimitError(fmt.Sprintf(errMaxChunksPerQueryLimit, fmt.Sprintf("{__name__=%q}", metricName), 3)),
+		},
+		"should fail the query once the max chunk bytes per query limit is reached": {
+			limits:       &blocksStoreLimitsMock{},
+			queryLimiter: limiter.NewQueryLimiter(0, 3*chunkSize),
+			expectedErr:  validation.LimitError(fmt.Sprintf(limiter.ErrMaxChunkBytesHit, 3*chunkSize)),
+		},
+	}
+
+	for testName, testData := range tests {
+		for _, hedgingEnabled := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s (hedging enabled: %t)", testName, hedgingEnabled), func(t *testing.T) {
+				client1 := newClient("1.1.1.1", block1)
+				client2 := newClient("2.2.2.2", block2)
+
+				reg := prometheus.NewPedanticRegistry()
+				stores := &blocksStoreSetMock{mockedResponses: []interface{}{
+					map[BlocksStoreClient][]ulid.ULID{client1: {block1}, client2: {block2}},
+				}}
+				finder := &blocksFinderMock{}
+				finder.On("GetBlocks", mock.Anything, "user-1", minT, maxT).Return(bucketindex.Blocks{{ID: block1}, {ID: block2}}, map[ulid.ULID]*bucketindex.BlockDeletionMark(nil), nil)
+
+				q := &blocksStoreQuerier{
+					ctx:         limiter.AddQueryLimiterToContext(context.Background(), testData.queryLimiter),
+					minT:        minT,
+					maxT:        maxT,
+					userID:      "user-1",
+					finder:      finder,
+					stores:      stores,
+					consistency: NewBlocksConsistencyChecker(0, 0, log.NewNopLogger(), nil),
+					logger:      log.NewNopLogger(),
+					metrics:     newBlocksStoreQueryableMetrics(reg),
+					limits:      testData.limits,
+				}
+				if hedgingEnabled {
+					q.hedging = newStoreGatewayHedging(StoreGatewayHedgingConfig{Enabled: true, LatencyPercentile: 90, MinDelay: time.Second, MaxPerQuery: 1}, reg)
+					q.hedgingBudget = q.hedging.newBudget()
+				}
+
+				set := q.Select(true, nil, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, metricName))
+				assert.Equal(t, testData.expectedErr, set.Err())
+
+				// No request should have completed.
+				assert.False(t, client1.completed.Load())
+				assert.False(t, client2.completed.Load())
+			})
+		}
+	}
+}
+
 type blocksStoreSetMock struct {
 	services.Service
 
@@ -1274,7 +1585,7 @@ type blocksStoreSetMock struct {
 	nextResult      int
 }
 
-func (m *blocksStoreSetMock) GetClientsFor(_ string, _ []ulid.ULID, _ map[ulid.ULID][]string) (map[BlocksStoreClient][]ulid.ULID, error) {
+func (m *blocksStoreSetMock) GetClientsFor(_ string, _ bucketindex.Blocks, _ map[ulid.ULID][]string) (map[BlocksStoreClient][]ulid.ULID, error) {
 	if m.nextResult >= len(m.mockedResponses) {
 		panic("not enough mocked results")
 	}
@@ -1307,6 +1618,7 @@ type storeGatewayClientMock struct {
 	mockedSeriesResponses     []*storepb.SeriesResponse
 	mockedLabelNamesResponse  *storepb.LabelNamesResponse
 	mockedLabelValuesResponse *storepb.LabelValuesResponse
+	mockedExemplarsResponse   *storegatewaypb.ExemplarsResponse
 }
 
 func (m *storeGatewayClientMock) Series(ctx context.Context, in *storepb.SeriesRequest, opts ...grpc.CallOption) (storegatewaypb.StoreGateway_SeriesClient, error) {
@@ -1325,10 +1637,51 @@ func (m *storeGatewayClientMock) LabelValues(context.Context, *storepb.LabelValu
 	return m.mockedLabelValuesResponse, nil
 }
 
+func (m *storeGatewayClientMock) Exemplars(context.Context, *storegatewaypb.ExemplarsRequest, ...grpc.CallOption) (*storegatewaypb.ExemplarsResponse, error) {
+	return m.mockedExemplarsResponse, nil
+}
+
 func (m *storeGatewayClientMock) RemoteAddress() string {
 	return m.remoteAddr
 }
 
+// labelNamesRecorderClientMock is a storeGatewayClientMock which records the last label names request received.
+type labelNamesRecorderClientMock struct {
+	storeGatewayClientMock
+
+	lastLabelNamesRequest *storepb.LabelNamesRequest
+}
+
+func (m *labelNamesRecorderClientMock) LabelNames(ctx context.Context, req *storepb.LabelNamesRequest, opts ...grpc.CallOption) (*storepb.LabelNamesResponse, error) {
+	m.lastLabelNamesRequest = req
+	return m.storeGatewayClientMock.LabelNames(ctx, req, opts...)
+}
+
+type failingStoreGatewayClientMock struct {
+	remoteAddr string
+	err        error
+}
+
+func (m *failingStoreGatewayClientMock) Series(context.Context, *storepb.SeriesRequest, ...grpc.CallOption) (storegatewaypb.StoreGateway_SeriesClient, error) {
+	return nil, m.err
+}
+
+func (m *failingStoreGatewayClientMock) LabelNames(context.Context, *storepb.LabelNamesRequest, ...grpc.CallOption) (*storepb.LabelNamesResponse, error) {
+	return nil, m.err
+}
+
+func (m *failingStoreGatewayClientMock) LabelValues(context.Context, *storepb.LabelValuesRequest, ...grpc.CallOption) (*storepb.LabelValuesResponse, error) {
+	return nil, m.err
+}
+
+func (m *failingStoreGatewayClientMock) Exemplars(context.Context, *storegatewaypb.ExemplarsRequest, ...grpc.CallOption) (*storegatewaypb.ExemplarsResponse, error) {
+	return nil, m.err
+}
+
+func (m *failingStoreGatewayClientMock) RemoteAddress() string {
+	return m.remoteAddr
+}
+
 type storeGatewaySeriesClientMock struct {
 	grpc.ClientStream
 
@@ -1348,9 +1701,51 @@ func (m *storeGatewaySeriesClientMock) Recv() (*storepb.SeriesResponse, error) {
 	return res, nil
 }
 
+// blockingStoreGatewayClientMock is a storeGatewayClientMock whose series requests, once all the
+// mocked responses have been received, don't complete until the context is canceled or the timeout expires.
+type blockingStoreGatewayClientMock struct {
+	storeGatewayClientMock
+
+	timeout   time.Duration
+	completed atomic.Bool
+}
+
+func (m *blockingStoreGatewayClientMock) Series(ctx context.Context, in *storepb.SeriesRequest, opts ...grpc.CallOption) (storegatewaypb.StoreGateway_SeriesClient, error) {
+	return &blockingStoreGatewaySeriesClientMock{
+		storeGatewaySeriesClientMock: storeGatewaySeriesClientMock{mockedResponses: m.mockedSeriesResponses},
+		ctx:                          ctx,
+		timeout:                      m.timeout,
+		completed:                    &m.completed,
+	}, nil
+}
+
+type blockingStoreGatewaySeriesClientMock struct {
+	storeGatewaySeriesClientMock
+
+	ctx       context.Context
+	timeout   time.Duration
+	completed *atomic.Bool
+}
+
+func (m *blockingStoreGatewaySeriesClientMock) Recv() (*storepb.SeriesResponse, error) {
+	if len(m.mockedResponses) > 0 {
+		return m.storeGatewaySeriesClientMock.Recv()
+	}
+
+	select {
+	case <-m.ctx.Done():
+		return nil, m.ctx.Err()
+	case <-time.After(m.timeout):
+		m.completed.Store(true)
+		return nil, io.EOF
+	}
+}
+
 type blocksStoreLimitsMock struct {
 	maxChunksPerQuery           int
 	storeGatewayTenantShardSize int
+	partialResponseEnabled      bool
+	maxFetchedExemplarsPerQuery int
 }
 
 func (m *blocksStoreLimitsMock) MaxChunksPerQueryFromStore(_ string) int {
@@ -1361,6 +1756,14 @@ func (m *blocksStoreLimitsMock) StoreGatewayTenantShardSize(_ string) int {
 	return m.storeGatewayTenantShardSize
 }
 
+func (m *blocksStoreLimitsMock) QueryPartialResponseEnabled(_ string) bool {
+	return m.partialResponseEnabled
+}
+
+func (m *blocksStoreLimitsMock) MaxFetchedExemplarsPerQuery(_ string) int {
+	return m.maxFetchedExemplarsPerQuery
+}
+
 func (m *blocksStoreLimitsMock) S3SSEType(_ string) string {
 	return ""
 }
@@ -17,6 +17,7 @@ import (
 	"github.com/cortexproject/cortex/pkg/ring"
 	"github.com/cortexproject/cortex/pkg/ring/kv/consul"
 	cortex_tsdb "github.com/cortexproject/cortex/pkg/storage/tsdb"
+	"github.com/cortexproject/cortex/pkg/storage/tsdb/bucketindex"
 	"github.com/cortexproject/cortex/pkg/util"
 	"github.com/cortexproject/cortex/pkg/util/flagext"
 	"github.com/cortexproject/cortex/pkg/util/services"
@@ -358,7 +359,7 @@ func TestBlocksStoreReplicationSet_GetClientsFor(t *testing.T) {
 				return err == nil && len(all.Instances) > 0
 			})
 
-			clients, err := s.GetClientsFor(userID, testData.queryBlocks, testData.exclude)
+			clients, err := s.GetClientsFor(userID, convertULIDsToBlocks(testData.queryBlocks), testData.exclude)
 			assert.Equal(t, testData.expectedErr, err)
 
 			if testData.expectedErr == nil {
@@ -421,7 +422,7 @@ func TestBlocksStoreReplicationSet_GetClientsFor_ShouldSupportRandomLoadBalancin
 	distribution := map[string]int{}
 
 	for n := 0; n < numRuns; n++ {
-		clients, err := s.GetClientsFor(userID, []ulid.ULID{block1}, nil)
+		clients, err := s.GetClientsFor(userID, convertULIDsToBlocks([]ulid.ULID{block1}), nil)
 		require.NoError(t, err)
 		require.Len(t, clients, 1)
 
@@ -438,6 +439,78 @@ func TestBlocksStoreReplicationSet_GetClientsFor_ShouldSupportRandomLoadBalancin
 	}
 }
 
+func TestBlocksStoreReplicationSet_GetClientsFor_ShouldHonorDataAgeRange(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-A"
+	now := time.Now()
+	registeredAt := now
+
+	// Blocks have been picked in order to have the hash of each block owned by a different
+	// instance, if all instances would serve any time range.
+	recentBlock := &bucketindex.Block{ID: ulid.MustNew(1, nil), MinTime: util.TimeToMillis(now.Add(-4 * time.Hour)), MaxTime: util.TimeToMillis(now.Add(-2 * time.Hour))}
+	oldBlock := &bucketindex.Block{ID: ulid.MustNew(2, nil), MinTime: util.TimeToMillis(now.Add(-30 * 24 * time.Hour)), MaxTime: util.TimeToMillis(now.Add(-29 * 24 * time.Hour))}
+	boundaryBlock := &bucketindex.Block{ID: ulid.MustNew(5, nil), MinTime: util.TimeToMillis(now.Add(-15 * 24 * time.Hour)), MaxTime: util.TimeToMillis(now.Add(-13 * 24 * time.Hour))}
+
+	// Create a ring with a hot and a cold store-gateway.
+	ringStore := consul.NewInMemoryClient(ring.GetCodec())
+	require.NoError(t, ringStore.CAS(ctx, "test", func(in interface{}) (interface{}, bool, error) {
+		d := ring.NewDesc()
+		d.AddIngester("instance-hot", "127.0.0.1", "", []uint32{cortex_tsdb.HashBlockID(oldBlock.ID) + 1}, ring.ACTIVE, registeredAt)
+		d.AddIngester("instance-cold", "127.0.0.2", "", []uint32{cortex_tsdb.HashBlockID(recentBlock.ID) + 1}, ring.ACTIVE, registeredAt)
+
+		hot := d.Ingesters["instance-hot"]
+		hot.MaxDataAgeSeconds = int64((14 * 24 * time.Hour).Seconds())
+		d.Ingesters["instance-hot"] = hot
+
+		cold := d.Ingesters["instance-cold"]
+		cold.MinDataAgeSeconds = int64((14 * 24 * time.Hour).Seconds())
+		d.Ingesters["instance-cold"] = cold
+
+		return d, true, nil
+	}))
+
+	ringCfg := ring.Config{}
+	flagext.DefaultValues(&ringCfg)
+	ringCfg.ReplicationFactor = 1
+
+	r, err := ring.NewWithStoreClientAndStrategy(ringCfg, "test", "test", ringStore, ring.NewIgnoreUnhealthyInstancesReplicationStrategy())
+	require.NoError(t, err)
+
+	s, err := newBlocksStoreReplicationSet(r, util.ShardingStrategyDefault, noLoadBalancing, &blocksStoreLimitsMock{}, ClientConfig{}, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, s))
+	defer services.StopAndAwaitTerminated(ctx, s) //nolint:errcheck
+
+	// Wait until the ring client has initialised the state.
+	test.Poll(t, time.Second, true, func() interface{} {
+		all, err := r.GetAllHealthy(ring.Read)
+		return err == nil && len(all.Instances) > 0
+	})
+
+	clients, err := s.GetClientsFor(userID, bucketindex.Blocks{recentBlock, oldBlock}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]ulid.ULID{
+		"127.0.0.1": {recentBlock.ID},
+		"127.0.0.2": {oldBlock.ID},
+	}, getStoreGatewayClientAddrs(clients))
+
+	// A block overlapping both tiers can be queried from either of them, so the ring
+	// sharding applies as usual (the block hash wraps around to the cold instance token).
+	clients, err = s.GetClientsFor(userID, bucketindex.Blocks{boundaryBlock}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]ulid.ULID{
+		"127.0.0.2": {boundaryBlock.ID},
+	}, getStoreGatewayClientAddrs(clients))
+}
+
+func convertULIDsToBlocks(ids []ulid.ULID) bucketindex.Blocks {
+	blocks := make(bucketindex.Blocks, 0, len(ids))
+	for _, id := range ids {
+		blocks = append(blocks, &bucketindex.Block{ID: id})
+	}
+	return blocks
+}
+
 func getStoreGatewayClientAddrs(clients map[BlocksStoreClient][]ulid.ULID) map[string][]ulid.ULID {
 	addrs := map[string][]ulid.ULID{}
 	for c, blockIDs := range clients {
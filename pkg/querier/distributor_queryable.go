@@ -30,7 +30,7 @@ type Distributor interface {
 	QueryStream(ctx context.Context, from, to model.Time, matchers ...*labels.Matcher) (*client.QueryStreamResponse, error)
 	QueryExemplars(ctx context.Context, from, to model.Time, matchers ...[]*labels.Matcher) (*client.ExemplarQueryResponse, error)
 	LabelValuesForLabelName(ctx context.Context, from, to model.Time, label model.LabelName, matchers ...*labels.Matcher) ([]string, error)
-	LabelNames(context.Context, model.Time, model.Time) ([]string, error)
+	LabelNames(ctx context.Context, from, to model.Time, matchers ...*labels.Matcher) ([]string, error)
 	MetricsForLabelMatchers(ctx context.Context, from, through model.Time, matchers ...*labels.Matcher) ([]metric.Metric, error)
 	MetricsMetadata(ctx context.Context) ([]scrape.MetricMetadata, error)
 }
@@ -197,6 +197,12 @@ func (q *distributorQuerier) LabelNames() ([]string, storage.Warnings, error) {
 	return ln, nil, err
 }
 
+// LabelNamesWithMatchers implements series.LabelNamesWithMatchersQuerier.
+func (q *distributorQuerier) LabelNamesWithMatchers(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	ln, err := q.distributor.LabelNames(q.ctx, model.Time(q.mint), model.Time(q.maxt), matchers...)
+	return ln, nil, err
+}
+
 func (q *distributorQuerier) Close() error {
 	return nil
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/prometheus/prometheus/storage"
 
 	"github.com/cortexproject/cortex/pkg/chunk"
+	"github.com/cortexproject/cortex/pkg/querier/series"
 	"github.com/cortexproject/cortex/pkg/util/validation"
 )
 
@@ -101,6 +102,11 @@ func (e errorTranslateQuerier) LabelNames() ([]string, storage.Warnings, error)
 	return values, warnings, TranslateToPromqlAPIError(err)
 }
 
+func (e errorTranslateQuerier) LabelNamesWithMatchers(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	values, warnings, err := series.LabelNamesWithMatchers(e.q, nil, matchers...)
+	return values, warnings, TranslateToPromqlAPIError(err)
+}
+
 func (e errorTranslateQuerier) Close() error {
 	return TranslateToPromqlAPIError(e.q.Close())
 }
@@ -0,0 +1,158 @@
+package querier
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/prometheus/pkg/exemplar"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+	cortex_tsdb "github.com/cortexproject/cortex/pkg/storage/tsdb"
+	"github.com/cortexproject/cortex/pkg/tenant"
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+// exemplarStore is a store supporting exemplar queries. The store is queried only
+// if the filter queryable is used for the query time range.
+type exemplarStore struct {
+	queryable storage.ExemplarQueryable
+	filter    QueryableWithFilter
+}
+
+// newMergeExemplarQueryable returns an exemplar queryable merging the exemplars returned by the
+// ingesters with the exemplars persisted in the long-term storage, for the stores supporting it.
+func newMergeExemplarQueryable(distributor storage.ExemplarQueryable, stores []QueryableWithFilter, limits *validation.Overrides) storage.ExemplarQueryable {
+	var exemplarStores []exemplarStore
+	for _, s := range stores {
+		if q, ok := storeExemplarQueryable(s); ok {
+			exemplarStores = append(exemplarStores, exemplarStore{queryable: q, filter: s})
+		}
+	}
+
+	return &mergeExemplarQueryable{
+		distributor: distributor,
+		stores:      exemplarStores,
+		limits:      limits,
+	}
+}
+
+// storeExemplarQueryable returns the exemplar queryable of the input store,
+// if the store (wrapped by the filtering queryables) supports exemplar queries.
+func storeExemplarQueryable(s QueryableWithFilter) (storage.ExemplarQueryable, bool) {
+	var q interface{} = s
+
+	switch w := s.(type) {
+	case storeQueryable:
+		return storeExemplarQueryable(w.QueryableWithFilter)
+	case alwaysTrueFilterQueryable:
+		q = w.Queryable
+	case useBeforeTimestampQueryable:
+		q = w.Queryable
+	}
+
+	eq, ok := q.(storage.ExemplarQueryable)
+	return eq, ok
+}
+
+type mergeExemplarQueryable struct {
+	distributor storage.ExemplarQueryable
+	stores      []exemplarStore
+	limits      *validation.Overrides
+}
+
+func (m *mergeExemplarQueryable) ExemplarQuerier(ctx context.Context) (storage.ExemplarQuerier, error) {
+	return &mergeExemplarQuerier{ctx: ctx, queryable: m}, nil
+}
+
+type mergeExemplarQuerier struct {
+	ctx       context.Context
+	queryable *mergeExemplarQueryable
+}
+
+// Select implements storage.ExemplarQuerier.
+func (q *mergeExemplarQuerier) Select(start, end int64, matchers ...[]*labels.Matcher) ([]exemplar.QueryResult, error) {
+	userID, err := tenant.TenantID(q.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queryables := []storage.ExemplarQueryable{q.queryable.distributor}
+	now := time.Now()
+	for _, s := range q.queryable.stores {
+		if s.filter.UseQueryable(now, start, end) {
+			queryables = append(queryables, s.queryable)
+		}
+	}
+
+	// Concurrently query the ingesters and the stores.
+	var (
+		g, gCtx = errgroup.WithContext(q.ctx)
+		results = make([][]exemplar.QueryResult, len(queryables))
+	)
+
+	for ix, queryable := range queryables {
+		ix, queryable := ix, queryable
+
+		g.Go(func() error {
+			querier, err := queryable.ExemplarQuerier(gCtx)
+			if err != nil {
+				return err
+			}
+
+			results[ix], err = querier.Select(start, end, matchers...)
+			return err
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	merged := results[0]
+	if len(results) > 1 {
+		sets := make([][]cortexpb.TimeSeries, 0, len(results))
+		for _, res := range results {
+			sets = append(sets, queryResultsToExemplarSeries(res))
+		}
+		merged = exemplarSeriesToQueryResults(cortex_tsdb.MergeExemplarSeries(sets...))
+	}
+
+	if maxExemplars := q.queryable.limits.MaxFetchedExemplarsPerQuery(userID); maxExemplars > 0 {
+		count := 0
+		for _, res := range merged {
+			count += len(res.Exemplars)
+		}
+
+		if count > maxExemplars {
+			return nil, validation.LimitError(fmt.Sprintf(errMaxFetchedExemplarsPerQueryLimit, maxExemplars))
+		}
+	}
+
+	return merged, nil
+}
+
+func queryResultsToExemplarSeries(results []exemplar.QueryResult) []cortexpb.TimeSeries {
+	series := make([]cortexpb.TimeSeries, 0, len(results))
+	for _, r := range results {
+		series = append(series, cortexpb.TimeSeries{
+			Labels:    cortexpb.FromLabelsToLabelAdapters(r.SeriesLabels),
+			Exemplars: cortexpb.FromExemplarsToExemplarProtos(r.Exemplars),
+		})
+	}
+	return series
+}
+
+func exemplarSeriesToQueryResults(series []cortexpb.TimeSeries) []exemplar.QueryResult {
+	results := make([]exemplar.QueryResult, 0, len(series))
+	for _, s := range series {
+		results = append(results, exemplar.QueryResult{
+			SeriesLabels: cortexpb.FromLabelAdaptersToLabels(s.Labels),
+			Exemplars:    cortexpb.FromExemplarProtosToExemplars(s.Exemplars),
+		})
+	}
+	return results
+}
@@ -0,0 +1,106 @@
+package querier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/pkg/exemplar"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+func TestMergeExemplarQueryable(t *testing.T) {
+	var (
+		series1 = labels.FromStrings(labels.MetricName, "metric_1")
+		series2 = labels.FromStrings(labels.MetricName, "metric_2")
+		trace1  = labels.FromStrings("traceID", "1")
+		trace2  = labels.FromStrings("traceID", "2")
+	)
+
+	ingesterResults := []exemplar.QueryResult{
+		{SeriesLabels: series1, Exemplars: []exemplar.Exemplar{{Labels: trace2, Value: 2, Ts: 20}}},
+	}
+	storeResults := []exemplar.QueryResult{
+		{SeriesLabels: series1, Exemplars: []exemplar.Exemplar{{Labels: trace1, Value: 1, Ts: 10}, {Labels: trace2, Value: 2, Ts: 20}}},
+		{SeriesLabels: series2, Exemplars: []exemplar.Exemplar{{Labels: trace1, Value: 3, Ts: 15}}},
+	}
+
+	tests := map[string]struct {
+		stores          []QueryableWithFilter
+		maxExemplars    int
+		expectedResults []exemplar.QueryResult
+		expectedErr     error
+	}{
+		"no store supporting exemplars": {
+			stores: []QueryableWithFilter{UseAlwaysQueryable(storage.QueryableFunc(func(_ context.Context, _, _ int64) (storage.Querier, error) {
+				return storage.NoopQuerier(), nil
+			}))},
+			expectedResults: ingesterResults,
+		},
+		"store not used for the query time range": {
+			stores:          []QueryableWithFilter{UseBeforeTimestampQueryable(&exemplarQueryableMock{results: storeResults}, time.Unix(1, 0))},
+			expectedResults: ingesterResults,
+		},
+		"exemplars from ingesters and store are merged": {
+			stores: []QueryableWithFilter{UseAlwaysQueryable(&exemplarQueryableMock{results: storeResults})},
+			expectedResults: []exemplar.QueryResult{
+				{SeriesLabels: series1, Exemplars: []exemplar.Exemplar{{Labels: trace1, Value: 1, Ts: 10}, {Labels: trace2, Value: 2, Ts: 20}}},
+				{SeriesLabels: series2, Exemplars: []exemplar.Exemplar{{Labels: trace1, Value: 3, Ts: 15}}},
+			},
+		},
+		"max fetched exemplars per query limit hit": {
+			stores:       []QueryableWithFilter{UseAlwaysQueryable(&exemplarQueryableMock{results: storeResults})},
+			maxExemplars: 2,
+			expectedErr:  validation.LimitError(fmt.Sprintf(errMaxFetchedExemplarsPerQueryLimit, 2)),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			limits := defaultLimitsConfig()
+			limits.MaxFetchedExemplarsPerQuery = testData.maxExemplars
+			overrides, err := validation.NewOverrides(limits, nil)
+			require.NoError(t, err)
+
+			queryable := newMergeExemplarQueryable(&exemplarQueryableMock{results: ingesterResults}, testData.stores, overrides)
+
+			ctx := user.InjectOrgID(context.Background(), "user-1")
+			querier, err := queryable.ExemplarQuerier(ctx)
+			require.NoError(t, err)
+
+			actual, err := querier.Select(1000, 2000, []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, "metric_.*")})
+			if testData.expectedErr != nil {
+				require.Error(t, err)
+				assert.Equal(t, testData.expectedErr.Error(), err.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedResults, actual)
+		})
+	}
+}
+
+// exemplarQueryableMock is a queryable returning the mocked exemplars from any exemplar query.
+type exemplarQueryableMock struct {
+	results []exemplar.QueryResult
+}
+
+func (m *exemplarQueryableMock) Querier(_ context.Context, _, _ int64) (storage.Querier, error) {
+	return storage.NoopQuerier(), nil
+}
+
+func (m *exemplarQueryableMock) ExemplarQuerier(_ context.Context) (storage.ExemplarQuerier, error) {
+	return m, nil
+}
+
+func (m *exemplarQueryableMock) Select(_, _ int64, _ ...[]*labels.Matcher) ([]exemplar.QueryResult, error) {
+	return m.results, nil
+}
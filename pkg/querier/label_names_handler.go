@@ -0,0 +1,176 @@
+package querier
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/cortexproject/cortex/pkg/querier/series"
+	"github.com/cortexproject/cortex/pkg/util"
+)
+
+// Same defaults used by the Prometheus API when the start and end parameters are not provided.
+var (
+	labelNamesMinTime = timestamp.FromTime(time.Unix(math.MinInt64/1000+62135596801, 0).UTC())
+	labelNamesMaxTime = timestamp.FromTime(time.Unix(math.MaxInt64/1000-62135596801, 999999999).UTC())
+)
+
+type labelNamesResult struct {
+	Status string `json:"status"`
+	// Data is an interface, so that an empty list of names is not omitted.
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Warnings  []string    `json:"warnings,omitempty"`
+}
+
+// LabelNamesHandler returns the label names of the series matching the match[] parameters, pushing down
+// the matchers to the queriers supporting it instead of selecting the matching series and extracting
+// their label names. Requests without match[] parameters are served by the next handler.
+// TODO: This custom handler can be removed once Prometheus pushes down the matchers in the label names API.
+func LabelNamesHandler(queryable storage.Queryable, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeLabelNamesError(w, http.StatusBadRequest, "bad_data", errors.Wrap(err, "error parsing form values"))
+			return
+		}
+		if len(r.Form["match[]"]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start, err := parseLabelNamesTime(r, "start", labelNamesMinTime)
+		if err != nil {
+			writeLabelNamesError(w, http.StatusBadRequest, "bad_data", errors.Wrap(err, "invalid parameter 'start'"))
+			return
+		}
+		end, err := parseLabelNamesTime(r, "end", labelNamesMaxTime)
+		if err != nil {
+			writeLabelNamesError(w, http.StatusBadRequest, "bad_data", errors.Wrap(err, "invalid parameter 'end'"))
+			return
+		}
+
+		matcherSets, err := parseLabelNamesMatchers(r.Form["match[]"])
+		if err != nil {
+			writeLabelNamesError(w, http.StatusBadRequest, "bad_data", err)
+			return
+		}
+
+		q, err := queryable.Querier(r.Context(), start, end)
+		if err != nil {
+			writeLabelNamesAPIError(w, err)
+			return
+		}
+		defer q.Close()
+
+		var (
+			hints    = &storage.SelectHints{Start: start, End: end, Func: "series"}
+			sets     = make([][]string, 0, len(matcherSets))
+			warnings []string
+		)
+
+		for _, matchers := range matcherSets {
+			names, ws, err := series.LabelNamesWithMatchers(q, hints, matchers...)
+			for _, w := range ws {
+				warnings = append(warnings, w.Error())
+			}
+			if err != nil {
+				writeLabelNamesAPIError(w, err)
+				return
+			}
+
+			sets = append(sets, names)
+		}
+
+		names := mergeLabelNames(sets)
+		util.WriteJSONResponse(w, labelNamesResult{Status: statusSuccess, Data: names, Warnings: warnings})
+	})
+}
+
+func parseLabelNamesTime(r *http.Request, param string, defaultValue int64) (int64, error) {
+	value := r.Form.Get(param)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	t, err := util.ParseTime(value)
+	if err != nil {
+		// The error returned by ParseTime is an HTTP gRPC error, so we build a plain one.
+		return 0, errors.Errorf("cannot parse %q to a valid timestamp", value)
+	}
+
+	return t, nil
+}
+
+func parseLabelNamesMatchers(values []string) ([][]*labels.Matcher, error) {
+	matcherSets := make([][]*labels.Matcher, 0, len(values))
+
+	for _, value := range values {
+		matchers, err := parser.ParseMetricSelector(value)
+		if err != nil {
+			return nil, err
+		}
+
+		// Like Prometheus, we don't allow selectors matching all series.
+		matchesAll := true
+		for _, m := range matchers {
+			if !m.Matches("") {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			return nil, errors.New("match[] must contain at least one non-empty matcher")
+		}
+
+		matcherSets = append(matcherSets, matchers)
+	}
+
+	return matcherSets, nil
+}
+
+// mergeLabelNames returns the sorted unique label names of the input sets.
+func mergeLabelNames(sets [][]string) []string {
+	unique := map[string]struct{}{}
+	for _, set := range sets {
+		for _, name := range set {
+			unique[name] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(unique))
+	for name := range unique {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// writeLabelNamesAPIError writes the input error with the same status code and error type used by the Prometheus API.
+func writeLabelNamesAPIError(w http.ResponseWriter, err error) {
+	switch errors.Cause(err).(type) {
+	case promql.ErrQueryCanceled:
+		writeLabelNamesError(w, http.StatusServiceUnavailable, "canceled", err)
+	case promql.ErrQueryTimeout:
+		writeLabelNamesError(w, http.StatusServiceUnavailable, "timeout", err)
+	case promql.ErrStorage:
+		writeLabelNamesError(w, http.StatusInternalServerError, "internal", err)
+	default:
+		writeLabelNamesError(w, http.StatusUnprocessableEntity, "execution", err)
+	}
+}
+
+func writeLabelNamesError(w http.ResponseWriter, statusCode int, errorType string, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	util.WriteJSONResponse(w, labelNamesResult{Status: statusError, ErrorType: errorType, Error: err.Error()})
+}
@@ -0,0 +1,132 @@
+package querier
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cortexproject/cortex/pkg/querier/series"
+)
+
+func TestLabelNamesHandler(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("next"))
+	})
+
+	tests := map[string]struct {
+		url                string
+		queryable          func() storage.Queryable
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		"should call the next handler if no match[] parameter is provided": {
+			url:                "/api/v1/labels",
+			queryable:          func() storage.Queryable { return newDistributorQueryable(&mockDistributor{}, false, nil, 0) },
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "next",
+		},
+		"should push down the matchers to the querier": {
+			url: "/api/v1/labels?match[]=metric_1&match[]=metric_2&start=10&end=20",
+			queryable: func() storage.Queryable {
+				d := &mockDistributor{}
+				d.On("LabelNames", mock.Anything, mock.Anything, mock.Anything, []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "metric_1")}).Return([]string{labels.MetricName, "status"}, nil)
+				d.On("LabelNames", mock.Anything, mock.Anything, mock.Anything, []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "metric_2")}).Return([]string{labels.MetricName, "route"}, nil)
+				return newDistributorQueryable(d, false, nil, 0)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"status":"success","data":["__name__","route","status"]}`,
+		},
+		"should select the matching series if the querier doesn't support pushing down the matchers": {
+			url: "/api/v1/labels?match[]=metric_1",
+			queryable: func() storage.Queryable {
+				return storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+					return &selectOnlyQuerierMock{series: []labels.Labels{labels.FromStrings(labels.MetricName, "metric_1", "status", "200")}}, nil
+				})
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"status":"success","data":["__name__","status"]}`,
+		},
+		"should return an empty list if no series match": {
+			url: "/api/v1/labels?match[]=metric_1",
+			queryable: func() storage.Queryable {
+				d := &mockDistributor{}
+				d.On("LabelNames", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]string(nil), nil)
+				return newDistributorQueryable(d, false, nil, 0)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"status":"success","data":[]}`,
+		},
+		"should return bad data on invalid matchers": {
+			url:                "/api/v1/labels?match[]={__name__=~\".*\"}",
+			queryable:          func() storage.Queryable { return newDistributorQueryable(&mockDistributor{}, false, nil, 0) },
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"status":"error","errorType":"bad_data","error":"match[] must contain at least one non-empty matcher"}`,
+		},
+		"should return bad data on invalid start time": {
+			url:                "/api/v1/labels?match[]=metric_1&start=xxx",
+			queryable:          func() storage.Queryable { return newDistributorQueryable(&mockDistributor{}, false, nil, 0) },
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"status":"error","errorType":"bad_data","error":"invalid parameter 'start': cannot parse \"xxx\" to a valid timestamp"}`,
+		},
+		"should return an execution error if the querier fails": {
+			url: "/api/v1/labels?match[]=metric_1",
+			queryable: func() storage.Queryable {
+				return newDistributorQueryable(&errDistributor{}, false, nil, 0)
+			},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedBody:       `{"status":"error","errorType":"execution","error":"errDistributorError"}`,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			handler := LabelNamesHandler(testData.queryable(), nextHandler)
+
+			request, err := http.NewRequest("GET", testData.url, nil)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testData.expectedStatusCode, recorder.Result().StatusCode)
+			body, err := ioutil.ReadAll(recorder.Result().Body)
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedBody, string(body))
+		})
+	}
+}
+
+// selectOnlyQuerierMock is a querier which doesn't support pushing down the label names matchers.
+type selectOnlyQuerierMock struct {
+	storage.Querier
+
+	series []labels.Labels
+}
+
+func (m *selectOnlyQuerierMock) Select(_ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	var res []storage.Series
+
+outer:
+	for _, s := range m.series {
+		for _, matcher := range matchers {
+			if !matcher.Matches(s.Get(matcher.Name)) {
+				continue outer
+			}
+		}
+		res = append(res, series.NewEmptySeries(s))
+	}
+
+	return series.NewConcreteSeriesSet(res)
+}
+
+func (m *selectOnlyQuerierMock) Close() error {
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/cortexproject/cortex/pkg/chunk"
 	"github.com/cortexproject/cortex/pkg/querier/chunkstore"
+	"github.com/cortexproject/cortex/pkg/querier/series"
 )
 
 // LazyQueryable wraps a storage.Queryable
@@ -67,6 +68,11 @@ func (l LazyQuerier) LabelNames() ([]string, storage.Warnings, error) {
 	return l.next.LabelNames()
 }
 
+// LabelNamesWithMatchers implements series.LabelNamesWithMatchersQuerier
+func (l LazyQuerier) LabelNamesWithMatchers(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return series.LabelNamesWithMatchers(l.next, nil, matchers...)
+}
+
 // Close implements Storage.Querier
 func (l LazyQuerier) Close() error {
 	return l.next.Close()
@@ -65,8 +65,9 @@ type Config struct {
 	LookbackDelta time.Duration `yaml:"lookback_delta"`
 
 	// Blocks storage only.
-	StoreGatewayAddresses string       `yaml:"store_gateway_addresses"`
-	StoreGatewayClient    ClientConfig `yaml:"store_gateway_client"`
+	StoreGatewayAddresses string                    `yaml:"store_gateway_addresses"`
+	StoreGatewayClient    ClientConfig              `yaml:"store_gateway_client"`
+	StoreGatewayHedging   StoreGatewayHedgingConfig `yaml:"store_gateway_hedging"`
 
 	SecondStoreEngine        string       `yaml:"second_store_engine"`
 	UseSecondStoreBeforeTime flagext.Time `yaml:"use_second_store_before_time"`
@@ -83,6 +84,7 @@ var (
 // RegisterFlags adds the flags required to config this to the given FlagSet.
 func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	cfg.StoreGatewayClient.RegisterFlagsWithPrefix("querier.store-gateway-client", f)
+	cfg.StoreGatewayHedging.RegisterFlagsWithPrefix("querier.store-gateway-hedging", f)
 	f.IntVar(&cfg.MaxConcurrent, "querier.max-concurrent", 20, "The maximum number of concurrent queries.")
 	f.DurationVar(&cfg.Timeout, "querier.timeout", 2*time.Minute, "The timeout for a query.")
 	f.BoolVar(&cfg.Iterators, "querier.iterators", false, "Use iterators to execute query, as opposed to fully materialising the series in memory.")
@@ -118,6 +120,10 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if err := cfg.StoreGatewayHedging.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -157,7 +163,7 @@ func New(cfg Config, limits *validation.Overrides, distributor Distributor, stor
 		}
 	}
 	queryable := NewQueryable(distributorQueryable, ns, iteratorFunc, cfg, limits, tombstonesLoader)
-	exemplarQueryable := newDistributorExemplarQueryable(distributor)
+	exemplarQueryable := newMergeExemplarQueryable(newDistributorExemplarQueryable(distributor), ns, limits)
 
 	lazyQueryable := storage.QueryableFunc(func(ctx context.Context, mint int64, maxt int64) (storage.Querier, error) {
 		querier, err := queryable.Querier(ctx, mint, maxt)
@@ -432,12 +438,20 @@ func (q querier) LabelValues(name string, matchers ...*labels.Matcher) ([]string
 }
 
 func (q querier) LabelNames() ([]string, storage.Warnings, error) {
+	return q.LabelNamesWithMatchers()
+}
+
+// LabelNamesWithMatchers implements series.LabelNamesWithMatchersQuerier.
+func (q querier) LabelNamesWithMatchers(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	// Used only by the queriers which can't push down the matchers.
+	hints := &storage.SelectHints{Start: q.mint, End: q.maxt, Func: "series"}
+
 	if !q.queryStoreForLabels {
-		return q.metadataQuerier.LabelNames()
+		return series.LabelNamesWithMatchers(q.metadataQuerier, hints, matchers...)
 	}
 
 	if len(q.queriers) == 1 {
-		return q.queriers[0].LabelNames()
+		return series.LabelNamesWithMatchers(q.queriers[0], hints, matchers...)
 	}
 
 	var (
@@ -453,7 +467,7 @@ func (q querier) LabelNames() ([]string, storage.Warnings, error) {
 		querier := querier
 		g.Go(func() error {
 			// NB: Names are sorted in Cortex already.
-			myNames, myWarnings, err := querier.LabelNames()
+			myNames, myWarnings, err := series.LabelNamesWithMatchers(querier, hints, matchers...)
 			if err != nil {
 				return err
 			}
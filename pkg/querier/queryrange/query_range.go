@@ -95,6 +95,8 @@ type Response interface {
 	proto.Message
 	// GetHeaders returns the HTTP headers in the response.
 	GetHeaders() []*PrometheusResponseHeader
+	// GetWarnings returns the warnings in the response.
+	GetWarnings() []string
 }
 
 type prometheusCodec struct{}
@@ -184,9 +186,32 @@ func (prometheusCodec) MergeResponse(responses ...Response) (Response, error) {
 		}}
 	}
 
+	response.Warnings = mergeWarnings(promResponses)
+
 	return &response, nil
 }
 
+// mergeWarnings returns the unique warnings of the input responses, preserving their order.
+func mergeWarnings(responses []*PrometheusResponse) []string {
+	var (
+		warnings []string
+		seen     = map[string]struct{}{}
+	)
+
+	for _, res := range responses {
+		for _, w := range res.Warnings {
+			if _, ok := seen[w]; ok {
+				continue
+			}
+
+			seen[w] = struct{}{}
+			warnings = append(warnings, w)
+		}
+	}
+
+	return warnings
+}
+
 func (prometheusCodec) DecodeRequest(_ context.Context, r *http.Request) (Request, error) {
 	var result PrometheusRequest
 	var err error
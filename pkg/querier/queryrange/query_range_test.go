@@ -171,6 +171,34 @@ func TestMergeAPIResponses(t *testing.T) {
 			},
 		},
 
+		{
+			name: "Warnings of the merged responses are deduplicated.",
+			input: []Response{
+				&PrometheusResponse{
+					Data: PrometheusData{
+						ResultType: matrix,
+						Result:     []SampleStream{},
+					},
+					Warnings: []string{"warning 1", "warning 2"},
+				},
+				&PrometheusResponse{
+					Data: PrometheusData{
+						ResultType: matrix,
+						Result:     []SampleStream{},
+					},
+					Warnings: []string{"warning 2", "warning 3"},
+				},
+			},
+			expected: &PrometheusResponse{
+				Status: StatusSuccess,
+				Data: PrometheusData{
+					ResultType: matrix,
+					Result:     []SampleStream{},
+				},
+				Warnings: []string{"warning 1", "warning 2", "warning 3"},
+			},
+		},
+
 		{
 			name: "Basic merging of two responses.",
 			input: []Response{
@@ -9,6 +9,7 @@ import (
 	"github.com/prometheus/prometheus/storage"
 
 	"github.com/cortexproject/cortex/pkg/querier/astmapper"
+	"github.com/cortexproject/cortex/pkg/querier/series"
 )
 
 const (
@@ -84,7 +85,7 @@ func (q *ShardedQuerier) handleEmbeddedQuery(encoded string) storage.SeriesSet {
 
 	// buffer channels to length of queries to prevent leaking memory due to sending to unbuffered channels after cancel/err
 	errCh := make(chan error, len(queries))
-	samplesCh := make(chan []SampleStream, len(queries))
+	respCh := make(chan Response, len(queries))
 	// TODO(owen-d): impl unified concurrency controls, not per middleware
 	for _, query := range queries {
 		go func(query string) {
@@ -93,27 +94,36 @@ func (q *ShardedQuerier) handleEmbeddedQuery(encoded string) storage.SeriesSet {
 				errCh <- err
 				return
 			}
-			streams, err := ResponseToSamples(resp)
-			if err != nil {
-				errCh <- err
-				return
-			}
 			q.setResponseHeaders(resp.(*PrometheusResponse).Headers)
-			samplesCh <- streams
+			respCh <- resp
 		}(query)
 	}
 
-	var samples []SampleStream
+	var (
+		samples  []SampleStream
+		warnings storage.Warnings
+	)
 
 	for i := 0; i < len(queries); i++ {
 		select {
 		case err := <-errCh:
 			return storage.ErrSeriesSet(err)
-		case streams := <-samplesCh:
+		case resp := <-respCh:
+			streams, err := ResponseToSamples(resp)
+			if err != nil {
+				return storage.ErrSeriesSet(err)
+			}
 			samples = append(samples, streams...)
+
+			for _, w := range resp.GetWarnings() {
+				warnings = append(warnings, errors.New(w))
+			}
 		}
 	}
 
+	if len(warnings) > 0 {
+		return series.NewSeriesSetWithWarnings(NewSeriesSet(samples), warnings)
+	}
 	return NewSeriesSet(samples)
 }
 
@@ -180,6 +180,7 @@ type PrometheusResponse struct {
 	ErrorType string                      `protobuf:"bytes,3,opt,name=ErrorType,proto3" json:"errorType,omitempty"`
 	Error     string                      `protobuf:"bytes,4,opt,name=Error,proto3" json:"error,omitempty"`
 	Headers   []*PrometheusResponseHeader `protobuf:"bytes,5,rep,name=Headers,proto3" json:"-"`
+	Warnings  []string                    `protobuf:"bytes,6,rep,name=Warnings,proto3" json:"warnings,omitempty"`
 }
 
 func (m *PrometheusResponse) Reset()      { *m = PrometheusResponse{} }
@@ -249,6 +250,13 @@ func (m *PrometheusResponse) GetHeaders() []*PrometheusResponseHeader {
 	return nil
 }
 
+func (m *PrometheusResponse) GetWarnings() []string {
+	if m != nil {
+		return m.Warnings
+	}
+	return nil
+}
+
 type PrometheusData struct {
 	ResultType string         `protobuf:"bytes,1,opt,name=ResultType,proto3" json:"resultType"`
 	Result     []SampleStream `protobuf:"bytes,2,rep,name=Result,proto3" json:"result"`
@@ -520,59 +528,60 @@ func init() {
 func init() { proto.RegisterFile("queryrange.proto", fileDescriptor_79b02382e213d0b2) }
 
 var fileDescriptor_79b02382e213d0b2 = []byte{
-	// 827 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0xcd, 0x8e, 0x1b, 0x45,
-	0x10, 0x76, 0xaf, 0xed, 0xb1, 0xdd, 0x1b, 0x39, 0x4b, 0x6f, 0x04, 0xe3, 0x95, 0x98, 0xb1, 0x46,
-	0x1c, 0x16, 0x29, 0x99, 0x95, 0x16, 0x71, 0x00, 0x09, 0x94, 0x0c, 0x59, 0x14, 0x7e, 0x04, 0x51,
-	0x6f, 0xc4, 0x81, 0x0b, 0x6a, 0x7b, 0x0a, 0x7b, 0x12, 0xcf, 0x4f, 0x7a, 0x7a, 0xd0, 0xfa, 0x86,
-	0xf2, 0x04, 0x1c, 0x79, 0x04, 0x90, 0x78, 0x0c, 0x0e, 0x39, 0xee, 0x31, 0xe2, 0x30, 0xb0, 0xde,
-	0x0b, 0x9a, 0x53, 0x1e, 0x01, 0xf5, 0xcf, 0x78, 0x66, 0x77, 0xb9, 0xe4, 0x62, 0x55, 0x55, 0xd7,
-	0x57, 0xf5, 0xd5, 0xd7, 0x3d, 0x65, 0xbc, 0xf7, 0xbc, 0x00, 0xbe, 0xe6, 0x2c, 0x59, 0x80, 0x9f,
-	0xf1, 0x54, 0xa4, 0x04, 0x37, 0x91, 0x83, 0x7b, 0x8b, 0x48, 0x2c, 0x8b, 0x99, 0x3f, 0x4f, 0xe3,
-	0xa3, 0x45, 0xba, 0x48, 0x8f, 0x54, 0xca, 0xac, 0xf8, 0x51, 0x79, 0xca, 0x51, 0x96, 0x86, 0x1e,
-	0x38, 0x8b, 0x34, 0x5d, 0xac, 0xa0, 0xc9, 0x0a, 0x0b, 0xce, 0x44, 0x94, 0x26, 0xe6, 0xfc, 0xa3,
-	0x56, 0xb9, 0x79, 0xca, 0x05, 0x9c, 0x65, 0x3c, 0x7d, 0x0a, 0x73, 0x61, 0xbc, 0xa3, 0xec, 0xd9,
-	0xa2, 0x3e, 0x98, 0x19, 0xc3, 0x40, 0x27, 0xd7, 0x4b, 0xb3, 0x64, 0xad, 0x8f, 0xbc, 0x17, 0x3b,
-	0xf8, 0xad, 0xc7, 0x3c, 0x8d, 0x41, 0x2c, 0xa1, 0xc8, 0x29, 0x3c, 0x2f, 0x20, 0x17, 0x84, 0xe0,
-	0x5e, 0xc6, 0xc4, 0xd2, 0x46, 0x53, 0x74, 0x38, 0xa2, 0xca, 0x26, 0x77, 0x70, 0x3f, 0x17, 0x8c,
-	0x0b, 0x7b, 0x67, 0x8a, 0x0e, 0xbb, 0x54, 0x3b, 0x64, 0x0f, 0x77, 0x21, 0x09, 0xed, 0xae, 0x8a,
-	0x49, 0x53, 0x62, 0x73, 0x01, 0x99, 0xdd, 0x53, 0x21, 0x65, 0x93, 0x4f, 0xf0, 0x40, 0x44, 0x31,
-	0xa4, 0x85, 0xb0, 0xfb, 0x53, 0x74, 0xb8, 0x7b, 0x3c, 0xf1, 0x35, 0x25, 0xbf, 0xa6, 0xe4, 0x3f,
-	0x34, 0xd3, 0x06, 0xc3, 0x97, 0xa5, 0xdb, 0xf9, 0xf5, 0x6f, 0x17, 0xd1, 0x1a, 0x23, 0x5b, 0x2b,
-	0x5d, 0x6d, 0x4b, 0xf1, 0xd1, 0x0e, 0x79, 0x84, 0xc7, 0x73, 0x36, 0x5f, 0x46, 0xc9, 0xe2, 0xdb,
-	0x4c, 0x22, 0x73, 0x7b, 0xa0, 0x6a, 0x1f, 0xf8, 0xad, 0x6b, 0xf9, 0xec, 0x4a, 0x46, 0xd0, 0x93,
-	0xc5, 0xe9, 0x35, 0x9c, 0xf7, 0x04, 0xdb, 0x6d, 0x0d, 0xf2, 0x2c, 0x4d, 0x72, 0x78, 0x04, 0x2c,
-	0x04, 0x4e, 0x26, 0xb8, 0xf7, 0x0d, 0x8b, 0x41, 0x4b, 0x11, 0xf4, 0xab, 0xd2, 0x45, 0xf7, 0xa8,
-	0x0a, 0x91, 0x77, 0xb1, 0xf5, 0x1d, 0x5b, 0x15, 0x90, 0xdb, 0x3b, 0xd3, 0x6e, 0x73, 0x68, 0x82,
-	0xde, 0xef, 0x3b, 0x98, 0xdc, 0x2c, 0x4b, 0x3c, 0x6c, 0x9d, 0x0a, 0x26, 0x8a, 0xdc, 0x94, 0xc4,
-	0x55, 0xe9, 0x5a, 0xb9, 0x8a, 0x50, 0x73, 0x42, 0x3e, 0xc7, 0xbd, 0x87, 0x4c, 0x30, 0x25, 0xf5,
-	0xb5, 0x81, 0x9a, 0x8a, 0x32, 0x23, 0x78, 0x5b, 0x0e, 0x54, 0x95, 0xee, 0x38, 0x64, 0x82, 0xdd,
-	0x4d, 0xe3, 0x48, 0x40, 0x9c, 0x89, 0x35, 0x55, 0x78, 0xf2, 0x21, 0x1e, 0x9d, 0x70, 0x9e, 0xf2,
-	0x27, 0xeb, 0x0c, 0xd4, 0x1d, 0x8d, 0x82, 0x77, 0xaa, 0xd2, 0xdd, 0x87, 0x3a, 0xd8, 0x42, 0x34,
-	0x99, 0xe4, 0x7d, 0xdc, 0x57, 0x8e, 0xba, 0xc3, 0x51, 0xb0, 0x5f, 0x95, 0xee, 0x6d, 0x05, 0x69,
-	0xa5, 0xeb, 0x0c, 0x72, 0x82, 0x07, 0x5a, 0xa8, 0xdc, 0xee, 0x4f, 0xbb, 0x87, 0xbb, 0xc7, 0xef,
-	0xfd, 0x3f, 0xd9, 0xab, 0xaa, 0xd6, 0x52, 0xd5, 0x58, 0xef, 0x05, 0xc2, 0xe3, 0xab, 0x93, 0x11,
-	0x1f, 0x63, 0x0a, 0x79, 0xb1, 0x12, 0x8a, 0xbc, 0xd6, 0x6a, 0x5c, 0x95, 0x2e, 0xe6, 0xdb, 0x28,
-	0x6d, 0x65, 0x90, 0xfb, 0xd8, 0xd2, 0x9e, 0xba, 0x8d, 0xdd, 0x63, 0xbb, 0x4d, 0xe4, 0x94, 0xc5,
-	0xd9, 0x0a, 0x4e, 0x05, 0x07, 0x16, 0x07, 0x63, 0xa3, 0x99, 0xa5, 0x2b, 0x51, 0x83, 0xf3, 0xfe,
-	0x44, 0xf8, 0x56, 0x3b, 0x91, 0x9c, 0x61, 0x6b, 0xc5, 0x66, 0xb0, 0x92, 0x57, 0x25, 0x4b, 0xee,
-	0xfb, 0xf5, 0xf7, 0xe5, 0x7f, 0x2d, 0xe3, 0x8f, 0x59, 0xc4, 0x83, 0xaf, 0x64, 0xb5, 0xbf, 0x4a,
-	0xf7, 0x8d, 0xbe, 0x4f, 0x8d, 0x7f, 0x10, 0xb2, 0x4c, 0x00, 0x97, 0x54, 0x62, 0x10, 0x3c, 0x9a,
-	0x53, 0xd3, 0x8f, 0x7c, 0x8c, 0x07, 0xb9, 0x62, 0x92, 0x9b, 0x69, 0xf6, 0x9a, 0xd6, 0x9a, 0x62,
-	0x33, 0xc5, 0x4f, 0xea, 0xb9, 0xd1, 0x1a, 0xe0, 0x3d, 0xc5, 0x63, 0xf9, 0xea, 0x21, 0xdc, 0x3e,
-	0xb9, 0x09, 0xee, 0x3e, 0x83, 0xb5, 0xd1, 0x70, 0x50, 0x95, 0xae, 0x74, 0xa9, 0xfc, 0x91, 0x5f,
-	0x26, 0x9c, 0x09, 0x48, 0x44, 0xdd, 0x88, 0xb4, 0x65, 0x3b, 0x51, 0x47, 0xc1, 0x6d, 0xd3, 0xaa,
-	0x4e, 0xa5, 0xb5, 0xe1, 0xfd, 0x81, 0xb0, 0xa5, 0x93, 0x88, 0x5b, 0xef, 0x07, 0xd9, 0xa6, 0x1b,
-	0x8c, 0xaa, 0xd2, 0xd5, 0x81, 0x7a, 0x55, 0x4c, 0xf4, 0xaa, 0x50, 0xeb, 0x43, 0xb3, 0x80, 0x24,
-	0xd4, 0x3b, 0x63, 0x8a, 0x87, 0x82, 0xb3, 0x39, 0xfc, 0x10, 0x85, 0xe6, 0xcd, 0xd5, 0x0f, 0x44,
-	0x85, 0xbf, 0x08, 0xc9, 0xa7, 0x78, 0xc8, 0xcd, 0x38, 0x66, 0x85, 0xdc, 0xb9, 0xb1, 0x42, 0x1e,
-	0x24, 0xeb, 0xe0, 0x56, 0x55, 0xba, 0xdb, 0x4c, 0xba, 0xb5, 0xbe, 0xec, 0x0d, 0xbb, 0x7b, 0x3d,
-	0xef, 0xae, 0x96, 0xa6, 0xf9, 0xf4, 0xc9, 0x01, 0x1e, 0x86, 0x51, 0xce, 0x66, 0x2b, 0x08, 0x15,
-	0xf1, 0x21, 0xdd, 0xfa, 0xc1, 0xfd, 0xf3, 0x0b, 0xa7, 0xf3, 0xea, 0xc2, 0xe9, 0xbc, 0xbe, 0x70,
-	0xd0, 0xcf, 0x1b, 0x07, 0xfd, 0xb6, 0x71, 0xd0, 0xcb, 0x8d, 0x83, 0xce, 0x37, 0x0e, 0xfa, 0x67,
-	0xe3, 0xa0, 0x7f, 0x37, 0x4e, 0xe7, 0xf5, 0xc6, 0x41, 0xbf, 0x5c, 0x3a, 0x9d, 0xf3, 0x4b, 0xa7,
-	0xf3, 0xea, 0xd2, 0xe9, 0x7c, 0xdf, 0xfa, 0x0b, 0x98, 0x59, 0x8a, 0xdb, 0x07, 0xff, 0x05, 0x00,
-	0x00, 0xff, 0xff, 0x08, 0x83, 0xd3, 0x7d, 0x29, 0x06, 0x00, 0x00,
+	// 846 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0x4b, 0x8f, 0xdb, 0x54,
+	0x14, 0x8e, 0xf3, 0x70, 0x92, 0x33, 0x55, 0x3a, 0xdc, 0xa9, 0x8a, 0x33, 0x12, 0x76, 0x64, 0xb1,
+	0x18, 0xa4, 0xd6, 0x23, 0x0d, 0x62, 0x01, 0x12, 0xa8, 0x35, 0x1d, 0x54, 0x1e, 0x82, 0xca, 0x53,
+	0x81, 0xc4, 0x06, 0xdd, 0xc4, 0x07, 0x8f, 0xdb, 0xf8, 0xd1, 0xeb, 0x6b, 0x98, 0xec, 0x50, 0x7f,
+	0x01, 0x4b, 0x7e, 0x02, 0x0b, 0x7e, 0x06, 0x8b, 0x2e, 0x67, 0x59, 0x21, 0x61, 0x98, 0xcc, 0x06,
+	0x79, 0xd5, 0x9f, 0x80, 0xee, 0xc3, 0x89, 0xa7, 0xc3, 0x86, 0x4d, 0x74, 0xce, 0xb9, 0xdf, 0x77,
+	0x1e, 0xdf, 0xf5, 0x3d, 0x81, 0xdd, 0x67, 0x25, 0xb2, 0x15, 0xa3, 0x69, 0x84, 0x5e, 0xce, 0x32,
+	0x9e, 0x11, 0xd8, 0x46, 0xf6, 0xef, 0x46, 0x31, 0x3f, 0x2d, 0xe7, 0xde, 0x22, 0x4b, 0x0e, 0xa3,
+	0x2c, 0xca, 0x0e, 0x25, 0x64, 0x5e, 0x7e, 0x2f, 0x3d, 0xe9, 0x48, 0x4b, 0x51, 0xf7, 0xed, 0x28,
+	0xcb, 0xa2, 0x25, 0x6e, 0x51, 0x61, 0xc9, 0x28, 0x8f, 0xb3, 0x54, 0x9f, 0xbf, 0xdf, 0x4a, 0xb7,
+	0xc8, 0x18, 0xc7, 0xb3, 0x9c, 0x65, 0x4f, 0x70, 0xc1, 0xb5, 0x77, 0x98, 0x3f, 0x8d, 0x9a, 0x83,
+	0xb9, 0x36, 0x34, 0x75, 0xfa, 0x7a, 0x6a, 0x9a, 0xae, 0xd4, 0x91, 0xfb, 0xbc, 0x0b, 0x6f, 0x3c,
+	0x62, 0x59, 0x82, 0xfc, 0x14, 0xcb, 0x22, 0xc0, 0x67, 0x25, 0x16, 0x9c, 0x10, 0xe8, 0xe7, 0x94,
+	0x9f, 0x5a, 0xc6, 0xcc, 0x38, 0x18, 0x07, 0xd2, 0x26, 0xb7, 0x60, 0x50, 0x70, 0xca, 0xb8, 0xd5,
+	0x9d, 0x19, 0x07, 0xbd, 0x40, 0x39, 0x64, 0x17, 0x7a, 0x98, 0x86, 0x56, 0x4f, 0xc6, 0x84, 0x29,
+	0xb8, 0x05, 0xc7, 0xdc, 0xea, 0xcb, 0x90, 0xb4, 0xc9, 0x87, 0x30, 0xe4, 0x71, 0x82, 0x59, 0xc9,
+	0xad, 0xc1, 0xcc, 0x38, 0xd8, 0x39, 0x9a, 0x7a, 0xaa, 0x25, 0xaf, 0x69, 0xc9, 0x7b, 0xa0, 0xa7,
+	0xf5, 0x47, 0x2f, 0x2a, 0xa7, 0xf3, 0xcb, 0x5f, 0x8e, 0x11, 0x34, 0x1c, 0x51, 0x5a, 0xea, 0x6a,
+	0x99, 0xb2, 0x1f, 0xe5, 0x90, 0x87, 0x30, 0x59, 0xd0, 0xc5, 0x69, 0x9c, 0x46, 0x5f, 0xe5, 0x82,
+	0x59, 0x58, 0x43, 0x99, 0x7b, 0xdf, 0x6b, 0x5d, 0xcb, 0xc7, 0x57, 0x10, 0x7e, 0x5f, 0x24, 0x0f,
+	0x5e, 0xe3, 0xb9, 0x8f, 0xc1, 0x6a, 0x6b, 0x50, 0xe4, 0x59, 0x5a, 0xe0, 0x43, 0xa4, 0x21, 0x32,
+	0x32, 0x85, 0xfe, 0x97, 0x34, 0x41, 0x25, 0x85, 0x3f, 0xa8, 0x2b, 0xc7, 0xb8, 0x1b, 0xc8, 0x10,
+	0x79, 0x0b, 0xcc, 0xaf, 0xe9, 0xb2, 0xc4, 0xc2, 0xea, 0xce, 0x7a, 0xdb, 0x43, 0x1d, 0x74, 0xff,
+	0xec, 0x02, 0xb9, 0x9e, 0x96, 0xb8, 0x60, 0x9e, 0x70, 0xca, 0xcb, 0x42, 0xa7, 0x84, 0xba, 0x72,
+	0xcc, 0x42, 0x46, 0x02, 0x7d, 0x42, 0x3e, 0x81, 0xfe, 0x03, 0xca, 0xa9, 0xd5, 0xbd, 0x3e, 0xd0,
+	0x36, 0xa3, 0x40, 0xf8, 0xb7, 0xc5, 0x40, 0x75, 0xe5, 0x4c, 0x42, 0xca, 0xe9, 0x9d, 0x2c, 0x89,
+	0x39, 0x26, 0x39, 0x5f, 0x05, 0x92, 0x4f, 0xde, 0x83, 0xf1, 0x31, 0x63, 0x19, 0x7b, 0xbc, 0xca,
+	0x51, 0xde, 0xd1, 0xd8, 0x7f, 0xb3, 0xae, 0x9c, 0x3d, 0x6c, 0x82, 0x2d, 0xc6, 0x16, 0x49, 0xde,
+	0x81, 0x81, 0x74, 0xe4, 0x1d, 0x8e, 0xfd, 0xbd, 0xba, 0x72, 0x6e, 0x4a, 0x4a, 0x0b, 0xae, 0x10,
+	0xe4, 0x18, 0x86, 0x4a, 0xa8, 0xc2, 0x1a, 0xcc, 0x7a, 0x07, 0x3b, 0x47, 0x6f, 0xff, 0x77, 0xb3,
+	0x57, 0x55, 0x6d, 0xa4, 0x6a, 0xb8, 0xe4, 0x08, 0x46, 0xdf, 0x50, 0x96, 0xc6, 0x69, 0x54, 0x58,
+	0xa6, 0x14, 0xf3, 0x76, 0x5d, 0x39, 0xe4, 0x47, 0x1d, 0x6b, 0xd5, 0xdd, 0xe0, 0xdc, 0xe7, 0x06,
+	0x4c, 0xae, 0xaa, 0x41, 0x3c, 0x80, 0x00, 0x8b, 0x72, 0xc9, 0xe5, 0xc0, 0x4a, 0xdf, 0x49, 0x5d,
+	0x39, 0xc0, 0x36, 0xd1, 0xa0, 0x85, 0x20, 0xf7, 0xc0, 0x54, 0x9e, 0xbc, 0xc1, 0x9d, 0x23, 0xab,
+	0xdd, 0xfc, 0x09, 0x4d, 0xf2, 0x25, 0x9e, 0x70, 0x86, 0x34, 0xf1, 0x27, 0x5a, 0x67, 0x53, 0x65,
+	0x0a, 0x34, 0xcf, 0xfd, 0xdd, 0x80, 0x1b, 0x6d, 0x20, 0x39, 0x03, 0x73, 0x49, 0xe7, 0xb8, 0x14,
+	0xd7, 0x2b, 0x52, 0xee, 0x79, 0xcd, 0x9b, 0xf4, 0xbe, 0x10, 0xf1, 0x47, 0x34, 0x66, 0xfe, 0xe7,
+	0x22, 0xdb, 0x1f, 0x95, 0xf3, 0xbf, 0xde, 0xb4, 0xe2, 0xdf, 0x0f, 0x69, 0xce, 0x91, 0x89, 0x56,
+	0x12, 0xe4, 0x2c, 0x5e, 0x04, 0xba, 0x1e, 0xf9, 0x00, 0x86, 0x85, 0xec, 0xa4, 0xd0, 0xd3, 0xec,
+	0x6e, 0x4b, 0xab, 0x16, 0xb7, 0x53, 0xfc, 0x20, 0x3f, 0xd1, 0xa0, 0x21, 0xb8, 0x4f, 0x60, 0x22,
+	0x5e, 0x0a, 0x86, 0x9b, 0xcf, 0x74, 0x0a, 0xbd, 0xa7, 0xb8, 0xd2, 0x1a, 0x0e, 0xeb, 0xca, 0x11,
+	0x6e, 0x20, 0x7e, 0xc4, 0x6b, 0xc6, 0x33, 0x8e, 0x29, 0x6f, 0x0a, 0x91, 0xb6, 0x6c, 0xc7, 0xf2,
+	0xc8, 0xbf, 0xa9, 0x4b, 0x35, 0xd0, 0xa0, 0x31, 0xdc, 0xdf, 0x0c, 0x30, 0x15, 0x88, 0x38, 0xcd,
+	0x4e, 0x11, 0x65, 0x7a, 0xfe, 0xb8, 0xae, 0x1c, 0x15, 0x68, 0xd6, 0xcb, 0x54, 0xad, 0x17, 0xb9,
+	0x72, 0x54, 0x17, 0x98, 0x86, 0x6a, 0xcf, 0xcc, 0x60, 0xc4, 0x19, 0x5d, 0xe0, 0x77, 0x71, 0xa8,
+	0xbf, 0xd3, 0xe6, 0xa3, 0x92, 0xe1, 0x4f, 0x43, 0xf2, 0x11, 0x8c, 0x98, 0x1e, 0x47, 0xaf, 0x9d,
+	0x5b, 0xd7, 0xd6, 0xce, 0xfd, 0x74, 0xe5, 0xdf, 0xa8, 0x2b, 0x67, 0x83, 0x0c, 0x36, 0xd6, 0x67,
+	0xfd, 0x51, 0x6f, 0xb7, 0xef, 0xde, 0x51, 0xd2, 0x6c, 0xd7, 0x05, 0xd9, 0x87, 0x51, 0x18, 0x17,
+	0x74, 0xbe, 0xc4, 0x50, 0x36, 0x3e, 0x0a, 0x36, 0xbe, 0x7f, 0xef, 0xfc, 0xc2, 0xee, 0xbc, 0xbc,
+	0xb0, 0x3b, 0xaf, 0x2e, 0x6c, 0xe3, 0xa7, 0xb5, 0x6d, 0xfc, 0xba, 0xb6, 0x8d, 0x17, 0x6b, 0xdb,
+	0x38, 0x5f, 0xdb, 0xc6, 0xdf, 0x6b, 0xdb, 0xf8, 0x67, 0x6d, 0x77, 0x5e, 0xad, 0x6d, 0xe3, 0xe7,
+	0x4b, 0xbb, 0x73, 0x7e, 0x69, 0x77, 0x5e, 0x5e, 0xda, 0x9d, 0x6f, 0x5b, 0x7f, 0x1b, 0x73, 0x53,
+	0xf6, 0xf6, 0xee, 0xbf, 0x03, 0x00, 0xbc, 0x65, 0x75, 0x5a, 0x5d, 0x06, 0x00, 0x00,
 }
 
 func (this *PrometheusRequest) Equal(that interface{}) bool {
@@ -688,6 +697,14 @@ func (this *PrometheusResponse) Equal(that interface{}) bool {
 			return false
 		}
 	}
+	if len(this.Warnings) != len(that1.Warnings) {
+		return false
+	}
+	for i := range this.Warnings {
+		if this.Warnings[i] != that1.Warnings[i] {
+			return false
+		}
+	}
 	return true
 }
 func (this *PrometheusData) Equal(that interface{}) bool {
@@ -879,7 +896,7 @@ func (this *PrometheusResponse) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 9)
+	s := make([]string, 0, 10)
 	s = append(s, "&queryrange.PrometheusResponse{")
 	s = append(s, "Status: "+fmt.Sprintf("%#v", this.Status)+",\n")
 	s = append(s, "Data: "+strings.Replace(this.Data.GoString(), `&`, ``, 1)+",\n")
@@ -888,6 +905,7 @@ func (this *PrometheusResponse) GoString() string {
 	if this.Headers != nil {
 		s = append(s, "Headers: "+fmt.Sprintf("%#v", this.Headers)+",\n")
 	}
+	s = append(s, "Warnings: "+fmt.Sprintf("%#v", this.Warnings)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -1104,6 +1122,15 @@ func (m *PrometheusResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Warnings) > 0 {
+		for iNdEx := len(m.Warnings) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Warnings[iNdEx])
+			copy(dAtA[i:], m.Warnings[iNdEx])
+			i = encodeVarintQueryrange(dAtA, i, uint64(len(m.Warnings[iNdEx])))
+			i--
+			dAtA[i] = 0x32
+		}
+	}
 	if len(m.Headers) > 0 {
 		for iNdEx := len(m.Headers) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -1462,6 +1489,12 @@ func (m *PrometheusResponse) Size() (n int) {
 			n += 1 + l + sovQueryrange(uint64(l))
 		}
 	}
+	if len(m.Warnings) > 0 {
+		for _, s := range m.Warnings {
+			l = len(s)
+			n += 1 + l + sovQueryrange(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -1607,6 +1640,7 @@ func (this *PrometheusResponse) String() string {
 		`ErrorType:` + fmt.Sprintf("%v", this.ErrorType) + `,`,
 		`Error:` + fmt.Sprintf("%v", this.Error) + `,`,
 		`Headers:` + repeatedStringForHeaders + `,`,
+		`Warnings:` + fmt.Sprintf("%v", this.Warnings) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -2239,6 +2273,38 @@ func (m *PrometheusResponse) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Warnings", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowQueryrange
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthQueryrange
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthQueryrange
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Warnings = append(m.Warnings, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipQueryrange(dAtA[iNdEx:])
@@ -230,13 +230,19 @@ func (qs *queryShard) Do(ctx context.Context, r Request) (Response, error) {
 		return nil, err
 
 	}
+	var warnings []string
+	for _, w := range res.Warnings {
+		warnings = append(warnings, w.Error())
+	}
+
 	return &PrometheusResponse{
 		Status: StatusSuccess,
 		Data: PrometheusData{
 			ResultType: string(res.Value.Type()),
 			Result:     extracted,
 		},
-		Headers: shardedQueryable.getResponseHeaders(),
+		Headers:  shardedQueryable.getResponseHeaders(),
+		Warnings: warnings,
 	}, nil
 }
 
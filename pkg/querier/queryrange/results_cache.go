@@ -239,6 +239,12 @@ func (s resultsCache) shouldCacheResponse(ctx context.Context, req Request, r Re
 		}
 	}
 
+	// Responses with warnings may be partial (e.g. some blocks couldn't be queried), so we don't cache them.
+	if len(r.GetWarnings()) > 0 {
+		level.Debug(s.logger).Log("msg", "response has warnings, not caching the response", "warnings", len(r.GetWarnings()))
+		return false
+	}
+
 	if !s.isAtModifierCachable(req, maxCacheTime) {
 		return false
 	}
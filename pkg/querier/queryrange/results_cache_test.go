@@ -171,6 +171,15 @@ func TestShouldCache(t *testing.T) {
 			}),
 			expected: true,
 		},
+		// Tests only for warnings
+		{
+			name:    "response with warnings",
+			request: &PrometheusRequest{Query: "metric"},
+			input: Response(&PrometheusResponse{
+				Warnings: []string{"partial response"},
+			}),
+			expected: false,
+		},
 		{
 			name:    "had cacheControl header but no values",
 			request: &PrometheusRequest{Query: "metric"},
@@ -0,0 +1,49 @@
+package series
+
+import (
+	"sort"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// LabelNamesWithMatchersQuerier is implemented by the queriers which can push down the
+// matchers of a label names request to the underlying storage, instead of selecting
+// the matching series and extracting their label names.
+type LabelNamesWithMatchersQuerier interface {
+	// LabelNamesWithMatchers returns the sorted unique label names of the series matching the input matchers.
+	LabelNamesWithMatchers(matchers ...*labels.Matcher) ([]string, storage.Warnings, error)
+}
+
+// LabelNamesWithMatchers returns the sorted unique label names of the series matching the input
+// matchers. If the querier doesn't implement LabelNamesWithMatchersQuerier, the matching series
+// are selected with the input hints and their label names are extracted.
+func LabelNamesWithMatchers(q storage.Querier, hints *storage.SelectHints, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	if len(matchers) == 0 {
+		return q.LabelNames()
+	}
+
+	if lq, ok := q.(LabelNamesWithMatchersQuerier); ok {
+		return lq.LabelNamesWithMatchers(matchers...)
+	}
+
+	set := q.Select(false, hints, matchers...)
+
+	names := map[string]struct{}{}
+	for set.Next() {
+		for _, l := range set.At().Labels() {
+			names[l.Name] = struct{}{}
+		}
+	}
+	if err := set.Err(); err != nil {
+		return nil, set.Warnings(), err
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result, set.Warnings(), nil
+}
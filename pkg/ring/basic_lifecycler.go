@@ -51,6 +51,11 @@ type BasicLifecyclerConfig struct {
 	HeartbeatPeriod     time.Duration
 	TokensObservePeriod time.Duration
 	NumTokens           int
+
+	// MinDataAge and MaxDataAge are the age range of the data served by the instance,
+	// used to shard data by time across instances. 0 means no limit.
+	MinDataAge time.Duration
+	MaxDataAge time.Duration
 }
 
 // BasicLifecycler is a basic ring lifecycler which allows to hook custom
@@ -270,13 +275,17 @@ func (l *BasicLifecycler) registerInstance(ctx context.Context) error {
 
 		if !exists {
 			instanceDesc = ringDesc.AddIngester(l.cfg.ID, l.cfg.Addr, l.cfg.Zone, tokens, state, registeredAt)
+			l.setDataAgeRange(&instanceDesc)
+			ringDesc.Ingesters[l.cfg.ID] = instanceDesc
 			return ringDesc, true, nil
 		}
 
 		// Always overwrite the instance in the ring (even if already exists) because some properties
-		// may have changed (stated, tokens, zone, address) and even if they didn't the heartbeat at
-		// least did.
+		// may have changed (stated, tokens, zone, address, data age range) and even if they didn't the
+		// heartbeat at least did.
 		instanceDesc = ringDesc.AddIngester(l.cfg.ID, l.cfg.Addr, l.cfg.Zone, tokens, state, registeredAt)
+		l.setDataAgeRange(&instanceDesc)
+		ringDesc.Ingesters[l.cfg.ID] = instanceDesc
 		return ringDesc, true, nil
 	})
 
@@ -399,6 +408,7 @@ func (l *BasicLifecycler) updateInstance(ctx context.Context, update func(*Desc,
 		if !ok {
 			level.Warn(l.logger).Log("msg", "instance missing in the ring, adding it back", "ring", l.ringName)
 			instanceDesc = ringDesc.AddIngester(l.cfg.ID, l.cfg.Addr, l.cfg.Zone, l.GetTokens(), l.GetState(), l.GetRegisteredAt())
+			l.setDataAgeRange(&instanceDesc)
 		}
 
 		prevTimestamp := instanceDesc.Timestamp
@@ -428,10 +438,23 @@ func (l *BasicLifecycler) updateInstance(ctx context.Context, update func(*Desc,
 	return nil
 }
 
+// setDataAgeRange sets the configured data age range on the input instance.
+func (l *BasicLifecycler) setDataAgeRange(instanceDesc *InstanceDesc) {
+	instanceDesc.MinDataAgeSeconds = int64(l.cfg.MinDataAge / time.Second)
+	instanceDesc.MaxDataAgeSeconds = int64(l.cfg.MaxDataAge / time.Second)
+}
+
 // heartbeat updates the instance timestamp within the ring. This function is guaranteed
 // to be called within the lifecycler main goroutine.
 func (l *BasicLifecycler) heartbeat(ctx context.Context) {
 	err := l.updateInstance(ctx, func(r *Desc, i *InstanceDesc) bool {
+		// The READONLY state may have been toggled from the ring status page, but it's only
+		// supported by the ingesters, so we switch back to our state.
+		if state := l.GetState(); isReadOnlyToggle(state, i.State) {
+			level.Warn(l.logger).Log("msg", "the read-only state is not supported by this ring, switching back to the previous state", "ring", l.ringName, "state", state)
+			i.State = state
+		}
+
 		l.delegate.OnRingInstanceHeartbeat(l, r, i)
 		i.Timestamp = time.Now().Unix()
 		return true
@@ -156,6 +156,28 @@ func TestBasicLifecycler_RegisterOnStart(t *testing.T) {
 	}
 }
 
+func TestBasicLifecycler_RegisterOnStartWithDataAgeRange(t *testing.T) {
+	ctx := context.Background()
+	cfg := prepareBasicLifecyclerConfig()
+	cfg.MinDataAge = 24 * time.Hour
+	cfg.MaxDataAge = 14 * 24 * time.Hour
+
+	lifecycler, delegate, store, err := prepareBasicLifecycler(cfg)
+	require.NoError(t, err)
+	defer services.StopAndAwaitTerminated(ctx, lifecycler) //nolint:errcheck
+
+	delegate.onRegister = func(_ *BasicLifecycler, _ Desc, _ bool, _ string, _ InstanceDesc) (InstanceState, Tokens) {
+		return ACTIVE, Tokens{1, 2, 3, 4, 5}
+	}
+
+	require.NoError(t, services.StartAndAwaitRunning(ctx, lifecycler))
+
+	desc, ok := getInstanceFromStore(t, store, testInstanceID)
+	require.True(t, ok)
+	assert.Equal(t, int64(24*60*60), desc.GetMinDataAgeSeconds())
+	assert.Equal(t, int64(14*24*60*60), desc.GetMaxDataAgeSeconds())
+}
+
 func TestBasicLifecycler_UnregisterOnStop(t *testing.T) {
 	ctx := context.Background()
 	cfg := prepareBasicLifecyclerConfig()
@@ -313,6 +335,36 @@ func TestBasicLifecycler_ChangeState(t *testing.T) {
 	}
 }
 
+func TestBasicLifecycler_HeartbeatShouldSwitchBackFromReadOnlyState(t *testing.T) {
+	ctx := context.Background()
+	cfg := prepareBasicLifecyclerConfig()
+	lifecycler, delegate, store, err := prepareBasicLifecycler(cfg)
+	require.NoError(t, err)
+	defer services.StopAndAwaitTerminated(ctx, lifecycler) //nolint:errcheck
+
+	delegate.onRegister = func(_ *BasicLifecycler, _ Desc, _ bool, _ string, _ InstanceDesc) (InstanceState, Tokens) {
+		return ACTIVE, Tokens{1, 2, 3, 4, 5}
+	}
+
+	require.NoError(t, services.StartAndAwaitRunning(ctx, lifecycler))
+
+	// Switch the instance to READONLY, like from the ring status page.
+	require.NoError(t, store.CAS(ctx, testRingKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		ringDesc := GetOrCreateRingDesc(in)
+		instanceDesc := ringDesc.Ingesters[testInstanceID]
+		instanceDesc.State = READONLY
+		ringDesc.Ingesters[testInstanceID] = instanceDesc
+		return ringDesc, true, nil
+	}))
+
+	lifecycler.heartbeat(ctx)
+	assert.Equal(t, ACTIVE, lifecycler.GetState())
+
+	desc, ok := getInstanceFromStore(t, store, testInstanceID)
+	assert.True(t, ok)
+	assert.Equal(t, ACTIVE, desc.GetState())
+}
+
 func TestBasicLifecycler_TokensObservePeriod(t *testing.T) {
 	ctx := context.Background()
 	cfg := prepareBasicLifecyclerConfig()
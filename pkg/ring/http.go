@@ -56,7 +56,14 @@ const pageContent = `
 						<td>{{ .HeartbeatTimestamp }}</td>
 						<td>{{ .NumTokens }}</td>
 						<td>{{ .Ownership }}%</td>
-						<td><button name="forget" value="{{ .ID }}" type="submit">Forget</button></td>
+						<td>
+							<button name="forget" value="{{ .ID }}" type="submit">Forget</button>
+							{{ if eq .State "ACTIVE" }}
+							<button name="readonly" value="{{ .ID }}" type="submit">Set Read-Only</button>
+							{{ else if eq .State "READONLY" }}
+							<button name="readonly" value="{{ .ID }}" type="submit">Set Active</button>
+							{{ end }}
+						</td>
 					</tr>
 					{{ end }}
 				</tbody>
@@ -104,11 +111,48 @@ func (r *Ring) forget(ctx context.Context, id string) error {
 	return r.KVClient.CAS(ctx, r.key, unregister)
 }
 
+// toggleReadOnly switches the instance from ACTIVE to READONLY or vice versa. The new state
+// is picked up by the instance's lifecycler at the next heartbeat. Only the ingesters support
+// the READONLY state, while the instances of the other rings switch back to their state.
+func (r *Ring) toggleReadOnly(ctx context.Context, id string) error {
+	toggle := func(in interface{}) (out interface{}, retry bool, err error) {
+		if in == nil {
+			return nil, false, fmt.Errorf("found empty ring when trying to toggle the read-only state")
+		}
+
+		ringDesc := in.(*Desc)
+		instance, ok := ringDesc.Ingesters[id]
+		if !ok {
+			return nil, false, ErrInstanceNotFound
+		}
+
+		switch instance.State {
+		case ACTIVE:
+			instance.State = READONLY
+		case READONLY:
+			instance.State = ACTIVE
+		default:
+			return nil, false, fmt.Errorf("the read-only state can't be toggled for an instance in state %v", instance.State)
+		}
+
+		ringDesc.Ingesters[id] = instance
+		return ringDesc, true, nil
+	}
+	return r.KVClient.CAS(ctx, r.key, toggle)
+}
+
 func (r *Ring) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if req.Method == http.MethodPost {
-		ingesterID := req.FormValue("forget")
-		if err := r.forget(req.Context(), ingesterID); err != nil {
-			level.Error(log.WithContext(req.Context(), log.Logger)).Log("msg", "error forgetting instance", "err", err)
+		if ingesterID := req.FormValue("forget"); ingesterID != "" {
+			if err := r.forget(req.Context(), ingesterID); err != nil {
+				level.Error(log.WithContext(req.Context(), log.Logger)).Log("msg", "error forgetting instance", "err", err)
+			}
+		}
+
+		if ingesterID := req.FormValue("readonly"); ingesterID != "" {
+			if err := r.toggleReadOnly(req.Context(), ingesterID); err != nil {
+				level.Error(log.WithContext(req.Context(), log.Logger)).Log("msg", "error toggling the read-only state of instance", "err", err)
+			}
 		}
 
 		// Implement PRG pattern to prevent double-POST and work with CSRF middleware.
@@ -133,6 +133,10 @@ type Lifecycler struct {
 	tokens       Tokens
 	registeredAt time.Time
 
+	// The state last written to the KV store, used to detect the READONLY state being toggled
+	// by an operator from the ring status page. Only accessed by the loop() goroutine.
+	storedState InstanceState
+
 	// Controls the ready-reporting
 	readyLock sync.Mutex
 	startTime time.Time
@@ -719,6 +723,13 @@ func (i *Lifecycler) updateConsul(ctx context.Context) error {
 			level.Info(log.Logger).Log("msg", "found empty ring, inserting tokens", "ring", i.RingName)
 			ringDesc.AddIngester(i.ID, i.Addr, i.Zone, i.getTokens(), i.GetState(), i.getRegisteredAt())
 		} else {
+			// The READONLY state may have been toggled from the ring status page. We honor it unless
+			// our state has changed in the meanwhile.
+			if state := i.GetState(); state == i.storedState && isReadOnlyToggle(state, instanceDesc.State) {
+				level.Info(log.Logger).Log("msg", "instance state changed in the ring", "old_state", state, "new_state", instanceDesc.State, "ring", i.RingName)
+				i.setState(instanceDesc.State)
+			}
+
 			instanceDesc.Timestamp = time.Now().Unix()
 			instanceDesc.State = i.GetState()
 			instanceDesc.Addr = i.Addr
@@ -732,12 +743,18 @@ func (i *Lifecycler) updateConsul(ctx context.Context) error {
 
 	// Update counters
 	if err == nil {
+		i.storedState = i.GetState()
 		i.updateCounters(ringDesc)
 	}
 
 	return err
 }
 
+// isReadOnlyToggle returns whether the change from one state to the other is a toggle of the READONLY state.
+func isReadOnlyToggle(from, to InstanceState) bool {
+	return (from == ACTIVE && to == READONLY) || (from == READONLY && to == ACTIVE)
+}
+
 // changeState updates consul with state transitions for us.  NB this must be
 // called from loop()!  Use ChangeState for calls from outside of loop().
 func (i *Lifecycler) changeState(ctx context.Context, state InstanceState) error {
@@ -747,7 +764,9 @@ func (i *Lifecycler) changeState(ctx context.Context, state InstanceState) error
 		(currState == JOINING && state == PENDING) || // triggered by TransferChunks on failure
 		(currState == JOINING && state == ACTIVE) || // triggered by TransferChunks on success
 		(currState == PENDING && state == ACTIVE) || // triggered by autoJoin
-		(currState == ACTIVE && state == LEAVING)) { // triggered by shutdown
+		(currState == ACTIVE && state == LEAVING) || // triggered by shutdown
+		(currState == READONLY && state == LEAVING) || // triggered by shutdown
+		isReadOnlyToggle(currState, state)) { // triggered by the read-only mode being switched on or off
 		return fmt.Errorf("Changing instance state from %v -> %v is disallowed", currState, state)
 	}
 
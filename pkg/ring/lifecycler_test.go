@@ -129,6 +129,58 @@ func TestLifecycler_ZonesCount(t *testing.T) {
 	}
 }
 
+func TestLifecycler_ShouldHonorReadOnlyStateToggledInTheRing(t *testing.T) {
+	var ringConfig Config
+	flagext.DefaultValues(&ringConfig)
+	ringConfig.KVStore.Mock = consul.NewInMemoryClient(GetCodec())
+
+	ctx := context.Background()
+
+	r, err := New(ringConfig, "ingester", IngesterRingKey, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, r))
+	defer services.StopAndAwaitTerminated(ctx, r) // nolint:errcheck
+
+	cfg := testLifecyclerConfig(ringConfig, "ing1")
+	cfg.JoinAfter = 100 * time.Millisecond
+
+	l, err := NewLifecycler(cfg, &nopFlushTransferer{}, "ingester", IngesterRingKey, true, nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, l))
+	defer services.StopAndAwaitTerminated(ctx, l) // nolint:errcheck
+
+	test.Poll(t, time.Second, ACTIVE, func() interface{} {
+		return l.GetState()
+	})
+
+	getStateInTheRing := func() interface{} {
+		desc, err := l.KVStore.Get(ctx, IngesterRingKey)
+		require.NoError(t, err)
+		return desc.(*Desc).Ingesters["ing1"].State
+	}
+
+	// Switch the instance to read-only from the ring status page.
+	require.NoError(t, r.toggleReadOnly(ctx, "ing1"))
+	test.Poll(t, time.Second, READONLY, func() interface{} {
+		return l.GetState()
+	})
+	test.Poll(t, time.Second, READONLY, getStateInTheRing)
+
+	// Switch it back to active from the lifecycler.
+	require.NoError(t, l.ChangeState(ctx, ACTIVE))
+	test.Poll(t, time.Second, ACTIVE, getStateInTheRing)
+
+	// Wait for a few heartbeats, and ensure the local state is kept.
+	time.Sleep(3 * cfg.HeartbeatPeriod)
+	assert.Equal(t, ACTIVE, l.GetState())
+	assert.Equal(t, ACTIVE, getStateInTheRing())
+
+	// Other states can't be toggled.
+	require.NoError(t, l.ChangeState(ctx, LEAVING))
+	require.Error(t, r.toggleReadOnly(ctx, "ing1"))
+	require.Error(t, r.toggleReadOnly(ctx, "unknown"))
+}
+
 func TestLifecycler_NilFlushTransferer(t *testing.T) {
 	var ringConfig Config
 	flagext.DefaultValues(&ringConfig)
@@ -10,6 +10,7 @@ import (
 
 	"github.com/cortexproject/cortex/pkg/ring/kv/codec"
 	"github.com/cortexproject/cortex/pkg/ring/kv/memberlist"
+	"github.com/cortexproject/cortex/pkg/util"
 )
 
 // ByAddr is a sortable list of InstanceDesc.
@@ -97,13 +98,13 @@ func (d *Desc) FindIngestersByState(state InstanceState) []InstanceDesc {
 	return result
 }
 
-// Ready returns no error when all ingesters are active and healthy.
+// Ready returns no error when all ingesters are active (or read-only) and healthy.
 func (d *Desc) Ready(now time.Time, heartbeatTimeout time.Duration) error {
 	numTokens := 0
 	for id, ingester := range d.Ingesters {
 		if now.Sub(time.Unix(ingester.Timestamp, 0)) > heartbeatTimeout {
 			return fmt.Errorf("instance %s past heartbeat timeout", id)
-		} else if ingester.State != ACTIVE {
+		} else if ingester.State != ACTIVE && ingester.State != READONLY {
 			return fmt.Errorf("instance %s in state %v", id, ingester.State)
 		}
 		numTokens += len(ingester.Tokens)
@@ -133,6 +134,18 @@ func (i *InstanceDesc) GetRegisteredAt() time.Time {
 	return time.Unix(i.RegisteredTimestamp, 0)
 }
 
+// HasDataAgeRange returns whether the instance declares a min or max age of the data it serves.
+func (i *InstanceDesc) HasDataAgeRange() bool {
+	return i.MinDataAgeSeconds > 0 || i.MaxDataAgeSeconds > 0
+}
+
+// ServesDataRange returns whether the data age range declared by the instance overlaps with
+// the input time range (milliseconds, both included). An instance which doesn't declare any
+// data age range serves any time range.
+func (i *InstanceDesc) ServesDataRange(minT, maxT int64, now time.Time) bool {
+	return dataAgeRange{minSeconds: i.MinDataAgeSeconds, maxSeconds: i.MaxDataAgeSeconds}.servesDataRange(minT, maxT, now)
+}
+
 func (i *InstanceDesc) IsHealthy(op Operation, heartbeatTimeout time.Duration, now time.Time) bool {
 	healthy := op.IsInstanceInStateHealthy(i.State)
 
@@ -440,6 +453,72 @@ func (d *Desc) getTokensByZone() map[string][]uint32 {
 	return MergeTokensByZone(zones)
 }
 
+// dataAgeRange is the min and max age (in seconds) of the data served by an instance. 0 means no limit.
+type dataAgeRange struct {
+	minSeconds int64
+	maxSeconds int64
+}
+
+func (r dataAgeRange) servesDataRange(minT, maxT int64, now time.Time) bool {
+	if r.maxSeconds > 0 && maxT < util.TimeToMillis(now.Add(-time.Duration(r.maxSeconds)*time.Second)) {
+		return false
+	}
+	if r.minSeconds > 0 && minT > util.TimeToMillis(now.Add(-time.Duration(r.minSeconds)*time.Second)) {
+		return false
+	}
+	return true
+}
+
+// getDataAgeRanges returns the distinct data age ranges declared by the instances within the ring,
+// sorted by min and max age, or nil if no instance declares a data age range.
+func (d *Desc) getDataAgeRanges() []dataAgeRange {
+	var ranges []dataAgeRange
+	for _, instance := range d.Ingesters {
+		r := dataAgeRange{minSeconds: instance.MinDataAgeSeconds, maxSeconds: instance.MaxDataAgeSeconds}
+		if !containsDataAgeRange(ranges, r) {
+			ranges = append(ranges, r)
+		}
+	}
+
+	// Instances not declaring a data age range serve any data.
+	if len(ranges) == 0 || (len(ranges) == 1 && ranges[0] == dataAgeRange{}) {
+		return nil
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].minSeconds != ranges[j].minSeconds {
+			return ranges[i].minSeconds < ranges[j].minSeconds
+		}
+		return ranges[i].maxSeconds < ranges[j].maxSeconds
+	})
+
+	return ranges
+}
+
+func containsDataAgeRange(ranges []dataAgeRange, r dataAgeRange) bool {
+	for _, other := range ranges {
+		if other == r {
+			return true
+		}
+	}
+	return false
+}
+
+// readOnlyStatesChanged returns whether any instance switched from or to the READONLY state,
+// assuming both rings contain the same instances. Read-only instances affect the shuffle sharding.
+func (d *Desc) readOnlyStatesChanged(o *Desc) bool {
+	if d == nil || o == nil {
+		return false
+	}
+
+	for name, ing := range d.Ingesters {
+		if oing, ok := o.Ingesters[name]; ok && (ing.State == READONLY) != (oing.State == READONLY) {
+			return true
+		}
+	}
+	return false
+}
+
 type CompareResult int
 
 const (
@@ -487,6 +566,10 @@ func (d *Desc) RingCompare(o *Desc) CompareResult {
 			return Different
 		}
 
+		if ing.MinDataAgeSeconds != oing.MinDataAgeSeconds || ing.MaxDataAgeSeconds != oing.MaxDataAgeSeconds {
+			return Different
+		}
+
 		if len(ing.Tokens) != len(oing.Tokens) {
 			return Different
 		}
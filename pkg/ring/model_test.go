@@ -45,6 +45,13 @@ func TestInstanceDesc_IsHealthy_ForIngesterOperations(t *testing.T) {
 			readExpected:   true,
 			reportExpected: true,
 		},
+		"READONLY ingester with last keepalive newer than timeout": {
+			ingester:       &InstanceDesc{State: READONLY, Timestamp: time.Now().Add(-30 * time.Second).Unix()},
+			timeout:        time.Minute,
+			writeExpected:  false,
+			readExpected:   true,
+			reportExpected: true,
+		},
 	}
 
 	for testName, testData := range tests {
@@ -93,6 +100,74 @@ func TestInstanceDesc_GetRegisteredAt(t *testing.T) {
 	}
 }
 
+func TestInstanceDesc_ServesDataRange(t *testing.T) {
+	now := time.Now()
+	day := int64(24 * time.Hour / time.Second)
+	toMillis := func(age time.Duration) int64 {
+		return now.Add(-age).UnixNano() / int64(time.Millisecond)
+	}
+
+	tests := map[string]struct {
+		desc       InstanceDesc
+		minT, maxT int64
+		expected   bool
+	}{
+		"should serve any time range if no data age range is declared": {
+			desc:     InstanceDesc{},
+			minT:     toMillis(365 * 24 * time.Hour),
+			maxT:     toMillis(0),
+			expected: true,
+		},
+		"should serve a time range within the max data age": {
+			desc:     InstanceDesc{MaxDataAgeSeconds: 14 * day},
+			minT:     toMillis(3 * 24 * time.Hour),
+			maxT:     toMillis(2 * 24 * time.Hour),
+			expected: true,
+		},
+		"should not serve a time range older than the max data age": {
+			desc:     InstanceDesc{MaxDataAgeSeconds: 14 * day},
+			minT:     toMillis(16 * 24 * time.Hour),
+			maxT:     toMillis(15 * 24 * time.Hour),
+			expected: false,
+		},
+		"should serve a time range older than the min data age": {
+			desc:     InstanceDesc{MinDataAgeSeconds: 14 * day},
+			minT:     toMillis(16 * 24 * time.Hour),
+			maxT:     toMillis(15 * 24 * time.Hour),
+			expected: true,
+		},
+		"should not serve a time range newer than the min data age": {
+			desc:     InstanceDesc{MinDataAgeSeconds: 14 * day},
+			minT:     toMillis(3 * 24 * time.Hour),
+			maxT:     toMillis(2 * 24 * time.Hour),
+			expected: false,
+		},
+		"should serve a time range overlapping the data age range": {
+			desc:     InstanceDesc{MinDataAgeSeconds: 7 * day, MaxDataAgeSeconds: 14 * day},
+			minT:     toMillis(20 * 24 * time.Hour),
+			maxT:     toMillis(10 * 24 * time.Hour),
+			expected: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, testData.desc.ServesDataRange(testData.minT, testData.maxT, now))
+		})
+	}
+}
+
+func TestDesc_getDataAgeRanges(t *testing.T) {
+	assert.Nil(t, (&Desc{Ingesters: map[string]InstanceDesc{"ing1": {}, "ing2": {}}}).getDataAgeRanges())
+
+	assert.Equal(t, []dataAgeRange{{}, {maxSeconds: 10}, {minSeconds: 10}}, (&Desc{Ingesters: map[string]InstanceDesc{
+		"ing1": {MaxDataAgeSeconds: 10},
+		"ing2": {MinDataAgeSeconds: 10},
+		"ing3": {MaxDataAgeSeconds: 10},
+		"ing4": {},
+	}}).getDataAgeRanges())
+}
+
 func normalizedSource() *Desc {
 	r := NewDesc()
 	r.Ingesters["first"] = InstanceDesc{
@@ -161,6 +236,16 @@ func TestDesc_Ready(t *testing.T) {
 	if err := r.Ready(now, 10*time.Second); err != nil {
 		t.Fatal("expected ready, got", err)
 	}
+
+	r.Ingesters["read-only ingester"] = InstanceDesc{
+		Tokens:    []uint32{23456},
+		State:     READONLY,
+		Timestamp: now.Unix(),
+	}
+
+	if err := r.Ready(now, 10*time.Second); err != nil {
+		t.Fatal("expected ready, got", err)
+	}
 }
 
 func TestDesc_getTokensByZone(t *testing.T) {
@@ -307,6 +392,11 @@ func TestDesc_RingsCompare(t *testing.T) {
 			r2:       &Desc{Ingesters: map[string]InstanceDesc{"ing1": {Addr: "addr1", Tokens: []uint32{1, 2, 4}}}},
 			expected: Different,
 		},
+		"same single instance, different data age range": {
+			r1:       &Desc{Ingesters: map[string]InstanceDesc{"ing1": {Addr: "addr1", MinDataAgeSeconds: 1}}},
+			r2:       &Desc{Ingesters: map[string]InstanceDesc{"ing1": {Addr: "addr1", MaxDataAgeSeconds: 1}}},
+			expected: Different,
+		},
 		"same number of instances, using different IDs": {
 			r1:       &Desc{Ingesters: map[string]InstanceDesc{"ing1": {Addr: "addr1", Tokens: []uint32{1, 2, 3}}}},
 			r2:       &Desc{Ingesters: map[string]InstanceDesc{"ing2": {Addr: "addr1", Tokens: []uint32{1, 2, 3}}}},
@@ -408,3 +498,20 @@ func TestMergeTokensByZone(t *testing.T) {
 		})
 	}
 }
+
+func TestDesc_readOnlyStatesChanged(t *testing.T) {
+	prev := &Desc{Ingesters: map[string]InstanceDesc{
+		"ing1": {State: ACTIVE},
+		"ing2": {State: LEAVING},
+	}}
+
+	assert.False(t, prev.readOnlyStatesChanged(&Desc{Ingesters: map[string]InstanceDesc{
+		"ing1": {State: ACTIVE},
+		"ing2": {State: ACTIVE},
+	}}))
+	assert.True(t, prev.readOnlyStatesChanged(&Desc{Ingesters: map[string]InstanceDesc{
+		"ing1": {State: READONLY},
+		"ing2": {State: LEAVING},
+	}}))
+	assert.False(t, prev.readOnlyStatesChanged(nil))
+}
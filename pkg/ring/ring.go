@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
@@ -75,6 +76,14 @@ type ReadRing interface {
 	// all instances that have been part of the identifier's shard since "now - lookbackPeriod".
 	ShuffleShardWithLookback(identifier string, size int, lookbackPeriod time.Duration, now time.Time) ReadRing
 
+	// DataRangeSubring returns a subring with only the instances serving data in the input
+	// time range (milliseconds, both included), based on the data age range of each instance.
+	DataRangeSubring(minT, maxT int64, now time.Time) ReadRing
+
+	// DataRangeChanges returns the times, within the (from, to] interval, at which the instances
+	// serving data in the input time range (milliseconds, both included) change as the data ages.
+	DataRangeChanges(minT, maxT int64, from, to time.Time) []time.Time
+
 	// HasInstance returns whether the ring contains an instance matching the provided instanceID.
 	HasInstance(instanceID string) bool
 
@@ -85,8 +94,8 @@ type ReadRing interface {
 var (
 	// Write operation that also extends replica set, if instance state is not ACTIVE.
 	Write = NewOp([]InstanceState{ACTIVE}, func(s InstanceState) bool {
-		// We do not want to Write to instances that are not ACTIVE, but we do want
-		// to write the extra replica somewhere.  So we increase the size of the set
+		// We do not want to Write to instances that are not ACTIVE (e.g. READONLY), but we do
+		// want to write the extra replica somewhere.  So we increase the size of the set
 		// of replicas for the key.
 		// NB unhealthy instances will be filtered later by defaultReplicationStrategy.Filter().
 		return s != ACTIVE
@@ -95,9 +104,11 @@ var (
 	// WriteNoExtend is like Write, but with no replicaset extension.
 	WriteNoExtend = NewOp([]InstanceState{ACTIVE}, nil)
 
-	Read = NewOp([]InstanceState{ACTIVE, PENDING, LEAVING}, func(s InstanceState) bool {
+	Read = NewOp([]InstanceState{ACTIVE, PENDING, LEAVING, READONLY}, func(s InstanceState) bool {
 		// To match Write with extended replica set we have to also increase the
 		// size of the replica set for Read, but we can read from LEAVING ingesters.
+		// READONLY ingesters are read too, but the extra replica written in place
+		// of them must be read as well.
 		return s != ACTIVE && s != LEAVING
 	})
 
@@ -183,6 +194,14 @@ type Ring struct {
 	// If set to nil, no caching is done (used by tests, and subrings).
 	shuffledSubringCache map[subringCacheKey]*Ring
 
+	// Distinct data age ranges declared by the instances in the ring. Empty if no
+	// instance declares a data age range.
+	ringDataAgeRanges []dataAgeRange
+
+	// Cache of data range subrings per set of matching data age ranges. Invalidated
+	// when topology changes.
+	dataRangeSubringCache map[string]*Ring
+
 	memberOwnershipDesc *prometheus.Desc
 	numMembersDesc      *prometheus.Desc
 	totalTokensDesc     *prometheus.Desc
@@ -295,7 +314,7 @@ func (r *Ring) updateRingState(ringDesc *Desc) {
 	r.mtx.RUnlock()
 
 	rc := prevRing.RingCompare(ringDesc)
-	if rc == Equal || rc == EqualButStatesAndTimestamps {
+	if rc == Equal || (rc == EqualButStatesAndTimestamps && !prevRing.readOnlyStatesChanged(ringDesc)) {
 		// No need to update tokens or zones. Only states and timestamps
 		// have changed. (If Equal, nothing has changed, but that doesn't happen
 		// when watching the ring for updates).
@@ -310,6 +329,7 @@ func (r *Ring) updateRingState(ringDesc *Desc) {
 	ringTokensByZone := ringDesc.getTokensByZone()
 	ringInstanceByToken := ringDesc.getTokensInfo()
 	ringZones := getZones(ringTokensByZone)
+	ringDataAgeRanges := ringDesc.getDataAgeRanges()
 
 	r.mtx.Lock()
 	defer r.mtx.Unlock()
@@ -318,6 +338,8 @@ func (r *Ring) updateRingState(ringDesc *Desc) {
 	r.ringTokensByZone = ringTokensByZone
 	r.ringInstanceByToken = ringInstanceByToken
 	r.ringZones = ringZones
+	r.ringDataAgeRanges = ringDataAgeRanges
+	r.dataRangeSubringCache = nil
 	r.lastTopologyChange = now
 	if r.shuffledSubringCache != nil {
 		// Invalidate all cached subrings.
@@ -565,7 +587,7 @@ func (r *Ring) Collect(ch chan<- prometheus.Metric) {
 	oldestTimestampByState := map[string]int64{}
 
 	// Initialised to zero so we emit zero-metrics (instead of not emitting anything)
-	for _, s := range []string{unhealthy, ACTIVE.String(), LEAVING.String(), PENDING.String(), JOINING.String()} {
+	for _, s := range []string{unhealthy, ACTIVE.String(), LEAVING.String(), PENDING.String(), JOINING.String(), READONLY.String()} {
 		numByState[s] = 0
 		oldestTimestampByState[s] = 0
 	}
@@ -728,6 +750,12 @@ func (r *Ring) shuffleShard(identifier string, size int, lookbackPeriod time.Dur
 					continue
 				}
 
+				// A read-only instance may hold data of the identifier, so we should include it in the subring,
+				// but it doesn't receive writes so we continue selecting instances.
+				if instance.State == READONLY {
+					continue
+				}
+
 				found = true
 				break
 			}
@@ -741,17 +769,23 @@ func (r *Ring) shuffleShard(identifier string, size int, lookbackPeriod time.Dur
 		}
 	}
 
-	// Build a read-only ring for the shard.
-	shardDesc := &Desc{Ingesters: shard}
+	return r.buildSubring(shard)
+}
+
+// buildSubring builds a read-only ring with the input instances, which are expected to be
+// a subset of the instances of this ring. The caller must hold the ring read lock.
+func (r *Ring) buildSubring(instances map[string]InstanceDesc) *Ring {
+	shardDesc := &Desc{Ingesters: instances}
 	shardTokensByZone := shardDesc.getTokensByZone()
 
 	return &Ring{
-		cfg:              r.cfg,
-		strategy:         r.strategy,
-		ringDesc:         shardDesc,
-		ringTokens:       shardDesc.GetTokens(),
-		ringTokensByZone: shardTokensByZone,
-		ringZones:        getZones(shardTokensByZone),
+		cfg:               r.cfg,
+		strategy:          r.strategy,
+		ringDesc:          shardDesc,
+		ringTokens:        shardDesc.GetTokens(),
+		ringTokensByZone:  shardTokensByZone,
+		ringZones:         getZones(shardTokensByZone),
+		ringDataAgeRanges: shardDesc.getDataAgeRanges(),
 
 		// We reference the original map as is in order to avoid copying. It's safe to do
 		// because this map is immutable by design and it's a superset of the actual instances
@@ -763,6 +797,98 @@ func (r *Ring) shuffleShard(identifier string, size int, lookbackPeriod time.Dur
 	}
 }
 
+// DataRangeSubring returns a subring with only the instances serving data in the input time
+// range (milliseconds, both included), based on the data age range declared by each instance.
+// Instances not declaring a data age range serve any time range, so if no instance in the ring
+// declares a data age range the ring itself is returned.
+//
+// Subrings are cached by the set of matching data age ranges, so blocks covering the same
+// tiers share the same subring.
+func (r *Ring) DataRangeSubring(minT, maxT int64, now time.Time) ReadRing {
+	r.mtx.RLock()
+
+	if len(r.ringDataAgeRanges) == 0 {
+		r.mtx.RUnlock()
+		return r
+	}
+
+	// Build the cache key from the data age ranges matching the input time range.
+	key := make([]byte, len(r.ringDataAgeRanges))
+	matchingAll := true
+	for i, ageRange := range r.ringDataAgeRanges {
+		if ageRange.servesDataRange(minT, maxT, now) {
+			key[i] = '1'
+		} else {
+			key[i] = '0'
+			matchingAll = false
+		}
+	}
+
+	if matchingAll {
+		r.mtx.RUnlock()
+		return r
+	}
+
+	if cached := r.dataRangeSubringCache[string(key)]; cached != nil {
+		r.updateCachedSubringStates(cached)
+		r.mtx.RUnlock()
+		return cached
+	}
+
+	instances := make(map[string]InstanceDesc, len(r.ringDesc.Ingesters))
+	for id, instance := range r.ringDesc.Ingesters {
+		if instance.ServesDataRange(minT, maxT, now) {
+			instances[id] = instance
+		}
+	}
+
+	subring := r.buildSubring(instances)
+	r.mtx.RUnlock()
+
+	// Only cache if *this* ring hasn't changed since computing the subring.
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.lastTopologyChange.Equal(subring.lastTopologyChange) {
+		if r.dataRangeSubringCache == nil {
+			r.dataRangeSubringCache = map[string]*Ring{}
+		}
+		r.dataRangeSubringCache[string(key)] = subring
+	}
+
+	return subring
+}
+
+// DataRangeChanges returns the times, within the (from, to] interval, at which the instances serving
+// data in the input time range (milliseconds, both included) change as the data ages, sorted by time.
+// The DataRangeSubring() returned for any time within the interval is the same as the one returned
+// for from or for the latest change before it.
+func (r *Ring) DataRangeChanges(minT, maxT int64, from, to time.Time) []time.Time {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	var changes []time.Time
+	add := func(t time.Time) {
+		if t.After(from) && !t.After(to) {
+			changes = append(changes, t)
+		}
+	}
+
+	for _, ageRange := range r.ringDataAgeRanges {
+		// The instances stop serving the data once it's older than the max age...
+		if ageRange.maxSeconds > 0 {
+			add(util.TimeFromMillis(maxT).Add(time.Duration(ageRange.maxSeconds)*time.Second + time.Millisecond))
+		}
+		// ...and start serving it once it's older than the min age.
+		if ageRange.minSeconds > 0 {
+			add(util.TimeFromMillis(minT).Add(time.Duration(ageRange.minSeconds) * time.Second))
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Before(changes[j]) })
+	return changes
+}
+
 // GetInstanceState returns the current state of an instance or an error if the
 // instance does not exist in the ring.
 func (r *Ring) GetInstanceState(instanceID string) (InstanceState, error) {
@@ -802,6 +928,13 @@ func (r *Ring) getCachedShuffledSubring(identifier string, size int) *Ring {
 		return nil
 	}
 
+	r.updateCachedSubringStates(cached)
+	return cached
+}
+
+// updateCachedSubringStates updates the instance states and timestamps of a cached subring.
+// The caller must hold the ring read lock.
+func (r *Ring) updateCachedSubringStates(cached *Ring) {
 	cached.mtx.Lock()
 	defer cached.mtx.Unlock()
 
@@ -813,7 +946,6 @@ func (r *Ring) getCachedShuffledSubring(identifier string, size int) *Ring {
 		cachedIng.Timestamp = ing.Timestamp
 		cached.ringDesc.Ingesters[name] = cachedIng
 	}
-	return cached
 }
 
 func (r *Ring) setCachedShuffledSubring(identifier string, size int, subring *Ring) {
@@ -861,7 +993,7 @@ func NewOp(healthyStates []InstanceState, shouldExtendReplicaSet func(s Instance
 	}
 
 	if shouldExtendReplicaSet != nil {
-		for _, s := range []InstanceState{ACTIVE, LEAVING, PENDING, JOINING, LEAVING, LEFT} {
+		for _, s := range []InstanceState{ACTIVE, LEAVING, PENDING, JOINING, LEAVING, LEFT, READONLY} {
 			if shouldExtendReplicaSet(s) {
 				op |= (0x10000 << s)
 			}
@@ -37,6 +37,8 @@ const (
 	// This state is only used by gossiping code to distribute information about
 	// instances that have been removed from the ring. Ring users should not use it directly.
 	LEFT InstanceState = 4
+	// The instance serves reads but doesn't accept writes, e.g. because it's being drained.
+	READONLY InstanceState = 5
 )
 
 var InstanceState_name = map[int32]string{
@@ -45,14 +47,16 @@ var InstanceState_name = map[int32]string{
 	2: "PENDING",
 	3: "JOINING",
 	4: "LEFT",
+	5: "READONLY",
 }
 
 var InstanceState_value = map[string]int32{
-	"ACTIVE":  0,
-	"LEAVING": 1,
-	"PENDING": 2,
-	"JOINING": 3,
-	"LEFT":    4,
+	"ACTIVE":   0,
+	"LEAVING":  1,
+	"PENDING":  2,
+	"JOINING":  3,
+	"LEFT":     4,
+	"READONLY": 5,
 }
 
 func (InstanceState) EnumDescriptor() ([]byte, []int) {
@@ -124,6 +128,10 @@ type InstanceDesc struct {
 	// was already registered before "now". If unknown (0), it should be left as is, and the
 	// Cortex code will properly deal with that.
 	RegisteredTimestamp int64 `protobuf:"varint,8,opt,name=registered_timestamp,json=registeredTimestamp,proto3" json:"registered_timestamp,omitempty"`
+	// Min and max age (in seconds) of the data served by the instance, used to
+	// shard data by time across instances. 0 means no limit.
+	MinDataAgeSeconds int64 `protobuf:"varint,9,opt,name=min_data_age_seconds,json=minDataAgeSeconds,proto3" json:"min_data_age_seconds,omitempty"`
+	MaxDataAgeSeconds int64 `protobuf:"varint,10,opt,name=max_data_age_seconds,json=maxDataAgeSeconds,proto3" json:"max_data_age_seconds,omitempty"`
 }
 
 func (m *InstanceDesc) Reset()      { *m = InstanceDesc{} }
@@ -200,6 +208,20 @@ func (m *InstanceDesc) GetRegisteredTimestamp() int64 {
 	return 0
 }
 
+func (m *InstanceDesc) GetMinDataAgeSeconds() int64 {
+	if m != nil {
+		return m.MinDataAgeSeconds
+	}
+	return 0
+}
+
+func (m *InstanceDesc) GetMaxDataAgeSeconds() int64 {
+	if m != nil {
+		return m.MaxDataAgeSeconds
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("ring.InstanceState", InstanceState_name, InstanceState_value)
 	proto.RegisterType((*Desc)(nil), "ring.Desc")
@@ -210,34 +232,38 @@ func init() {
 func init() { proto.RegisterFile("ring.proto", fileDescriptor_26381ed67e202a6e) }
 
 var fileDescriptor_26381ed67e202a6e = []byte{
-	// 425 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x92, 0xc1, 0x6e, 0xd3, 0x40,
-	0x18, 0x84, 0xf7, 0x8f, 0x37, 0xae, 0xf3, 0x87, 0x56, 0xd6, 0x16, 0x21, 0x53, 0xa1, 0xc5, 0xea,
-	0xc9, 0x20, 0xe1, 0x8a, 0xc0, 0x01, 0x21, 0x71, 0x68, 0xa9, 0x41, 0xb6, 0xa2, 0x50, 0x99, 0xa8,
-	0x57, 0xe4, 0x24, 0x8b, 0xb1, 0x4a, 0xec, 0xca, 0xde, 0x20, 0x95, 0x13, 0x8f, 0xc0, 0x0b, 0x70,
-	0xe7, 0x51, 0x7a, 0xcc, 0x09, 0xf5, 0x84, 0x88, 0x73, 0xe1, 0xd8, 0x47, 0x40, 0xbb, 0x6e, 0x65,
-	0x72, 0x9b, 0xcf, 0x33, 0xff, 0x8c, 0x2d, 0x19, 0xb1, 0xcc, 0xf2, 0xd4, 0x3f, 0x2f, 0x0b, 0x59,
-	0x30, 0xaa, 0xf4, 0xde, 0x93, 0x34, 0x93, 0x9f, 0x16, 0x13, 0x7f, 0x5a, 0xcc, 0x0f, 0xd2, 0x22,
-	0x2d, 0x0e, 0xb4, 0x39, 0x59, 0x7c, 0xd4, 0xa4, 0x41, 0xab, 0xe6, 0x68, 0xff, 0x07, 0x20, 0x3d,
-	0x16, 0xd5, 0x94, 0xbd, 0xc2, 0x5e, 0x96, 0xa7, 0xa2, 0x92, 0xa2, 0xac, 0x1c, 0x70, 0x0d, 0xaf,
-	0x3f, 0xb8, 0xef, 0xeb, 0x76, 0x65, 0xfb, 0xe1, 0xad, 0x17, 0xe4, 0xb2, 0xbc, 0x38, 0xa2, 0x97,
-	0xbf, 0x1f, 0x92, 0xb8, 0xbd, 0xd8, 0x3b, 0xc1, 0x9d, 0xcd, 0x08, 0xb3, 0xd1, 0x38, 0x13, 0x17,
-	0x0e, 0xb8, 0xe0, 0xf5, 0x62, 0x25, 0x99, 0x87, 0xdd, 0x2f, 0xc9, 0xe7, 0x85, 0x70, 0x3a, 0x2e,
-	0x78, 0xfd, 0x01, 0x6b, 0xea, 0xc3, 0xbc, 0x92, 0x49, 0x3e, 0x15, 0x6a, 0x26, 0x6e, 0x02, 0x2f,
-	0x3b, 0x2f, 0x20, 0xa2, 0x56, 0xc7, 0x36, 0xf6, 0x7f, 0x01, 0xde, 0xf9, 0x3f, 0xc1, 0x18, 0xd2,
-	0x64, 0x36, 0x2b, 0x6f, 0x7a, 0xb5, 0x66, 0x0f, 0xb0, 0x27, 0xb3, 0xb9, 0xa8, 0x64, 0x32, 0x3f,
-	0xd7, 0xe5, 0x46, 0xdc, 0x3e, 0x60, 0x8f, 0xb0, 0x5b, 0xc9, 0x44, 0x0a, 0xc7, 0x70, 0xc1, 0xdb,
-	0x19, 0xec, 0x6e, 0xce, 0xbe, 0x57, 0x56, 0xdc, 0x24, 0xd8, 0x3d, 0x34, 0x65, 0x71, 0x26, 0xf2,
-	0xca, 0x31, 0x5d, 0xc3, 0xdb, 0x8e, 0x6f, 0x48, 0x8d, 0x7e, 0x2d, 0x72, 0xe1, 0x6c, 0x35, 0xa3,
-	0x4a, 0xb3, 0xa7, 0x78, 0xb7, 0x14, 0x69, 0xa6, 0xbe, 0x58, 0xcc, 0x3e, 0xb4, 0xfb, 0x96, 0xde,
-	0xdf, 0x6d, 0xbd, 0xf1, 0xad, 0x15, 0x51, 0x8b, 0xda, 0xdd, 0x88, 0x5a, 0x5d, 0xdb, 0x7c, 0x3c,
-	0xc4, 0xed, 0x8d, 0x57, 0x60, 0x88, 0xe6, 0xe1, 0xeb, 0x71, 0x78, 0x1a, 0xd8, 0x84, 0xf5, 0x71,
-	0x6b, 0x18, 0x1c, 0x9e, 0x86, 0xa3, 0xb7, 0x36, 0x28, 0x38, 0x09, 0x46, 0xc7, 0x0a, 0x3a, 0x0a,
-	0xa2, 0x77, 0xe1, 0x48, 0x81, 0xc1, 0x2c, 0xa4, 0xc3, 0xe0, 0xcd, 0xd8, 0xa6, 0x47, 0xcf, 0x97,
-	0x2b, 0x4e, 0xae, 0x56, 0x9c, 0x5c, 0xaf, 0x38, 0x7c, 0xab, 0x39, 0xfc, 0xac, 0x39, 0x5c, 0xd6,
-	0x1c, 0x96, 0x35, 0x87, 0x3f, 0x35, 0x87, 0xbf, 0x35, 0x27, 0xd7, 0x35, 0x87, 0xef, 0x6b, 0x4e,
-	0x96, 0x6b, 0x4e, 0xae, 0xd6, 0x9c, 0x4c, 0x4c, 0xfd, 0x0f, 0x3c, 0xfb, 0x17, 0x00, 0x00, 0xff,
-	0xff, 0x97, 0x76, 0x41, 0xaf, 0x46, 0x02, 0x00, 0x00,
+	// 482 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0x52, 0x4f, 0x6e, 0xd3, 0x4c,
+	0x1c, 0xf5, 0xc4, 0x93, 0xd4, 0xf9, 0xa5, 0xad, 0xfc, 0x4d, 0xab, 0x4f, 0xa6, 0x42, 0x43, 0xd4,
+	0x95, 0x41, 0x22, 0x11, 0x81, 0x05, 0x42, 0x62, 0x91, 0x12, 0x83, 0x1c, 0x45, 0x69, 0xe5, 0x46,
+	0x95, 0x60, 0x13, 0x4d, 0xe2, 0xc1, 0x58, 0xc5, 0xe3, 0xca, 0x9e, 0xa0, 0x96, 0x15, 0x47, 0xe0,
+	0x02, 0xec, 0x39, 0x02, 0x47, 0xe8, 0x32, 0xcb, 0xae, 0x10, 0x71, 0x36, 0x2c, 0x7b, 0x04, 0x34,
+	0xe3, 0x40, 0x88, 0xd8, 0xbd, 0xe7, 0xf7, 0xcf, 0x3f, 0x69, 0x00, 0xb2, 0x58, 0x44, 0xad, 0x8b,
+	0x2c, 0x95, 0x29, 0xc1, 0x0a, 0x1f, 0x3c, 0x8c, 0x62, 0xf9, 0x6e, 0x36, 0x69, 0x4d, 0xd3, 0xa4,
+	0x1d, 0xa5, 0x51, 0xda, 0xd6, 0xe2, 0x64, 0xf6, 0x56, 0x33, 0x4d, 0x34, 0x2a, 0x43, 0x87, 0x5f,
+	0x10, 0xe0, 0x1e, 0xcf, 0xa7, 0xe4, 0x39, 0xd4, 0x63, 0x11, 0xf1, 0x5c, 0xf2, 0x2c, 0x77, 0x50,
+	0xd3, 0x74, 0x1b, 0x9d, 0x3b, 0x2d, 0xdd, 0xae, 0xe4, 0x96, 0xff, 0x5b, 0xf3, 0x84, 0xcc, 0xae,
+	0x8e, 0xf0, 0xf5, 0xf7, 0x7b, 0x46, 0xb0, 0x4e, 0x1c, 0x9c, 0xc0, 0xee, 0xa6, 0x85, 0xd8, 0x60,
+	0x9e, 0xf3, 0x2b, 0x07, 0x35, 0x91, 0x5b, 0x0f, 0x14, 0x24, 0x2e, 0x54, 0x3f, 0xb0, 0xf7, 0x33,
+	0xee, 0x54, 0x9a, 0xc8, 0x6d, 0x74, 0x48, 0x59, 0xef, 0x8b, 0x5c, 0x32, 0x31, 0xe5, 0x6a, 0x26,
+	0x28, 0x0d, 0xcf, 0x2a, 0x4f, 0x51, 0x1f, 0x5b, 0x15, 0xdb, 0x3c, 0xfc, 0x56, 0x81, 0xed, 0xbf,
+	0x1d, 0x84, 0x00, 0x66, 0x61, 0x98, 0xad, 0x7a, 0x35, 0x26, 0x77, 0xa1, 0x2e, 0xe3, 0x84, 0xe7,
+	0x92, 0x25, 0x17, 0xba, 0xdc, 0x0c, 0xd6, 0x1f, 0xc8, 0x7d, 0xa8, 0xe6, 0x92, 0x49, 0xee, 0x98,
+	0x4d, 0xe4, 0xee, 0x76, 0xf6, 0x36, 0x67, 0x4f, 0x95, 0x14, 0x94, 0x0e, 0xf2, 0x3f, 0xd4, 0x64,
+	0x7a, 0xce, 0x45, 0xee, 0xd4, 0x9a, 0xa6, 0xbb, 0x13, 0xac, 0x98, 0x1a, 0xfd, 0x98, 0x0a, 0xee,
+	0x6c, 0x95, 0xa3, 0x0a, 0x93, 0x47, 0xb0, 0x9f, 0xf1, 0x28, 0x56, 0x17, 0xf3, 0x70, 0xbc, 0xde,
+	0xb7, 0xf4, 0xfe, 0xde, 0x5a, 0x1b, 0xfd, 0xf9, 0x93, 0x36, 0xec, 0x27, 0xb1, 0x18, 0x87, 0x4c,
+	0xb2, 0x31, 0x8b, 0xf8, 0x38, 0xe7, 0xd3, 0x54, 0x84, 0xb9, 0x53, 0xd7, 0x91, 0xff, 0x92, 0x58,
+	0xf4, 0x98, 0x64, 0xdd, 0x88, 0x9f, 0x96, 0x82, 0x0e, 0xb0, 0xcb, 0x7f, 0x03, 0xb0, 0x0a, 0xb0,
+	0xcb, 0xcd, 0x40, 0x1f, 0x5b, 0xd8, 0xae, 0xf6, 0xb1, 0x55, 0xb5, 0x6b, 0x0f, 0xde, 0xc0, 0xce,
+	0xc6, 0x91, 0x04, 0xa0, 0xd6, 0x7d, 0x31, 0xf2, 0xcf, 0x3c, 0xdb, 0x20, 0x0d, 0xd8, 0x1a, 0x78,
+	0xdd, 0x33, 0x7f, 0xf8, 0xca, 0x46, 0x8a, 0x9c, 0x78, 0xc3, 0x9e, 0x22, 0x15, 0x45, 0xfa, 0xc7,
+	0xfe, 0x50, 0x11, 0x93, 0x58, 0x80, 0x07, 0xde, 0xcb, 0x91, 0x8d, 0xc9, 0x36, 0x58, 0x81, 0xd7,
+	0xed, 0x1d, 0x0f, 0x07, 0xaf, 0xed, 0xea, 0xd1, 0x93, 0xf9, 0x82, 0x1a, 0x37, 0x0b, 0x6a, 0xdc,
+	0x2e, 0x28, 0xfa, 0x54, 0x50, 0xf4, 0xb5, 0xa0, 0xe8, 0xba, 0xa0, 0x68, 0x5e, 0x50, 0xf4, 0xa3,
+	0xa0, 0xe8, 0x67, 0x41, 0x8d, 0xdb, 0x82, 0xa2, 0xcf, 0x4b, 0x6a, 0xcc, 0x97, 0xd4, 0xb8, 0x59,
+	0x52, 0x63, 0x52, 0xd3, 0x6f, 0xee, 0xf1, 0xaf, 0x01, 0x00, 0x75, 0x6c, 0xa0, 0xab, 0xb6, 0x02,
+	0x00, 0x00,
 }
 
 func (x InstanceState) String() string {
@@ -320,6 +346,12 @@ func (this *InstanceDesc) Equal(that interface{}) bool {
 	if this.RegisteredTimestamp != that1.RegisteredTimestamp {
 		return false
 	}
+	if this.MinDataAgeSeconds != that1.MinDataAgeSeconds {
+		return false
+	}
+	if this.MaxDataAgeSeconds != that1.MaxDataAgeSeconds {
+		return false
+	}
 	return true
 }
 func (this *Desc) GoString() string {
@@ -348,7 +380,7 @@ func (this *InstanceDesc) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 10)
+	s := make([]string, 0, 12)
 	s = append(s, "&ring.InstanceDesc{")
 	s = append(s, "Addr: "+fmt.Sprintf("%#v", this.Addr)+",\n")
 	s = append(s, "Timestamp: "+fmt.Sprintf("%#v", this.Timestamp)+",\n")
@@ -356,6 +388,8 @@ func (this *InstanceDesc) GoString() string {
 	s = append(s, "Tokens: "+fmt.Sprintf("%#v", this.Tokens)+",\n")
 	s = append(s, "Zone: "+fmt.Sprintf("%#v", this.Zone)+",\n")
 	s = append(s, "RegisteredTimestamp: "+fmt.Sprintf("%#v", this.RegisteredTimestamp)+",\n")
+	s = append(s, "MinDataAgeSeconds: "+fmt.Sprintf("%#v", this.MinDataAgeSeconds)+",\n")
+	s = append(s, "MaxDataAgeSeconds: "+fmt.Sprintf("%#v", this.MaxDataAgeSeconds)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -434,6 +468,16 @@ func (m *InstanceDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.MaxDataAgeSeconds != 0 {
+		i = encodeVarintRing(dAtA, i, uint64(m.MaxDataAgeSeconds))
+		i--
+		dAtA[i] = 0x50
+	}
+	if m.MinDataAgeSeconds != 0 {
+		i = encodeVarintRing(dAtA, i, uint64(m.MinDataAgeSeconds))
+		i--
+		dAtA[i] = 0x48
+	}
 	if m.RegisteredTimestamp != 0 {
 		i = encodeVarintRing(dAtA, i, uint64(m.RegisteredTimestamp))
 		i--
@@ -543,6 +587,12 @@ func (m *InstanceDesc) Size() (n int) {
 	if m.RegisteredTimestamp != 0 {
 		n += 1 + sovRing(uint64(m.RegisteredTimestamp))
 	}
+	if m.MinDataAgeSeconds != 0 {
+		n += 1 + sovRing(uint64(m.MinDataAgeSeconds))
+	}
+	if m.MaxDataAgeSeconds != 0 {
+		n += 1 + sovRing(uint64(m.MaxDataAgeSeconds))
+	}
 	return n
 }
 
@@ -583,6 +633,8 @@ func (this *InstanceDesc) String() string {
 		`Tokens:` + fmt.Sprintf("%v", this.Tokens) + `,`,
 		`Zone:` + fmt.Sprintf("%v", this.Zone) + `,`,
 		`RegisteredTimestamp:` + fmt.Sprintf("%v", this.RegisteredTimestamp) + `,`,
+		`MinDataAgeSeconds:` + fmt.Sprintf("%v", this.MinDataAgeSeconds) + `,`,
+		`MaxDataAgeSeconds:` + fmt.Sprintf("%v", this.MaxDataAgeSeconds) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1003,6 +1055,44 @@ func (m *InstanceDesc) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MinDataAgeSeconds", wireType)
+			}
+			m.MinDataAgeSeconds = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRing
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MinDataAgeSeconds |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxDataAgeSeconds", wireType)
+			}
+			m.MaxDataAgeSeconds = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRing
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxDataAgeSeconds |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRing(dAtA[iNdEx:])
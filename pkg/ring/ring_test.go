@@ -209,6 +209,55 @@ func TestRing_Get_ZoneAwarenessWithIngesterLeaving(t *testing.T) {
 	}
 }
 
+func TestRing_Get_ShouldNotWriteToReadOnlyInstances(t *testing.T) {
+	const testCount = 10000
+
+	r := NewDesc()
+	var prevTokens []uint32
+	for id, state := range map[string]InstanceState{"instance-1": ACTIVE, "instance-2": ACTIVE, "instance-3": READONLY, "instance-4": ACTIVE, "instance-5": ACTIVE} {
+		ingTokens := GenerateTokens(128, prevTokens)
+		r.AddIngester(id, id, "", ingTokens, state, time.Now())
+		prevTokens = append(prevTokens, ingTokens...)
+	}
+
+	ring := Ring{
+		cfg: Config{
+			HeartbeatTimeout:  time.Hour,
+			ReplicationFactor: 3,
+		},
+		ringDesc:            r,
+		ringTokens:          r.GetTokens(),
+		ringTokensByZone:    r.getTokensByZone(),
+		ringInstanceByToken: r.getTokensInfo(),
+		ringZones:           getZones(r.getTokensByZone()),
+		strategy:            NewDefaultReplicationStrategy(),
+	}
+
+	bufDescs, bufHosts, bufZones := MakeBuffersForGet()
+
+	// Use the GenerateTokens to get an array of random uint32 values.
+	testValues := GenerateTokens(testCount, nil)
+
+	for i := 0; i < testCount; i++ {
+		writeSet, err := ring.Get(testValues[i], Write, bufDescs, bufHosts, bufZones)
+		require.NoError(t, err)
+		assert.Len(t, writeSet.Instances, 3)
+		assert.False(t, writeSet.Includes("instance-3"))
+
+		// The read-only instance is read, in addition to the instances written in place of it.
+		readSet, err := ring.Get(testValues[i], Read, bufDescs, bufHosts, bufZones)
+		require.NoError(t, err)
+		for _, addr := range writeSet.GetAddresses() {
+			assert.True(t, readSet.Includes(addr))
+		}
+		if readSet.Includes("instance-3") {
+			assert.Len(t, readSet.Instances, 4)
+		} else {
+			assert.Len(t, readSet.Instances, 3)
+		}
+	}
+}
+
 func TestRing_Get_ZoneAwareness(t *testing.T) {
 	// Number of tests to run.
 	const testCount = 10000
@@ -1463,6 +1512,18 @@ func TestRing_ShuffleShardWithLookback(t *testing.T) {
 				{what: test, shardSize: 2, expected: []string{"instance-1", "instance-2" /* lookback: */, "instance-3" /* side effect:*/, "instance-4"}},
 			},
 		},
+		"single zone, shard size = 1, instance switched to read-only": {
+			timeline: []event{
+				{what: add, instanceID: "instance-1", instanceDesc: generateRingInstanceWithInfo("instance-1", "zone-a", []uint32{userToken(userID, "zone-a", 0) + 1}, now.Add(-2*lookbackPeriod))},
+				{what: add, instanceID: "instance-2", instanceDesc: generateRingInstanceWithInfo("instance-2", "zone-a", []uint32{userToken(userID, "zone-a", 1) + 1}, now.Add(-2*lookbackPeriod))},
+				{what: add, instanceID: "instance-3", instanceDesc: generateRingInstanceWithInfo("instance-3", "zone-a", []uint32{userToken(userID, "zone-a", 2) + 1}, now.Add(-2*lookbackPeriod))},
+				{what: test, shardSize: 1, expected: []string{"instance-1"}},
+				{what: add, instanceID: "instance-1", instanceDesc: withState(generateRingInstanceWithInfo("instance-1", "zone-a", []uint32{userToken(userID, "zone-a", 0) + 1}, now.Add(-2*lookbackPeriod)), READONLY)},
+				{what: test, shardSize: 1, expected: []string{"instance-3" /* read-only: */, "instance-1"}},
+				{what: add, instanceID: "instance-1", instanceDesc: generateRingInstanceWithInfo("instance-1", "zone-a", []uint32{userToken(userID, "zone-a", 0) + 1}, now.Add(-2*lookbackPeriod))},
+				{what: test, shardSize: 1, expected: []string{"instance-1"}},
+			},
+		},
 		"single zone, increase shard size": {
 			timeline: []event{
 				{what: add, instanceID: "instance-1", instanceDesc: generateRingInstanceWithInfo("instance-1", "zone-a", []uint32{userToken(userID, "zone-a", 0) + 1}, now.Add(-2*lookbackPeriod))},
@@ -1818,6 +1879,122 @@ func BenchmarkRing_Get(b *testing.B) {
 	}
 }
 
+func TestRing_DataRangeSubring(t *testing.T) {
+	now := time.Now()
+	registeredAt := now.Add(-time.Hour)
+	maxAge := int64((14 * 24 * time.Hour).Seconds())
+	toMillis := func(age time.Duration) int64 {
+		return util.TimeToMillis(now.Add(-age))
+	}
+
+	withDataAgeRange := func(instance InstanceDesc, minSeconds, maxSeconds int64) InstanceDesc {
+		instance.MinDataAgeSeconds = minSeconds
+		instance.MaxDataAgeSeconds = maxSeconds
+		return instance
+	}
+
+	t.Run("should return the ring itself if no instance declares a data age range", func(t *testing.T) {
+		ring := Ring{cfg: Config{HeartbeatTimeout: time.Hour, ReplicationFactor: 1}, strategy: NewDefaultReplicationStrategy()}
+		ring.updateRingState(&Desc{Ingesters: map[string]InstanceDesc{
+			"instance-1": generateRingInstanceWithInfo("instance-1", "", []uint32{1}, registeredAt),
+			"instance-2": generateRingInstanceWithInfo("instance-2", "", []uint32{2}, registeredAt),
+		}})
+
+		assert.True(t, ring.DataRangeSubring(toMillis(30*24*time.Hour), toMillis(0), now) == ReadRing(&ring))
+	})
+
+	t.Run("should only include the instances serving the input time range", func(t *testing.T) {
+		newDesc := func(hotState InstanceState, hotMaxAge int64) *Desc {
+			return &Desc{Ingesters: map[string]InstanceDesc{
+				"instance-hot":  withDataAgeRange(withState(generateRingInstanceWithInfo("instance-hot", "", []uint32{1}, registeredAt), hotState), 0, hotMaxAge),
+				"instance-cold": withDataAgeRange(generateRingInstanceWithInfo("instance-cold", "", []uint32{2}, registeredAt), maxAge, 0),
+				"instance-any":  generateRingInstanceWithInfo("instance-any", "", []uint32{3}, registeredAt),
+			}}
+		}
+
+		ring := Ring{cfg: Config{HeartbeatTimeout: time.Hour, ReplicationFactor: 1}, strategy: NewDefaultReplicationStrategy()}
+		ring.updateRingState(newDesc(ACTIVE, maxAge))
+
+		getInstances := func(r ReadRing) []string {
+			set, err := r.GetAllHealthy(Read)
+			require.NoError(t, err)
+
+			addrs := set.GetAddresses()
+			sort.Strings(addrs)
+			return addrs
+		}
+
+		// Recent data.
+		recent := ring.DataRangeSubring(toMillis(3*time.Hour), toMillis(time.Hour), now)
+		assert.Equal(t, []string{"instance-any", "instance-hot"}, getInstances(recent))
+
+		// Old data.
+		old := ring.DataRangeSubring(toMillis(30*24*time.Hour), toMillis(29*24*time.Hour), now)
+		assert.Equal(t, []string{"instance-any", "instance-cold"}, getInstances(old))
+
+		// Data overlapping both tiers.
+		assert.True(t, ring.DataRangeSubring(toMillis(15*24*time.Hour), toMillis(13*24*time.Hour), now) == ReadRing(&ring))
+
+		// The subring should be cached per matching data age ranges.
+		assert.True(t, recent == ring.DataRangeSubring(toMillis(5*time.Hour), toMillis(4*time.Hour), now))
+
+		// Cached subrings should reflect the latest instance states.
+		ring.updateRingState(newDesc(LEAVING, maxAge))
+
+		cached := ring.DataRangeSubring(toMillis(3*time.Hour), toMillis(time.Hour), now)
+		require.True(t, recent == cached)
+		set, err := cached.GetAllHealthy(WriteNoExtend)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"instance-any"}, set.GetAddresses())
+
+		// The cache should be invalidated when the data age range of an instance changes.
+		ring.updateRingState(newDesc(LEAVING, 0))
+
+		assert.True(t, ring.DataRangeSubring(toMillis(30*24*time.Hour), toMillis(29*24*time.Hour), now) == ReadRing(&ring))
+		assert.False(t, recent == ring.DataRangeSubring(toMillis(3*time.Hour), toMillis(time.Hour), now))
+	})
+}
+
+func TestRing_DataRangeChanges(t *testing.T) {
+	now := time.Now()
+	registeredAt := now.Add(-time.Hour)
+	boundary := 14 * 24 * time.Hour
+	toMillis := func(age time.Duration) int64 {
+		return util.TimeToMillis(now.Add(-age))
+	}
+
+	hot := generateRingInstanceWithInfo("instance-hot", "", []uint32{1}, registeredAt)
+	hot.MaxDataAgeSeconds = int64(boundary.Seconds())
+	cold := generateRingInstanceWithInfo("instance-cold", "", []uint32{2}, registeredAt)
+	cold.MinDataAgeSeconds = int64(boundary.Seconds())
+
+	ring := Ring{cfg: Config{HeartbeatTimeout: time.Hour, ReplicationFactor: 1}, strategy: NewDefaultReplicationStrategy()}
+	ring.updateRingState(&Desc{Ingesters: map[string]InstanceDesc{"instance-hot": hot, "instance-cold": cold}})
+
+	minT, maxT := toMillis(boundary-time.Hour), toMillis(boundary-2*time.Hour)
+	from, to := now, now.Add(3*time.Hour)
+
+	// The cold instance starts serving the data in 1h, and the hot one stops serving it in 2h.
+	changes := ring.DataRangeChanges(minT, maxT, from, to)
+	require.Len(t, changes, 2)
+	assert.Equal(t, util.TimeFromMillis(minT).Add(boundary), changes[0])
+	assert.Equal(t, util.TimeFromMillis(maxT).Add(boundary+time.Millisecond), changes[1])
+
+	assert.False(t, ring.DataRangeSubring(minT, maxT, changes[0].Add(-time.Millisecond)).HasInstance("instance-cold"))
+	assert.True(t, ring.DataRangeSubring(minT, maxT, changes[0]).HasInstance("instance-cold"))
+	assert.True(t, ring.DataRangeSubring(minT, maxT, changes[1].Add(-time.Millisecond)).HasInstance("instance-hot"))
+	assert.False(t, ring.DataRangeSubring(minT, maxT, changes[1]).HasInstance("instance-hot"))
+
+	// Changes out of the interval are not returned.
+	assert.Empty(t, ring.DataRangeChanges(minT, maxT, from, now.Add(30*time.Minute)))
+
+	// There are no changes if no instance declares a data age range.
+	ring.updateRingState(&Desc{Ingesters: map[string]InstanceDesc{
+		"instance-1": generateRingInstanceWithInfo("instance-1", "", []uint32{1}, registeredAt),
+	}})
+	assert.Empty(t, ring.DataRangeChanges(minT, maxT, from, to))
+}
+
 func TestRing_Get_NoMemoryAllocations(t *testing.T) {
 	// Initialise the ring.
 	ringDesc := &Desc{Ingesters: generateRingInstances(3, 3, 128)}
@@ -1888,6 +2065,11 @@ func generateRingInstanceWithInfo(addr, zone string, tokens []uint32, registered
 	}
 }
 
+func withState(instance InstanceDesc, state InstanceState) InstanceDesc {
+	instance.State = state
+	return instance
+}
+
 // compareReplicationSets returns the list of instance addresses which differ between the two sets.
 func compareReplicationSets(first, second ReplicationSet) (added, removed []string) {
 	for _, instance := range first.Instances {
@@ -0,0 +1,199 @@
+package tsdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+	"github.com/cortexproject/cortex/pkg/ingester/client"
+	util_log "github.com/cortexproject/cortex/pkg/util/log"
+)
+
+// BlockExemplarsFilename is the name of the file, stored in the block directory, holding the
+// exemplars of the block series. Exemplars are not part of the TSDB block format, so the file
+// is written by Cortex and its absence means the block has no exemplars.
+const BlockExemplarsFilename = "exemplars.pb.gz"
+
+// BlockExemplarsPath returns the path of the exemplars file of the input block, relative to the user-specific prefix.
+func BlockExemplarsPath(blockID ulid.ULID) string {
+	return path.Join(blockID.String(), BlockExemplarsFilename)
+}
+
+// EncodeBlockExemplars encodes the input series exemplars in the format of the block exemplars file.
+func EncodeBlockExemplars(series []cortexpb.TimeSeries) ([]byte, error) {
+	data, err := (&client.ExemplarQueryResponse{Timeseries: series}).Marshal()
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal block exemplars")
+	}
+
+	buf := bytes.Buffer{}
+	gzw := gzip.NewWriter(&buf)
+	if _, err := gzw.Write(data); err != nil {
+		return nil, errors.Wrap(err, "compress block exemplars")
+	}
+	if err := gzw.Close(); err != nil {
+		return nil, errors.Wrap(err, "compress block exemplars")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DecodeBlockExemplars decodes the series exemplars from the content of a block exemplars file.
+func DecodeBlockExemplars(data []byte) ([]cortexpb.TimeSeries, error) {
+	gzr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "decompress block exemplars")
+	}
+
+	raw, err := ioutil.ReadAll(gzr)
+	if err != nil {
+		return nil, errors.Wrap(err, "decompress block exemplars")
+	}
+
+	res := &client.ExemplarQueryResponse{}
+	if err := res.Unmarshal(raw); err != nil {
+		return nil, errors.Wrap(err, "unmarshal block exemplars")
+	}
+
+	return res.Timeseries, nil
+}
+
+// WriteBlockExemplarsFile writes the exemplars file in the input block directory.
+func WriteBlockExemplarsFile(blockDir string, series []cortexpb.TimeSeries) error {
+	data, err := EncodeBlockExemplars(series)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that a partially written file is never read.
+	dst := filepath.Join(blockDir, BlockExemplarsFilename)
+	tmp := dst + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, 0666); err != nil {
+		return errors.Wrap(err, "write block exemplars file")
+	}
+
+	return errors.Wrap(os.Rename(tmp, dst), "rename block exemplars file")
+}
+
+// ReadBlockExemplarsFile reads the exemplars file from the input block directory. If the
+// file doesn't exist, returns false and no error.
+func ReadBlockExemplarsFile(blockDir string) ([]cortexpb.TimeSeries, bool, error) {
+	data, err := ioutil.ReadFile(filepath.Join(blockDir, BlockExemplarsFilename))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "read block exemplars file")
+	}
+
+	series, err := DecodeBlockExemplars(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return series, true, nil
+}
+
+// UploadBlockExemplars uploads the exemplars file of the input block to the user bucket.
+func UploadBlockExemplars(ctx context.Context, bkt objstore.Bucket, blockID ulid.ULID, series []cortexpb.TimeSeries) error {
+	data, err := EncodeBlockExemplars(series)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(bkt.Upload(ctx, BlockExemplarsPath(blockID), bytes.NewReader(data)), "upload block exemplars")
+}
+
+// ReadBlockExemplars reads the exemplars file of the input block from the user bucket. If the
+// block has no exemplars file, returns no exemplars and no error.
+func ReadBlockExemplars(ctx context.Context, bkt objstore.BucketReader, blockID ulid.ULID) ([]cortexpb.TimeSeries, error) {
+	r, err := bkt.Get(ctx, BlockExemplarsPath(blockID))
+	if bkt.IsObjNotFoundErr(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "read block exemplars: %s", blockID.String())
+	}
+
+	data, err := ioutil.ReadAll(r)
+
+	// Close reader before dealing with read error.
+	if closeErr := r.Close(); closeErr != nil {
+		level.Warn(util_log.Logger).Log("msg", "failed to close bucket reader", "err", closeErr)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "read block exemplars: %s", blockID.String())
+	}
+
+	return DecodeBlockExemplars(data)
+}
+
+// MergeExemplarSeries merges the exemplars of the input sets of series. The returned series are
+// sorted by labels, and their exemplars are sorted by timestamp and deduplicated.
+func MergeExemplarSeries(sets ...[]cortexpb.TimeSeries) []cortexpb.TimeSeries {
+	byLabels := map[string]*cortexpb.TimeSeries{}
+
+	for _, set := range sets {
+		for _, s := range set {
+			key := cortexpb.FromLabelAdaptersToLabels(s.Labels).String()
+
+			if merged, ok := byLabels[key]; ok {
+				merged.Exemplars = append(merged.Exemplars, s.Exemplars...)
+				continue
+			}
+
+			byLabels[key] = &cortexpb.TimeSeries{
+				Labels:    s.Labels,
+				Exemplars: append([]cortexpb.Exemplar(nil), s.Exemplars...),
+			}
+		}
+	}
+
+	res := make([]cortexpb.TimeSeries, 0, len(byLabels))
+	for _, s := range byLabels {
+		s.Exemplars = dedupeExemplars(s.Exemplars)
+		res = append(res, *s)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return labels.Compare(cortexpb.FromLabelAdaptersToLabels(res[i].Labels), cortexpb.FromLabelAdaptersToLabels(res[j].Labels)) < 0
+	})
+
+	return res
+}
+
+// dedupeExemplars sorts the input exemplars by timestamp and removes the duplicated ones.
+func dedupeExemplars(exemplars []cortexpb.Exemplar) []cortexpb.Exemplar {
+	sort.SliceStable(exemplars, func(i, j int) bool {
+		return exemplars[i].TimestampMs < exemplars[j].TimestampMs
+	})
+
+	res := exemplars[:0]
+
+Outer:
+	for _, e := range exemplars {
+		// Compare with the exemplars having the same timestamp.
+		for i := len(res) - 1; i >= 0 && res[i].TimestampMs == e.TimestampMs; i-- {
+			if res[i].Equal(e) {
+				continue Outer
+			}
+		}
+		res = append(res, e)
+	}
+
+	return res
+}
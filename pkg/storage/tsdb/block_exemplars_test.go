@@ -0,0 +1,121 @@
+package tsdb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/objstore"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+)
+
+func TestBlockExemplars_UploadAndRead(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	blockID := ulid.MustNew(1, nil)
+
+	// Reading the exemplars of a block without exemplars file should not fail.
+	actual, err := ReadBlockExemplars(ctx, bkt, blockID)
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+
+	series := []cortexpb.TimeSeries{
+		{
+			Labels:    []cortexpb.LabelAdapter{{Name: "__name__", Value: "series_1"}},
+			Exemplars: []cortexpb.Exemplar{{Labels: []cortexpb.LabelAdapter{{Name: "traceID", Value: "123"}}, Value: 1, TimestampMs: 10}},
+		},
+	}
+
+	require.NoError(t, UploadBlockExemplars(ctx, bkt, blockID, series))
+
+	actual, err = ReadBlockExemplars(ctx, bkt, blockID)
+	require.NoError(t, err)
+	assert.Equal(t, series, actual)
+}
+
+func TestBlockExemplars_WriteAndReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "block-exemplars")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, os.RemoveAll(dir))
+	})
+
+	// Reading the exemplars file of a block without exemplars should not fail.
+	actual, ok, err := ReadBlockExemplarsFile(dir)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Empty(t, actual)
+
+	series := []cortexpb.TimeSeries{
+		{
+			Labels:    []cortexpb.LabelAdapter{{Name: "__name__", Value: "series_1"}},
+			Exemplars: []cortexpb.Exemplar{{Labels: []cortexpb.LabelAdapter{{Name: "traceID", Value: "123"}}, Value: 1, TimestampMs: 10}},
+		},
+	}
+
+	require.NoError(t, WriteBlockExemplarsFile(dir, series))
+
+	actual, ok, err = ReadBlockExemplarsFile(dir)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, series, actual)
+}
+
+func TestMergeExemplarSeries(t *testing.T) {
+	series1 := []cortexpb.LabelAdapter{{Name: "__name__", Value: "series_1"}}
+	series2 := []cortexpb.LabelAdapter{{Name: "__name__", Value: "series_2"}}
+	trace1 := []cortexpb.LabelAdapter{{Name: "traceID", Value: "1"}}
+	trace2 := []cortexpb.LabelAdapter{{Name: "traceID", Value: "2"}}
+
+	tests := map[string]struct {
+		input    [][]cortexpb.TimeSeries
+		expected []cortexpb.TimeSeries
+	}{
+		"no sets": {
+			input:    nil,
+			expected: []cortexpb.TimeSeries{},
+		},
+		"series are sorted by labels": {
+			input: [][]cortexpb.TimeSeries{
+				{{Labels: series2, Exemplars: []cortexpb.Exemplar{{Labels: trace1, Value: 1, TimestampMs: 10}}}},
+				{{Labels: series1, Exemplars: []cortexpb.Exemplar{{Labels: trace1, Value: 1, TimestampMs: 10}}}},
+			},
+			expected: []cortexpb.TimeSeries{
+				{Labels: series1, Exemplars: []cortexpb.Exemplar{{Labels: trace1, Value: 1, TimestampMs: 10}}},
+				{Labels: series2, Exemplars: []cortexpb.Exemplar{{Labels: trace1, Value: 1, TimestampMs: 10}}},
+			},
+		},
+		"exemplars of the same series are sorted by timestamp and deduplicated": {
+			input: [][]cortexpb.TimeSeries{
+				{{Labels: series1, Exemplars: []cortexpb.Exemplar{
+					{Labels: trace1, Value: 1, TimestampMs: 10},
+					{Labels: trace2, Value: 3, TimestampMs: 30},
+				}}},
+				{{Labels: series1, Exemplars: []cortexpb.Exemplar{
+					{Labels: trace2, Value: 2, TimestampMs: 20},
+					{Labels: trace1, Value: 1, TimestampMs: 10},
+					{Labels: trace2, Value: 1, TimestampMs: 10},
+				}}},
+			},
+			expected: []cortexpb.TimeSeries{
+				{Labels: series1, Exemplars: []cortexpb.Exemplar{
+					{Labels: trace1, Value: 1, TimestampMs: 10},
+					{Labels: trace2, Value: 1, TimestampMs: 10},
+					{Labels: trace2, Value: 2, TimestampMs: 20},
+					{Labels: trace2, Value: 3, TimestampMs: 30},
+				}},
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, testData.expected, MergeExemplarSeries(testData.input...))
+		})
+	}
+}
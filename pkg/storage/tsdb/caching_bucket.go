@@ -1,6 +1,7 @@
 package tsdb
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"path/filepath"
@@ -19,20 +20,26 @@ import (
 	"github.com/thanos-io/thanos/pkg/cacheutil"
 	"github.com/thanos-io/thanos/pkg/objstore"
 	storecache "github.com/thanos-io/thanos/pkg/store/cache"
+
+	"github.com/cortexproject/cortex/pkg/util"
 )
 
 const (
 	CacheBackendMemcached = "memcached"
+	CacheBackendRedis     = "redis"
 )
 
+var supportedCacheBackends = []string{CacheBackendMemcached, CacheBackendRedis}
+
 type CacheBackend struct {
 	Backend   string                `yaml:"backend"`
 	Memcached MemcachedClientConfig `yaml:"memcached"`
+	Redis     RedisClientConfig     `yaml:"redis"`
 }
 
 // Validate the config.
 func (cfg *CacheBackend) Validate() error {
-	if cfg.Backend != "" && cfg.Backend != CacheBackendMemcached {
+	if cfg.Backend != "" && !util.StringsContain(supportedCacheBackends, cfg.Backend) {
 		return fmt.Errorf("unsupported cache backend: %s", cfg.Backend)
 	}
 
@@ -42,11 +49,18 @@ func (cfg *CacheBackend) Validate() error {
 		}
 	}
 
+	if cfg.Backend == CacheBackendRedis {
+		if err := cfg.Redis.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 type ChunksCacheConfig struct {
 	CacheBackend `yaml:",inline"`
+	Disk         DiskCacheConfig `yaml:"disk"`
 
 	SubrangeSize        int64         `yaml:"subrange_size"`
 	MaxGetRangeRequests int           `yaml:"max_get_range_requests"`
@@ -55,9 +69,11 @@ type ChunksCacheConfig struct {
 }
 
 func (cfg *ChunksCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
-	f.StringVar(&cfg.Backend, prefix+"backend", "", fmt.Sprintf("Backend for chunks cache, if not empty. Supported values: %s.", CacheBackendMemcached))
+	f.StringVar(&cfg.Backend, prefix+"backend", "", fmt.Sprintf("Backend for chunks cache, if not empty. Supported values: %s.", strings.Join(supportedCacheBackends, ", ")))
 
 	cfg.Memcached.RegisterFlagsWithPrefix(f, prefix+"memcached.")
+	cfg.Redis.RegisterFlagsWithPrefix(f, prefix)
+	cfg.Disk.RegisterFlagsWithPrefix(f, prefix)
 
 	f.Int64Var(&cfg.SubrangeSize, prefix+"subrange-size", 16000, "Size of each subrange that bucket object is split into for better caching.")
 	f.IntVar(&cfg.MaxGetRangeRequests, prefix+"max-get-range-requests", 3, "Maximum number of sub-GetRange requests that a single GetRange request can be split into when fetching chunks. Zero or negative value = unlimited number of sub-requests.")
@@ -66,6 +82,10 @@ func (cfg *ChunksCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix st
 }
 
 func (cfg *ChunksCacheConfig) Validate() error {
+	if err := cfg.Disk.Validate(); err != nil {
+		return err
+	}
+
 	return cfg.CacheBackend.Validate()
 }
 
@@ -86,9 +106,10 @@ type MetadataCacheConfig struct {
 }
 
 func (cfg *MetadataCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
-	f.StringVar(&cfg.Backend, prefix+"backend", "", fmt.Sprintf("Backend for metadata cache, if not empty. Supported values: %s.", CacheBackendMemcached))
+	f.StringVar(&cfg.Backend, prefix+"backend", "", fmt.Sprintf("Backend for metadata cache, if not empty. Supported values: %s.", strings.Join(supportedCacheBackends, ", ")))
 
 	cfg.Memcached.RegisterFlagsWithPrefix(f, prefix+"memcached.")
+	cfg.Redis.RegisterFlagsWithPrefix(f, prefix)
 
 	f.DurationVar(&cfg.TenantsListTTL, prefix+"tenants-list-ttl", 15*time.Minute, "How long to cache list of tenants in the bucket.")
 	f.DurationVar(&cfg.TenantBlocksListTTL, prefix+"tenant-blocks-list-ttl", 5*time.Minute, "How long to cache list of blocks for each tenant.")
@@ -107,11 +128,14 @@ func (cfg *MetadataCacheConfig) Validate() error {
 	return cfg.CacheBackend.Validate()
 }
 
-func CreateCachingBucket(chunksConfig ChunksCacheConfig, metadataConfig MetadataCacheConfig, bkt objstore.Bucket, logger log.Logger, reg prometheus.Registerer) (objstore.Bucket, error) {
+// CreateCachingBucket wraps the input bucket with the configured caches. The on-disk chunks cache, if enabled,
+// stores its entries in a subdirectory named after the input component, so that multiple components running
+// in the same process don't share the same directory, and it's stopped once the returned bucket is closed.
+func CreateCachingBucket(component string, chunksConfig ChunksCacheConfig, metadataConfig MetadataCacheConfig, bkt objstore.Bucket, logger log.Logger, reg prometheus.Registerer) (objstore.Bucket, error) {
 	cfg := storecache.NewCachingBucketConfig()
 	cachingConfigured := false
 
-	chunksCache, err := createCache("chunks-cache", chunksConfig.Backend, chunksConfig.Memcached, logger, reg)
+	chunksCache, disk, err := createChunksCache("chunks-cache", component, chunksConfig, logger, reg)
 	if err != nil {
 		return nil, errors.Wrapf(err, "chunks-cache")
 	}
@@ -121,7 +145,7 @@ func CreateCachingBucket(chunksConfig ChunksCacheConfig, metadataConfig Metadata
 		cfg.CacheGetRange("chunks", chunksCache, isTSDBChunkFile, chunksConfig.SubrangeSize, chunksConfig.AttributesTTL, chunksConfig.SubrangeTTL, chunksConfig.MaxGetRangeRequests)
 	}
 
-	metadataCache, err := createCache("metadata-cache", metadataConfig.Backend, metadataConfig.Memcached, logger, reg)
+	metadataCache, err := createCache("metadata-cache", metadataConfig.CacheBackend, logger, reg)
 	if err != nil {
 		return nil, errors.Wrapf(err, "metadata-cache")
 	}
@@ -146,25 +170,130 @@ func CreateCachingBucket(chunksConfig ChunksCacheConfig, metadataConfig Metadata
 		return bkt, nil
 	}
 
-	return storecache.NewCachingBucket(bkt, cfg, logger, reg)
+	cachingBucket, err := storecache.NewCachingBucket(bkt, cfg, logger, reg)
+	if err != nil {
+		if disk != nil {
+			disk.Stop()
+		}
+		return nil, err
+	}
+
+	if disk == nil {
+		return cachingBucket, nil
+	}
+	return &diskCacheStoppingBucket{Bucket: cachingBucket, disk: disk}, nil
+}
+
+// createChunksCache creates the chunks cache. When the disk cache is enabled, it's used as
+// first-level cache in front of the configured backend (if any), and it's returned as well
+// because it needs to be stopped once not used anymore.
+func createChunksCache(cacheName, component string, cfg ChunksCacheConfig, logger log.Logger, reg prometheus.Registerer) (cache.Cache, *diskCache, error) {
+	backend, err := createCache(cacheName, cfg.CacheBackend, logger, reg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.Disk.Directory == "" {
+		return backend, nil, nil
+	}
+
+	diskCfg := cfg.Disk
+	diskCfg.Directory = filepath.Join(cfg.Disk.Directory, component)
+
+	disk, err := newDiskCache(cacheName, diskCfg, logger, reg)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to create disk cache")
+	}
+
+	if backend == nil {
+		return disk, disk, nil
+	}
+	return newTieredCache(disk, backend), disk, nil
 }
 
-func createCache(cacheName string, backend string, memcached MemcachedClientConfig, logger log.Logger, reg prometheus.Registerer) (cache.Cache, error) {
-	switch backend {
+// diskCacheStoppingBucket is an objstore.Bucket which stops the on-disk cache once closed.
+type diskCacheStoppingBucket struct {
+	objstore.Bucket
+
+	disk *diskCache
+}
+
+// Close implements objstore.Bucket.
+func (b *diskCacheStoppingBucket) Close() error {
+	b.disk.Stop()
+	return b.Bucket.Close()
+}
+
+// tieredCache is a Thanos cache.Cache looking up the keys in the first-level cache,
+// and the missing ones in the second-level cache. The second-level cache hits are
+// stored in the first-level cache.
+type tieredCache struct {
+	first  cache.Cache
+	second cache.Cache
+}
+
+func newTieredCache(first, second cache.Cache) *tieredCache {
+	return &tieredCache{first: first, second: second}
+}
+
+// Store implements cache.Cache.
+func (c *tieredCache) Store(ctx context.Context, data map[string][]byte, ttl time.Duration) {
+	c.first.Store(ctx, data, ttl)
+	c.second.Store(ctx, data, ttl)
+}
+
+// Fetch implements cache.Cache.
+func (c *tieredCache) Fetch(ctx context.Context, keys []string) map[string][]byte {
+	hits := c.first.Fetch(ctx, keys)
+	if len(hits) == len(keys) {
+		return hits
+	}
+
+	missing := make([]string, 0, len(keys)-len(hits))
+	for _, key := range keys {
+		if _, ok := hits[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	secondHits := c.second.Fetch(ctx, missing)
+	if len(secondHits) == 0 {
+		return hits
+	}
+
+	// The TTL of the second-level cache entries is unknown, so they're kept in the first-level
+	// cache until evicted or expired according to its own validity.
+	c.first.Store(ctx, secondHits, 0)
+
+	for key, value := range secondHits {
+		hits[key] = value
+	}
+	return hits
+}
+
+func createCache(cacheName string, backend CacheBackend, logger log.Logger, reg prometheus.Registerer) (cache.Cache, error) {
+	switch backend.Backend {
 	case "":
 		// No caching.
 		return nil, nil
 
 	case CacheBackendMemcached:
 		var client cacheutil.MemcachedClient
-		client, err := cacheutil.NewMemcachedClientWithConfig(logger, cacheName, memcached.ToMemcachedClientConfig(), reg)
+		client, err := cacheutil.NewMemcachedClientWithConfig(logger, cacheName, backend.Memcached.ToMemcachedClientConfig(), reg)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to create memcached client")
 		}
 		return cache.NewMemcachedCache(cacheName, logger, client, reg), nil
 
+	case CacheBackendRedis:
+		client, err := newRedisClient(cacheName, backend.Redis, logger, reg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create redis client")
+		}
+		return newRedisCache(cacheName, client, reg), nil
+
 	default:
-		return nil, errors.Errorf("unsupported cache type for cache %s: %s", cacheName, backend)
+		return nil, errors.Errorf("unsupported cache type for cache %s: %s", cacheName, backend.Backend)
 	}
 }
 
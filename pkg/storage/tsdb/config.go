@@ -50,9 +50,14 @@ var (
 	errInvalidWALSegmentSizeBytes   = errors.New("invalid TSDB WAL segment size bytes")
 	errInvalidStripeSize            = errors.New("invalid TSDB stripe size")
 	errEmptyBlockranges             = errors.New("empty block ranges for TSDB")
+
+	errInvalidLazyPostingsMinCostRatio = errors.New("invalid lazy postings min cost ratio")
+	errInvalidIndexCacheWarmupMatchers = errors.New("invalid index cache warm-up max matchers per tenant")
+	errInvalidIndexCacheWarmupTimeout  = errors.New("invalid index cache warm-up timeout")
 )
 
 // BlocksStorageConfig holds the config information for the blocks storage.
+//
 //nolint:golint
 type BlocksStorageConfig struct {
 	Bucket      bucket.Config     `yaml:",inline"`
@@ -118,6 +123,7 @@ func (cfg *BlocksStorageConfig) Validate() error {
 }
 
 // TSDBConfig holds the config for TSDB opened in the ingesters.
+//
 //nolint:golint
 type TSDBConfig struct {
 	Dir                       string        `yaml:"dir"`
@@ -223,18 +229,20 @@ func (cfg *TSDBConfig) IsBlocksShippingEnabled() bool {
 
 // BucketStoreConfig holds the config information for Bucket Stores used by the querier and store-gateway.
 type BucketStoreConfig struct {
-	SyncDir                  string              `yaml:"sync_dir"`
-	SyncInterval             time.Duration       `yaml:"sync_interval"`
-	MaxConcurrent            int                 `yaml:"max_concurrent"`
-	TenantSyncConcurrency    int                 `yaml:"tenant_sync_concurrency"`
-	BlockSyncConcurrency     int                 `yaml:"block_sync_concurrency"`
-	MetaSyncConcurrency      int                 `yaml:"meta_sync_concurrency"`
-	ConsistencyDelay         time.Duration       `yaml:"consistency_delay"`
-	IndexCache               IndexCacheConfig    `yaml:"index_cache"`
-	ChunksCache              ChunksCacheConfig   `yaml:"chunks_cache"`
-	MetadataCache            MetadataCacheConfig `yaml:"metadata_cache"`
-	IgnoreDeletionMarksDelay time.Duration       `yaml:"ignore_deletion_mark_delay"`
-	BucketIndex              BucketIndexConfig   `yaml:"bucket_index"`
+	SyncDir                  string                 `yaml:"sync_dir"`
+	SyncInterval             time.Duration          `yaml:"sync_interval"`
+	MaxConcurrent            int                    `yaml:"max_concurrent"`
+	TenantSyncConcurrency    int                    `yaml:"tenant_sync_concurrency"`
+	BlockSyncConcurrency     int                    `yaml:"block_sync_concurrency"`
+	MetaSyncConcurrency      int                    `yaml:"meta_sync_concurrency"`
+	ConsistencyDelay         time.Duration          `yaml:"consistency_delay"`
+	IndexCache               IndexCacheConfig       `yaml:"index_cache"`
+	ChunksCache              ChunksCacheConfig      `yaml:"chunks_cache"`
+	MetadataCache            MetadataCacheConfig    `yaml:"metadata_cache"`
+	IgnoreDeletionMarksDelay time.Duration          `yaml:"ignore_deletion_mark_delay"`
+	BucketIndex              BucketIndexConfig      `yaml:"bucket_index"`
+	LazyPostings             LazyPostingsConfig     `yaml:"lazy_postings"`
+	IndexCacheWarmup         IndexCacheWarmupConfig `yaml:"index_cache_warmup"`
 
 	// Chunk pool.
 	MaxChunkPoolBytes           uint64 `yaml:"max_chunk_pool_bytes"`
@@ -263,6 +271,8 @@ func (cfg *BucketStoreConfig) RegisterFlags(f *flag.FlagSet) {
 	cfg.ChunksCache.RegisterFlagsWithPrefix(f, "blocks-storage.bucket-store.chunks-cache.")
 	cfg.MetadataCache.RegisterFlagsWithPrefix(f, "blocks-storage.bucket-store.metadata-cache.")
 	cfg.BucketIndex.RegisterFlagsWithPrefix(f, "blocks-storage.bucket-store.bucket-index.")
+	cfg.LazyPostings.RegisterFlagsWithPrefix(f, "blocks-storage.bucket-store.lazy-postings.")
+	cfg.IndexCacheWarmup.RegisterFlagsWithPrefix(f, "blocks-storage.bucket-store.index-cache-warmup.")
 
 	f.StringVar(&cfg.SyncDir, "blocks-storage.bucket-store.sync-dir", "tsdb-sync", "Directory to store synchronized TSDB index headers.")
 	f.DurationVar(&cfg.SyncInterval, "blocks-storage.bucket-store.sync-interval", 15*time.Minute, "How frequently to scan the bucket, or to refresh the bucket index (if enabled), in order to look for changes (new blocks shipped by ingesters and blocks deleted by retention or compaction).")
@@ -297,6 +307,58 @@ func (cfg *BucketStoreConfig) Validate() error {
 	if err != nil {
 		return errors.Wrap(err, "metadata-cache configuration")
 	}
+	err = cfg.LazyPostings.Validate()
+	if err != nil {
+		return errors.Wrap(err, "lazy-postings configuration")
+	}
+	err = cfg.IndexCacheWarmup.Validate()
+	if err != nil {
+		return errors.Wrap(err, "index-cache-warmup configuration")
+	}
+	return nil
+}
+
+type LazyPostingsConfig struct {
+	Enabled      bool    `yaml:"enabled"`
+	MinCostRatio float64 `yaml:"min_cost_ratio"`
+}
+
+func (cfg *LazyPostingsConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
+	f.BoolVar(&cfg.Enabled, prefix+"enabled", false, "True to enable the store-gateway to estimate the postings size of each matcher from the index-header, and defer the matchers whose postings are much bigger than the most selective ones to series-level filtering, instead of expanding their postings.")
+	f.Float64Var(&cfg.MinCostRatio, prefix+"min-cost-ratio", 10, "A matcher is deferred to series-level filtering when its estimated postings size is at least this many times bigger than the smallest estimated postings size of the query matchers. Must be greater or equal than 1.")
+}
+
+// Validate the config.
+func (cfg *LazyPostingsConfig) Validate() error {
+	if cfg.Enabled && cfg.MinCostRatio < 1 {
+		return errInvalidLazyPostingsMinCostRatio
+	}
+	return nil
+}
+
+type IndexCacheWarmupConfig struct {
+	Enabled              bool          `yaml:"enabled"`
+	MaxMatchersPerTenant int           `yaml:"max_matchers_per_tenant"`
+	Timeout              time.Duration `yaml:"timeout"`
+}
+
+func (cfg *IndexCacheWarmupConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
+	f.BoolVar(&cfg.Enabled, prefix+"enabled", false, "True to enable the store-gateway to pre-populate the index cache with the postings and series of the most queried label matchers when it loads new blocks. While the initial blocks synchronization is warming up the index cache, the store-gateway is not reported ACTIVE in the ring.")
+	f.IntVar(&cfg.MaxMatchersPerTenant, prefix+"max-matchers-per-tenant", 20, "Max number of most queried label matchers sets, per tenant, used to warm up the index cache.")
+	f.DurationVar(&cfg.Timeout, prefix+"timeout", 5*time.Minute, "Max time spent warming up the index cache after each blocks synchronization. Once the timeout expires, the warm-up is interrupted and the remaining blocks are warmed up at the next synchronization.")
+}
+
+// Validate the config.
+func (cfg *IndexCacheWarmupConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.MaxMatchersPerTenant <= 0 {
+		return errInvalidIndexCacheWarmupMatchers
+	}
+	if cfg.Timeout <= 0 {
+		return errInvalidIndexCacheWarmupTimeout
+	}
 	return nil
 }
 
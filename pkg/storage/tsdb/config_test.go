@@ -178,3 +178,16 @@ func TestConfig_DurationList(t *testing.T) {
 		})
 	}
 }
+
+func TestLazyPostingsConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&LazyPostingsConfig{Enabled: false, MinCostRatio: 0}).Validate())
+	assert.NoError(t, (&LazyPostingsConfig{Enabled: true, MinCostRatio: 1}).Validate())
+	assert.Equal(t, errInvalidLazyPostingsMinCostRatio, (&LazyPostingsConfig{Enabled: true, MinCostRatio: 0.5}).Validate())
+}
+
+func TestIndexCacheWarmupConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&IndexCacheWarmupConfig{Enabled: false}).Validate())
+	assert.NoError(t, (&IndexCacheWarmupConfig{Enabled: true, MaxMatchersPerTenant: 1, Timeout: time.Minute}).Validate())
+	assert.Equal(t, errInvalidIndexCacheWarmupMatchers, (&IndexCacheWarmupConfig{Enabled: true, MaxMatchersPerTenant: 0, Timeout: time.Minute}).Validate())
+	assert.Equal(t, errInvalidIndexCacheWarmupTimeout, (&IndexCacheWarmupConfig{Enabled: true, MaxMatchersPerTenant: 1, Timeout: 0}).Validate())
+}
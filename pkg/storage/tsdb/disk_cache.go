@@ -0,0 +1,67 @@
+package tsdb
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cortexproject/cortex/pkg/chunk/cache"
+)
+
+type DiskCacheConfig struct {
+	cache.DiskCacheConfig `yaml:",inline"`
+}
+
+func (cfg *DiskCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
+	cfg.DiskCacheConfig.RegisterFlagsWithPrefix(prefix, "", f)
+}
+
+// diskCache is a Thanos cache.Cache backed by the on-disk cache.
+type diskCache struct {
+	cache *cache.DiskCache
+}
+
+func newDiskCache(name string, cfg DiskCacheConfig, logger log.Logger, reg prometheus.Registerer) (*diskCache, error) {
+	c, err := cache.NewDiskCache(name, cfg.DiskCacheConfig, reg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &diskCache{cache: c}, nil
+}
+
+// Store implements cache.Cache. Entries stored without a TTL expire according to the disk cache validity.
+func (c *diskCache) Store(_ context.Context, data map[string][]byte, ttl time.Duration) {
+	keys := make([]string, 0, len(data))
+	values := make([][]byte, 0, len(data))
+	for key, value := range data {
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+
+	if ttl <= 0 {
+		c.cache.Store(context.Background(), keys, values)
+		return
+	}
+
+	c.cache.StoreWithTTL(keys, values, ttl)
+}
+
+// Fetch implements cache.Cache.
+func (c *diskCache) Fetch(ctx context.Context, keys []string) map[string][]byte {
+	found, values, _ := c.cache.Fetch(ctx, keys)
+
+	hits := make(map[string][]byte, len(found))
+	for i, key := range found {
+		hits[key] = values[i]
+	}
+	return hits
+}
+
+// Stop stops the disk cache background writes.
+func (c *diskCache) Stop() {
+	c.cache.Stop()
+}
@@ -0,0 +1,145 @@
+package tsdb
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cortexproject/cortex/pkg/util/test"
+)
+
+func TestChunksCacheConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		setup       func(cfg *ChunksCacheConfig)
+		expectedErr bool
+	}{
+		"should pass with the disk cache and no backend": {
+			setup: func(cfg *ChunksCacheConfig) {
+				cfg.Disk.Directory = "/data/chunks-cache"
+			},
+		},
+		"should pass with the disk cache in front of a backend": {
+			setup: func(cfg *ChunksCacheConfig) {
+				cfg.Backend = CacheBackendMemcached
+				cfg.Memcached.Addresses = "dns+localhost:11211"
+				cfg.Disk.Directory = "/data/chunks-cache"
+			},
+		},
+		"should fail on invalid disk cache config": {
+			setup: func(cfg *ChunksCacheConfig) {
+				cfg.Disk.Directory = "/data/chunks-cache"
+				cfg.Disk.MaxSizeBytes = 0
+			},
+			expectedErr: true,
+		},
+		"should fail on the redis backend without endpoint": {
+			setup: func(cfg *ChunksCacheConfig) {
+				cfg.Backend = CacheBackendRedis
+			},
+			expectedErr: true,
+		},
+		"should fail on the disk backend": {
+			setup: func(cfg *ChunksCacheConfig) {
+				cfg.Backend = "disk"
+			},
+			expectedErr: true,
+		},
+		"should not validate the disk config if the disk cache is not enabled": {
+			setup: func(cfg *ChunksCacheConfig) {
+				cfg.Disk.MaxSizeBytes = 0
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			cfg := ChunksCacheConfig{}
+			cfg.RegisterFlagsWithPrefix(flag.NewFlagSet("", flag.PanicOnError), "")
+			testData.setup(&cfg)
+
+			if testData.expectedErr {
+				assert.Error(t, cfg.Validate())
+			} else {
+				assert.NoError(t, cfg.Validate())
+			}
+		})
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	cfg := ChunksCacheConfig{}
+	cfg.RegisterFlagsWithPrefix(flag.NewFlagSet("", flag.PanicOnError), "")
+	cfg.Disk.Directory = t.TempDir()
+	cfg.Disk.WriteBackGoroutines = 0
+	require.NoError(t, cfg.Validate())
+
+	c, disk, err := createChunksCache("chunks-cache", "test", cfg, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	t.Cleanup(disk.Stop)
+
+	ctx := context.Background()
+	c.Store(ctx, map[string][]byte{"key-1": []byte("value-1"), "key-2": []byte("value-2")}, time.Hour)
+	c.Store(ctx, map[string][]byte{"key-3": []byte("value-3")}, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	assert.Equal(t, map[string][]byte{
+		"key-1": []byte("value-1"),
+		"key-2": []byte("value-2"),
+	}, c.Fetch(ctx, []string{"key-1", "key-2", "key-3", "key-4"}))
+
+	// The entries are stored in the component subdirectory.
+	entries, err := ioutil.ReadDir(filepath.Join(cfg.Disk.Directory, "test"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, entries)
+}
+
+func TestDiskCache_ShouldBeFirstLevelCacheInFrontOfTheBackend(t *testing.T) {
+	redisServer, redisCfg := prepareRedisClientConfig(t)
+
+	cfg := ChunksCacheConfig{}
+	cfg.RegisterFlagsWithPrefix(flag.NewFlagSet("", flag.PanicOnError), "")
+	cfg.Backend = CacheBackendRedis
+	cfg.Redis = redisCfg
+	cfg.Disk.Directory = t.TempDir()
+	require.NoError(t, cfg.Validate())
+
+	c, disk, err := createChunksCache("chunks-cache", "test", cfg, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	t.Cleanup(disk.Stop)
+	require.IsType(t, &tieredCache{}, c)
+
+	require.Same(t, disk, c.(*tieredCache).first)
+
+	ctx := context.Background()
+
+	// Entries are stored in both the disk cache and the backend.
+	c.Store(ctx, map[string][]byte{"key-1": []byte("value-1")}, time.Hour)
+	test.Poll(t, time.Second, true, func() interface{} {
+		return redisServer.Exists("key-1") && len(disk.Fetch(ctx, []string{"key-1"})) == 1
+	})
+
+	// The backend hits are stored in the disk cache.
+	require.NoError(t, redisServer.Set("key-2", "value-2"))
+	assert.Equal(t, map[string][]byte{
+		"key-1": []byte("value-1"),
+		"key-2": []byte("value-2"),
+	}, c.Fetch(ctx, []string{"key-1", "key-2", "key-3"}))
+
+	test.Poll(t, time.Second, map[string][]byte{"key-2": []byte("value-2")}, func() interface{} {
+		return disk.Fetch(ctx, []string{"key-2"})
+	})
+
+	// The disk cache hits are not looked up in the backend.
+	redisServer.FlushAll()
+	assert.Equal(t, map[string][]byte{
+		"key-1": []byte("value-1"),
+		"key-2": []byte("value-2"),
+	}, c.Fetch(ctx, []string{"key-1", "key-2"}))
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/cortexproject/cortex/pkg/chunk/cache"
 	"github.com/cortexproject/cortex/pkg/util/flagext"
 )
 
@@ -40,6 +41,20 @@ func TestIndexCacheConfig_Validate(t *testing.T) {
 				},
 			},
 		},
+		"no redis endpoint should fail": {
+			cfg: IndexCacheConfig{
+				Backend: "redis",
+			},
+			expected: errNoRedisEndpoint,
+		},
+		"redis endpoint should pass": {
+			cfg: IndexCacheConfig{
+				Backend: "redis",
+				Redis: RedisClientConfig{
+					RedisConfig: cache.RedisConfig{Endpoint: "localhost:6379"},
+				},
+			},
+		},
 	}
 
 	for testName, testData := range tests {
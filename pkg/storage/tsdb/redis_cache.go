@@ -0,0 +1,189 @@
+package tsdb
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/cortexproject/cortex/pkg/chunk/cache"
+)
+
+var errRedisAsyncBufferFull = errors.New("the async buffer is full")
+
+type redisSetOp struct {
+	key   string
+	value []byte
+	ttl   time.Duration
+}
+
+// redisClient is a redis client implementing the Thanos cacheutil.MemcachedClient interface,
+// so that it can be used as backend for the Thanos index cache. Like the Thanos memcached
+// client, write operations are asynchronous.
+type redisClient struct {
+	logger      log.Logger
+	client      *cache.RedisClient
+	maxItemSize int
+
+	asyncQueue chan redisSetOp
+	workers    sync.WaitGroup
+	stopOnce   sync.Once
+
+	// Metrics.
+	operations *prometheus.CounterVec
+	failures   *prometheus.CounterVec
+	skipped    *prometheus.CounterVec
+}
+
+// newRedisClient returns an error if redis can't be reached, so that a misconfigured
+// cache fails at startup like the memcached client does.
+func newRedisClient(name string, cfg RedisClientConfig, logger log.Logger, reg prometheus.Registerer) (*redisClient, error) {
+	c := &redisClient{
+		logger:      log.With(logger, "name", name),
+		client:      cache.NewRedisClient(&cfg.RedisConfig),
+		maxItemSize: cfg.MaxItemSize,
+		asyncQueue:  make(chan redisSetOp, cfg.MaxAsyncBufferSize),
+	}
+
+	if err := c.client.Ping(context.Background()); err != nil {
+		_ = c.client.Close()
+		return nil, errors.Wrap(err, "failed to connect to redis")
+	}
+
+	reg = prometheus.WrapRegistererWith(prometheus.Labels{"name": name}, reg)
+
+	c.operations = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "cortex_redis_operations_total",
+		Help: "Total number of operations against redis.",
+	}, []string{"operation"})
+
+	c.failures = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "cortex_redis_operation_failures_total",
+		Help: "Total number of operations against redis that failed.",
+	}, []string{"operation"})
+
+	c.skipped = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "cortex_redis_operation_skipped_total",
+		Help: "Total number of operations against redis that have been skipped.",
+	}, []string{"operation", "reason"})
+
+	c.workers.Add(cfg.MaxAsyncConcurrency)
+	for i := 0; i < cfg.MaxAsyncConcurrency; i++ {
+		go c.asyncQueueProcessLoop()
+	}
+
+	return c, nil
+}
+
+// GetMulti implements cacheutil.MemcachedClient.
+func (c *redisClient) GetMulti(ctx context.Context, keys []string) map[string][]byte {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	c.operations.WithLabelValues("getmulti").Inc()
+
+	values, err := c.client.MGet(ctx, keys)
+	if err != nil {
+		c.failures.WithLabelValues("getmulti").Inc()
+		level.Warn(c.logger).Log("msg", "failed to fetch items from redis", "numKeys", len(keys), "err", err)
+		return nil
+	}
+
+	hits := make(map[string][]byte, len(keys))
+	for i, value := range values {
+		if value != nil {
+			hits[keys[i]] = value
+		}
+	}
+	return hits
+}
+
+// SetAsync implements cacheutil.MemcachedClient.
+func (c *redisClient) SetAsync(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	if c.maxItemSize > 0 && len(value) > c.maxItemSize {
+		c.skipped.WithLabelValues("set", "max-item-size").Inc()
+		return nil
+	}
+
+	select {
+	case c.asyncQueue <- redisSetOp{key: key, value: value, ttl: ttl}:
+		return nil
+	default:
+		c.skipped.WithLabelValues("set", "async-buffer-full").Inc()
+		return errRedisAsyncBufferFull
+	}
+}
+
+// Stop implements cacheutil.MemcachedClient.
+func (c *redisClient) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.asyncQueue)
+		c.workers.Wait()
+
+		if err := c.client.Close(); err != nil {
+			level.Warn(c.logger).Log("msg", "failed to close redis client", "err", err)
+		}
+	})
+}
+
+func (c *redisClient) asyncQueueProcessLoop() {
+	defer c.workers.Done()
+
+	for op := range c.asyncQueue {
+		c.operations.WithLabelValues("set").Inc()
+
+		// The request context may be canceled by the time the operation is processed.
+		if err := c.client.MSetWithTTL(context.Background(), []string{op.key}, [][]byte{op.value}, op.ttl); err != nil {
+			c.failures.WithLabelValues("set").Inc()
+			level.Debug(c.logger).Log("msg", "failed to store item to redis", "key", op.key, "err", err)
+		}
+	}
+}
+
+// redisCache is a Thanos cache.Cache backed by redis.
+type redisCache struct {
+	client *redisClient
+
+	// Metrics.
+	requests prometheus.Counter
+	hits     prometheus.Counter
+}
+
+func newRedisCache(name string, client *redisClient, reg prometheus.Registerer) *redisCache {
+	return &redisCache{
+		client: client,
+		requests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name:        "thanos_cache_redis_requests_total",
+			Help:        "Total number of items requests to redis.",
+			ConstLabels: prometheus.Labels{"name": name},
+		}),
+		hits: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name:        "thanos_cache_redis_hits_total",
+			Help:        "Total number of items requests to the cache that were a hit.",
+			ConstLabels: prometheus.Labels{"name": name},
+		}),
+	}
+}
+
+// Store implements cache.Cache.
+func (c *redisCache) Store(ctx context.Context, data map[string][]byte, ttl time.Duration) {
+	for key, value := range data {
+		if err := c.client.SetAsync(ctx, key, value, ttl); err != nil {
+			level.Error(c.client.logger).Log("msg", "failed to store item into redis", "key", key, "err", err)
+		}
+	}
+}
+
+// Fetch implements cache.Cache.
+func (c *redisCache) Fetch(ctx context.Context, keys []string) map[string][]byte {
+	c.requests.Add(float64(len(keys)))
+	hits := c.client.GetMulti(ctx, keys)
+	c.hits.Add(float64(len(hits)))
+	return hits
+}
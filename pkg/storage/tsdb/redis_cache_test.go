@@ -0,0 +1,98 @@
+package tsdb
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kit/kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cortexproject/cortex/pkg/util/test"
+)
+
+func TestRedisCache(t *testing.T) {
+	redisServer, cfg := prepareRedisClientConfig(t)
+
+	client, err := newRedisClient("test", cfg, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	c := newRedisCache("test", client, prometheus.NewPedanticRegistry())
+
+	ctx := context.Background()
+	c.Store(ctx, map[string][]byte{"key-1": []byte("value-1"), "key-2": []byte("value-2")}, time.Hour)
+	c.Store(ctx, map[string][]byte{"key-too-big": make([]byte, cfg.MaxItemSize+1)}, time.Hour)
+
+	// Wait until all asynchronous writes have been processed.
+	client.Stop()
+	assert.Equal(t, time.Hour, redisServer.TTL("key-1"))
+	assert.False(t, redisServer.Exists("key-too-big"))
+
+	// Create a new client, given the previous one has been stopped.
+	client, err = newRedisClient("test", cfg, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	defer client.Stop()
+	c = newRedisCache("test", client, prometheus.NewPedanticRegistry())
+
+	assert.Equal(t, map[string][]byte{
+		"key-1": []byte("value-1"),
+		"key-2": []byte("value-2"),
+	}, c.Fetch(ctx, []string{"key-1", "key-2", "key-3"}))
+}
+
+func TestRedisClient_ShouldFailIfRedisIsNotReachable(t *testing.T) {
+	redisServer, cfg := prepareRedisClientConfig(t)
+	redisServer.Close()
+
+	_, err := newRedisClient("test", cfg, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.Error(t, err)
+
+	_, err = NewIndexCache(IndexCacheConfig{Backend: IndexCacheBackendRedis, Redis: cfg}, log.NewNopLogger(), nil)
+	require.Error(t, err)
+}
+
+func TestRedisIndexCache(t *testing.T) {
+	_, cfg := prepareRedisClientConfig(t)
+
+	blockID := ulid.MustNew(1, nil)
+	lbl := labels.Label{Name: "foo", Value: "bar"}
+
+	indexCache, err := NewIndexCache(IndexCacheConfig{Backend: IndexCacheBackendRedis, Redis: cfg}, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	indexCache.StorePostings(ctx, blockID, lbl, []byte("postings"))
+	indexCache.StoreSeries(ctx, blockID, 1, []byte("series"))
+
+	// Writes are asynchronous, so we wait until they have been processed.
+	test.Poll(t, time.Second, 1, func() interface{} {
+		hits, _ := indexCache.FetchMultiPostings(ctx, blockID, []labels.Label{lbl})
+		return len(hits)
+	})
+
+	postings, misses := indexCache.FetchMultiPostings(ctx, blockID, []labels.Label{lbl, {Name: "foo", Value: "baz"}})
+	assert.Equal(t, map[labels.Label][]byte{lbl: []byte("postings")}, postings)
+	assert.Equal(t, []labels.Label{{Name: "foo", Value: "baz"}}, misses)
+
+	test.Poll(t, time.Second, 1, func() interface{} {
+		hits, _ := indexCache.FetchMultiSeries(ctx, blockID, []uint64{1, 2})
+		return len(hits)
+	})
+}
+
+func prepareRedisClientConfig(t *testing.T) (*miniredis.Miniredis, RedisClientConfig) {
+	redisServer, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(redisServer.Close)
+
+	cfg := RedisClientConfig{}
+	cfg.RegisterFlagsWithPrefix(flag.NewFlagSet("", flag.PanicOnError), "")
+	cfg.Endpoint = redisServer.Addr()
+
+	return redisServer, cfg
+}
@@ -0,0 +1,36 @@
+package tsdb
+
+import (
+	"flag"
+
+	"github.com/pkg/errors"
+
+	"github.com/cortexproject/cortex/pkg/chunk/cache"
+)
+
+var errNoRedisEndpoint = errors.New("no redis endpoint")
+
+type RedisClientConfig struct {
+	cache.RedisConfig `yaml:",inline"`
+
+	MaxAsyncConcurrency int `yaml:"max_async_concurrency"`
+	MaxAsyncBufferSize  int `yaml:"max_async_buffer_size"`
+	MaxItemSize         int `yaml:"max_item_size"`
+}
+
+func (cfg *RedisClientConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
+	cfg.RedisConfig.RegisterFlagsWithPrefix(prefix, "", f)
+
+	f.IntVar(&cfg.MaxAsyncConcurrency, prefix+"redis.max-async-concurrency", 50, "The maximum number of concurrent asynchronous operations can occur.")
+	f.IntVar(&cfg.MaxAsyncBufferSize, prefix+"redis.max-async-buffer-size", 10000, "The maximum number of enqueued asynchronous operations allowed.")
+	f.IntVar(&cfg.MaxItemSize, prefix+"redis.max-item-size", 1024*1024, "The maximum size of an item stored in redis. Bigger items are not stored. If set to 0, no maximum size is enforced.")
+}
+
+// Validate the config.
+func (cfg *RedisClientConfig) Validate() error {
+	if cfg.Endpoint == "" {
+		return errNoRedisEndpoint
+	}
+
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/pkg/labels"
 	tsdb_errors "github.com/prometheus/prometheus/tsdb/errors"
 	"github.com/thanos-io/thanos/pkg/block"
 	thanos_metadata "github.com/thanos-io/thanos/pkg/block/metadata"
@@ -25,21 +26,31 @@ import (
 	"github.com/thanos-io/thanos/pkg/gate"
 	"github.com/thanos-io/thanos/pkg/objstore"
 	"github.com/thanos-io/thanos/pkg/pool"
-	"github.com/thanos-io/thanos/pkg/store"
 	storecache "github.com/thanos-io/thanos/pkg/store/cache"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/logging"
 	"google.golang.org/grpc/metadata"
 
+	"github.com/cortexproject/cortex/pkg/cortexpb"
 	"github.com/cortexproject/cortex/pkg/storage/bucket"
 	"github.com/cortexproject/cortex/pkg/storage/tsdb"
+	"github.com/cortexproject/cortex/pkg/storegateway/store"
+	"github.com/cortexproject/cortex/pkg/storegateway/storegatewaypb"
 	"github.com/cortexproject/cortex/pkg/util"
+	"github.com/cortexproject/cortex/pkg/util/limiter"
 	util_log "github.com/cortexproject/cortex/pkg/util/log"
 	"github.com/cortexproject/cortex/pkg/util/spanlogger"
 	"github.com/cortexproject/cortex/pkg/util/validation"
 )
 
+const (
+	rejectReasonMaxInflightSeriesRequests = "max-inflight-series-requests"
+	rejectReasonMaxFetchedChunkBytes      = "max-fetched-chunk-bytes"
+
+	errMaxInflightSeriesRequestsReached = "the tenant reached the max number of in-flight series requests on the store-gateway (limit: %d)"
+)
+
 // BucketStores is a multi-tenant wrapper of Thanos BucketStore.
 type BucketStores struct {
 	logger             log.Logger
@@ -63,20 +74,31 @@ type BucketStores struct {
 	// Gate used to limit query concurrency across all tenants.
 	queryGate gate.Gate
 
+	// Planner deferring expensive matchers to series-level filtering (nil if disabled).
+	postingsPlanner *postingsPlanner
+
+	// Warmer pre-populating the index cache when new blocks are loaded (nil if disabled).
+	indexCacheWarmer *indexCacheWarmer
+
 	// Keeps a bucket store for each tenant.
 	storesMu sync.RWMutex
 	stores   map[string]*store.BucketStore
 
+	// Keeps the number of in-flight Series requests for each tenant.
+	inflightSeriesMu sync.Mutex
+	inflightSeries   map[string]int
+
 	// Metrics.
-	syncTimes         prometheus.Histogram
-	syncLastSuccess   prometheus.Gauge
-	tenantsDiscovered prometheus.Gauge
-	tenantsSynced     prometheus.Gauge
+	syncTimes              prometheus.Histogram
+	syncLastSuccess        prometheus.Gauge
+	tenantsDiscovered      prometheus.Gauge
+	tenantsSynced          prometheus.Gauge
+	seriesRequestsRejected *prometheus.CounterVec
 }
 
 // NewBucketStores makes a new BucketStores.
 func NewBucketStores(cfg tsdb.BlocksStorageConfig, shardingStrategy ShardingStrategy, bucketClient objstore.Bucket, limits *validation.Overrides, logLevel logging.Level, logger log.Logger, reg prometheus.Registerer) (*BucketStores, error) {
-	cachingBucket, err := tsdb.CreateCachingBucket(cfg.BucketStore.ChunksCache, cfg.BucketStore.MetadataCache, bucketClient, logger, reg)
+	cachingBucket, err := tsdb.CreateCachingBucket("store-gateway", cfg.BucketStore.ChunksCache, cfg.BucketStore.MetadataCache, bucketClient, logger, reg)
 	if err != nil {
 		return nil, errors.Wrapf(err, "create caching bucket")
 	}
@@ -96,6 +118,7 @@ func NewBucketStores(cfg tsdb.BlocksStorageConfig, shardingStrategy ShardingStra
 		bucket:             cachingBucket,
 		shardingStrategy:   shardingStrategy,
 		stores:             map[string]*store.BucketStore{},
+		inflightSeries:     map[string]int{},
 		logLevel:           logLevel,
 		bucketStoreMetrics: NewBucketStoreMetrics(),
 		metaFetcherMetrics: NewMetadataFetcherMetrics(),
@@ -118,6 +141,18 @@ func NewBucketStores(cfg tsdb.BlocksStorageConfig, shardingStrategy ShardingStra
 			Name: "cortex_bucket_stores_tenants_synced",
 			Help: "Number of tenants synced.",
 		}),
+		seriesRequestsRejected: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_bucket_stores_series_requests_rejected_total",
+			Help: "Total number of Series requests rejected because of a per-tenant limit.",
+		}, []string{"reason"}),
+	}
+
+	if cfg.BucketStore.LazyPostings.Enabled {
+		u.postingsPlanner = newPostingsPlanner(cfg.BucketStore, logger, reg)
+	}
+
+	if cfg.BucketStore.IndexCacheWarmup.Enabled {
+		u.indexCacheWarmer = newIndexCacheWarmer(cfg.BucketStore.IndexCacheWarmup, logger, reg)
 	}
 
 	// Init the index cache.
@@ -148,15 +183,29 @@ func (u *BucketStores) InitialSync(ctx context.Context) error {
 		return err
 	}
 
+	// Warm up the index cache of the loaded blocks before returning, so that the initial
+	// sync (and so switching the store-gateway to ACTIVE in the ring) waits for it.
+	if u.indexCacheWarmer != nil {
+		u.indexCacheWarmer.warmup(ctx, u.getUserIDs(), u.getStore, u.syncDirForUser, u.cfg.BucketStore.TenantSyncConcurrency)
+	}
+
 	level.Info(u.logger).Log("msg", "successfully synchronized TSDB blocks for all users")
 	return nil
 }
 
 // SyncBlocks synchronizes the stores state with the Bucket store for every user.
 func (u *BucketStores) SyncBlocks(ctx context.Context) error {
-	return u.syncUsersBlocksWithRetries(ctx, func(ctx context.Context, s *store.BucketStore) error {
+	err := u.syncUsersBlocksWithRetries(ctx, func(ctx context.Context, s *store.BucketStore) error {
 		return s.SyncBlocks(ctx)
 	})
+
+	// The index cache of the newly loaded blocks is warmed up in background, so that the
+	// periodic sync isn't delayed by it.
+	if u.indexCacheWarmer != nil {
+		u.indexCacheWarmer.warmupAsync(ctx, u.getUserIDs(), u.getStore, u.syncDirForUser, u.cfg.BucketStore.TenantSyncConcurrency)
+	}
+
+	return err
 }
 
 func (u *BucketStores) syncUsersBlocksWithRetries(ctx context.Context, f func(context.Context, *store.BucketStore) error) error {
@@ -287,12 +336,70 @@ func (u *BucketStores) Series(req *storepb.SeriesRequest, srv storepb.Store_Seri
 		return nil
 	}
 
+	if !u.acquireInflightSeriesRequest(userID) {
+		u.seriesRequestsRejected.WithLabelValues(rejectReasonMaxInflightSeriesRequests).Inc()
+		return httpgrpc.Errorf(http.StatusTooManyRequests, errMaxInflightSeriesRequestsReached, u.limits.StoreGatewayMaxInflightSeriesRequests(userID))
+	}
+	defer u.releaseInflightSeriesRequest(userID)
+
+	// The chunk bytes limit is enforced by the bucket store while loading the chunks, so the
+	// per-request limiter is passed down through the context.
+	chunkBytesLimiter := newChunkBytesLimiter(u.limits.MaxFetchedChunkBytesPerQuery(userID), u.seriesRequestsRejected.WithLabelValues(rejectReasonMaxFetchedChunkBytes))
+
+	if u.indexCacheWarmer != nil {
+		u.indexCacheWarmer.record(userID, req.Matchers)
+	}
+
+	seriesCtx := withChunkBytesLimiter(spanCtx, chunkBytesLimiter)
+
+	if u.postingsPlanner != nil {
+		matchers, deferred := u.postingsPlanner.plan(userID, store, req)
+		if len(deferred) > 0 {
+			level.Debug(spanLog).Log("msg", "deferred matchers to series-level filtering", "deferred", util.LabelMatchersToString(deferred))
+
+			plannedReq := *req
+			plannedReq.Matchers = matchers
+			req = &plannedReq
+
+			// The deferred matchers are applied by the bucket store before fetching the chunks.
+			seriesCtx = u.postingsPlanner.withDeferredMatchers(seriesCtx, deferred)
+		}
+	}
+
 	return store.Series(req, spanSeriesServer{
 		Store_SeriesServer: srv,
-		ctx:                spanCtx,
+		ctx:                seriesCtx,
 	})
 }
 
+// acquireInflightSeriesRequest returns false if the tenant has reached the max number of
+// in-flight Series requests, otherwise it tracks a new in-flight request and returns true.
+func (u *BucketStores) acquireInflightSeriesRequest(userID string) bool {
+	limit := u.limits.StoreGatewayMaxInflightSeriesRequests(userID)
+
+	u.inflightSeriesMu.Lock()
+	defer u.inflightSeriesMu.Unlock()
+
+	if limit > 0 && u.inflightSeries[userID] >= limit {
+		return false
+	}
+
+	u.inflightSeries[userID]++
+	return true
+}
+
+func (u *BucketStores) releaseInflightSeriesRequest(userID string) {
+	u.inflightSeriesMu.Lock()
+	defer u.inflightSeriesMu.Unlock()
+
+	if u.inflightSeries[userID] <= 1 {
+		delete(u.inflightSeries, userID)
+		return
+	}
+
+	u.inflightSeries[userID]--
+}
+
 // LabelNames implements the Storegateway proto service.
 func (u *BucketStores) LabelNames(ctx context.Context, req *storepb.LabelNamesRequest) (*storepb.LabelNamesResponse, error) {
 	spanLog, spanCtx := spanlogger.New(ctx, "BucketStores.LabelNames")
@@ -329,6 +436,120 @@ func (u *BucketStores) LabelValues(ctx context.Context, req *storepb.LabelValues
 	return store.LabelValues(ctx, req)
 }
 
+// Exemplars implements the Storegateway proto service. The exemplars are read from the exemplars
+// files of the requested blocks, only if the blocks are loaded by this store-gateway.
+func (u *BucketStores) Exemplars(ctx context.Context, req *storegatewaypb.ExemplarsRequest) (*storegatewaypb.ExemplarsResponse, error) {
+	spanLog, spanCtx := spanlogger.New(ctx, "BucketStores.Exemplars")
+	defer spanLog.Span.Finish()
+
+	userID := getUserIDFromGRPCContext(spanCtx)
+	if userID == "" {
+		return nil, fmt.Errorf("no userID")
+	}
+
+	store := u.getStore(userID)
+	if store == nil {
+		return &storegatewaypb.ExemplarsResponse{}, nil
+	}
+
+	matcherSets := make([][]*labels.Matcher, 0, len(req.Matchers))
+	for _, m := range req.Matchers {
+		matchers, err := storepb.MatchersToPromMatchers(m.Matchers...)
+		if err != nil {
+			return nil, httpgrpc.Errorf(http.StatusBadRequest, err.Error())
+		}
+		matcherSets = append(matcherSets, matchers)
+	}
+
+	localBlocks, err := listLocalBlocks(u.syncDirForUser(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[ulid.ULID]struct{}, len(localBlocks))
+	for _, id := range localBlocks {
+		loaded[id] = struct{}{}
+	}
+
+	var (
+		userBkt       = bucket.NewUserBucketClient(userID, u.bucket, u.limits)
+		sets          = make([][]cortexpb.TimeSeries, 0, len(req.BlockIds))
+		queriedBlocks = make([]string, 0, len(req.BlockIds))
+	)
+
+	for _, rawID := range req.BlockIds {
+		id, err := ulid.Parse(rawID)
+		if err != nil {
+			return nil, httpgrpc.Errorf(http.StatusBadRequest, err.Error())
+		}
+
+		if _, ok := loaded[id]; !ok {
+			continue
+		}
+
+		series, err := tsdb.ReadBlockExemplars(spanCtx, userBkt, id)
+		if err != nil {
+			return nil, err
+		}
+
+		sets = append(sets, filterExemplarSeries(series, req.MinTime, req.MaxTime, matcherSets))
+		queriedBlocks = append(queriedBlocks, rawID)
+	}
+
+	merged := tsdb.MergeExemplarSeries(sets...)
+	level.Debug(spanLog).Log("msg", "read exemplars from blocks", "queried blocks", strings.Join(queriedBlocks, " "), "num series", len(merged))
+
+	res := &storegatewaypb.ExemplarsResponse{
+		Series:          make([]storegatewaypb.ExemplarSeries, 0, len(merged)),
+		QueriedBlockIds: queriedBlocks,
+	}
+
+	for _, s := range merged {
+		res.Series = append(res.Series, storegatewaypb.FromCortexExemplarSeries(s))
+	}
+
+	return res, nil
+}
+
+// filterExemplarSeries returns the input series matching any of the matchers sets, with only
+// the exemplars within the input time range (both included).
+func filterExemplarSeries(series []cortexpb.TimeSeries, minT, maxT int64, matcherSets [][]*labels.Matcher) []cortexpb.TimeSeries {
+	var res []cortexpb.TimeSeries
+
+	for _, s := range series {
+		if !matchesAnyMatchersSet(cortexpb.FromLabelAdaptersToLabels(s.Labels), matcherSets) {
+			continue
+		}
+
+		var exemplars []cortexpb.Exemplar
+		for _, e := range s.Exemplars {
+			if e.TimestampMs >= minT && e.TimestampMs <= maxT {
+				exemplars = append(exemplars, e)
+			}
+		}
+
+		if len(exemplars) > 0 {
+			res = append(res, cortexpb.TimeSeries{Labels: s.Labels, Exemplars: exemplars})
+		}
+	}
+
+	return res
+}
+
+func matchesAnyMatchersSet(lset labels.Labels, matcherSets [][]*labels.Matcher) bool {
+outer:
+	for _, matchers := range matcherSets {
+		for _, m := range matchers {
+			if !m.Matches(lset.Get(m.Name)) {
+				continue outer
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
 // scanUsers in the bucket and return the list of found users. If an error occurs while
 // iterating the bucket, it may return both an error and a subset of the users in the bucket.
 func (u *BucketStores) scanUsers(ctx context.Context) ([]string, error) {
@@ -351,11 +572,34 @@ func (u *BucketStores) getStore(userID string) *store.BucketStore {
 	return u.stores[userID]
 }
 
+// getUserIDs returns the tenants having a bucket store.
+func (u *BucketStores) getUserIDs() []string {
+	u.storesMu.RLock()
+	defer u.storesMu.RUnlock()
+
+	userIDs := make([]string, 0, len(u.stores))
+	for userID := range u.stores {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
+
 var (
 	errBucketStoreNotEmpty = errors.New("bucket store not empty")
 	errBucketStoreNotFound = errors.New("bucket store not found")
 )
 
+// Close releases the resources held by the BucketStores, like the bucket client
+// and its caches. It must be called once the BucketStores is not used anymore.
+func (u *BucketStores) Close() error {
+	// Stop the index cache warm-up running in background, which uses the bucket client.
+	if u.indexCacheWarmer != nil {
+		u.indexCacheWarmer.stop()
+	}
+
+	return u.bucket.Close()
+}
+
 // closeEmptyBucketStore closes bucket store for given user, if it is empty,
 // and removes it from bucket stores map and metrics.
 // If bucket store doesn't exist, returns errBucketStoreNotFound.
@@ -385,6 +629,12 @@ func (u *BucketStores) closeEmptyBucketStore(userID string) error {
 
 	u.metaFetcherMetrics.RemoveUserRegistry(userID)
 	u.bucketStoreMetrics.RemoveUserRegistry(userID)
+	if u.postingsPlanner != nil {
+		u.postingsPlanner.closeUser(userID)
+	}
+	if u.indexCacheWarmer != nil {
+		u.indexCacheWarmer.closeUser(userID)
+	}
 	return bs.Close()
 }
 
@@ -492,7 +742,7 @@ func (u *BucketStores) getOrCreateStore(userID string) (*store.BucketStore, erro
 		fetcher,
 		u.syncDirForUser(userID),
 		newChunksLimiterFactory(u.limits, userID),
-		store.NewSeriesLimiterFactory(0), // No series limiter.
+		newSeriesLimiterFactory(u.limits, userID),
 		u.partitioner,
 		u.cfg.BucketStore.BlockSyncConcurrency,
 		false, // No need to enable backward compatibility with Thanos pre 0.8.0 queriers
@@ -627,3 +877,31 @@ func newChunksLimiterFactory(limits *validation.Overrides, userID string) store.
 		}
 	}
 }
+
+// seriesLimiter limits the number of series fetched by a single request, summed up across all
+// the queried blocks. A series stored in multiple blocks is counted once for each block.
+type seriesLimiter struct {
+	limiter *store.Limiter
+	limit   uint64
+}
+
+func (s *seriesLimiter) Reserve(num uint64) error {
+	if err := s.limiter.Reserve(num); err != nil {
+		return httpgrpc.Errorf(http.StatusUnprocessableEntity, limiter.ErrMaxSeriesHit, s.limit)
+	}
+
+	return nil
+}
+
+func newSeriesLimiterFactory(limits *validation.Overrides, userID string) store.SeriesLimiterFactory {
+	return func(failedCounter prometheus.Counter) store.SeriesLimiter {
+		// Since limit overrides could be live reloaded, we have to get the current user's limit
+		// each time a new limiter is instantiated.
+		limit := uint64(limits.MaxFetchedSeriesPerQuery(userID))
+
+		return &seriesLimiter{
+			limiter: store.NewLimiter(limit, failedCounter),
+			limit:   limit,
+		}
+	}
+}
@@ -2,11 +2,13 @@ package storegateway
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
@@ -27,18 +29,23 @@ import (
 	thanos_metadata "github.com/thanos-io/thanos/pkg/block/metadata"
 	"github.com/thanos-io/thanos/pkg/extprom"
 	"github.com/thanos-io/thanos/pkg/objstore"
-	"github.com/thanos-io/thanos/pkg/store"
 	"github.com/thanos-io/thanos/pkg/store/labelpb"
 	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/logging"
 	"go.uber.org/atomic"
 	"google.golang.org/grpc/metadata"
 
+	"github.com/cortexproject/cortex/pkg/cortexpb"
 	"github.com/cortexproject/cortex/pkg/storage/bucket"
 	"github.com/cortexproject/cortex/pkg/storage/bucket/filesystem"
 	cortex_tsdb "github.com/cortexproject/cortex/pkg/storage/tsdb"
+	"github.com/cortexproject/cortex/pkg/storegateway/store"
+	"github.com/cortexproject/cortex/pkg/storegateway/storegatewaypb"
 	"github.com/cortexproject/cortex/pkg/util"
 	"github.com/cortexproject/cortex/pkg/util/flagext"
+	"github.com/cortexproject/cortex/pkg/util/limiter"
+	"github.com/cortexproject/cortex/pkg/util/validation"
 )
 
 func TestBucketStores_InitialSync(t *testing.T) {
@@ -378,6 +385,262 @@ func testBucketStoresSeriesShouldCorrectlyQuerySeriesSpanningMultipleChunks(t *t
 	}
 }
 
+func TestBucketStores_Series_ShouldEnforceLimits(t *testing.T) {
+	const userID = "user-1"
+
+	storageDir, err := ioutil.TempDir(os.TempDir(), "storage-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, os.RemoveAll(storageDir)) })
+
+	// Generate a block with 2 series and another block with 1 series, which is also in the first block.
+	generateStorageBlockWithSeries(t, storageDir, userID, []string{"series_1", "series_2"}, 0, 10000, 1)
+	generateStorageBlockWithSeries(t, storageDir, userID, []string{"series_1"}, 10000, 20000, 1)
+
+	bucket, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		limits         func(*validation.Limits)
+		reqMinTime     int64
+		reqMaxTime     int64
+		expectedSeries int
+		expectedErr    string
+	}{
+		"no limits": {
+			limits:         func(*validation.Limits) {},
+			reqMinTime:     math.MinInt64,
+			reqMaxTime:     math.MaxInt64,
+			expectedSeries: 2,
+		},
+		"series limit reached by a single block": {
+			limits: func(l *validation.Limits) {
+				l.MaxFetchedSeriesPerQuery = 1
+			},
+			reqMinTime:  math.MinInt64,
+			reqMaxTime:  math.MaxInt64,
+			expectedErr: fmt.Sprintf(limiter.ErrMaxSeriesHit, 1),
+		},
+		"series limit reached by the series summed up across blocks": {
+			limits: func(l *validation.Limits) {
+				l.MaxFetchedSeriesPerQuery = 2
+			},
+			reqMinTime:  math.MinInt64,
+			reqMaxTime:  math.MaxInt64,
+			expectedErr: fmt.Sprintf(limiter.ErrMaxSeriesHit, 2),
+		},
+		"series limit not reached by any block": {
+			limits: func(l *validation.Limits) {
+				l.MaxFetchedSeriesPerQuery = 1
+			},
+			reqMinTime:     10000,
+			reqMaxTime:     20000,
+			expectedSeries: 1,
+		},
+		"chunk bytes limit reached": {
+			limits: func(l *validation.Limits) {
+				l.MaxFetchedChunkBytesPerQuery = 100
+			},
+			reqMinTime:  math.MinInt64,
+			reqMaxTime:  math.MaxInt64,
+			expectedErr: fmt.Sprintf(limiter.ErrMaxChunkBytesHit, 100),
+		},
+		"chunk bytes limit not reached": {
+			limits: func(l *validation.Limits) {
+				l.MaxFetchedChunkBytesPerQuery = 10 * 1024 * 1024
+			},
+			reqMinTime:     math.MinInt64,
+			reqMaxTime:     math.MaxInt64,
+			expectedSeries: 2,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			cfg, cleanup := prepareStorageConfig(t)
+			defer cleanup()
+
+			limits := defaultLimitsConfig()
+			testData.limits(&limits)
+			overrides, err := validation.NewOverrides(limits, nil)
+			require.NoError(t, err)
+
+			reg := prometheus.NewPedanticRegistry()
+			stores, err := NewBucketStores(cfg, NewNoShardingStrategy(), bucket, overrides, mockLoggingLevel(), log.NewNopLogger(), reg)
+			require.NoError(t, err)
+			require.NoError(t, stores.InitialSync(context.Background()))
+
+			seriesSet, _, err := querySeriesWithMatcher(stores, userID, storepb.LabelMatcher{Type: storepb.LabelMatcher_RE, Name: labels.MetricName, Value: "series_.*"}, testData.reqMinTime, testData.reqMaxTime)
+			if testData.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testData.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, seriesSet, testData.expectedSeries)
+		})
+	}
+}
+
+func TestBucketStores_Series_ShouldEnforceChunkBytesLimitOnActualChunksSize(t *testing.T) {
+	const userID = "user-1"
+
+	storageDir, err := ioutil.TempDir(os.TempDir(), "storage-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, os.RemoveAll(storageDir)) })
+
+	generateStorageBlockWithSeries(t, storageDir, userID, []string{"series_1", "series_2"}, 0, 10000, 1)
+
+	bucket, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	querySeriesWithLimit := func(limit int) ([]*storepb.Series, error) {
+		cfg, cleanup := prepareStorageConfig(t)
+		defer cleanup()
+
+		limits := defaultLimitsConfig()
+		limits.MaxFetchedChunkBytesPerQuery = limit
+		overrides, err := validation.NewOverrides(limits, nil)
+		require.NoError(t, err)
+
+		stores, err := NewBucketStores(cfg, NewNoShardingStrategy(), bucket, overrides, mockLoggingLevel(), log.NewNopLogger(), nil)
+		require.NoError(t, err)
+		require.NoError(t, stores.InitialSync(context.Background()))
+
+		seriesSet, _, err := querySeriesWithMatcher(stores, userID, storepb.LabelMatcher{Type: storepb.LabelMatcher_RE, Name: labels.MetricName, Value: "series_.*"}, math.MinInt64, math.MaxInt64)
+		return seriesSet, err
+	}
+
+	seriesSet, err := querySeriesWithLimit(0)
+	require.NoError(t, err)
+	require.Len(t, seriesSet, 2)
+
+	// The size of each chunk is its data length, plus the varint-encoded length and the encoding byte.
+	chunksSize := 0
+	for _, s := range seriesSet {
+		for _, c := range s.Chunks {
+			chunksSize += len(c.Raw.Data) + binary.PutUvarint(make([]byte, binary.MaxVarintLen64), uint64(len(c.Raw.Data))) + 1
+		}
+	}
+
+	// The bytes fetched in excess of the actual chunks size are not counted.
+	_, err = querySeriesWithLimit(chunksSize)
+	require.NoError(t, err)
+
+	_, err = querySeriesWithLimit(chunksSize - 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf(limiter.ErrMaxChunkBytesHit, chunksSize-1))
+}
+
+func TestBucketStores_Series_ShouldEnforceMaxInflightSeriesRequests(t *testing.T) {
+	const userID = "user-1"
+
+	cfg, cleanup := prepareStorageConfig(t)
+	defer cleanup()
+
+	storageDir, err := ioutil.TempDir(os.TempDir(), "storage-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, os.RemoveAll(storageDir)) })
+	generateStorageBlock(t, storageDir, userID, "series_1", 0, 100, 1)
+
+	bucket, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	limits := defaultLimitsConfig()
+	limits.StoreGatewayMaxInflightSeriesRequests = 1
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+
+	reg := prometheus.NewPedanticRegistry()
+	stores, err := NewBucketStores(cfg, NewNoShardingStrategy(), bucket, overrides, mockLoggingLevel(), log.NewNopLogger(), reg)
+	require.NoError(t, err)
+	require.NoError(t, stores.InitialSync(context.Background()))
+
+	// Simulate an in-flight request.
+	require.True(t, stores.acquireInflightSeriesRequest(userID))
+
+	_, _, err = querySeries(stores, userID, "series_1", 0, 100)
+	require.Error(t, err)
+	resp, ok := httpgrpc.HTTPResponseFromError(err)
+	require.True(t, ok)
+	assert.Equal(t, int32(http.StatusTooManyRequests), resp.Code)
+
+	// Another tenant is not affected.
+	require.True(t, stores.acquireInflightSeriesRequest("user-2"))
+	stores.releaseInflightSeriesRequest("user-2")
+
+	// Once the in-flight request completes, requests are served again.
+	stores.releaseInflightSeriesRequest(userID)
+	seriesSet, _, err := querySeries(stores, userID, "series_1", 0, 100)
+	require.NoError(t, err)
+	assert.Len(t, seriesSet, 1)
+	assert.Empty(t, stores.inflightSeries)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_bucket_stores_series_requests_rejected_total Total number of Series requests rejected because of a per-tenant limit.
+		# TYPE cortex_bucket_stores_series_requests_rejected_total counter
+		cortex_bucket_stores_series_requests_rejected_total{reason="max-fetched-chunk-bytes"} 0
+		cortex_bucket_stores_series_requests_rejected_total{reason="max-inflight-series-requests"} 1
+	`), "cortex_bucket_stores_series_requests_rejected_total"))
+}
+
+func TestBucketStores_Exemplars(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	cfg, cleanup := prepareStorageConfig(t)
+	defer cleanup()
+
+	storageDir, err := ioutil.TempDir(os.TempDir(), "storage-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, os.RemoveAll(storageDir)) })
+	generateStorageBlockWithSeries(t, storageDir, userID, []string{"series_1", "series_2"}, 0, 100, 1)
+
+	bkt, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	// Upload the exemplars of the generated block.
+	blockIDs, err := listLocalBlocks(filepath.Join(storageDir, userID))
+	require.NoError(t, err)
+	require.Len(t, blockIDs, 1)
+	blockID := blockIDs[0]
+
+	trace := []cortexpb.LabelAdapter{{Name: "traceID", Value: "123"}}
+	require.NoError(t, cortex_tsdb.UploadBlockExemplars(ctx, bucket.NewUserBucketClient(userID, bkt, nil), blockID, []cortexpb.TimeSeries{
+		{
+			Labels:    []cortexpb.LabelAdapter{{Name: labels.MetricName, Value: "series_1"}},
+			Exemplars: []cortexpb.Exemplar{{Labels: trace, Value: 1, TimestampMs: 10}, {Labels: trace, Value: 2, TimestampMs: 50}},
+		}, {
+			Labels:    []cortexpb.LabelAdapter{{Name: labels.MetricName, Value: "series_2"}},
+			Exemplars: []cortexpb.Exemplar{{Labels: trace, Value: 3, TimestampMs: 20}},
+		},
+	}))
+
+	stores, err := NewBucketStores(cfg, NewNoShardingStrategy(), bkt, defaultLimitsOverrides(t), mockLoggingLevel(), log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	require.NoError(t, stores.InitialSync(ctx))
+
+	// Only the blocks loaded by the store-gateway are queried.
+	notLoadedBlockID := ulid.MustNew(1, nil)
+
+	res, err := stores.Exemplars(setUserIDToGRPCContext(ctx, userID), &storegatewaypb.ExemplarsRequest{
+		MinTime: 0,
+		MaxTime: 40,
+		Matchers: []storegatewaypb.ExemplarsMatchers{
+			{Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: labels.MetricName, Value: "series_1"}}},
+		},
+		BlockIds: []string{blockID.String(), notLoadedBlockID.String()},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{blockID.String()}, res.QueriedBlockIds)
+	assert.Equal(t, []storegatewaypb.ExemplarSeries{
+		{
+			Labels:    []labelpb.ZLabel{{Name: labels.MetricName, Value: "series_1"}},
+			Exemplars: []storegatewaypb.Exemplar{{Labels: []labelpb.ZLabel{{Name: "traceID", Value: "123"}}, Value: 1, TimestampMs: 10}},
+		},
+	}, res.Series)
+}
+
 func prepareStorageConfig(t *testing.T) (cortex_tsdb.BlocksStorageConfig, func()) {
 	tmpDir, err := ioutil.TempDir(os.TempDir(), "blocks-sync-*")
 	require.NoError(t, err)
@@ -394,6 +657,10 @@ func prepareStorageConfig(t *testing.T) (cortex_tsdb.BlocksStorageConfig, func()
 }
 
 func generateStorageBlock(t *testing.T, storageDir, userID string, metricName string, minT, maxT int64, step int) {
+	generateStorageBlockWithSeries(t, storageDir, userID, []string{metricName}, minT, maxT, step)
+}
+
+func generateStorageBlockWithSeries(t *testing.T, storageDir, userID string, metricNames []string, minT, maxT int64, step int) {
 	// Create a directory for the user (if doesn't already exist).
 	userDir := filepath.Join(storageDir, userID)
 	if _, err := os.Stat(userDir); err != nil {
@@ -414,12 +681,14 @@ func generateStorageBlock(t *testing.T, storageDir, userID string, metricName st
 		require.NoError(t, db.Close())
 	}()
 
-	series := labels.Labels{labels.Label{Name: labels.MetricName, Value: metricName}}
-
 	app := db.Appender(context.Background())
-	for ts := minT; ts < maxT; ts += int64(step) {
-		_, err = app.Append(0, series, ts, 1)
-		require.NoError(t, err)
+	for _, metricName := range metricNames {
+		series := labels.Labels{labels.Label{Name: labels.MetricName, Value: metricName}}
+
+		for ts := minT; ts < maxT; ts += int64(step) {
+			_, err = app.Append(0, series, ts, 1)
+			require.NoError(t, err)
+		}
 	}
 	require.NoError(t, app.Commit())
 
@@ -428,14 +697,14 @@ func generateStorageBlock(t *testing.T, storageDir, userID string, metricName st
 }
 
 func querySeries(stores *BucketStores, userID, metricName string, minT, maxT int64) ([]*storepb.Series, storage.Warnings, error) {
+	return querySeriesWithMatcher(stores, userID, storepb.LabelMatcher{Type: storepb.LabelMatcher_EQ, Name: labels.MetricName, Value: metricName}, minT, maxT)
+}
+
+func querySeriesWithMatcher(stores *BucketStores, userID string, matcher storepb.LabelMatcher, minT, maxT int64) ([]*storepb.Series, storage.Warnings, error) {
 	req := &storepb.SeriesRequest{
-		MinTime: minT,
-		MaxTime: maxT,
-		Matchers: []storepb.LabelMatcher{{
-			Type:  storepb.LabelMatcher_EQ,
-			Name:  labels.MetricName,
-			Value: metricName,
-		}},
+		MinTime:                 minT,
+		MaxTime:                 maxT,
+		Matchers:                []storepb.LabelMatcher{matcher},
 		PartialResponseStrategy: storepb.PartialResponseStrategy_ABORT,
 	}
 
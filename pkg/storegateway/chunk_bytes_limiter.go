@@ -0,0 +1,50 @@
+package storegateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaveworks/common/httpgrpc"
+	"go.uber.org/atomic"
+
+	"github.com/cortexproject/cortex/pkg/storegateway/store"
+	"github.com/cortexproject/cortex/pkg/util/limiter"
+)
+
+// chunkBytesLimiter limits the size of the chunks fetched by a single Series request.
+type chunkBytesLimiter struct {
+	limit         uint64
+	reserved      atomic.Uint64
+	failedCounter prometheus.Counter
+}
+
+func newChunkBytesLimiter(limit int, failedCounter prometheus.Counter) *chunkBytesLimiter {
+	return &chunkBytesLimiter{
+		limit:         uint64(limit),
+		failedCounter: failedCounter,
+	}
+}
+
+// Reserve num bytes out of the limit. Returns an error if the limit has been reached.
+func (l *chunkBytesLimiter) Reserve(num uint64) error {
+	if l.limit == 0 {
+		return nil
+	}
+
+	if reserved := l.reserved.Add(num); reserved > l.limit {
+		// We need to protect from the counter being incremented twice due to concurrency
+		// while calling Reserve().
+		if reserved-num <= l.limit {
+			l.failedCounter.Inc()
+		}
+		return httpgrpc.Errorf(http.StatusUnprocessableEntity, limiter.ErrMaxChunkBytesHit, l.limit)
+	}
+
+	return nil
+}
+
+// withChunkBytesLimiter returns a context with the limiter enforced by the bucket store while loading the chunks.
+func withChunkBytesLimiter(ctx context.Context, l *chunkBytesLimiter) context.Context {
+	return store.ContextWithChunkBytesLimiter(ctx, l)
+}
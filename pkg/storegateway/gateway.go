@@ -47,6 +47,7 @@ var (
 	// Validation errors.
 	errInvalidShardingStrategy = errors.New("invalid sharding strategy")
 	errInvalidTenantShardSize  = errors.New("invalid tenant shard size, the value must be greater than 0")
+	errInvalidDataAgeRange     = errors.New("invalid data age range, the max data age must be greater than the min data age")
 )
 
 // Config holds the store gateway config.
@@ -74,6 +75,10 @@ func (cfg *Config) Validate(limits validation.Limits) error {
 		if cfg.ShardingStrategy == util.ShardingStrategyShuffle && limits.StoreGatewayTenantShardSize <= 0 {
 			return errInvalidTenantShardSize
 		}
+
+		if cfg.ShardingRing.MaxDataAge > 0 && cfg.ShardingRing.MaxDataAge <= cfg.ShardingRing.MinDataAge {
+			return errInvalidDataAgeRange
+		}
 	}
 
 	return nil
@@ -175,9 +180,9 @@ func newStoreGateway(gatewayCfg Config, storageCfg cortex_tsdb.BlocksStorageConf
 		// Instance the right strategy.
 		switch gatewayCfg.ShardingStrategy {
 		case util.ShardingStrategyDefault:
-			shardingStrategy = NewDefaultShardingStrategy(g.ring, lifecyclerCfg.Addr, logger)
+			shardingStrategy = NewDefaultShardingStrategy(g.ring, lifecyclerCfg.Addr, storageCfg.BucketStore.SyncInterval, logger)
 		case util.ShardingStrategyShuffle:
-			shardingStrategy = NewShuffleShardingStrategy(g.ring, lifecyclerCfg.ID, lifecyclerCfg.Addr, limits, logger)
+			shardingStrategy = NewShuffleShardingStrategy(g.ring, lifecyclerCfg.ID, lifecyclerCfg.Addr, storageCfg.BucketStore.SyncInterval, limits, logger)
 		default:
 			return nil, errInvalidShardingStrategy
 		}
@@ -315,10 +320,16 @@ func (g *StoreGateway) running(ctx context.Context) error {
 }
 
 func (g *StoreGateway) stopping(_ error) error {
+	var err error
 	if g.subservices != nil {
-		return services.StopManagerAndAwaitStopped(context.Background(), g.subservices)
+		err = services.StopManagerAndAwaitStopped(context.Background(), g.subservices)
 	}
-	return nil
+
+	if closeErr := g.stores.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
 }
 
 func (g *StoreGateway) syncStores(ctx context.Context, reason string) {
@@ -346,6 +357,11 @@ func (g *StoreGateway) LabelValues(ctx context.Context, req *storepb.LabelValues
 	return g.stores.LabelValues(ctx, req)
 }
 
+// Exemplars implements the Storegateway proto service.
+func (g *StoreGateway) Exemplars(ctx context.Context, req *storegatewaypb.ExemplarsRequest) (*storegatewaypb.ExemplarsResponse, error) {
+	return g.stores.Exemplars(ctx, req)
+}
+
 func (g *StoreGateway) OnRingInstanceRegister(_ *ring.BasicLifecycler, ringDesc ring.Desc, instanceExists bool, instanceID string, instanceDesc ring.InstanceDesc) (ring.InstanceState, ring.Tokens) {
 	// When we initialize the store-gateway instance in the ring we want to start from
 	// a clean situation, so whatever is the state we set it JOINING, while we keep existing
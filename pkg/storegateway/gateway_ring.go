@@ -70,6 +70,10 @@ type RingConfig struct {
 	WaitStabilityMinDuration time.Duration `yaml:"wait_stability_min_duration"`
 	WaitStabilityMaxDuration time.Duration `yaml:"wait_stability_max_duration"`
 
+	// Time-based sharding.
+	MinDataAge time.Duration `yaml:"min_data_age"`
+	MaxDataAge time.Duration `yaml:"max_data_age"`
+
 	// Instance details
 	InstanceID             string   `yaml:"instance_id" doc:"hidden"`
 	InstanceInterfaceNames []string `yaml:"instance_interface_names"`
@@ -104,6 +108,10 @@ func (cfg *RingConfig) RegisterFlags(f *flag.FlagSet) {
 	f.DurationVar(&cfg.WaitStabilityMinDuration, ringFlagsPrefix+"wait-stability-min-duration", time.Minute, "Minimum time to wait for ring stability at startup. 0 to disable.")
 	f.DurationVar(&cfg.WaitStabilityMaxDuration, ringFlagsPrefix+"wait-stability-max-duration", 5*time.Minute, "Maximum time to wait for ring stability at startup. If the store-gateway ring keeps changing after this period of time, the store-gateway will start anyway.")
 
+	// Time-based sharding flags.
+	f.DurationVar(&cfg.MinDataAge, ringFlagsPrefix+"min-data-age", 0, "Min age of the blocks served by this store-gateway. A block is loaded only if it contains samples older than this age. Used together with -"+ringFlagsPrefix+"max-data-age to run store-gateways dedicated to a time range (e.g. hot and cold tiers). 0 to disable.")
+	f.DurationVar(&cfg.MaxDataAge, ringFlagsPrefix+"max-data-age", 0, "Max age of the blocks served by this store-gateway. A block is loaded only if it contains samples newer than this age. Used together with -"+ringFlagsPrefix+"min-data-age to run store-gateways dedicated to a time range (e.g. hot and cold tiers). 0 to disable.")
+
 	// Instance flags
 	cfg.InstanceInterfaceNames = []string{"eth0", "en0"}
 	f.Var((*flagext.StringSlice)(&cfg.InstanceInterfaceNames), ringFlagsPrefix+"instance-interface-names", "Name of network interface to read address from.")
@@ -144,5 +152,7 @@ func (cfg *RingConfig) ToLifecyclerConfig() (ring.BasicLifecyclerConfig, error)
 		HeartbeatPeriod:     cfg.HeartbeatPeriod,
 		TokensObservePeriod: 0,
 		NumTokens:           RingNumTokens,
+		MinDataAge:          cfg.MinDataAge,
+		MaxDataAge:          cfg.MaxDataAge,
 	}, nil
 }
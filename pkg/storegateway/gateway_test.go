@@ -80,6 +80,21 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expected: nil,
 		},
+		"should fail if the max data age is not greater than the min data age": {
+			setup: func(cfg *Config, limits *validation.Limits) {
+				cfg.ShardingEnabled = true
+				cfg.ShardingRing.MinDataAge = 14 * 24 * time.Hour
+				cfg.ShardingRing.MaxDataAge = 7 * 24 * time.Hour
+			},
+			expected: errInvalidDataAgeRange,
+		},
+		"should pass if only the min data age has been set": {
+			setup: func(cfg *Config, limits *validation.Limits) {
+				cfg.ShardingEnabled = true
+				cfg.ShardingRing.MinDataAge = 14 * 24 * time.Hour
+			},
+			expected: nil,
+		},
 	}
 
 	for testName, testData := range tests {
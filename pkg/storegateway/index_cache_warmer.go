@@ -0,0 +1,489 @@
+package storegateway
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/gogo/protobuf/types"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"go.uber.org/atomic"
+
+	"github.com/cortexproject/cortex/pkg/storage/tsdb"
+	"github.com/cortexproject/cortex/pkg/storegateway/store"
+	"github.com/cortexproject/cortex/pkg/util/concurrency"
+)
+
+const (
+	// The name of the file, in the tenant sync dir, where the most queried matchers are persisted,
+	// so that they survive a store-gateway restart.
+	queriedMatchersFilename = "index-cache-warmup-matchers.json"
+
+	// The number of matchers sets tracked for each tenant, relative to the number of matchers
+	// sets used to warm up the index cache. We track more matchers sets than the ones we use,
+	// to give to recently queried matchers the chance to climb the ranking.
+	queriedMatchersTrackedFactor = 10
+)
+
+// queriedMatchers is a set of label matchers received in a Series request and the
+// (decayed) number of times it has been queried.
+type queriedMatchers struct {
+	Matchers []storepb.LabelMatcher `json:"matchers"`
+	Count    float64                `json:"count"`
+}
+
+// tenantWarmupState holds the warm-up state of a single tenant.
+type tenantWarmupState struct {
+	// The most queried matchers sets, by key.
+	matchers map[string]*queriedMatchers
+
+	// Whether the matchers persisted on disk have been loaded.
+	loaded bool
+
+	// The local blocks whose index cache has already been warmed up.
+	warmed map[ulid.ULID]struct{}
+}
+
+// inflightWarmup is a warm-up of a single tenant which is running.
+type inflightWarmup struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// indexCacheWarmer pre-populates the index cache with the postings and series of the most
+// queried label matchers each time new blocks are loaded by the store-gateway (eg. after a
+// ring change), so that the new blocks owner doesn't serve queries with a cold index cache.
+//
+// The matchers of each Series request are tracked per tenant. Their counts are halved at
+// each warm-up, so that the ranking reflects the recently queried matchers.
+type indexCacheWarmer struct {
+	cfg    tsdb.IndexCacheWarmupConfig
+	logger log.Logger
+
+	tenantsMx sync.Mutex
+	tenants   map[string]*tenantWarmupState
+
+	// The tenants warm-ups which are running, so that they can be interrupted once
+	// the tenant is closed.
+	inflightMx sync.Mutex
+	inflight   map[string]*inflightWarmup
+
+	// Whether a background warm-up is running, and the wait group tracking it.
+	running *atomic.Bool
+	wg      sync.WaitGroup
+
+	// Context canceled on stop, to interrupt the running warm-ups.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Metrics.
+	warmupRequests prometheus.Counter
+	warmupFailures prometheus.Counter
+	warmedBlocks   prometheus.Counter
+	warmupDuration prometheus.Histogram
+}
+
+func newIndexCacheWarmer(cfg tsdb.IndexCacheWarmupConfig, logger log.Logger, reg prometheus.Registerer) *indexCacheWarmer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &indexCacheWarmer{
+		cfg:      cfg,
+		logger:   logger,
+		tenants:  map[string]*tenantWarmupState{},
+		inflight: map[string]*inflightWarmup{},
+		running:  atomic.NewBool(false),
+		ctx:      ctx,
+		cancel:   cancel,
+		warmupRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_bucket_stores_index_cache_warmup_requests_total",
+			Help: "Total number of Series requests run to warm up the index cache.",
+		}),
+		warmupFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_bucket_stores_index_cache_warmup_failures_total",
+			Help: "Total number of Series requests run to warm up the index cache which failed.",
+		}),
+		warmedBlocks: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_bucket_stores_index_cache_warmup_blocks_total",
+			Help: "Total number of blocks whose index cache has been warmed up.",
+		}),
+		warmupDuration: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:    "cortex_bucket_stores_index_cache_warmup_duration_seconds",
+			Help:    "Time spent warming up the index cache after a blocks synchronization.",
+			Buckets: []float64{0.1, 1, 10, 30, 60, 120, 300, 600},
+		}),
+	}
+}
+
+// record tracks the matchers of a Series request received for the input tenant.
+func (w *indexCacheWarmer) record(userID string, matchers []storepb.LabelMatcher) {
+	if len(matchers) == 0 {
+		return
+	}
+
+	key := storepb.MatchersToString(matchers...)
+
+	w.tenantsMx.Lock()
+	defer w.tenantsMx.Unlock()
+
+	state := w.getTenant(userID)
+	if m, ok := state.matchers[key]; ok {
+		m.Count++
+		return
+	}
+
+	// Make room for the new matchers evicting the least queried ones.
+	if len(state.matchers) >= w.cfg.MaxMatchersPerTenant*queriedMatchersTrackedFactor {
+		evictKey, evictCount := "", math.MaxFloat64
+		for k, m := range state.matchers {
+			if m.Count < evictCount {
+				evictKey, evictCount = k, m.Count
+			}
+		}
+		delete(state.matchers, evictKey)
+	}
+
+	// The input matchers are copied because they belong to the request.
+	state.matchers[key] = &queriedMatchers{
+		Matchers: append([]storepb.LabelMatcher(nil), matchers...),
+		Count:    1,
+	}
+}
+
+// warmup runs the warm-up of the input tenants' blocks which have not been warmed up yet,
+// until all blocks have been warmed up, the configured timeout expires or the warmer is stopped.
+// Failures are logged and never returned, because the warm-up is a best-effort optimization.
+func (w *indexCacheWarmer) warmup(ctx context.Context, userIDs []string, getStore func(string) *store.BucketStore, syncDirForUser func(string) string, concurrencyLimit int) {
+	start := time.Now()
+	defer func() {
+		w.warmupDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.Timeout)
+	defer cancel()
+
+	// Interrupt the warm-up once the warmer is stopped.
+	go func() {
+		select {
+		case <-w.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	_ = concurrency.ForEachUser(ctx, userIDs, concurrencyLimit, func(ctx context.Context, userID string) error {
+		ctx, done := w.startUserWarmup(ctx, userID)
+		defer done()
+
+		// The store is looked up once the warm-up has been tracked, so that a tenant closed in
+		// the meanwhile is either skipped or its warm-up interrupted by closeUser().
+		if bs := getStore(userID); bs != nil {
+			w.warmupUser(ctx, userID, syncDirForUser(userID), bs)
+		}
+		return nil
+	})
+
+	if ctx.Err() == context.DeadlineExceeded {
+		level.Warn(w.logger).Log("msg", "index cache warm-up interrupted because the timeout expired", "timeout", w.cfg.Timeout)
+	}
+}
+
+// warmupAsync runs the warm-up in background. The warm-up is skipped if the previous one is
+// still running: the blocks which have not been warmed up yet will be warmed up by the next one.
+func (w *indexCacheWarmer) warmupAsync(ctx context.Context, userIDs []string, getStore func(string) *store.BucketStore, syncDirForUser func(string) string, concurrencyLimit int) {
+	if !w.running.CAS(false, true) {
+		level.Debug(w.logger).Log("msg", "skipped index cache warm-up because the previous one is still running")
+		return
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer w.running.Store(false)
+
+		w.warmup(ctx, userIDs, getStore, syncDirForUser, concurrencyLimit)
+	}()
+}
+
+// startUserWarmup tracks the warm-up of the input tenant as running. The returned function
+// must be called once the warm-up completed.
+func (w *indexCacheWarmer) startUserWarmup(ctx context.Context, userID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	warmup := &inflightWarmup{cancel: cancel, done: make(chan struct{})}
+
+	w.inflightMx.Lock()
+	w.inflight[userID] = warmup
+	w.inflightMx.Unlock()
+
+	return ctx, func() {
+		w.inflightMx.Lock()
+		delete(w.inflight, userID)
+		w.inflightMx.Unlock()
+
+		cancel()
+		close(warmup.done)
+	}
+}
+
+// stop interrupts the running warm-ups and waits until they've completed.
+func (w *indexCacheWarmer) stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *indexCacheWarmer) warmupUser(ctx context.Context, userID, userDir string, bs *store.BucketStore) {
+	blockIDs, err := listLocalBlocks(userDir)
+	if err != nil {
+		level.Warn(w.logger).Log("msg", "failed to list local blocks to warm up the index cache", "user", userID, "err", err)
+		return
+	}
+
+	newBlockIDs, top := w.prepareUser(userID, userDir, blockIDs)
+	if len(newBlockIDs) == 0 {
+		return
+	}
+
+	// Even if there are no matchers to warm up the index cache, the blocks are marked as warmed up
+	// in order to not warm them up later (when they're already serving queries).
+	if len(top) > 0 {
+		hints, err := types.MarshalAny(&hintspb.SeriesRequestHints{
+			BlockMatchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_RE, Name: block.BlockIDLabel, Value: joinBlockIDs(newBlockIDs)}},
+		})
+		if err != nil {
+			level.Warn(w.logger).Log("msg", "failed to build series request hints to warm up the index cache", "user", userID, "err", err)
+			return
+		}
+
+		for _, matchers := range top {
+			if ctx.Err() != nil {
+				// The remaining blocks will be warmed up at the next sync.
+				return
+			}
+
+			req := &storepb.SeriesRequest{
+				MinTime:                 math.MinInt64,
+				MaxTime:                 math.MaxInt64,
+				Matchers:                matchers,
+				SkipChunks:              true,
+				PartialResponseStrategy: storepb.PartialResponseStrategy_ABORT,
+				Hints:                   hints,
+			}
+
+			w.warmupRequests.Inc()
+			if err := bs.Series(req, newBucketStoreSeriesServer(ctx)); err != nil {
+				w.warmupFailures.Inc()
+				level.Warn(w.logger).Log("msg", "failed to warm up the index cache", "user", userID, "matchers", storepb.MatchersToString(matchers...), "err", err)
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	w.markWarmed(userID, userDir, newBlockIDs)
+	level.Info(w.logger).Log("msg", "warmed up the index cache", "user", userID, "blocks", len(newBlockIDs), "matchers", len(top))
+}
+
+// prepareUser returns the local blocks which have not been warmed up yet and the most
+// queried matchers sets.
+func (w *indexCacheWarmer) prepareUser(userID, userDir string, blockIDs []ulid.ULID) ([]ulid.ULID, [][]storepb.LabelMatcher) {
+	w.tenantsMx.Lock()
+	defer w.tenantsMx.Unlock()
+
+	state := w.getTenant(userID)
+	if !state.loaded {
+		w.loadMatchers(userID, userDir, state)
+	}
+
+	// Forget about the blocks which are not on the local disk anymore, so that
+	// they're warmed up again if the store-gateway takes back their ownership.
+	local := make(map[ulid.ULID]struct{}, len(blockIDs))
+	var newBlockIDs []ulid.ULID
+	for _, id := range blockIDs {
+		local[id] = struct{}{}
+		if _, ok := state.warmed[id]; !ok {
+			newBlockIDs = append(newBlockIDs, id)
+		}
+	}
+	for id := range state.warmed {
+		if _, ok := local[id]; !ok {
+			delete(state.warmed, id)
+		}
+	}
+
+	if len(newBlockIDs) == 0 {
+		return nil, nil
+	}
+
+	ranked := make([]*queriedMatchers, 0, len(state.matchers))
+	for _, m := range state.matchers {
+		ranked = append(ranked, m)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return storepb.MatchersToString(ranked[i].Matchers...) < storepb.MatchersToString(ranked[j].Matchers...)
+	})
+	if len(ranked) > w.cfg.MaxMatchersPerTenant {
+		ranked = ranked[:w.cfg.MaxMatchersPerTenant]
+	}
+
+	top := make([][]storepb.LabelMatcher, 0, len(ranked))
+	for _, m := range ranked {
+		top = append(top, m.Matchers)
+	}
+
+	return newBlockIDs, top
+}
+
+// markWarmed marks the input blocks as warmed up, decays the matchers counts
+// and persists the matchers on disk.
+func (w *indexCacheWarmer) markWarmed(userID, userDir string, blockIDs []ulid.ULID) {
+	w.tenantsMx.Lock()
+	defer w.tenantsMx.Unlock()
+
+	state := w.getTenant(userID)
+	for _, id := range blockIDs {
+		state.warmed[id] = struct{}{}
+	}
+	w.warmedBlocks.Add(float64(len(blockIDs)))
+
+	for _, m := range state.matchers {
+		m.Count /= 2
+	}
+
+	if err := w.storeMatchers(userDir, state); err != nil {
+		level.Warn(w.logger).Log("msg", "failed to persist the most queried matchers", "user", userID, "err", err)
+	}
+}
+
+// closeUser interrupts the running warm-up of the input tenant, waiting until it has
+// completed, and removes the warm-up state of the tenant.
+func (w *indexCacheWarmer) closeUser(userID string) {
+	w.inflightMx.Lock()
+	warmup := w.inflight[userID]
+	w.inflightMx.Unlock()
+
+	if warmup != nil {
+		warmup.cancel()
+		<-warmup.done
+	}
+
+	w.tenantsMx.Lock()
+	defer w.tenantsMx.Unlock()
+
+	delete(w.tenants, userID)
+}
+
+// getTenant returns the state of the input tenant, creating it if it doesn't exist.
+// The caller must hold the lock.
+func (w *indexCacheWarmer) getTenant(userID string) *tenantWarmupState {
+	state, ok := w.tenants[userID]
+	if !ok {
+		state = &tenantWarmupState{
+			matchers: map[string]*queriedMatchers{},
+			warmed:   map[ulid.ULID]struct{}{},
+		}
+		w.tenants[userID] = state
+	}
+	return state
+}
+
+// loadMatchers merges the matchers persisted on disk into the input state. The caller must hold the lock.
+func (w *indexCacheWarmer) loadMatchers(userID, userDir string, state *tenantWarmupState) {
+	state.loaded = true
+
+	data, err := ioutil.ReadFile(filepath.Join(userDir, queriedMatchersFilename))
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		level.Warn(w.logger).Log("msg", "failed to read the most queried matchers", "user", userID, "err", err)
+		return
+	}
+
+	var persisted []*queriedMatchers
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		level.Warn(w.logger).Log("msg", "failed to decode the most queried matchers", "user", userID, "err", err)
+		return
+	}
+
+	for _, m := range persisted {
+		key := storepb.MatchersToString(m.Matchers...)
+		if existing, ok := state.matchers[key]; ok {
+			existing.Count += m.Count
+			continue
+		}
+		if len(state.matchers) < w.cfg.MaxMatchersPerTenant*queriedMatchersTrackedFactor {
+			state.matchers[key] = m
+		}
+	}
+}
+
+// storeMatchers persists the matchers of the input state on disk. The caller must hold the lock.
+func (w *indexCacheWarmer) storeMatchers(userDir string, state *tenantWarmupState) error {
+	persisted := make([]*queriedMatchers, 0, len(state.matchers))
+	for _, m := range state.matchers {
+		persisted = append(persisted, m)
+	}
+
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first and then rename it, to never leave a partially written file.
+	tmpPath := filepath.Join(userDir, queriedMatchersFilename+".tmp")
+	if err := ioutil.WriteFile(tmpPath, data, 0666); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(userDir, queriedMatchersFilename))
+}
+
+// listLocalBlocks returns the IDs of the blocks in the input tenant sync dir.
+func listLocalBlocks(userDir string) ([]ulid.ULID, error) {
+	entries, err := ioutil.ReadDir(userDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read dir")
+	}
+
+	var ids []ulid.ULID
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if id, ok := block.IsBlockDir(entry.Name()); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func joinBlockIDs(ids []ulid.ULID) string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id.String())
+	}
+	return strings.Join(values, "|")
+}
@@ -0,0 +1,231 @@
+package storegateway
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"go.uber.org/atomic"
+
+	"github.com/cortexproject/cortex/pkg/storage/bucket/filesystem"
+	cortex_tsdb "github.com/cortexproject/cortex/pkg/storage/tsdb"
+	"github.com/cortexproject/cortex/pkg/storegateway/store"
+)
+
+func TestBucketStores_ShouldWarmUpIndexCacheOfNewBlocks(t *testing.T) {
+	const userID = "user-1"
+
+	storageDir, err := ioutil.TempDir(os.TempDir(), "storage-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, os.RemoveAll(storageDir)) })
+
+	series := []labels.Labels{
+		labels.FromStrings(labels.MetricName, "up", "job", "small"),
+		labels.FromStrings(labels.MetricName, "up", "job", "big"),
+	}
+	generateStorageBlockWithLabels(t, storageDir, userID, series, 0, 100)
+
+	bucket, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	cfg, cleanup := prepareStorageConfig(t)
+	defer cleanup()
+	cfg.BucketStore.IndexCacheWarmup = cortex_tsdb.IndexCacheWarmupConfig{Enabled: true, MaxMatchersPerTenant: 5, Timeout: time.Minute}
+
+	reg := prometheus.NewPedanticRegistry()
+	stores, err := NewBucketStores(cfg, NewNoShardingStrategy(), bucket, defaultLimitsOverrides(t), mockLoggingLevel(), log.NewNopLogger(), reg)
+	require.NoError(t, err)
+
+	// No matchers have been queried yet, so the initial sync doesn't run any warm-up request
+	// but marks the block as warmed up.
+	require.NoError(t, stores.InitialSync(context.Background()))
+	assert.Equal(t, float64(0), testutil.ToFloat64(stores.indexCacheWarmer.warmupRequests))
+	assert.Equal(t, float64(1), testutil.ToFloat64(stores.indexCacheWarmer.warmedBlocks))
+
+	// Query the store-gateway to track the matchers.
+	matcher := storepb.LabelMatcher{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "small"}
+	seriesSet, _, err := querySeriesWithMatcher(stores, userID, matcher, 0, 100)
+	require.NoError(t, err)
+	require.Len(t, seriesSet, 1)
+
+	// Upload a new block and sync it. Only the new block should be warmed up, in background.
+	generateStorageBlockWithLabels(t, storageDir, userID, series, 100, 200)
+	itemsAddedBefore := sumMetricValues(t, reg, "thanos_store_index_cache_items_added_total")
+
+	require.NoError(t, stores.SyncBlocks(context.Background()))
+	stores.indexCacheWarmer.wg.Wait()
+	assert.Equal(t, float64(1), testutil.ToFloat64(stores.indexCacheWarmer.warmupRequests))
+	assert.Equal(t, float64(0), testutil.ToFloat64(stores.indexCacheWarmer.warmupFailures))
+	assert.Equal(t, float64(2), testutil.ToFloat64(stores.indexCacheWarmer.warmedBlocks))
+	assert.Greater(t, sumMetricValues(t, reg, "thanos_store_index_cache_items_added_total"), itemsAddedBefore)
+
+	// A sync without new blocks shouldn't run any warm-up request.
+	require.NoError(t, stores.SyncBlocks(context.Background()))
+	stores.indexCacheWarmer.wg.Wait()
+	assert.Equal(t, float64(1), testutil.ToFloat64(stores.indexCacheWarmer.warmupRequests))
+
+	// The queried matchers should have been persisted, so that a restarted store-gateway
+	// warms up all blocks with them.
+	assert.FileExists(t, filepath.Join(cfg.BucketStore.SyncDir, userID, queriedMatchersFilename))
+
+	restarted, err := NewBucketStores(cfg, NewNoShardingStrategy(), bucket, defaultLimitsOverrides(t), mockLoggingLevel(), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	require.NoError(t, restarted.InitialSync(context.Background()))
+	assert.Equal(t, float64(1), testutil.ToFloat64(restarted.indexCacheWarmer.warmupRequests))
+	assert.Equal(t, float64(2), testutil.ToFloat64(restarted.indexCacheWarmer.warmedBlocks))
+}
+
+func TestIndexCacheWarmer_ShouldSkipBackgroundWarmupWhileThePreviousOneIsRunning(t *testing.T) {
+	w := newIndexCacheWarmer(cortex_tsdb.IndexCacheWarmupConfig{Enabled: true, MaxMatchersPerTenant: 1, Timeout: time.Minute}, log.NewNopLogger(), nil)
+
+	// Simulate a running warm-up.
+	w.running.Store(true)
+	w.warmupAsync(context.Background(), []string{"user-1"}, func(string) *store.BucketStore { return nil }, func(string) string { return "" }, 1)
+	w.wg.Wait()
+	assert.True(t, w.running.Load(), "the warm-up should have been skipped")
+
+	w.running.Store(false)
+	w.warmupAsync(context.Background(), nil, func(string) *store.BucketStore { return nil }, func(string) string { return "" }, 1)
+	w.wg.Wait()
+	assert.False(t, w.running.Load())
+}
+
+func TestIndexCacheWarmer_StopShouldInterruptTheBackgroundWarmupAndWaitForIt(t *testing.T) {
+	w := newIndexCacheWarmer(cortex_tsdb.IndexCacheWarmupConfig{Enabled: true, MaxMatchersPerTenant: 1, Timeout: time.Minute}, log.NewNopLogger(), nil)
+
+	started := make(chan struct{})
+	completed := atomic.NewBool(false)
+	getStore := func(string) *store.BucketStore {
+		close(started)
+		<-w.ctx.Done()
+		completed.Store(true)
+		return nil
+	}
+
+	w.warmupAsync(context.Background(), []string{"user-1"}, getStore, func(string) string { return "" }, 1)
+	<-started
+
+	w.stop()
+	assert.True(t, completed.Load())
+	assert.False(t, w.running.Load())
+}
+
+func TestIndexCacheWarmer_CloseUserShouldInterruptTheUserWarmupAndWaitForIt(t *testing.T) {
+	w := newIndexCacheWarmer(cortex_tsdb.IndexCacheWarmupConfig{Enabled: true, MaxMatchersPerTenant: 1, Timeout: time.Minute}, log.NewNopLogger(), nil)
+
+	ctx, done := w.startUserWarmup(context.Background(), "user-1")
+	closed := make(chan struct{})
+	go func() {
+		w.closeUser("user-1")
+		close(closed)
+	}()
+
+	// The user warm-up is interrupted, but closeUser() waits until it has completed.
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		require.Fail(t, "the user warm-up has not been interrupted")
+	}
+
+	select {
+	case <-closed:
+		require.Fail(t, "closeUser() should wait until the user warm-up has completed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	done()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		require.Fail(t, "closeUser() has not returned once the user warm-up completed")
+	}
+}
+
+func TestIndexCacheWarmer_ShouldRankMostQueriedMatchers(t *testing.T) {
+	const userID = "user-1"
+
+	userDir, err := ioutil.TempDir(os.TempDir(), "warmup-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, os.RemoveAll(userDir)) })
+
+	w := newIndexCacheWarmer(cortex_tsdb.IndexCacheWarmupConfig{Enabled: true, MaxMatchersPerTenant: 2, Timeout: time.Minute}, log.NewNopLogger(), nil)
+
+	matchersFor := func(job string) []storepb.LabelMatcher {
+		return []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "job", Value: job}}
+	}
+
+	// Query "a" 3 times, "b" 2 times and "c" once.
+	for job, count := range map[string]int{"a": 3, "b": 2, "c": 1} {
+		for i := 0; i < count; i++ {
+			w.record(userID, matchersFor(job))
+		}
+	}
+
+	block1 := ulid.MustNew(1, nil)
+	block2 := ulid.MustNew(2, nil)
+
+	newBlocks, top := w.prepareUser(userID, userDir, []ulid.ULID{block1})
+	assert.Equal(t, []ulid.ULID{block1}, newBlocks)
+	assert.Equal(t, [][]storepb.LabelMatcher{matchersFor("a"), matchersFor("b")}, top)
+
+	// Once warmed up, the block is not returned anymore and the counts are decayed.
+	w.markWarmed(userID, userDir, newBlocks)
+	newBlocks, _ = w.prepareUser(userID, userDir, []ulid.ULID{block1})
+	assert.Empty(t, newBlocks)
+
+	// After the decay, recently queried matchers climb the ranking.
+	for i := 0; i < 2; i++ {
+		w.record(userID, matchersFor("c"))
+	}
+
+	newBlocks, top = w.prepareUser(userID, userDir, []ulid.ULID{block1, block2})
+	assert.Equal(t, []ulid.ULID{block2}, newBlocks)
+	assert.Equal(t, [][]storepb.LabelMatcher{matchersFor("c"), matchersFor("a")}, top)
+}
+
+func TestIndexCacheWarmer_ShouldEvictLeastQueriedMatchers(t *testing.T) {
+	const userID = "user-1"
+
+	w := newIndexCacheWarmer(cortex_tsdb.IndexCacheWarmupConfig{Enabled: true, MaxMatchersPerTenant: 1, Timeout: time.Minute}, log.NewNopLogger(), nil)
+
+	for i := 0; i < queriedMatchersTrackedFactor*2; i++ {
+		w.record(userID, []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: "pod", Value: string(rune('a' + i))}})
+	}
+
+	assert.Len(t, w.tenants[userID].matchers, queriedMatchersTrackedFactor)
+}
+
+func sumMetricValues(t *testing.T, reg prometheus.Gatherer, name string) float64 {
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	sum := float64(0)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			switch {
+			case m.GetCounter() != nil:
+				sum += m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				sum += m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	require.False(t, math.IsNaN(sum))
+	return sum
+}
@@ -8,7 +8,8 @@ import (
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"github.com/thanos-io/thanos/pkg/store"
+
+	"github.com/cortexproject/cortex/pkg/storegateway/store"
 )
 
 func TestGapBasedPartitioner_Partition(t *testing.T) {
@@ -0,0 +1,343 @@
+package storegateway
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/gogo/protobuf/types"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/indexheader"
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+
+	"github.com/cortexproject/cortex/pkg/storage/tsdb"
+	"github.com/cortexproject/cortex/pkg/storegateway/store"
+)
+
+const (
+	// The max number of label values for which the postings size is looked up when estimating
+	// the postings size of a matcher. If a label has more values, they're sampled.
+	maxEstimatedLabelValues = 256
+
+	// The max number of matchers whose estimated postings size is cached for each block.
+	maxCachedEstimatesPerBlock = 1000
+)
+
+// postingsPlanner estimates the size of the postings each matcher of a Series request expands to,
+// looking up the postings offsets in the index-header of the queried blocks. The matchers whose
+// postings are much bigger than the postings of the most selective matcher are removed from the
+// request and deferred to series-level filtering: the series matched by the remaining matchers are
+// filtered by the deferred matchers before their chunks are fetched. Since the series are always
+// selected by the most selective matcher, deferring a matcher never increases the number of series
+// loaded by more than the size of the most selective postings, while it saves fetching and
+// intersecting the (much bigger) postings of the deferred matcher.
+type postingsPlanner struct {
+	logger       log.Logger
+	minCostRatio float64
+
+	// Estimated postings size of the matchers, for each tenant and block. Blocks are immutable,
+	// so the estimates are cached until the block is unloaded.
+	estimatesMx sync.Mutex
+	estimates   map[string]map[ulid.ULID]map[string]int64
+
+	// Metrics.
+	plannedRequests      prometheus.Counter
+	deferredRequests     prometheus.Counter
+	deferredMatchers     prometheus.Counter
+	skippedPostingsBytes prometheus.Counter
+	filteredSeries       prometheus.Counter
+}
+
+func newPostingsPlanner(cfg tsdb.BucketStoreConfig, logger log.Logger, reg prometheus.Registerer) *postingsPlanner {
+	return &postingsPlanner{
+		logger:       logger,
+		minCostRatio: cfg.LazyPostings.MinCostRatio,
+		estimates:    map[string]map[ulid.ULID]map[string]int64{},
+		plannedRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_bucket_stores_lazy_postings_planned_requests_total",
+			Help: "Total number of Series requests whose matchers have been evaluated by the postings planner.",
+		}),
+		deferredRequests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_bucket_stores_lazy_postings_deferred_requests_total",
+			Help: "Total number of Series requests for which at least one matcher has been deferred to series-level filtering, skipping its postings.",
+		}),
+		deferredMatchers: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_bucket_stores_lazy_postings_deferred_matchers_total",
+			Help: "Total number of matchers deferred to series-level filtering, skipping their postings.",
+		}),
+		skippedPostingsBytes: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_bucket_stores_lazy_postings_skipped_bytes_total",
+			Help: "Total estimated size in bytes of the postings skipped because their matchers have been deferred to series-level filtering.",
+		}),
+		filteredSeries: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_bucket_stores_lazy_postings_filtered_series_total",
+			Help: "Total number of series discarded by the series-level filtering of deferred matchers.",
+		}),
+	}
+}
+
+// plan returns the matchers to run against the index and the matchers deferred to series-level filtering.
+func (p *postingsPlanner) plan(userID string, bs *store.BucketStore, req *storepb.SeriesRequest) ([]storepb.LabelMatcher, []*labels.Matcher) {
+	// There's nothing to defer if there's a single matcher.
+	if len(req.Matchers) < 2 {
+		return req.Matchers, nil
+	}
+
+	blockIDs := seriesRequestBlockIDs(req)
+	if len(blockIDs) == 0 {
+		return req.Matchers, nil
+	}
+
+	matchers, err := storepb.MatchersToPromMatchers(req.Matchers...)
+	if err != nil {
+		return req.Matchers, nil
+	}
+
+	costs := make([]int64, len(matchers))
+	estimated := false
+
+	for _, blockID := range blockIDs {
+		// The index-header reader of the block is the one loaded by the bucket store, so
+		// blocks not loaded by the store-gateway (or unloaded meanwhile) are skipped.
+		loaded, err := bs.IndexHeaderReader(blockID, func(reader indexheader.Reader) error {
+			for i, m := range matchers {
+				cost, err := p.estimate(userID, blockID, reader, m)
+				if err != nil {
+					return errors.Wrapf(err, "matcher %s", m.String())
+				}
+				costs[i] += cost
+			}
+			return nil
+		})
+		if err != nil {
+			level.Warn(p.logger).Log("msg", "failed to estimate postings size", "user", userID, "block", blockID.String(), "err", err)
+			return req.Matchers, nil
+		}
+		if !loaded {
+			p.dropBlock(userID, blockID)
+			continue
+		}
+		estimated = true
+	}
+
+	if !estimated {
+		return req.Matchers, nil
+	}
+
+	p.plannedRequests.Inc()
+
+	// The series are selected by the cheapest matcher which doesn't match the empty string,
+	// because the others would require to expand the postings of all series.
+	anchor := -1
+	for i, m := range matchers {
+		if m.Matches("") {
+			continue
+		}
+		if anchor < 0 || costs[i] < costs[anchor] {
+			anchor = i
+		}
+	}
+	if anchor < 0 {
+		return req.Matchers, nil
+	}
+
+	var (
+		remaining = make([]storepb.LabelMatcher, 0, len(matchers))
+		deferred  []*labels.Matcher
+	)
+
+	for i, m := range matchers {
+		if i != anchor && costs[i] > 0 && float64(costs[i]) >= p.minCostRatio*float64(costs[anchor]) {
+			deferred = append(deferred, m)
+			p.deferredMatchers.Inc()
+			p.skippedPostingsBytes.Add(float64(costs[i]))
+			continue
+		}
+
+		remaining = append(remaining, req.Matchers[i])
+	}
+
+	if len(deferred) > 0 {
+		p.deferredRequests.Inc()
+	}
+
+	return remaining, deferred
+}
+
+// estimate returns the estimated postings size of the matcher in the input block, looking it up
+// in the cache first.
+func (p *postingsPlanner) estimate(userID string, blockID ulid.ULID, reader indexheader.Reader, m *labels.Matcher) (int64, error) {
+	key := m.String()
+
+	p.estimatesMx.Lock()
+	cost, ok := p.estimates[userID][blockID][key]
+	p.estimatesMx.Unlock()
+
+	if ok {
+		return cost, nil
+	}
+
+	cost, err := estimatePostingsSize(reader, m)
+	if err != nil {
+		return 0, err
+	}
+
+	p.estimatesMx.Lock()
+	defer p.estimatesMx.Unlock()
+
+	if p.estimates[userID] == nil {
+		p.estimates[userID] = map[ulid.ULID]map[string]int64{}
+	}
+	// The number of distinct matchers queried is unbounded, so the block's estimates are
+	// reset once they reach the max size.
+	if p.estimates[userID][blockID] == nil || len(p.estimates[userID][blockID]) >= maxCachedEstimatesPerBlock {
+		p.estimates[userID][blockID] = map[string]int64{}
+	}
+	p.estimates[userID][blockID][key] = cost
+
+	return cost, nil
+}
+
+// dropBlock removes the cached estimates of a block which is not loaded anymore.
+func (p *postingsPlanner) dropBlock(userID string, blockID ulid.ULID) {
+	p.estimatesMx.Lock()
+	defer p.estimatesMx.Unlock()
+
+	delete(p.estimates[userID], blockID)
+	if len(p.estimates[userID]) == 0 {
+		delete(p.estimates, userID)
+	}
+}
+
+// closeUser removes the cached estimates of the input tenant.
+func (p *postingsPlanner) closeUser(userID string) {
+	p.estimatesMx.Lock()
+	defer p.estimatesMx.Unlock()
+
+	delete(p.estimates, userID)
+}
+
+// estimatePostingsSize returns the estimated size, in bytes, of the postings fetched to evaluate the input matcher.
+func estimatePostingsSize(r indexheader.Reader, m *labels.Matcher) (int64, error) {
+	if m.Type == labels.MatchEqual && m.Value != "" {
+		return postingsSize(r, m.Name, m.Value)
+	}
+
+	values, err := r.LabelValues(m.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	// If the matcher matches the empty string, the postings of all series are fetched and
+	// the postings of the values not matching are removed from them.
+	matchesEmpty := m.Matches("")
+
+	step := 1
+	if len(values) > maxEstimatedLabelValues {
+		step = (len(values) + maxEstimatedLabelValues - 1) / maxEstimatedLabelValues
+	}
+
+	var size, sampled int64
+	for i := 0; i < len(values); i += step {
+		sampled++
+
+		if m.Matches(values[i]) == matchesEmpty {
+			continue
+		}
+
+		valueSize, err := postingsSize(r, m.Name, values[i])
+		if err != nil {
+			return 0, err
+		}
+		size += valueSize
+	}
+
+	if sampled > 0 {
+		size = size * int64(len(values)) / sampled
+	}
+
+	if matchesEmpty {
+		allName, allValue := index.AllPostingsKey()
+		allSize, err := postingsSize(r, allName, allValue)
+		if err != nil {
+			return 0, err
+		}
+		size += allSize
+	}
+
+	return size, nil
+}
+
+func postingsSize(r indexheader.Reader, name, value string) (int64, error) {
+	rng, err := r.PostingsOffset(name, value)
+	if err == indexheader.NotFoundRangeErr {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return rng.End - rng.Start, nil
+}
+
+// seriesRequestBlockIDs returns the IDs of the blocks the request is selectively querying, if any.
+func seriesRequestBlockIDs(req *storepb.SeriesRequest) []ulid.ULID {
+	if req.Hints == nil {
+		return nil
+	}
+
+	hints := hintspb.SeriesRequestHints{}
+	if err := types.UnmarshalAny(req.Hints, &hints); err != nil {
+		return nil
+	}
+
+	var ids []ulid.ULID
+	for _, m := range hints.BlockMatchers {
+		if m.Name != block.BlockIDLabel || (m.Type != storepb.LabelMatcher_RE && m.Type != storepb.LabelMatcher_EQ) {
+			continue
+		}
+
+		for _, value := range strings.Split(m.Value, "|") {
+			if id, err := ulid.Parse(value); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids
+}
+
+// withDeferredMatchers returns a context with the series filter applying the deferred matchers.
+func (p *postingsPlanner) withDeferredMatchers(ctx context.Context, deferred []*labels.Matcher) context.Context {
+	return store.ContextWithSeriesFilter(ctx, &deferredMatchersFilter{
+		matchers: deferred,
+		filtered: p.filteredSeries,
+	})
+}
+
+// deferredMatchersFilter filters the series matched by the remaining matchers of a planned
+// request by the deferred matchers, before their chunks are fetched.
+type deferredMatchersFilter struct {
+	matchers []*labels.Matcher
+	filtered prometheus.Counter
+}
+
+// Matches implements store.SeriesFilter.
+func (f *deferredMatchersFilter) Matches(lset labels.Labels) bool {
+	for _, m := range f.matchers {
+		if !m.Matches(lset.Get(m.Name)) {
+			f.filtered.Inc()
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,252 @@
+package storegateway
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gogo/protobuf/types"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/store/hintspb"
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+
+	"github.com/cortexproject/cortex/pkg/storage/bucket/filesystem"
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+func TestBucketStores_Series_ShouldDeferExpensiveMatchers(t *testing.T) {
+	const userID = "user-1"
+
+	storageDir, err := ioutil.TempDir(os.TempDir(), "storage-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, os.RemoveAll(storageDir)) })
+
+	// Generate a block with many series for the job "big", and few series for the job "small".
+	var series []labels.Labels
+	for i := 0; i < 200; i++ {
+		series = append(series, labels.FromStrings(labels.MetricName, "up", "job", "big", "pod", fmt.Sprintf("pod-%d", i)))
+	}
+	series = append(series,
+		labels.FromStrings(labels.MetricName, "up", "job", "small", "pod", "pod-1"),
+		labels.FromStrings(labels.MetricName, "up", "job", "small", "pod", "pod-2"),
+		labels.FromStrings(labels.MetricName, "up", "job", "small"),
+	)
+	blockID := generateStorageBlockWithLabels(t, storageDir, userID, series, 0, 100)
+
+	bucket, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		matchers         []storepb.LabelMatcher
+		expectedSeries   []labels.Labels
+		expectedDeferred int
+		expectedFiltered int
+	}{
+		"should defer a regex matcher barely filtering": {
+			matchers: []storepb.LabelMatcher{
+				{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "small"},
+				{Type: storepb.LabelMatcher_RE, Name: "pod", Value: ".+"},
+			},
+			expectedSeries: []labels.Labels{
+				labels.FromStrings(labels.MetricName, "up", "job", "small", "pod", "pod-1"),
+				labels.FromStrings(labels.MetricName, "up", "job", "small", "pod", "pod-2"),
+			},
+			expectedDeferred: 1,
+			expectedFiltered: 1,
+		},
+		"should defer an equal matcher with big postings": {
+			matchers: []storepb.LabelMatcher{
+				{Type: storepb.LabelMatcher_EQ, Name: labels.MetricName, Value: "up"},
+				{Type: storepb.LabelMatcher_EQ, Name: "pod", Value: "pod-2"},
+			},
+			expectedSeries: []labels.Labels{
+				labels.FromStrings(labels.MetricName, "up", "job", "big", "pod", "pod-2"),
+				labels.FromStrings(labels.MetricName, "up", "job", "small", "pod", "pod-2"),
+			},
+			expectedDeferred: 1,
+		},
+		"should defer a negative matcher": {
+			matchers: []storepb.LabelMatcher{
+				{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "small"},
+				{Type: storepb.LabelMatcher_NEQ, Name: "pod", Value: "pod-1"},
+			},
+			expectedSeries: []labels.Labels{
+				labels.FromStrings(labels.MetricName, "up", "job", "small"),
+				labels.FromStrings(labels.MetricName, "up", "job", "small", "pod", "pod-2"),
+			},
+			expectedDeferred: 1,
+			expectedFiltered: 1,
+		},
+		"should not defer matchers with similar postings size": {
+			matchers: []storepb.LabelMatcher{
+				{Type: storepb.LabelMatcher_EQ, Name: "job", Value: "small"},
+				{Type: storepb.LabelMatcher_EQ, Name: "pod", Value: "pod-1"},
+			},
+			expectedSeries: []labels.Labels{
+				labels.FromStrings(labels.MetricName, "up", "job", "small", "pod", "pod-1"),
+			},
+			expectedDeferred: 0,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			cfg, cleanup := prepareStorageConfig(t)
+			defer cleanup()
+			cfg.BucketStore.LazyPostings.Enabled = true
+
+			// The series filtered by the deferred matchers don't count towards the series limit.
+			limits := defaultLimitsConfig()
+			limits.MaxFetchedSeriesPerQuery = 2
+			overrides, err := validation.NewOverrides(limits, nil)
+			require.NoError(t, err)
+
+			reg := prometheus.NewPedanticRegistry()
+			stores, err := NewBucketStores(cfg, NewNoShardingStrategy(), bucket, overrides, mockLoggingLevel(), log.NewNopLogger(), reg)
+			require.NoError(t, err)
+			require.NoError(t, stores.InitialSync(context.Background()))
+
+			hints, err := types.MarshalAny(&hintspb.SeriesRequestHints{
+				BlockMatchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_RE, Name: block.BlockIDLabel, Value: blockID.String()}},
+			})
+			require.NoError(t, err)
+
+			req := &storepb.SeriesRequest{
+				MinTime:                 0,
+				MaxTime:                 100,
+				Matchers:                testData.matchers,
+				PartialResponseStrategy: storepb.PartialResponseStrategy_ABORT,
+				Hints:                   hints,
+			}
+
+			srv := newBucketStoreSeriesServer(setUserIDToGRPCContext(context.Background(), userID))
+			require.NoError(t, stores.Series(req, srv))
+
+			var actual []labels.Labels
+			for _, s := range srv.SeriesSet {
+				actual = append(actual, labelpb.ZLabelsToPromLabels(s.Labels))
+			}
+			assert.Equal(t, testData.expectedSeries, actual)
+
+			// The matchers of the input request must not be modified.
+			assert.Len(t, req.Matchers, 2)
+
+			assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(fmt.Sprintf(`
+				# HELP cortex_bucket_stores_lazy_postings_planned_requests_total Total number of Series requests whose matchers have been evaluated by the postings planner.
+				# TYPE cortex_bucket_stores_lazy_postings_planned_requests_total counter
+				cortex_bucket_stores_lazy_postings_planned_requests_total 1
+
+				# HELP cortex_bucket_stores_lazy_postings_deferred_requests_total Total number of Series requests for which at least one matcher has been deferred to series-level filtering, skipping its postings.
+				# TYPE cortex_bucket_stores_lazy_postings_deferred_requests_total counter
+				cortex_bucket_stores_lazy_postings_deferred_requests_total %d
+
+				# HELP cortex_bucket_stores_lazy_postings_deferred_matchers_total Total number of matchers deferred to series-level filtering, skipping their postings.
+				# TYPE cortex_bucket_stores_lazy_postings_deferred_matchers_total counter
+				cortex_bucket_stores_lazy_postings_deferred_matchers_total %d
+
+				# HELP cortex_bucket_stores_lazy_postings_filtered_series_total Total number of series discarded by the series-level filtering of deferred matchers.
+				# TYPE cortex_bucket_stores_lazy_postings_filtered_series_total counter
+				cortex_bucket_stores_lazy_postings_filtered_series_total %d
+			`, testData.expectedDeferred, testData.expectedDeferred, testData.expectedFiltered)),
+				"cortex_bucket_stores_lazy_postings_planned_requests_total",
+				"cortex_bucket_stores_lazy_postings_deferred_requests_total",
+				"cortex_bucket_stores_lazy_postings_deferred_matchers_total",
+				"cortex_bucket_stores_lazy_postings_filtered_series_total"))
+
+			// The estimates are cached for the queried block.
+			assert.Len(t, stores.postingsPlanner.estimates[userID][blockID], len(testData.matchers))
+		})
+	}
+}
+
+func TestBucketStores_Series_ShouldNotPlanRequestsWithoutBlockHints(t *testing.T) {
+	const userID = "user-1"
+
+	storageDir, err := ioutil.TempDir(os.TempDir(), "storage-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, os.RemoveAll(storageDir)) })
+	generateStorageBlock(t, storageDir, userID, "series_1", 0, 100, 1)
+
+	bucket, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	cfg, cleanup := prepareStorageConfig(t)
+	defer cleanup()
+	cfg.BucketStore.LazyPostings.Enabled = true
+
+	reg := prometheus.NewPedanticRegistry()
+	stores, err := NewBucketStores(cfg, NewNoShardingStrategy(), bucket, defaultLimitsOverrides(t), mockLoggingLevel(), log.NewNopLogger(), reg)
+	require.NoError(t, err)
+	require.NoError(t, stores.InitialSync(context.Background()))
+
+	seriesSet, _, err := querySeries(stores, userID, "series_1", 0, 100)
+	require.NoError(t, err)
+	assert.Len(t, seriesSet, 1)
+
+	assert.Equal(t, float64(0), testutil.ToFloat64(stores.postingsPlanner.plannedRequests))
+}
+
+func TestSeriesRequestBlockIDs(t *testing.T) {
+	block1 := ulid.MustNew(1, nil)
+	block2 := ulid.MustNew(2, nil)
+
+	hints, err := types.MarshalAny(&hintspb.SeriesRequestHints{
+		BlockMatchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_RE, Name: block.BlockIDLabel, Value: block1.String() + "|" + block2.String()}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []ulid.ULID{block1, block2}, seriesRequestBlockIDs(&storepb.SeriesRequest{Hints: hints}))
+	assert.Empty(t, seriesRequestBlockIDs(&storepb.SeriesRequest{}))
+}
+
+func generateStorageBlockWithLabels(t *testing.T, storageDir, userID string, series []labels.Labels, minT, maxT int64) ulid.ULID {
+	userDir := filepath.Join(storageDir, userID)
+	require.NoError(t, os.MkdirAll(userDir, os.ModePerm))
+
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "tsdb-*")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	db, err := tsdb.Open(tmpDir, log.NewNopLogger(), nil, tsdb.DefaultOptions())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	app := db.Appender(context.Background())
+	for _, s := range series {
+		for ts := minT; ts < maxT; ts++ {
+			_, err = app.Append(0, s, ts, 1)
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, app.Commit())
+
+	// Snapshot to an empty dir, to find the ID of the generated block, and then move it to the user dir.
+	snapshotDir := filepath.Join(tmpDir, "snapshot")
+	require.NoError(t, db.Snapshot(snapshotDir, true))
+
+	entries, err := ioutil.ReadDir(snapshotDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	blockID := ulid.MustParse(entries[0].Name())
+	require.NoError(t, os.Rename(filepath.Join(snapshotDir, blockID.String()), filepath.Join(userDir, blockID.String())))
+
+	return blockID
+}
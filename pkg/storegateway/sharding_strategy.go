@@ -2,6 +2,7 @@ package storegateway
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -54,17 +55,20 @@ func (s *NoShardingStrategy) FilterBlocks(_ context.Context, _ string, _ map[uli
 // DefaultShardingStrategy is a sharding strategy based on the hash ring formed by store-gateways.
 // Not go-routine safe.
 type DefaultShardingStrategy struct {
-	r            *ring.Ring
-	instanceAddr string
-	logger       log.Logger
+	r                  *ring.Ring
+	instanceAddr       string
+	dataAgeGracePeriod time.Duration
+	logger             log.Logger
 }
 
-// NewDefaultShardingStrategy creates DefaultShardingStrategy.
-func NewDefaultShardingStrategy(r *ring.Ring, instanceAddr string, logger log.Logger) *DefaultShardingStrategy {
+// NewDefaultShardingStrategy creates DefaultShardingStrategy. Blocks moving between the data age ranges
+// of the store-gateways are kept by both the old and new owners for the dataAgeGracePeriod.
+func NewDefaultShardingStrategy(r *ring.Ring, instanceAddr string, dataAgeGracePeriod time.Duration, logger log.Logger) *DefaultShardingStrategy {
 	return &DefaultShardingStrategy{
-		r:            r,
-		instanceAddr: instanceAddr,
-		logger:       logger,
+		r:                  r,
+		instanceAddr:       instanceAddr,
+		dataAgeGracePeriod: dataAgeGracePeriod,
+		logger:             logger,
 	}
 }
 
@@ -75,28 +79,31 @@ func (s *DefaultShardingStrategy) FilterUsers(_ context.Context, userIDs []strin
 
 // FilterBlocks implements ShardingStrategy.
 func (s *DefaultShardingStrategy) FilterBlocks(_ context.Context, _ string, metas map[ulid.ULID]*metadata.Meta, loaded map[ulid.ULID]struct{}, synced *extprom.TxGaugeVec) error {
-	filterBlocksByRingSharding(s.r, s.instanceAddr, metas, loaded, synced, s.logger)
+	filterBlocksByRingSharding(s.r, s.instanceAddr, s.dataAgeGracePeriod, metas, loaded, synced, s.logger)
 	return nil
 }
 
 // ShuffleShardingStrategy is a shuffle sharding strategy, based on the hash ring formed by store-gateways,
 // where each tenant blocks are sharded across a subset of store-gateway instances.
 type ShuffleShardingStrategy struct {
-	r            *ring.Ring
-	instanceID   string
-	instanceAddr string
-	limits       ShardingLimits
-	logger       log.Logger
+	r                  *ring.Ring
+	instanceID         string
+	instanceAddr       string
+	dataAgeGracePeriod time.Duration
+	limits             ShardingLimits
+	logger             log.Logger
 }
 
-// NewShuffleShardingStrategy makes a new ShuffleShardingStrategy.
-func NewShuffleShardingStrategy(r *ring.Ring, instanceID, instanceAddr string, limits ShardingLimits, logger log.Logger) *ShuffleShardingStrategy {
+// NewShuffleShardingStrategy makes a new ShuffleShardingStrategy. Blocks moving between the data age ranges
+// of the store-gateways are kept by both the old and new owners for the dataAgeGracePeriod.
+func NewShuffleShardingStrategy(r *ring.Ring, instanceID, instanceAddr string, dataAgeGracePeriod time.Duration, limits ShardingLimits, logger log.Logger) *ShuffleShardingStrategy {
 	return &ShuffleShardingStrategy{
-		r:            r,
-		instanceID:   instanceID,
-		instanceAddr: instanceAddr,
-		limits:       limits,
-		logger:       logger,
+		r:                  r,
+		instanceID:         instanceID,
+		instanceAddr:       instanceAddr,
+		dataAgeGracePeriod: dataAgeGracePeriod,
+		limits:             limits,
+		logger:             logger,
 	}
 }
 
@@ -119,18 +126,22 @@ func (s *ShuffleShardingStrategy) FilterUsers(_ context.Context, userIDs []strin
 // FilterBlocks implements ShardingStrategy.
 func (s *ShuffleShardingStrategy) FilterBlocks(_ context.Context, userID string, metas map[ulid.ULID]*metadata.Meta, loaded map[ulid.ULID]struct{}, synced *extprom.TxGaugeVec) error {
 	subRing := GetShuffleShardingSubring(s.r, userID, s.limits)
-	filterBlocksByRingSharding(subRing, s.instanceAddr, metas, loaded, synced, s.logger)
+	filterBlocksByRingSharding(subRing, s.instanceAddr, s.dataAgeGracePeriod, metas, loaded, synced, s.logger)
 	return nil
 }
 
-func filterBlocksByRingSharding(r ring.ReadRing, instanceAddr string, metas map[ulid.ULID]*metadata.Meta, loaded map[ulid.ULID]struct{}, synced *extprom.TxGaugeVec, logger log.Logger) {
+func filterBlocksByRingSharding(r ring.ReadRing, instanceAddr string, dataAgeGracePeriod time.Duration, metas map[ulid.ULID]*metadata.Meta, loaded map[ulid.ULID]struct{}, synced *extprom.TxGaugeVec, logger log.Logger) {
 	bufDescs, bufHosts, bufZones := ring.MakeBuffersForGet()
+	now := time.Now()
 
-	for blockID := range metas {
+	for blockID, meta := range metas {
 		key := cortex_tsdb.HashBlockID(blockID)
 
+		// Only the store-gateways whose data age range overlaps the block time range can own it.
+		blockRing := r.DataRangeSubring(meta.MinTime, meta.MaxTime, now)
+
 		// Check if the block is owned by the store-gateway
-		set, err := r.Get(key, BlocksOwnerSync, bufDescs, bufHosts, bufZones)
+		set, err := blockRing.Get(key, BlocksOwnerSync, bufDescs, bufHosts, bufZones)
 
 		// If an error occurs while checking the ring, we keep the previously loaded blocks.
 		if err != nil {
@@ -152,12 +163,19 @@ func filterBlocksByRingSharding(r ring.ReadRing, instanceAddr string, metas map[
 			continue
 		}
 
+		// The store-gateways owning the block change as the block ages, and queriers look for
+		// the owners at query time, so the block is kept if it's owned by the store-gateway at
+		// any time within the grace period before or after now.
+		if isBlockOwnedWithinGracePeriod(r, instanceAddr, key, meta, now, dataAgeGracePeriod) {
+			continue
+		}
+
 		// The block is not owned by the store-gateway. However, if it's currently loaded
 		// we can safely unload it only once at least 1 authoritative owner is available
 		// for queries.
 		if _, ok := loaded[blockID]; ok {
 			// The ring Get() returns an error if there's no available instance.
-			if _, err := r.Get(key, BlocksOwnerRead, bufDescs, bufHosts, bufZones); err != nil {
+			if _, err := blockRing.Get(key, BlocksOwnerRead, bufDescs, bufHosts, bufZones); err != nil {
 				// Keep the block.
 				continue
 			}
@@ -171,6 +189,34 @@ func filterBlocksByRingSharding(r ring.ReadRing, instanceAddr string, metas map[
 	}
 }
 
+// isBlockOwnedWithinGracePeriod returns whether the block is owned by the store-gateway, based on the
+// data age ranges of the store-gateways, at any time within the grace period before or after now.
+func isBlockOwnedWithinGracePeriod(r ring.ReadRing, instanceAddr string, key uint32, meta *metadata.Meta, now time.Time, gracePeriod time.Duration) bool {
+	if gracePeriod <= 0 {
+		return false
+	}
+
+	from, to := now.Add(-gracePeriod), now.Add(gracePeriod)
+
+	// If the store-gateways serving the block don't change within the grace period,
+	// the ownership is the same as the one checked at now.
+	changes := r.DataRangeChanges(meta.MinTime, meta.MaxTime, from, to)
+	if len(changes) == 0 {
+		return false
+	}
+
+	bufDescs, bufHosts, bufZones := ring.MakeBuffersForGet()
+
+	for _, t := range append([]time.Time{from}, changes...) {
+		set, err := r.DataRangeSubring(meta.MinTime, meta.MaxTime, t).Get(key, BlocksOwnerSync, bufDescs, bufHosts, bufZones)
+		if err == nil && set.Includes(instanceAddr) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // GetShuffleShardingSubring returns the subring to be used for a given user. This function
 // should be used both by store-gateway and querier in order to guarantee the same logic is used.
 func GetShuffleShardingSubring(ring *ring.Ring, userID string, limits ShardingLimits) ring.ReadRing {
@@ -17,6 +17,7 @@ import (
 	"github.com/cortexproject/cortex/pkg/ring"
 	"github.com/cortexproject/cortex/pkg/ring/kv/consul"
 	cortex_tsdb "github.com/cortexproject/cortex/pkg/storage/tsdb"
+	"github.com/cortexproject/cortex/pkg/util"
 	"github.com/cortexproject/cortex/pkg/util/services"
 )
 
@@ -266,7 +267,7 @@ func TestDefaultShardingStrategy(t *testing.T) {
 			require.NoError(t, ring.WaitInstanceState(ctx, r, "instance-1", ring.ACTIVE))
 
 			for instanceAddr, expectedBlocks := range testData.expectedBlocks {
-				filter := NewDefaultShardingStrategy(r, instanceAddr, log.NewNopLogger())
+				filter := NewDefaultShardingStrategy(r, instanceAddr, 0, log.NewNopLogger())
 				synced := extprom.NewTxGaugeVec(nil, prometheus.GaugeOpts{}, []string{"state"})
 				synced.WithLabelValues(shardExcludedMeta).Set(0)
 
@@ -295,6 +296,183 @@ func TestDefaultShardingStrategy(t *testing.T) {
 	}
 }
 
+func TestDefaultShardingStrategy_ShouldHonorDataAgeRange(t *testing.T) {
+	// The following block IDs have been picked to have increasing hash values
+	// in order to simplify the tests.
+	block1 := ulid.MustNew(1, nil) // hash: 283204220
+	block2 := ulid.MustNew(2, nil) // hash: 444110359
+	block3 := ulid.MustNew(5, nil) // hash: 2931974232
+	block4 := ulid.MustNew(6, nil) // hash: 3092880371
+
+	ctx := context.Background()
+	now := time.Now()
+	registeredAt := now
+	tierBoundary := 14 * 24 * time.Hour
+
+	newMeta := func(minAge, maxAge time.Duration) *metadata.Meta {
+		meta := &metadata.Meta{}
+		meta.MinTime = util.TimeToMillis(now.Add(-maxAge))
+		meta.MaxTime = util.TimeToMillis(now.Add(-minAge))
+		return meta
+	}
+
+	// Initialize the ring state with a hot and a cold store-gateway. Tokens are assigned
+	// such that, regardless of the data age range, each instance would own half of the blocks.
+	store := consul.NewInMemoryClient(ring.GetCodec())
+	require.NoError(t, store.CAS(ctx, "test", func(in interface{}) (interface{}, bool, error) {
+		d := ring.NewDesc()
+		d.AddIngester("instance-1", "127.0.0.1", "", []uint32{cortex_tsdb.HashBlockID(block1) + 1, cortex_tsdb.HashBlockID(block3) + 1}, ring.ACTIVE, registeredAt)
+		d.AddIngester("instance-2", "127.0.0.2", "", []uint32{cortex_tsdb.HashBlockID(block2) + 1, cortex_tsdb.HashBlockID(block4) + 1}, ring.ACTIVE, registeredAt)
+
+		hot := d.Ingesters["instance-1"]
+		hot.MaxDataAgeSeconds = int64(tierBoundary.Seconds())
+		d.Ingesters["instance-1"] = hot
+
+		cold := d.Ingesters["instance-2"]
+		cold.MinDataAgeSeconds = int64(tierBoundary.Seconds())
+		d.Ingesters["instance-2"] = cold
+
+		return d, true, nil
+	}))
+
+	cfg := ring.Config{
+		ReplicationFactor: 1,
+		HeartbeatTimeout:  time.Minute,
+	}
+
+	r, err := ring.NewWithStoreClientAndStrategy(cfg, "test", "test", store, ring.NewIgnoreUnhealthyInstancesReplicationStrategy())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, r))
+	defer services.StopAndAwaitTerminated(ctx, r) //nolint:errcheck
+
+	// Wait until the ring client has synced.
+	require.NoError(t, ring.WaitInstanceState(ctx, r, "instance-1", ring.ACTIVE))
+
+	expectedBlocks := map[string][]ulid.ULID{
+		"127.0.0.1": {block1, block2},
+		"127.0.0.2": {block3, block4},
+	}
+
+	for instanceAddr, expected := range expectedBlocks {
+		filter := NewDefaultShardingStrategy(r, instanceAddr, 0, log.NewNopLogger())
+		synced := extprom.NewTxGaugeVec(nil, prometheus.GaugeOpts{}, []string{"state"})
+
+		metas := map[ulid.ULID]*metadata.Meta{
+			block1: newMeta(2*time.Hour, 4*time.Hour),
+			block2: newMeta(24*time.Hour, 48*time.Hour),
+			block3: newMeta(20*24*time.Hour, 21*24*time.Hour),
+			block4: newMeta(30*24*time.Hour, 31*24*time.Hour),
+		}
+
+		require.NoError(t, filter.FilterBlocks(ctx, "user-1", metas, map[ulid.ULID]struct{}{}, synced))
+
+		var actualBlocks []ulid.ULID
+		for id := range metas {
+			actualBlocks = append(actualBlocks, id)
+		}
+
+		assert.ElementsMatch(t, expected, actualBlocks, "store-gateway: %s", instanceAddr)
+	}
+}
+
+func TestDefaultShardingStrategy_ShouldKeepBlocksCrossingDataAgeRangeWithinGracePeriod(t *testing.T) {
+	// The following block IDs have been picked to have increasing hash values
+	// in order to simplify the tests.
+	block1 := ulid.MustNew(1, nil) // hash: 283204220
+	block2 := ulid.MustNew(2, nil) // hash: 444110359
+
+	ctx := context.Background()
+	now := time.Now()
+	tierBoundary := 14 * 24 * time.Hour
+	gracePeriod := time.Hour
+
+	newMeta := func(minAge, maxAge time.Duration) *metadata.Meta {
+		meta := &metadata.Meta{}
+		meta.MinTime = util.TimeToMillis(now.Add(-maxAge))
+		meta.MaxTime = util.TimeToMillis(now.Add(-minAge))
+		return meta
+	}
+
+	// Initialize the ring state with a hot and a cold store-gateway. When both of them
+	// serve a block, block1 is owned by the hot one and block2 by the cold one.
+	store := consul.NewInMemoryClient(ring.GetCodec())
+	require.NoError(t, store.CAS(ctx, "test", func(in interface{}) (interface{}, bool, error) {
+		d := ring.NewDesc()
+		d.AddIngester("instance-1", "127.0.0.1", "", []uint32{cortex_tsdb.HashBlockID(block1) + 1}, ring.ACTIVE, now)
+		d.AddIngester("instance-2", "127.0.0.2", "", []uint32{cortex_tsdb.HashBlockID(block2) + 1}, ring.ACTIVE, now)
+
+		hot := d.Ingesters["instance-1"]
+		hot.MaxDataAgeSeconds = int64(tierBoundary.Seconds())
+		d.Ingesters["instance-1"] = hot
+
+		cold := d.Ingesters["instance-2"]
+		cold.MinDataAgeSeconds = int64(tierBoundary.Seconds())
+		d.Ingesters["instance-2"] = cold
+
+		return d, true, nil
+	}))
+
+	cfg := ring.Config{
+		ReplicationFactor: 1,
+		HeartbeatTimeout:  time.Minute,
+	}
+
+	r, err := ring.NewWithStoreClientAndStrategy(cfg, "test", "test", store, ring.NewIgnoreUnhealthyInstancesReplicationStrategy())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, r))
+	defer services.StopAndAwaitTerminated(ctx, r) //nolint:errcheck
+
+	// Wait until the ring client has synced.
+	require.NoError(t, ring.WaitInstanceState(ctx, r, "instance-1", ring.ACTIVE))
+
+	tests := map[string]struct {
+		gracePeriod    time.Duration
+		expectedBlocks map[string][]ulid.ULID
+	}{
+		"without grace period, blocks are owned by the store-gateways serving them at now": {
+			gracePeriod: 0,
+			expectedBlocks: map[string][]ulid.ULID{
+				"127.0.0.1": {block2},
+				"127.0.0.2": {block1},
+			},
+		},
+		"with grace period, blocks are owned by both the previous and next store-gateways serving them": {
+			gracePeriod: gracePeriod,
+			expectedBlocks: map[string][]ulid.ULID{
+				"127.0.0.1": {block1, block2},
+				"127.0.0.2": {block1, block2},
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			for instanceAddr, expected := range testData.expectedBlocks {
+				filter := NewDefaultShardingStrategy(r, instanceAddr, testData.gracePeriod, log.NewNopLogger())
+				synced := extprom.NewTxGaugeVec(nil, prometheus.GaugeOpts{}, []string{"state"})
+
+				metas := map[ulid.ULID]*metadata.Meta{
+					// Only served by the cold store-gateway, since it's become older than the tier
+					// boundary 30 minutes ago: it was served by both store-gateways before.
+					block1: newMeta(tierBoundary+30*time.Minute, tierBoundary+2*time.Hour),
+					// Only served by the hot store-gateway, until it becomes older than the tier
+					// boundary in 30 minutes: it will be served by both store-gateways after.
+					block2: newMeta(tierBoundary-2*time.Hour, tierBoundary-30*time.Minute),
+				}
+
+				require.NoError(t, filter.FilterBlocks(ctx, "user-1", metas, map[ulid.ULID]struct{}{}, synced))
+
+				var actualBlocks []ulid.ULID
+				for id := range metas {
+					actualBlocks = append(actualBlocks, id)
+				}
+
+				assert.ElementsMatch(t, expected, actualBlocks, "store-gateway: %s", instanceAddr)
+			}
+		})
+	}
+}
+
 func TestShuffleShardingStrategy(t *testing.T) {
 	// The following block IDs have been picked to have increasing hash values
 	// in order to simplify the tests.
@@ -624,13 +802,13 @@ func TestShuffleShardingStrategy(t *testing.T) {
 
 			// Assert on filter users.
 			for _, expected := range testData.expectedUsers {
-				filter := NewShuffleShardingStrategy(r, expected.instanceID, expected.instanceAddr, testData.limits, log.NewNopLogger())
+				filter := NewShuffleShardingStrategy(r, expected.instanceID, expected.instanceAddr, 0, testData.limits, log.NewNopLogger())
 				assert.Equal(t, expected.users, filter.FilterUsers(ctx, []string{userID}))
 			}
 
 			// Assert on filter blocks.
 			for _, expected := range testData.expectedBlocks {
-				filter := NewShuffleShardingStrategy(r, expected.instanceID, expected.instanceAddr, testData.limits, log.NewNopLogger())
+				filter := NewShuffleShardingStrategy(r, expected.instanceID, expected.instanceAddr, 0, testData.limits, log.NewNopLogger())
 				synced := extprom.NewTxGaugeVec(nil, prometheus.GaugeOpts{}, []string{"state"})
 				synced.WithLabelValues(shardExcludedMeta).Set(0)
 
@@ -0,0 +1,217 @@
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/thanos/pkg/block"
+	"github.com/thanos-io/thanos/pkg/block/indexheader"
+	"github.com/thanos-io/thanos/pkg/objstore"
+	"github.com/thanos-io/thanos/pkg/objstore/filesystem"
+	"github.com/thanos-io/thanos/pkg/store/storepb"
+	"go.uber.org/atomic"
+)
+
+func TestBucketStore_Series_ShouldFilterTheSeriesBeforeReservingThem(t *testing.T) {
+	s, _ := prepareBucketStore(t, 2)
+
+	// Without a filter, the series limit is exceeded by the 3 matching series.
+	_, err := seriesFromBucketStore(context.Background(), s)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeded series limit")
+
+	// The series not passing the filter aren't returned nor reserved.
+	ctx := ContextWithSeriesFilter(context.Background(), seriesFilterFunc(func(lset labels.Labels) bool {
+		return lset.Get("path") != "/c"
+	}))
+	series, err := seriesFromBucketStore(ctx, s)
+	require.NoError(t, err)
+	require.Len(t, series, 2)
+	assert.Equal(t, "/a", series[0].PromLabels().Get("path"))
+	assert.Equal(t, "/b", series[1].PromLabels().Get("path"))
+}
+
+func TestBucketStore_Series_ShouldReserveTheChunkBytes(t *testing.T) {
+	s, _ := prepareBucketStore(t, 0)
+
+	bytesLimiter := &chunkBytesLimiterMock{}
+	series, err := seriesFromBucketStore(ContextWithChunkBytesLimiter(context.Background(), bytesLimiter), s)
+	require.NoError(t, err)
+	require.Len(t, series, 3)
+
+	// The actual size of the fetched chunks is reserved, including their length and encoding.
+	expected := uint64(0)
+	for _, s := range series {
+		for _, c := range s.Chunks {
+			expected += uint64(binary.PutUvarint(make([]byte, binary.MaxVarintLen64), uint64(len(c.Raw.Data))) + 1 + len(c.Raw.Data))
+		}
+	}
+	assert.Equal(t, expected, bytesLimiter.reserved.Load())
+
+	// The request fails once the limit is exceeded.
+	bytesLimiter = &chunkBytesLimiterMock{err: errors.New("limit exceeded")}
+	_, err = seriesFromBucketStore(ContextWithChunkBytesLimiter(context.Background(), bytesLimiter), s)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeded chunk bytes limit")
+}
+
+func TestBucketStore_IndexHeaderReader(t *testing.T) {
+	s, blockID := prepareBucketStore(t, 0)
+
+	var values []string
+	ok, err := s.IndexHeaderReader(blockID, func(r indexheader.Reader) (err error) {
+		values, err = r.LabelValues("path")
+		return err
+	})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"/a", "/b", "/c"}, values)
+
+	// The function isn't called for a block not loaded.
+	ok, err = s.IndexHeaderReader(ulid.MustNew(1, nil), func(indexheader.Reader) error {
+		return errors.New("unexpected call")
+	})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// prepareBucketStore returns a bucket store with a single block, containing 3 series
+// of the "up" metric, and a series limit of maxSeries (0 to disable it).
+func prepareBucketStore(t *testing.T, maxSeries uint64) (*BucketStore, ulid.ULID) {
+	storageDir, err := ioutil.TempDir(os.TempDir(), "storage-*")
+	require.NoError(t, err)
+	syncDir, err := ioutil.TempDir(os.TempDir(), "sync-*")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, os.RemoveAll(storageDir))
+		require.NoError(t, os.RemoveAll(syncDir))
+	})
+
+	blockID := generateBlock(t, storageDir, []labels.Labels{
+		labels.FromStrings(labels.MetricName, "up", "path", "/a"),
+		labels.FromStrings(labels.MetricName, "up", "path", "/b"),
+		labels.FromStrings(labels.MetricName, "up", "path", "/c"),
+	})
+
+	fsBkt, err := filesystem.NewBucket(storageDir)
+	require.NoError(t, err)
+	bkt := objstore.WithNoopInstr(fsBkt)
+
+	fetcher, err := block.NewMetaFetcher(log.NewNopLogger(), 1, bkt, filepath.Join(syncDir, "meta-syncer"), nil, nil, nil)
+	require.NoError(t, err)
+
+	s, err := NewBucketStore(
+		bkt,
+		fetcher,
+		syncDir,
+		func(failedCounter prometheus.Counter) ChunksLimiter { return NewLimiter(0, failedCounter) },
+		func(failedCounter prometheus.Counter) SeriesLimiter { return NewLimiter(maxSeries, failedCounter) },
+		NewGapBasedPartitioner(0),
+		1,
+		false,
+		32,
+		true,
+		false,
+		0,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, s.Close())
+	})
+	require.NoError(t, s.SyncBlocks(context.Background()))
+
+	return s, blockID
+}
+
+func generateBlock(t *testing.T, storageDir string, series []labels.Labels) ulid.ULID {
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "tsdb-*")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	db, err := tsdb.Open(tmpDir, log.NewNopLogger(), nil, tsdb.DefaultOptions())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	app := db.Appender(context.Background())
+	for _, s := range series {
+		for ts := int64(0); ts < 100; ts++ {
+			_, err = app.Append(0, s, ts, float64(ts))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, app.Commit())
+
+	// Snapshot to an empty dir, to find the ID of the generated block, and then move it to the storage dir.
+	snapshotDir := filepath.Join(tmpDir, "snapshot")
+	require.NoError(t, db.Snapshot(snapshotDir, true))
+
+	entries, err := ioutil.ReadDir(snapshotDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	blockID := ulid.MustParse(entries[0].Name())
+	require.NoError(t, os.Rename(filepath.Join(snapshotDir, blockID.String()), filepath.Join(storageDir, blockID.String())))
+
+	return blockID
+}
+
+func seriesFromBucketStore(ctx context.Context, s *BucketStore) ([]*storepb.Series, error) {
+	srv := &seriesServerMock{ctx: ctx}
+	err := s.Series(&storepb.SeriesRequest{
+		MinTime:  0,
+		MaxTime:  100,
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: labels.MetricName, Value: "up"}},
+	}, srv)
+
+	return srv.series, err
+}
+
+type seriesFilterFunc func(lset labels.Labels) bool
+
+func (f seriesFilterFunc) Matches(lset labels.Labels) bool {
+	return f(lset)
+}
+
+type chunkBytesLimiterMock struct {
+	reserved atomic.Uint64
+	err      error
+}
+
+func (l *chunkBytesLimiterMock) Reserve(num uint64) error {
+	l.reserved.Add(num)
+	return l.err
+}
+
+type seriesServerMock struct {
+	storepb.Store_SeriesServer
+
+	ctx    context.Context
+	series []*storepb.Series
+}
+
+func (s *seriesServerMock) Send(res *storepb.SeriesResponse) error {
+	if series := res.GetSeries(); series != nil {
+		s.series = append(s.series, series)
+	}
+	return nil
+}
+
+func (s *seriesServerMock) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,14 @@
+// Package store is a fork of the Thanos bucket store (github.com/thanos-io/thanos/pkg/store/bucket.go),
+// at the Thanos version vendored by Cortex, extended with the hooks required by the Cortex
+// store-gateway:
+//
+//   - ContextWithSeriesFilter, to filter the series matched by a Series request before their
+//     chunks are fetched and the series are reserved out of the series limit.
+//   - ContextWithChunkBytesLimiter, to limit the size of the chunks fetched by a Series request.
+//   - BucketStore.IndexHeaderReader, to access the index-header reader of a loaded block.
+//
+// Only bucket.go is forked, and the rest of its code is kept unchanged to ease syncing it with
+// upstream. The rest of the Thanos store package is used from the vendored Thanos (see thanos.go).
+// When Thanos is upgraded, bucket.go and the helpers copied in thanos.go should be synced with
+// the new version, keeping the hooks above.
+package store
@@ -0,0 +1,334 @@
+// Copyright (c) The Thanos Authors.
+// Licensed under the Apache License 2.0.
+
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/tsdb/encoding"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/thanos-io/thanos/pkg/store"
+)
+
+// This file contains what the bucket store requires from the rest of the Thanos store package.
+// The exported limiters are aliased, while the unexported helpers are copied unchanged from
+// io.go, opts.go and postings_codec.go, because they can't be imported.
+
+type (
+	ChunksLimiter        = store.ChunksLimiter
+	SeriesLimiter        = store.SeriesLimiter
+	ChunksLimiterFactory = store.ChunksLimiterFactory
+	SeriesLimiterFactory = store.SeriesLimiterFactory
+	Limiter              = store.Limiter
+)
+
+// NewLimiter returns a new limiter with a specified limit. 0 disables the limit.
+func NewLimiter(limit uint64, ctr prometheus.Counter) *Limiter {
+	return store.NewLimiter(limit, ctr)
+}
+
+const (
+	readerBufferSize = 32 * 1024
+)
+
+// byteRange holds information about a single byte range.
+type byteRange struct {
+	offset int
+	length int
+}
+
+// byteRanges holds a list of non-overlapping byte ranges sorted by offset.
+type byteRanges []byteRange
+
+// size returns the total number of bytes in the byte ranges.
+func (r byteRanges) size() int {
+	size := 0
+	for _, c := range r {
+		size += c.length
+	}
+	return size
+}
+
+// areContiguous returns whether all byte ranges are contiguous (no gaps).
+func (r byteRanges) areContiguous() bool {
+	if len(r) < 2 {
+		return true
+	}
+
+	for off, idx := r[0].offset+r[0].length, 1; idx < len(r); idx++ {
+		if r[idx].offset != off {
+			return false
+		}
+		off += r[idx].length
+	}
+	return true
+}
+
+// readByteRanges reads the provided byteRanges from src and append them to dst. The provided
+// byteRanges must be sorted by offset and non overlapping. The byteRanges offset must be
+// relative to the beginning of the provided src (offset 0 == first byte will be read from src).
+func readByteRanges(src io.Reader, dst []byte, byteRanges byteRanges) ([]byte, error) {
+	if len(byteRanges) == 0 {
+		return nil, nil
+	}
+
+	// Ensure the provided dst buffer has enough capacity.
+	expectedSize := byteRanges.size()
+	if cap(dst) < expectedSize {
+		return nil, io.ErrShortBuffer
+	}
+
+	// Size the destination buffer accordingly.
+	dst = dst[0:expectedSize]
+
+	// Optimisation for the case all ranges are contiguous.
+	if byteRanges[0].offset == 0 && byteRanges.areContiguous() {
+		// We get an ErrUnexpectedEOF if EOF is reached before we fill allocated dst slice.
+		// Due to how the reading logic works in the bucket store, we may try to overread at
+		// the end of an object, so we consider it legit.
+		if _, err := io.ReadFull(src, dst); err != nil && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		return dst, nil
+	}
+
+	// To keep implementation easier we frequently call Read() for short lengths.
+	// In such scenario, having a buffered reader improves performances at the cost
+	// of 1 more buffer allocation and memory copy.
+	reader := bufio.NewReaderSize(src, readerBufferSize)
+
+	for dstOffset, idx := 0, 0; idx < len(byteRanges); idx++ {
+		curr := byteRanges[idx]
+
+		// Read and discard all bytes before the current chunk offset.
+		discard := 0
+		if idx == 0 {
+			discard = curr.offset
+		} else {
+			prev := byteRanges[idx-1]
+			discard = curr.offset - (prev.offset + prev.length)
+		}
+
+		if _, err := reader.Discard(discard); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, errors.Wrap(err, "discard bytes")
+		}
+
+		// At this point the next byte to read from the reader is the current chunk,
+		// so we'll read it fully. io.ReadFull() returns an error if less bytes than
+		// expected have been read.
+		readBytes, err := io.ReadFull(reader, dst[dstOffset:dstOffset+curr.length])
+		if readBytes > 0 {
+			dstOffset += readBytes
+		}
+		if err != nil {
+			// We get an ErrUnexpectedEOF if EOF is reached before we fill the slice.
+			// Due to how the reading logic works in the bucket store, we may try to overread
+			// the last byte range so, if the error occurrs on the last one, we consider it legit.
+			if err == io.ErrUnexpectedEOF && idx == len(byteRanges)-1 {
+				return dst, nil
+			}
+
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, errors.Wrap(err, "read byte range")
+		}
+	}
+
+	return dst, nil
+}
+
+// Bitmap used by func isRegexMetaCharacter to check whether a character needs to be escaped.
+var regexMetaCharacterBytes [16]byte
+
+// isRegexMetaCharacter reports whether byte b needs to be escaped.
+func isRegexMetaCharacter(b byte) bool {
+	return b < utf8.RuneSelf && regexMetaCharacterBytes[b%16]&(1<<(b/16)) != 0
+}
+
+func init() {
+	for _, b := range []byte(`.+*?()|[]{}^$`) {
+		regexMetaCharacterBytes[b%16] |= 1 << (b / 16)
+	}
+}
+
+// Copied from Prometheus querier.go, removed check for Prometheus wrapper.
+// Returns list of values that can regex matches.
+func findSetMatches(pattern string) []string {
+	escaped := false
+	sets := []*strings.Builder{{}}
+	for i := 0; i < len(pattern); i++ {
+		if escaped {
+			switch {
+			case isRegexMetaCharacter(pattern[i]):
+				sets[len(sets)-1].WriteByte(pattern[i])
+			case pattern[i] == '\\':
+				sets[len(sets)-1].WriteByte('\\')
+			default:
+				return nil
+			}
+			escaped = false
+		} else {
+			switch {
+			case isRegexMetaCharacter(pattern[i]):
+				if pattern[i] == '|' {
+					sets = append(sets, &strings.Builder{})
+				} else {
+					return nil
+				}
+			case pattern[i] == '\\':
+				escaped = true
+			default:
+				sets[len(sets)-1].WriteByte(pattern[i])
+			}
+		}
+	}
+	matches := make([]string, 0, len(sets))
+	for _, s := range sets {
+		if s.Len() > 0 {
+			matches = append(matches, s.String())
+		}
+	}
+	return matches
+}
+
+// The following implements encoding and decoding of postings using diff (or delta) + varint
+// number encoding. On top of that, we apply Snappy compression.
+//
+// On its own, Snappy compressing raw postings doesn't really help, because there is no
+// repetition in raw data. Using diff (delta) between postings entries makes values small,
+// and Varint is very efficient at encoding small values (values < 128 are encoded as
+// single byte, values < 16384 are encoded as two bytes). Diff + varint reduces postings size
+// significantly (to about 20% of original), snappy then halves it to ~10% of the original.
+
+const (
+	codecHeaderSnappy = "dvs" // As in "diff+varint+snappy".
+)
+
+// isDiffVarintSnappyEncodedPostings returns true, if input looks like it has been encoded by diff+varint+snappy codec.
+func isDiffVarintSnappyEncodedPostings(input []byte) bool {
+	return bytes.HasPrefix(input, []byte(codecHeaderSnappy))
+}
+
+// diffVarintSnappyEncode encodes postings into diff+varint representation,
+// and applies snappy compression on the result.
+// Returned byte slice starts with codecHeaderSnappy header.
+// Length argument is expected number of postings, used for preallocating buffer.
+func diffVarintSnappyEncode(p index.Postings, length int) ([]byte, error) {
+	buf, err := diffVarintEncodeNoHeader(p, length)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make result buffer large enough to hold our header and compressed block.
+	result := make([]byte, len(codecHeaderSnappy)+snappy.MaxEncodedLen(len(buf)))
+	copy(result, codecHeaderSnappy)
+
+	compressed := snappy.Encode(result[len(codecHeaderSnappy):], buf)
+
+	// Slice result buffer based on compressed size.
+	result = result[:len(codecHeaderSnappy)+len(compressed)]
+	return result, nil
+}
+
+// diffVarintEncodeNoHeader encodes postings into diff+varint representation.
+// It doesn't add any header to the output bytes.
+// Length argument is expected number of postings, used for preallocating buffer.
+func diffVarintEncodeNoHeader(p index.Postings, length int) ([]byte, error) {
+	buf := encoding.Encbuf{}
+
+	// This encoding uses around ~1 bytes per posting, but let's use
+	// conservative 1.25 bytes per posting to avoid extra allocations.
+	if length > 0 {
+		buf.B = make([]byte, 0, 5*length/4)
+	}
+
+	prev := uint64(0)
+	for p.Next() {
+		v := p.At()
+		if v < prev {
+			return nil, errors.Errorf("postings entries must be in increasing order, current: %d, previous: %d", v, prev)
+		}
+
+		// This is the 'diff' part -- compute difference from previous value.
+		buf.PutUvarint64(v - prev)
+		prev = v
+	}
+	if p.Err() != nil {
+		return nil, p.Err()
+	}
+
+	return buf.B, nil
+}
+
+func diffVarintSnappyDecode(input []byte) (index.Postings, error) {
+	if !isDiffVarintSnappyEncodedPostings(input) {
+		return nil, errors.New("header not found")
+	}
+
+	raw, err := snappy.Decode(nil, input[len(codecHeaderSnappy):])
+	if err != nil {
+		return nil, errors.Wrap(err, "snappy decode")
+	}
+
+	return newDiffVarintPostings(raw), nil
+}
+
+func newDiffVarintPostings(input []byte) *diffVarintPostings {
+	return &diffVarintPostings{buf: &encoding.Decbuf{B: input}}
+}
+
+// diffVarintPostings is an implementation of index.Postings based on diff+varint encoded data.
+type diffVarintPostings struct {
+	buf *encoding.Decbuf
+	cur uint64
+}
+
+func (it *diffVarintPostings) At() uint64 {
+	return it.cur
+}
+
+func (it *diffVarintPostings) Next() bool {
+	if it.buf.Err() != nil || it.buf.Len() == 0 {
+		return false
+	}
+
+	val := it.buf.Uvarint64()
+	if it.buf.Err() != nil {
+		return false
+	}
+
+	it.cur = it.cur + val
+	return true
+}
+
+func (it *diffVarintPostings) Seek(x uint64) bool {
+	if it.cur >= x {
+		return true
+	}
+
+	// We cannot do any search due to how values are stored,
+	// so we simply advance until we find the right value.
+	for it.Next() {
+		if it.At() >= x {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (it *diffVarintPostings) Err() error {
+	return it.buf.Err()
+}
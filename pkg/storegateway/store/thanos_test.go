@@ -0,0 +1,56 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffVarintSnappyCodec(t *testing.T) {
+	postings := []uint64{1, 2, 10, 128, 16384, 1 << 40}
+
+	data, err := diffVarintSnappyEncode(index.NewListPostings(postings), len(postings))
+	require.NoError(t, err)
+	assert.True(t, isDiffVarintSnappyEncodedPostings(data))
+
+	decoded, err := diffVarintSnappyDecode(data)
+	require.NoError(t, err)
+	actual, err := index.ExpandPostings(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, postings, actual)
+
+	_, err = diffVarintSnappyDecode([]byte("invalid"))
+	assert.Error(t, err)
+}
+
+func TestFindSetMatches(t *testing.T) {
+	tests := map[string][]string{
+		"foo":          {"foo"},
+		"foo|bar|baz":  {"foo", "bar", "baz"},
+		`foo\.bar|baz`: {"foo.bar", "baz"},
+		"foo.*":        nil,
+		"(foo|bar)":    nil,
+	}
+
+	for pattern, expected := range tests {
+		assert.Equal(t, expected, findSetMatches(pattern), pattern)
+	}
+}
+
+func TestReadByteRanges(t *testing.T) {
+	src := []byte("0123456789")
+
+	actual, err := readByteRanges(bytes.NewReader(src), make([]byte, 0, 10), byteRanges{{offset: 1, length: 2}, {offset: 5, length: 3}})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("12567"), actual)
+
+	actual, err = readByteRanges(bytes.NewReader(src), make([]byte, 0, 10), byteRanges{{offset: 0, length: 4}, {offset: 4, length: 2}})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("012345"), actual)
+
+	_, err = readByteRanges(bytes.NewReader(src), make([]byte, 0, 2), byteRanges{{offset: 0, length: 4}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,43 @@
+package storegatewaypb
+
+import (
+	"github.com/thanos-io/thanos/pkg/store/labelpb"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+)
+
+// FromCortexExemplarSeries converts the input series exemplars to the store-gateway format.
+func FromCortexExemplarSeries(s cortexpb.TimeSeries) ExemplarSeries {
+	res := ExemplarSeries{
+		Labels:    labelpb.ZLabelsFromPromLabels(cortexpb.FromLabelAdaptersToLabels(s.Labels)),
+		Exemplars: make([]Exemplar, 0, len(s.Exemplars)),
+	}
+
+	for _, e := range s.Exemplars {
+		res.Exemplars = append(res.Exemplars, Exemplar{
+			Labels:      labelpb.ZLabelsFromPromLabels(cortexpb.FromLabelAdaptersToLabels(e.Labels)),
+			Value:       e.Value,
+			TimestampMs: e.TimestampMs,
+		})
+	}
+
+	return res
+}
+
+// ToCortexExemplarSeries converts the input series exemplars from the store-gateway format.
+func ToCortexExemplarSeries(s ExemplarSeries) cortexpb.TimeSeries {
+	res := cortexpb.TimeSeries{
+		Labels:    cortexpb.FromLabelsToLabelAdapters(labelpb.ZLabelsToPromLabels(s.Labels)),
+		Exemplars: make([]cortexpb.Exemplar, 0, len(s.Exemplars)),
+	}
+
+	for _, e := range s.Exemplars {
+		res.Exemplars = append(res.Exemplars, cortexpb.Exemplar{
+			Labels:      cortexpb.FromLabelsToLabelAdapters(labelpb.ZLabelsToPromLabels(e.Labels)),
+			Value:       e.Value,
+			TimestampMs: e.TimestampMs,
+		})
+	}
+
+	return res
+}
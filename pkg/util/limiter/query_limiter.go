@@ -93,3 +93,11 @@ func (ql *QueryLimiter) AddChunkBytes(chunkSizeInBytes int) error {
 	}
 	return nil
 }
+
+// ReleaseChunkBytes removes the input chunk size in bytes, previously added with AddChunkBytes.
+func (ql *QueryLimiter) ReleaseChunkBytes(chunkSizeInBytes int) {
+	if ql.maxChunkBytesPerQuery == 0 {
+		return
+	}
+	ql.chunkBytesCount.Sub(int64(chunkSizeInBytes))
+}
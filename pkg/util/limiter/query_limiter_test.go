@@ -70,6 +70,15 @@ func TestQueryLimiter_AddChunkBytes(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestQueryLimiter_ReleaseChunkBytes(t *testing.T) {
+	var limiter = NewQueryLimiter(0, 100)
+
+	require.NoError(t, limiter.AddChunkBytes(100))
+	limiter.ReleaseChunkBytes(50)
+	require.NoError(t, limiter.AddChunkBytes(50))
+	require.Error(t, limiter.AddChunkBytes(1))
+}
+
 func BenchmarkQueryLimiter_AddSeries(b *testing.B) {
 	const (
 		metricName = "test_metric"
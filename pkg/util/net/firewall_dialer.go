@@ -54,17 +54,26 @@ func (d *FirewallDialer) control(_, address string, _ syscall.RawConn) error {
 		return errBlockedAddress
 	}
 
-	if blockPrivateAddresses && (isPrivate(ip) || isLocal(ip)) {
+	if d.IsBlocked(ip) {
 		return errBlockedAddress
 	}
 
-	for _, cidr := range blockCIDRNetworks {
+	return nil
+}
+
+// IsBlocked returns whether the input IP address would be blocked by the firewall.
+func (d *FirewallDialer) IsBlocked(ip net.IP) bool {
+	if d.cfgProvider.BlockPrivateAddresses() && (isPrivate(ip) || isLocal(ip)) {
+		return true
+	}
+
+	for _, cidr := range d.cfgProvider.BlockCIDRNetworks() {
 		if cidr.Value.Contains(ip) {
-			return errBlockedAddress
+			return true
 		}
 	}
 
-	return nil
+	return false
 }
 
 func isLocal(ip net.IP) bool {
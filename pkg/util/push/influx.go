@@ -0,0 +1,246 @@
+package push
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/weaveworks/common/middleware"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+	"github.com/cortexproject/cortex/pkg/tenant"
+	"github.com/cortexproject/cortex/pkg/util"
+	"github.com/cortexproject/cortex/pkg/util/log"
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+const (
+	// Reasons for discarding samples received via the InfluxDB line protocol.
+	influxInvalidLine          = "influx_invalid_line"
+	influxUnsupportedFieldType = "influx_unsupported_field_type"
+	influxDuplicateLabelName   = "influx_duplicate_label_name"
+)
+
+// InfluxConfig configures how the InfluxDB line protocol points are mapped to series.
+type InfluxConfig struct {
+	MetricNamePrefix    string `yaml:"metric_name_prefix"`
+	MetricNameSeparator string `yaml:"metric_name_separator"`
+	ValueFieldName      string `yaml:"value_field_name"`
+}
+
+// RegisterFlags adds the flags required to config this to the given FlagSet.
+func (cfg *InfluxConfig) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&cfg.MetricNamePrefix, "distributor.influx.metric-name-prefix", "", "Prefix added to the name of the metrics received via the InfluxDB line protocol push API.")
+	f.StringVar(&cfg.MetricNameSeparator, "distributor.influx.metric-name-separator", "_", "Separator between the measurement and the field name in the name of the metrics received via the InfluxDB line protocol push API.")
+	f.StringVar(&cfg.ValueFieldName, "distributor.influx.value-field-name", "value", "Fields with this name are mapped to a metric named after the measurement only, without the field name, when received via the InfluxDB line protocol push API. Empty to always include the field name.")
+}
+
+// InfluxHandler is a http.Handler which accepts points in the InfluxDB line protocol, as sent to
+// the InfluxDB v1 and v2 write APIs. Each numeric field of a point is mapped to a sample of the
+// series named after the point measurement and field, and labelled with the point tags. Booleans
+// are mapped to 0 and 1, while string fields, invalid lines and points whose tags are mapped to
+// the same label name are discarded. The request fails if any line is discarded, although all
+// the valid points are pushed.
+func InfluxHandler(cfg InfluxConfig, maxRecvMsgSize int, sourceIPs *middleware.SourceIPExtractor, push Func) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.WithContext(ctx, log.Logger)
+		if sourceIPs != nil {
+			source := sourceIPs.Get(r)
+			if source != "" {
+				ctx = util.AddSourceIPsToOutgoingContext(ctx, source)
+				logger = log.WithSourceIPs(source, logger)
+			}
+		}
+
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		precision, err := influxPrecision(r.FormValue("precision"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		body, err := readInfluxBody(r, maxRecvMsgSize)
+		if err != nil {
+			level.Error(logger).Log("err", err.Error())
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		points, parseErrs := parseInfluxLines(body)
+		if len(parseErrs) > 0 {
+			validation.DiscardedSamples.WithLabelValues(influxInvalidLine, userID).Add(float64(len(parseErrs)))
+		}
+
+		req, unsupported, pointErrs := influxPointsToWriteRequest(cfg, points, precision, time.Now())
+		if unsupported > 0 {
+			validation.DiscardedSamples.WithLabelValues(influxUnsupportedFieldType, userID).Add(float64(unsupported))
+		}
+		if len(pointErrs) > 0 {
+			validation.DiscardedSamples.WithLabelValues(influxDuplicateLabelName, userID).Add(float64(len(pointErrs)))
+		}
+
+		if len(req.Timeseries) > 0 {
+			if _, err := push(ctx, req); err != nil {
+				handlePushError(w, logger, err)
+				return
+			}
+		}
+
+		if rejected := append(parseErrs, pointErrs...); len(rejected) > 0 {
+			msg := fmt.Sprintf("partial write: rejected %d lines, first error: %v", len(rejected), rejected[0])
+			level.Error(logger).Log("msg", "invalid InfluxDB line protocol", "err", msg)
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// influxPrecision returns the duration of a timestamp unit for the precision supported
+// by both the InfluxDB v1 and v2 write APIs. The default precision is nanoseconds.
+func influxPrecision(precision string) (time.Duration, error) {
+	switch precision {
+	case "", "n", "ns":
+		return time.Nanosecond, nil
+	case "u", "us":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	default:
+		return 0, fmt.Errorf("invalid precision %q", precision)
+	}
+}
+
+func readInfluxBody(r *http.Request, maxSize int) ([]byte, error) {
+	var reader io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gzReader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gzReader.Close()
+		reader = gzReader
+	}
+
+	// Read one extra byte to detect whether the body exceeds the max size.
+	body, err := ioutil.ReadAll(io.LimitReader(reader, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxSize {
+		return nil, fmt.Errorf("received message larger than max (%d bytes)", maxSize)
+	}
+	return body, nil
+}
+
+// influxPointsToWriteRequest maps the points to series, and returns the number of fields
+// skipped because of their unsupported type and an error for each point skipped because
+// multiple tags are mapped to the same label name (eg. "a-b" and "a_b").
+func influxPointsToWriteRequest(cfg InfluxConfig, points []influxPoint, precision time.Duration, now time.Time) (*cortexpb.WriteRequest, int, []error) {
+	req := &cortexpb.WriteRequest{Source: cortexpb.API}
+	unsupported := 0
+	var errs []error
+
+	for _, p := range points {
+		timestampMs := util.TimeToMillis(now)
+		if p.hasTimestamp {
+			timestampMs = p.timestamp * int64(precision) / int64(time.Millisecond)
+		}
+
+		tags, err := influxTagsToLabels(p.tags)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %v", p.line, err))
+			continue
+		}
+
+		for _, f := range p.fields {
+			if f.typ == influxString {
+				unsupported++
+				continue
+			}
+
+			lbls := make([]cortexpb.LabelAdapter, 0, len(tags)+1)
+			lbls = append(lbls, cortexpb.LabelAdapter{Name: labels.MetricName, Value: influxMetricName(cfg, p.measurement, f.key)})
+			lbls = append(lbls, tags...)
+			sort.Slice(lbls, func(i, j int) bool { return lbls[i].Name < lbls[j].Name })
+
+			req.Timeseries = append(req.Timeseries, cortexpb.PreallocTimeseries{
+				TimeSeries: &cortexpb.TimeSeries{
+					Labels:  lbls,
+					Samples: []cortexpb.Sample{{Value: f.value, TimestampMs: timestampMs}},
+				},
+			})
+		}
+	}
+
+	return req, unsupported, errs
+}
+
+// influxTagsToLabels maps the tags to labels. Returns an error if multiple tags are mapped to the same label name.
+func influxTagsToLabels(tags []influxTag) ([]cortexpb.LabelAdapter, error) {
+	lbls := make([]cortexpb.LabelAdapter, 0, len(tags)+1)
+	keys := make(map[string]string, len(tags))
+
+	for _, t := range tags {
+		name := influxLabelName(t.key)
+		if other, ok := keys[name]; ok {
+			return nil, fmt.Errorf("tags %q and %q are both mapped to the label name %q", other, t.key, name)
+		}
+		keys[name] = t.key
+
+		lbls = append(lbls, cortexpb.LabelAdapter{Name: name, Value: t.value})
+	}
+
+	return lbls, nil
+}
+
+func influxMetricName(cfg InfluxConfig, measurement, field string) string {
+	name := cfg.MetricNamePrefix + measurement
+	if cfg.ValueFieldName == "" || field != cfg.ValueFieldName {
+		name += cfg.MetricNameSeparator + field
+	}
+	return sanitizeInfluxName(name, true)
+}
+
+func influxLabelName(key string) string {
+	name := sanitizeInfluxName(key, false)
+
+	// Label names starting with "__" are reserved for internal use.
+	if strings.HasPrefix(name, "__") {
+		name = "tag" + name
+	}
+	return name
+}
+
+// sanitizeInfluxName replaces the characters not allowed in metric (or label) names with underscores.
+func sanitizeInfluxName(name string, isMetricName bool) string {
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9' && i > 0) || (isMetricName && c == ':')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
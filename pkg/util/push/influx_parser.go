@@ -0,0 +1,222 @@
+package push
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// influxFieldType is the type of a field value in the InfluxDB line protocol.
+type influxFieldType int
+
+const (
+	influxFloat influxFieldType = iota
+	influxInteger
+	influxUnsigned
+	influxBoolean
+	influxString
+)
+
+type influxTag struct {
+	key, value string
+}
+
+type influxField struct {
+	key   string
+	typ   influxFieldType
+	value float64
+}
+
+// influxPoint is a point parsed from a line of the InfluxDB line protocol.
+type influxPoint struct {
+	measurement string
+	tags        []influxTag
+	fields      []influxField
+
+	// timestamp is zero if the point has no timestamp.
+	timestamp    int64
+	hasTimestamp bool
+
+	// line is the number of the line the point has been parsed from.
+	line int
+}
+
+// parseInfluxLines parses the points in the InfluxDB line protocol payload. Empty lines and
+// comments are skipped. Lines failing to parse are reported as errors, together with their
+// line number, and don't prevent the other lines from being parsed.
+func parseInfluxLines(body []byte) ([]influxPoint, []error) {
+	var (
+		points []influxPoint
+		errs   []error
+	)
+
+	for i, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		p, err := parseInfluxLine(string(line))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %v", i+1, err))
+			continue
+		}
+		p.line = i + 1
+		points = append(points, p)
+	}
+
+	return points, errs
+}
+
+// parseInfluxLine parses a single line in the format
+// "<measurement>[,<tag_key>=<tag_value>...] <field_key>=<field_value>[,<field_key>=<field_value>...] [<timestamp>]".
+func parseInfluxLine(line string) (influxPoint, error) {
+	p := influxPoint{}
+
+	measurement, i := readInfluxToken(line, 0, ", ")
+	if measurement == "" {
+		return p, errors.New("missing measurement")
+	}
+	p.measurement = measurement
+
+	// Tags.
+	for i < len(line) && line[i] == ',' {
+		var key, value string
+		key, i = readInfluxToken(line, i+1, ",= ")
+		if key == "" || i >= len(line) || line[i] != '=' {
+			return p, errors.New("invalid tag: missing tag key or value")
+		}
+		value, i = readInfluxToken(line, i+1, ",= ")
+		if value == "" || (i < len(line) && line[i] == '=') {
+			return p, fmt.Errorf("invalid tag %q: missing tag value", key)
+		}
+		p.tags = append(p.tags, influxTag{key: key, value: value})
+	}
+
+	// Fields.
+	i = skipInfluxSpaces(line, i)
+	if i >= len(line) {
+		return p, errors.New("missing fields")
+	}
+	for {
+		var (
+			key string
+			f   influxField
+			err error
+		)
+
+		key, i = readInfluxToken(line, i, ",= ")
+		if key == "" || i >= len(line) || line[i] != '=' {
+			return p, errors.New("invalid field: missing field key or value")
+		}
+
+		f, i, err = readInfluxFieldValue(line, i+1)
+		if err != nil {
+			return p, fmt.Errorf("invalid field %q: %v", key, err)
+		}
+		f.key = key
+		p.fields = append(p.fields, f)
+
+		if i >= len(line) || line[i] != ',' {
+			break
+		}
+		i++
+	}
+
+	// Timestamp.
+	if i < len(line) && line[i] != ' ' {
+		return p, fmt.Errorf("unexpected character %q", line[i])
+	}
+	if rest := strings.TrimSpace(line[i:]); rest != "" {
+		ts, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil {
+			return p, fmt.Errorf("invalid timestamp %q", rest)
+		}
+		p.timestamp = ts
+		p.hasTimestamp = true
+	}
+
+	return p, nil
+}
+
+// readInfluxToken reads the token starting at index i up to the first unescaped character in
+// stops, and returns the unescaped token and the index of the stop character.
+func readInfluxToken(line string, i int, stops string) (string, int) {
+	var sb strings.Builder
+	for ; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' && i+1 < len(line) && (strings.IndexByte(stops, line[i+1]) >= 0 || line[i+1] == '\\') {
+			sb.WriteByte(line[i+1])
+			i++
+			continue
+		}
+		if strings.IndexByte(stops, c) >= 0 {
+			break
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String(), i
+}
+
+func skipInfluxSpaces(line string, i int) int {
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+// readInfluxFieldValue reads the field value starting at index i, and returns the
+// parsed value and the index of the character following it.
+func readInfluxFieldValue(line string, i int) (influxField, int, error) {
+	f := influxField{}
+
+	if i < len(line) && line[i] == '"' {
+		// String values are quoted, and can contain escaped quotes and backslashes.
+		for i++; i < len(line); i++ {
+			switch line[i] {
+			case '\\':
+				i++
+			case '"':
+				f.typ = influxString
+				return f, i + 1, nil
+			}
+		}
+		return f, i, errors.New("unterminated string value")
+	}
+
+	start := i
+	for i < len(line) && line[i] != ',' && line[i] != ' ' {
+		i++
+	}
+	raw := line[start:i]
+	if raw == "" {
+		return f, i, errors.New("missing value")
+	}
+
+	var err error
+	switch last := raw[len(raw)-1]; {
+	case last == 'i':
+		var v int64
+		v, err = strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+		f.typ, f.value = influxInteger, float64(v)
+	case last == 'u':
+		var v uint64
+		v, err = strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+		f.typ, f.value = influxUnsigned, float64(v)
+	case raw == "t" || raw == "T" || raw == "true" || raw == "True" || raw == "TRUE":
+		f.typ, f.value = influxBoolean, 1
+	case raw == "f" || raw == "F" || raw == "false" || raw == "False" || raw == "FALSE":
+		f.typ, f.value = influxBoolean, 0
+	default:
+		f.typ = influxFloat
+		f.value, err = strconv.ParseFloat(raw, 64)
+	}
+	if err != nil {
+		return f, i, fmt.Errorf("invalid value %q", raw)
+	}
+
+	return f, i, nil
+}
@@ -0,0 +1,221 @@
+package push
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/httpgrpc"
+	"github.com/weaveworks/common/user"
+
+	"github.com/cortexproject/cortex/pkg/cortexpb"
+	"github.com/cortexproject/cortex/pkg/util/validation"
+)
+
+func TestParseInfluxLine(t *testing.T) {
+	tests := map[string]struct {
+		line          string
+		expected      influxPoint
+		expectedError string
+	}{
+		"measurement and field": {
+			line: "cpu value=1",
+			expected: influxPoint{
+				measurement: "cpu",
+				fields:      []influxField{{key: "value", typ: influxFloat, value: 1}},
+			},
+		},
+		"tags, fields of all types and timestamp": {
+			line: `cpu,host=a,region=eu usage=0.5,count=3i,total=4u,up=true,down=F,msg="hello, \"world\"" 1622548800000000000`,
+			expected: influxPoint{
+				measurement: "cpu",
+				tags:        []influxTag{{key: "host", value: "a"}, {key: "region", value: "eu"}},
+				fields: []influxField{
+					{key: "usage", typ: influxFloat, value: 0.5},
+					{key: "count", typ: influxInteger, value: 3},
+					{key: "total", typ: influxUnsigned, value: 4},
+					{key: "up", typ: influxBoolean, value: 1},
+					{key: "down", typ: influxBoolean, value: 0},
+					{key: "msg", typ: influxString},
+				},
+				timestamp:    1622548800000000000,
+				hasTimestamp: true,
+			},
+		},
+		"escaped characters": {
+			line: `disk\ io,path=/mnt\,a,dev\=x=sd\ a read\ bytes=1`,
+			expected: influxPoint{
+				measurement: "disk io",
+				tags:        []influxTag{{key: "path", value: "/mnt,a"}, {key: "dev=x", value: "sd a"}},
+				fields:      []influxField{{key: "read bytes", typ: influxFloat, value: 1}},
+			},
+		},
+		"missing fields": {
+			line:          "cpu,host=a",
+			expectedError: "missing fields",
+		},
+		"missing tag value": {
+			line:          "cpu,host= value=1",
+			expectedError: `invalid tag "host": missing tag value`,
+		},
+		"invalid field value": {
+			line:          "cpu value=abc",
+			expectedError: `invalid field "value": invalid value "abc"`,
+		},
+		"invalid integer": {
+			line:          "cpu value=1.5i",
+			expectedError: `invalid field "value": invalid value "1.5i"`,
+		},
+		"unterminated string": {
+			line:          `cpu msg="abc`,
+			expectedError: `invalid field "msg": unterminated string value`,
+		},
+		"invalid timestamp": {
+			line:          "cpu value=1 abc",
+			expectedError: `invalid timestamp "abc"`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := parseInfluxLine(tc.line)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, p)
+		})
+	}
+}
+
+func TestInfluxPointsToWriteRequest(t *testing.T) {
+	points, errs := parseInfluxLines([]byte(`
+# Comments and empty lines are skipped.
+
+cpu,host=a,__name__=b,cpu-id=0 value=1,usage.idle=0.5 1622548800
+net up=true,name="eth0"
+mem,host-name=a,host_name=b used=1
+`))
+	require.Empty(t, errs)
+
+	now := time.Unix(1622548900, 0)
+	req, unsupported, errs := influxPointsToWriteRequest(InfluxConfig{MetricNamePrefix: "influx_", MetricNameSeparator: "_", ValueFieldName: "value"}, points, time.Second, now)
+	assert.Equal(t, 1, unsupported)
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], `line 6: tags "host-name" and "host_name" are both mapped to the label name "host_name"`)
+
+	tags := []cortexpb.LabelAdapter{{Name: "cpu_id", Value: "0"}, {Name: "host", Value: "a"}, {Name: "tag__name__", Value: "b"}}
+	assert.Equal(t, []cortexpb.PreallocTimeseries{
+		{TimeSeries: &cortexpb.TimeSeries{
+			Labels:  append([]cortexpb.LabelAdapter{{Name: "__name__", Value: "influx_cpu"}}, tags...),
+			Samples: []cortexpb.Sample{{Value: 1, TimestampMs: 1622548800000}},
+		}},
+		{TimeSeries: &cortexpb.TimeSeries{
+			Labels:  append([]cortexpb.LabelAdapter{{Name: "__name__", Value: "influx_cpu_usage_idle"}}, tags...),
+			Samples: []cortexpb.Sample{{Value: 0.5, TimestampMs: 1622548800000}},
+		}},
+		{TimeSeries: &cortexpb.TimeSeries{
+			Labels:  []cortexpb.LabelAdapter{{Name: "__name__", Value: "influx_net_up"}},
+			Samples: []cortexpb.Sample{{Value: 1, TimestampMs: 1622548900000}},
+		}},
+	}, req.Timeseries)
+	assert.Equal(t, cortexpb.API, req.Source)
+}
+
+func TestInfluxHandler(t *testing.T) {
+	cfg := InfluxConfig{MetricNameSeparator: "_", ValueFieldName: "value"}
+
+	tests := map[string]struct {
+		body             string
+		gzip             bool
+		precision        string
+		pushErr          error
+		expectedCode     int
+		expectedSeries   []string
+		expectedDiscards map[string]float64
+	}{
+		"valid lines": {
+			body:           "cpu,host=a value=1 1622548800000\nmem used=2i 1622548800000",
+			precision:      "ms",
+			expectedCode:   http.StatusNoContent,
+			expectedSeries: []string{`{__name__="cpu", host="a"}`, `{__name__="mem_used"}`},
+		},
+		"gzip compressed body": {
+			body:           "cpu value=1",
+			gzip:           true,
+			expectedCode:   http.StatusNoContent,
+			expectedSeries: []string{`{__name__="cpu"}`},
+		},
+		"invalid lines are discarded, while valid lines are pushed": {
+			body:             "cpu value=1\ncpu value=abc\nlog msg=\"text\"",
+			expectedCode:     http.StatusBadRequest,
+			expectedSeries:   []string{`{__name__="cpu"}`},
+			expectedDiscards: map[string]float64{influxInvalidLine: 1, influxUnsupportedFieldType: 1},
+		},
+		"points whose tags are mapped to the same label name are discarded": {
+			body:             "cpu,a-b=1,a_b=2 value=1\ncpu,a-b=1 value=1",
+			expectedCode:     http.StatusBadRequest,
+			expectedSeries:   []string{`{__name__="cpu", a_b="1"}`},
+			expectedDiscards: map[string]float64{influxDuplicateLabelName: 1},
+		},
+		"invalid precision": {
+			body:         "cpu value=1",
+			precision:    "d",
+			expectedCode: http.StatusBadRequest,
+		},
+		"push error": {
+			body:           "cpu value=1",
+			pushErr:        httpgrpc.Errorf(http.StatusTooManyRequests, "rate limited"),
+			expectedCode:   http.StatusTooManyRequests,
+			expectedSeries: []string{`{__name__="cpu"}`},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			userID := "influx-" + strings.ReplaceAll(name, " ", "-")
+
+			var pushed []string
+			handler := InfluxHandler(cfg, 100000, nil, func(ctx context.Context, req *cortexpb.WriteRequest) (*cortexpb.WriteResponse, error) {
+				for _, ts := range req.Timeseries {
+					pushed = append(pushed, cortexpb.FromLabelAdaptersToLabels(ts.Labels).String())
+				}
+				return &cortexpb.WriteResponse{}, tc.pushErr
+			})
+
+			body := []byte(tc.body)
+			if tc.gzip {
+				buf := bytes.Buffer{}
+				gz := gzip.NewWriter(&buf)
+				_, err := gz.Write(body)
+				require.NoError(t, err)
+				require.NoError(t, gz.Close())
+				body = buf.Bytes()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/push/influx/write?db=telegraf&precision="+tc.precision, bytes.NewReader(body))
+			if tc.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+			req = req.WithContext(user.InjectOrgID(req.Context(), userID))
+
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			assert.Equal(t, tc.expectedCode, resp.Code, resp.Body.String())
+			assert.Equal(t, tc.expectedSeries, pushed)
+
+			for _, reason := range []string{influxInvalidLine, influxUnsupportedFieldType, influxDuplicateLabelName} {
+				assert.Equal(t, tc.expectedDiscards[reason], testutil.ToFloat64(validation.DiscardedSamples.WithLabelValues(reason, userID)), reason)
+			}
+		})
+	}
+}
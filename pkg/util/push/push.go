@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 
+	kitlog "github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/weaveworks/common/httpgrpc"
 	"github.com/weaveworks/common/middleware"
@@ -42,15 +43,20 @@ func Handler(maxRecvMsgSize int, sourceIPs *middleware.SourceIPExtractor, push F
 		}
 
 		if _, err := push(ctx, &req.WriteRequest); err != nil {
-			resp, ok := httpgrpc.HTTPResponseFromError(err)
-			if !ok {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			if resp.GetCode() != 202 {
-				level.Error(logger).Log("msg", "push error", "err", err)
-			}
-			http.Error(w, string(resp.Body), int(resp.Code))
+			handlePushError(w, logger, err)
 		}
 	})
 }
+
+// handlePushError writes the error returned by the push function to the response.
+func handlePushError(w http.ResponseWriter, logger kitlog.Logger, err error) {
+	resp, ok := httpgrpc.HTTPResponseFromError(err)
+	if !ok {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if resp.GetCode() != 202 {
+		level.Error(logger).Log("msg", "push error", "err", err)
+	}
+	http.Error(w, string(resp.Body), int(resp.Code))
+}
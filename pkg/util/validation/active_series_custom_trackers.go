@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// ActiveSeriesCustomTrackers is a map of active series custom tracker names to their series selector.
+type ActiveSeriesCustomTrackers map[string]string
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (t *ActiveSeriesCustomTrackers) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	m := map[string]string{}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+
+	return t.set(m)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (t *ActiveSeriesCustomTrackers) UnmarshalJSON(data []byte) error {
+	m := map[string]string{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	return t.set(m)
+}
+
+func (t *ActiveSeriesCustomTrackers) set(m map[string]string) error {
+	for name, matcher := range m {
+		if name == "" {
+			return fmt.Errorf("the name of the active series custom tracker with matcher %s is required", matcher)
+		}
+		if _, err := parser.ParseMetricSelector(matcher); err != nil {
+			return fmt.Errorf("invalid matcher of the active series custom tracker %s: %w", name, err)
+		}
+	}
+
+	*t = m
+	return nil
+}
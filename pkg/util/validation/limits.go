@@ -16,12 +16,19 @@ import (
 	"github.com/cortexproject/cortex/pkg/util/flagext"
 )
 
-var errMaxGlobalSeriesPerUserValidation = errors.New("The ingester.max-global-series-per-user limit is unsupported if distributor.shard-by-all-labels is disabled")
+var (
+	errMaxGlobalSeriesPerUserValidation = errors.New("The ingester.max-global-series-per-user limit is unsupported if distributor.shard-by-all-labels is disabled")
+	errInvalidHADeduplicationMode       = errors.New("invalid HA deduplication mode, supported values are: election, samples")
+)
 
 // Supported values for enum limits
 const (
 	LocalIngestionRateStrategy  = "local"
 	GlobalIngestionRateStrategy = "global"
+	SharedIngestionRateStrategy = "shared"
+
+	HADeduplicationElection = "election"
+	HADeduplicationSamples  = "samples"
 )
 
 // LimitError are errors that do not comply with the limits specified.
@@ -42,6 +49,8 @@ type Limits struct {
 	HAClusterLabel            string              `yaml:"ha_cluster_label" json:"ha_cluster_label"`
 	HAReplicaLabel            string              `yaml:"ha_replica_label" json:"ha_replica_label"`
 	HAMaxClusters             int                 `yaml:"ha_max_clusters" json:"ha_max_clusters"`
+	HADeduplicationMode       string              `yaml:"ha_deduplication_mode" json:"ha_deduplication_mode"`
+	HADeduplicationInterval   model.Duration      `yaml:"ha_deduplication_min_sample_interval" json:"ha_deduplication_min_sample_interval"`
 	DropLabels                flagext.StringSlice `yaml:"drop_labels" json:"drop_labels"`
 	MaxLabelNameLength        int                 `yaml:"max_label_name_length" json:"max_label_name_length"`
 	MaxLabelValueLength       int                 `yaml:"max_label_value_length" json:"max_label_value_length"`
@@ -54,6 +63,9 @@ type Limits struct {
 	EnforceMetricName         bool                `yaml:"enforce_metric_name" json:"enforce_metric_name"`
 	IngestionTenantShardSize  int                 `yaml:"ingestion_tenant_shard_size" json:"ingestion_tenant_shard_size"`
 	MetricRelabelConfigs      []*relabel.Config   `yaml:"metric_relabel_configs,omitempty" json:"metric_relabel_configs,omitempty" doc:"nocli|description=List of metric relabel configurations. Note that in most situations, it is more effective to use metrics relabeling directly in the Prometheus server, e.g. remote_write.write_relabel_configs."`
+	ForwardingEndpoints       []string            `yaml:"forwarding_endpoints" json:"forwarding_endpoints" doc:"nocli|description=List of remote-write endpoints the tenant's accepted samples are asynchronously forwarded to. Requires -distributor.forwarding.enabled=true."`
+	ForwardingRelabelConfigs  []*relabel.Config   `yaml:"forwarding_relabel_configs,omitempty" json:"forwarding_relabel_configs,omitempty" doc:"nocli|description=List of relabel configurations applied to the tenant's series before forwarding them. Series whose labels are dropped are not forwarded."`
+	MetricLimits              []MetricLimit       `yaml:"metric_limits,omitempty" json:"metric_limits,omitempty" doc:"nocli|description=List of limits applied to the tenant's series matching a series selector. Each rule has a unique name, a matcher (series selector, e.g. {__name__=~\"http_.*\"}), the max_samples_per_sec enforced by the distributors according to the ingestion rate strategy with the optional burst_size (defaults to the tenant's ingestion burst size), and the max_series enforced by the ingesters (blocks storage only) like the global series limits. 0 disables the limit. Samples discarded by a rule are tracked in cortex_discarded_samples_total with a reason including the rule name."`
 
 	// Ingester enforced limits.
 	// Series
@@ -64,6 +76,10 @@ type Limits struct {
 	MaxGlobalSeriesPerUser   int `yaml:"max_global_series_per_user" json:"max_global_series_per_user"`
 	MaxGlobalSeriesPerMetric int `yaml:"max_global_series_per_metric" json:"max_global_series_per_metric"`
 	MinChunkLength           int `yaml:"min_chunk_length" json:"min_chunk_length"`
+	// Label values
+	MaxLabelValuesPerLabelName int `yaml:"max_label_values_per_label_name" json:"max_label_values_per_label_name"`
+	// Active series
+	ActiveSeriesCustomTrackers ActiveSeriesCustomTrackers `yaml:"active_series_custom_trackers" json:"active_series_custom_trackers" doc:"nocli|description=Additional active series trackers of the user, exported by the ingesters in the cortex_ingester_active_series_custom_tracker metric. Each tracker name is mapped to a series selector, e.g. checkout: '{team=\"checkout\"}'. Requires -ingester.active-series-metrics-enabled. This option is only supported by the blocks storage."`
 	// Metadata
 	MaxLocalMetricsWithMetadataPerUser  int `yaml:"max_metadata_per_user" json:"max_metadata_per_user"`
 	MaxLocalMetadataPerMetric           int `yaml:"max_metadata_per_metric" json:"max_metadata_per_metric"`
@@ -75,12 +91,14 @@ type Limits struct {
 	MaxChunksPerQuery            int            `yaml:"max_fetched_chunks_per_query" json:"max_fetched_chunks_per_query"`
 	MaxFetchedSeriesPerQuery     int            `yaml:"max_fetched_series_per_query" json:"max_fetched_series_per_query"`
 	MaxFetchedChunkBytesPerQuery int            `yaml:"max_fetched_chunk_bytes_per_query" json:"max_fetched_chunk_bytes_per_query"`
+	MaxFetchedExemplarsPerQuery  int            `yaml:"max_fetched_exemplars_per_query" json:"max_fetched_exemplars_per_query"`
 	MaxQueryLookback             model.Duration `yaml:"max_query_lookback" json:"max_query_lookback"`
 	MaxQueryLength               model.Duration `yaml:"max_query_length" json:"max_query_length"`
 	MaxQueryParallelism          int            `yaml:"max_query_parallelism" json:"max_query_parallelism"`
 	CardinalityLimit             int            `yaml:"cardinality_limit" json:"cardinality_limit"`
 	MaxCacheFreshness            model.Duration `yaml:"max_cache_freshness" json:"max_cache_freshness"`
 	MaxQueriersPerTenant         int            `yaml:"max_queriers_per_tenant" json:"max_queriers_per_tenant"`
+	QueryPartialResponseEnabled  bool           `yaml:"query_partial_response_enabled" json:"query_partial_response_enabled"`
 
 	// Ruler defaults and limits.
 	RulerEvaluationDelay        model.Duration `yaml:"ruler_evaluation_delay_duration" json:"ruler_evaluation_delay_duration"`
@@ -89,7 +107,8 @@ type Limits struct {
 	RulerMaxRuleGroupsPerTenant int            `yaml:"ruler_max_rule_groups_per_tenant" json:"ruler_max_rule_groups_per_tenant"`
 
 	// Store-gateway.
-	StoreGatewayTenantShardSize int `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
+	StoreGatewayTenantShardSize           int `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
+	StoreGatewayMaxInflightSeriesRequests int `yaml:"store_gateway_max_inflight_series_requests" json:"store_gateway_max_inflight_series_requests"`
 
 	// Compactor.
 	CompactorBlocksRetentionPeriod model.Duration `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
@@ -113,18 +132,24 @@ type Limits struct {
 	AlertmanagerMaxDispatcherAggregationGroups int `yaml:"alertmanager_max_dispatcher_aggregation_groups" json:"alertmanager_max_dispatcher_aggregation_groups"`
 	AlertmanagerMaxAlertsCount                 int `yaml:"alertmanager_max_alerts_count" json:"alertmanager_max_alerts_count"`
 	AlertmanagerMaxAlertsSizeBytes             int `yaml:"alertmanager_max_alerts_size_bytes" json:"alertmanager_max_alerts_size_bytes"`
+	AlertmanagerNotificationHistoryMaxEntries  int `yaml:"alertmanager_notification_history_max_entries" json:"alertmanager_notification_history_max_entries"`
+	AlertmanagerMaxSilencesCount               int `yaml:"alertmanager_max_silences_count" json:"alertmanager_max_silences_count"`
+	AlertmanagerMaxSilenceSizeBytes            int `yaml:"alertmanager_max_silence_size_bytes" json:"alertmanager_max_silence_size_bytes"`
 }
 
 // RegisterFlags adds the flags required to config this to the given FlagSet
 func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.IngestionTenantShardSize, "distributor.ingestion-tenant-shard-size", 0, "The default tenant's shard size when the shuffle-sharding strategy is used. Must be set both on ingesters and distributors. When this setting is specified in the per-tenant overrides, a value of 0 disables shuffle sharding for the tenant.")
 	f.Float64Var(&l.IngestionRate, "distributor.ingestion-rate-limit", 25000, "Per-user ingestion rate limit in samples per second.")
-	f.StringVar(&l.IngestionRateStrategy, "distributor.ingestion-rate-limit-strategy", "local", "Whether the ingestion rate limit should be applied individually to each distributor instance (local), or evenly shared across the cluster (global).")
+	f.StringVar(&l.IngestionRateStrategy, "distributor.ingestion-rate-limit-strategy", "local", "Whether the ingestion rate limit should be applied individually to each distributor instance (local), evenly shared across the cluster (global), or shared across the cluster proportionally to the rate of samples received by each distributor (shared).")
 	f.IntVar(&l.IngestionBurstSize, "distributor.ingestion-burst-size", 50000, "Per-user allowed ingestion burst size (in number of samples).")
 	f.BoolVar(&l.AcceptHASamples, "distributor.ha-tracker.enable-for-all-users", false, "Flag to enable, for all users, handling of samples with external labels identifying replicas in an HA Prometheus setup.")
 	f.StringVar(&l.HAClusterLabel, "distributor.ha-tracker.cluster", "cluster", "Prometheus label to look for in samples to identify a Prometheus HA cluster.")
 	f.StringVar(&l.HAReplicaLabel, "distributor.ha-tracker.replica", "__replica__", "Prometheus label to look for in samples to identify a Prometheus HA replica.")
 	f.IntVar(&l.HAMaxClusters, "distributor.ha-tracker.max-clusters", 0, "Maximum number of clusters that HA tracker will keep track of for single user. 0 to disable the limit.")
+	f.StringVar(&l.HADeduplicationMode, "distributor.ha-tracker.deduplication-mode", HADeduplicationElection, "How samples from Prometheus HA replicas are deduplicated. Supported values are: election (the HA tracker elects a replica for each cluster, and samples from the other replicas are dropped by the distributors), samples (samples from all the replicas are accepted without their replica label, and duplicated or out of order samples are dropped by the ingesters). The samples mode is only supported by the blocks storage.")
+	_ = l.HADeduplicationInterval.Set("10s")
+	f.Var(&l.HADeduplicationInterval, "distributor.ha-tracker.deduplication-min-sample-interval", "When deduplicating the samples from Prometheus HA replicas in the samples mode, the ingesters drop the samples of a series received less than this interval after the latest ingested sample, so that the samples of replicas not scraping targets at the same time are not interleaved. It should be lower than the scrape interval. 0 to only drop duplicated and out of order samples.")
 	f.Var(&l.DropLabels, "distributor.drop-label", "This flag can be used to specify label names that to drop during sample ingestion within the distributor and can be repeated in order to drop multiple labels.")
 	f.IntVar(&l.MaxLabelNameLength, "validation.max-length-label-name", 1024, "Maximum length accepted for label names")
 	f.IntVar(&l.MaxLabelValueLength, "validation.max-length-label-value", 2048, "Maximum length accepted for label value. This setting also applies to the metric name")
@@ -145,6 +170,7 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.MaxGlobalSeriesPerUser, "ingester.max-global-series-per-user", 0, "The maximum number of active series per user, across the cluster before replication. 0 to disable. Supported only if -distributor.shard-by-all-labels is true.")
 	f.IntVar(&l.MaxGlobalSeriesPerMetric, "ingester.max-global-series-per-metric", 0, "The maximum number of active series per metric name, across the cluster before replication. 0 to disable.")
 	f.IntVar(&l.MinChunkLength, "ingester.min-chunk-length", 0, "Minimum number of samples in an idle chunk to flush it to the store. Use with care, if chunks are less than this size they will be discarded. This option is ignored when running the Cortex blocks storage. 0 to disable.")
+	f.IntVar(&l.MaxLabelValuesPerLabelName, "ingester.max-label-values-per-label-name", 0, "The maximum number of distinct values of each label name (except the metric name) in the in-memory series of a user, per ingester. New series introducing a value beyond the limit are rejected. This limit is enforced only when running the Cortex blocks storage. The in-memory series created while the limit is disabled are not counted until the ingester restarts. 0 to disable.")
 
 	f.IntVar(&l.MaxLocalMetricsWithMetadataPerUser, "ingester.max-metadata-per-user", 8000, "The maximum number of active metrics with metadata per user, per ingester. 0 to disable.")
 	f.IntVar(&l.MaxLocalMetadataPerMetric, "ingester.max-metadata-per-metric", 10, "The maximum number of metadata per metric, per ingester. 0 to disable.")
@@ -152,8 +178,9 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.MaxGlobalMetadataPerMetric, "ingester.max-global-metadata-per-metric", 0, "The maximum number of metadata per metric, across the cluster. 0 to disable.")
 	f.IntVar(&l.MaxChunksPerQueryFromStore, "store.query-chunk-limit", 2e6, "Deprecated. Use -querier.max-fetched-chunks-per-query CLI flag and its respective YAML config option instead. Maximum number of chunks that can be fetched in a single query. This limit is enforced when fetching chunks from the long-term storage only. When running the Cortex chunks storage, this limit is enforced in the querier and ruler, while when running the Cortex blocks storage this limit is enforced in the querier, ruler and store-gateway. 0 to disable.")
 	f.IntVar(&l.MaxChunksPerQuery, "querier.max-fetched-chunks-per-query", 0, "Maximum number of chunks that can be fetched in a single query from ingesters and long-term storage: the total number of actual fetched chunks could be 2x the limit, being independently applied when querying ingesters and long-term storage. This limit is enforced in the ingester (if chunks streaming is enabled), querier, ruler and store-gateway. Takes precedence over the deprecated -store.query-chunk-limit. 0 to disable.")
-	f.IntVar(&l.MaxFetchedSeriesPerQuery, "querier.max-fetched-series-per-query", 0, "The maximum number of unique series for which a query can fetch samples from each ingesters and blocks storage. This limit is enforced in the querier only when running Cortex with blocks storage. 0 to disable")
-	f.IntVar(&l.MaxFetchedChunkBytesPerQuery, "querier.max-fetched-chunk-bytes-per-query", 0, "The maximum size of all chunks in bytes that a query can fetch from each ingester and storage. This limit is enforced in the querier and ruler only when running Cortex with blocks storage. 0 to disable.")
+	f.IntVar(&l.MaxFetchedSeriesPerQuery, "querier.max-fetched-series-per-query", 0, "The maximum number of unique series for which a query can fetch samples from each ingesters and blocks storage. This limit is enforced in the querier and store-gateway only when running Cortex with blocks storage. The store-gateway enforces it on the series matched by the query summed up across all the queried blocks, before fetching chunks. 0 to disable")
+	f.IntVar(&l.MaxFetchedChunkBytesPerQuery, "querier.max-fetched-chunk-bytes-per-query", 0, "The maximum size of all chunks in bytes that a query can fetch from each ingester and storage. This limit is enforced in the querier, ruler and store-gateway only when running Cortex with blocks storage. The store-gateway enforces it on the actual size of the chunks, while loading them. 0 to disable.")
+	f.IntVar(&l.MaxFetchedExemplarsPerQuery, "querier.max-fetched-exemplars-per-query", 0, "The maximum number of exemplars an exemplars query can fetch from ingesters and long-term storage. This limit is enforced in the querier. 0 to disable.")
 	f.Var(&l.MaxQueryLength, "store.max-query-length", "Limit the query time range (end - start time). This limit is enforced in the query-frontend (on the received query), in the querier (on the query possibly split by the query-frontend) and in the chunks storage. 0 to disable.")
 	f.Var(&l.MaxQueryLookback, "querier.max-query-lookback", "Limit how long back data (series and metadata) can be queried, up until <lookback> duration ago. This limit is enforced in the query-frontend, querier and ruler. If the requested time range is outside the allowed range, the request will not fail but will be manipulated to only query data within the allowed time range. 0 to disable.")
 	f.IntVar(&l.MaxQueryParallelism, "querier.max-query-parallelism", 14, "Maximum number of split queries will be scheduled in parallel by the frontend.")
@@ -161,6 +188,7 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	_ = l.MaxCacheFreshness.Set("1m")
 	f.Var(&l.MaxCacheFreshness, "frontend.max-cache-freshness", "Most recent allowed cacheable result per-tenant, to prevent caching very recent results that might still be in flux.")
 	f.IntVar(&l.MaxQueriersPerTenant, "frontend.max-queriers-per-tenant", 0, "Maximum number of queriers that can handle requests for a single tenant. If set to 0 or value higher than number of available queriers, *all* queriers will handle requests for the tenant. Each frontend (or query-scheduler, if used) will select the same set of queriers for the same tenant (given that all queriers are connected to all frontends / query-schedulers). This option only works with queriers connecting to the query-frontend / query-scheduler, not when using downstream URL.")
+	f.BoolVar(&l.QueryPartialResponseEnabled, "querier.partial-response-enabled", false, "Experimental. True to return partial results instead of failing the query when some blocks can't be queried from any store-gateway. The time ranges of the missing blocks are reported as warnings in the response, and the query-frontend doesn't cache responses with warnings. This option is only supported by the blocks storage.")
 
 	f.Var(&l.RulerEvaluationDelay, "ruler.evaluation-delay-duration", "Duration to delay the evaluation of rules to ensure the underlying metrics have been pushed to Cortex.")
 	f.IntVar(&l.RulerTenantShardSize, "ruler.tenant-shard-size", 0, "The default tenant's shard size when the shuffle-sharding strategy is used by ruler. When this setting is specified in the per-tenant overrides, a value of 0 disables shuffle sharding for the tenant.")
@@ -171,6 +199,7 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 
 	// Store-gateway.
 	f.IntVar(&l.StoreGatewayTenantShardSize, "store-gateway.tenant-shard-size", 0, "The default tenant's shard size when the shuffle-sharding strategy is used. Must be set when the store-gateway sharding is enabled with the shuffle-sharding strategy. When this setting is specified in the per-tenant overrides, a value of 0 disables shuffle sharding for the tenant.")
+	f.IntVar(&l.StoreGatewayMaxInflightSeriesRequests, "store-gateway.max-inflight-series-requests", 0, "Maximum number of in-flight Series requests a single store-gateway serves concurrently for a tenant. Requests received once the limit is reached are rejected. 0 to disable.")
 
 	// Alertmanager.
 	f.Var(&l.AlertmanagerReceiversBlockCIDRNetworks, "alertmanager.receivers-firewall-block-cidr-networks", "Comma-separated list of network CIDRs to block in Alertmanager receiver integrations.")
@@ -188,6 +217,9 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.AlertmanagerMaxDispatcherAggregationGroups, "alertmanager.max-dispatcher-aggregation-groups", 0, "Maximum number of aggregation groups in Alertmanager's dispatcher that a tenant can have. Each active aggregation group uses single goroutine. When the limit is reached, dispatcher will not dispatch alerts that belong to additional aggregation groups, but existing groups will keep working properly. 0 = no limit.")
 	f.IntVar(&l.AlertmanagerMaxAlertsCount, "alertmanager.max-alerts-count", 0, "Maximum number of alerts that a single user can have. Inserting more alerts will fail with a log message and metric increment. 0 = no limit.")
 	f.IntVar(&l.AlertmanagerMaxAlertsSizeBytes, "alertmanager.max-alerts-size-bytes", 0, "Maximum total size of alerts that a single user can have, alert size is the sum of the bytes of its labels, annotations and generatorURL. Inserting more alerts will fail with a log message and metric increment. 0 = no limit.")
+	f.IntVar(&l.AlertmanagerNotificationHistoryMaxEntries, "alertmanager.notification-history-max-entries", 0, "Maximum number of notification attempts kept in the tenant's notification history. When the limit is reached, the oldest entries are dropped. 0 = notification history disabled. Enable it only once all the Alertmanager replicas have been upgraded to a version supporting the notification history, because older replicas skip its replicated state.")
+	f.IntVar(&l.AlertmanagerMaxSilencesCount, "alertmanager.max-silences-count", 0, "Maximum number of active and pending silences that a tenant can have. Creating more silences via the Alertmanager API will fail. 0 = no limit.")
+	f.IntVar(&l.AlertmanagerMaxSilenceSizeBytes, "alertmanager.max-silence-size-bytes", 0, "Maximum size of a single silence that a tenant can create via the Alertmanager API, silence size is the sum of the bytes of its matchers, comment and author. 0 = no limit.")
 }
 
 // Validate the limits config and returns an error if the validation
@@ -199,6 +231,16 @@ func (l *Limits) Validate(shardByAllLabels bool) error {
 		return errMaxGlobalSeriesPerUserValidation
 	}
 
+	switch l.HADeduplicationMode {
+	case "", HADeduplicationElection, HADeduplicationSamples:
+	default:
+		return errInvalidHADeduplicationMode
+	}
+
+	if err := validateMetricLimits(l.MetricLimits); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -286,7 +328,8 @@ func (o *Overrides) IngestionRate(userID string) float64 {
 }
 
 // IngestionRateStrategy returns whether the ingestion rate limit should be individually applied
-// to each distributor instance (local) or evenly shared across the cluster (global).
+// to each distributor instance (local), evenly shared across the cluster (global) or shared across
+// the cluster proportionally to the rate of samples received by each distributor (shared).
 func (o *Overrides) IngestionRateStrategy() string {
 	// The ingestion rate strategy can't be overridden on a per-tenant basis
 	return o.defaultLimits.IngestionRateStrategy
@@ -307,6 +350,17 @@ func (o *Overrides) HAClusterLabel(userID string) string {
 	return o.getOverridesForUser(userID).HAClusterLabel
 }
 
+// HADeduplicationMode returns how samples from Prometheus HA replicas are deduplicated for a given user.
+func (o *Overrides) HADeduplicationMode(userID string) string {
+	return o.getOverridesForUser(userID).HADeduplicationMode
+}
+
+// HADeduplicationInterval returns the min interval between the ingested samples of a series
+// when deduplicating the samples from Prometheus HA replicas for a given user.
+func (o *Overrides) HADeduplicationInterval(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).HADeduplicationInterval)
+}
+
 // HAReplicaLabel returns the replica label to look for when deciding whether to accept a sample from a Prometheus HA replica.
 func (o *Overrides) HAReplicaLabel(userID string) string {
 	return o.getOverridesForUser(userID).HAReplicaLabel
@@ -386,6 +440,16 @@ func (o *Overrides) MaxGlobalSeriesPerMetric(userID string) int {
 	return o.getOverridesForUser(userID).MaxGlobalSeriesPerMetric
 }
 
+// MaxLabelValuesPerLabelName returns the maximum number of distinct values of each label name in a single ingester.
+func (o *Overrides) MaxLabelValuesPerLabelName(userID string) int {
+	return o.getOverridesForUser(userID).MaxLabelValuesPerLabelName
+}
+
+// ActiveSeriesCustomTrackers returns the active series custom trackers of a given user.
+func (o *Overrides) ActiveSeriesCustomTrackers(userID string) ActiveSeriesCustomTrackers {
+	return o.getOverridesForUser(userID).ActiveSeriesCustomTrackers
+}
+
 // MaxChunksPerQueryFromStore returns the maximum number of chunks allowed per query when fetching
 // chunks from the long-term storage.
 func (o *Overrides) MaxChunksPerQueryFromStore(userID string) int {
@@ -416,6 +480,18 @@ func (o *Overrides) MaxFetchedChunkBytesPerQuery(userID string) int {
 	return o.getOverridesForUser(userID).MaxFetchedChunkBytesPerQuery
 }
 
+// MaxFetchedExemplarsPerQuery returns the maximum number of exemplars allowed per query when fetching
+// exemplars from ingesters and blocks storage.
+func (o *Overrides) MaxFetchedExemplarsPerQuery(userID string) int {
+	return o.getOverridesForUser(userID).MaxFetchedExemplarsPerQuery
+}
+
+// QueryPartialResponseEnabled returns whether the queries of the user can return partial results when
+// some blocks can't be queried from any store-gateway.
+func (o *Overrides) QueryPartialResponseEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).QueryPartialResponseEnabled
+}
+
 // MaxQueryLookback returns the max lookback period of queries.
 func (o *Overrides) MaxQueryLookback(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).MaxQueryLookback)
@@ -503,6 +579,21 @@ func (o *Overrides) MetricRelabelConfigs(userID string) []*relabel.Config {
 	return o.getOverridesForUser(userID).MetricRelabelConfigs
 }
 
+// ForwardingEndpoints returns the remote-write endpoints the samples of a given user are forwarded to.
+func (o *Overrides) ForwardingEndpoints(userID string) []string {
+	return o.getOverridesForUser(userID).ForwardingEndpoints
+}
+
+// ForwardingRelabelConfigs returns the relabel configs applied to the series of a given user before forwarding them.
+func (o *Overrides) ForwardingRelabelConfigs(userID string) []*relabel.Config {
+	return o.getOverridesForUser(userID).ForwardingRelabelConfigs
+}
+
+// MetricLimits returns the limits applied to the series of a given user matching a series selector.
+func (o *Overrides) MetricLimits(userID string) []MetricLimit {
+	return o.getOverridesForUser(userID).MetricLimits
+}
+
 // RulerTenantShardSize returns shard size (number of rulers) used by this tenant when using shuffle-sharding strategy.
 func (o *Overrides) RulerTenantShardSize(userID string) int {
 	return o.getOverridesForUser(userID).RulerTenantShardSize
@@ -523,6 +614,12 @@ func (o *Overrides) StoreGatewayTenantShardSize(userID string) int {
 	return o.getOverridesForUser(userID).StoreGatewayTenantShardSize
 }
 
+// StoreGatewayMaxInflightSeriesRequests returns the maximum number of in-flight Series requests
+// a single store-gateway serves concurrently for a given user.
+func (o *Overrides) StoreGatewayMaxInflightSeriesRequests(userID string) int {
+	return o.getOverridesForUser(userID).StoreGatewayMaxInflightSeriesRequests
+}
+
 // MaxHAClusters returns maximum number of clusters that HA tracker will track for a user.
 func (o *Overrides) MaxHAClusters(user string) int {
 	return o.getOverridesForUser(user).HAMaxClusters
@@ -627,6 +724,18 @@ func (o *Overrides) AlertmanagerMaxAlertsSizeBytes(userID string) int {
 	return o.getOverridesForUser(userID).AlertmanagerMaxAlertsSizeBytes
 }
 
+func (o *Overrides) AlertmanagerNotificationHistoryMaxEntries(userID string) int {
+	return o.getOverridesForUser(userID).AlertmanagerNotificationHistoryMaxEntries
+}
+
+func (o *Overrides) AlertmanagerMaxSilencesCount(userID string) int {
+	return o.getOverridesForUser(userID).AlertmanagerMaxSilencesCount
+}
+
+func (o *Overrides) AlertmanagerMaxSilenceSizeBytes(userID string) int {
+	return o.getOverridesForUser(userID).AlertmanagerMaxSilenceSizeBytes
+}
+
 func (o *Overrides) getOverridesForUser(userID string) *Limits {
 	if o.tenantLimits != nil {
 		l := o.tenantLimits.ByUserID(userID)
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/pkg/relabel"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -61,6 +62,26 @@ func TestLimits_Validate(t *testing.T) {
 			shardByAllLabels: true,
 			expected:         nil,
 		},
+		"ha-deduplication-mode=samples": {
+			limits:   Limits{HADeduplicationMode: HADeduplicationSamples},
+			expected: nil,
+		},
+		"invalid ha-deduplication-mode": {
+			limits:   Limits{HADeduplicationMode: "unknown"},
+			expected: errInvalidHADeduplicationMode,
+		},
+		"metric limit rules with unique names": {
+			limits:   Limits{MetricLimits: []MetricLimit{{Name: "first"}, {Name: "second"}}},
+			expected: nil,
+		},
+		"metric limit rules with duplicated names": {
+			limits:   Limits{MetricLimits: []MetricLimit{{Name: "first"}, {Name: "first"}}},
+			expected: errDuplicateMetricLimitName,
+		},
+		"metric limit rule without name": {
+			limits:   Limits{MetricLimits: []MetricLimit{{Matcher: `{__name__="foo"}`}}},
+			expected: errMissingMetricLimitName,
+		},
 	}
 
 	for testName, testData := range tests {
@@ -255,6 +276,73 @@ metric_relabel_configs:
 	assert.Equal(t, []*relabel.Config{&exp}, l.MetricRelabelConfigs)
 }
 
+func TestMetricLimitsLoadingFromYamlAndJson(t *testing.T) {
+	SetDefaultLimitsForYAMLUnmarshalling(Limits{})
+
+	inputYAML := `
+metric_limits:
+- name: http
+  matcher: '{__name__=~"http_.*", job!="test"}'
+  max_samples_per_sec: 100
+  max_series: 1000
+`
+	inputJSON := `{"metric_limits": [{"name": "http", "matcher": "{__name__=~\"http_.*\", job!=\"test\"}", "max_samples_per_sec": 100, "max_series": 1000}]}`
+
+	limitsYAML := Limits{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(inputYAML), &limitsYAML))
+
+	limitsJSON := Limits{}
+	require.NoError(t, json.Unmarshal([]byte(inputJSON), &limitsJSON))
+
+	assert.Equal(t, limitsYAML, limitsJSON)
+	require.Len(t, limitsYAML.MetricLimits, 1)
+
+	rule := limitsYAML.MetricLimits[0]
+	assert.Equal(t, "http", rule.Name)
+	assert.Equal(t, 100.0, rule.MaxSamplesPerSec)
+	assert.Equal(t, 1000, rule.MaxSeries)
+	assert.True(t, rule.Matches(labels.FromStrings(labels.MetricName, "http_requests_total", "job", "api")))
+	assert.False(t, rule.Matches(labels.FromStrings(labels.MetricName, "http_requests_total", "job", "test")))
+	assert.False(t, rule.Matches(labels.FromStrings(labels.MetricName, "grpc_requests_total", "job", "api")))
+
+	// The matcher is parsed on demand if the rule hasn't been unmarshalled.
+	rule = MetricLimit{Name: "http", Matcher: `{__name__=~"http_.*"}`}
+	assert.True(t, rule.Matches(labels.FromStrings(labels.MetricName, "http_requests_total")))
+
+	// Invalid rules are rejected.
+	for _, input := range []string{
+		"metric_limits:\n- matcher: '{__name__=\"foo\"}'\n",
+		"metric_limits:\n- name: foo\n  matcher: 'foo{'\n",
+	} {
+		assert.Error(t, yaml.UnmarshalStrict([]byte(input), &Limits{}), input)
+	}
+}
+
+func TestActiveSeriesCustomTrackersLoadingFromYamlAndJson(t *testing.T) {
+	SetDefaultLimitsForYAMLUnmarshalling(Limits{})
+
+	inputYAML := `
+active_series_custom_trackers:
+  checkout: '{team="checkout"}'
+  http: '{__name__=~"http_.*"}'
+`
+	inputJSON := `{"active_series_custom_trackers": {"checkout": "{team=\"checkout\"}", "http": "{__name__=~\"http_.*\"}"}}`
+
+	limitsYAML := Limits{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(inputYAML), &limitsYAML))
+
+	limitsJSON := Limits{}
+	require.NoError(t, json.Unmarshal([]byte(inputJSON), &limitsJSON))
+
+	expected := ActiveSeriesCustomTrackers{"checkout": `{team="checkout"}`, "http": `{__name__=~"http_.*"}`}
+	assert.Equal(t, expected, limitsYAML.ActiveSeriesCustomTrackers)
+	assert.Equal(t, expected, limitsJSON.ActiveSeriesCustomTrackers)
+
+	// Invalid matchers are rejected.
+	assert.Error(t, yaml.UnmarshalStrict([]byte("active_series_custom_trackers:\n  checkout: '{team='\n"), &Limits{}))
+	assert.Error(t, json.Unmarshal([]byte(`{"active_series_custom_trackers": {"checkout": "{team="}}`), &Limits{}))
+}
+
 func TestSmallestPositiveIntPerTenant(t *testing.T) {
 	tenantLimits := map[string]*Limits{
 		"tenant-a": {
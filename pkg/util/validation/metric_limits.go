@@ -0,0 +1,110 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+var (
+	errMissingMetricLimitName   = errors.New("the name of the metric limit rule is required")
+	errDuplicateMetricLimitName = errors.New("the names of the metric limit rules must be unique")
+)
+
+// MetricLimit is a limit applied to the series of a tenant matching a series selector.
+type MetricLimit struct {
+	// Name of the rule, used in the discarded samples reasons.
+	Name string `yaml:"name" json:"name"`
+	// Series selector, e.g. {__name__=~"http_requests_.*"}.
+	Matcher string `yaml:"matcher" json:"matcher"`
+	// Maximum rate of samples per second of the matching series. 0 to disable.
+	MaxSamplesPerSec float64 `yaml:"max_samples_per_sec" json:"max_samples_per_sec"`
+	// Maximum burst of samples of the matching series. 0 to use the tenant's ingestion burst size.
+	BurstSize int `yaml:"burst_size" json:"burst_size"`
+	// Maximum number of matching series. 0 to disable.
+	MaxSeries int `yaml:"max_series" json:"max_series"`
+
+	matchers []*labels.Matcher
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (m *MetricLimit) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain MetricLimit
+	if err := unmarshal((*plain)(m)); err != nil {
+		return err
+	}
+
+	return m.compile()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (m *MetricLimit) UnmarshalJSON(data []byte) error {
+	type plain MetricLimit
+	if err := json.Unmarshal(data, (*plain)(m)); err != nil {
+		return err
+	}
+
+	return m.compile()
+}
+
+func (m *MetricLimit) compile() error {
+	if m.Name == "" {
+		return errMissingMetricLimitName
+	}
+
+	matchers, err := parser.ParseMetricSelector(m.Matcher)
+	if err != nil {
+		return fmt.Errorf("invalid matcher of the metric limit rule %s: %w", m.Name, err)
+	}
+
+	m.matchers = matchers
+	return nil
+}
+
+// Matches returns whether the input series matches the rule's series selector.
+func (m *MetricLimit) Matches(series labels.Labels) bool {
+	matchers := m.matchers
+	if matchers == nil {
+		// The rule hasn't been unmarshalled, so the matcher is parsed on demand.
+		var err error
+		if matchers, err = parser.ParseMetricSelector(m.Matcher); err != nil {
+			return false
+		}
+	}
+
+	for _, matcher := range matchers {
+		if !matcher.Matches(series.Get(matcher.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// MetricLimitRateLimitedReason returns the reason to discard the samples exceeding
+// the samples rate of a metric limit rule.
+func MetricLimitRateLimitedReason(rule string) string {
+	return "metric_limit_rate_limited:" + rule
+}
+
+// MetricLimitMaxSeriesReason returns the reason to discard the samples of the series
+// exceeding the series limit of a metric limit rule.
+func MetricLimitMaxSeriesReason(rule string) string {
+	return "metric_limit_max_series:" + rule
+}
+
+func validateMetricLimits(rules []MetricLimit) error {
+	names := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return errMissingMetricLimitName
+		}
+		if _, ok := names[rule.Name]; ok {
+			return errDuplicateMetricLimitName
+		}
+		names[rule.Name] = struct{}{}
+	}
+	return nil
+}
@@ -257,6 +257,8 @@ func getFieldType(t reflect.Type) (string, error) {
 		return "string", nil
 	case "[]*relabel.Config":
 		return "relabel_config...", nil
+	case "[]validation.MetricLimit":
+		return "metric_limit...", nil
 	}
 
 	// Fallback to auto-detection of built-in data types